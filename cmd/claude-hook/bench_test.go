@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/pkg/hooktest"
+)
+
+// benchConfig turns on the checks that work out what to run from the tree, so
+// their cost grows with the fixture like it would in a real project
+const benchConfig = `go:
+  vet: true
+  dependents:
+    enabled: true
+complexity:
+  enabled: true
+`
+
+// fixtureRepo creates a committed module of packages packages, each with a
+// few functions, and fakes go so only the hooks' own work is measured.
+// It returns the repository and the log of go calls.
+func fixtureRepo(tb testing.TB, packages int) (root, goLog string) {
+	tb.Helper()
+	hooktest.Isolate(tb)
+	files := map[string]string{
+		"go.mod":             "module example.com/fixture\n\ngo 1.22\n",
+		".claude-hooks.yaml": benchConfig,
+	}
+	for i := range packages {
+		var src strings.Builder
+		fmt.Fprintf(&src, "package p%d\n", i)
+		for j := range 5 {
+			fmt.Fprintf(&src, "\nfunc F%d(n int) int {\n\tif n > %d {\n\t\treturn n\n\t}\n\treturn %d\n}\n", j, j, i)
+		}
+		files[fmt.Sprintf("p%d/p.go", i)] = src.String()
+	}
+	root = hooktest.Repo(tb, files)
+	goLog = hooktest.FakeTool(tb, "go", "exit 0")
+	return root, goLog
+}
+
+// editPackages changes the first function of the first n packages, as an
+// Edit would, and returns the edited files
+func editPackages(tb testing.TB, root string, n int) []string {
+	tb.Helper()
+	var files []string
+	for i := range n {
+		src := fmt.Sprintf("package p%d\n\nfunc F0(n int) int {\n\treturn n * 2\n}\n", i)
+		files = append(files, hooktest.WriteFile(tb, root, fmt.Sprintf("p%d/p.go", i), src))
+	}
+	return files
+}
+
+func BenchmarkPostEdit(b *testing.B) {
+	for _, size := range []struct {
+		packages, edited int
+	}{{10, 1}, {100, 1}, {1000, 1}, {1000, 20}} {
+		b.Run(fmt.Sprintf("packages=%d/edited=%d", size.packages, size.edited), func(b *testing.B) {
+			root, _ := fixtureRepo(b, size.packages)
+			files := editPackages(b, root, size.edited)
+			b.ResetTimer()
+			for b.Loop() {
+				if result := runPipeline("post-edit", files, nil, nil, false); len(result.errorMessages) > 0 {
+					b.Fatalf("Expected the edit to pass, got %v", result.errorMessages)
+				}
+			}
+		})
+	}
+}
+
+// TestPipelineToolCalls guards the pipeline's cost on a large repository:
+// edits across many packages of one module still run each go step once
+func TestPipelineToolCalls(t *testing.T) {
+	root, goLog := fixtureRepo(t, 200)
+	files := editPackages(t, root, 30)
+
+	result := runPipeline("post-edit", files, nil, nil, false)
+	if len(result.errorMessages) > 0 {
+		t.Fatalf("Expected the edit to pass, got %v", result.errorMessages)
+	}
+
+	calls := hooktest.Calls(t, goLog)
+	if len(calls) != 2 || !strings.HasPrefix(calls[0], "vet ") || !strings.HasPrefix(calls[1], "list ") {
+		t.Fatalf("Expected one go vet and one go list for the whole module, got %d calls:\n%s", len(calls), strings.Join(calls, "\n"))
+	}
+	if got := strings.Count(calls[0], "./p"); got != len(files) {
+		t.Errorf("Expected go vet to get all %d edited packages, got %d", len(files), got)
+	}
+
+	var stages []string
+	for _, timing := range result.timings {
+		stages = append(stages, timing.Stage)
+	}
+	if !strings.HasPrefix(strings.Join(stages, ","), "go hook,build configurations,duplicates,complexity") {
+		t.Errorf("Expected the stages to be timed, got %v", stages)
+	}
+}
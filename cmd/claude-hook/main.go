@@ -1,39 +1,109 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"maps"
+	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
+	"github.com/brianleishman/claude-hooks/internal/audit"
+	"github.com/brianleishman/claude-hooks/internal/bench"
+	"github.com/brianleishman/claude-hooks/internal/ci"
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/guardrails"
+	"github.com/brianleishman/claude-hooks/internal/health"
 	"github.com/brianleishman/claude-hooks/internal/hooks"
+	"github.com/brianleishman/claude-hooks/internal/lsp"
+	"github.com/brianleishman/claude-hooks/internal/network"
+	"github.com/brianleishman/claude-hooks/internal/notify"
+	"github.com/brianleishman/claude-hooks/internal/policy"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/profile"
+	"github.com/brianleishman/claude-hooks/internal/reason"
+	"github.com/brianleishman/claude-hooks/internal/rollback"
+	"github.com/brianleishman/claude-hooks/internal/settings"
+	"github.com/brianleishman/claude-hooks/internal/shell"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/telemetry"
+	"github.com/brianleishman/claude-hooks/internal/tools"
+	"github.com/brianleishman/claude-hooks/internal/update"
+	"github.com/brianleishman/claude-hooks/internal/vcs"
+	"github.com/brianleishman/claude-hooks/internal/version"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+	"github.com/brianleishman/claude-hooks/internal/watch"
+	"gopkg.in/yaml.v3"
 )
 
 // ToolInput represents the input from Claude Code
 type ToolInput struct {
-	FilePath  string   `json:"file_path"`
-	FilePaths []string `json:"file_paths"`
-	Command   string   `json:"command"` // For Bash commands in PreToolUse
-	Content   string   `json:"content"` // For Write tool content
+	FilePath     string     `json:"file_path"`
+	FilePaths    []string   `json:"file_paths"`
+	NotebookPath string     `json:"notebook_path"` // For NotebookEdit
+	Command      string     `json:"command"`       // For Bash commands in PreToolUse
+	Content      string     `json:"content"`       // For Write tool content
+	OldString    string     `json:"old_string"`    // For Edit
+	NewString    string     `json:"new_string"`    // For Edit
+	ReplaceAll   bool       `json:"replace_all"`   // For Edit: every occurrence was replaced
+	Edits        []ToolEdit `json:"edits"`         // For MultiEdit
+}
+
+// ToolEdit is one replacement of a MultiEdit call
+type ToolEdit struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all"`
 }
 
 // Input represents the complete input structure
 type Input struct {
+	SessionID      string    `json:"session_id"`
 	ToolName       string    `json:"tool_name"` // Tool being called (e.g., "Bash")
 	ToolInput      ToolInput `json:"tool_input"`
-	TranscriptPath string    `json:"transcript_path"` // Path to conversation transcript
-	Cwd            string    `json:"cwd"`             // Current working directory
+	TranscriptPath string    `json:"transcript_path"`  // Path to conversation transcript
+	Cwd            string    `json:"cwd"`              // Current working directory
+	StopHookActive bool      `json:"stop_hook_active"` // Stop hooks: Claude is already continuing because of one
+	AgentID        string    `json:"agent_id"`         // Set for subagents only
+	AgentType      string    `json:"agent_type"`       // The subagent's type, e.g. Explore
 }
 
-// HookOutput represents the JSON response for PostToolUse hooks
+// HookOutput represents the JSON response for PostToolUse and Stop hooks
 type HookOutput struct {
-	Decision string `json:"decision,omitempty"` // "block" to notify Claude of issues
-	Reason   string `json:"reason,omitempty"`   // Detailed explanation for Claude
+	Continue       *bool  `json:"continue,omitempty"`       // false stops Claude altogether, whatever the decision
+	StopReason     string `json:"stopReason,omitempty"`     // Shown to the user when Continue is false
+	SuppressOutput bool   `json:"suppressOutput,omitempty"` // Keeps stdout out of the transcript
+	SystemMessage  string `json:"systemMessage,omitempty"`  // Shown to the user
+	Decision       string `json:"decision,omitempty"`       // "block" to notify Claude of issues
+	Reason         string `json:"reason,omitempty"`         // Detailed explanation for Claude
+	// HookSpecificOutput gives Claude context without blocking; PostToolUse
+	// and UserPromptSubmit only
+	HookSpecificOutput *PostToolUseHookOutput `json:"hookSpecificOutput,omitempty"`
+}
+
+// SystemMessageOutput shows the user a message without making a decision, so
+// Claude Code's own permission handling still applies
+type SystemMessageOutput struct {
+	SystemMessage string `json:"systemMessage"`
+}
+
+// PostToolUseHookOutput gives Claude context after a tool call without
+// blocking it
+type PostToolUseHookOutput struct {
+	HookEventName     string `json:"hookEventName"`
+	AdditionalContext string `json:"additionalContext"`
 }
 
 // PreToolUseOutput represents the JSON response for PreToolUse hooks
@@ -43,8 +113,10 @@ type PreToolUseOutput struct {
 
 type PreToolUseHookOutput struct {
 	HookEventName            string `json:"hookEventName"`
-	PermissionDecision       string `json:"permissionDecision"` // "allow", "deny", or "ask"
-	PermissionDecisionReason string `json:"permissionDecisionReason"`
+	PermissionDecision       string `json:"permissionDecision,omitempty"` // "allow", "deny", or "ask"
+	PermissionDecisionReason string `json:"permissionDecisionReason,omitempty"`
+	// AdditionalContext is added to Claude's context before the tool runs
+	AdditionalContext string `json:"additionalContext,omitempty"`
 }
 
 // SessionStartInput represents the input for SessionStart hooks
@@ -56,7 +128,8 @@ type SessionStartInput struct {
 	Source         string `json:"source"` // "startup", "resume", "clear", or "compact"
 }
 
-// getCurrentBranch returns the current git branch name, or empty string if not in a git repo
+// getCurrentBranch returns the current branch name (a bookmark in jj, or in hg
+// when one is active), or empty string if not in a repo
 // workingDir specifies which directory to check (empty string uses current directory)
 func getCurrentBranch(workingDir string, verbose bool) string {
 	if verbose {
@@ -66,50 +139,17 @@ func getCurrentBranch(workingDir string, verbose bool) string {
 			fmt.Fprintf(os.Stderr, "🔍 Detecting current git branch...\n")
 		}
 	}
-
-	var cmd *exec.Cmd
-	if workingDir != "" {
-		cmd = exec.Command("git", "-C", workingDir, "branch", "--show-current")
-	} else {
-		cmd = exec.Command("git", "branch", "--show-current")
-	}
-
-	output, err := cmd.Output()
-	if err != nil {
-		if verbose {
-			fmt.Fprintf(os.Stderr, "🔍 First method failed (%v), trying fallback...\n", err)
-		}
-		// Try alternative method for older git versions or detached HEAD
-		if workingDir != "" {
-			cmd = exec.Command("git", "-C", workingDir, "rev-parse", "--abbrev-ref", "HEAD")
-		} else {
-			cmd = exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-		}
-		output, err = cmd.Output()
-		if err != nil {
-			if verbose {
-				fmt.Fprintf(os.Stderr, "🔍 Fallback method also failed (%v), not in git repo\n", err)
-			}
-			return "" // Not in a git repo or other error
-		}
-	}
-
-	branch := strings.TrimSpace(string(output))
-	if verbose {
-		fmt.Fprintf(os.Stderr, "🔍 Raw branch output: %q\n", branch)
+	if workingDir == "" {
+		workingDir = "."
 	}
 
-	// Handle detached HEAD case
-	if branch == "HEAD" {
-		if verbose {
-			fmt.Fprintf(os.Stderr, "🔍 Detected detached HEAD state\n")
-		}
+	repo := vcs.Detect(workingDir)
+	if repo == nil {
+		vlog.Printf(verbose, "🔍 Not in a repository\n")
 		return ""
 	}
-
-	if verbose {
-		fmt.Fprintf(os.Stderr, "🔍 Current branch: %q\n", branch)
-	}
+	branch := repo.Branch()
+	vlog.Printf(verbose, "🔍 Current %s branch: %q\n", repo.Kind(), branch)
 
 	return branch
 }
@@ -120,26 +160,34 @@ func isProtectedBranch(branch string) bool {
 	return slices.Contains(protectedBranches, branch)
 }
 
+// featureBranchSteps tells how to commit to a feature branch instead of base
+// with executable's workflow
+func featureBranchSteps(executable, base string) string {
+	switch executable {
+	case vcs.HG:
+		return "1. Create and activate a bookmark:\n   hg bookmark feature/your-feature-name\n\n2. Make your commits on the bookmark:\n   hg commit -m \"your commit message\"\n\n3. Push the bookmark:\n   hg push -B feature/your-feature-name\n\n4. Create a pull request to merge into " + base
+	case vcs.JJ:
+		return "1. Point a new bookmark at your change:\n   jj bookmark create feature/your-feature-name -r @-\n\n2. Push the bookmark:\n   jj git push -b feature/your-feature-name\n\n3. Create a pull request to merge into " + base
+	}
+	return "1. Create and switch to a new branch:\n   git checkout -b feature/your-feature-name\n\n2. Make your commits on the feature branch:\n   git commit -m \"your commit message\"\n\n3. Push the feature branch:\n   git push -u origin feature/your-feature-name\n\n4. Create a pull request to merge into " + base
+}
+
 // getTargetWorkingDirectory determines the target project directory from available context
 // Returns empty string if we can't confidently determine the target directory
 func getTargetWorkingDirectory(input Input, verbose bool) string {
 	// Option 1: Check for CLAUDE_CODE_CWD environment variable
 	if claudeDir := os.Getenv("CLAUDE_CODE_CWD"); claudeDir != "" {
-		if verbose {
-			fmt.Fprintf(os.Stderr, "🔍 Using working directory from CLAUDE_CODE_CWD: %s\n", claudeDir)
-		}
+		vlog.Printf(verbose, "🔍 Using working directory from CLAUDE_CODE_CWD: %s\n", claudeDir)
 		return claudeDir
 	}
 
 	// Option 2: Infer from file paths (most reliable)
 	files := collectFiles(input.ToolInput)
 	if len(files) > 0 {
-		// Find the git repository root for any file
+		// Find the repository root for any file
 		for _, file := range files {
-			if dir := findGitRoot(file, verbose); dir != "" {
-				if verbose {
-					fmt.Fprintf(os.Stderr, "🔍 Inferred working directory from file path: %s\n", dir)
-				}
+			if dir, _ := vcs.FindRoot(filepath.Dir(file)); dir != "" {
+				vlog.Printf(verbose, "🔍 Inferred working directory from file path: %s\n", dir)
 				return dir
 			}
 		}
@@ -156,15 +204,11 @@ func getTargetWorkingDirectory(input Input, verbose bool) string {
 			return "" // Return empty to skip protection
 		}
 
-		if verbose {
-			fmt.Fprintf(os.Stderr, "🔍 Using current working directory as fallback: %s\n", cwd)
-		}
+		vlog.Printf(verbose, "🔍 Using current working directory as fallback: %s\n", cwd)
 		return cwd
 	}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "🔍 Could not determine target working directory\n")
-	}
+	vlog.Printf(verbose, "🔍 Could not determine target working directory\n")
 	return ""
 }
 
@@ -176,9 +220,7 @@ func findGitRoot(filePath string, verbose bool) string {
 	for {
 		gitDir := filepath.Join(dir, ".git")
 		if _, err := os.Stat(gitDir); err == nil {
-			if verbose {
-				fmt.Fprintf(os.Stderr, "🔍 Found git root at: %s\n", dir)
-			}
+			vlog.Printf(verbose, "🔍 Found git root at: %s\n", dir)
 			return dir
 		}
 
@@ -190,25 +232,28 @@ func findGitRoot(filePath string, verbose bool) string {
 		dir = parent
 	}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "🔍 No git root found for file: %s\n", filePath)
-	}
+	vlog.Printf(verbose, "🔍 No git root found for file: %s\n", filePath)
 	return ""
 }
 
-func handleSessionStart(verbose bool) {
-	// Read SessionStart input from stdin
+func handleSessionStart(inputPath string, verbose bool) {
 	var input SessionStartInput
-	decoder := json.NewDecoder(os.Stdin)
-	if err := decoder.Decode(&input); err != nil {
-		if verbose {
-			fmt.Fprintf(os.Stderr, "Failed to parse SessionStart input: %v\n", err)
+	if err := readInput(inputPath, &input); err != nil {
+		if inputPath != "" && inputPath != "-" {
+			fmt.Fprintf(os.Stderr, "❌ Could not read -input %s: %v\n", inputPath, err)
+			os.Exit(1)
 		}
+		vlog.Printf(verbose, "Failed to parse SessionStart input: %v\n", err)
 		os.Exit(0)
 	}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "SessionStart hook triggered (source: %s)\n", input.Source)
+	vlog.Printf(verbose, "SessionStart hook triggered (source: %s)\n", input.Source)
+
+	// Sessions are a natural point to drop state left behind by ones that ended
+	if result, err := state.AutoClean(); err != nil {
+		vlog.Printf(verbose, "⚠️  Could not clean up runtime state: %v\n", err)
+	} else if verbose && len(result.Removed) > 0 {
+		fmt.Fprintf(os.Stderr, "🧹 Removed %d stale state entries (%s)\n", len(result.Removed), formatBytes(result.Freed))
 	}
 
 	// Determine the working directory
@@ -225,17 +270,44 @@ func handleSessionStart(verbose bool) {
 		var err error
 		workingDir, err = os.Getwd()
 		if err != nil {
-			if verbose {
-				fmt.Fprintf(os.Stderr, "Could not determine working directory: %v\n", err)
-			}
+			vlog.Printf(verbose, "Could not determine working directory: %v\n", err)
 			os.Exit(0)
 		}
 	}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Looking for agents.md in: %s\n", workingDir)
+	// Hooks that stopped firing fail silently, so this is where anyone hears of it
+	previous, err := health.Record("session-start", version.Current())
+	if err != nil {
+		vlog.Printf(verbose, "⚠️  Could not record heartbeat: %v\n", err)
+	}
+	binary, _ := os.Executable()
+	if problems := healthProblems(previous, binary, workingDir); len(problems) > 0 {
+		message := "⚠️  claude-hooks may be broken: " + strings.Join(problems, " ") + " Run `claude-hook doctor` for details."
+		fmt.Fprintln(os.Stderr, message)
+		fmt.Println(message) // In Claude's context, to pass on to the user
+	}
+
+	// What's checked, so Claude can conform up front and a missing check
+	// shows at a glance
+	cfg, err := config.Load(workingDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, using defaults: %v\n", err)
+		cfg = config.Default()
+	}
+	fmt.Println(hooks.Capabilities(cfg, state.ProjectRoot(workingDir), config.ResolveOffline(cfg)))
+
+	// Sessions sharing a checkout edit the same files; the others hear of
+	// this one at their next edit
+	peers, err := hooks.NewPeers(hooks.Session{Dir: workingDir, ID: input.SessionID, TranscriptPath: input.TranscriptPath})
+	if err != nil {
+		vlog.Printf(verbose, "⚠️  Could not check for other sessions: %v\n", err)
+	}
+	if len(peers) > 0 {
+		fmt.Println(hooks.PeersMessage(peers))
 	}
 
+	vlog.Printf(verbose, "Looking for agents.md in: %s\n", workingDir)
+
 	// Look for agents.md in the working directory
 	agentsPath := filepath.Join(workingDir, "agents.md")
 	content, err := os.ReadFile(agentsPath)
@@ -250,43 +322,114 @@ func handleSessionStart(verbose bool) {
 		os.Exit(0)
 	}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Injecting agents.md content (%d bytes)\n", len(content))
-	}
+	vlog.Printf(verbose, "Injecting agents.md content (%d bytes)\n", len(content))
 
 	// Output the content to stdout (this gets injected into Claude's context)
 	fmt.Println(string(content))
 	os.Exit(0)
 }
 
+// readInput decodes the hook's JSON input into v from the file at path, or
+// from stdin when path is empty or "-"
+func readInput(path string, v any) error {
+	r := io.Reader(os.Stdin)
+	if path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("reading hook input: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("parsing hook input: %w", err)
+	}
+	return nil
+}
+
+// invocationStart is used to measure how long each hook invocation takes
+var invocationStart = time.Now()
+
 func main() {
+	// Tools installed from a bundle are found before the rest of PATH
+	tools.AddToPath()
+
+	// Subcommands like `claude-hook stats` bypass the hook flags entirely
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+	// The checkout shim passes its first argument as -type
+	if len(os.Args) > 2 && os.Args[1] == "-type" {
+		if run, ok := subcommands[os.Args[2]]; ok {
+			os.Exit(run(os.Args[3:]))
+		}
+	}
+
+	if len(os.Args) > 1 && slices.Contains(hookTypes, os.Args[1]) {
+		os.Args = append([]string{os.Args[0], "-type", os.Args[1]}, os.Args[2:]...)
+	}
+
 	// Parse command-line flags
 	var (
-		hookType = flag.String("type", "post-edit", "Hook type (post-edit, pre-edit, pre-bash, session-start)")
+		hookType = flag.String("type", "post-edit", "Hook type (post-edit, pre-edit, pre-bash, plan-review, session-start, stop, user-prompt)")
 		verbose  = flag.Bool("v", false, "Verbose output")
+		// Payloads saved from a session can be replayed, and big ones needn't be piped
+		inputPath = flag.String("input", "", "Read the hook's JSON input from this file instead of stdin (- for stdin)")
+
+		minSeverity = flag.String("min-severity", "", "Hide plan review issues below this severity (critical, high, medium, low)")
+
+		diagnosticsFormat = flag.String("diagnostics", "", "Also write post-edit diagnostics for editors (rdjsonl or lsp)")
+		diagnosticsFile   = flag.String("diagnostics-file", "", "Diagnostics output path (default: well-known file in the state directory)")
 	)
+	setFlagsFromEnv(flag.CommandLine, "")
 	flag.Parse()
+	// Until the project config is found, for input too broken to find it from
+	active.enforcement, _ = config.ResolveEnforcement(nil)
+
+	// When Claude aborts the tool call, children are killed and the hook exits at
+	// its next checkpoint; this is the backstop if it never reaches one
+	proc.HandleInterrupts(interruptTimeout, func() {
+		exitIfInterrupted(audit.Event{Hook: *hookType}, *verbose)
+	})
 
 	// Handle session-start hook separately (different input format)
 	if *hookType == "session-start" {
-		handleSessionStart(*verbose)
+		handleSessionStart(*inputPath, *verbose)
 		return
 	}
 
-	// Read input from stdin (Claude Code sends JSON via stdin)
+	// Claude Code sends JSON via stdin
 	var input Input
-	decoder := json.NewDecoder(os.Stdin)
-	if err := decoder.Decode(&input); err != nil {
+	if err := readInput(*inputPath, &input); err != nil {
+		// A file asked for by name must be there: running without it would
+		// pass silently
+		if *inputPath != "" && *inputPath != "-" {
+			fmt.Fprintf(os.Stderr, "❌ Could not read -input %s: %v\n", *inputPath, err)
+			os.Exit(1)
+		}
 		// If no JSON input, check if file paths were passed as arguments
 		if flag.NArg() > 0 {
 			input.ToolInput.FilePaths = flag.Args()
 		} else {
+			resolveProfile(input, *verbose)
+			if active.profile == profile.Unattended {
+				failClosed(*hookType, err, *verbose)
+			}
 			if *verbose {
 				log.Printf("No input provided or failed to parse JSON: %v\n", err)
 			}
 			os.Exit(0)
 		}
 	}
+	resolveProfile(input, *verbose)
+	if err := vlog.Open(active.project, active.session, active.transcript, *hookType); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not open the session log: %v\n", err)
+	}
+	if _, err := health.Record(*hookType, version.Current()); err != nil {
+		vlog.Printf(*verbose, "⚠️  Could not record heartbeat: %v\n", err)
+	}
 
 	// Handle pre-bash blocking for MySQL commands
 	if *hookType == "pre-bash" {
@@ -296,293 +439,3731 @@ func main() {
 
 	// Handle plan review for ExitPlanMode
 	if *hookType == "plan-review" {
-		handlePlanReview(input, *verbose)
+		handlePlanReview(input, *minSeverity, *verbose)
+		return
+	}
+
+	if *hookType == "stop" {
+		handleStop(input, *verbose)
+		return
+	}
+
+	if *hookType == "user-prompt" {
+		handleUserPrompt(*verbose)
 		return
 	}
 
 	// Collect all files to process
 	files := collectFiles(input.ToolInput)
-	if len(files) == 0 {
+
+	// Files outside the session's repository are denied, or checked where
+	// they are, as paths.outside says
+	if *hookType == "pre-edit" && !active.agent.CanEdit() {
+		denyAgent("pre-edit", "agent:edit", fmt.Sprintf("%s can't edit files here (agents config). Report what should change instead, and leave the edit to the main agent.", agentName()), *verbose)
+	}
+	outside, outsideMode := outsidePaths(files, *verbose)
+	if len(outside) > 0 && outsideMode == config.OutsideBlock && *hookType == "pre-edit" {
+		denyOutsidePaths(outside, *verbose)
+	}
+
+	// CODEOWNERS: other teams' files warn, restricted ones are denied
+	var othersFiles, restricted []hooks.OwnedFile
+	if *hookType == "pre-edit" || *hookType == "post-edit" {
+		var err error
+		if othersFiles, restricted, err = hooks.CheckOwners(files); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Ownership check failed: %v\n", err)
+		}
+	}
+	if len(restricted) > 0 && *hookType == "pre-edit" {
+		denyRestricted(restricted, *verbose)
+	}
+
+	// Dangerous code is cheaper to stop before it's on disk
+	var contentWarnings []string
+	if *hookType == "pre-edit" {
+		contentWarnings = checkEditContent(input, *verbose)
+		if len(outside) > 0 && outsideMode == config.OutsideWarn {
+			contentWarnings = append(contentWarnings, outsideMessage(outside))
+		}
+		peers, err := hooks.NewPeers(hooks.Session{Dir: active.project, ID: active.session, TranscriptPath: active.transcript})
+		if err != nil {
+			vlog.Printf(*verbose, "⚠️  Could not check for other sessions: %v\n", err)
+		}
+		if len(peers) > 0 {
+			contentWarnings = append(contentWarnings, hooks.PeersMessage(peers))
+		}
+		if len(othersFiles) > 0 {
+			contentWarnings = append(contentWarnings, hooks.OwnersMessage(othersFiles))
+		}
+	}
+
+	// Moved files need their new location validated and their old importers re-checked,
+	// and deleted files need their packages re-verified even though nothing is left to edit
+	var moves []hooks.FileMove
+	var deleted []string
+	if *hookType == "post-edit" {
+		moves = collectMoves(input, files, *verbose)
+		deleted = collectDeletions(input, moves, *verbose)
+		files = slices.DeleteFunc(files, func(f string) bool { return slices.Contains(deleted, f) })
+		for _, m := range moves {
+			if !slices.Contains(files, m.To) {
+				files = append(files, m.To)
+			}
+		}
+		files = filterFiles(files)
+	}
+
+	if len(files) == 0 && len(deleted) == 0 {
 		if *verbose {
 			log.Println("No files to process")
 		}
 		os.Exit(0)
 	}
 
-	// Process files based on their type
-	filesByType := groupFilesByType(files)
+	if *hookType != "post-edit" && *hookType != "pre-edit" {
+		fmt.Fprintf(os.Stderr, "Unknown hook type: %s\n", *hookType)
+		os.Exit(2)
+	}
+
+	// Comment and whitespace edits can't change any check's verdict
+	if *hookType == "post-edit" && len(moves) == 0 && len(deleted) == 0 && trivialEdit(input, files) {
+		recordEdits(input, files, nil, *verbose)
+		recordAudit(audit.Event{Hook: "post-edit", Decision: "allow", Rule: "trivial-edit"}, *verbose)
+		fmt.Println("✅ Trivial change, checks skipped")
+		os.Exit(0)
+	}
 
-	hasErrors := false
-	var errorMessages []string
+	result := runPipeline(*hookType, files, moves, deleted, *verbose)
+	exitIfInterrupted(result.auditEvent(*hookType), *verbose)
 
-	for fileType, fileList := range filesByType {
-		if *verbose {
-			fmt.Printf("Processing %d %s files...\n", len(fileList), fileType)
+	if *hookType == "post-edit" && len(outside) > 0 {
+		switch outsideMode {
+		case config.OutsideBlock:
+			result.errorMessages = append(result.errorMessages, outsideMessage(outside)+" Undo the change, and make it in this repository instead, or ask the user to.")
+			result.failedRules = append(result.failedRules, "outside-repo")
+		case config.OutsideWarn:
+			result.warnings = append(result.warnings, outsideMessage(outside))
 		}
+	}
 
-		hook := hooks.GetHook(fileType)
-		if hook == nil {
-			if *verbose {
-				fmt.Printf("No hook registered for %s files\n", fileType)
-			}
-			continue
+	if *hookType == "post-edit" {
+		if len(restricted) > 0 {
+			result.errorMessages = append(result.errorMessages, hooks.RestrictedMessage(restricted)+" Undo the change.")
+			result.failedRules = append(result.failedRules, "codeowners-restricted")
 		}
+		pingOwners(othersFiles, *verbose)
+	}
 
-		// Run the hook based on type
-		var err error
-		switch *hookType {
-		case "post-edit":
-			err = hook.PostEditJSON(fileList, *verbose)
-		case "pre-edit":
-			err = hook.PreEdit(fileList, *verbose)
-		default:
-			fmt.Fprintf(os.Stderr, "Unknown hook type: %s\n", *hookType)
-			os.Exit(2)
+	if *hookType == "post-edit" {
+		if over := recordEdits(input, files, deleted, *verbose); len(over) > 0 {
+			result.errorMessages = append(result.errorMessages, budgetExceededMessage(input.SessionID, over))
+			result.failedRules = append(result.failedRules, "session-budget")
 		}
-
-		if err != nil {
-			errorMsg := fmt.Sprintf("%s hook failed: %v", fileType, err)
-			fmt.Fprintf(os.Stderr, "❌ %s\n", errorMsg)
-			errorMessages = append(errorMessages, errorMsg)
-			hasErrors = true
+		if len(result.errorMessages) > 0 {
+			result.loopGuidance = checkLoops(input, files, result, *verbose)
 		}
 	}
 
-	if hasErrors {
+	if *diagnosticsFormat != "" && *hookType == "post-edit" {
+		writeEditorDiagnostics(*diagnosticsFormat, *diagnosticsFile, files, result.diagnostics, *verbose)
+	}
+
+	if *hookType == "post-edit" {
+		ev := result.auditEvent("post-edit")
+		ev.Files = len(files)
+		recordAudit(ev, *verbose)
+		recordOutstanding(append(slices.Clone(files), deleted...), result.failedRules, *verbose)
+	}
+
+	if len(result.errorMessages) > 0 {
 		// For PostToolUse hooks, output JSON to communicate with Claude
 		if *hookType == "post-edit" {
-			output := HookOutput{
-				Decision: "block",
-				Reason:   strings.Join(errorMessages, "\n\n"),
+			output := HookOutput{Decision: "block", Reason: blockReason(result.loopGuidance, result.reasonSections())}
+			// Past the session budget the human has to come back, so Claude
+			// stops instead of working on the block
+			if slices.Contains(result.failedRules, "session-budget") {
+				output.Continue = new(bool)
+				output.StopReason = "⛔ claude-hooks: session budget exceeded; raise the limits or run `claude-hook budget -reset " + input.SessionID + "` to continue."
+			}
+			if !relaxed("PostToolUse", output.Reason) {
+				writeHookOutput(output)
+			}
+			os.Exit(0) // Exit with 0 when using JSON output
+		} else {
+			if relaxed("PreToolUse", blockReason("", result.reasonSections())) {
+				os.Exit(0)
 			}
+			os.Exit(2) // Use exit code 2 for non-PostToolUse hooks
+		}
+	}
 
-			jsonOutput, err := json.Marshal(output)
+	// Warnings and notes reach Claude as context; the edit itself passed
+	if (len(result.warnings) > 0 || len(result.timeouts) > 0 || len(result.notes) > 0) && *hookType == "post-edit" {
+		writeHookOutput(HookOutput{
+			SuppressOutput: len(result.warnings) == 0 && len(result.timeouts) == 0,
+			HookSpecificOutput: &PostToolUseHookOutput{
+				HookEventName:     "PostToolUse",
+				AdditionalContext: blockReason("", result.reasonSections()),
+			},
+		})
+		os.Exit(0)
+	}
+
+	// Conventions Claude would otherwise be corrected on after the edit are
+	// given before it, once per session, along with the content policy's
+	// warnings
+	if *hookType == "pre-edit" {
+		conventions, err := hooks.NewConventions(files, hooks.Session{Dir: active.project, ID: active.session, TranscriptPath: active.transcript})
+		if err != nil {
+			vlog.Printf(*verbose, "⚠️  Could not remember the conventions given: %v\n", err)
+		}
+		var context []string
+		if len(contentWarnings) > 0 {
+			context = append(context, strings.Join(contentWarnings, "\n"))
+		}
+		if len(conventions) > 0 {
+			context = append(context, "Project conventions for this edit:\n- "+strings.Join(conventions, "\n- "))
+		}
+		if len(context) > 0 {
+			jsonOutput, err := json.Marshal(PreToolUseOutput{HookSpecificOutput: PreToolUseHookOutput{
+				HookEventName:     "PreToolUse",
+				AdditionalContext: strings.Join(context, "\n\n"),
+			}})
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to marshal JSON output: %v\n", err)
-				os.Exit(2)
+				os.Exit(1)
 			}
-
 			fmt.Println(string(jsonOutput))
-			os.Exit(0) // Exit with 0 when using JSON output
-		} else {
-			os.Exit(2) // Use exit code 2 for non-PostToolUse hooks
+			os.Exit(0)
 		}
 	}
 
 	fmt.Println("✅ All checks passed!")
 }
 
-func collectFiles(input ToolInput) []string {
-	seen := make(map[string]bool)
-	var files []string
+// subcommands are invoked as `claude-hook <name> [flags]` rather than as hooks.
+// Each returns the process exit code.
+var subcommands = map[string]func(args []string) int{
+	"stats":       runStats,
+	"report":      runReport,
+	"version":     runVersion,
+	"update":      runUpdate,
+	"telemetry":   runTelemetry,
+	"clean":       runClean,
+	"watch":       runWatch,
+	"lsp":         runLSP,
+	"ci":          runCI,
+	"budget":      runBudget,
+	"reason":      runReason,
+	"explain":     runExplain,
+	"doctor":      runDoctor,
+	"rollback":    runRollback,
+	"apply-fixes": runApplyFixes,
+	"bench":       runBench,
+	"profile":     runProfile,
+	"policy":      runPolicy,
+	"init":        runInit,
+	"config":      runConfig,
+	"tools":       runTools,
+	// sync-claude-md keeps the CLAUDE.md Claude reads in line with the hooks
+	"sync-claude-md": runSyncClaudeMD,
+}
 
-	// Add single file if present
-	if input.FilePath != "" {
-		if !seen[input.FilePath] {
-			seen[input.FilePath] = true
-			files = append(files, input.FilePath)
-		}
-	}
+// sharedFlagEnv is the environment variable of flags meaning the same in
+// every command, which sets them wherever they appear
+var sharedFlagEnv = map[string]string{
+	"v":       "CLAUDE_HOOKS_VERBOSE",
+	"dry-run": config.DryRunEnv,
+	"n":       config.DryRunEnv,
+}
 
-	// Add multiple files
-	for _, f := range input.FilePaths {
-		if !seen[f] {
-			seen[f] = true
-			files = append(files, f)
-		}
+// flagEnv returns the environment variable that sets flag name of command:
+// CLAUDE_HOOKS_<FLAG> for the hooks (command "") and
+// CLAUDE_HOOKS_<COMMAND>_<FLAG> for subcommands, e.g. CLAUDE_HOOKS_CLEAN_MAX_AGE
+func flagEnv(command, name string) string {
+	if env, ok := sharedFlagEnv[name]; ok {
+		return env
+	}
+	if command != "" {
+		name = command + "_" + name
 	}
+	return "CLAUDE_HOOKS_" + strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(name))
+}
 
-	// Filter out vendor and generated files
-	var filtered []string
-	for _, f := range files {
-		if strings.Contains(f, "/vendor/") ||
-			strings.HasSuffix(f, ".pb.go") ||
-			strings.HasSuffix(f, ".gen.go") {
-			continue
+// setFlagsFromEnv sets the flags of fs from their environment variables, so
+// they can change per shell or session where editing the command in
+// settings.json means re-running setup. The command line is parsed after, so
+// its flags still win.
+func setFlagsFromEnv(fs *flag.FlagSet, command string) {
+	fs.VisitAll(func(f *flag.Flag) {
+		if command == "" && f.Name == "type" {
+			return // Each hook's command in settings.json names its own type
 		}
-		filtered = append(filtered, f)
-	}
+		env := flagEnv(command, f.Name)
+		value := os.Getenv(env)
+		if value == "" {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Ignoring $%s: %v\n", env, err)
+		}
+	})
+}
 
-	return filtered
+// parseFlags parses a subcommand's arguments over the flags its environment sets
+func parseFlags(fs *flag.FlagSet, args []string) {
+	setFlagsFromEnv(fs, fs.Name())
+	_ = fs.Parse(args)
 }
 
-func groupFilesByType(files []string) map[string][]string {
-	groups := make(map[string][]string)
+// hookTypes may be given positionally (`claude-hook post-edit`), which is how the
+// generated settings.json commands invoke a released binary
+var hookTypes = []string{"post-edit", "pre-edit", "pre-bash", "plan-review", "session-start", "stop", "user-prompt"}
 
-	for _, f := range files {
-		ext := strings.ToLower(filepath.Ext(f))
-		var fileType string
-
-		switch ext {
-		case ".go":
-			fileType = "go"
-		case ".ts", ".tsx":
-			fileType = "typescript"
-		case ".js", ".jsx":
-			fileType = "javascript"
-		case ".py":
-			fileType = "python"
-		default:
-			continue // Skip unknown types
-		}
+// runVersion implements `claude-hook version`
+func runVersion(args []string) int {
+	fmt.Println(version.String())
+	return 0
+}
+
+// runUpdate implements `claude-hook update`: it installs the latest release
+// binary where settings.json commands look for it, so no checkout or Go
+// toolchain is needed to run hooks
+func runUpdate(args []string) int {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	check := fs.Bool("check", false, "Only report whether a newer release is available")
+	force := fs.Bool("force", false, "Reinstall even if already up to date")
+	target := fs.String("target", "", "Install the binary here and point settings at it (default: ~/.claude/bin/claude-hook)")
+	insecure := fs.Bool("insecure", false, "Install even if the release is unsigned or has no checksum (a wrong checksum or signature still fails)")
+	parseFlags(fs, args)
 
-		groups[fileType] = append(groups[fileType], f)
+	cfg, _ := config.Load(".")
+	if config.ResolveOffline(cfg) {
+		fmt.Fprintf(os.Stderr, "❌ Offline, not checking for updates\n")
+		return 1
 	}
+	network.UseCABundle(config.ResolveCABundle(cfg))
 
-	return groups
-}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
 
-func handlePreBashBlocking(input Input, verbose bool) {
-	// Check if this is a Bash tool call
-	if input.ToolName != "Bash" && input.ToolName != "bash" {
-		if verbose {
-			fmt.Printf("Tool %s is not Bash, allowing\n", input.ToolName)
-		}
-		os.Exit(0)
+	release, err := update.Latest(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
 	}
 
-	command := input.ToolInput.Command
-	if command == "" {
-		if verbose {
-			fmt.Println("No command found in input, allowing")
-		}
-		os.Exit(0)
+	current := version.Current()
+	if !*force && !update.IsNewer(current, release.TagName) {
+		fmt.Printf("✅ claude-hook %s is up to date\n", current)
+		return 0
+	}
+	if *check {
+		fmt.Printf("⬆️  claude-hook %s is available (running %s)\n", release.TagName, current)
+		return 0
 	}
 
-	// Check for MySQL/MariaDB commands in compound commands
-	// Split by common shell operators to check all sub-commands
-	subCommands := parseCompoundCommand(command)
+	assetName := update.AssetName(runtime.GOOS, runtime.GOARCH)
+	asset, ok := release.Asset(assetName)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "❌ Release %s has no %s binary\n", release.TagName, assetName)
+		return 1
+	}
 
-	for _, subCmd := range subCommands {
-		parts := strings.Fields(strings.TrimSpace(subCmd))
-		if len(parts) > 0 {
-			executable := strings.ToLower(filepath.Base(parts[0]))
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error getting home directory: %v\n", err)
+		return 1
+	}
+	shimPath := settings.ShimPath(homeDir)
+	path := *target
+	if path == "" {
+		path = shimPath
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
 
-			// Check if the executable itself is a MySQL/MariaDB command
-			if executable == "mysql" || executable == "mysqldump" || executable == "mariadb" {
-				reason := fmt.Sprintf("MySQL commands are not allowed. You attempted to run: %s\n\nDetected MySQL command in: %s\n\nPlease use the Go database connection methods instead. The codebase already has database access configured through Go.\n\nAlternatives:\n- Check existing Go code for database queries\n- Look at the model definitions in the codebase\n- Read the existing test files for schema information", command, subCmd)
+	fmt.Printf("⬇️  Downloading %s %s...\n", assetName, release.TagName)
+	data, err := update.Download(ctx, asset.URL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if err := update.Verify(ctx, release, assetName, data, *insecure); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Refusing to install %s: %v\n", assetName, err)
+		if errors.Is(err, update.ErrUnverified) {
+			fmt.Fprintf(os.Stderr, "   Re-run with -insecure to install an unverified binary anyway\n")
+		}
+		return 1
+	}
+	if !*insecure {
+		fmt.Println("🔒 Verified checksum and signature")
+	}
+	if err := update.Install(data, path); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
 
-				output := PreToolUseOutput{
-					HookSpecificOutput: PreToolUseHookOutput{
-						HookEventName:            "PreToolUse",
-						PermissionDecision:       "deny", // Automatically block without prompting
-						PermissionDecisionReason: reason,
-					},
-				}
+	// Point settings at the binary: legacy checkout commands are rewritten to the
+	// portable form, and a non-default location is exported via CLAUDE_HOOKS_BIN
+	settingsPath := filepath.Join(homeDir, ".claude", "settings.json")
+	_, err = settings.Update(settingsPath, func(s *settings.Settings) error {
+		if n := s.RepointLegacyHooks(); n > 0 {
+			fmt.Printf("🔧 Re-pointed %d hook command(s) at the installed binary\n", n)
+		}
+		if n := s.InstallMissingHooks(); n > 0 {
+			fmt.Printf("🔧 Configured %d default hook(s)\n", n)
+		}
+		if path != shimPath {
+			s.SetEnv(settings.BinEnv, path)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error updating settings: %v\n", err)
+		return 1
+	}
 
-				jsonOutput, err := json.Marshal(output)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to marshal JSON output: %v\n", err)
-					os.Exit(1)
-				}
+	fmt.Printf("✅ Installed claude-hook %s at %s\n", release.TagName, path)
+	return 0
+}
 
-				// Output JSON to stdout for Claude
-				fmt.Println(string(jsonOutput))
+// runWatch implements `claude-hook watch`: the post-edit pipeline runs whenever
+// files under the directory change on disk, so edits from any editor get the
+// same checks (and the same .claude-hooks.yaml) as Claude's
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory to watch")
+	interval := fs.Duration("interval", 500*time.Millisecond, "How often to scan for changes")
+	diagnosticsFormat := fs.String("diagnostics", "", "Also write diagnostics for editors after each run (rdjsonl or lsp)")
+	diagnosticsFile := fs.String("diagnostics-file", "", "Diagnostics output path (default: well-known file in the state directory)")
+	verbose := fs.Bool("v", false, "Verbose output")
+	parseFlags(fs, args)
 
-				// Also output user-friendly message to stderr
-				fmt.Fprintf(os.Stderr, "❌ BLOCKED: MySQL commands are not allowed\n")
-				fmt.Fprintf(os.Stderr, "\n")
-				fmt.Fprintf(os.Stderr, "You attempted to run: %s\n", command)
-				fmt.Fprintf(os.Stderr, "Detected MySQL command in: %s\n", subCmd)
-				fmt.Fprintf(os.Stderr, "\n")
-				fmt.Fprintf(os.Stderr, "Please use the Go database connection methods instead.\n")
-				fmt.Fprintf(os.Stderr, "The codebase already has database access configured through Go.\n")
-				fmt.Fprintf(os.Stderr, "\n")
-				fmt.Fprintf(os.Stderr, "Alternatives:\n")
-				fmt.Fprintf(os.Stderr, "- Check existing Go code for database queries\n")
-				fmt.Fprintf(os.Stderr, "- Look at the model definitions in the codebase\n")
-				fmt.Fprintf(os.Stderr, "- Read the existing test files for schema information\n")
+	root, err := filepath.Abs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
 
-				os.Exit(0) // Exit successfully since we provided JSON
-			}
+	proc.HandleInterrupts(interruptTimeout, func() { os.Exit(0) })
+	keepLastGoodConfig(root)
 
-			// Check if the command is a git commit on a protected branch
-			if executable == "git" && len(parts) >= 2 && parts[1] == "commit" {
-				if verbose {
-					fmt.Fprintf(os.Stderr, "🔍 Detected git commit command, checking branch protection...\n")
-				}
+	w := watch.New(root)
+	w.Interval = *interval
+	fmt.Fprintf(os.Stderr, "👀 Watching %s (Ctrl-C to stop)\n", root)
 
-				// Determine the target working directory for git branch check
-				targetDir := getTargetWorkingDirectory(input, verbose)
+	err = w.Run(proc.Context(), func(changes []watch.Change) {
+		var files, deleted []string
+		for _, c := range changes {
+			// Loading it reports the change right away, not at the next run
+			if filepath.Base(c.Path) == config.FileName && !c.Deleted {
+				_, _ = config.Load(filepath.Dir(c.Path))
+			}
+			if c.Deleted {
+				deleted = append(deleted, c.Path)
+			} else {
+				files = append(files, c.Path)
+			}
+		}
+		files = filterFiles(files)
+		if len(hooks.GroupFiles(files)) == 0 && !slices.ContainsFunc(deleted, func(f string) bool { return filepath.Ext(f) == ".go" }) {
+			return
+		}
 
-				// Skip protection if we can't confidently determine the target directory
-				if targetDir == "" {
-					if verbose {
-						fmt.Fprintf(os.Stderr, "✅ Skipping branch protection check - cannot determine target project directory\n")
-					}
-					// Allow the command to proceed by continuing to next subcommand
-					continue
-				}
+		start := time.Now()
+		fmt.Fprintf(os.Stderr, "\n🔍 %d file(s) changed, %d deleted\n", len(files), len(deleted))
+		result := runPipeline("post-edit", files, nil, deleted, *verbose)
 
-				currentBranch := getCurrentBranch(targetDir, verbose)
+		if *diagnosticsFormat != "" {
+			writeEditorDiagnostics(*diagnosticsFormat, *diagnosticsFile, files, result.diagnostics, *verbose)
+		}
+		ev := result.auditEvent("watch")
+		ev.DurationMS = time.Since(start).Milliseconds()
+		recordAudit(ev, *verbose)
 
-				if verbose {
-					fmt.Fprintf(os.Stderr, "🔍 Checking if branch %q is protected...\n", currentBranch)
-				}
-				if currentBranch != "" && isProtectedBranch(currentBranch) {
-					if verbose {
-						fmt.Fprintf(os.Stderr, "🚫 Branch %q is protected - blocking commit\n", currentBranch)
-					}
-					reason := fmt.Sprintf("Direct commits to the '%s' branch are not allowed. You attempted to run: %s\n\nDetected git commit command in: %s\n\nPlease create a feature branch instead:\n\n1. Create and switch to a new branch:\n   git checkout -b feature/your-feature-name\n\n2. Make your commits on the feature branch:\n   git commit -m \"your commit message\"\n\n3. Push the feature branch:\n   git push -u origin feature/your-feature-name\n\n4. Create a pull request to merge into %s", currentBranch, command, subCmd, currentBranch)
-
-					output := PreToolUseOutput{
-						HookSpecificOutput: PreToolUseHookOutput{
-							HookEventName:            "PreToolUse",
-							PermissionDecision:       "deny", // Automatically block without prompting
-							PermissionDecisionReason: reason,
-						},
+		if len(result.errorMessages) == 0 {
+			fmt.Fprintln(os.Stderr, "✅ All checks passed!")
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// keepLastGoodConfig makes a long-running command keep checking with the last
+// config that loaded while a changed one doesn't, starting from the one for
+// dir, and say on stderr when a change is picked up or rejected
+func keepLastGoodConfig(dir string) {
+	config.KeepLastGood(func(r config.Reload) {
+		if r.Err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s no longer loads, still using its last good version: %v\n", r.Path, r.Err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "🔄 Reloaded %s\n", r.Path)
+	})
+	if _, err := config.Load(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+}
+
+// runLSP implements `claude-hook lsp`, a language server on stdio that publishes
+// the post-edit pipeline's findings whenever a file is opened or saved, so editor
+// users see what the hooks will flag before Claude ever runs
+func runLSP(args []string) int {
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	verbose := fs.Bool("v", false, "Log pipeline progress to stderr")
+	parseFlags(fs, args)
+
+	proc.HandleInterrupts(interruptTimeout, func() { os.Exit(0) })
+	if cwd, err := os.Getwd(); err == nil {
+		keepLastGoodConfig(cwd)
+	}
+
+	server := lsp.NewServer(os.Stdin, os.Stdout, func(files []string) []hooks.Diagnostic {
+		files = filterFiles(files)
+		if len(files) == 0 {
+			return nil
+		}
+		return runPipeline("post-edit", files, nil, nil, *verbose).diagnostics
+	})
+	if err := server.Serve(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runCI implements `claude-hook ci`: the post-edit pipeline runs over the files a
+// pull request changed and the push checks over its commits, so the rules that
+// gate Claude sessions also gate PRs. Findings are reported in the CI service's
+// native format and the exit code is 1 when any of them blocks.
+func runCI(args []string) int {
+	fs := flag.NewFlagSet("ci", flag.ExitOnError)
+	base := fs.String("base", ci.DefaultBase(), "Ref the branch is compared against (its merge base with HEAD)")
+	dir := fs.String("dir", ".", "Directory inside the repository")
+	format := fs.String("format", ci.DetectFormat(), "Report format: "+strings.Join(ci.Formats, ", "))
+	output := fs.String("output", "", "Report file (default: stdout, gl-code-quality-report.json for gitlab)")
+	verbose := fs.Bool("v", false, "Verbose output")
+	parseFlags(fs, args)
+
+	if !slices.Contains(ci.Formats, *format) {
+		fmt.Fprintf(os.Stderr, "❌ Unknown format %q (want %s)\n", *format, strings.Join(ci.Formats, ", "))
+		return 2
+	}
+
+	root, err := ci.RepoRoot(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	changes, err := ci.ChangedFiles(root, *base)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	cfg, err := config.Load(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	abs := func(paths []string) []string {
+		var out []string
+		for _, p := range paths {
+			out = append(out, filepath.Join(root, p))
+		}
+		return out
+	}
+	files := filterFiles(abs(changes.Changed))
+	fmt.Fprintf(os.Stderr, "🔍 Checking %d changed and %d deleted file(s) since %s\n", len(files), len(changes.Deleted), changes.MergeBase[:min(len(changes.MergeBase), 8)])
+
+	var findings []ci.Finding
+	result := runPipeline("post-edit", files, nil, abs(changes.Deleted), *verbose)
+	for _, d := range result.diagnostics {
+		file := d.File
+		if rel, err := filepath.Rel(root, file); err == nil && filepath.IsAbs(file) {
+			file = rel
+		}
+		findings = append(findings, ci.Finding{File: filepath.ToSlash(file), Line: d.Line, Column: d.Column, Severity: d.Severity, Rule: d.Source, Message: d.Message})
+	}
+	for _, msg := range result.unlocated {
+		findings = append(findings, ci.Finding{Severity: "error", Rule: "post-edit", Message: msg})
+	}
+
+	if cfg.Push.Enabled {
+		pushFindings, err := hooks.ScanCommitRange(root, changes.MergeBase+"..HEAD", cfg.Push)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		for _, f := range pushFindings {
+			findings = append(findings, ci.Finding{File: f.File, Line: f.Line, Severity: "error", Rule: "push/" + f.Kind,
+				Message: fmt.Sprintf("%s in commit %s (%s)", f.Kind, f.Commit, f.Detail)})
+		}
+	}
+
+	if err := writeCIReport(*format, *output, findings, len(files)); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	if ci.Blocking(findings) {
+		fmt.Fprintf(os.Stderr, "❌ %d finding(s), failing the build\n", len(findings))
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "✅ All checks passed!")
+	return 0
+}
+
+// writeCIReport reports findings in format. GitHub reads annotations from stdout
+// and the summary from $GITHUB_STEP_SUMMARY, GitLab reads a report artifact,
+// and in Bitbucket Pipelines the report is published to Code Insights directly.
+func writeCIReport(format, output string, findings []ci.Finding, checked int) error {
+	write := func(fn func(io.Writer) error) error {
+		if output == "" {
+			return fn(os.Stdout)
+		}
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("creating report: %w", err)
+		}
+		if err := fn(file); err != nil {
+			_ = file.Close()
+			return err
+		}
+		return file.Close()
+	}
+
+	switch format {
+	case ci.FormatGitLab:
+		if output == "" {
+			output = "gl-code-quality-report.json"
+		}
+		if err := write(func(w io.Writer) error { return ci.WriteGitLabCodeQuality(w, findings) }); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "📊 Code Quality report written to %s\n", output)
+
+	case ci.FormatBitbucket:
+		repo, commit := os.Getenv("BITBUCKET_REPO_FULL_NAME"), os.Getenv("BITBUCKET_COMMIT")
+		if repo == "" || commit == "" || output != "" {
+			return write(func(w io.Writer) error { return ci.WriteBitbucketReport(w, findings, checked) })
+		}
+		transport, err := network.Transport()
+		if err != nil {
+			return err
+		}
+		// Pipelines authenticate the API through their own proxy
+		proxy, _ := url.Parse(ci.BitbucketProxy)
+		transport.Proxy = http.ProxyURL(proxy)
+		client := &http.Client{Transport: transport}
+		report := ci.NewBitbucketReport(findings, checked)
+		// A report that can't be published shouldn't change the build's verdict
+		if err := ci.PublishBitbucket(proc.Context(), client, ci.BitbucketAPI, repo, commit, report); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "📊 Code Insights report published for %s\n", commit)
+		}
+
+	default:
+		if err := write(func(w io.Writer) error { return ci.WriteGitHubAnnotations(w, findings) }); err != nil {
+			return err
+		}
+		if summaryPath := os.Getenv("GITHUB_STEP_SUMMARY"); summaryPath != "" {
+			if err := appendFile(summaryPath, func(w io.Writer) error { return ci.WriteGitHubSummary(w, findings, checked) }); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Could not write job summary: %v\n", err)
+			}
+		}
+	}
+	return nil
+}
+
+// appendFile opens path for appending and hands it to write
+func appendFile(path string, write func(io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// runReason implements `claude-hook reason`: it prints the full output of a
+// block reason that was cut to fit reasons.max_chars, the latest by default
+func runReason(args []string) int {
+	fs := flag.NewFlagSet("reason", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory inside the project")
+	rule := fs.String("rule", "", "Only print the output of this check")
+	parseFlags(fs, args)
+
+	sections, err := reason.Load(*dir, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	var texts, rules []string
+	for _, s := range sections {
+		if *rule == "" || s.Rule == *rule {
+			texts = append(texts, s.Text)
+		}
+		if !slices.Contains(rules, s.Rule) {
+			rules = append(rules, s.Rule)
+		}
+	}
+	if len(texts) == 0 {
+		fmt.Fprintf(os.Stderr, "❌ No output from %s; the reason has %s\n", *rule, strings.Join(rules, ", "))
+		return 1
+	}
+	fmt.Println(strings.Join(texts, "\n\n"))
+	return 0
+}
+
+// runExplain implements `claude-hook explain <rule-id|diagnostic-id>`: it
+// prints what a rule or a tool's diagnostic means, how to fix it, and its
+// output from the latest reason that had it. It's meant for Claude to run.
+func runExplain(args []string) int {
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory inside the project")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "❌ Usage: claude-hook explain [-dir <dir>] <rule-id|diagnostic-id>\n")
+		return 1
+	}
+
+	explanation, err := reason.Explain(*dir, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Print(explanation)
+	return 0
+}
+
+// runApplyFixes implements `claude-hook apply-fixes`: it applies the fix patch
+// the post-edit hook collects with fixes.patch, or with -n prints it
+func runApplyFixes(args []string) int {
+	fs := flag.NewFlagSet("apply-fixes", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory inside the project")
+	dryRun := fs.Bool("n", false, "Print the patch and what it would change without applying it")
+	parseFlags(fs, args)
+
+	result, err := hooks.ApplyFixPatch(*dir, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if result.Applied == 0 && len(result.Failed) == 0 {
+		fmt.Println("✅ No fixes to apply")
+		return 0
+	}
+
+	verb := "Applied"
+	if *dryRun {
+		verb = "Would apply"
+		if patch, err := os.ReadFile(result.Path); err == nil {
+			fmt.Print(string(patch))
+		}
+	}
+	fmt.Printf("🔧 %s %d fixes to %d files\n", verb, result.Applied, len(result.Files))
+	for _, f := range result.Files {
+		fmt.Printf("  %s\n", f)
+	}
+	for _, f := range result.Failed {
+		fmt.Printf("⚠️  No longer applies: %s\n", f)
+	}
+	if len(result.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runBench implements `claude-hook bench`: it runs the post-edit pipeline on
+// the given files, or the project's changed ones, several times and reports
+// how long each stage took, so users can see what dominates their setup
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory inside the project whose changed files are checked when none are given")
+	runs := fs.Int("runs", 5, "Number of times to run the pipeline")
+	verbose := fs.Bool("v", false, "Verbose output")
+	parseFlags(fs, args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		root, err := ci.RepoRoot(*dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if files, err = hooks.DetectChangedFiles(root); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+	}
+	for i, f := range files {
+		if abs, err := filepath.Abs(f); err == nil {
+			files[i] = abs
+		}
+	}
+	files = filterFiles(files)
+	if len(hooks.GroupFiles(files)) == 0 {
+		fmt.Fprintln(os.Stderr, "❌ No files for any hook; pass the files to check")
+		return 1
+	}
+	if *runs < 1 {
+		fmt.Fprintln(os.Stderr, "❌ -runs must be at least 1")
+		return 1
+	}
+
+	proc.HandleInterrupts(interruptTimeout, func() { os.Exit(1) })
+
+	fmt.Fprintf(os.Stderr, "⏱️  Checking %d file(s) %d times\n", len(files), *runs)
+	var results []bench.Run
+	for i := range *runs {
+		start := time.Now()
+		result := runPipeline("post-edit", files, nil, nil, *verbose)
+		if proc.Interrupted() {
+			return 1
+		}
+		total := time.Since(start)
+		results = append(results, bench.Run{Total: total, Stages: result.timings})
+		fmt.Fprintf(os.Stderr, "   run %d: %s\n", i+1, total.Round(time.Millisecond))
+	}
+
+	if err := bench.WriteTable(os.Stdout, results); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if stages, _ := bench.Summarize(results); len(stages) > 0 {
+		fmt.Printf("\n🐢 %s takes %.0f%% of the time\n", stages[0].Stage, 100*stages[0].Share)
+	}
+	return 0
+}
+
+// runBudget implements `claude-hook budget`: it shows the guardrail counters of
+// the project's sessions, or resets one so a session that hit a ceiling can
+// continue unattended
+func runBudget(args []string) int {
+	fs := flag.NewFlagSet("budget", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory inside the project")
+	reset := fs.String("reset", "", "Clear the counters of this session id")
+	parseFlags(fs, args)
+
+	if *reset != "" {
+		if err := guardrails.Reset(*dir, *reset); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✅ Reset the budget of session %s\n", *reset)
+		return 0
+	}
+
+	cfg, err := config.Load(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	sessions, err := guardrails.List(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if !guardrails.Enabled(cfg.Guardrails.Session) {
+		fmt.Println("No session ceilings configured (guardrails.session in .claude-hooks.yaml)")
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No sessions tracked for this project")
+		return 0
+	}
+
+	ids := make([]string, 0, len(sessions))
+	for id := range sessions {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int { return sessions[b].Updated.Compare(sessions[a].Updated) })
+
+	for _, id := range ids {
+		c := sessions[id]
+		status := "✅"
+		if len(guardrails.Exceeded(cfg.Guardrails.Session, c)) > 0 {
+			status = "⚠️ "
+		}
+		fmt.Printf("%s %s  %d files  %d commands  %d API calls  $%.2f  (updated %s)\n",
+			status, id, len(c.Files), c.Commands, c.APICalls, c.SpendUSD, c.Updated.Local().Format(time.DateTime))
+	}
+	return 0
+}
+
+// runRollback implements `claude-hook rollback [<id>]`: without an id it lists
+// the project's snapshots, with one it restores it
+func runRollback(args []string) int {
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory inside the project")
+	list := fs.Bool("list", false, "List snapshots, newest first")
+	parseFlags(fs, args)
+
+	if *list || fs.NArg() == 0 {
+		bundles, err := rollback.List(*dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if len(bundles) == 0 {
+			fmt.Println("No snapshots for this project")
+			return 0
+		}
+		for _, b := range bundles {
+			fmt.Printf("📦 %s  %s  %d untracked files  before: %s\n", b.ID, b.Created.Local().Format(time.DateTime), len(b.Untracked), b.Command)
+		}
+		return 0
+	}
+
+	id := fs.Arg(0)
+	bundle, current, err := rollback.Restore(*dir, id)
+	if current != nil {
+		fmt.Printf("📦 Saved the state before this rollback as %s\n", current.ID)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		if current != nil {
+			fmt.Fprintf(os.Stderr, "   The working tree may be partially restored; claude-hook rollback %s returns to where it was\n", current.ID)
+		}
+		return 1
+	}
+
+	where := bundle.Head[:min(12, len(bundle.Head))]
+	if bundle.Branch != "" {
+		where = bundle.Branch + " at " + where
+	}
+	fmt.Printf("✅ Restored %s (%s), taken before: %s\n", bundle.ID, where, bundle.Command)
+	fmt.Printf("   Undo with: claude-hook rollback %s\n", current.ID)
+	return 0
+}
+
+// runClean implements `claude-hook clean`, garbage-collecting per-project runtime
+// state. Session start runs the same cleanup with state.DefaultCleanOptions (which
+// the flag defaults mirror) once a day.
+func runClean(args []string) int {
+	fs := flag.NewFlagSet("clean", flag.ExitOnError)
+	maxAge := fs.String("max-age", "30d", "Remove cache and log files older than this")
+	maxSize := fs.String("max-size", "512MB", "Then remove the oldest cache and log files until their total is under this (0 for no limit)")
+	sessionMaxAge := fs.String("session-max-age", "7d", "Remove session state untouched for this long")
+	dryRun := fs.Bool("dry-run", false, "Only list what would be removed")
+	parseFlags(fs, args)
+
+	opts := state.CleanOptions{DryRun: *dryRun}
+	var err error
+	if opts.MaxAge, err = audit.ParseSince(*maxAge); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if opts.SessionMaxAge, err = audit.ParseSince(*sessionMaxAge); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if opts.MaxSize, err = state.ParseSize(*maxSize); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	result, err := state.Clean(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+	for _, path := range result.Removed {
+		fmt.Printf("   %s\n", path)
+	}
+	fmt.Printf("🧹 %s %d entries, freeing %s\n", verb, len(result.Removed), formatBytes(result.Freed))
+	return 0
+}
+
+// runDoctor implements `claude-hook doctor`: it checks that the installed
+// hooks run, and that the binary they run isn't older than the checkout it was
+// built from or the project config
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory inside the project")
+	parseFlags(fs, args)
+
+	binary := os.Getenv(settings.BinEnv)
+	if binary == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		binary = settings.ShimPath(homeDir)
+	}
+	problems := 0
+	_, err := os.Stat(binary)
+	if err != nil {
+		fmt.Printf("❌ Hook binary: %v\n", err)
+		problems++
+		binary = "" // Nothing to compare against the sources
+	} else if checkout := settings.ShimCheckout(binary); checkout != "" {
+		fmt.Printf("✅ Hook binary: %s (shim running %s)\n", binary, checkout)
+	} else {
+		fmt.Printf("✅ Hook binary: %s\n", binary)
+	}
+
+	heartbeat, err := health.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+	stale := heartbeat.Stale()
+	for _, hook := range hookTypes {
+		beat, ok := heartbeat.Hooks[hook]
+		switch {
+		case !ok:
+			fmt.Printf("⏭️  %s: never ran\n", hook)
+		case slices.Contains(stale, hook):
+			fmt.Printf("⚠️  %s: last ran %s ago (%s), though sessions kept starting\n", hook, formatAge(time.Since(beat.Time)), beat.Version)
+			problems++
+		default:
+			fmt.Printf("✅ %s: last ran %s ago (%s)\n", hook, formatAge(time.Since(beat.Time)), beat.Version)
+		}
+	}
+	if start, ok := heartbeat.Hooks["session-start"]; ok && time.Since(start.Time) > health.StaleAfter {
+		fmt.Printf("⚠️  No session has started for %s; check the hooks in ~/.claude/settings.json\n", formatAge(time.Since(start.Time)))
+		problems++
+	}
+
+	for _, problem := range healthProblems(health.Heartbeat{}, binary, *dir) {
+		fmt.Printf("⚠️  %s\n", problem)
+		problems++
+	}
+	problems += checkNetwork(*dir)
+	var missing []string
+	for _, s := range tools.Statuses() {
+		if s.Path == "" {
+			missing = append(missing, s.Name)
+		}
+	}
+	if len(missing) > 0 {
+		// Optional, so not a problem: the checks that need them are skipped
+		fmt.Printf("⏭️  Tools not installed: %s (without internet access, install a bundle with `claude-hook tools install`)\n", strings.Join(missing, ", "))
+	}
+
+	if problems > 0 {
+		return 1
+	}
+	fmt.Println("✅ Hooks look healthy")
+	return 0
+}
+
+// checkNetwork prints whether the release endpoint and the webhook, the
+// outbound calls that don't go through a reviewer CLI, are reachable with the
+// proxy and CA bundle in effect for dir, and returns how many aren't
+func checkNetwork(dir string) int {
+	cfg, _ := config.Load(dir)
+	if config.ResolveOffline(cfg) {
+		fmt.Println("⏭️  Network: offline")
+		return 0
+	}
+	bundle := config.ResolveCABundle(cfg)
+	network.UseCABundle(bundle)
+	if bundle != "" {
+		fmt.Printf("🔐 CA bundle: %s\n", bundle)
+	}
+
+	targets := []string{update.APIURL()}
+	if cfg != nil {
+		if webhook := notify.WebhookURL(cfg.Unattended); webhook != "" {
+			targets = append(targets, webhook)
+		}
+	}
+	problems := 0
+	for _, target := range targets {
+		host := target
+		if u, err := url.Parse(target); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		ctx, cancel := context.WithTimeout(proc.Context(), 10*time.Second)
+		proxy, err := network.Reach(ctx, target)
+		cancel()
+		via := "directly"
+		if proxy != "" {
+			via = "through proxy " + proxy
+		}
+		if err != nil {
+			fmt.Printf("❌ Network: can't reach %s %s: %v\n", host, via, err)
+			problems++
+		} else {
+			fmt.Printf("✅ Network: reached %s %s\n", host, via)
+		}
+	}
+	return problems
+}
+
+// healthProblems describes what's wrong with the installation running hooks
+// from binary in dir: watched hooks that went silent before the last session,
+// and a binary older than the project config or the checkout it's built from.
+// A shim or go run builds from source, so it's never out of date.
+func healthProblems(previous health.Heartbeat, binary, dir string) []string {
+	var problems []string
+	for _, hook := range previous.Stale() {
+		problems = append(problems, fmt.Sprintf("the %s hook hasn't run in over %s of sessions.", hook, formatAge(health.StaleAfter)))
+	}
+	if binary == "" || health.FromSource(binary) || settings.ShimCheckout(binary) != "" {
+		return problems
+	}
+
+	var against []string
+	if cfg, err := config.Load(dir); err == nil && cfg.Path != "" {
+		against = append(against, cfg.Path)
+	}
+	if checkout := health.Checkout(dir); checkout != "" {
+		against = append(against, health.Sources(checkout)...)
+	}
+	newer, err := health.NewerThan(binary, against)
+	if err != nil {
+		return append(problems, err.Error()+".")
+	}
+	if len(newer) > 0 {
+		problems = append(problems, fmt.Sprintf("%s is older than %s; rebuild it or run `claude-hook update`.", binary, strings.Join(newer, ", ")))
+	}
+	return problems
+}
+
+// formatAge renders d in its largest whole unit, e.g. "3d" or "5h"
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	}
+}
+
+// formatBytes renders n with a binary unit, e.g. "1.5 MiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// runStats implements `claude-hook stats`, summarizing the audit log
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	since := fs.String("since", "30d", "Only include events newer than this (e.g. 7d, 12h)")
+	htmlPath := fs.String("html", "", "Also write an HTML report to this path")
+	parseFlags(fs, args)
+
+	window, err := audit.ParseSince(*since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	events, err := audit.Read(time.Now().Add(-window))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error reading audit log: %v\n", err)
+		return 1
+	}
+
+	stats := audit.Aggregate(events)
+	if err := audit.WriteStatsTable(os.Stdout, stats, *since); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error writing stats: %v\n", err)
+		return 1
+	}
+
+	if *htmlPath != "" {
+		file, err := os.Create(*htmlPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error creating HTML report: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+
+		if err := audit.WriteStatsHTML(file, stats, *since); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error writing HTML report: %v\n", err)
+			return 1
+		}
+		fmt.Printf("\nHTML report written to %s\n", *htmlPath)
+	}
+
+	return 0
+}
+
+// runReport implements `claude-hook report`: a digest of the audit log and the
+// plan review archive for a team retro, as Markdown or HTML, optionally posted
+// to the webhook
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	since := fs.String("since", "7d", "Only include events newer than this (e.g. 7d, 12h)")
+	format := fs.String("format", "markdown", "Output format: markdown or html")
+	output := fs.String("o", "", "Write the digest to this file instead of stdout")
+	post := fs.Bool("notify", false, "Also post the Markdown digest to the webhook (unattended.webhook or $"+notify.WebhookEnv+")")
+	parseFlags(fs, args)
+
+	if *format != "markdown" && *format != "html" {
+		fmt.Fprintf(os.Stderr, "❌ Unknown format %q (want markdown or html)\n", *format)
+		return 2
+	}
+	window, err := audit.ParseSince(*since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	now := time.Now()
+	events, err := audit.Read(now.Add(-window))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error reading audit log: %v\n", err)
+		return 1
+	}
+	digest := audit.NewDigest(events, *since, now.Add(-window), now)
+
+	reviews, err := hooks.ArchivedReviews(now.Add(-window))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read the plan review archive: %v\n", err)
+	}
+	issues := make(map[string]int)
+	for _, result := range reviews {
+		for _, r := range result.Reviews {
+			if r.Structured == nil {
+				continue
+			}
+			for _, issue := range r.Structured.Issues {
+				issues[issue.Severity]++
+			}
+		}
+	}
+	digest.Reviews = len(reviews)
+	for _, severity := range []string{"critical", "high", "medium", "low"} {
+		if issues[severity] > 0 {
+			digest.ReviewIssues = append(digest.ReviewIssues, audit.Count{Name: severity, Count: issues[severity]})
+		}
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error creating report: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+	if *format == "html" {
+		err = audit.WriteDigestHTML(out, digest)
+	} else {
+		err = audit.WriteDigestMarkdown(out, digest)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error writing report: %v\n", err)
+		return 1
+	}
+	if *output != "" {
+		fmt.Printf("Report written to %s\n", *output)
+	}
+
+	if !*post {
+		return 0
+	}
+	cfg, err := config.Load(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, using defaults: %v\n", err)
+		cfg = config.Default()
+	}
+	url := notify.WebhookURL(cfg.Unattended)
+	switch {
+	case url == "":
+		fmt.Fprintf(os.Stderr, "❌ No webhook configured (unattended.webhook or $%s)\n", notify.WebhookEnv)
+		return 1
+	case config.ResolveOffline(cfg):
+		fmt.Fprintf(os.Stderr, "❌ Offline, not posting the report\n")
+		return 1
+	}
+	network.UseCABundle(config.ResolveCABundle(cfg))
+	var markdown strings.Builder
+	_ = audit.WriteDigestMarkdown(&markdown, digest)
+	if err := notify.Send(url, notify.Event{Hook: "report", Decision: "report", Message: markdown.String()}); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "📨 Report posted to the webhook")
+	return 0
+}
+
+// runPolicy implements `claude-hook policy bundle <dir> | eval`: it writes the
+// built-in rules as a Rego bundle to start policies from, or evaluates the
+// project's policies on a policy input, to try them out
+func runPolicy(args []string) int {
+	usage := "usage: claude-hook policy bundle <dir> | eval [-dir DIR] [-input FILE] | corpus [-since 30d] | simulate -corpus FILE -candidate PATH [-dir DIR]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "bundle":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		cfg, err := config.Load(".")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, using defaults: %v\n", err)
+			cfg = config.Default()
+		}
+		if err := policy.WriteBundle(args[1], cfg.Content); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✅ Wrote the built-in rules to %s; add data.claude_hooks.builtin.decisions to your policies' decisions to keep them\n", args[1])
+	case "eval":
+		fs := flag.NewFlagSet("policy eval", flag.ExitOnError)
+		dir := fs.String("dir", ".", "Directory inside the project")
+		inputPath := fs.String("input", "", "Policy input JSON (default: stdin)")
+		parseFlags(fs, args[1:])
+
+		cfg, err := config.Load(*dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if cfg.Policy.Engine == "" {
+			fmt.Fprintln(os.Stderr, "❌ policy.engine isn't set")
+			return 1
+		}
+		var in policy.Input
+		if err := readInput(*inputPath, &in); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Invalid policy input: %v\n", err)
+			return 1
+		}
+		d, err := projectPolicies(cfg, *dir).Decide(in)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if d == nil {
+			fmt.Println("✅ allow")
+			return 0
+		}
+		fmt.Printf("❌ %s (%s): %s\n", d.Decision, d.Rule, d.Reason)
+	case "corpus":
+		fs := flag.NewFlagSet("policy corpus", flag.ExitOnError)
+		since := fs.String("since", "30d", "Only include commands newer than this (e.g. 7d, 12h)")
+		parseFlags(fs, args[1:])
+
+		window, err := audit.ParseSince(*since)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		events, err := audit.Read(time.Now().Add(-window))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error reading audit log: %v\n", err)
+			return 1
+		}
+		var commands []string
+		for _, ev := range events {
+			if ev.Command != "" && !slices.Contains(commands, ev.Command) {
+				commands = append(commands, ev.Command)
+			}
+		}
+		if len(commands) == 0 {
+			fmt.Fprintln(os.Stderr, "⚠️  No commands in the audit log; set audit.commands to record them")
+		}
+		if err := policy.WriteCorpus(os.Stdout, commands); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+	case "simulate":
+		fs := flag.NewFlagSet("policy simulate", flag.ExitOnError)
+		corpusPath := fs.String("corpus", "", "File of commands, one per line (see policy corpus)")
+		candidatePath := fs.String("candidate", "", "Config file with the candidate policy section, or Rego file or directory")
+		dir := fs.String("dir", ".", "Directory inside the project")
+		parseFlags(fs, args[1:])
+		if *corpusPath == "" || *candidatePath == "" {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+
+		cfg, err := config.Load(*dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		current := projectPolicies(cfg, *dir)
+		candidate, err := policy.Candidate(current, *candidatePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		file, err := os.Open(*corpusPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		commands, err := policy.ReadCorpus(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+
+		changes, err := policy.Simulate(current, candidate, commands)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		writeSimulation(os.Stdout, len(commands), changes)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+// projectPolicies are the policies of cfg, loaded from dir
+func projectPolicies(cfg *config.Config, dir string) policy.Policies {
+	if cfg.Path != "" {
+		dir = filepath.Dir(cfg.Path)
+	}
+	return policy.Policies{Config: cfg.Policy, Content: cfg.Content, Dir: dir}
+}
+
+// writeSimulation reports the commands of a corpus of total the candidate
+// policies decide differently
+func writeSimulation(out io.Writer, total int, changes []policy.Change) {
+	describe := func(d *policy.Decision) string {
+		if d == nil {
+			return "allow"
+		}
+		return fmt.Sprintf("%s (%s)", d.Decision, d.Rule)
+	}
+	counts := make(map[string]int)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if len(changes) > 0 {
+		fmt.Fprintln(tw, "CHANGE\tBEFORE\tAFTER\tCOMMAND")
+	}
+	for _, c := range changes {
+		counts[c.Kind()]++
+		command := strings.Join(strings.Fields(c.Command), " ")
+		if len(command) > 80 {
+			command = command[:77] + "..."
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Kind(), describe(c.Before), describe(c.After), command)
+	}
+	tw.Flush()
+	fmt.Fprintf(out, "\n📊 %d commands: %d newly blocked, %d newly allowed, %d decided differently, %d unchanged\n",
+		total, counts["blocked"], counts["allowed"], counts["changed"], total-len(changes))
+}
+
+// runProfile implements `claude-hook profile use <name>|none|list`: it picks
+// which of the profiles defined in .claude-hooks.yaml files applies, in every
+// project defining it
+func runProfile(args []string) int {
+	usage := "usage: claude-hook profile use <name>|none | list [-dir DIR]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "use":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+		name := args[1]
+		if name == "none" {
+			name = ""
+		}
+		if err := config.UseProfile(name); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if name == "" {
+			fmt.Println("✅ No settings profile; projects use their own settings")
+			return 0
+		}
+		fmt.Printf("✅ Using settings profile %s in every project that defines it\n", name)
+		if cfg, err := config.Load("."); err == nil && cfg.Path != "" {
+			if _, ok := cfg.Profiles[name]; !ok {
+				fmt.Printf("⚠️  %s doesn't define %s, so this project keeps its own settings\n", cfg.Path, name)
+			}
+		}
+		if env := os.Getenv(config.SettingsProfileEnv); env != "" {
+			fmt.Printf("⚠️  $%s=%s overrides it in this shell\n", config.SettingsProfileEnv, env)
+		}
+	case "list":
+		fs := flag.NewFlagSet("profile list", flag.ExitOnError)
+		dir := fs.String("dir", ".", "Directory inside the project")
+		parseFlags(fs, args[1:])
+
+		selected, source, err := config.SelectedProfile()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		cfg, err := config.Load(*dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if selected == "" {
+			fmt.Println("No settings profile selected")
+		} else {
+			fmt.Printf("Selected: %s (from %s)\n", selected, source)
+		}
+		if len(cfg.Profiles) == 0 {
+			fmt.Println("This project defines no profiles")
+			return 0
+		}
+		for _, name := range slices.Sorted(maps.Keys(cfg.Profiles)) {
+			marker := " "
+			if name == cfg.SettingsProfile {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+// runConfig implements `claude-hook config lint|show|schema`: checking the
+// project's config against the schema, printing it as written or with
+// defaults and the settings profile applied, and printing the schema itself
+func runConfig(args []string) int {
+	usage := "usage: claude-hook config lint [-dir DIR] | show [-effective] [-dir DIR] | schema"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "lint":
+		fs := flag.NewFlagSet("config lint", flag.ExitOnError)
+		dir := fs.String("dir", ".", "Directory inside the project")
+		parseFlags(fs, args[1:])
+
+		cfg, err := config.Load(*dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if cfg.Global != "" {
+			fmt.Printf("✅ %s is valid\n", cfg.Global)
+		}
+		if cfg.Path == "" {
+			fmt.Printf("No %s found in the project; the defaults apply\n", config.FileName)
+			return 0
+		}
+		fmt.Printf("✅ %s is valid\n", cfg.Path)
+		for _, name := range slices.Sorted(maps.Keys(cfg.Scopes)) {
+			if _, err := os.Stat(filepath.Join(filepath.Dir(cfg.Path), name)); err != nil {
+				fmt.Printf("⚠️  Scope %s matches no directory\n", name)
+			}
+		}
+	case "show":
+		fs := flag.NewFlagSet("config show", flag.ExitOnError)
+		dir := fs.String("dir", ".", "Directory inside the project")
+		effective := fs.Bool("effective", false, "Print every setting, with the defaults, the settings profile, and the directory's scope applied")
+		parseFlags(fs, args[1:])
+
+		cfg, err := config.Load(*dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if !*effective {
+			if cfg.Path == "" {
+				fmt.Fprintf(os.Stderr, "No %s found; run with -effective to see the defaults\n", config.FileName)
+				return 1
+			}
+			data, err := os.ReadFile(cfg.Path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+				return 1
+			}
+			fmt.Print(string(data))
+			return 0
+		}
+
+		source := "defaults"
+		if cfg.Global != "" {
+			source += ", " + cfg.Global
+		}
+		if cfg.Path != "" {
+			source += ", " + cfg.Path
+		}
+		if cfg.SettingsProfile != "" {
+			source += ", settings profile " + cfg.SettingsProfile
+		}
+		if cfg.Scope != "" {
+			source += ", scope " + cfg.Scope
+		}
+		cfg.Profiles, cfg.Scopes = nil, nil // Already applied
+		fmt.Printf("# Effective configuration (%s)\n", source)
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+	case "schema":
+		schema, err := config.Schema()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		fmt.Print(string(schema))
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+// runTools implements `claude-hook tools`: it bundles the tools the hooks run
+// on a machine that has them, and installs a bundle on one without internet
+// access
+func runTools(args []string) int {
+	usage := "usage: claude-hook tools list | bundle [-o FILE] [TOOL...] | install -from FILE"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		for _, s := range tools.Statuses() {
+			switch {
+			case s.Path == "":
+				fmt.Printf("⏭️  %s: not installed\n", s.Name)
+			case s.Bundled && s.Version != "":
+				fmt.Printf("📦 %s: %s (%s)\n", s.Name, s.Path, s.Version)
+			case s.Bundled:
+				fmt.Printf("📦 %s: %s\n", s.Name, s.Path)
+			default:
+				fmt.Printf("✅ %s: %s\n", s.Name, s.Path)
+			}
+		}
+	case "bundle":
+		fs := flag.NewFlagSet("tools bundle", flag.ExitOnError)
+		output := fs.String("o", "claude-hooks-tools.tar", "Write the bundle here")
+		parseFlags(fs, args[1:])
+
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		manifest, missing, err := tools.Bundle(file, fs.Args())
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(*output)
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		for _, entry := range manifest.Tools {
+			fmt.Printf("📦 %s\n", strings.TrimSpace(entry.Name+" "+entry.Version))
+		}
+		if len(missing) > 0 {
+			fmt.Printf("⏭️  Not installed here, so left out: %s\n", strings.Join(missing, ", "))
+		}
+		fmt.Printf("✅ Wrote %d tools for %s to %s\n", len(manifest.Tools), manifest.Platform, *output)
+	case "install":
+		fs := flag.NewFlagSet("tools install", flag.ExitOnError)
+		from := fs.String("from", "", "Bundle written by `claude-hook tools bundle`")
+		parseFlags(fs, args[1:])
+		if *from == "" {
+			fmt.Fprintln(os.Stderr, usage)
+			return 2
+		}
+
+		file, err := os.Open(*from)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		manifest, err := tools.Install(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		bin, _ := tools.BinDir()
+		for _, entry := range manifest.Tools {
+			fmt.Printf("📦 %s\n", strings.TrimSpace(entry.Name+" "+entry.Version))
+		}
+		fmt.Printf("✅ Installed %d tools in %s; the hooks use them before PATH\n", len(manifest.Tools), bin)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+// runInit implements `claude-hook init -template NAME`: it writes the named
+// project template as the project's .claude-hooks.yaml
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	template := fs.String("template", "", "Project template: "+strings.Join(config.Templates(), ", "))
+	dir := fs.String("dir", ".", "Project root to write the config to")
+	force := fs.Bool("force", false, "Overwrite an existing config")
+	parseFlags(fs, args)
+
+	if *template == "" {
+		fmt.Fprintf(os.Stderr, "usage: claude-hook init -template %s [-dir DIR] [-force]\n", strings.Join(config.Templates(), "|"))
+		return 2
+	}
+	data, err := config.Template(*template)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 2
+	}
+	path := filepath.Join(*dir, config.FileName)
+	if _, err := os.Stat(path); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "❌ %s already exists; use -force to replace it\n", path)
+		return 1
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("✅ Wrote the %s template to %s; review it and commit it with the project\n", *template, path)
+	return 0
+}
+
+// runSyncClaudeMD implements `claude-hook sync-claude-md`: it writes what the
+// hooks enforce in the project into a managed section of its CLAUDE.md, so
+// Claude's instructions say what the hooks will block
+func runSyncClaudeMD(args []string) int {
+	fs := flag.NewFlagSet("sync-claude-md", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Directory in the project")
+	file := fs.String("file", "", "CLAUDE.md to update (default: CLAUDE.md at the project root)")
+	check := fs.Bool("check", false, "Only report whether the section is out of date, exiting 1 if it is (for CI)")
+	printOnly := fs.Bool("print", false, "Print the section instead of writing it")
+	parseFlags(fs, args)
+
+	cfg, err := config.Load(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	root := state.ProjectRoot(*dir)
+	section := hooks.ClaudeMDSection(cfg, root)
+	if *printOnly {
+		fmt.Print(section)
+		return 0
+	}
+	path := *file
+	if path == "" {
+		path = filepath.Join(root, "CLAUDE.md")
+	}
+
+	if *check || config.ReadOnly() {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		updated, err := hooks.ReplaceClaudeMDSection(string(data), section)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", path, err)
+			return 1
+		}
+		if updated != string(data) {
+			fmt.Fprintf(os.Stderr, "❌ %s doesn't describe what the hooks enforce; run `claude-hook sync-claude-md`\n", path)
+			return 1
+		}
+		fmt.Printf("✅ %s is up to date\n", path)
+		return 0
+	}
+
+	changed, err := hooks.SyncClaudeMD(path, section)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if changed {
+		fmt.Printf("✅ Updated the claude-hooks section of %s\n", path)
+	} else {
+		fmt.Printf("✅ %s is up to date\n", path)
+	}
+	return 0
+}
+
+// runTelemetry implements `claude-hook telemetry enable|disable|status|preview`.
+// Nothing is ever sent until the user runs `enable`.
+func runTelemetry(args []string) int {
+	usage := "usage: claude-hook telemetry enable -endpoint URL | disable | status | preview"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	prefs, err := telemetry.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "enable":
+		fs := flag.NewFlagSet("telemetry enable", flag.ExitOnError)
+		endpoint := fs.String("endpoint", prefs.Endpoint, "URL aggregate reports are POSTed to")
+		parseFlags(fs, args[1:])
+		if *endpoint == "" {
+			fmt.Fprintln(os.Stderr, "❌ An -endpoint is required to enable telemetry")
+			return 1
+		}
+		if !prefs.Enabled {
+			prefs.EnabledAt = time.Now()
+		}
+		prefs.Enabled, prefs.Endpoint = true, *endpoint
+		if err := telemetry.Save(prefs); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✅ Telemetry enabled; at most one aggregate report per day will be sent to %s\n", *endpoint)
+		fmt.Println("   Run `claude-hook telemetry preview` to see exactly what is sent")
+	case "disable":
+		prefs.Enabled = false
+		if err := telemetry.Save(prefs); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		fmt.Println("✅ Telemetry disabled")
+	case "status":
+		endpoint, active := prefs.Active()
+		switch {
+		case active:
+			fmt.Printf("📊 Telemetry is enabled (endpoint %s)\n", endpoint)
+			if !prefs.LastSent.IsZero() {
+				fmt.Printf("   Last report sent %s\n", prefs.LastSent.Format(time.RFC3339))
+			}
+		case prefs.Enabled:
+			fmt.Printf("📊 Telemetry is enabled but suppressed (%s or no endpoint)\n", telemetry.DisableEnv)
+		default:
+			fmt.Println("📊 Telemetry is disabled")
+		}
+	case "preview":
+		report, err := telemetry.Pending(prefs, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		fmt.Println(string(out))
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+// interruptTimeout bounds how long an interrupted hook may take to reach a
+// checkpoint before it exits regardless
+const interruptTimeout = 5 * time.Second
+
+var interruptExit sync.Mutex
+
+// exitIfInterrupted ends an interrupted hook with a neutral decision (no output,
+// exit 0), since results from killed tools would be misleading. ev records how far
+// the hook got; only the audit log is written, so telemetry can't stall the exit.
+func exitIfInterrupted(ev audit.Event, verbose bool) {
+	if !proc.Interrupted() {
+		return
+	}
+	interruptExit.Lock() // Never unlocked: whichever of main and the backstop gets here first exits
+	ev.Decision = "cancelled"
+	ev.DurationMS = time.Since(invocationStart).Milliseconds()
+	if err := audit.Record(ev); err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not write audit log: %v\n", err)
+	}
+	fmt.Fprintln(os.Stderr, "⏹️  Interrupted; exiting without a decision")
+	os.Exit(0)
+}
+
+// recordAudit appends an event for this invocation to the audit log, then sends
+// the daily telemetry report if the user opted in. Both are best effort and never
+// affect the hook's decision.
+func recordAudit(ev audit.Event, verbose bool) {
+	if ev.DurationMS == 0 {
+		ev.DurationMS = time.Since(invocationStart).Milliseconds()
+	}
+	if blocking(ev.Decision) && active.enforcement != "" && active.enforcement != config.EnforceBlock {
+		ev.Enforcement = active.enforcement
+	}
+	if ev.Session == "" {
+		ev.Session = active.session
+	}
+	if ev.Cache == "" {
+		ev.Cache = hooks.CacheResult()
+	}
+	if ev.Hook == "pre-bash" && active.logCommands {
+		ev.Command = active.command
+	}
+	if err := audit.Record(ev); err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not write audit log: %v\n", err)
+	}
+	exportAudit(ev, verbose)
+	if active.offline {
+		return
+	}
+	if err := telemetry.MaybeSend(verbose); err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not send telemetry: %v\n", err)
+	}
+	notifyWebhook(ev, verbose)
+}
+
+// active is the profile this invocation runs under, resolved once its input is read
+var active struct {
+	profile    string
+	webhook    string
+	session    string
+	transcript string
+	project    string
+	// enforcement is block unless warn or dry-run relax what the hooks decide
+	enforcement string
+	offline     bool
+	// export sends blocked commands and found secrets to a SIEM
+	export       config.AuditExportConfig
+	exportTarget string
+	// command is the Bash command of a pre-bash invocation, logged with
+	// audit.commands
+	command     string
+	logCommands bool
+	// agent is what the agents section lets the calling agent do; the main
+	// agent has no agentID
+	agent     config.AgentConfig
+	agentID   string
+	agentType string
+}
+
+// resolveProfile loads the project config for the input's working directory (the
+// process's when the input couldn't be parsed) and picks the profile from it
+func resolveProfile(input Input, verbose bool) {
+	dir := input.Cwd
+	if dir == "" {
+		if files := collectFiles(input.ToolInput); len(files) > 0 {
+			dir = filepath.Dir(files[0])
+		}
+	}
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	cfg, err := config.Load(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, using defaults: %v\n", err)
+		cfg = config.Default()
+	}
+	name, err := profile.Resolve(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+
+	enforcement, err := config.ResolveEnforcement(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+
+	active.profile = name
+	active.enforcement = enforcement
+	active.offline = config.ResolveOffline(cfg)
+	active.webhook = notify.WebhookURL(cfg.Unattended)
+	active.export = cfg.Audit.Export
+	active.exportTarget = config.ResolveAuditExport(cfg)
+	active.command = input.ToolInput.Command
+	active.logCommands = cfg.Audit.Commands
+	active.agentID, active.agentType = input.AgentID, input.AgentType
+	active.agent = cfg.Agents.For(input.AgentID, input.AgentType)
+	network.UseCABundle(config.ResolveCABundle(cfg))
+	active.session = input.SessionID
+	active.transcript = input.TranscriptPath
+	active.project = state.ProjectRoot(dir)
+	vlog.Printf(verbose, "🔧 Profile: %s\n", name)
+	if cfg.SettingsProfile != "" {
+		vlog.Printf(verbose, "🔧 Settings profile: %s\n", cfg.SettingsProfile)
+	}
+	if config.ReadOnly() && enforcement == config.EnforceWarn {
+		fmt.Fprintf(os.Stderr, "🔒 Read-only: checks report, but nothing is changed or blocked\n")
+	} else if enforcement != config.EnforceBlock {
+		fmt.Fprintf(os.Stderr, "🔍 Enforcement: %s, nothing will be blocked\n", enforcement)
+	}
+}
+
+// failClosed ends an unattended hook whose input couldn't be parsed with a
+// blocking decision: without the input there is no way to tell the tool call is safe
+func failClosed(hookType string, err error, verbose bool) {
+	reason := fmt.Sprintf("The %s hook could not parse its input (%v), and the unattended profile blocks what it can't check.", hookType, err)
+	fmt.Fprintf(os.Stderr, "❌ BLOCKED: %s\n", reason)
+
+	switch hookType {
+	case "pre-bash", "plan-review", "pre-edit":
+		writePreToolUseDecision("deny", reason)
+		recordAudit(audit.Event{Hook: hookType, Decision: "deny", Rule: "unattended:unparseable-input"}, verbose)
+	case "post-edit":
+		writeBlockDecision("PostToolUse", reason)
+		recordAudit(audit.Event{Hook: hookType, Decision: "block", Rule: "unattended:unparseable-input"}, verbose)
+	default:
+		writeBlockDecision("Stop", reason)
+		recordAudit(audit.Event{Hook: hookType, Decision: "block", Rule: "unattended:unparseable-input"}, verbose)
+	}
+	os.Exit(0)
+}
+
+// exportAudit sends blocked commands and found secrets to the SIEM of
+// audit.export. Offline, only a file target is written. Failures only warn.
+func exportAudit(ev audit.Event, verbose bool) {
+	target := active.exportTarget
+	if target == "" || (active.offline && strings.Contains(target, "://") && !strings.HasPrefix(target, "file://")) {
+		return
+	}
+	if ev.Detail == "" && ev.Hook == "pre-bash" {
+		ev.Detail = active.command
+	}
+	err := audit.Export(active.export, target, ev, audit.Context{Session: active.session, Project: active.project, Profile: active.profile})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not export audit event: %v\n", err)
+	} else if audit.Kind(ev) != "" {
+		vlog.Printf(verbose, "📤 Exported %s audit event\n", ev.Rule)
+	}
+}
+
+// notifyWebhook tells a human about every decision that stopped an unattended
+// session. Failures only warn, like the rest of the reporting.
+func notifyWebhook(ev audit.Event, verbose bool) {
+	if active.profile != profile.Unattended || active.webhook == "" {
+		return
+	}
+	if !blocking(ev.Decision) || ev.Enforcement != "" {
+		return // Nobody was stopped
+	}
+	err := notify.Send(active.webhook, notify.Event{
+		Hook:     ev.Hook,
+		Decision: ev.Decision,
+		Rule:     ev.Rule,
+		Session:  active.session,
+		Project:  active.project,
+		Profile:  active.profile,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not send webhook notification: %v\n", err)
+	} else {
+		vlog.Printf(verbose, "📣 Sent %s notification to webhook\n", ev.Decision)
+	}
+}
+
+// writeEditorDiagnostics publishes diagnostics for editor plugins. Failures only warn
+// since they must never change the outcome of the hook itself.
+func writeEditorDiagnostics(format, path string, files []string, diagnostics []hooks.Diagnostic, verbose bool) {
+	if path == "" {
+		var err error
+		path, err = state.Path(hooks.DefaultDiagnosticsFile(format))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not locate diagnostics file: %v\n", err)
+			return
+		}
+	}
+
+	if err := hooks.WriteDiagnostics(path, format, files, diagnostics); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not write diagnostics: %v\n", err)
+		return
+	}
+
+	vlog.Printf(verbose, "📝 Wrote %d diagnostics to %s\n", len(diagnostics), path)
+}
+
+func collectFiles(input ToolInput) []string {
+	seen := make(map[string]bool)
+	var files []string
+
+	// Files are checked where they really are, so symlinks are resolved
+	add := func(f string) {
+		if f == "" {
+			return
+		}
+		if f = state.RealPath(f); !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+
+	// Add single file if present
+	add(input.FilePath)
+
+	// NotebookEdit reports its file separately
+	add(input.NotebookPath)
+
+	// Add multiple files
+	for _, f := range input.FilePaths {
+		add(f)
+	}
+
+	return filterFiles(files)
+}
+
+// trivialEdit reports whether an Edit, MultiEdit, or Write of a single file only
+// changed comments or whitespace. The file's previous content is rebuilt by
+// undoing the replacements, or read from HEAD for a Write; when that isn't
+// possible the edit isn't trivial.
+func trivialEdit(input Input, files []string) bool {
+	if len(files) != 1 || files[0] != state.RealPath(input.ToolInput.FilePath) {
+		return false
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		return false
+	}
+	after := string(data)
+
+	var edits []ToolEdit
+	switch input.ToolName {
+	case "Edit":
+		edits = []ToolEdit{{OldString: input.ToolInput.OldString, NewString: input.ToolInput.NewString, ReplaceAll: input.ToolInput.ReplaceAll}}
+	case "MultiEdit":
+		edits = input.ToolInput.Edits
+	case "Write":
+		repo := vcs.Detect(filepath.Dir(files[0]))
+		if repo == nil {
+			return false
+		}
+		before, ok := repo.Base(files[0])
+		if !ok {
+			return false // New file
+		}
+		return hooks.TrivialEdit(files[0], string(before), after)
+	default:
+		return false
+	}
+
+	// Undo the replacements last to first; each must be unambiguous
+	before := after
+	for _, e := range slices.Backward(edits) {
+		if e.NewString == "" {
+			return false
+		}
+		if e.ReplaceAll {
+			before = strings.ReplaceAll(before, e.NewString, e.OldString)
+			continue
+		}
+		if strings.Count(before, e.NewString) != 1 {
+			return false
+		}
+		before = strings.Replace(before, e.NewString, e.OldString, 1)
+	}
+	return len(edits) > 0 && hooks.TrivialEdit(files[0], before, after)
+}
+
+// filterFiles drops vendored and generated files that hooks should never touch
+// outsidePaths returns the files outside the session's git repository, once
+// symlinks are resolved, and the paths.outside policy for them. Sessions
+// outside a repository have nothing to be outside of.
+func outsidePaths(files []string, verbose bool) ([]string, string) {
+	root := active.project
+	if root == "" {
+		return nil, ""
+	}
+	if repoRoot, _ := vcs.FindRoot(root); repoRoot != root {
+		return nil, ""
+	}
+	cfg, err := config.Load(root)
+	if err != nil {
+		cfg = config.Default()
+	}
+	mode := cfg.Paths.Outside
+	switch mode {
+	case config.OutsideBlock, config.OutsideWarn, config.OutsideValidate:
+	default:
+		fmt.Fprintf(os.Stderr, "⚠️  Unknown paths.outside %q (want %s, %s, or %s), using %s\n", mode, config.OutsideBlock, config.OutsideWarn, config.OutsideValidate, config.OutsideWarn)
+		mode = config.OutsideWarn
+	}
+
+	var outside []string
+	for _, f := range files {
+		if !state.Within(root, f) {
+			outside = append(outside, f)
+		}
+	}
+	if len(outside) > 0 {
+		vlog.Printf(verbose, "🔍 %d file(s) outside %s (paths.outside: %s)\n", len(outside), root, mode)
+	}
+	return outside, mode
+}
+
+// outsideMessage tells Claude the files aren't part of the session's repository
+func outsideMessage(outside []string) string {
+	return fmt.Sprintf("Outside this session's repository (%s), directly or through a symlink: %s.", active.project, strings.Join(outside, ", "))
+}
+
+// denyOutsidePaths denies an edit of files outside the repository and exits
+func denyOutsidePaths(outside []string, verbose bool) {
+	reason := outsideMessage(outside) + " paths.outside is block, so they can't be edited. Make the change in this repository, or ask the user to make it."
+	writePreToolUseDecision("deny", reason)
+	fmt.Fprintf(os.Stderr, "❌ BLOCKED: %s\n", outsideMessage(outside))
+	recordAudit(audit.Event{Hook: "pre-edit", Decision: "deny", Rule: "outside-repo"}, verbose)
+	os.Exit(0) // Exit successfully since we provided JSON
+}
+
+// denyRestricted denies an edit of files owners.restricted covers and exits
+func denyRestricted(restricted []hooks.OwnedFile, verbose bool) {
+	writePreToolUseDecision("deny", hooks.RestrictedMessage(restricted))
+	for _, o := range restricted {
+		fmt.Fprintf(os.Stderr, "❌ BLOCKED: %s\n", o)
+	}
+	recordAudit(audit.Event{Hook: "pre-edit", Decision: "deny", Rule: "codeowners-restricted"}, verbose)
+	os.Exit(0) // Exit successfully since we provided JSON
+}
+
+// pingOwners posts to the owners.webhooks of the teams whose files the
+// session edited, once per session. Failures only warn.
+func pingOwners(others []hooks.OwnedFile, verbose bool) {
+	if active.offline {
+		return
+	}
+	pings, err := hooks.NewOwnerPings(hooks.Session{Dir: active.project, ID: active.session, TranscriptPath: active.transcript}, others)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not record owner pings: %v\n", err)
+	}
+	for _, owner := range slices.Sorted(maps.Keys(pings)) {
+		var owned []string
+		for _, o := range others {
+			if slices.Contains(o.Owners, owner) {
+				owned = append(owned, o.File)
+			}
+		}
+		err := notify.Send(pings[owner], notify.Event{
+			Hook:     "post-edit",
+			Decision: "warn",
+			Rule:     "codeowners",
+			Session:  active.session,
+			Project:  active.project,
+			Profile:  active.profile,
+			Message:  fmt.Sprintf("Claude is editing files %s owns: %s", owner, strings.Join(owned, ", ")),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not notify %s: %v\n", owner, err)
+		} else {
+			vlog.Printf(verbose, "📣 Notified %s of the edit\n", owner)
+		}
+	}
+}
+
+func filterFiles(files []string) []string {
+	var filtered []string
+	for _, f := range files {
+		if strings.Contains(f, "/vendor/") ||
+			strings.HasSuffix(f, ".pb.go") ||
+			strings.HasSuffix(f, ".gen.go") {
+			continue
+		}
+		filtered = append(filtered, f)
+	}
+
+	return filtered
+}
+
+// collectMoves detects files moved by this tool call. For Write/Edit only moves
+// ending at one of the edited files count; Bash calls are only inspected when
+// they actually run mv, since git status isn't free.
+func collectMoves(input Input, files []string, verbose bool) []hooks.FileMove {
+	isBash := input.ToolName == "Bash" || input.ToolName == "bash"
+
+	var root string
+	if isBash {
+		if !commandRunsAny(input.ToolInput.Command, "mv", "git mv") {
+			return nil
+		}
+		root = bashGitRoot(input, verbose)
+	} else {
+		for _, f := range files {
+			if root = findGitRoot(f, verbose); root != "" {
+				break
+			}
+		}
+	}
+	if root == "" {
+		return nil
+	}
+
+	moves, err := hooks.DetectMoves(root, verbose)
+	if err != nil {
+		vlog.Printf(verbose, "⚠️  Could not detect moved files: %v\n", err)
+		return nil
+	}
+
+	if isBash {
+		return moves
+	}
+
+	var relevant []hooks.FileMove
+	for _, m := range moves {
+		for _, f := range files {
+			if abs, err := filepath.Abs(f); err == nil && abs == m.To {
+				relevant = append(relevant, m)
+				break
+			}
+		}
+	}
+	return relevant
+}
+
+// commandRunsAny reports whether any sub-command of a shell command runs one of
+// programs, which may include a subcommand (e.g. "git mv")
+func commandRunsAny(command string, programs ...string) bool {
+	for _, subCmd := range shell.Split(command) {
+		parts := shell.Words(subCmd)
+		if len(parts) == 0 {
+			continue
+		}
+		parts[0] = filepath.Base(parts[0])
+
+		for _, program := range programs {
+			want := strings.Fields(program)
+			if len(parts) >= len(want) && slices.Equal(parts[:len(want)], want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// commandArgs returns the non-flag arguments of the sub-commands of a shell
+// command that run one of programs, as commandRunsAny matches them
+func commandArgs(command string, programs ...string) []string {
+	var args []string
+	for _, subCmd := range shell.Split(command) {
+		parts := shell.Words(subCmd)
+		if len(parts) == 0 {
+			continue
+		}
+		parts[0] = filepath.Base(parts[0])
+
+		for _, program := range programs {
+			want := strings.Fields(program)
+			if len(parts) < len(want) || !slices.Equal(parts[:len(want)], want) {
+				continue
+			}
+			flags := true
+			for _, arg := range parts[len(want):] {
+				switch {
+				case flags && arg == "--":
+					flags = false
+				case flags && strings.HasPrefix(arg, "-"):
+				default:
+					args = append(args, arg)
+				}
+			}
+			break
+		}
+	}
+	return args
+}
+
+// collectDeletions finds files removed by this tool call: tracked files a Bash
+// rm/git rm named, directly or through a directory or glob, or a file emptied
+// by Write that's gone by the time the hook runs. Files that were moved rather
+// than deleted are excluded.
+func collectDeletions(input Input, moves []hooks.FileMove, verbose bool) []string {
+	var deleted []string
+
+	switch input.ToolName {
+	case "Write":
+		// An empty Write is a deletion in all but name, but only once the file is
+		// actually gone; a 0-byte file still on disk is validated like any other
+		if input.ToolInput.FilePath != "" && strings.TrimSpace(input.ToolInput.Content) == "" {
+			if _, err := os.Stat(input.ToolInput.FilePath); os.IsNotExist(err) {
+				deleted = append(deleted, state.RealPath(input.ToolInput.FilePath))
+			}
+		}
+	case "Bash", "bash":
+		args := commandArgs(input.ToolInput.Command, "rm", "git rm")
+		if len(args) == 0 {
+			return nil
+		}
+		dir := bashDir(input, verbose)
+		root := bashGitRoot(input, verbose)
+		if dir == "" || root == "" {
+			return nil
+		}
+		all, err := hooks.DetectDeletions(root)
+		if err != nil {
+			vlog.Printf(verbose, "⚠️  Could not detect deleted files: %v\n", err)
+			return nil
+		}
+		// Only what this command removed; other deletions in the tree were
+		// already checked when they happened, or aren't this session's
+		for _, f := range all {
+			if slices.ContainsFunc(args, func(arg string) bool { return removedBy(f, dir, arg) }) {
+				deleted = append(deleted, f)
+			}
+		}
+	}
+
+	return slices.DeleteFunc(deleted, func(f string) bool {
+		return slices.ContainsFunc(moves, func(m hooks.FileMove) bool { return m.From == f })
+	})
+}
+
+// removedBy reports whether an rm argument, relative to dir, names file: the
+// file itself, a directory containing it, or a glob matching it
+func removedBy(file, dir, arg string) bool {
+	if !filepath.IsAbs(arg) {
+		arg = filepath.Join(dir, arg)
+	}
+	arg = filepath.Clean(arg)
+	if file == arg || strings.HasPrefix(file, arg+string(filepath.Separator)) {
+		return true
+	}
+	matched, _ := filepath.Match(arg, file)
+	return matched
+}
+
+// bashDir returns the directory a Bash command ran in, or empty if unknown
+func bashDir(input Input, verbose bool) string {
+	if input.Cwd != "" {
+		return input.Cwd
+	}
+	return getTargetWorkingDirectory(input, verbose)
+}
+
+// bashGitRoot returns the git root a Bash command ran in, or empty if unknown
+func bashGitRoot(input Input, verbose bool) string {
+	dir := bashDir(input, verbose)
+	if dir == "" {
+		return ""
+	}
+	// findGitRoot searches upward from the directory containing the path it's given
+	return findGitRoot(filepath.Join(dir, ".git"), verbose)
+}
+
+// pipelineResult is what one run of the edit pipeline found
+type pipelineResult struct {
+	errorMessages []string
+	diagnostics   []hooks.Diagnostic
+	failedRules   []string
+	validated     []string // File types a hook ran for
+	unlocated     []string // Failures whose output pointed at no file or line
+	warnings      []string // Findings shown to Claude without blocking
+	notes         []string // What passed, for Claude's context; no finding
+	tests         map[string]bool
+	loopGuidance  string // Escalation for files the session keeps getting blocked on
+	fixes         string // Patches the tools suggest for the diagnostics
+	timeouts      []string
+	timings       []bench.Timing // How long each stage took, for claude-hook bench
+}
+
+// stage starts timing a stage of the pipeline; the returned stop records it
+func (r *pipelineResult) stage(name string) (stop func()) {
+	start := time.Now()
+	return func() {
+		r.timings = append(r.timings, bench.Timing{Stage: name, Duration: time.Since(start)})
+	}
+}
+
+// timedOut reports the steps that ran out of time checking files, as warnings
+// and diagnostics of their own. The hooks leave the output of the commands
+// killed for it out of their errors, so failures of the steps that finished
+// still count.
+func (r *pipelineResult) timedOut(files []string, timeouts []*proc.Timeout) {
+	for _, t := range timeouts {
+		msg := t.Error()
+		fmt.Fprintf(os.Stderr, "⏱️  %s\n", msg)
+		vlog.Logf("⏱️  %s\n", msg)
+		r.timeouts = append(r.timeouts, msg+". The check didn't finish, so it neither passed nor failed; raise its limit under timeouts in .claude-hooks.yaml if it's just slow.")
+		for _, f := range files {
+			r.diagnostics = append(r.diagnostics, hooks.Diagnostic{File: f, Line: 1, Severity: "warning", Message: msg, Source: "timeout"})
+		}
+	}
+}
+
+// auditEvent summarizes the result for the audit log
+func (r pipelineResult) auditEvent(hook string) audit.Event {
+	ev := audit.Event{Hook: hook, Decision: "allow", Languages: slices.Sorted(slices.Values(r.validated)), Tests: r.tests}
+	if len(r.errorMessages) > 0 {
+		ev.Decision = "block"
+		ev.Rule = strings.Join(slices.Sorted(slices.Values(r.failedRules)), ",")
+	}
+	return ev
+}
+
+// reasonSections ranks the result's messages for the reason budget: what
+// keeps code from building first, then failing tests, then other findings,
+// then warnings
+func (r pipelineResult) reasonSections() []reason.Section {
+	var sections []reason.Section
+	for i, msg := range r.errorMessages {
+		rule := r.failedRules[i]
+		priority := reason.Lint
+		switch {
+		case strings.HasSuffix(rule, "-post-edit"), rule == "go-build-configs", rule == "go-embed", rule == "merge-artifacts", rule == "moved-references", rule == "deleted-files", rule == "session-budget":
+			priority = reason.Compile
+		case rule == "go-tests":
+			priority = reason.Test
+		}
+		sections = append(sections, reason.Section{Rule: rule, Priority: priority, Text: msg})
+	}
+	if r.fixes != "" {
+		sections = append(sections, reason.Section{Rule: "suggested-fixes", Priority: reason.Lint, Text: r.fixes})
+	}
+	for _, msg := range r.timeouts {
+		sections = append(sections, reason.Section{Rule: "timeouts", Priority: reason.Warning, Text: msg})
+	}
+	for _, msg := range r.warnings {
+		sections = append(sections, reason.Section{Rule: "warnings", Priority: reason.Warning, Text: msg})
+	}
+	for _, msg := range r.notes {
+		sections = append(sections, reason.Section{Rule: "notes", Priority: reason.Warning, Text: msg})
+	}
+	return sections
+}
+
+// blockReason joins the sections of a reason within the project's reason
+// budget. A session sent the same failures as last time gets a short note
+// instead, so a loop on one failing test doesn't fill its context.
+func blockReason(header string, sections []reason.Section) string {
+	cfg, err := config.Load(active.project)
+	if err != nil {
+		cfg = config.Default()
+	}
+	if fingerprint := reason.Fingerprint(sections); fingerprint != "" && active.session != "" {
+		attempt, err := reason.Attempt(reason.Session{Dir: active.project, ID: active.session, TranscriptPath: active.transcript}, fingerprint)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not record the reason fingerprint: %v\n", err)
+		} else if attempt > 1 {
+			fmt.Fprintf(os.Stderr, "🔁 Same failures as the last block, attempt #%d\n", attempt)
+			return reason.Repeated(header, sections, attempt)
+		}
+	}
+	return reason.Build(active.project, header, sections, reason.Limit(cfg.Reasons))
+}
+
+// runPipeline runs the post-edit or pre-edit hooks for files, grouped by type,
+// then re-checks the importers of moved packages and packages that lost files.
+// Claude-triggered hooks and `claude-hook watch` share it, so both are held to
+// the same checks.
+func runPipeline(hookType string, files []string, moves []hooks.FileMove, deleted []string, verbose bool) pipelineResult {
+	var result pipelineResult
+	fail := func(rule, errorMsg, source string, err error) {
+		fmt.Fprintf(os.Stderr, "❌ %s\n", errorMsg)
+		vlog.Logf("❌ %s\n", errorMsg)
+		result.errorMessages = append(result.errorMessages, errorMsg)
+		result.failedRules = append(result.failedRules, rule)
+		diagnostics := hooks.ParseDiagnostics(err.Error(), source)
+		result.diagnostics = append(result.diagnostics, diagnostics...)
+		if len(diagnostics) == 0 {
+			result.unlocated = append(result.unlocated, errorMsg)
+		}
+	}
+
+	for fileType, fileList := range hooks.GroupFiles(files) {
+		// Progress goes to stderr: stdout carries the hook's JSON, or LSP messages
+		vlog.Printf(verbose, "Processing %d %s files...\n", len(fileList), fileType)
+
+		hook := hooks.LookupHook(fileType, filepath.Dir(fileList[0]))
+		if hook == nil {
+			vlog.Printf(verbose, "No hook registered for %s files\n", fileType)
+			continue
+		}
+
+		result.validated = append(result.validated, fileType)
+
+		cfg, cfgErr := config.Load(filepath.Dir(fileList[0]))
+		if cfgErr != nil {
+			cfg = config.Default()
+		}
+		proc.SetStepTimeouts(cfg.Timeouts.Steps)
+		end := proc.Bound(fileType, cfg.Timeouts.Languages[fileType])
+
+		stop := result.stage(fileType + " hook")
+		var err error
+		if hookType == "pre-edit" {
+			err = hook.PreEdit(fileList, verbose)
+		} else {
+			err = hook.PostEditJSON(fileList, verbose)
+		}
+		timeouts := end()
+		stop()
+
+		exitIfInterrupted(result.auditEvent(hookType), verbose)
+
+		result.timedOut(fileList, timeouts)
+		if err != nil {
+			fail(fileType+"-post-edit", fmt.Sprintf("%s hook failed: %v", fileType, err), fileType, err)
+		}
+	}
+
+	// Sparse checkouts and partial clones skip what they can't check; say so,
+	// so a pass isn't taken for more than it is
+	if hookType == "post-edit" {
+		result.notes = append(result.notes, hooks.CoverageNotes(files)...)
+	}
+
+	// Files build constraints keep out of go vet's default build are vetted
+	// under the go.builds configurations that compile them
+	if hookType == "post-edit" && !slices.Contains(result.failedRules, "go-post-edit") {
+		stop := result.stage("build configurations")
+		check, err := hooks.CheckBuildConfigs(files, verbose)
+		stop()
+		if err != nil {
+			fail("go-build-configs", fmt.Sprintf("go vet failed under other build configurations:\n%v", err), "go", err)
+		}
+		if len(check.Validated) > 0 {
+			result.notes = append(result.notes, fmt.Sprintf("go vet ran under build configurations: %s.", strings.Join(check.Validated, "; ")))
+		}
+		for _, f := range check.Unchecked {
+			msg := fmt.Sprintf("%s is excluded from the default build by its build constraints and no go.builds configuration includes it, so it wasn't compiled", f)
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", msg)
+			result.warnings = append(result.warnings, msg+".")
+		}
+	}
+
+	if len(moves) > 0 {
+		stop := result.stage("moves")
+		if err := hooks.CheckMovedReferences(moves, verbose); err != nil {
+			fail("moved-references", fmt.Sprintf("move check failed: %v", err), "moves", err)
+		}
+		stop()
+	}
+
+	if len(deleted) > 0 {
+		stop := result.stage("deletions")
+		if err := hooks.CheckDeletedFiles(deleted, verbose); err != nil {
+			fail("deleted-files", fmt.Sprintf("deleted file check failed: %v", err), "deletions", err)
+		}
+		stop()
+	}
+
+	// Renaming or deleting an embedded asset breaks the embedding package
+	// without touching any of its Go files
+	if changed := embedCandidates(files, moves, deleted); len(changed) > 0 {
+		stop := result.stage("embeds")
+		if err := hooks.CheckEmbeds(changed, verbose); err != nil {
+			fail("go-embed", fmt.Sprintf("embed check failed: %v", err), "go", err)
+		}
+		stop()
+	}
+
+	// Copies are worth pointing out but not worth blocking on: the copy may be
+	// the start of a deliberate divergence
+	if hookType == "post-edit" {
+		stop := result.stage("duplicates")
+		clones, err := hooks.FindDuplicates(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Duplicate check failed: %v\n", err)
+		}
+		for _, c := range clones {
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", c)
+			result.warnings = append(result.warnings, fmt.Sprintf("%s. Refactor to reuse the existing code (or extract a shared function) instead of copying it.", c))
+			result.diagnostics = append(result.diagnostics, c.Diagnostic())
+		}
+
+		// Oversized functions only warn unless complexity.block is set
+		stop = result.stage("complexity")
+		funcs, err := hooks.FindComplexFunctions(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Complexity check failed: %v\n", err)
+		}
+		var tooComplex []string
+		for _, f := range funcs {
+			if f.Limits.Block {
+				tooComplex = append(tooComplex, f.String())
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", f)
+			result.warnings = append(result.warnings, f.String())
+			result.diagnostics = append(result.diagnostics, f.Diagnostic())
+		}
+		if len(tooComplex) > 0 {
+			err := errors.New(strings.Join(tooComplex, "\n"))
+			fail("complexity", fmt.Sprintf("complexity check failed:\n%v", err), "complexity", err)
+		}
+
+		// Error handling patterns only warn unless go.errors.block is set
+		stop = result.stage("go errors")
+		errorIssues, err := hooks.FindGoErrorIssues(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Error handling check failed: %v\n", err)
+		}
+		var mishandled []string
+		for _, e := range errorIssues {
+			result.diagnostics = append(result.diagnostics, e.Diagnostic())
+			if e.Block {
+				mishandled = append(mishandled, e.String())
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", e)
+			result.warnings = append(result.warnings, e.String())
+		}
+		if len(mishandled) > 0 {
+			err := errors.New(strings.Join(mishandled, "\n"))
+			fail("go-errors", fmt.Sprintf("error handling check failed:\n%v", err), "go-errors", err)
+		}
+
+		// A syntactic scan without types, so its findings only warn
+		stop = result.stage("go concurrency")
+		hazards, err := hooks.FindConcurrencyHazards(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Concurrency check failed: %v\n", err)
+		}
+		for _, h := range hazards {
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", h)
+			result.warnings = append(result.warnings, h.String())
+			result.diagnostics = append(result.diagnostics, h.Diagnostic())
+		}
+
+		stop = result.stage("bundle size")
+		growth, err := hooks.FindBundleGrowth(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Bundle size check failed: %v\n", err)
+		}
+		for _, g := range growth {
+			if g.Block {
+				fail("bundle-size", fmt.Sprintf("bundle size check failed: %s", g), "bundle", errors.New(g.String()))
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", g)
+			result.warnings = append(result.warnings, g.String())
+		}
+
+		// Conflict markers and files merge tools leave behind are cheap to
+		// find and never meant to stay
+		stop = result.stage("merge artifacts")
+		artifacts, err := hooks.FindMergeArtifacts(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Merge artifact check failed: %v\n", err)
+		}
+		if len(artifacts) > 0 {
+			var lines []string
+			for _, a := range artifacts {
+				result.diagnostics = append(result.diagnostics, a.Diagnostic())
+				lines = append(lines, a.String())
+			}
+			err := errors.New(strings.Join(lines, "\n"))
+			fail("merge-artifacts", fmt.Sprintf("leftovers of a merge or patch:\n%v", err), "merge-artifacts", err)
+		}
+
+		// Stubs, elided code, and swallowed errors let an edit look done
+		// when it isn't; only what the edits added blocks
+		stop = result.stage("placeholders")
+		placeholders, err := hooks.FindPlaceholders(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Placeholder check failed: %v\n", err)
+		}
+		if len(placeholders) > 0 {
+			var lines []string
+			for _, p := range placeholders {
+				result.diagnostics = append(result.diagnostics, p.Diagnostic())
+				lines = append(lines, p.String())
+			}
+			err := errors.New(strings.Join(lines, "\n"))
+			fail("placeholders", fmt.Sprintf("placeholder code instead of an implementation:\n%v", err), "placeholders", err)
+		}
+
+		// semgrep.severities decides which findings block and which only warn
+		stop = result.stage("semgrep")
+		semgrepFindings, err := hooks.FindSemgrepFindings(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Semgrep scan failed: %v\n", err)
+		}
+		var insecure []string
+		for _, f := range semgrepFindings {
+			result.diagnostics = append(result.diagnostics, f.Diagnostic())
+			if f.Block {
+				insecure = append(insecure, f.String())
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", f)
+			result.warnings = append(result.warnings, f.String())
+		}
+		if len(insecure) > 0 {
+			err := errors.New(strings.Join(insecure, "\n"))
+			fail("semgrep", fmt.Sprintf("semgrep findings:\n%v", err), "semgrep", err)
+		}
+
+		// gosec and dependency audits; security.block decides which severities
+		// fail the edit
+		stop = result.stage("security")
+		securityFindings, err := hooks.FindSecurityIssues(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Security scan failed: %v\n", err)
+		}
+		var vulnerable []string
+		for _, f := range securityFindings {
+			result.diagnostics = append(result.diagnostics, f.Diagnostic())
+			if f.Block {
+				vulnerable = append(vulnerable, f.String())
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", f)
+			result.warnings = append(result.warnings, f.String())
+		}
+		if len(vulnerable) > 0 {
+			err := errors.New(strings.Join(vulnerable, "\n"))
+			fail("security", fmt.Sprintf("security findings:\n%v", err), "security", err)
+		}
+
+		// A rewrite that changes line endings or adds a BOM turns every line
+		// into a diff; encoding.fix puts them back instead of blocking
+		stop = result.stage("encoding")
+		changes, err := hooks.CheckEncoding(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Encoding check failed: %v\n", err)
+		}
+		var encoding []string
+		for _, c := range changes {
+			if c.Fixed {
+				fmt.Fprintf(os.Stderr, "🔧 %s\n", c)
+				result.notes = append(result.notes, c.String()+". Read the file again before editing it.")
+				continue
+			}
+			encoding = append(encoding, c.String())
+		}
+		if len(encoding) > 0 {
+			err := errors.New(strings.Join(encoding, "\n"))
+			fail("encoding", fmt.Sprintf("the edit changed how files are encoded:\n%v\n\nKeep each file's line endings, byte order mark, and UTF-8 as they were.", err), "encoding", err)
+		}
+
+		// Basics like indentation and final newlines only warn unless
+		// editorconfig.block is set
+		stop = result.stage("editorconfig")
+		violations, err := hooks.CheckEditorConfig(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  EditorConfig check failed: %v\n", err)
+		}
+		var broken []string
+		for _, v := range violations {
+			result.diagnostics = append(result.diagnostics, v.Diagnostic())
+			if v.Block {
+				broken = append(broken, v.String())
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", v)
+			result.warnings = append(result.warnings, v.String())
+		}
+		if len(broken) > 0 {
+			err := errors.New(strings.Join(broken, "\n"))
+			fail("editorconfig", fmt.Sprintf("editorconfig check failed:\n%v", err), "editorconfig", err)
+		}
+
+		// Unpinned images only warn unless images.block is set
+		stop = result.stage("images")
+		imageIssues, err := hooks.FindImageIssues(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Image check failed: %v\n", err)
+		}
+		var unpinned []string
+		for _, i := range imageIssues {
+			result.diagnostics = append(result.diagnostics, i.Diagnostic())
+			if i.Block {
+				unpinned = append(unpinned, i.String())
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", i)
+			result.warnings = append(result.warnings, i.String())
+		}
+		if len(unpinned) > 0 {
+			err := errors.New(strings.Join(unpinned, "\n"))
+			fail("images", fmt.Sprintf("image check failed:\n%v", err), "images", err)
+		}
+	}
+
+	// Only hooks have a plan that can declare the break; ci, watch, and lsp
+	// runs have no session
+	if hookType == "post-edit" && active.transcript != "" {
+		stop := result.stage("api compatibility")
+		changes, err := hooks.FindAPIBreaks(files, verbose)
+		stop()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  API compatibility check failed: %v\n", err)
+		}
+		if len(changes) > 0 && hooks.PlanDeclaresBreakingChange(active.transcript) {
+			fmt.Fprintf(os.Stderr, "⚠️  %d incompatible API change(s), declared as breaking by the plan\n", len(changes))
+		} else if len(changes) > 0 {
+			lines := make([]string, len(changes))
+			for i, c := range changes {
+				lines[i] = "- " + c.String()
+			}
+			err := errors.New(strings.Join(lines, "\n"))
+			fail("api-compatibility", fmt.Sprintf("incompatible changes to the exported API:\n%v\n\nRestore the API (add new identifiers instead of changing existing ones), or, if the break is intended, add a \"BREAKING CHANGE:\" line to the plan explaining it.", err), "apidiff", err)
+		}
+	}
+
+	// Packages that don't compile already failed go vet. The session is what
+	// remembers passing packages, so only hooks run the tests.
+	if hookType == "post-edit" && active.session != "" && !slices.Contains(result.failedRules, "go-post-edit") {
+		end := proc.Bound("go tests", 0)
+		stop := result.stage("go tests")
+		run, err := hooks.TestGoPackages(files, hooks.Session{Dir: active.project, ID: active.session, TranscriptPath: active.transcript}, verbose)
+		stop()
+		result.tests = run.Results
+		result.timedOut(files, end())
+		if err != nil {
+			fail("go-tests", fmt.Sprintf("go tests failed:\n%v", err), "go", err)
+		} else if passed := passedPackages(run.Results); len(passed) > 0 {
+			result.notes = append(result.notes, fmt.Sprintf("go tests passed: %s.", strings.Join(passed, ", ")))
+		}
+	}
+
+	if hookType == "post-edit" && len(files) > 0 {
+		stop := result.stage("fixes")
+		suggestFixes(files, &result, verbose)
+		stop()
+	}
+
+	return result
+}
+
+// embedCandidates returns the changed paths a //go:embed pattern could match:
+// every edited, deleted, or moved path that isn't Go source
+func embedCandidates(files []string, moves []hooks.FileMove, deleted []string) []string {
+	var paths []string
+	for _, m := range moves {
+		paths = append(paths, m.From, m.To)
+	}
+	return slices.DeleteFunc(slices.Concat(files, deleted, paths), func(p string) bool { return filepath.Ext(p) == ".go" })
+}
+
+// passedPackages returns the sorted packages of results that passed
+func passedPackages(results map[string]bool) []string {
+	var passed []string
+	for _, pkg := range slices.Sorted(maps.Keys(results)) {
+		if results[pkg] {
+			passed = append(passed, pkg)
+		}
+	}
+	return passed
+}
+
+// suggestFixes adds the tools' patches to the result's diagnostics and reason.
+// Fixers are slow and change nothing for a passing edit, so they only run for
+// a blocked one, unless fixes.patch collects the fixes of every edit.
+func suggestFixes(files []string, result *pipelineResult, verbose bool) {
+	cfg, err := config.Load(filepath.Dir(files[0]))
+	if err != nil {
+		cfg = config.Default()
+	}
+	blocked := len(result.errorMessages) > 0
+	if !blocked && !cfg.Fixes.Patch {
+		return
+	}
+
+	diagnostics, err := hooks.SuggestFixes(files, result.diagnostics, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Fix suggestions failed: %v\n", err)
+	}
+	result.diagnostics = diagnostics
+	result.fixes = hooks.FormatFixes(diagnostics)
+
+	path, hunks, err := hooks.WriteFixPatch(files, diagnostics, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not write the fix patch: %v\n", err)
+		return
+	}
+	if hunks == 0 {
+		return
+	}
+	note := fmt.Sprintf("%d fixes and formatting changes for the edited files are in %s; `%s apply-fixes` applies them all.", hunks, path, reason.Command)
+	if blocked {
+		result.fixes = strings.TrimSpace(result.fixes + "\n\n" + note)
+	} else {
+		fmt.Fprintf(os.Stderr, "🔧 %s\n", note)
+		result.warnings = append(result.warnings, note)
+	}
+}
+
+// toolEdits returns the changes an Edit, MultiEdit, or Write makes
+func toolEdits(input Input) []policy.Edit {
+	path := input.ToolInput.FilePath
+	var edits []policy.Edit
+	switch {
+	case len(input.ToolInput.Edits) > 0:
+		for _, e := range input.ToolInput.Edits {
+			edits = append(edits, policy.Edit{Path: path, Old: e.OldString, New: e.NewString})
+		}
+	case input.ToolInput.OldString != "" || input.ToolInput.NewString != "":
+		edits = append(edits, policy.Edit{Path: path, Old: input.ToolInput.OldString, New: input.ToolInput.NewString})
+	case input.ToolName == "Write":
+		existing, _ := os.ReadFile(path) // A new file replaces nothing
+		edits = append(edits, policy.Edit{Path: path, Old: string(existing), New: input.ToolInput.Content})
+	}
+	return edits
+}
+
+// enforcePolicies has the policies of policy.engine decide hook in.Hook and,
+// when one denies, asks, or blocks, writes its decision and exits. Policies
+// run from the directory of the config defining them.
+func enforcePolicies(cfg *config.Config, in policy.Input, verbose bool) {
+	if !policy.Applies(cfg.Policy, in.Hook) {
+		return
+	}
+	dir := active.project
+	if cfg.Path != "" {
+		dir = filepath.Dir(cfg.Path)
+	}
+	in.Session, in.Project, in.Profile = active.session, active.project, active.profile
+	if active.agentID != "" {
+		in.Agent = &policy.AgentInput{ID: active.agentID, Type: active.agentType}
+	}
+	if in.Session != "" {
+		if sessions, err := guardrails.List(active.project); err == nil {
+			if counters, ok := sessions[in.Session]; ok {
+				in.State = &counters
+			}
+		}
+	}
+
+	d, err := policy.Decide(cfg.Policy, cfg.Content, dir, in)
+	exitIfInterrupted(audit.Event{Hook: in.Hook}, verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+		if !cfg.Policy.FailClosed {
+			return
+		}
+		d = &policy.Decision{Decision: policy.Deny, Rule: "unavailable", Reason: fmt.Sprintf("The policies couldn't be evaluated, and policy.fail_closed is set: %v", err)}
+	}
+	if d == nil {
+		vlog.Printf(verbose, "✅ Policies allow the %s\n", in.Hook)
+		return
+	}
+
+	reason := d.Reason
+	if reason == "" {
+		reason = fmt.Sprintf("The %s policy doesn't allow this.", d.Rule)
+	}
+	decision := d.Decision
+	if in.Hook == "stop" {
+		decision = "block"
+		writeBlockDecision("Stop", reason)
+	} else {
+		if decision == "block" {
+			decision = policy.Deny
+		}
+		writePreToolUseDecision(decision, reason)
+	}
+	fmt.Fprintf(os.Stderr, "❌ BLOCKED by policy %s: %s\n", d.Rule, reason)
+	recordAudit(audit.Event{Hook: in.Hook, Decision: decision, Rule: "policy:" + d.Rule}, verbose)
+	os.Exit(0) // Exit successfully since we provided JSON
+}
+
+// agentName names the calling subagent in denial reasons
+func agentName() string {
+	if active.agentType == "" {
+		return "This subagent"
+	}
+	return fmt.Sprintf("The %s subagent", active.agentType)
+}
+
+// denyAgent denies what the agents config doesn't let the calling subagent do
+func denyAgent(hook, rule, reason string, verbose bool) {
+	writePreToolUseDecision("deny", reason)
+	fmt.Fprintf(os.Stderr, "❌ BLOCKED: %s\n", reason)
+	recordAudit(audit.Event{Hook: hook, Decision: "deny", Rule: rule}, verbose)
+	os.Exit(0) // Exit successfully since we provided JSON
+}
+
+// checkEditContent denies an edit policy.engine's policies deny or ask
+// about, whose new text introduces code the content policy blocks, or a
+// Write of an oversized, binary, or base64 file. It returns when the edit is
+// allowed, with warnings for Claude such as a Write rewriting most of an
+// existing file.
+func checkEditContent(input Input, verbose bool) (warnings []string) {
+	path := input.ToolInput.FilePath
+	if path == "" {
+		return nil
+	}
+	cfg, err := config.Load(filepath.Dir(path))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, using defaults: %v\n", err)
+		cfg = config.Default()
+	}
+
+	edits := toolEdits(input)
+	enforcePolicies(cfg, policy.Input{Hook: "pre-edit", Tool: input.ToolName, Cwd: input.Cwd, Edits: edits}, verbose)
+	if !cfg.Content.Enabled {
+		return nil
+	}
+
+	root := state.ProjectRoot(filepath.Dir(path))
+	violations := policy.CheckEdits(cfg.Content, root, edits)
+	var rewrite *policy.Rewrite
+	if input.ToolName == "Write" {
+		violations = append(violations, policy.CheckWrite(cfg.Content, root, path, input.ToolInput.Content)...)
+		if r, ok := policy.CheckRewrite(cfg.Content, path, edits[0].Old, input.ToolInput.Content); ok && r.Block {
+			violations = append(violations, policy.ContentViolation{
+				Rule:   "whole-file-rewrite",
+				Path:   path,
+				Reason: fmt.Sprintf("rewrites %d of the %d lines of a file of %d bytes (limit %d); use Edit to change only what the task needs", r.Changed, r.Lines, len(edits[0].Old), cfg.Content.BlockRewriteSize),
+			})
+		} else if ok {
+			rewrite = &r
+		}
+	}
+	if len(violations) == 0 {
+		if rewrite != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", rewrite)
+			recordAudit(audit.Event{Hook: "pre-edit", Decision: "allow", Rule: "content:whole-file-rewrite"}, verbose)
+			return []string{rewrite.String() + "."}
+		}
+		if verbose && len(edits) > 0 {
+			fmt.Fprintf(os.Stderr, "✅ Edit content passed the content policy\n")
+		}
+		return nil
+	}
+
+	var list strings.Builder
+	var rules []string
+	for _, v := range violations {
+		fmt.Fprintf(&list, "- %s\n", v)
+		if !slices.Contains(rules, v.Rule) {
+			rules = append(rules, v.Rule)
+		}
+	}
+	reason := fmt.Sprintf("This edit was blocked because it introduces code the content policy doesn't allow:\n%s\nRewrite the change without it. If it is really needed, ask the user; they can disable a rule under content.disabled in .claude-hooks.yaml.", list.String())
+	writePreToolUseDecision("deny", reason)
+
+	fmt.Fprintf(os.Stderr, "❌ BLOCKED: Edit introduces code the content policy doesn't allow\n")
+	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(os.Stderr, "%s", list.String())
+
+	recordAudit(audit.Event{Hook: "pre-edit", Decision: "deny", Rule: "content:" + strings.Join(rules, ",")}, verbose)
+	os.Exit(0) // Exit successfully since we provided JSON
+	return nil
+}
+
+func handlePreBashBlocking(input Input, verbose bool) {
+	// Check if this is a Bash tool call
+	if input.ToolName != "Bash" && input.ToolName != "bash" {
+		if verbose {
+			fmt.Printf("Tool %s is not Bash, allowing\n", input.ToolName)
+		}
+		os.Exit(0)
+	}
+
+	command := input.ToolInput.Command
+	if command == "" {
+		if verbose {
+			fmt.Println("No command found in input, allowing")
+		}
+		os.Exit(0)
+	}
+
+	dir, cfg := loadBashConfig(input, verbose)
+	rules := cfg.Bash.Rules
+	var policyContext *policy.Context
+	if len(rules) > 0 {
+		policyContext = policy.LoadContext(dir)
+	}
+	overBudget := recordSessionActivity(input, dir, cfg.Guardrails.Session, func(c *guardrails.Counters) { c.Commands++ }, verbose)
+
+	enforcePolicies(cfg, policy.Input{Hook: "pre-bash", Tool: input.ToolName, Cwd: input.Cwd, Command: policy.NewCommandInput(command)}, verbose)
+
+	// Check every command the line runs, including those in substitutions,
+	// subshells, sh -c scripts, and behind wrappers like env or sudo
+	subCommands := shell.Split(command)
+
+	// Subagents may be limited to reading, or to not writing history
+	if active.agent.ReadOnly() {
+		if sub := policy.ReadOnly(command); sub != "" {
+			denyAgent("pre-bash", "agent:read-only", fmt.Sprintf("%s may only run commands that read (agents config), and this one could change something: %s\n\nUse read-only commands like ls, grep, git log, or git diff, and leave changes to the main agent.", agentName(), sub), verbose)
+		}
+	}
+	if !active.agent.CanCommit() {
+		for _, sub := range subCommands {
+			if _, words := shell.Unwrap(shell.Words(sub)); vcs.WritesHistory(words) {
+				denyAgent("pre-bash", "agent:commit", fmt.Sprintf("%s can't commit, push, merge, rebase, or tag (agents config): %s\n\nLeave the changes in the working tree for the main agent to commit.", agentName(), sub), verbose)
+			}
+		}
+	}
+
+	// Nobody is watching an unattended session to catch a destructive command
+	if active.profile == profile.Unattended {
+		if rule := profile.Denied(command, subCommands); rule != nil {
+			reason := fmt.Sprintf("%s, so the unattended profile doesn't allow it. You attempted to run: %s\n\nFind a reversible alternative, or stop and leave this step to the user.", rule.Reason, command)
+			writePreToolUseDecision("deny", reason)
+
+			fmt.Fprintf(os.Stderr, "❌ BLOCKED by the unattended profile (%s)\n", rule.Name)
+			fmt.Fprintf(os.Stderr, "\n")
+			fmt.Fprintf(os.Stderr, "You attempted to run: %s\n", command)
+
+			recordAudit(audit.Event{Hook: "pre-bash", Decision: "deny", Rule: "unattended:" + rule.Name}, verbose)
+			os.Exit(0) // Exit successfully since we provided JSON
+		}
+	}
+
+	for _, subCmd := range subCommands {
+		parts := shell.Words(subCmd)
+		if len(parts) > 0 {
+			executable := strings.ToLower(filepath.Base(parts[0]))
+
+			// Check if the executable itself is a MySQL/MariaDB command
+			if executable == "mysql" || executable == "mysqldump" || executable == "mariadb" {
+				reason := fmt.Sprintf("MySQL commands are not allowed. You attempted to run: %s\n\nDetected MySQL command in: %s\n\nPlease use the Go database connection methods instead. The codebase already has database access configured through Go.\n\nAlternatives:\n- Check existing Go code for database queries\n- Look at the model definitions in the codebase\n- Read the existing test files for schema information", command, subCmd)
+
+				// Output JSON to stdout for Claude
+				writePreToolUseDecision("deny", reason)
+
+				// Also output user-friendly message to stderr
+				fmt.Fprintf(os.Stderr, "❌ BLOCKED: MySQL commands are not allowed\n")
+				fmt.Fprintf(os.Stderr, "\n")
+				fmt.Fprintf(os.Stderr, "You attempted to run: %s\n", command)
+				fmt.Fprintf(os.Stderr, "Detected MySQL command in: %s\n", subCmd)
+				fmt.Fprintf(os.Stderr, "\n")
+				fmt.Fprintf(os.Stderr, "Please use the Go database connection methods instead.\n")
+				fmt.Fprintf(os.Stderr, "The codebase already has database access configured through Go.\n")
+				fmt.Fprintf(os.Stderr, "\n")
+				fmt.Fprintf(os.Stderr, "Alternatives:\n")
+				fmt.Fprintf(os.Stderr, "- Check existing Go code for database queries\n")
+				fmt.Fprintf(os.Stderr, "- Look at the model definitions in the codebase\n")
+				fmt.Fprintf(os.Stderr, "- Read the existing test files for schema information\n")
+
+				recordAudit(audit.Event{Hook: "pre-bash", Decision: "deny", Rule: "mysql-cli"}, verbose)
+				os.Exit(0) // Exit successfully since we provided JSON
+			}
+
+			// Check the project's environment-aware rules, e.g. psql is fine against
+			// localhost but not with a production connection string
+			if len(rules) > 0 {
+				verdict, err := policy.Evaluate(rules, subCmd, policyContext)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "⚠️  Skipping invalid rule: %v\n", err)
+				}
+				if verdict != nil && verdict.Decision != policy.Allow {
+					reason := verdict.Rule.Reason
+					if reason == "" {
+						reason = fmt.Sprintf("This command is not allowed in the current environment (bash rule %q).", verdict.Name())
+					}
+					reason = fmt.Sprintf("%s You attempted to run: %s\n\nDetected in: %s\n\nMatched: %s", reason, command, subCmd, strings.Join(verdict.Matched, ", "))
+					writePreToolUseDecision(verdict.Decision, reason)
+
+					if verdict.Decision == policy.Ask {
+						fmt.Fprintf(os.Stderr, "⚠️  Confirmation required by bash rule %q\n", verdict.Name())
+					} else {
+						fmt.Fprintf(os.Stderr, "❌ BLOCKED by bash rule %q\n", verdict.Name())
+					}
+					fmt.Fprintf(os.Stderr, "\n")
+					fmt.Fprintf(os.Stderr, "You attempted to run: %s\n", command)
+					fmt.Fprintf(os.Stderr, "Matched: %s\n", strings.Join(verdict.Matched, ", "))
+
+					recordAudit(audit.Event{Hook: "pre-bash", Decision: verdict.Decision, Rule: "bash-rule:" + verdict.Name()}, verbose)
+					os.Exit(0) // Exit successfully since we provided JSON
+				} else if verdict != nil && verbose {
+					fmt.Fprintf(os.Stderr, "✅ Bash rule %q allows: %s\n", verdict.Name(), subCmd)
+				}
+			}
+
+			// Deploys outside working hours would land when nobody is around to watch them
+			if window := cfg.Guardrails.DeployWindow; window.Hours != "" {
+				pattern, err := guardrails.DeployPattern(window, subCmd)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+				}
+				open, err := guardrails.InWindow(window, time.Now())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+					open = true
+				}
+				if pattern != "" && !open {
+					days := "every day"
+					if len(window.Days) > 0 {
+						days = strings.Join(window.Days, ", ")
 					}
+					reason := fmt.Sprintf("Deploy-like commands are only allowed %s (%s). You attempted to run: %s\n\nDetected in: %s\n\nWait for the deploy window, or ask the user to run it themselves.", window.Hours, days, command, subCmd)
+					writePreToolUseDecision("deny", reason)
+
+					fmt.Fprintf(os.Stderr, "❌ BLOCKED: Deploys are only allowed %s (%s)\n", window.Hours, days)
+					fmt.Fprintf(os.Stderr, "\n")
+					fmt.Fprintf(os.Stderr, "You attempted to run: %s\n", command)
+
+					recordAudit(audit.Event{Hook: "pre-bash", Decision: "deny", Rule: "deploy-window"}, verbose)
+					os.Exit(0) // Exit successfully since we provided JSON
+				}
+			}
+
+			// Check if the command commits to a protected branch: a git or hg commit
+			// on it, or a jj bookmark set, create, or move of it
+			if (executable == vcs.Git && len(parts) >= 2 && parts[1] == "commit") || executable == vcs.HG || executable == vcs.JJ {
+				vlog.Printf(verbose, "🔍 Detected %s command, checking branch protection...\n", executable)
+
+				// Determine the target working directory for the branch check
+				targetDir := getTargetWorkingDirectory(input, verbose)
+
+				// Skip protection if we can't confidently determine the target directory
+				if targetDir == "" {
+					vlog.Printf(verbose, "✅ Skipping branch protection check - cannot determine target project directory\n")
+					// Allow the command to proceed by continuing to next subcommand
+					continue
+				}
 
-					jsonOutput, err := json.Marshal(output)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "Failed to marshal JSON output: %v\n", err)
-						os.Exit(1)
-					}
+				var targets []string
+				if repo := vcs.ForCommand(executable, targetDir); repo != nil {
+					targets = repo.CommitTargets(parts)
+				}
+				vlog.Printf(verbose, "🔍 Checking if %q is protected...\n", targets)
+				if i := slices.IndexFunc(targets, isProtectedBranch); i >= 0 {
+					currentBranch := targets[i]
+					vlog.Printf(verbose, "🚫 Branch %q is protected - blocking commit\n", currentBranch)
+					steps := featureBranchSteps(executable, currentBranch)
+					reason := fmt.Sprintf("Direct commits to the '%s' branch are not allowed. You attempted to run: %s\n\nDetected %s commit command in: %s\n\nPlease create a feature branch instead:\n\n%s", currentBranch, command, executable, subCmd, steps)
 
 					// Output JSON to stdout for Claude
-					fmt.Println(string(jsonOutput))
+					writePreToolUseDecision("deny", reason)
 
 					// Also output user-friendly message to stderr
 					fmt.Fprintf(os.Stderr, "❌ BLOCKED: Direct commits to '%s' branch are not allowed\n", currentBranch)
 					fmt.Fprintf(os.Stderr, "\n")
 					fmt.Fprintf(os.Stderr, "You attempted to run: %s\n", command)
-					fmt.Fprintf(os.Stderr, "Detected git commit in: %s\n", subCmd)
+					fmt.Fprintf(os.Stderr, "Detected %s commit in: %s\n", executable, subCmd)
 					fmt.Fprintf(os.Stderr, "\n")
 					fmt.Fprintf(os.Stderr, "Please create a feature branch instead:\n")
 					fmt.Fprintf(os.Stderr, "\n")
-					fmt.Fprintf(os.Stderr, "1. Create and switch to a new branch:\n")
-					fmt.Fprintf(os.Stderr, "   git checkout -b feature/your-feature-name\n")
-					fmt.Fprintf(os.Stderr, "\n")
-					fmt.Fprintf(os.Stderr, "2. Make your commits on the feature branch:\n")
-					fmt.Fprintf(os.Stderr, "   git commit -m \"your commit message\"\n")
-					fmt.Fprintf(os.Stderr, "\n")
-					fmt.Fprintf(os.Stderr, "3. Push the feature branch:\n")
-					fmt.Fprintf(os.Stderr, "   git push -u origin feature/your-feature-name\n")
-					fmt.Fprintf(os.Stderr, "\n")
-					fmt.Fprintf(os.Stderr, "4. Create a pull request to merge into %s\n", currentBranch)
+					fmt.Fprintf(os.Stderr, "%s\n", steps)
 
+					recordAudit(audit.Event{Hook: "pre-bash", Decision: "deny", Rule: "protected-branch"}, verbose)
 					os.Exit(0) // Exit successfully since we provided JSON
 				} else if verbose {
-					if currentBranch == "" {
-						fmt.Fprintf(os.Stderr, "✅ Not in a git repo or detached HEAD - allowing commit\n")
+					if len(targets) == 0 {
+						fmt.Fprintf(os.Stderr, "✅ Not in a repository, detached HEAD, or not a commit - allowing command\n")
 					} else {
-						fmt.Fprintf(os.Stderr, "✅ Branch %q is not protected - allowing commit\n", currentBranch)
+						fmt.Fprintf(os.Stderr, "✅ %q not protected - allowing commit\n", targets)
+					}
+				}
+			}
+
+			// Scan outgoing commits for secrets, oversized files, and disallowed paths before a push
+			if push, ok := vcs.ParseGitPush(parts); ok {
+				vlog.Printf(verbose, "🔍 Detected git push command, scanning outgoing commits...\n")
+
+				targetDir := getTargetWorkingDirectory(input, verbose)
+				if targetDir == "" {
+					vlog.Printf(verbose, "✅ Skipping push scan - cannot determine target project directory\n")
+					continue
+				}
+				// git -C other push pushes from other
+				if filepath.IsAbs(push.Dir) {
+					targetDir = push.Dir
+				} else if push.Dir != "" {
+					targetDir = filepath.Join(targetDir, push.Dir)
+				}
+
+				cfg, err := config.Load(targetDir)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, using defaults: %v\n", err)
+					cfg = config.Default()
+				}
+				if !cfg.Push.Enabled {
+					continue
+				}
+
+				findings, err := hooks.ScanOutgoingCommits(targetDir, push, cfg.Push, verbose)
+				exitIfInterrupted(audit.Event{Hook: "pre-bash"}, verbose)
+				if err != nil {
+					vlog.Printf(verbose, "⚠️  Skipping push scan: %v\n", err)
+					continue
+				}
+
+				if len(findings) > 0 {
+					var list strings.Builder
+					secret := false
+					for _, f := range findings {
+						fmt.Fprintf(&list, "- %s\n", f)
+						secret = secret || f.Kind == hooks.SecretFinding
 					}
+
+					reason := fmt.Sprintf("This push was blocked because the outgoing commits contain files that should not be pushed. You attempted to run: %s\n\nDetected git push command in: %s\n\nProblems found:\n%s\nPlease fix the offending commits before pushing:\n- Remove secrets and rotate any credential that was committed\n- Keep large binaries out of git (use releases or an artifact store)\n- Add disallowed files to .gitignore and remove them from history (git rebase -i or git commit --amend)", command, subCmd, list.String())
+
+					// Output JSON to stdout for Claude
+					writePreToolUseDecision("deny", reason)
+
+					// Also output user-friendly message to stderr
+					fmt.Fprintf(os.Stderr, "❌ BLOCKED: Outgoing commits failed the pre-push scan\n")
+					fmt.Fprintf(os.Stderr, "\n")
+					fmt.Fprintf(os.Stderr, "You attempted to run: %s\n", command)
+					fmt.Fprintf(os.Stderr, "\n")
+					fmt.Fprintf(os.Stderr, "Problems found:\n%s", list.String())
+
+					recordAudit(audit.Event{Hook: "pre-bash", Decision: "deny", Rule: "pre-push", Secret: secret, Detail: command + "\n" + list.String()}, verbose)
+					os.Exit(0) // Exit successfully since we provided JSON
+				} else {
+					vlog.Printf(verbose, "✅ Outgoing commits look clean - allowing push\n")
 				}
 			}
 		}
 	}
 
+	// Past a session ceiling every command needs a human to approve it
+	if len(overBudget) > 0 {
+		writePreToolUseDecision("ask", budgetExceededMessage(input.SessionID, overBudget))
+		fmt.Fprintf(os.Stderr, "⚠️  Session budget exceeded: %s\n", strings.Join(overBudget, ", "))
+		recordAudit(audit.Event{Hook: "pre-bash", Decision: "ask", Rule: "session-budget"}, verbose)
+		os.Exit(0)
+	}
+
+	// Risky commands get a snapshot first, so the user can undo them in one step
+	if cfg.Rollback.Enabled && dir != "" {
+		snapshotBeforeRisky(dir, command, subCommands, cfg.Rollback, verbose)
+	}
+
 	if verbose {
 		fmt.Printf("Command '%s' is allowed\n", command)
 	}
 
 	// Command is allowed
+	recordAudit(audit.Event{Hook: "pre-bash", Decision: "allow"}, verbose)
 	os.Exit(0)
 }
 
+// snapshotBeforeRisky saves a rollback bundle when any sub-command is risky and
+// tells the user how to restore it. Failures only warn: a missing snapshot is no
+// reason to stop a command the rest of the checks allowed.
+func snapshotBeforeRisky(dir, command string, subCommands []string, cfg config.RollbackConfig, verbose bool) {
+	risky := ""
+	for _, subCmd := range subCommands {
+		pattern, err := rollback.RiskyPattern(cfg, subCmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			return
+		}
+		if pattern != "" {
+			risky = subCmd
+			break
+		}
+	}
+	if risky == "" {
+		return
+	}
+	// A snapshot writes refs and objects into the repository
+	if config.ReadOnly() {
+		vlog.Printf(verbose, "🔒 Read-only, no snapshot before %q\n", risky)
+		return
+	}
+
+	bundle, err := rollback.Create(dir, command, cfg.MaxUntrackedSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not snapshot before %q: %v\n", risky, err)
+		return
+	}
+	if err := rollback.Prune(dir, cfg.Keep); err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not prune old snapshots: %v\n", err)
+	}
+
+	message := fmt.Sprintf("📦 Snapshot %s saved before: %s\n   Undo with: claude-hook rollback %s", bundle.ID, risky, bundle.ID)
+	fmt.Fprintln(os.Stderr, message)
+	jsonOutput, err := json.Marshal(SystemMessageOutput{SystemMessage: message})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to marshal JSON output: %v\n", err)
+		return
+	}
+	fmt.Println(string(jsonOutput))
+}
+
+// loadBashConfig returns the directory a Bash command runs in and the project
+// config there, falling back to defaults when either can't be determined
+func loadBashConfig(input Input, verbose bool) (string, *config.Config) {
+	dir := input.Cwd
+	if dir == "" {
+		dir = getTargetWorkingDirectory(input, verbose)
+	}
+	if dir == "" {
+		return "", config.Default()
+	}
+
+	cfg, err := config.Load(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, using defaults: %v\n", err)
+		return dir, config.Default()
+	}
+	return dir, cfg
+}
+
+// recordSessionActivity updates the session's guardrail counters and returns
+// the ceilings now exceeded. Sessions without ceilings aren't tracked.
+func recordSessionActivity(input Input, dir string, budget config.SessionBudgetConfig, change func(*guardrails.Counters), verbose bool) []string {
+	if !guardrails.Enabled(budget) || input.SessionID == "" || dir == "" {
+		return nil
+	}
+	counters, err := guardrails.Update(guardrails.Session{Dir: dir, ID: input.SessionID, TranscriptPath: input.TranscriptPath}, change)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not update session budget: %v\n", err)
+		return nil
+	}
+	vlog.Printf(verbose, "📊 Session %s: %d files, %d commands, %d API calls, $%.2f\n",
+		input.SessionID, len(counters.Files), counters.Commands, counters.APICalls, counters.SpendUSD)
+	return guardrails.Exceeded(budget, counters)
+}
+
+// recordOutstanding remembers the checks that failed on the directories of
+// files, clearing those that pass now, for handleUserPrompt to remind Claude of
+func recordOutstanding(files, failedRules []string, verbose bool) {
+	if active.session == "" || len(files) == 0 {
+		return
+	}
+	var dirs []string
+	for _, f := range files {
+		dir, err := filepath.Rel(active.project, filepath.Dir(f))
+		if err != nil || strings.HasPrefix(dir, "..") {
+			dir = filepath.Dir(f)
+		}
+		if dir = filepath.ToSlash(dir); !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	rules := slices.DeleteFunc(slices.Clone(failedRules), func(rule string) bool { return rule == "session-budget" })
+	if err := reason.RecordFailures(reason.Session{Dir: active.project, ID: active.session, TranscriptPath: active.transcript}, dirs, rules); err != nil {
+		vlog.Printf(verbose, "⚠️  Could not record outstanding failures: %v\n", err)
+	}
+}
+
+// handleUserPrompt reminds Claude, as the user's next prompt arrives, of the
+// checks its earlier edits left failing, so a change of topic or a compaction
+// doesn't leave them behind
+func handleUserPrompt(verbose bool) {
+	if active.session == "" {
+		return
+	}
+	failures, err := reason.Outstanding(reason.Session{Dir: active.project, ID: active.session, TranscriptPath: active.transcript})
+	if err != nil {
+		vlog.Printf(verbose, "⚠️  Could not read outstanding failures: %v\n", err)
+		return
+	}
+	reminder := reason.Reminder(failures)
+	if reminder == "" {
+		vlog.Printf(verbose, "✅ No outstanding failures\n")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "📌 %s\n", reminder)
+	writeHookOutput(HookOutput{
+		SuppressOutput: true,
+		HookSpecificOutput: &PostToolUseHookOutput{
+			HookEventName:     "UserPromptSubmit",
+			AdditionalContext: reminder,
+		},
+	})
+}
+
+// recordEdits counts the files a post-edit call modified against the session budget
+func recordEdits(input Input, files, deleted []string, verbose bool) []string {
+	dir := input.Cwd
+	if dir == "" && len(files) > 0 {
+		dir = filepath.Dir(files[0])
+	}
+	if dir == "" {
+		return nil
+	}
+	cfg, err := config.Load(dir)
+	if err != nil {
+		return nil
+	}
+	record := func(c *guardrails.Counters) {
+		c.AddFiles(files...)
+		c.AddFiles(deleted...)
+	}
+	// The changelog rule reads the session's files at Stop, so they're tracked
+	// for it even without a budget
+	if cfg.Guardrails.Changelog.Enabled && !guardrails.Enabled(cfg.Guardrails.Session) && input.SessionID != "" {
+		if _, err := guardrails.Update(guardrails.Session{Dir: dir, ID: input.SessionID, TranscriptPath: input.TranscriptPath}, record); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not record session files: %v\n", err)
+		}
+		return nil
+	}
+	return recordSessionActivity(input, dir, cfg.Guardrails.Session, record, verbose)
+}
+
+// checkLoops counts a post-edit block against the session and, when a file
+// keeps being blocked for the same rule, returns guidance to put first in the
+// reason. A new loop is also analyzed by a model and sent to the webhook when
+// loops.analyze and loops.notify are set.
+func checkLoops(input Input, files []string, result pipelineResult, verbose bool) string {
+	if input.SessionID == "" || len(files) == 0 {
+		return ""
+	}
+	dir := input.Cwd
+	if dir == "" {
+		dir = filepath.Dir(files[0])
+	}
+	cfg, err := config.Load(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, skipping loop detection: %v\n", err)
+		return ""
+	}
+	rules := slices.DeleteFunc(slices.Clone(result.failedRules), func(rule string) bool { return rule == "session-budget" })
+	session := guardrails.Session{Dir: dir, ID: input.SessionID, TranscriptPath: input.TranscriptPath}
+	loops, err := guardrails.RecordBlocks(cfg.Guardrails.Loops, session, rules, files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not record blocks: %v\n", err)
+		return ""
+	}
+	if len(loops) == 0 {
+		return ""
+	}
+	fmt.Fprintf(os.Stderr, "🔁 Loop detected: blocked more than %d times for the same rule\n", cfg.Guardrails.Loops.MaxBlocks)
+	guidance := guardrails.LoopGuidance(cfg.Guardrails.Loops, loops)
+
+	// Analysis and notification happen once per loop, not on every block of it
+	var fresh []string
+	for _, l := range loops {
+		if l.New {
+			fresh = append(fresh, fmt.Sprintf("%s (%s, %d blocks)", l.File, l.Rule, l.Blocks))
+		}
+	}
+	if len(fresh) == 0 {
+		return guidance
+	}
+	if cfg.Guardrails.Loops.Analyze {
+		var loopFiles []string
+		for _, l := range loops {
+			if l.New && !slices.Contains(loopFiles, l.File) {
+				loopFiles = append(loopFiles, l.File)
+			}
+		}
+		analysis, calls, err := hooks.AnalyzeLoop(active.project, loopFiles, strings.Join(result.errorMessages, "\n\n"), verbose)
+		budget := cfg.Guardrails.Session
+		recordSessionActivity(input, dir, budget, func(c *guardrails.Counters) {
+			c.APICalls += calls
+			c.SpendUSD += float64(calls) * budget.CostPerReviewUSD
+		}, verbose)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Loop analysis failed: %v\n", err)
+		} else {
+			guidance += "\n\nA second opinion on why it keeps failing:\n" + analysis
+		}
+	}
+	if url := notify.WebhookURL(cfg.Unattended); cfg.Guardrails.Loops.Notify && url != "" && !active.offline {
+		err := notify.Send(url, notify.Event{
+			Hook:     "post-edit",
+			Decision: "block",
+			Rule:     "loop",
+			Session:  input.SessionID,
+			Project:  active.project,
+			Profile:  active.profile,
+			Message:  "Claude keeps getting blocked on " + strings.Join(fresh, ", "),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not send webhook notification: %v\n", err)
+		} else {
+			vlog.Printf(verbose, "📣 Sent loop notification to webhook\n")
+		}
+	}
+	return guidance
+}
+
+// budgetExceededMessage tells Claude to hand control back to the user
+func budgetExceededMessage(sessionID string, over []string) string {
+	return fmt.Sprintf("Session budget exceeded: %s.\n\nStop and ask the user before continuing. They can raise the limits under guardrails.session in .claude-hooks.yaml or reset this session's counters with `claude-hook budget -reset %s`.", strings.Join(over, ", "), sessionID)
+}
+
+// writePreToolUseDecision prints the PreToolUse JSON response for Claude
+func writePreToolUseDecision(decision, reason string) {
+	if blocking(decision) && relaxed("PreToolUse", reason) {
+		return
+	}
+	output := PreToolUseOutput{
+		HookSpecificOutput: PreToolUseHookOutput{
+			HookEventName:            "PreToolUse",
+			PermissionDecision:       decision,
+			PermissionDecisionReason: reason,
+		},
+	}
+
+	jsonOutput, err := json.Marshal(output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to marshal JSON output: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(jsonOutput))
+}
+
+// writeBlockDecision prints the JSON response blocking a PostToolUse or Stop
+// event for Claude
+func writeBlockDecision(event, reason string) {
+	if relaxed(event, reason) {
+		return
+	}
+	writeHookOutput(HookOutput{Decision: "block", Reason: reason})
+}
+
+// writeHookOutput prints the JSON response of a PostToolUse or Stop hook
+func writeHookOutput(output HookOutput) {
+	jsonOutput, err := json.Marshal(output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to marshal JSON output: %v\n", err)
+		os.Exit(2)
+	}
+	fmt.Println(string(jsonOutput))
+}
+
+// blocking tells whether a decision stops Claude
+func blocking(decision string) bool {
+	return decision == "block" || decision == "deny" || decision == "ask"
+}
+
+// relaxed reports a decision stopping Claude instead of making it when the
+// enforcement is warn or dry-run, and tells whether it did
+func relaxed(event, reason string) bool {
+	switch active.enforcement {
+	case config.EnforceWarn:
+		fmt.Fprintf(os.Stderr, "⚠️  Would block (enforcement: warn)\n")
+		var output any = SystemMessageOutput{SystemMessage: "⚠️  Would block (enforcement: warn): " + reason}
+		if event == "PostToolUse" {
+			output = HookOutput{HookSpecificOutput: &PostToolUseHookOutput{HookEventName: event, AdditionalContext: reason}}
+		}
+		jsonOutput, err := json.Marshal(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to marshal JSON output: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(jsonOutput))
+	case config.EnforceDryRun:
+		fmt.Fprintf(os.Stderr, "🔍 Dry run, would block:\n%s\n", reason)
+		vlog.Logf("dry run, would block: %s\n", reason)
+	default:
+		return false
+	}
+	return true
+}
+
 // handlePlanReview runs the plan through multiple AI models for feedback
-func handlePlanReview(input Input, verbose bool) {
+func handlePlanReview(input Input, minSeverity string, verbose bool) {
 	// Always log to stderr so we can see if the hook is being called
 	fmt.Fprintf(os.Stderr, "🧠 AI Council hook triggered!\n")
 	fmt.Fprintf(os.Stderr, "   Tool: %s\n", input.ToolName)
@@ -595,14 +4176,48 @@ func handlePlanReview(input Input, verbose bool) {
 		os.Exit(0)
 	}
 
+	if active.offline {
+		fmt.Fprintf(os.Stderr, "⏭️  Skipping plan review: offline\n")
+		if active.profile == profile.Unattended {
+			writePreToolUseDecision("deny", "The plan could not be reviewed offline, and the unattended profile requires an approving review before implementation starts.")
+			recordAudit(audit.Event{Hook: "plan-review", Decision: "deny", Rule: "unattended:review-failed"}, verbose)
+		}
+		os.Exit(0)
+	}
+
+	// Reviews call paid APIs, so an exhausted session budget hands the plan to the user
+	cfg := config.Default()
+	if input.Cwd != "" {
+		if loaded, err := config.Load(input.Cwd); err == nil {
+			cfg = loaded
+		}
+	}
+	if over := recordSessionActivity(input, input.Cwd, cfg.Guardrails.Session, func(*guardrails.Counters) {}, verbose); len(over) > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  Session budget exceeded, skipping plan review: %s\n", strings.Join(over, ", "))
+		writePreToolUseDecision("ask", budgetExceededMessage(input.SessionID, over))
+		recordAudit(audit.Event{Hook: "plan-review", Decision: "ask", Rule: "session-budget"}, verbose)
+		os.Exit(0)
+	}
+
 	reviewInput := hooks.PlanReviewInput{
+		SessionID:      input.SessionID,
 		TranscriptPath: input.TranscriptPath,
 		Cwd:            input.Cwd,
+		MinSeverity:    minSeverity,
+		Reviewers:      cfg.PlanReview.Reviewers,
+		Secrets:        cfg.PlanReview.Secrets,
 	}
 
 	result, err := hooks.ReviewPlan(reviewInput, verbose)
+	exitIfInterrupted(audit.Event{Hook: "plan-review"}, verbose)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "⚠️  Plan review error: %v\n", err)
+		// Unattended sessions need the review to gate the plan, so they can't go without one
+		if active.profile == profile.Unattended {
+			writePreToolUseDecision("deny", fmt.Sprintf("The plan could not be reviewed (%v), and the unattended profile requires an approving review before implementation starts.", err))
+			recordAudit(audit.Event{Hook: "plan-review", Decision: "deny", Rule: "unattended:review-failed"}, verbose)
+			os.Exit(0)
+		}
 		// Don't block on review errors, just warn
 		os.Exit(0)
 	}
@@ -613,6 +4228,34 @@ func handlePlanReview(input Input, verbose bool) {
 	fmt.Fprintln(os.Stderr, result.Summary)
 	fmt.Fprintln(os.Stderr, strings.Repeat("=", 60))
 
+	recordSessionActivity(input, input.Cwd, cfg.Guardrails.Session, func(c *guardrails.Counters) {
+		for _, r := range result.Reviews {
+			c.APICalls += r.Calls
+			c.SpendUSD += float64(r.Calls) * cfg.Guardrails.Session.CostPerReviewUSD
+		}
+	}, verbose)
+
+	ev := audit.Event{Hook: "plan-review", Decision: "allow"}
+	for _, r := range result.Reviews {
+		if r.Structured != nil {
+			ev.Verdicts = append(ev.Verdicts, r.Structured.Verdict)
+		}
+	}
+
+	// Interactive users read the feedback and decide; unattended sessions only
+	// proceed when every reviewer that answered approved
+	if active.profile == profile.Unattended {
+		if reason := unattendedPlanGate(ev.Verdicts); reason != "" {
+			ev.Decision, ev.Rule = "deny", "unattended:plan-gate"
+			recordAudit(ev, verbose)
+			fmt.Fprintf(os.Stderr, "❌ BLOCKED: %s\n", reason)
+			writePreToolUseDecision("deny", reason+"\n\n"+result.Summary)
+			os.Exit(0)
+		}
+	}
+
+	recordAudit(ev, verbose)
+
 	// ALLOW the plan to proceed - feedback has been shown
 	// Use JSON output with "allow" so the plan can finalize
 	output := PreToolUseOutput{
@@ -634,66 +4277,159 @@ func handlePlanReview(input Input, verbose bool) {
 	os.Exit(0)
 }
 
-// parseCompoundCommand splits a shell command by common operators to extract sub-commands
-func parseCompoundCommand(command string) []string {
-	// Replace shell operators with a delimiter we can split on
-	// Handle &&, ||, ;, and | (pipe)
-	delim := "||SPLIT||"
+// unattendedPlanGate returns why the plan may not proceed unattended, or "" when
+// at least one reviewer gave a verdict and all verdicts approve
+func unattendedPlanGate(verdicts []string) string {
+	if len(verdicts) == 0 {
+		return "No reviewer returned a verdict, and the unattended profile requires an approving review before implementation starts."
+	}
+	for _, v := range verdicts {
+		if v != "approve" {
+			return fmt.Sprintf("The AI Council did not approve the plan (verdicts: %s). Revise the plan to address the feedback below and try again.", strings.Join(verdicts, ", "))
+		}
+	}
+	return ""
+}
 
-	// Replace operators with our delimiter
-	command = strings.ReplaceAll(command, "&&", delim)
-	command = strings.ReplaceAll(command, "||", delim)
-	command = strings.ReplaceAll(command, ";", delim)
+// handleStop verifies every file changed in the working tree before an
+// unattended session ends its turn, so nothing it edited (including through Bash)
+// is left failing with nobody around to notice. Interactive sessions skip it.
+func handleStop(input Input, verbose bool) {
+	// Claude is already continuing because of an earlier block; blocking again
+	// could keep it from ever stopping
+	if input.StopHookActive {
+		vlog.Printf(verbose, "⏭️  Stop hook already active, allowing stop\n")
+		os.Exit(0)
+	}
 
-	// Handle pipes - but be careful not to break quoted strings
-	// Simple approach: split on | only if not inside quotes
-	command = splitPipes(command, delim)
+	dir := input.Cwd
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	root, err := ci.RepoRoot(dir)
+	if err != nil {
+		vlog.Printf(verbose, "⏭️  Skipping stop verification: %v\n", err)
+		os.Exit(0)
+	}
 
-	// Split by our delimiter and clean up
-	parts := strings.Split(command, delim)
-	var subCommands []string
+	checkChangelog(input, root, verbose)
+	checkGoTests(input, root, verbose)
+	checkStopPolicies(input, root, verbose)
+	if active.profile != profile.Unattended {
+		os.Exit(0)
+	}
 
-	for _, part := range parts {
-		trimmed := strings.TrimSpace(part)
-		if trimmed != "" {
-			subCommands = append(subCommands, trimmed)
-		}
+	files, err := hooks.DetectChangedFiles(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Skipping stop verification: %v\n", err)
+		os.Exit(0)
+	}
+	deleted, err := hooks.DetectDeletions(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not detect deleted files: %v\n", err)
+	}
+	files = filterFiles(files)
+	if len(files) == 0 && len(deleted) == 0 {
+		os.Exit(0)
 	}
 
-	return subCommands
+	result := runPipeline("post-edit", files, nil, deleted, verbose)
+	exitIfInterrupted(result.auditEvent("stop"), verbose)
+	recordAudit(result.auditEvent("stop"), verbose)
+
+	if len(result.errorMessages) > 0 {
+		writeBlockDecision("Stop", blockReason("The unattended profile verifies the working tree before the turn ends, and these checks failed. Fix them before finishing:", result.reasonSections()))
+	}
+	os.Exit(0)
 }
 
-// splitPipes replaces pipes with delimiter, avoiding pipes inside quotes
-func splitPipes(command, delim string) string {
-	var result strings.Builder
-	inQuotes := false
-	var quoteChar rune
+// checkStopPolicies has policies decide whether the turn can end, given the
+// files changed in the working tree, and returns when they allow it
+func checkStopPolicies(input Input, root string, verbose bool) {
+	cfg, err := config.Load(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, skipping policies: %v\n", err)
+		return
+	}
+	if !policy.Applies(cfg.Policy, "stop") {
+		return
+	}
+	files, err := hooks.DetectChangedFiles(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not list changed files for the policies: %v\n", err)
+	}
+	enforcePolicies(cfg, policy.Input{Hook: "stop", Cwd: input.Cwd, Files: files}, verbose)
+}
 
-	for i, char := range command {
-		switch char {
-		case '"', '\'', '`':
-			if !inQuotes {
-				inQuotes = true
-				quoteChar = char
-			} else if char == quoteChar {
-				inQuotes = false
-			}
-			result.WriteRune(char)
-		case '|':
-			if !inQuotes {
-				// Check if it's not part of ||
-				if i+1 < len(command) && rune(command[i+1]) == '|' {
-					result.WriteRune(char) // Let || be handled by the main replacement
-				} else {
-					result.WriteString(delim)
-				}
-			} else {
-				result.WriteRune(char)
-			}
-		default:
-			result.WriteRune(char)
+// checkChangelog blocks the end of a turn whose session changed files covered
+// by guardrails.changelog without adding a changelog entry, and returns otherwise
+func checkChangelog(input Input, root string, verbose bool) {
+	cfg, err := config.Load(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, skipping changelog check: %v\n", err)
+		return
+	}
+	changelog := cfg.Guardrails.Changelog
+	if !changelog.Enabled || input.SessionID == "" {
+		return
+	}
+	counters, err := guardrails.Update(guardrails.Session{Dir: root, ID: input.SessionID, TranscriptPath: input.TranscriptPath}, func(*guardrails.Counters) {})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read session files, skipping changelog check: %v\n", err)
+		return
+	}
+
+	missing := guardrails.ChangelogMissing(changelog, root, counters.Files)
+	if len(missing) == 0 {
+		vlog.Printf(verbose, "✅ Changelog check passed\n")
+		return
+	}
+
+	where := "`" + changelog.File + "`"
+	if changelog.FragmentsDir != "" {
+		where = fmt.Sprintf("`%s`, or add a file under `%s/`", changelog.File, strings.TrimSuffix(changelog.FragmentsDir, "/"))
+	}
+	reason := fmt.Sprintf("This session changed files that need a changelog entry (%s), but the changelog wasn't updated. Update %s, for example:\n\n%s",
+		strings.Join(missing, ", "), where, guardrails.ChangelogEntry(changelog, missing, time.Now()))
+	fmt.Fprintf(os.Stderr, "❌ BLOCKED: no changelog entry for %s\n", strings.Join(missing, ", "))
+	recordAudit(audit.Event{Hook: "stop", Decision: "block", Rule: "changelog"}, verbose)
+
+	writeBlockDecision("Stop", reason)
+	os.Exit(0)
+}
+
+// checkGoTests blocks the end of a turn when the tests of a Go package the
+// session touched fail, and returns otherwise. Every package runs again: edits
+// to the packages it imports can break it without changing its own files.
+func checkGoTests(input Input, root string, verbose bool) {
+	cfg, err := config.Load(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, skipping Go tests: %v\n", err)
+		return
+	}
+	if !cfg.Go.Tests.Enabled || input.SessionID == "" {
+		return
+	}
+	proc.SetStepTimeouts(cfg.Timeouts.Steps)
+	end := proc.Bound("go tests", 0)
+	run, err := hooks.TestSessionPackages(hooks.Session{Dir: root, ID: input.SessionID, TranscriptPath: input.TranscriptPath}, verbose)
+	for _, t := range end() {
+		fmt.Fprintf(os.Stderr, "⏱️  %s\n", t.Error())
+		if t.Killed {
+			err = nil // What did run was cut off; a slow suite doesn't block the turn
+		}
+	}
+	if err == nil {
+		if len(run.Results) > 0 {
+			vlog.Printf(verbose, "✅ Tests of %d package(s) changed this session pass\n", len(run.Results))
+			recordAudit(audit.Event{Hook: "stop", Decision: "allow", Rule: "go-tests", Tests: run.Results}, verbose)
 		}
+		return
 	}
 
-	return result.String()
+	fmt.Fprintf(os.Stderr, "❌ BLOCKED: tests of packages changed this session fail\n")
+	recordAudit(audit.Event{Hook: "stop", Decision: "block", Rule: "go-tests", Tests: run.Results}, verbose)
+
+	writeBlockDecision("Stop", blockReason("Tests of Go packages changed in this session fail. Fix them before finishing:", []reason.Section{{Rule: "go-tests", Priority: reason.Test, Text: err.Error()}}))
+	os.Exit(0)
 }
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/reason"
+	"github.com/brianleishman/claude-hooks/pkg/hooktest"
 )
 
 func TestGetCurrentBranch(t *testing.T) {
@@ -116,3 +124,375 @@ func TestFindGitRoot(t *testing.T) {
 		t.Errorf("Git root %q is not a parent of current directory %q", root, cwd)
 	}
 }
+
+func TestTrivialEdit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.go")
+	content := "package a\n\n// Load reads the config file\nfunc Load() {}\n\n// Save writes the config file\nfunc Save() {}\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	edit := func(toolInput ToolInput) Input {
+		toolInput.FilePath = file
+		return Input{ToolName: "Edit", ToolInput: toolInput}
+	}
+
+	tests := []struct {
+		name  string
+		input Input
+		want  bool
+	}{
+		{"comment", edit(ToolInput{OldString: "reads the config", NewString: "reads the config file"}), true},
+		{"code", edit(ToolInput{OldString: "func Load() {}", NewString: "func Load() {}\n\nfunc Reload() {}"}), false},
+		{"ambiguous replacement", edit(ToolInput{OldString: "the config", NewString: "the config file"}), false},
+		{"replace all", edit(ToolInput{OldString: "the config", NewString: "the config file", ReplaceAll: true}), true},
+		{"multi edit", Input{ToolName: "MultiEdit", ToolInput: ToolInput{FilePath: file, Edits: []ToolEdit{
+			{OldString: "reads the config", NewString: "reads the config file"},
+			{OldString: "Save writes", NewString: "Save writes"},
+		}}}, true},
+		{"deletion", edit(ToolInput{OldString: "// unused\n", NewString: ""}), false},
+		{"write outside a repository", Input{ToolName: "Write", ToolInput: ToolInput{FilePath: file, Content: content}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trivialEdit(tt.input, []string{file}); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReasonSections(t *testing.T) {
+	result := pipelineResult{
+		errorMessages: []string{"complexity check failed", "go tests failed", "go hook failed", "Session budget exceeded"},
+		failedRules:   []string{"complexity", "go-tests", "go-post-edit", "session-budget"},
+		warnings:      []string{"duplicate code"},
+		fixes:         "Suggested fixes",
+		timeouts:      []string{"step go test exceeded 30s and was aborted"},
+	}
+	want := map[string]int{
+		"complexity":      reason.Lint,
+		"go-tests":        reason.Test,
+		"go-post-edit":    reason.Compile,
+		"session-budget":  reason.Compile,
+		"suggested-fixes": reason.Lint,
+		"warnings":        reason.Warning,
+		"timeouts":        reason.Warning,
+	}
+	sections := result.reasonSections()
+	if len(sections) != 7 {
+		t.Fatalf("Expected 7 sections, got %+v", sections)
+	}
+	for _, s := range sections {
+		if s.Priority != want[s.Rule] {
+			t.Errorf("Expected %s to have priority %d, got %d", s.Rule, want[s.Rule], s.Priority)
+		}
+	}
+}
+
+func TestSetFlagsFromEnv(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_VERBOSE", "1")
+	t.Setenv("CLAUDE_HOOKS_CLEAN_MAX_AGE", "7d")
+	t.Setenv("CLAUDE_HOOKS_CLEAN_SESSION_MAX_AGE", "1d")
+	t.Setenv("CLAUDE_HOOKS_DRY_RUN", "true")
+
+	fs := flag.NewFlagSet("clean", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "")
+	maxAge := fs.String("max-age", "30d", "")
+	sessionMaxAge := fs.String("session-max-age", "7d", "")
+	dryRun := fs.Bool("dry-run", false, "")
+	setFlagsFromEnv(fs, fs.Name())
+	if err := fs.Parse([]string{"-session-max-age", "2d"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !*verbose || !*dryRun {
+		t.Errorf("Expected -v and -dry-run from their shared variables, got %v, %v", *verbose, *dryRun)
+	}
+	if *maxAge != "7d" {
+		t.Errorf("Expected -max-age from $CLAUDE_HOOKS_CLEAN_MAX_AGE, got %q", *maxAge)
+	}
+	if *sessionMaxAge != "2d" {
+		t.Errorf("Expected the command line to win over the environment, got %q", *sessionMaxAge)
+	}
+	if env := flagEnv("", "diagnostics-file"); env != "CLAUDE_HOOKS_DIAGNOSTICS_FILE" {
+		t.Errorf("Expected CLAUDE_HOOKS_DIAGNOSTICS_FILE for the hooks' flag, got %s", env)
+	}
+	if env := flagEnv("telemetry enable", "endpoint"); env != "CLAUDE_HOOKS_TELEMETRY_ENABLE_ENDPOINT" {
+		t.Errorf("Expected CLAUDE_HOOKS_TELEMETRY_ENABLE_ENDPOINT, got %s", env)
+	}
+}
+
+func TestCommandRunsAny(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"mv a.go b.go", true},
+		{"go build ./... && git mv a.go b.go", true},
+		{"echo $(mv a b)", true},
+		{`sh -c "git   mv a b"`, true},
+		{"'mv' a b", true},
+		{`echo "mv a b"`, false},
+		{"git commit -m 'git mv'", false},
+	}
+	for _, tt := range tests {
+		if got := commandRunsAny(tt.command, "mv", "git mv"); got != tt.want {
+			t.Errorf("commandRunsAny(%q) = %v, expected %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	got := commandArgs("go test ./... && rm -rf -- -odd a.go && git rm -q --cached b.go; git rmx c", "rm", "git rm")
+	if want := []string{"-odd", "a.go", "b.go"}; !slices.Equal(got, want) {
+		t.Errorf("commandArgs = %q, expected %q", got, want)
+	}
+}
+
+// TestCollectDeletions expects only the files an rm named, directly or through
+// a directory or glob, and an emptied file only once it's gone
+func TestCollectDeletions(t *testing.T) {
+	hooktest.Isolate(t)
+	root, err := filepath.EvalSymlinks(hooktest.Repo(t, map[string]string{
+		"a.go": "package a\n", "b.go": "package a\n", "c_test.go": "package a\n",
+		"sub/d.go": "package sub\n", "other.go": "package a\n",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"a.go", "c_test.go", "sub/d.go", "other.go"} {
+		if err := os.Remove(filepath.Join(root, f)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bash := Input{ToolName: "Bash", Cwd: root, ToolInput: ToolInput{Command: "rm a.go sub *_test.go"}}
+	want := []string{filepath.Join(root, "a.go"), filepath.Join(root, "c_test.go"), filepath.Join(root, "sub/d.go")}
+	if got := collectDeletions(bash, nil, false); !slices.Equal(got, want) {
+		t.Errorf("Expected the files rm named, not other.go, got %q", got)
+	}
+
+	write := Input{ToolName: "Write", ToolInput: ToolInput{FilePath: filepath.Join(root, "b.go")}}
+	if err := os.WriteFile(write.ToolInput.FilePath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := collectDeletions(write, nil, false); len(got) != 0 {
+		t.Errorf("Expected an empty file still on disk to be validated, not deleted, got %q", got)
+	}
+	write.ToolInput.FilePath = filepath.Join(root, "other.go")
+	if got := collectDeletions(write, nil, false); len(got) != 1 {
+		t.Errorf("Expected an emptied file that's gone to be a deletion, got %q", got)
+	}
+}
+
+func TestInputFile(t *testing.T) {
+	hooktest.Isolate(t)
+	payload := hooktest.Bash("mysql -e 'select 1'").In(t.TempDir())
+	path := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(path, []byte(payload.JSON(t)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(stdin string, args ...string) string {
+		cmd := exec.Command(hooktest.Binary(t), append([]string{"pre-bash"}, args...)...)
+		cmd.Stdin = strings.NewReader(stdin)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("pre-bash %v failed: %v", args, err)
+		}
+		return string(out)
+	}
+	if d := hooktest.ParseDecision(t, run("", "-input", path)); d.Kind != "deny" {
+		t.Errorf("Expected the payload in the file to be denied, got: %+v", d)
+	}
+	if d := hooktest.ParseDecision(t, run(payload.JSON(t), "--input", "-")); d.Kind != "deny" {
+		t.Errorf("Expected -input - to read stdin, got: %+v", d)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalid, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(t.TempDir(), "missing.json"), invalid} {
+		cmd := exec.Command(hooktest.Binary(t), "pre-bash", "-input", path)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err == nil || !strings.Contains(stderr.String(), "Could not read -input "+path) {
+			t.Errorf("Expected -input %s to fail with the error, got %v: %s", filepath.Base(path), err, stderr.String())
+		}
+	}
+}
+
+func TestSessionBudgetHaltsClaude(t *testing.T) {
+	hooktest.Isolate(t)
+	hooktest.FakeTool(t, "go", "exit 0")
+	root := hooktest.Repo(t, map[string]string{
+		"go.mod":             "module example.com/budget\n\ngo 1.22\n",
+		".claude-hooks.yaml": "guardrails:\n  session:\n    max_files_modified: 1\n",
+	})
+
+	edit := func(name string) hooktest.Decision {
+		file := hooktest.WriteFile(t, root, name, "package budget\n")
+		return hooktest.ParseDecision(t, hooktest.RunHook(t, "post-edit", hooktest.Write(file, "package budget\n").In(root).Session("s1", "")).Stdout)
+	}
+	if d := edit("a.go"); d.Stops() {
+		t.Fatalf("Expected the first file to be within budget, got %+v", d)
+	}
+	d := edit("b.go")
+	if d.Kind != "block" || !d.Halt || !strings.Contains(d.StopReason, "budget -reset s1") {
+		t.Errorf("Expected a block that halts Claude once over budget, got %+v", d)
+	}
+}
+
+func TestUserPromptRemindsOfFailures(t *testing.T) {
+	hooktest.Isolate(t)
+	hooktest.Binary(t) // Built with the real go
+	hooktest.FakeTool(t, "go", `grep -rq broken . && { echo "a.go:1:1: broken" >&2; exit 1; }; exit 0`)
+	root := hooktest.Repo(t, map[string]string{"go.mod": "module example.com/remind\n\ngo 1.22\n", ".claude-hooks.yaml": "go:\n  vet: true\n"})
+
+	edit := func(content string) {
+		file := hooktest.WriteFile(t, root, "api/a.go", content)
+		hooktest.RunHook(t, "post-edit", hooktest.Write(file, content).In(root).Session("s1", ""))
+	}
+	prompt := func() hooktest.Decision {
+		return hooktest.AssertNotStopped(t, hooktest.RunHook(t, "user-prompt", hooktest.UserPrompt("now the docs").In(root).Session("s1", "")).Stdout)
+	}
+
+	edit("package api // broken\n")
+	if d := prompt(); !strings.Contains(d.Context, "still failing from earlier edits") || !strings.Contains(d.Context, "in api") {
+		t.Errorf("Expected a reminder of the failure in api, got %+v", d)
+	}
+	edit("package api\n")
+	if d := prompt(); d.Context != "" {
+		t.Errorf("Expected no reminder once the check passes, got %q", d.Context)
+	}
+}
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+	if code := runInit([]string{"-template", "go-service", "-dir", dir}); code != 0 {
+		t.Fatalf("Expected init to succeed, got exit code %d", code)
+	}
+	cfg, err := config.Load(dir)
+	if err != nil || cfg.Path != filepath.Join(dir, config.FileName) || !cfg.Go.Tests.Enabled {
+		t.Fatalf("Expected the template to load as the project's config, got %+v (%v)", cfg, err)
+	}
+	if code := runInit([]string{"-template", "infra", "-dir", dir}); code != 1 {
+		t.Errorf("Expected init to refuse replacing the config, got exit code %d", code)
+	}
+	if code := runInit([]string{"-template", "infra", "-dir", dir, "-force"}); code != 0 {
+		t.Errorf("Expected -force to replace the config, got exit code %d", code)
+	}
+	if code := runInit([]string{"-template", "rails", "-dir", dir}); code != 2 {
+		t.Errorf("Expected an unknown template to be a usage error, got exit code %d", code)
+	}
+}
+
+func TestConfigLint(t *testing.T) {
+	dir := t.TempDir()
+	if code := runConfig([]string{"lint", "-dir", dir}); code != 0 {
+		t.Errorf("Expected no config to lint clean, got exit code %d", code)
+	}
+	hooktest.WriteFile(t, dir, config.FileName, "go:\n  tests:\n    enabled: true\n")
+	if code := runConfig([]string{"lint", "-dir", dir}); code != 0 {
+		t.Errorf("Expected a valid config to lint clean, got exit code %d", code)
+	}
+	hooktest.WriteFile(t, dir, config.FileName, "go:\n  test:\n    enabled: true\n")
+	if code := runConfig([]string{"lint", "-dir", dir}); code != 1 {
+		t.Errorf("Expected an unknown setting to fail lint, got exit code %d", code)
+	}
+	if code := runConfig([]string{"check"}); code != 2 {
+		t.Errorf("Expected an unknown subcommand to be a usage error, got exit code %d", code)
+	}
+}
+
+func TestPreEditConventions(t *testing.T) {
+	hooktest.Isolate(t)
+	root := hooktest.Repo(t, map[string]string{
+		"go.mod":        "module example.com/conv\n\ngo 1.22\n",
+		".editorconfig": "root = true\n\n[*.go]\nindent_style = tab\n",
+	})
+	file := filepath.Join(root, "a.go")
+	edit := func() hooktest.Decision {
+		return hooktest.AssertNotStopped(t, hooktest.RunHook(t, "pre-edit", hooktest.Write(file, "package conv\n").In(root).Session("s1", "")).Stdout)
+	}
+	if d := edit(); !strings.Contains(d.Context, "a.go: indent_style = tab (.editorconfig).") || !strings.Contains(d.Context, "gofmt") {
+		t.Errorf("Expected the conventions before the first edit, got %+v", d)
+	}
+	if d := edit(); d.Context != "" {
+		t.Errorf("Expected the conventions only once per session, got %q", d.Context)
+	}
+}
+
+func TestConcurrentSessions(t *testing.T) {
+	hooktest.Isolate(t)
+	root := hooktest.Repo(t, map[string]string{"go.mod": "module example.com/peers\n\ngo 1.22\n"})
+	file := filepath.Join(root, "a.go")
+	edit := func(session string) hooktest.Decision {
+		return hooktest.AssertNotStopped(t, hooktest.RunHook(t, "pre-edit", hooktest.Write(file, "package peers\n").In(root).Session(session, "")).Stdout)
+	}
+	if d := edit("session-one"); strings.Contains(d.Context, "active in this repository") {
+		t.Errorf("Expected no warning for the only session, got %q", d.Context)
+	}
+
+	start := hooktest.RunHook(t, "session-start", hooktest.SessionStart("startup").Session("session-two", ""), "CLAUDE_CODE_CWD="+root)
+	if !strings.Contains(start.Stdout, "Another Claude session (session-) is active in this repository") {
+		t.Errorf("Expected the new session to hear of the first, got: %s", start.Stdout)
+	}
+	if d := edit("session-one"); !strings.Contains(d.Context, "Another Claude session (session-) is active") {
+		t.Errorf("Expected the first session to hear of the new one at its next edit, got %q", d.Context)
+	}
+	if d := edit("session-one"); strings.Contains(d.Context, "active in this repository") {
+		t.Errorf("Expected each other session only once, got %q", d.Context)
+	}
+}
+
+func TestPreEditWholeFileRewrite(t *testing.T) {
+	hooktest.Isolate(t)
+	var old, rewritten strings.Builder
+	for i := range 30 {
+		fmt.Fprintf(&old, "key%d = %d\n", i, i)
+		fmt.Fprintf(&rewritten, "key%d = %d\n", i, i*2)
+	}
+	root := hooktest.Repo(t, map[string]string{"settings.ini": old.String()})
+	file := filepath.Join(root, "settings.ini")
+	write := hooktest.Write(file, rewritten.String()).In(root)
+
+	d := hooktest.AssertNotStopped(t, hooktest.RunHook(t, "pre-edit", write).Stdout)
+	if !strings.Contains(d.Context, "rewrites 29 of its 30 lines") || !strings.Contains(d.Context, "Use Edit") {
+		t.Errorf("Expected a warning that an Edit would be safer, got %+v", d)
+	}
+
+	hooktest.WriteFile(t, root, ".claude-hooks.yaml", "content:\n  block_rewrite_size: 100\n")
+	hooktest.AssertStopped(t, hooktest.RunHook(t, "pre-edit", write).Stdout, "whole-file-rewrite")
+}
+
+func TestEditOutsideRepo(t *testing.T) {
+	hooktest.Isolate(t)
+	root := hooktest.Repo(t, map[string]string{"README.md": "# app\n"})
+	elsewhere := t.TempDir()
+	hooktest.WriteFile(t, elsewhere, "notes.txt", "old\n")
+	link := filepath.Join(root, "notes.txt")
+	if err := os.Symlink(filepath.Join(elsewhere, "notes.txt"), link); err != nil {
+		t.Fatal(err)
+	}
+	edit := hooktest.Edit(link, "old", "new").In(root)
+
+	d := hooktest.AssertNotStopped(t, hooktest.RunHook(t, "pre-edit", edit).Stdout)
+	if !strings.Contains(d.Context, "Outside this session's repository") || !strings.Contains(d.Context, filepath.Join(elsewhere, "notes.txt")) {
+		t.Errorf("Expected a warning naming the symlink's target, got %+v", d)
+	}
+	inside := hooktest.Edit(filepath.Join(root, "README.md"), "app", "service").In(root)
+	if d := hooktest.AssertNotStopped(t, hooktest.RunHook(t, "pre-edit", inside).Stdout); strings.Contains(d.Context, "Outside") {
+		t.Errorf("Expected no warning for a file in the repository, got %+v", d)
+	}
+
+	hooktest.WriteFile(t, root, ".claude-hooks.yaml", "paths:\n  outside: block\n")
+	hooktest.AssertStopped(t, hooktest.RunHook(t, "pre-edit", edit).Stdout, "paths.outside is block")
+	hooktest.AssertStopped(t, hooktest.RunHook(t, "post-edit", edit).Stdout, "Undo the change")
+
+	hooktest.WriteFile(t, root, ".claude-hooks.yaml", "paths:\n  outside: validate\n")
+	if d := hooktest.AssertNotStopped(t, hooktest.RunHook(t, "pre-edit", edit).Stdout); strings.Contains(d.Context, "Outside") {
+		t.Errorf("Expected validate to check silently, got %+v", d)
+	}
+}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/policy"
+	"github.com/brianleishman/claude-hooks/pkg/hooktest"
 )
 
 func TestSessionStartHook(t *testing.T) {
@@ -92,9 +97,37 @@ func TestSessionStartHookMissingFile(t *testing.T) {
 		t.Fatalf("Hook should not fail when agents.md is missing: %v\nOutput: %s", err, string(output))
 	}
 
-	// Verify output is empty (or just whitespace)
+	// Verify output is only the capability summary
 	trimmed := strings.TrimSpace(string(output))
-	if trimmed != "" {
-		t.Errorf("Expected empty output when agents.md is missing, got: %s", trimmed)
+	if strings.Contains(trimmed, "\n") || !strings.HasPrefix(trimmed, "claude-hooks active:") {
+		t.Errorf("Expected only the capability summary when agents.md is missing, got: %s", trimmed)
+	}
+}
+
+func TestSessionStartCapabilities(t *testing.T) {
+	hooktest.Isolate(t)
+	root := hooktest.Repo(t, map[string]string{
+		"go.mod":             "module example.com/app\n\ngo 1.22\n",
+		"tsconfig.json":      "{}\n",
+		"package.json":       "{}\n",
+		".claude-hooks.yaml": "go:\n  vet: true\n  tests:\n    enabled: true\ncontent:\n  disabled: [lint-suppression]\nplan_review:\n  reviewers: [claude]\nhooks:\n  shellcheck:\n    command: shellcheck -x\n",
+	})
+
+	// Every built-in rule but the one disabled above, counted once by name
+	var rules []string
+	for _, r := range policy.ContentRules {
+		if r.Name != "lint-suppression" && !slices.Contains(rules, r.Name) {
+			rules = append(rules, r.Name)
+		}
+	}
+
+	result := hooktest.RunHook(t, "session-start", hooktest.SessionStart("startup").Session("test123", ""), "CLAUDE_CODE_CWD="+root)
+	for _, want := range []string{"claude-hooks active: Go(vet,tests), TS(none), shellcheck(shellcheck), edits: ", fmt.Sprintf("content rules: %d", len(rules)), "push scan", "plan review: claude"} {
+		if !strings.Contains(result.Stdout, want) {
+			t.Errorf("Expected the summary to contain %q, got: %s", want, result.Stdout)
+		}
+	}
+	if strings.Contains(result.Stdout, "JS(") {
+		t.Errorf("Expected a TS project not to list JS too, got: %s", result.Stdout)
 	}
 }
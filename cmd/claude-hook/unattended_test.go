@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/pkg/hooktest"
+)
+
+// runHook runs the hook with stdin, the given profile, and any extra
+// environment, returning its stdout
+func runHook(t *testing.T, hookType, profileName string, payload hooktest.Payload, env ...string) string {
+	t.Helper()
+	hooktest.Isolate(t)
+	return hooktest.RunHook(t, hookType, payload, append([]string{"CLAUDE_HOOKS_PROFILE=" + profileName}, env...)...).Stdout
+}
+
+func TestUnattendedFailsClosedOnBadInput(t *testing.T) {
+	if out := runHook(t, "pre-bash", "interactive", hooktest.Raw("not json")); strings.Contains(out, `"deny"`) {
+		t.Errorf("Expected interactive sessions to fail open, got: %s", out)
+	}
+	if d := hooktest.ParseDecision(t, runHook(t, "pre-bash", "unattended", hooktest.Raw("not json"))); d.Kind != "deny" {
+		t.Errorf("Expected unattended sessions to deny unparseable input, got: %+v", d)
+	}
+}
+
+func TestUnattendedDenylist(t *testing.T) {
+	payload := hooktest.Bash("git reset --hard HEAD~1").In(t.TempDir())
+
+	if out := runHook(t, "pre-bash", "interactive", payload); strings.Contains(out, `"deny"`) {
+		t.Errorf("Expected the denylist to be off interactively, got: %s", out)
+	}
+	if d := hooktest.ParseDecision(t, runHook(t, "pre-bash", "unattended", payload)); d.Kind != "deny" {
+		t.Errorf("Expected a hard reset to be denied unattended, got: %+v", d)
+	}
+}
+
+func TestUnattendedEnforcement(t *testing.T) {
+	payload := hooktest.Bash("git reset --hard HEAD~1").In(t.TempDir())
+
+	d := hooktest.AssertNotStopped(t, runHook(t, "pre-bash", "unattended", payload, "CLAUDE_HOOKS_ENFORCEMENT=warn"))
+	if d.SystemMessage == "" {
+		t.Errorf("Expected warn to show the reason, got: %+v", d)
+	}
+	if out := runHook(t, "pre-bash", "unattended", payload, "CLAUDE_HOOKS_DRY_RUN=1"); strings.TrimSpace(out) != "" {
+		t.Errorf("Expected a dry run to print no decision, got: %s", out)
+	}
+}
+
+func TestMySQLBypasses(t *testing.T) {
+	for _, command := range []string{`'my'sql -e 'select 1'`, `echo $(mysql -V)`, `bash -c "MYSQL_PWD=x mysql"`, "ls\nmysql"} {
+		if d := hooktest.ParseDecision(t, runHook(t, "pre-bash", "interactive", hooktest.Bash(command).In(t.TempDir()))); d.Kind != "deny" {
+			t.Errorf("Expected %q to be denied, got: %+v", command, d)
+		}
+	}
+	if out := runHook(t, "pre-bash", "interactive", hooktest.Bash(`command -v mysql || echo "no mysql"`).In(t.TempDir())); strings.Contains(out, `"deny"`) {
+		t.Errorf("Expected a lookup of mysql to be allowed, got: %s", out)
+	}
+}
@@ -1,33 +1,32 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
-)
-
-// ClaudeSettings represents the structure of Claude's settings.json
-type ClaudeSettings struct {
-	Model string                   `json:"model,omitempty"`
-	Hooks map[string][]HookMatcher `json:"hooks,omitempty"`
-}
+	"slices"
+	"strings"
 
-// HookMatcher represents a hook matcher configuration
-type HookMatcher struct {
-	Matcher string `json:"matcher"`
-	Hooks   []Hook `json:"hooks"`
-}
+	"github.com/brianleishman/claude-hooks/internal/settings"
+)
 
-// Hook represents a single hook configuration
-type Hook struct {
-	Type    string `json:"type"`
-	Command string `json:"command"`
+// options are setup's flags
+type options struct {
+	matchers  []string // Per settings.DefaultHooks; empty disables the hook
+	uninstall bool
+	project   string // Repo whose .claude settings to write instead of the user's
+	local     bool   // With project, write settings.local.json, which isn't committed
+	build     bool   // Compile claude-hook rather than install the go run shim
 }
 
 func main() {
-	fmt.Println("Setting up Claude Hooks...")
+	opts, err := parseFlags(os.Args[1:])
+	if err != nil {
+		os.Exit(2)
+	}
+	matchers := opts.matchers
 
 	// Get user's home directory
 	homeDir, err := os.UserHomeDir()
@@ -36,58 +35,57 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Get current working directory (claude-hooks repo)
-	cwd, err := os.Getwd()
+	settingsPath, err := opts.settingsPath(homeDir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Error getting current directory: %v\n", err)
+		fmt.Fprintf(os.Stderr, "❌ Error choosing the settings file: %v\n", err)
 		os.Exit(1)
 	}
 
-	settingsPath := filepath.Join(homeDir, ".claude", "settings.json")
-
-	// Read existing settings or create new ones
-	settings, err := readOrCreateSettings(settingsPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Error handling settings file: %v\n", err)
-		os.Exit(1)
+	if opts.uninstall {
+		uninstall(homeDir, settingsPath, opts.project == "")
+		return
 	}
 
-	// Create the go run commands that will work from any directory
-	postHookCommand := fmt.Sprintf("bash -c \"cd %s && go run cmd/claude-hook/main.go -type post-edit\"", cwd)
-	preHookCommand := fmt.Sprintf("bash -c \"cd %s && go run cmd/claude-hook/main.go -type pre-bash\"", cwd)
-	planReviewCommand := fmt.Sprintf("bash -c \"cd %s && go run cmd/claude-hook/main.go -type plan-review\"", cwd)
-	sessionStartCommand := fmt.Sprintf("bash -c \"cd %s && go run cmd/claude-hook/main.go -type session-start\"", cwd)
+	fmt.Println("Setting up Claude Hooks...")
 
-	// Add our hook configurations
-	err = addPostToolUseHook(settings, postHookCommand)
+	// Get current working directory (claude-hooks repo)
+	cwd, err := os.Getwd()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Error configuring PostToolUse hook: %v\n", err)
+		fmt.Fprintf(os.Stderr, "❌ Error getting current directory: %v\n", err)
 		os.Exit(1)
 	}
 
-	err = addPreToolUseHook(settings, preHookCommand)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Error configuring PreToolUse hook: %v\n", err)
+	// Install the shim that knows where this checkout lives, or the binary
+	// built from it, so settings.json commands don't hard-code the checkout and
+	// stay portable across machines
+	shimPath := settings.ShimPath(homeDir)
+	if opts.build {
+		fmt.Println("Building claude-hook...")
+		if err := settings.BuildBinary(shimPath, cwd); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error building hook binary: %v\n", err)
+			os.Exit(1)
+		}
+	} else if err := settings.InstallShim(shimPath, cwd); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error installing hook shim: %v\n", err)
 		os.Exit(1)
 	}
 
-	err = addPlanReviewHook(settings, planReviewCommand)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Error configuring PlanReview hook: %v\n", err)
-		os.Exit(1)
-	}
+	// Read, update, and write settings under a lock, merging with any concurrent writer
+	created, err := settings.Update(settingsPath, func(s *settings.Settings) error {
+		// Replace commands from older setups that embedded the checkout path
+		if removed := s.RemoveHooksFunc(settings.IsLegacyHookCommand); removed > 0 {
+			fmt.Printf("Replaced %d hook command(s) that hard-coded the checkout path\n", removed)
+		}
 
-	err = addSessionStartHook(settings, sessionStartCommand)
+		configureHooks(s, matchers)
+		return nil
+	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Error configuring SessionStart hook: %v\n", err)
+		fmt.Fprintf(os.Stderr, "❌ Error updating settings: %v\n", err)
 		os.Exit(1)
 	}
-
-	// Write settings back to file
-	err = writeSettings(settingsPath, settings)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Error writing settings: %v\n", err)
-		os.Exit(1)
+	if created {
+		fmt.Printf("Created %s\n", settingsPath)
 	}
 
 	fmt.Println("")
@@ -95,243 +93,173 @@ func main() {
 	fmt.Println("✅ Hooks automatically configured in Claude Code!")
 	fmt.Println("")
 	fmt.Printf("Hooks configured in: %s\n", settingsPath)
-	fmt.Println("  PostToolUse Event: Write|Edit|MultiEdit")
-	fmt.Printf("    Command: %s\n", postHookCommand)
-	fmt.Println("  PreToolUse Event: Bash (MySQL blocking + git commit protection)")
-	fmt.Printf("    Command: %s\n", preHookCommand)
-	fmt.Println("  PreToolUse Event: ExitPlanMode (AI Council plan review)")
-	fmt.Printf("    Command: %s\n", planReviewCommand)
-	fmt.Println("  SessionStart Event: startup|compact (inject agents.md)")
-	fmt.Printf("    Command: %s\n", sessionStartCommand)
+	if opts.build {
+		fmt.Printf("Hook binary built at: %s (from %s; override with $%s)\n", shimPath, cwd, settings.BinEnv)
+	} else {
+		fmt.Printf("Hook shim installed at: %s (runs %s; override with $%s)\n", shimPath, cwd, settings.BinEnv)
+	}
+	for i, spec := range settings.DefaultHooks {
+		if matchers[i] == "" {
+			fmt.Printf("  %s Event: disabled (-%s=none)\n", spec.Event, spec.Flag)
+			continue
+		}
+		fmt.Printf("  %s Event: %s (%s)\n", spec.Event, matchers[i], spec.Description)
+		fmt.Printf("    Command: %s\n", settings.HookCommand(spec.Type))
+	}
 	fmt.Println("")
-	fmt.Println("🔄 Live reloading enabled - changes to hook code take effect immediately!")
+	if opts.build {
+		fmt.Println("⚡ Hooks run the compiled binary - re-run setup with -build after changing hook code, or use -live")
+	} else {
+		fmt.Println("🔄 Live reloading enabled - changes to hook code take effect immediately!")
+	}
 	fmt.Println("")
 	fmt.Println("The hook will automatically:")
 	fmt.Println("  - Format Go files (goimports, gofumpt)")
 	fmt.Println("  - Run linters (golangci-lint or go vet)")
 	fmt.Println("  - Run tests for modified files")
-	fmt.Println("  - Tidy go.mod")
 	fmt.Println("  - Format TypeScript/JavaScript (prettier, eslint)")
 	fmt.Println("  - Type-check TypeScript files")
 	fmt.Println("  - Block MySQL commands (use Go database methods instead)")
 	fmt.Println("  - Block git commits on master/main branches (create feature branches instead)")
 	fmt.Println("  - 🧠 Review plans with AI Council (Claude Opus, GPT-5.2, Gemini 3 Pro)")
 	fmt.Println("  - Inject agents.md into context on session start and after compaction")
-}
-
-func readOrCreateSettings(settingsPath string) (*ClaudeSettings, error) {
-	// Ensure .claude directory exists
-	claudeDir := filepath.Dir(settingsPath)
-	if err := os.MkdirAll(claudeDir, 0o755); err != nil {
-		return nil, fmt.Errorf("creating .claude directory: %w", err)
+	if opts.project != "" && !opts.local {
+		fmt.Println("")
+		fmt.Printf("Commit %s to share the hooks; each teammate runs make setup once for the shim.\n", settingsPath)
 	}
+}
 
-	// Try to read existing settings
-	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
-		// File doesn't exist, create new settings
-		fmt.Printf("Creating %s...\n", settingsPath)
-		return &ClaudeSettings{
-			Hooks: make(map[string][]HookMatcher),
-		}, nil
+// settingsPath is the settings file setup edits: the user's, or with -project
+// the repo's shared .claude/settings.json or, with -local, its settings.local.json
+func (o options) settingsPath(homeDir string) (string, error) {
+	if o.project == "" {
+		if o.local {
+			return "", fmt.Errorf("-local needs -project")
+		}
+		return filepath.Join(homeDir, ".claude", "settings.json"), nil
 	}
-
-	// File exists, read it
-	file, err := os.Open(settingsPath)
+	dir, err := filepath.Abs(o.project)
 	if err != nil {
-		return nil, fmt.Errorf("opening settings file: %w", err)
+		return "", err
 	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to close file: %v\n", closeErr)
-		}
-	}()
-
-	data, err := io.ReadAll(file)
+	info, err := os.Stat(dir)
 	if err != nil {
-		return nil, fmt.Errorf("reading settings file: %w", err)
+		return "", err
 	}
-
-	var settings ClaudeSettings
-	if err := json.Unmarshal(data, &settings); err != nil {
-		return nil, fmt.Errorf("parsing settings JSON: %w", err)
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
 	}
-
-	// Ensure hooks map exists
-	if settings.Hooks == nil {
-		settings.Hooks = make(map[string][]HookMatcher)
+	name := "settings.json"
+	if o.local {
+		name = "settings.local.json"
 	}
-
-	return &settings, nil
+	return filepath.Join(dir, ".claude", name), nil
 }
 
-func addPostToolUseHook(settings *ClaudeSettings, hookCommand string) error {
-	// Check if our hook already exists in PostToolUse
-	postToolUse := settings.Hooks["PostToolUse"]
+// uninstall removes every claude-hooks command from the settings file, along
+// with the matchers and events left empty, and, for the user's settings, the
+// hook executable in ~/.claude/bin, which project settings share
+func uninstall(homeDir, settingsPath string, removeShim bool) {
+	fmt.Println("Removing Claude Hooks...")
 
-	for i, matcher := range postToolUse {
-		if matcher.Matcher == "Write|Edit|MultiEdit" {
-			// Check if our command already exists
-			for _, hook := range matcher.Hooks {
-				if hook.Command == hookCommand {
-					fmt.Println("PostToolUse hook already configured, skipping...")
-					return nil
-				}
-			}
-
-			// Add our hook to existing matcher
-			postToolUse[i].Hooks = append(postToolUse[i].Hooks, Hook{
-				Type:    "command",
-				Command: hookCommand,
-			})
-			settings.Hooks["PostToolUse"] = postToolUse
+	if _, err := os.Stat(settingsPath); err == nil {
+		removed := 0
+		_, err := settings.Update(settingsPath, func(s *settings.Settings) error {
+			removed = s.RemoveHooksFunc(settings.IsHookCommand)
 			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Error updating settings: %v\n", err)
+			os.Exit(1)
 		}
+		fmt.Printf("Removed %d hook command(s) from %s\n", removed, settingsPath)
 	}
 
-	// No existing matcher found, create new one
-	newMatcher := HookMatcher{
-		Matcher: "Write|Edit|MultiEdit",
-		Hooks: []Hook{{
-			Type:    "command",
-			Command: hookCommand,
-		}},
+	// The shim, the binary -build compiled, or a release update installed
+	shimPath := settings.ShimPath(homeDir)
+	if removeShim {
+		err := os.Remove(shimPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "❌ Error removing hook executable: %v\n", err)
+			os.Exit(1)
+		}
+		if err == nil {
+			fmt.Printf("Removed %s\n", shimPath)
+		}
 	}
 
-	settings.Hooks["PostToolUse"] = append(postToolUse, newMatcher)
-	return nil
+	fmt.Println("")
+	fmt.Println("✅ Claude Hooks uninstalled. Run make setup to install them again.")
 }
 
-func addPreToolUseHook(settings *ClaudeSettings, hookCommand string) error {
-	// Check if our hook already exists in PreToolUse
-	preToolUse := settings.Hooks["PreToolUse"]
-
-	for i, matcher := range preToolUse {
-		if matcher.Matcher == "Bash" {
-			// Check if our command already exists
-			for _, hook := range matcher.Hooks {
-				if hook.Command == hookCommand {
-					fmt.Println("PreToolUse hook already configured, skipping...")
-					return nil
-				}
-			}
-
-			// Add our hook to existing matcher
-			preToolUse[i].Hooks = append(preToolUse[i].Hooks, Hook{
-				Type:    "command",
-				Command: hookCommand,
-			})
-			settings.Hooks["PreToolUse"] = preToolUse
-			return nil
-		}
+// parseFlags registers one flag per hook spec, for the matcher of each, and
+// the flags choosing what setup does and to which settings file
+func parseFlags(args []string) (options, error) {
+	var opts options
+	fs := flag.NewFlagSet("setup", flag.ContinueOnError)
+	fs.BoolVar(&opts.uninstall, "uninstall", false, "remove every claude-hooks command from the settings file, and the hook shim, instead of installing")
+	fs.StringVar(&opts.project, "project", "", "install into the repo `dir`'s .claude/settings.json, to commit with it, instead of ~/.claude/settings.json")
+	fs.BoolVar(&opts.local, "local", false, "with -project, use .claude/settings.local.json, which isn't committed")
+	fs.BoolVar(&opts.build, "build", false, "compile a static claude-hook binary into ~/.claude/bin, so hooks don't compile on every tool call")
+	live := fs.Bool("live", false, "install the shim that runs the checkout with go run, so hook code changes apply at once (the default)")
+	values := make([]*string, len(settings.DefaultHooks))
+	for i, spec := range settings.DefaultHooks {
+		values[i] = fs.String(spec.Flag, strings.ReplaceAll(spec.Matcher, "|", ","),
+			fmt.Sprintf("comma-separated tools that trigger the %s hook (e.g. add NotebookEdit, Task, WebFetch, mcp__.*), or \"none\" to disable it", spec.Name))
 	}
-
-	// No existing matcher found, create new one
-	newMatcher := HookMatcher{
-		Matcher: "Bash",
-		Hooks: []Hook{{
-			Type:    "command",
-			Command: hookCommand,
-		}},
+	if err := fs.Parse(args); err != nil {
+		return options{}, err
 	}
-
-	settings.Hooks["PreToolUse"] = append(preToolUse, newMatcher)
-	return nil
-}
-
-func addPlanReviewHook(settings *ClaudeSettings, hookCommand string) error {
-	// This hook matches ExitPlanMode to review plans with multiple AI models
-	preToolUse := settings.Hooks["PreToolUse"]
-
-	for i, matcher := range preToolUse {
-		if matcher.Matcher == "ExitPlanMode" {
-			// Check if our command already exists
-			for _, hook := range matcher.Hooks {
-				if hook.Command == hookCommand {
-					fmt.Println("PlanReview hook already configured, skipping...")
-					return nil
-				}
-			}
-
-			// Add our hook to existing matcher
-			preToolUse[i].Hooks = append(preToolUse[i].Hooks, Hook{
-				Type:    "command",
-				Command: hookCommand,
-			})
-			settings.Hooks["PreToolUse"] = preToolUse
-			return nil
-		}
+	if opts.build && *live {
+		err := errors.New("-build and -live are mutually exclusive")
+		fmt.Fprintln(fs.Output(), err)
+		return options{}, err
 	}
 
-	// No existing matcher found, create new one
-	newMatcher := HookMatcher{
-		Matcher: "ExitPlanMode",
-		Hooks: []Hook{{
-			Type:    "command",
-			Command: hookCommand,
-		}},
+	opts.matchers = make([]string, len(settings.DefaultHooks))
+	for i, value := range values {
+		opts.matchers[i] = buildMatcher(*value)
 	}
-
-	settings.Hooks["PreToolUse"] = append(preToolUse, newMatcher)
-	return nil
+	return opts, nil
 }
 
-func addSessionStartHook(settings *ClaudeSettings, hookCommand string) error {
-	// Check if our hook already exists in SessionStart
-	sessionStart := settings.Hooks["SessionStart"]
-
-	for i, matcher := range sessionStart {
-		if matcher.Matcher == "startup|compact" {
-			// Check if our command already exists
-			for _, hook := range matcher.Hooks {
-				if hook.Command == hookCommand {
-					fmt.Println("SessionStart hook already configured, skipping...")
-					return nil
-				}
-			}
-
-			// Add our hook to existing matcher
-			sessionStart[i].Hooks = append(sessionStart[i].Hooks, Hook{
-				Type:    "command",
-				Command: hookCommand,
-			})
-			settings.Hooks["SessionStart"] = sessionStart
-			return nil
+// buildMatcher turns "Write, Edit|MultiEdit" into the matcher "Write|Edit|MultiEdit"
+func buildMatcher(tools string) string {
+	var parts []string
+	for _, tool := range strings.FieldsFunc(tools, func(r rune) bool { return r == ',' || r == '|' }) {
+		tool = strings.TrimSpace(tool)
+		if tool != "" && !strings.EqualFold(tool, "none") && !slices.Contains(parts, tool) {
+			parts = append(parts, tool)
 		}
 	}
-
-	// No existing matcher found, create new one
-	newMatcher := HookMatcher{
-		Matcher: "startup|compact",
-		Hooks: []Hook{{
-			Type:    "command",
-			Command: hookCommand,
-		}},
-	}
-
-	settings.Hooks["SessionStart"] = append(sessionStart, newMatcher)
-	return nil
+	return strings.Join(parts, "|")
 }
 
-func writeSettings(settingsPath string, settings *ClaudeSettings) error {
-	data, err := json.MarshalIndent(settings, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshaling settings to JSON: %w", err)
+// configureHooks installs each hook under its matcher and moves our commands off
+// matchers that are no longer configured, leaving everyone else's hooks alone
+func configureHooks(s *settings.Settings, matchers []string) {
+	type managedHook struct{ event, command string }
+	wanted := make(map[managedHook][]string)
+	for i, spec := range settings.DefaultHooks {
+		key := managedHook{spec.Event, settings.HookCommand(spec.Type)}
+		wanted[key] = append(wanted[key], matchers[i])
 	}
 
-	file, err := os.Create(settingsPath)
-	if err != nil {
-		return fmt.Errorf("creating settings file: %w", err)
-	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to close file: %v\n", closeErr)
+	for key, keep := range wanted {
+		for _, existing := range s.MatchersWithHook(key.event, key.command) {
+			if !slices.Contains(keep, existing) {
+				s.RemoveHook(key.event, existing, key.command)
+				fmt.Printf("Removed %s hook from matcher %q\n", key.event, existing)
+			}
 		}
-	}()
-
-	_, err = file.Write(data)
-	if err != nil {
-		return fmt.Errorf("writing settings file: %w", err)
 	}
 
-	return nil
+	for i, spec := range settings.DefaultHooks {
+		if matchers[i] == "" {
+			continue
+		}
+		if !s.AddHook(spec.Event, matchers[i], settings.Hook{Type: "command", Command: settings.HookCommand(spec.Type)}) {
+			fmt.Printf("%s hook already configured, skipping...\n", spec.Name)
+		}
+	}
 }
@@ -1,3 +1,10 @@
 module github.com/brianleishman/claude-hooks
 
-go 1.25
+go 1.25.0
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require (
+	golang.org/x/crypto v0.54.0
+	golang.org/x/sys v0.47.0
+)
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// logFile is the append-only JSONL audit log in the state directory
+const logFile = "audit.jsonl"
+
+// Event records the outcome of a single hook invocation
+type Event struct {
+	Time        time.Time       `json:"time"`
+	Hook        string          `json:"hook"`                  // post-edit, pre-bash, plan-review, session-start, watch
+	Decision    string          `json:"decision"`              // allow, block, deny, ask, or cancelled
+	Rule        string          `json:"rule,omitempty"`        // Rule that blocked, e.g. "mysql-cli"
+	DurationMS  int64           `json:"duration_ms"`           // Wall time of the invocation
+	Tests       map[string]bool `json:"tests,omitempty"`       // Package -> passed, for post-edit test runs
+	Verdicts    []string        `json:"verdicts,omitempty"`    // Plan review verdicts, one per reviewer
+	Cache       string          `json:"cache,omitempty"`       // "hit" or "miss" when a cache was consulted
+	Languages   []string        `json:"languages,omitempty"`   // File types a post-edit run validated
+	Enforcement string          `json:"enforcement,omitempty"` // "warn" or "dry-run" when Decision was only reported, not made
+	Command     string          `json:"command,omitempty"`     // Of pre-bash events, with audit.commands
+	Session     string          `json:"session,omitempty"`     // Claude session the hook ran for
+	Files       int             `json:"files,omitempty"`       // Files a post-edit run checked
+
+	// Exported only, never logged: the log stays free of commands and findings
+	Detail string `json:"-"` // What was stopped: the command, or the findings
+	Secret bool   `json:"-"` // The event is about a detected secret
+}
+
+// Path returns the location of the audit log
+func Path() (string, error) {
+	return state.Path(logFile)
+}
+
+// Record appends ev to the audit log, stamping it with the current time if unset
+func Record(ev Event) error {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshaling audit event: %w", err)
+	}
+
+	// O_APPEND keeps concurrent single-line writes from interleaving
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return nil
+}
+
+// Read returns every event in the audit log at or after since. A missing log is empty.
+func Read(since time.Time) ([]Event, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue // Skip lines truncated by a crash
+		}
+		if ev.Time.Before(since) {
+			continue
+		}
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading audit log: %w", err)
+	}
+
+	return events, nil
+}
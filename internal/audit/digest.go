@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// HookLatency is the latency distribution of one hook
+type HookLatency struct {
+	Hook          string
+	Runs          int
+	P50, P90, P99 time.Duration
+}
+
+// Digest summarizes a window of the audit log for a team retro
+type Digest struct {
+	Since          string // The window, e.g. "7d"
+	From, To       time.Time
+	Events         int
+	Sessions       int     // Distinct sessions with an event
+	FilesValidated int     // Files post-edit checked
+	Blocks         []Count // Blocks, denies, and asks by rule, most common first
+	Reported       int     // Blocks only reported under warn or dry-run
+	Verdicts       []Count
+	Latency        []HookLatency // By hook name
+	// Reviews and ReviewIssues come from the review archive: the plan reviews
+	// saved in the window and their issues by severity
+	Reviews      int
+	ReviewIssues []Count
+}
+
+// NewDigest summarizes events, read for the window since ending at to
+func NewDigest(events []Event, since string, from, to time.Time) Digest {
+	d := Digest{Since: since, From: from, To: to, Events: len(events)}
+	sessions := make(map[string]bool)
+	blocks := make(map[string]int)
+	verdicts := make(map[string]int)
+	durations := make(map[string][]time.Duration)
+	for _, ev := range events {
+		if ev.Session != "" {
+			sessions[ev.Session] = true
+		}
+		if ev.Hook == "post-edit" {
+			d.FilesValidated += ev.Files
+		}
+		if ev.Rule != "" && (ev.Decision == "block" || ev.Decision == "deny" || ev.Decision == "ask") {
+			blocks[ev.Rule]++
+			if ev.Enforcement != "" {
+				d.Reported++
+			}
+		}
+		for _, v := range ev.Verdicts {
+			verdicts[v]++
+		}
+		if ev.Hook != "" && ev.Decision != "cancelled" {
+			durations[ev.Hook] = append(durations[ev.Hook], time.Duration(ev.DurationMS)*time.Millisecond)
+		}
+	}
+	d.Sessions = len(sessions)
+	d.Blocks = sortedCounts(blocks)
+	d.Verdicts = sortedCounts(verdicts)
+	for hook, ds := range durations {
+		slices.Sort(ds)
+		d.Latency = append(d.Latency, HookLatency{Hook: hook, Runs: len(ds), P50: percentile(ds, 50), P90: percentile(ds, 90), P99: percentile(ds, 99)})
+	}
+	slices.SortFunc(d.Latency, func(a, b HookLatency) int { return cmp.Compare(a.Hook, b.Hook) })
+	return d
+}
+
+// percentile is the nearest-rank percentile p of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	return sorted[max(rank, 1)-1]
+}
+
+// WriteDigestMarkdown renders d as Markdown, for pasting into a retro or
+// posting to a chat webhook
+func WriteDigestMarkdown(out io.Writer, d Digest) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# claude-hooks digest: %s to %s\n\n", d.From.Format(time.DateOnly), d.To.Format(time.DateOnly))
+	fmt.Fprintf(&b, "- **Sessions:** %d\n", d.Sessions)
+	fmt.Fprintf(&b, "- **Files validated:** %d\n", d.FilesValidated)
+	fmt.Fprintf(&b, "- **Hook runs:** %d\n", d.Events)
+	blocked := 0
+	for _, c := range d.Blocks {
+		blocked += c.Count
+	}
+	fmt.Fprintf(&b, "- **Blocks:** %d", blocked)
+	if d.Reported > 0 {
+		fmt.Fprintf(&b, " (%d only reported)", d.Reported)
+	}
+	b.WriteString("\n")
+	if d.Reviews > 0 {
+		fmt.Fprintf(&b, "- **Plan reviews:** %d\n", d.Reviews)
+	}
+
+	b.WriteString("\n## Blocks by rule\n\n")
+	writeCountTable(&b, "Rule", d.Blocks)
+	b.WriteString("\n## Plan review verdicts\n\n")
+	writeCountTable(&b, "Verdict", d.Verdicts)
+	if d.Reviews > 0 {
+		b.WriteString("\n## Plan review issues\n\n")
+		writeCountTable(&b, "Severity", d.ReviewIssues)
+	}
+
+	b.WriteString("\n## Latency\n\n")
+	if len(d.Latency) == 0 {
+		b.WriteString("_None._\n")
+	} else {
+		b.WriteString("| Hook | Runs | p50 | p90 | p99 |\n|---|---:|---:|---:|---:|\n")
+		for _, l := range d.Latency {
+			fmt.Fprintf(&b, "| %s | %d | %s | %s | %s |\n", l.Hook, l.Runs, l.P50.Round(time.Millisecond), l.P90.Round(time.Millisecond), l.P99.Round(time.Millisecond))
+		}
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func writeCountTable(b *strings.Builder, heading string, counts []Count) {
+	if len(counts) == 0 {
+		b.WriteString("_None._\n")
+		return
+	}
+	fmt.Fprintf(b, "| %s | Count |\n|---|---:|\n", heading)
+	for _, c := range counts {
+		fmt.Fprintf(b, "| %s | %d |\n", strings.ReplaceAll(c.Name, "|", `\|`), c.Count)
+	}
+}
+
+// WriteDigestHTML renders d as a standalone HTML page
+func WriteDigestHTML(out io.Writer, d Digest) error {
+	return digestHTMLTemplate.Execute(out, d)
+}
+
+var digestHTMLTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"ms":   func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+	"date": func(t time.Time) string { return t.Format(time.DateOnly) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>claude-hooks digest</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 12px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>claude-hooks digest: {{date .From}} to {{date .To}}</h1>
+<ul>
+<li>Sessions: {{.Sessions}}</li>
+<li>Files validated: {{.FilesValidated}}</li>
+<li>Hook runs: {{.Events}}</li>
+{{if .Reported}}<li>Blocks only reported: {{.Reported}}</li>{{end}}
+{{if .Reviews}}<li>Plan reviews: {{.Reviews}}</li>{{end}}
+</ul>
+
+<h2>Blocks by rule</h2>
+<table><tr><th>Rule</th><th>Count</th></tr>
+{{range .Blocks}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+
+<h2>Plan review verdicts</h2>
+<table><tr><th>Verdict</th><th>Count</th></tr>
+{{range .Verdicts}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{if .Reviews}}
+<h2>Plan review issues</h2>
+<table><tr><th>Severity</th><th>Count</th></tr>
+{{range .ReviewIssues}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Latency</h2>
+<table><tr><th>Hook</th><th>Runs</th><th>p50</th><th>p90</th><th>p99</th></tr>
+{{range .Latency}}<tr><td>{{.Hook}}</td><td>{{.Runs}}</td><td>{{ms .P50}}</td><td>{{ms .P90}}</td><td>{{ms .P99}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigest(t *testing.T) {
+	to := time.Date(2026, 1, 8, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Hook: "post-edit", Decision: "allow", Session: "s1", Files: 2, DurationMS: 100},
+		{Hook: "post-edit", Decision: "block", Rule: "go-post-edit", Session: "s1", Files: 1, DurationMS: 300},
+		{Hook: "post-edit", Decision: "allow", Session: "s2", Files: 3, DurationMS: 200},
+		{Hook: "pre-bash", Decision: "deny", Rule: "mysql-cli", Session: "s2", DurationMS: 5},
+		{Hook: "pre-bash", Decision: "ask", Rule: "bash-rule:prod|db", Enforcement: "warn", DurationMS: 7},
+		{Hook: "plan-review", Decision: "allow", Verdicts: []string{"approve", "revise"}, DurationMS: 9000},
+	}
+	d := NewDigest(events, "7d", to.Add(-7*24*time.Hour), to)
+
+	if d.Sessions != 2 || d.FilesValidated != 6 || d.Reported != 1 || len(d.Blocks) != 3 {
+		t.Errorf("Unexpected digest %+v", d)
+	}
+	if len(d.Latency) != 3 || d.Latency[0].Hook != "plan-review" || d.Latency[1].P50 != 200*time.Millisecond || d.Latency[1].P99 != 300*time.Millisecond {
+		t.Errorf("Unexpected latency %+v", d.Latency)
+	}
+
+	var out strings.Builder
+	if err := WriteDigestMarkdown(&out, d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# claude-hooks digest: 2026-01-01 to 2026-01-08", "- **Sessions:** 2", "- **Blocks:** 3 (1 only reported)", `| bash-rule:prod\|db | 1 |`, "| post-edit | 3 | 200ms | 300ms | 300ms |"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the digest to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := WriteDigestHTML(&out, d); err != nil || !strings.Contains(out.String(), "<td>mysql-cli</td>") {
+		t.Errorf("Unexpected HTML digest (%v):\n%s", err, out.String())
+	}
+}
@@ -0,0 +1,258 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/network"
+	"github.com/brianleishman/claude-hooks/internal/version"
+)
+
+// ExportSchema identifies the JSON export format; it changes only when a
+// field is renamed or removed
+const ExportSchema = "claude-hooks/audit-export/v1"
+
+// Kinds of exported events
+const (
+	KindCommand = "commands"
+	KindSecret  = "secrets"
+)
+
+// Redacted replaces the value of a redacted field
+const Redacted = "[redacted]"
+
+// exportTimeout keeps a slow collector from delaying the hook's decision
+const exportTimeout = 3 * time.Second
+
+// Exported is an audit event as a SIEM receives it in the JSON format
+type Exported struct {
+	Schema      string    `json:"schema"`
+	Time        time.Time `json:"time"`
+	Kind        string    `json:"kind"` // commands or secrets
+	Hook        string    `json:"hook"`
+	Decision    string    `json:"decision"`
+	Rule        string    `json:"rule"`
+	Enforcement string    `json:"enforcement,omitempty"` // warn or dry-run when nothing was stopped
+	Severity    int       `json:"severity"`              // 0-10, as in CEF
+	Detail      string    `json:"detail,omitempty"`      // The command, or the findings
+	User        string    `json:"user,omitempty"`
+	Host        string    `json:"host,omitempty"`
+	Project     string    `json:"project,omitempty"`
+	Session     string    `json:"session,omitempty"`
+	Profile     string    `json:"profile,omitempty"`
+}
+
+// Context is what an exported event carries besides the audit event
+type Context struct {
+	Session string
+	Project string
+	Profile string
+}
+
+// Kind returns what an event is to the export: a command stopped before it
+// ran, a secret found, or "" for an event that isn't exported
+func Kind(ev Event) string {
+	switch {
+	case ev.Secret:
+		return KindSecret
+	case ev.Hook == "pre-bash" && (ev.Decision == "deny" || ev.Decision == "ask" || ev.Decision == "block"):
+		return KindCommand
+	}
+	return ""
+}
+
+// Export sends ev to cfg's target, to the file at target when it's a path,
+// when it's of a kind cfg exports. Events of no kind are ignored.
+func Export(cfg config.AuditExportConfig, target string, ev Event, c Context) error {
+	kind := Kind(ev)
+	if target == "" || kind == "" || (len(cfg.Events) > 0 && !slices.Contains(cfg.Events, kind)) {
+		return nil
+	}
+	x := newExported(ev, kind, c)
+	redact(&x, cfg.Redact)
+
+	var line []byte
+	contentType := "application/json"
+	if cfg.Format == "cef" {
+		line = []byte(CEF(x))
+		contentType = "text/plain"
+	} else {
+		var err error
+		if line, err = json.Marshal(x); err != nil {
+			return fmt.Errorf("marshaling audit export: %w", err)
+		}
+	}
+	return send(target, line, contentType)
+}
+
+func newExported(ev Event, kind string, c Context) Exported {
+	x := Exported{
+		Schema:      ExportSchema,
+		Time:        ev.Time.UTC(),
+		Kind:        kind,
+		Hook:        ev.Hook,
+		Decision:    ev.Decision,
+		Rule:        ev.Rule,
+		Enforcement: ev.Enforcement,
+		Detail:      ev.Detail,
+		Project:     c.Project,
+		Session:     c.Session,
+		Profile:     c.Profile,
+	}
+	if x.Time.IsZero() {
+		x.Time = time.Now().UTC()
+	}
+	switch {
+	case ev.Enforcement != "":
+		x.Severity = 3 // Only reported
+	case kind == KindSecret:
+		x.Severity = 8
+	case ev.Decision == "ask":
+		x.Severity = 4
+	default:
+		x.Severity = 6
+	}
+	if u, err := user.Current(); err == nil {
+		x.User = u.Username
+	}
+	x.Host, _ = os.Hostname()
+	return x
+}
+
+// redact blanks out the fields named in fields
+func redact(x *Exported, fields []string) {
+	for _, f := range fields {
+		var v *string
+		switch f {
+		case "detail":
+			v = &x.Detail
+		case "user":
+			v = &x.User
+		case "host":
+			v = &x.Host
+		case "project":
+			v = &x.Project
+		case "session":
+			v = &x.Session
+		}
+		if v != nil && *v != "" {
+			*v = Redacted
+		}
+	}
+}
+
+// CEF renders x as an ArcSight Common Event Format line
+func CEF(x Exported) string {
+	name := "Command blocked"
+	if x.Kind == KindSecret {
+		name = "Secret detected"
+	}
+	header := []string{"CEF:0", "claude-hooks", "claude-hook", version.Current(), x.Rule, name, strconv.Itoa(x.Severity)}
+	for i := 1; i < len(header); i++ {
+		header[i] = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", " ").Replace(header[i])
+	}
+
+	ext := []struct{ key, value string }{
+		{"rt", strconv.FormatInt(x.Time.UnixMilli(), 10)},
+		{"act", x.Decision},
+		{"cat", x.Kind},
+		{"suser", x.User},
+		{"shost", x.Host},
+		{"msg", x.Detail},
+		{"cs1Label", "hook"}, {"cs1", x.Hook},
+		{"cs2Label", "session"}, {"cs2", x.Session},
+		{"cs3Label", "project"}, {"cs3", x.Project},
+		{"cs4Label", "profile"}, {"cs4", x.Profile},
+		{"cs5Label", "enforcement"}, {"cs5", x.Enforcement},
+	}
+	escape := strings.NewReplacer(`\`, `\\`, "=", `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+	var pairs []string
+	for i, kv := range ext {
+		if kv.value == "" || (strings.HasSuffix(kv.key, "Label") && ext[i+1].value == "") {
+			continue
+		}
+		pairs = append(pairs, kv.key+"="+escape.Replace(kv.value))
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(pairs, " ")
+}
+
+// send writes line to target: POSTs it to an http(s) URL, writes it with a
+// newline to a socket, or appends it to a file
+func send(target string, line []byte, contentType string) error {
+	u, err := url.Parse(target)
+	if err != nil || !strings.Contains(target, "://") {
+		return appendLine(target, line)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return post(target, line, contentType)
+	case "unix", "udp", "tcp":
+		address := u.Host
+		if u.Scheme == "unix" {
+			address = u.Path
+		}
+		conn, err := net.DialTimeout(u.Scheme, address, exportTimeout)
+		if err != nil {
+			return fmt.Errorf("connecting to audit export: %w", err)
+		}
+		defer conn.Close()
+		_ = conn.SetWriteDeadline(time.Now().Add(exportTimeout))
+		if _, err := conn.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("writing audit export: %w", err)
+		}
+		return nil
+	case "file":
+		return appendLine(u.Path, line)
+	}
+	return fmt.Errorf("unknown audit export target %q (want a file, unix://, udp://, tcp://, or http(s)://)", target)
+}
+
+func appendLine(path string, line []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening audit export: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing audit export: %w", err)
+	}
+	return nil
+}
+
+func post(target string, body []byte, contentType string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating audit export request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	client, err := network.Client(0)
+	if err != nil {
+		return fmt.Errorf("sending audit export: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending audit export: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("sending audit export: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func TestExportJSON(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "siem.jsonl")
+	cfg := config.AuditExportConfig{Redact: []string{"detail", "host"}}
+	c := Context{Session: "s1", Project: "/repo"}
+
+	events := []Event{
+		{Hook: "pre-bash", Decision: "deny", Rule: "mysql-cli", Detail: "mysql -p secret"},
+		{Hook: "pre-bash", Decision: "deny", Rule: "pre-push", Secret: true},
+		{Hook: "pre-bash", Decision: "allow"},
+		{Hook: "post-edit", Decision: "block", Rule: "go-vet"},
+	}
+	for _, ev := range events {
+		if err := Export(cfg, target, ev, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected the blocked command and the secret only, got %d lines:\n%s", len(lines), data)
+	}
+	var x Exported
+	if err := json.Unmarshal([]byte(lines[0]), &x); err != nil {
+		t.Fatal(err)
+	}
+	if x.Schema != ExportSchema || x.Kind != KindCommand || x.Rule != "mysql-cli" || x.Session != "s1" || x.Project != "/repo" {
+		t.Errorf("Unexpected export %+v", x)
+	}
+	if x.Detail != Redacted || x.Host != Redacted {
+		t.Errorf("detail and host should be redacted, got %q and %q", x.Detail, x.Host)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &x); err != nil || x.Kind != KindSecret || x.Severity != 8 {
+		t.Errorf("Unexpected secret export %+v, %v", x, err)
+	}
+
+	// Only secrets
+	cfg.Events = []string{KindSecret}
+	if err := Export(cfg, target, events[0], c); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.ReadFile(target); len(after) != len(data) {
+		t.Error("A blocked command was exported with only secrets selected")
+	}
+}
+
+func TestExportCEF(t *testing.T) {
+	var body, contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, contentType = string(data), r.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+
+	ev := Event{Time: time.UnixMilli(1700000000000), Hook: "pre-bash", Decision: "deny", Rule: "bash-rule:a|b", Detail: "psql -c 'a=1'\nx"}
+	if err := Export(config.AuditExportConfig{Format: "cef"}, server.URL, ev, Context{Session: "s1"}); err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "text/plain" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	for _, want := range []string{`CEF:0|claude-hooks|claude-hook|`, `|bash-rule:a\|b|Command blocked|6|`, "rt=1700000000000", "act=deny", `msg=psql -c 'a\=1'\nx`, "cs2Label=session cs2=s1"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("CEF %q doesn't contain %q", body, want)
+		}
+	}
+	if strings.Contains(body, "cs3Label") {
+		t.Errorf("CEF %q labels the empty project", body)
+	}
+}
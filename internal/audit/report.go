@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ParseSince parses a lookback window such as "7d", "12h", or "30m"
+func ParseSince(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid day count %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 7d, 12h)", value)
+	}
+	return d, nil
+}
+
+// WriteStatsTable renders stats as aligned terminal tables
+func WriteStatsTable(out io.Writer, stats Stats, since string) error {
+	fmt.Fprintf(out, "📊 claude-hooks stats (last %s, %d events)\n", since, stats.Events)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "\nBLOCKING RULE\tCOUNT")
+	for _, c := range stats.BlockingRules {
+		fmt.Fprintf(tw, "%s\t%d\n", c.Name, c.Count)
+	}
+	if len(stats.BlockingRules) == 0 {
+		fmt.Fprintln(tw, "(none)\t")
+	}
+
+	fmt.Fprintln(tw, "\nDAY\tAVG POST-EDIT LATENCY\tRUNS")
+	for _, l := range stats.Latency {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", l.Day, l.Average.Round(time.Millisecond), l.Runs)
+	}
+	if len(stats.Latency) == 0 {
+		fmt.Fprintln(tw, "(none)\t\t")
+	}
+
+	fmt.Fprintln(tw, "\nPACKAGE\tTEST RUNS\tFAILURE RATE")
+	for _, p := range stats.Tests {
+		fmt.Fprintf(tw, "%s\t%d\t%.0f%%\n", p.Package, p.Runs, p.FailureRate()*100)
+	}
+	if len(stats.Tests) == 0 {
+		fmt.Fprintln(tw, "(none)\t\t")
+	}
+
+	fmt.Fprintln(tw, "\nPLAN REVIEW VERDICT\tCOUNT")
+	for _, c := range stats.Verdicts {
+		fmt.Fprintf(tw, "%s\t%d\n", c.Name, c.Count)
+	}
+	if len(stats.Verdicts) == 0 {
+		fmt.Fprintln(tw, "(none)\t")
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if rate := stats.CacheHitRate(); rate >= 0 {
+		_, err := fmt.Fprintf(out, "\nCache hit rate: %.0f%% (%d/%d)\n", rate*100, stats.CacheHits, stats.CacheHits+stats.CacheMisses)
+		return err
+	}
+	_, err := fmt.Fprintln(out, "\nCache hit rate: n/a (no cache lookups recorded)")
+	return err
+}
+
+// WriteStatsHTML renders stats as a standalone HTML page
+func WriteStatsHTML(out io.Writer, stats Stats, since string) error {
+	return statsHTMLTemplate.Execute(out, struct {
+		Stats
+		Since string
+	}{stats, since})
+}
+
+var statsHTMLTemplate = template.Must(template.New("stats").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"ms":      func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>claude-hooks stats</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 12px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>claude-hooks stats</h1>
+<p>Last {{.Since}}, {{.Events}} events.</p>
+
+<h2>Most common blocking rules</h2>
+<table><tr><th>Rule</th><th>Count</th></tr>
+{{range .BlockingRules}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+
+<h2>Average post-edit latency</h2>
+<table><tr><th>Day</th><th>Average</th><th>Runs</th></tr>
+{{range .Latency}}<tr><td>{{.Day}}</td><td>{{ms .Average}}</td><td>{{.Runs}}</td></tr>
+{{end}}</table>
+
+<h2>Test failure rate per package</h2>
+<table><tr><th>Package</th><th>Runs</th><th>Failure rate</th></tr>
+{{range .Tests}}<tr><td>{{.Package}}</td><td>{{.Runs}}</td><td>{{percent .FailureRate}}</td></tr>
+{{end}}</table>
+
+<h2>Plan review verdicts</h2>
+<table><tr><th>Verdict</th><th>Count</th></tr>
+{{range .Verdicts}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+
+<h2>Cache</h2>
+<p>{{if ge .CacheHitRate 0.0}}Hit rate {{percent .CacheHitRate}} ({{.CacheHits}} hits, {{.CacheMisses}} misses){{else}}No cache lookups recorded{{end}}</p>
+</body>
+</html>
+`))
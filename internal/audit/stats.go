@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Count pairs a name with how often it occurred
+type Count struct {
+	Name  string
+	Count int
+}
+
+// DayLatency is the average post-edit latency for one day
+type DayLatency struct {
+	Day     string // YYYY-MM-DD
+	Average time.Duration
+	Runs    int
+}
+
+// PackageTests is the test failure rate of one package
+type PackageTests struct {
+	Package  string
+	Runs     int
+	Failures int
+}
+
+// FailureRate returns the fraction of runs that failed
+func (p PackageTests) FailureRate() float64 {
+	if p.Runs == 0 {
+		return 0
+	}
+	return float64(p.Failures) / float64(p.Runs)
+}
+
+// Stats summarizes a set of audit events
+type Stats struct {
+	Events        int
+	BlockingRules []Count // Most common first
+	Latency       []DayLatency
+	Tests         []PackageTests // Highest failure rate first
+	Verdicts      []Count
+	CacheHits     int
+	CacheMisses   int
+}
+
+// CacheHitRate returns the fraction of cache lookups that hit, or -1 if none were recorded
+func (s Stats) CacheHitRate() float64 {
+	total := s.CacheHits + s.CacheMisses
+	if total == 0 {
+		return -1
+	}
+	return float64(s.CacheHits) / float64(total)
+}
+
+// Aggregate computes report statistics from events
+func Aggregate(events []Event) Stats {
+	stats := Stats{Events: len(events)}
+
+	rules := make(map[string]int)
+	verdicts := make(map[string]int)
+	tests := make(map[string]*PackageTests)
+	latencyTotal := make(map[string]time.Duration)
+	latencyRuns := make(map[string]int)
+
+	for _, ev := range events {
+		if ev.Rule != "" && (ev.Decision == "block" || ev.Decision == "deny") {
+			rules[ev.Rule]++
+		}
+
+		if ev.Hook == "post-edit" {
+			day := ev.Time.Local().Format(time.DateOnly)
+			latencyTotal[day] += time.Duration(ev.DurationMS) * time.Millisecond
+			latencyRuns[day]++
+		}
+
+		for pkg, passed := range ev.Tests {
+			pt := tests[pkg]
+			if pt == nil {
+				pt = &PackageTests{Package: pkg}
+				tests[pkg] = pt
+			}
+			pt.Runs++
+			if !passed {
+				pt.Failures++
+			}
+		}
+
+		for _, v := range ev.Verdicts {
+			verdicts[v]++
+		}
+
+		switch ev.Cache {
+		case "hit":
+			stats.CacheHits++
+		case "miss":
+			stats.CacheMisses++
+		}
+	}
+
+	stats.BlockingRules = sortedCounts(rules)
+	stats.Verdicts = sortedCounts(verdicts)
+
+	for day, total := range latencyTotal {
+		runs := latencyRuns[day]
+		stats.Latency = append(stats.Latency, DayLatency{Day: day, Average: total / time.Duration(runs), Runs: runs})
+	}
+	slices.SortFunc(stats.Latency, func(a, b DayLatency) int { return cmp.Compare(a.Day, b.Day) })
+
+	for _, pt := range tests {
+		stats.Tests = append(stats.Tests, *pt)
+	}
+	slices.SortFunc(stats.Tests, func(a, b PackageTests) int {
+		if c := cmp.Compare(b.FailureRate(), a.FailureRate()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Package, b.Package)
+	})
+
+	return stats
+}
+
+func sortedCounts(m map[string]int) []Count {
+	counts := make([]Count, 0, len(m))
+	for name, n := range m {
+		counts = append(counts, Count{Name: name, Count: n})
+	}
+	slices.SortFunc(counts, func(a, b Count) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return counts
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+
+	old := Event{Time: time.Now().Add(-48 * time.Hour), Hook: "pre-bash", Decision: "allow"}
+	recent := Event{Hook: "pre-bash", Decision: "deny", Rule: "mysql-cli"}
+
+	for _, ev := range []Event{old, recent} {
+		if err := Record(ev); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	events, err := Read(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Rule != "mysql-cli" {
+		t.Fatalf("Expected only the recent event, got %+v", events)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	day := time.Date(2026, 1, 2, 12, 0, 0, 0, time.Local)
+	events := []Event{
+		{Time: day, Hook: "pre-bash", Decision: "deny", Rule: "mysql-cli"},
+		{Time: day, Hook: "pre-bash", Decision: "deny", Rule: "mysql-cli"},
+		{Time: day, Hook: "pre-bash", Decision: "deny", Rule: "protected-branch"},
+		{Time: day, Hook: "post-edit", Decision: "allow", DurationMS: 100, Tests: map[string]bool{"./a": true}, Cache: "hit"},
+		{Time: day, Hook: "post-edit", Decision: "block", Rule: "go-post-edit", DurationMS: 300, Tests: map[string]bool{"./a": false, "./b": true}, Cache: "miss"},
+		{Time: day, Hook: "plan-review", Decision: "allow", Verdicts: []string{"approve", "revise", "approve"}},
+	}
+
+	stats := Aggregate(events)
+
+	if len(stats.BlockingRules) != 3 || stats.BlockingRules[0] != (Count{"mysql-cli", 2}) {
+		t.Errorf("Unexpected blocking rules: %+v", stats.BlockingRules)
+	}
+	if len(stats.Latency) != 1 || stats.Latency[0].Average != 200*time.Millisecond {
+		t.Errorf("Unexpected latency: %+v", stats.Latency)
+	}
+	if len(stats.Tests) != 2 || stats.Tests[0].Package != "./a" || stats.Tests[0].FailureRate() != 0.5 {
+		t.Errorf("Unexpected test stats: %+v", stats.Tests)
+	}
+	if stats.Verdicts[0] != (Count{"approve", 2}) {
+		t.Errorf("Unexpected verdicts: %+v", stats.Verdicts)
+	}
+	if stats.CacheHitRate() != 0.5 {
+		t.Errorf("Expected 50%% cache hit rate, got %v", stats.CacheHitRate())
+	}
+}
@@ -0,0 +1,110 @@
+// Package bench summarizes how long the stages of the post-edit pipeline take
+// over repeated runs, for `claude-hook bench`
+package bench
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// Timing is how long one stage of a pipeline run took, e.g. "go hook" or
+// "duplicates"
+type Timing struct {
+	Stage    string
+	Duration time.Duration
+}
+
+// Run is one timed run of the pipeline
+type Run struct {
+	Total  time.Duration
+	Stages []Timing
+}
+
+// Summary is a stage's timings over every run
+type Summary struct {
+	Stage string
+	Runs  int // Runs the stage took part in
+	Mean  time.Duration
+	Min   time.Duration
+	Max   time.Duration
+	// Share is the stage's part of the mean total, from 0 to 1
+	Share float64
+}
+
+// Summarize returns the summary of each stage, slowest first, and of the
+// runs' totals. A stage timed more than once in a run counts once, with the
+// durations added up.
+func Summarize(runs []Run) (stages []Summary, total Summary) {
+	byStage := make(map[string][]time.Duration)
+	var order []string
+	var totals []time.Duration
+	for _, run := range runs {
+		totals = append(totals, run.Total)
+		inRun := make(map[string]time.Duration)
+		for _, t := range run.Stages {
+			if _, ok := inRun[t.Stage]; !ok && !slices.Contains(order, t.Stage) {
+				order = append(order, t.Stage)
+			}
+			inRun[t.Stage] += t.Duration
+		}
+		for stage, d := range inRun {
+			byStage[stage] = append(byStage[stage], d)
+		}
+	}
+
+	total = summarize("total", totals)
+	total.Share = 1
+	for _, stage := range order {
+		s := summarize(stage, byStage[stage])
+		if total.Mean > 0 {
+			// Averaged over every run, so a stage that only sometimes runs
+			// isn't counted as if it always did
+			s.Share = float64(sum(byStage[stage])) / float64(len(runs)) / float64(total.Mean)
+		}
+		stages = append(stages, s)
+	}
+	slices.SortStableFunc(stages, func(a, b Summary) int { return cmp.Compare(b.Share, a.Share) })
+	return stages, total
+}
+
+func summarize(stage string, durations []time.Duration) Summary {
+	s := Summary{Stage: stage, Runs: len(durations)}
+	if len(durations) == 0 {
+		return s
+	}
+	s.Min, s.Max = slices.Min(durations), slices.Max(durations)
+	s.Mean = sum(durations) / time.Duration(len(durations))
+	return s
+}
+
+func sum(durations []time.Duration) time.Duration {
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total
+}
+
+// WriteTable writes the summaries of runs as a table, slowest stage first,
+// followed by the totals
+func WriteTable(w io.Writer, runs []Run) error {
+	stages, total := Summarize(runs)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Stage\tRuns\tMean\tMin\tMax\tShare\t")
+	for _, s := range append(stages, total) {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%.0f%%\t\n", s.Stage, s.Runs, round(s.Mean), round(s.Min), round(s.Max), 100*s.Share)
+	}
+	return tw.Flush()
+}
+
+// round keeps durations readable: whole milliseconds, or microseconds below one
+func round(d time.Duration) time.Duration {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
@@ -0,0 +1,58 @@
+package bench
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	ms := time.Millisecond
+	runs := []Run{
+		{Total: 100 * ms, Stages: []Timing{{"go hook", 60 * ms}, {"fixes", 10 * ms}, {"fixes", 10 * ms}}},
+		{Total: 300 * ms, Stages: []Timing{{"go hook", 180 * ms}, {"duplicates", 100 * ms}}},
+	}
+	stages, total := Summarize(runs)
+
+	if total.Mean != 200*ms || total.Min != 100*ms || total.Max != 300*ms || total.Runs != 2 {
+		t.Errorf("Unexpected total %+v", total)
+	}
+	var names []string
+	for _, s := range stages {
+		names = append(names, s.Stage)
+	}
+	if got := strings.Join(names, ","); got != "go hook,duplicates,fixes" {
+		t.Fatalf("Expected stages slowest first, got %s", got)
+	}
+
+	goHook := stages[0]
+	if goHook.Mean != 120*ms || goHook.Min != 60*ms || goHook.Max != 180*ms || goHook.Share != 0.6 {
+		t.Errorf("Unexpected go hook summary %+v", goHook)
+	}
+	// Both fixes timings of the first run count as one, and the run without
+	// any doesn't make the stage look slower than it is
+	fixes := stages[2]
+	if fixes.Runs != 1 || fixes.Mean != 20*ms || fixes.Share != 0.05 {
+		t.Errorf("Unexpected fixes summary %+v", fixes)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	var out strings.Builder
+	runs := []Run{{Total: 1500 * time.Microsecond, Stages: []Timing{{"go hook", 1234 * time.Microsecond}}}}
+	if err := WriteTable(&out, runs); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header, one stage, and the total, got:\n%s", out.String())
+	}
+	for i, want := range []string{"Stage", "go hook", "total"} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("Expected line %d to contain %q, got %q", i, want, lines[i])
+		}
+	}
+	if !strings.Contains(lines[1], "1ms") || !strings.Contains(lines[1], "82%") {
+		t.Errorf("Expected the go hook's rounded mean and share, got %q", lines[1])
+	}
+}
@@ -0,0 +1,170 @@
+package ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Bitbucket Pipelines exposes an authenticated proxy, so builds can publish Code
+// Insights reports without credentials as long as they use plain http
+const (
+	BitbucketAPI   = "http://api.bitbucket.org"
+	BitbucketProxy = "http://localhost:29418"
+)
+
+const (
+	bitbucketReportID = "claude-hooks"
+	// The annotations endpoint accepts at most 100 per request and 1000 per report
+	bitbucketBatch          = 100
+	bitbucketMaxAnnotations = 1000
+	bitbucketSummaryLimit   = 450
+	bitbucketTimeout        = 30 * time.Second
+)
+
+// BitbucketReport is a Code Insights report with its annotations
+type BitbucketReport struct {
+	Report      bitbucketReport       `json:"report"`
+	Annotations []bitbucketAnnotation `json:"annotations"`
+}
+
+type bitbucketReport struct {
+	Title      string          `json:"title"`
+	Details    string          `json:"details"`
+	ReportType string          `json:"report_type"`
+	Reporter   string          `json:"reporter"`
+	Result     string          `json:"result"`
+	Data       []bitbucketData `json:"data"`
+}
+
+type bitbucketData struct {
+	Title string `json:"title"`
+	Type  string `json:"type"`
+	Value int    `json:"value"`
+}
+
+type bitbucketAnnotation struct {
+	ExternalID     string `json:"external_id"`
+	AnnotationType string `json:"annotation_type"`
+	Path           string `json:"path,omitempty"`
+	Line           int    `json:"line,omitempty"`
+	Summary        string `json:"summary"`
+	Details        string `json:"details,omitempty"`
+	Severity       string `json:"severity"`
+	Result         string `json:"result"`
+}
+
+// NewBitbucketReport builds the Code Insights report for findings
+func NewBitbucketReport(findings []Finding, checked int) BitbucketReport {
+	result := "PASSED"
+	if Blocking(findings) {
+		result = "FAILED"
+	}
+	report := BitbucketReport{
+		Report: bitbucketReport{
+			Title:      "claude-hooks",
+			Details:    fmt.Sprintf("%d finding(s) in %d changed file(s)", len(findings), checked),
+			ReportType: "BUG",
+			Reporter:   "claude-hooks",
+			Result:     result,
+			Data: []bitbucketData{
+				{Title: "Files checked", Type: "NUMBER", Value: checked},
+				{Title: "Findings", Type: "NUMBER", Value: len(findings)},
+			},
+		},
+		Annotations: []bitbucketAnnotation{},
+	}
+
+	for _, f := range findings[:min(len(findings), bitbucketMaxAnnotations)] {
+		annotation := bitbucketAnnotation{
+			ExternalID:     fingerprint(f),
+			AnnotationType: "BUG",
+			Path:           f.File,
+			Line:           f.Line,
+			Summary:        truncate(firstLine(f.Message), bitbucketSummaryLimit),
+			Severity:       "LOW",
+			Result:         "PASSED",
+		}
+		if annotation.Summary != f.Message {
+			annotation.Details = f.Message
+		}
+		if f.Rule != "" {
+			annotation.Summary = truncate(f.Rule+": "+annotation.Summary, bitbucketSummaryLimit)
+		}
+		switch f.Severity {
+		case "error":
+			annotation.Severity, annotation.Result = "HIGH", "FAILED"
+		case "warning":
+			annotation.AnnotationType, annotation.Severity = "CODE_SMELL", "MEDIUM"
+		}
+		report.Annotations = append(report.Annotations, annotation)
+	}
+	return report
+}
+
+// WriteBitbucketReport writes the Code Insights report and annotations as JSON
+func WriteBitbucketReport(w io.Writer, findings []Finding, checked int) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(NewBitbucketReport(findings, checked)); err != nil {
+		return fmt.Errorf("writing Bitbucket report: %w", err)
+	}
+	return nil
+}
+
+// PublishBitbucket uploads the report to the Code Insights API for commit in repo
+// (workspace/slug), replacing any earlier claude-hooks report on that commit
+func PublishBitbucket(ctx context.Context, client *http.Client, apiURL, repo, commit string, report BitbucketReport) error {
+	ctx, cancel := context.WithTimeout(ctx, bitbucketTimeout)
+	defer cancel()
+
+	reportURL := fmt.Sprintf("%s/2.0/repositories/%s/commit/%s/reports/%s", apiURL, repo, url.PathEscape(commit), bitbucketReportID)
+	if err := bitbucketRequest(ctx, client, http.MethodPut, reportURL, report.Report); err != nil {
+		return fmt.Errorf("publishing Bitbucket report: %w", err)
+	}
+	for start := 0; start < len(report.Annotations); start += bitbucketBatch {
+		batch := report.Annotations[start:min(start+bitbucketBatch, len(report.Annotations))]
+		if err := bitbucketRequest(ctx, client, http.MethodPost, reportURL+"/annotations", batch); err != nil {
+			return fmt.Errorf("publishing Bitbucket annotations: %w", err)
+		}
+	}
+	return nil
+}
+
+func bitbucketRequest(ctx context.Context, client *http.Client, method, target string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s %s", method, target, resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
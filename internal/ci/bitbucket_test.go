@@ -0,0 +1,86 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewBitbucketReport(t *testing.T) {
+	report := NewBitbucketReport([]Finding{
+		{File: "a.go", Line: 3, Severity: "error", Rule: "go", Message: "undefined: x\nmore detail"},
+		{File: "b.ts", Line: 1, Severity: "warning", Rule: "eslint", Message: "unused"},
+	}, 2)
+
+	if report.Report.Result != "FAILED" {
+		t.Errorf("Expected a FAILED report with an error finding, got %s", report.Report.Result)
+	}
+	if len(report.Annotations) != 2 {
+		t.Fatalf("Expected 2 annotations, got %d", len(report.Annotations))
+	}
+	first := report.Annotations[0]
+	if first.Summary != "go: undefined: x" || first.Details != "undefined: x\nmore detail" || first.Severity != "HIGH" || first.Result != "FAILED" {
+		t.Errorf("Unexpected error annotation: %+v", first)
+	}
+	second := report.Annotations[1]
+	if second.AnnotationType != "CODE_SMELL" || second.Severity != "MEDIUM" || second.Result != "PASSED" || second.Details != "" {
+		t.Errorf("Unexpected warning annotation: %+v", second)
+	}
+
+	if got := NewBitbucketReport(nil, 1).Report.Result; got != "PASSED" {
+		t.Errorf("Expected a PASSED report without findings, got %s", got)
+	}
+}
+
+func TestPublishBitbucket(t *testing.T) {
+	var requests []string
+	var annotations int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/annotations") {
+			var batch []bitbucketAnnotation
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &batch); err != nil || len(batch) > bitbucketBatch {
+				http.Error(w, "bad batch", http.StatusBadRequest)
+				return
+			}
+			annotations += len(batch)
+		}
+	}))
+	defer server.Close()
+
+	var findings []Finding
+	for i := range 150 {
+		findings = append(findings, Finding{File: "a.go", Line: i + 1, Severity: "error", Rule: "go", Message: fmt.Sprintf("problem %d", i)})
+	}
+	report := NewBitbucketReport(findings, 1)
+	if err := PublishBitbucket(context.Background(), server.Client(), server.URL, "team/repo", "abc123", report); err != nil {
+		t.Fatalf("PublishBitbucket failed: %v", err)
+	}
+
+	reportPath := "/2.0/repositories/team/repo/commit/abc123/reports/claude-hooks"
+	want := []string{"PUT " + reportPath, "POST " + reportPath + "/annotations", "POST " + reportPath + "/annotations"}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected requests %v, got %v", want, requests)
+	}
+	if annotations != 150 {
+		t.Errorf("Expected 150 annotations uploaded, got %d", annotations)
+	}
+}
+
+func TestPublishBitbucketError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no access", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := PublishBitbucket(context.Background(), server.Client(), server.URL, "team/repo", "abc123", NewBitbucketReport(nil, 0))
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected a 403 error, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package ci
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+)
+
+// Finding is one problem reported by a CI run
+type Finding struct {
+	File     string // Relative to the repository root; empty for findings without a location
+	Line     int    // 1-based, 0 when unknown
+	Column   int    // 1-based, 0 when unknown
+	Severity string // "error", "warning", or "info"
+	Rule     string // Hook or check that produced the finding
+	Message  string
+}
+
+// Blocking reports whether any finding should fail the build
+func Blocking(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+// Report formats
+const (
+	FormatGitHub    = "github"    // Workflow command annotations and a job summary
+	FormatGitLab    = "gitlab"    // Code Quality report artifact
+	FormatBitbucket = "bitbucket" // Code Insights report and annotations
+)
+
+// Formats lists the supported report formats
+var Formats = []string{FormatGitHub, FormatGitLab, FormatBitbucket}
+
+// DetectFormat picks the report format for the CI service the command runs on,
+// defaulting to GitHub
+func DetectFormat() string {
+	switch {
+	case os.Getenv("GITLAB_CI") != "":
+		return FormatGitLab
+	case os.Getenv("BITBUCKET_BUILD_NUMBER") != "":
+		return FormatBitbucket
+	}
+	return FormatGitHub
+}
+
+// DefaultBase is the ref a pull request is compared against: the target branch
+// of the GitHub pull request, GitLab merge request, or Bitbucket pull request
+// being built, otherwise origin/main
+func DefaultBase() string {
+	for _, env := range []string{"GITHUB_BASE_REF", "CI_MERGE_REQUEST_TARGET_BRANCH_NAME", "BITBUCKET_PR_DESTINATION_BRANCH"} {
+		if ref := os.Getenv(env); ref != "" {
+			return "origin/" + ref
+		}
+	}
+	return "origin/main"
+}
+
+// RepoRoot returns the top level of the git repository containing dir
+func RepoRoot(dir string) (string, error) {
+	output, err := proc.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("finding repository root: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Changes are the files a branch changed relative to its merge base
+type Changes struct {
+	MergeBase string
+	Changed   []string // Added, copied, modified, or renamed; relative to the root
+	Deleted   []string
+}
+
+// ChangedFiles compares HEAD with its merge base with base, which is what a pull
+// request's diff shows regardless of how far base has moved since
+func ChangedFiles(root, base string) (Changes, error) {
+	var changes Changes
+	output, err := proc.Command("git", "-C", root, "merge-base", base, "HEAD").Output()
+	if err != nil {
+		return changes, fmt.Errorf("finding merge base with %s (is it fetched? use fetch-depth: 0): %w", base, err)
+	}
+	changes.MergeBase = strings.TrimSpace(string(output))
+
+	for filter, dst := range map[string]*[]string{"ACMR": &changes.Changed, "D": &changes.Deleted} {
+		output, err := proc.Command("git", "-C", root, "diff", "--name-only", "--no-renames", "--diff-filter="+filter, changes.MergeBase, "HEAD").Output()
+		if err != nil {
+			return changes, fmt.Errorf("listing changed files: %w", err)
+		}
+		*dst = strings.Fields(string(output))
+	}
+	return changes, nil
+}
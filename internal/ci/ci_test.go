@@ -0,0 +1,99 @@
+package ci
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, root, "init", "-q", "-b", "main")
+	write("kept.go", "package a\n")
+	write("gone.go", "package a\n")
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "base")
+
+	runGit(t, root, "checkout", "-q", "-b", "feature")
+	write("kept.go", "package a\n\nvar x = 1\n")
+	write("new.go", "package a\n")
+	runGit(t, root, "rm", "-q", "gone.go")
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "change")
+
+	// Commits landing on main after the branch point aren't part of the PR
+	runGit(t, root, "checkout", "-q", "main")
+	write("main-only.go", "package a\n")
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "main moves on")
+	runGit(t, root, "checkout", "-q", "feature")
+
+	changes, err := ChangedFiles(root, "main")
+	if err != nil {
+		t.Fatalf("ChangedFiles failed: %v", err)
+	}
+	if !slices.Equal(changes.Changed, []string{"kept.go", "new.go"}) {
+		t.Errorf("Expected kept.go and new.go changed, got %v", changes.Changed)
+	}
+	if !slices.Equal(changes.Deleted, []string{"gone.go"}) {
+		t.Errorf("Expected gone.go deleted, got %v", changes.Deleted)
+	}
+	if changes.MergeBase == "" {
+		t.Error("Expected a merge base")
+	}
+}
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	var sb strings.Builder
+	err := WriteGitHubAnnotations(&sb, []Finding{
+		{File: "a,b.go", Line: 3, Column: 7, Severity: "error", Rule: "go", Message: "100% broken\nsee above"},
+		{File: "c.ts", Line: 1, Severity: "warning", Rule: "eslint", Message: "unused"},
+		{Severity: "info", Message: "note"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "::error file=a%2Cb.go,line=3,col=7,title=claude-hooks%3A go::100%25 broken%0Asee above\n" +
+		"::warning file=c.ts,line=1,title=claude-hooks%3A eslint::unused\n" +
+		"::notice::note\n"
+	if sb.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, sb.String())
+	}
+}
+
+func TestWriteGitHubSummary(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteGitHubSummary(&sb, nil, 4); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), "4 changed file(s) checked, no findings") {
+		t.Errorf("Expected a clean summary, got %q", sb.String())
+	}
+
+	sb.Reset()
+	findings := []Finding{{File: "a.go", Line: 2, Severity: "error", Rule: "go", Message: "x | y\nmore"}}
+	if err := WriteGitHubSummary(&sb, findings, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), "❌ Blocking findings") || !strings.Contains(sb.String(), `| error | a.go:2 | go | x \| y |`) {
+		t.Errorf("Expected a blocking summary row, got %q", sb.String())
+	}
+}
@@ -0,0 +1,90 @@
+package ci
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteGitHubAnnotations emits a workflow command per finding, which GitHub
+// shows inline on the pull request diff
+func WriteGitHubAnnotations(w io.Writer, findings []Finding) error {
+	for _, f := range findings {
+		command := "error"
+		switch f.Severity {
+		case "warning":
+			command = "warning"
+		case "info":
+			command = "notice"
+		}
+
+		var props []string
+		if f.File != "" {
+			props = append(props, "file="+escapeProperty(f.File))
+			if f.Line > 0 {
+				props = append(props, fmt.Sprintf("line=%d", f.Line))
+			}
+			if f.Column > 0 {
+				props = append(props, fmt.Sprintf("col=%d", f.Column))
+			}
+		}
+		if f.Rule != "" {
+			props = append(props, "title="+escapeProperty("claude-hooks: "+f.Rule))
+		}
+
+		line := "::" + command
+		if len(props) > 0 {
+			line += " " + strings.Join(props, ",")
+		}
+		if _, err := fmt.Fprintf(w, "%s::%s\n", line, escapeData(f.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteGitHubSummary writes a Markdown job summary (for $GITHUB_STEP_SUMMARY)
+func WriteGitHubSummary(w io.Writer, findings []Finding, checked int) error {
+	var sb strings.Builder
+	sb.WriteString("## claude-hooks\n\n")
+	if len(findings) == 0 {
+		fmt.Fprintf(&sb, "✅ %d changed file(s) checked, no findings\n", checked)
+		_, err := io.WriteString(w, sb.String())
+		return err
+	}
+
+	status := "✅ No blocking findings"
+	if Blocking(findings) {
+		status = "❌ Blocking findings"
+	}
+	fmt.Fprintf(&sb, "%s in %d changed file(s)\n\n", status, checked)
+	sb.WriteString("| Severity | Location | Rule | Message |\n|---|---|---|---|\n")
+	for _, f := range findings {
+		location := f.File
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n", f.Severity, escapeCell(location), escapeCell(f.Rule), escapeCell(firstLine(f.Message)))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// escapeData escapes a workflow command message
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a workflow command property value
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
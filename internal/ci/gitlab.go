@@ -0,0 +1,72 @@
+package ci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// gitlabIssue is one entry of a GitLab Code Quality report, the Code Climate
+// subset GitLab reads
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+// WriteGitLabCodeQuality writes findings as a GitLab Code Quality report, which
+// merge requests show inline when it is uploaded as a codequality artifact
+func WriteGitLabCodeQuality(w io.Writer, findings []Finding) error {
+	issues := make([]gitlabIssue, 0, len(findings))
+	for _, f := range findings {
+		issue := gitlabIssue{
+			Description: f.Message,
+			CheckName:   "claude-hooks/" + f.Rule,
+			Fingerprint: fingerprint(f),
+			Severity:    gitlabSeverity(f.Severity),
+		}
+		// GitLab requires a location; findings without one are pinned to the top
+		// of the repository so they still show in the report
+		issue.Location.Path = f.File
+		if issue.Location.Path == "" {
+			issue.Location.Path = "."
+		}
+		issue.Location.Lines.Begin = max(f.Line, 1)
+		issues = append(issues, issue)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(issues); err != nil {
+		return fmt.Errorf("writing code quality report: %w", err)
+	}
+	return nil
+}
+
+func gitlabSeverity(severity string) string {
+	switch severity {
+	case "error":
+		return "major"
+	case "warning":
+		return "minor"
+	}
+	return "info"
+}
+
+// fingerprint identifies a finding across pipelines, which GitLab uses to tell
+// new findings from ones the target branch already has
+func fingerprint(f Finding) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%d\x00%s", f.Rule, f.File, f.Line, f.Message))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,43 @@
+package ci
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteGitLabCodeQuality(t *testing.T) {
+	var buf bytes.Buffer
+	findings := []Finding{
+		{File: "a.go", Line: 3, Severity: "error", Rule: "go", Message: "undefined: x"},
+		{Severity: "warning", Rule: "post-edit", Message: "tests failed"},
+	}
+	if err := WriteGitLabCodeQuality(&buf, findings); err != nil {
+		t.Fatal(err)
+	}
+
+	var issues []gitlabIssue
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatalf("Report isn't JSON: %v\n%s", err, buf.String())
+	}
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %d", len(issues))
+	}
+	if issues[0].CheckName != "claude-hooks/go" || issues[0].Severity != "major" || issues[0].Location.Path != "a.go" || issues[0].Location.Lines.Begin != 3 {
+		t.Errorf("Unexpected first issue: %+v", issues[0])
+	}
+	if issues[1].Severity != "minor" || issues[1].Location.Path != "." || issues[1].Location.Lines.Begin != 1 {
+		t.Errorf("Expected the unlocated finding at the repository root, got %+v", issues[1])
+	}
+	if issues[0].Fingerprint == issues[1].Fingerprint || issues[0].Fingerprint != fingerprint(findings[0]) {
+		t.Errorf("Expected stable, distinct fingerprints, got %q and %q", issues[0].Fingerprint, issues[1].Fingerprint)
+	}
+
+	buf.Reset()
+	if err := WriteGitLabCodeQuality(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := bytes.TrimSpace(buf.Bytes()); string(got) != "[]" {
+		t.Errorf("Expected an empty array without findings, got %s", got)
+	}
+}
@@ -0,0 +1,46 @@
+package ci
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/pkg/hooktest/golden"
+)
+
+// goldenFindings cover what each format has to escape or map: locations with
+// and without columns, findings without one, every severity, and multi-line
+// messages with the characters CI syntaxes reserve
+var goldenFindings = []Finding{
+	{File: "cmd/app/main.go", Line: 12, Column: 5, Severity: "error", Rule: "go", Message: "undefined: client\nrun `go build ./...` to reproduce"},
+	{File: "web/src/a,b.tsx", Line: 3, Severity: "warning", Rule: "eslint", Message: "'x' is assigned a value but never used (100% sure) | table"},
+	{Severity: "info", Rule: "post-edit", Message: "tests of 2 package(s) changed this session pass"},
+}
+
+func TestGoldenGitHub(t *testing.T) {
+	var annotations, summary bytes.Buffer
+	if err := WriteGitHubAnnotations(&annotations, goldenFindings); err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, "github_annotations", annotations.Bytes())
+
+	if err := WriteGitHubSummary(&summary, goldenFindings, 5); err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, "github_summary", summary.Bytes())
+}
+
+func TestGoldenGitLab(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGitLabCodeQuality(&buf, goldenFindings); err != nil {
+		t.Fatal(err)
+	}
+	golden.AssertJSON(t, "gitlab_code_quality", buf.Bytes())
+}
+
+func TestGoldenBitbucket(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteBitbucketReport(&buf, goldenFindings, 5); err != nil {
+		t.Fatal(err)
+	}
+	golden.AssertJSON(t, "bitbucket_report", buf.Bytes())
+}
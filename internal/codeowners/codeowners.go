@@ -0,0 +1,134 @@
+// Package codeowners reads a repository's CODEOWNERS file, in the syntax
+// GitHub, GitLab, and Bitbucket share: gitignore-style patterns followed by
+// owners, the last matching line deciding a file's owners
+package codeowners
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Locations are where a CODEOWNERS file is looked up, relative to the
+// repository root, in the order GitHub and GitLab look
+var Locations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// Rule is one line of a CODEOWNERS file
+type Rule struct {
+	Pattern string
+	Owners  []string // Empty for a pattern that unsets ownership
+	Line    int
+	re      *regexp.Regexp
+}
+
+// File is a parsed CODEOWNERS file
+type File struct {
+	Path  string
+	Rules []Rule
+}
+
+// Load reads the CODEOWNERS file of the repository at root, or returns nil
+// when it has none
+func Load(root string) (*File, error) {
+	for _, location := range Locations {
+		path := filepath.Join(root, filepath.FromSlash(location))
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		f := Parse(data)
+		f.Path = path
+		return f, nil
+	}
+	return nil, nil
+}
+
+// Parse reads CODEOWNERS content. GitLab section headers like
+// [Payments][2] @acme/payments only group rules here; their default owners
+// aren't applied.
+func Parse(data []byte) *File {
+	f := &File{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		fields := strings.Fields(strings.ReplaceAll(line, `\ `, "\x00"))
+		pattern := strings.ReplaceAll(fields[0], "\x00", " ")
+		f.Rules = append(f.Rules, Rule{Pattern: pattern, Owners: fields[1:], Line: n, re: compile(pattern)})
+	}
+	return f
+}
+
+// Match returns the rule deciding who owns the file at rel, a slash-separated
+// path relative to the repository root, or nil when no rule matches
+func (f *File) Match(rel string) *Rule {
+	for i := len(f.Rules) - 1; i >= 0; i-- {
+		if f.Rules[i].re.MatchString(rel) {
+			return &f.Rules[i]
+		}
+	}
+	return nil
+}
+
+// Owners returns the owners of the file at rel, or nil when nobody owns it
+func (f *File) Owners(rel string) []string {
+	if r := f.Match(rel); r != nil {
+		return r.Owners
+	}
+	return nil
+}
+
+// compile turns a gitignore-style pattern into a regexp of the paths it
+// covers: a pattern with a slash before its end is anchored at the root,
+// others match at any depth, and a matching directory covers everything in it.
+// As on GitHub, dir/* covers the files directly in dir only.
+func compile(pattern string) *regexp.Regexp {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	switch {
+	case dirOnly:
+		re.WriteString("/.*$")
+	case strings.HasSuffix(pattern, "/*"):
+		re.WriteString("$")
+	default:
+		re.WriteString("(?:/.*)?$")
+	}
+	return regexp.MustCompile(re.String())
+}
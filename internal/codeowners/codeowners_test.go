@@ -0,0 +1,69 @@
+package codeowners
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOwners(t *testing.T) {
+	f := Parse([]byte(`# Default owners
+*                       @acme/everyone
+*.js                    @acme/web
+/build/logs/            @acme/ops
+docs/*                  docs@example.com
+apps/                   @acme/apps
+/services/payments/     @acme/payments @alice # money
+**/migrations           @acme/dba
+/services/payments/README.md
+
+[Security][2] @acme/security
+/infra/secrets/         @acme/security
+`))
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"main.go", []string{"@acme/everyone"}},
+		{"web/app.js", []string{"@acme/web"}},
+		{"build/logs/today/x.log", []string{"@acme/ops"}},
+		{"src/build/logs/x.log", []string{"@acme/everyone"}}, // Anchored
+		{"docs/intro.md", []string{"docs@example.com"}},
+		{"docs/guides/intro.md", []string{"@acme/everyone"}}, // dir/* isn't recursive
+		{"nested/apps/x.go", []string{"@acme/apps"}},
+		{"services/payments/ledger/ledger.go", []string{"@acme/payments", "@alice"}},
+		{"services/payments/README.md", nil}, // Unowned by the last match
+		{"db/migrations/001.sql", []string{"@acme/dba"}},
+		{"infra/secrets/prod.yaml", []string{"@acme/security"}},
+	}
+	for _, tt := range tests {
+		if got := f.Owners(tt.path); !slices.Equal(got, tt.want) {
+			t.Errorf("Owners(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if r := f.Match("services/payments/x.go"); r == nil || r.Line != 7 {
+		t.Errorf("Expected line 7 to match, got %+v", r)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	if f, err := Load(root); f != nil || err != nil {
+		t.Fatalf("Expected no file, got %v (%v)", f, err)
+	}
+	for _, location := range []string{"CODEOWNERS", ".github/CODEOWNERS"} {
+		path := filepath.Join(root, location)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("* @"+location+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := Load(root)
+	if err != nil || f == nil || f.Path != filepath.Join(root, ".github", "CODEOWNERS") {
+		t.Fatalf("Expected .github/CODEOWNERS to win, got %+v (%v)", f, err)
+	}
+}
@@ -0,0 +1,893 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the per-project configuration file, looked up from the edited
+// file's directory upward. The same name in the home directory is the user's
+// config, applied to every project under the project's own.
+const FileName = ".claude-hooks.yaml"
+
+// Config controls hook behavior for a project
+type Config struct {
+	Go       GoConfig       `yaml:"go"`
+	Push     PushConfig     `yaml:"push"`
+	Proto    ProtoConfig    `yaml:"proto"`
+	Bazel    BazelConfig    `yaml:"bazel"`
+	Monorepo MonorepoConfig `yaml:"monorepo"`
+	Bash     BashConfig     `yaml:"bash"`
+
+	Routes []RouteConfig                `yaml:"routes"`
+	Hooks  map[string]CommandHookConfig `yaml:"hooks"`
+
+	Content    ContentConfig    `yaml:"content"`
+	Duplicates DuplicatesConfig `yaml:"duplicates"`
+	Complexity ComplexityConfig `yaml:"complexity"`
+	Bundle     BundleConfig     `yaml:"bundle"`
+	// EditorConfig checks edited files against their .editorconfig
+	EditorConfig EditorConfigConfig `yaml:"editorconfig"`
+	Encoding     EncodingConfig     `yaml:"encoding"`
+	// MergeArtifacts blocks conflict markers, .orig/.rej files, and
+	// duplicated definitions left by a botched merge
+	MergeArtifacts MergeArtifactsConfig `yaml:"merge_artifacts"`
+	// Placeholders blocks stub code edits leave behind: elided code,
+	// unimplemented bodies, swallowed errors, and lorem ipsum
+	Placeholders PlaceholdersConfig `yaml:"placeholders"`
+	// Semgrep scans edited files with Semgrep rulesets, for the patterns not
+	// worth writing a check of our own for
+	Semgrep SemgrepConfig `yaml:"semgrep"`
+	// Security runs gosec on edited Go packages and audits dependencies
+	// when a manifest changes
+	Security SecurityConfig `yaml:"security"`
+	// Images checks the container images edited Dockerfiles, compose files,
+	// and Kubernetes manifests refer to
+	Images ImagesConfig `yaml:"images"`
+	// Paths decides what happens to edits of files outside the session's
+	// git repository, including through symlinks
+	Paths PathsConfig `yaml:"paths"`
+	// Owners brings CODEOWNERS into the session: edits of files other teams
+	// own warn, and restricted ones are blocked
+	Owners OwnersConfig `yaml:"owners"`
+
+	Guardrails GuardrailsConfig `yaml:"guardrails"`
+	Rollback   RollbackConfig   `yaml:"rollback"`
+	Reasons    ReasonsConfig    `yaml:"reasons"`
+	Fixes      FixesConfig      `yaml:"fixes"`
+	Timeouts   TimeoutsConfig   `yaml:"timeouts"`
+	// Policy has user-supplied policies decide pre-bash, pre-edit, and stop
+	// besides the built-in checks
+	Policy PolicyConfig `yaml:"policy"`
+	// Agents restricts what subagents may do; the main agent keeps the
+	// guarded capabilities of the rest of the config
+	Agents AgentsConfig `yaml:"agents"`
+	// Audit exports the blocked commands and detected secrets of the audit
+	// log to a SIEM
+	Audit AuditConfig `yaml:"audit"`
+
+	// Profile is "interactive" (the default) or "unattended"; $CLAUDE_HOOKS_PROFILE overrides it
+	Profile    string           `yaml:"profile"`
+	Unattended UnattendedConfig `yaml:"unattended"`
+
+	// Enforcement is "block" (the default), "warn", or "dry-run"; $CLAUDE_HOOKS_ENFORCEMENT overrides it
+	Enforcement string `yaml:"enforcement"`
+	// Offline skips what needs the network: plan review, webhooks, telemetry, and update
+	Offline bool `yaml:"offline"`
+	// CABundle is a PEM file of certificates to trust besides the system's,
+	// for a proxy that intercepts TLS, relative to this file;
+	// $CLAUDE_HOOKS_CA_BUNDLE overrides it
+	CABundle string `yaml:"ca_bundle"`
+
+	PlanReview PlanReviewConfig `yaml:"plan_review"`
+
+	// Profiles are named sets of overrides of the settings above, e.g. a
+	// stricter "client-a". The one selected with `claude-hook profile use` or
+	// $CLAUDE_HOOKS_SETTINGS_PROFILE is applied on top of the rest.
+	Profiles map[string]yaml.Node `yaml:"profiles"`
+	// SettingsProfile is the name of the profile applied, empty when none is
+	SettingsProfile string `yaml:"-"`
+
+	// Scopes override the settings above, profile included, for the files
+	// under a directory relative to this file, e.g. a stricter
+	// services/payments. Only the longest matching scope applies.
+	Scopes map[string]yaml.Node `yaml:"scopes"`
+	// Scope is the scope applied, empty when none is
+	Scope string `yaml:"-"`
+
+	// Path is the file the config was loaded from, empty when using defaults
+	Path string `yaml:"-"`
+	// Global is the user config applied before Path, empty when there's none
+	Global string `yaml:"-"`
+}
+
+// GoConfig controls the Go hook
+type GoConfig struct {
+	// Vet compile-checks edited packages with go vet on every edit; off by
+	// default for speed
+	Vet         bool                `yaml:"vet"`
+	Dependents  DependentsConfig    `yaml:"dependents"`
+	API         APIConfig           `yaml:"api"`
+	Tests       GoTestsConfig       `yaml:"tests"`
+	Errors      GoErrorsConfig      `yaml:"errors"`
+	Concurrency GoConcurrencyConfig `yaml:"concurrency"`
+	// Builds are the other build configurations edited files their build
+	// constraints leave out of the default build are vetted under
+	Builds []GoBuildConfig `yaml:"builds"`
+}
+
+// DefaultErrorsWrap is how errors get context unless go.errors.wrap says otherwise
+const DefaultErrorsWrap = `fmt.Errorf("doing what: %w", err)`
+
+// GoErrorsConfig controls the checks of how edited Go code handles errors
+type GoErrorsConfig struct {
+	// Enabled reports, on lines changed since HEAD, errors of other packages
+	// returned without context or formatted without %w, errors discarded
+	// into _, and errors.New messages too vague to act on
+	Enabled bool `yaml:"enabled"`
+	// Disabled names checks to skip: unwrapped, discarded, or no-context
+	Disabled []string `yaml:"disabled"`
+	// Wrap is the project's idiom for adding context, shown in the
+	// remediation, e.g. errors.Wrap(err, "loading config")
+	Wrap string `yaml:"wrap"`
+	// Block fails the edit instead of only telling Claude
+	Block bool `yaml:"block"`
+}
+
+// GoConcurrencyConfig controls the quick scan of edited Go files for
+// concurrency bugs, which only warns
+type GoConcurrencyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Disabled names checks to skip: loop-capture, lock-copy, waitgroup-add,
+	// or sleep-sync
+	Disabled []string `yaml:"disabled"`
+}
+
+// GoBuildConfig is a GOOS/GOARCH, build tags, and cgo combination
+type GoBuildConfig struct {
+	GOOS   string   `yaml:"goos"`   // Empty for the host's
+	GOARCH string   `yaml:"goarch"` // Empty for the host's
+	Tags   []string `yaml:"tags"`
+	// CGO sets CGO_ENABLED; unset keeps the environment's
+	CGO *bool `yaml:"cgo"`
+}
+
+// DependentsConfig controls reverse-dependency checking of edited Go packages
+type DependentsConfig struct {
+	// Enabled also builds and tests packages that directly import an edited package
+	Enabled bool `yaml:"enabled"`
+	// Max caps how many dependent packages are checked per edit
+	Max int `yaml:"max"`
+}
+
+// APIConfig controls the exported API compatibility check of edited Go packages
+type APIConfig struct {
+	// Enabled blocks edits that break the exported API, using apidiff
+	Enabled bool `yaml:"enabled"`
+	// Base is the branch or commit the API is compared against
+	Base string `yaml:"base"`
+}
+
+// GoTestsConfig controls running the tests of edited Go packages
+type GoTestsConfig struct {
+	// Enabled tests edited packages after each edit, skipping those already
+	// green at the same content this session, and everything the session
+	// tested again before the turn ends
+	Enabled bool `yaml:"enabled"`
+}
+
+// PushConfig controls the scan of outgoing commits before `git push`
+type PushConfig struct {
+	// Enabled turns the scan on; set to false to let every push through
+	Enabled bool `yaml:"enabled"`
+	// MaxFileSize is the largest file, in bytes, a pushed commit may add (0 disables the check)
+	MaxFileSize int64 `yaml:"max_file_size"`
+	// DisallowedPaths are glob patterns matched against the path and base name of pushed files
+	DisallowedPaths []string `yaml:"disallowed_paths"`
+}
+
+// ProtoConfig controls the check run after .proto edits
+type ProtoConfig struct {
+	// Generate is the command that regenerates code from the protos, e.g. "buf generate",
+	// run from the config file's directory before checking. It is split on spaces
+	// and run without a shell. Empty means generated code is expected to be
+	// regenerated by hand, and stale code is reported instead.
+	Generate string `yaml:"generate"`
+}
+
+// BazelConfig checks edits through the build graph of a Bazel or Buck2
+// workspace instead of the language hooks
+type BazelConfig struct {
+	// Enabled sends the files of packages in the workspace to the build system:
+	// the targets owning them are built, and BUILD files are checked with buildifier
+	Enabled bool `yaml:"enabled"`
+	// Test also runs the tests of the owning targets and of their direct dependents
+	Test bool `yaml:"test"`
+	// Command is the build tool, e.g. bazelisk; empty means bazel, or buck2 in a
+	// Buck2 workspace
+	Command string `yaml:"command"`
+	// Flags are added to every build and test, e.g. ["--config=ci"]
+	Flags []string `yaml:"flags"`
+}
+
+// MonorepoConfig checks edits in an Nx or Turborepo workspace through its task
+// runner, and so its cache, instead of running eslint and tsc directly
+type MonorepoConfig struct {
+	// Enabled sends the JS and TS files of a workspace with an nx.json or
+	// turbo.json to its task runner
+	Enabled bool `yaml:"enabled"`
+	// Targets are the tasks run for the projects the edits affect
+	Targets []string `yaml:"targets"`
+}
+
+// RouteConfig sends files to a hook by their name or content instead of their
+// extension, for files like BUILD.bazel, Jenkinsfile, or extensionless scripts.
+// Every condition that is set must match; routes are tried in order before
+// the built-in extensions.
+type RouteConfig struct {
+	// Glob matches the path relative to the project root or the base name,
+	// e.g. "Jenkinsfile", "*.bazel", or "scripts/*"
+	Glob string `yaml:"glob"`
+	// Shebang is a regular expression matched against a "#!" first line, e.g. "python3?$"
+	Shebang string `yaml:"shebang"`
+	// Content is a regular expression matched against the first 4 KiB of the file
+	Content string `yaml:"content"`
+	// Hook is a built-in hook (go, typescript, javascript, proto) or one under hooks
+	Hook string `yaml:"hook"`
+}
+
+// CommandHookConfig is a hook that runs a command on the files routed to it.
+// One named after a built-in file type, like go, replaces the built-in hook.
+type CommandHookConfig struct {
+	// Command is run from the config file's directory with the files appended.
+	// It is split on spaces and run without a shell; a non-zero exit fails the
+	// edit with its output.
+	Command string `yaml:"command"`
+	// Make lists make targets run instead of Command, e.g. ["lint-go",
+	// "test-fast"], each with FILES set to the files relative to the config
+	// file's directory
+	Make []string `yaml:"make"`
+}
+
+// BashConfig controls the pre-bash command policy
+type BashConfig struct {
+	// Rules are checked in order against each command; the first match decides
+	Rules []CommandRule `yaml:"rules"`
+}
+
+// ContentConfig controls the pre-edit check of the code an edit would introduce
+type ContentConfig struct {
+	// Enabled turns the check on; set to false to let every edit through
+	Enabled bool `yaml:"enabled"`
+	// Disabled names built-in rules to skip, e.g. lint-suppression
+	Disabled []string `yaml:"disabled"`
+	// MaxDeletedLines is the most lines one edit may remove from a file (0 disables the check)
+	MaxDeletedLines int `yaml:"max_deleted_lines"`
+	// MaxWriteSize is the largest file, in bytes, a Write may create (0 disables the check)
+	MaxWriteSize int64 `yaml:"max_write_size"`
+	// BinaryPaths are globs, matched against the repo-relative path, its base name, and
+	// its parent directories, where large, binary, and base64 content may be written
+	BinaryPaths []string `yaml:"binary_paths"`
+	// OutputPaths are globs, matched like BinaryPaths, where printing is
+	// legitimate, like CLIs and scripts, so debug-output doesn't apply
+	OutputPaths []string `yaml:"output_paths"`
+	// MaxRewritePercent is the share of an existing file's lines a Write may
+	// change before Claude is told an Edit would be safer (0 disables the check)
+	MaxRewritePercent int `yaml:"max_rewrite_percent"`
+	// BlockRewriteSize denies those rewrites of files of at least this many
+	// bytes instead of warning (0 never denies)
+	BlockRewriteSize int64 `yaml:"block_rewrite_size"`
+}
+
+// DuplicatesConfig controls the check for code copied from elsewhere in the
+// repository into edited files
+type DuplicatesConfig struct {
+	// Enabled runs dupl (Go) and jscpd (JavaScript/TypeScript) after edits
+	Enabled bool `yaml:"enabled"`
+	// MinTokens is the shortest duplicated token sequence reported
+	MinTokens int `yaml:"min_tokens"`
+}
+
+// ComplexityConfig controls the size and complexity limits for functions an
+// edit adds or changes
+type ComplexityConfig struct {
+	// Enabled checks edited Go functions natively and TypeScript/JavaScript ones with eslint
+	Enabled bool `yaml:"enabled"`
+	// MaxCyclomatic is the highest cyclomatic complexity allowed (0 disables the check)
+	MaxCyclomatic int `yaml:"max_cyclomatic"`
+	// MaxLines is the longest function allowed, in lines (0 disables the check)
+	MaxLines int `yaml:"max_lines"`
+	// Block fails the edit instead of only telling Claude about the functions
+	Block bool `yaml:"block"`
+}
+
+// BundleConfig controls the bundle size estimate for web projects, made when
+// an edit changes package.json dependencies or imports a package
+type BundleConfig struct {
+	// Enabled bundles the entrypoints with the project's esbuild before and after the edit
+	Enabled bool `yaml:"enabled"`
+	// Entrypoints are relative to the package.json directory; empty means the
+	// first of src/index and src/main that exists
+	Entrypoints []string `yaml:"entrypoints"`
+	// MaxIncrease is how many bytes of minified output an edit may add (0 reports any growth)
+	MaxIncrease int64 `yaml:"max_increase"`
+	// Block fails the edit instead of only telling Claude about the growth
+	Block bool `yaml:"block"`
+}
+
+// EditorConfigConfig controls checking the changed lines of edited files, in
+// any language, against the .editorconfig properties that apply to them
+type EditorConfigConfig struct {
+	// Enabled checks indentation, line endings, the final newline, trailing
+	// whitespace, and the charset
+	Enabled bool `yaml:"enabled"`
+	// Block fails the edit instead of only telling Claude about the violations
+	Block bool `yaml:"block"`
+}
+
+// EncodingConfig controls the guard against edits that change a file's line
+// endings or byte order mark, or break its UTF-8, compared with HEAD
+type EncodingConfig struct {
+	// Enabled blocks such edits
+	Enabled bool `yaml:"enabled"`
+	// Fix restores the line endings and byte order mark instead of blocking;
+	// invalid UTF-8 still blocks
+	Fix bool `yaml:"fix"`
+}
+
+// MergeArtifactsConfig controls the check for leftovers of merges, rebases,
+// and patches in and around edited files
+type MergeArtifactsConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// PlaceholdersConfig controls the check for placeholder code in edited files
+type PlaceholdersConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Disabled names patterns to skip: elided-code, not-implemented,
+	// empty-catch, todo-stub, or lorem-ipsum
+	Disabled []string `yaml:"disabled"`
+}
+
+// Actions for a Semgrep severity
+const (
+	SemgrepBlock  = "block"  // Fail the edit
+	SemgrepWarn   = "warn"   // Tell Claude about the finding
+	SemgrepIgnore = "ignore" // Drop the finding
+)
+
+// SemgrepConfig controls scanning edited files with semgrep, when it's
+// installed. Results are cached by file content, so files that haven't
+// changed aren't scanned again.
+type SemgrepConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Rulesets are passed to semgrep as --config: registry rulesets like
+	// p/golang and p/typescript, or rule files and directories relative to
+	// this file, such as the organization's own rules
+	Rulesets []string `yaml:"rulesets"`
+	// Severities map each Semgrep severity (ERROR, WARNING, INFO) to
+	// SemgrepBlock, SemgrepWarn, or SemgrepIgnore; unlisted ones warn
+	Severities map[string]string `yaml:"severities"`
+}
+
+// Severities of security findings, lowest first
+var SecuritySeverities = []string{"low", "medium", "high", "critical"}
+
+// SecurityConfig controls the security scanners run after edits, each
+// skipped when its tool isn't installed
+type SecurityConfig struct {
+	// Gosec scans the packages of edited Go files with gosec
+	Gosec bool `yaml:"gosec"`
+	// Audit checks the dependencies of an edited manifest for known
+	// vulnerabilities: npm audit --omit=dev for package.json and
+	// package-lock.json, osv-scanner for the other lockfiles and go.mod
+	Audit bool `yaml:"audit"`
+	// Block is the lowest of SecuritySeverities that fails the edit; findings
+	// below it warn, and "none" only warns
+	Block string `yaml:"block"`
+}
+
+// ImagesConfig controls the check of container image references on the
+// changed lines of Dockerfiles, compose files, and Kubernetes manifests
+type ImagesConfig struct {
+	// Enabled reports images without a tag or tagged latest
+	Enabled bool `yaml:"enabled"`
+	// RequireDigest also reports images not pinned to an @sha256 digest
+	RequireDigest bool `yaml:"require_digest"`
+	// Registries are the registries, or repository prefixes, images may come
+	// from, e.g. ghcr.io/acme; Docker Hub is docker.io. Empty allows any.
+	Registries []string `yaml:"registries"`
+	// Block fails the edit instead of only telling Claude about the images
+	Block bool `yaml:"block"`
+}
+
+// Policies for edits outside the session's repository
+const (
+	OutsideBlock    = "block"    // Deny the edit
+	OutsideWarn     = "warn"     // Check the file where it is and tell Claude it's outside
+	OutsideValidate = "validate" // Check the file where it is, silently
+)
+
+// PathsConfig controls how edited paths outside the repository are treated
+type PathsConfig struct {
+	Outside string `yaml:"outside"` // OutsideBlock, OutsideWarn, or OutsideValidate
+}
+
+// OwnersConfig controls what edits of files owned by others in CODEOWNERS do
+type OwnersConfig struct {
+	// Team are the owners the sessions work for, e.g. ["@acme/platform"].
+	// Edits of files with other owners warn; empty turns the warning off.
+	Team []string `yaml:"team"`
+	// Webhooks maps owners to a URL posted once per session when Claude
+	// edits their files, e.g. a Slack workflow posting to their channel
+	Webhooks map[string]string `yaml:"webhooks"`
+	// Restricted blocks edits of the files under these directories, relative
+	// to the repository root, and of the files owned by the owners listed
+	// (entries with an @)
+	Restricted []string `yaml:"restricted"`
+}
+
+// CommandRule matches commands by what they run and the environment they would
+// run in (kube context, AWS profile, environment and .env values). Every
+// condition that is set must match; a rule without conditions matches nothing.
+type CommandRule struct {
+	Name string `yaml:"name"`
+	// Commands are the executables the rule applies to; empty means any
+	Commands []string `yaml:"commands"`
+	// Match is a regular expression the command must match after $VAR expansion
+	Match string `yaml:"match"`
+	// KubeContext is a glob the kube context the command would use must match
+	KubeContext string `yaml:"kube_context"`
+	// AWSProfile is a glob the AWS profile the command would use must match
+	AWSProfile string `yaml:"aws_profile"`
+	// Env maps variable names to globs their values must match
+	Env map[string]string `yaml:"env"`
+	// Decision is "deny" (the default), "ask", or "allow" to exempt matching commands from later rules
+	Decision string `yaml:"decision"`
+	// Reason is shown to Claude when the rule denies or asks
+	Reason string `yaml:"reason"`
+}
+
+// GuardrailsConfig limits what long unattended sessions can do
+type GuardrailsConfig struct {
+	DeployWindow DeployWindowConfig  `yaml:"deploy_window"`
+	Session      SessionBudgetConfig `yaml:"session"`
+	Changelog    ChangelogConfig     `yaml:"changelog"`
+	Loops        LoopsConfig         `yaml:"loops"`
+}
+
+// DeployWindowConfig restricts deploy-like commands to working hours
+type DeployWindowConfig struct {
+	// Hours is the allowed window, e.g. "09:00-17:00" (may wrap past midnight); empty disables the check
+	Hours string `yaml:"hours"`
+	// Days are the allowed weekdays (mon, tue, ...); empty means every day
+	Days []string `yaml:"days"`
+	// Timezone is an IANA zone like "Europe/Berlin"; empty means local time
+	Timezone string `yaml:"timezone"`
+	// Commands are regular expressions for deploy-like commands, replacing the built-in list
+	Commands []string `yaml:"commands"`
+}
+
+// SessionBudgetConfig caps what one Claude session may do before every further
+// action needs a human. Zero leaves a counter unlimited.
+type SessionBudgetConfig struct {
+	MaxFilesModified int     `yaml:"max_files_modified"`
+	MaxCommands      int     `yaml:"max_commands"`
+	MaxAPICalls      int     `yaml:"max_api_calls"` // Plan reviewer invocations
+	MaxSpendUSD      float64 `yaml:"max_spend_usd"`
+	// CostPerReviewUSD is the estimated cost of one reviewer invocation, used for spend
+	CostPerReviewUSD float64 `yaml:"cost_per_review_usd"`
+}
+
+// ChangelogConfig requires a changelog entry from sessions that change release
+// relevant files, checked when Claude stops
+type ChangelogConfig struct {
+	// Enabled turns the rule on
+	Enabled bool `yaml:"enabled"`
+	// Paths are globs, relative to the repository root, for files needing an
+	// entry; a directory covers everything under it, and empty means every file
+	Paths []string `yaml:"paths"`
+	// File is the changelog, relative to the repository root
+	File string `yaml:"file"`
+	// FragmentsDir is a directory of unreleased entries (e.g. .changeset); a new or
+	// changed file in it also counts as an entry
+	FragmentsDir string `yaml:"fragments_dir"`
+	// Template is the suggested entry; {files} is replaced by the changed files
+	// and {date} by today's date
+	Template string `yaml:"template"`
+}
+
+// LoopsConfig escalates when the hooks keep blocking the same file for the
+// same rule within a session
+type LoopsConfig struct {
+	// MaxBlocks is how many blocks of one file for one rule a session gets
+	// before the reason escalates (0 disables loop detection)
+	MaxBlocks int `yaml:"max_blocks"`
+	// Analyze asks a model why the check keeps failing, from the session's
+	// diff, once per loop
+	Analyze bool `yaml:"analyze"`
+	// Notify sends each loop to the unattended webhook, once per loop
+	Notify bool `yaml:"notify"`
+}
+
+// RollbackConfig controls the working tree snapshots taken before risky Bash
+// commands, which `claude-hook rollback <id>` restores
+type RollbackConfig struct {
+	// Enabled turns snapshots on; set to false to run risky commands without one
+	Enabled bool `yaml:"enabled"`
+	// Commands are regular expressions for risky commands, replacing the built-in list
+	Commands []string `yaml:"commands"`
+	// Keep is how many snapshots each project keeps, oldest removed first
+	Keep int `yaml:"keep"`
+	// MaxUntrackedSize is the most untracked file data, in bytes, a snapshot copies;
+	// larger snapshots are skipped with a warning (0 disables the limit)
+	MaxUntrackedSize int64 `yaml:"max_untracked_size"`
+}
+
+// ReasonsConfig bounds the block reasons shown to Claude, which otherwise carry
+// all of the failing tools' output
+type ReasonsConfig struct {
+	// MaxChars caps the length of a reason (0 disables the cap)
+	MaxChars int `yaml:"max_chars"`
+	// MaxTokens caps it in tokens, estimated at four characters each; the
+	// smaller of the two caps applies
+	MaxTokens int `yaml:"max_tokens"`
+}
+
+// FixesConfig controls the patches tools suggest for the findings of a blocked edit
+type FixesConfig struct {
+	// Enabled asks gopls, golangci-lint, and eslint for fixes when an edit is blocked
+	Enabled bool `yaml:"enabled"`
+	// Patch also collects the fixes and the formatting of every edit, blocked
+	// or not, in a patch file that `claude-hook apply-fixes` applies
+	Patch bool `yaml:"patch"`
+}
+
+// TimeoutsConfig bounds how long checks may run. A check that runs out of time
+// is reported as aborted, not as failing, and doesn't block the edit.
+type TimeoutsConfig struct {
+	// Languages bounds each language's hook as a whole, by file type, e.g. {go: 2m}
+	Languages map[string]time.Duration `yaml:"languages"`
+	// Steps bounds each run of a tool, by its name and subcommand, e.g.
+	// {"go test": 1m, tsc: 90s, "make lint-go": 30s}. "go test" is also go
+	// test's -timeout, 30s by default.
+	Steps map[string]time.Duration `yaml:"steps"`
+}
+
+// PlanReviewConfig controls the AI council reviewing plans
+type PlanReviewConfig struct {
+	// Reviewers are the CLIs asked to review (claude, codex, gemini); empty
+	// means all of them
+	Reviewers []string `yaml:"reviewers"`
+	// Secrets maps reviewers to environment variables their CLI gets, like
+	// OPENAI_API_KEY, as secret references (keyring:, op://, vault:,
+	// aws-sm:, env:) resolved only when that reviewer runs
+	Secrets map[string]map[string]string `yaml:"secrets"`
+}
+
+// UnattendedConfig controls the extra checks of the unattended profile
+type UnattendedConfig struct {
+	// Webhook receives a JSON POST for every block, deny, or ask; $CLAUDE_HOOKS_WEBHOOK_URL overrides it
+	Webhook string `yaml:"webhook"`
+}
+
+// PolicyConfig runs policies as code on the hooks' structured input
+type PolicyConfig struct {
+	// Engine is opa (Rego, evaluated with the opa CLI) or exec (a command
+	// reading the input on stdin, e.g. a CEL evaluator); empty turns it off
+	Engine string `yaml:"engine"`
+	// Paths are the .rego files and directories opa loads, relative to this
+	// file, besides the built-in bundle. Empty evaluates the built-in bundle.
+	Paths []string `yaml:"paths"`
+	// Query is what opa evaluates, data.claude_hooks.decisions by default
+	Query string `yaml:"query"`
+	// Command is what exec runs, with its arguments, from this file's directory
+	Command []string `yaml:"command"`
+	// Hooks are the hooks policies decide in; empty means pre-bash,
+	// pre-edit, and stop
+	Hooks []string `yaml:"hooks"`
+	// FailClosed denies, or blocks the stop, when the policies can't be
+	// evaluated, instead of warning
+	FailClosed bool `yaml:"fail_closed"`
+}
+
+// AgentsConfig restricts subagents by the agent type Claude Code reports
+type AgentsConfig struct {
+	// Subagents applies to every subagent without an entry in Types
+	Subagents AgentConfig `yaml:"subagents"`
+	// Types replace Subagents for subagents of that type, e.g. Explore
+	Types map[string]AgentConfig `yaml:"types"`
+}
+
+// AgentConfig is what an agent may do
+type AgentConfig struct {
+	// Bash is full (the default) or read-only, which denies commands that
+	// could change anything: unknown commands, redirections to files, and
+	// subcommands like git checkout
+	Bash string `yaml:"bash"`
+	// Commit false denies commands writing version control history: commits,
+	// pushes, merges, rebases, and tags. Unset allows them.
+	Commit *bool `yaml:"commit"`
+	// Edit false denies Edit, MultiEdit, and Write. Unset allows them.
+	Edit *bool `yaml:"edit"`
+}
+
+// Agent bash modes
+const (
+	AgentBashFull     = "full"
+	AgentBashReadOnly = "read-only"
+)
+
+// For returns what the agent of id and agentType may do. The main agent,
+// which has no id, is never restricted.
+func (a AgentsConfig) For(id, agentType string) AgentConfig {
+	if id == "" {
+		return AgentConfig{}
+	}
+	if c, ok := a.Types[agentType]; ok {
+		return c
+	}
+	return a.Subagents
+}
+
+// ReadOnly reports whether the agent's Bash commands may only read
+func (c AgentConfig) ReadOnly() bool { return c.Bash == AgentBashReadOnly }
+
+// CanCommit reports whether the agent may write version control history
+func (c AgentConfig) CanCommit() bool { return c.Commit == nil || *c.Commit }
+
+// CanEdit reports whether the agent may edit files
+func (c AgentConfig) CanEdit() bool { return c.Edit == nil || *c.Edit }
+
+// AuditConfig controls what the audit log records and what leaves the machine
+type AuditConfig struct {
+	// Commands records the command of pre-bash events in the audit log, for
+	// `claude-hook policy corpus`. Off by default since commands can carry
+	// credentials.
+	Commands bool              `yaml:"commands"`
+	Export   AuditExportConfig `yaml:"export"`
+}
+
+// AuditExportConfig sends audit events to a SIEM as they are recorded
+type AuditExportConfig struct {
+	// Target is a file appended to (relative to this file), unix:///path of a
+	// socket, udp://host:port or tcp://host:port of a collector, or an http(s)
+	// URL events are POSTed to. Empty turns the export off.
+	Target string `yaml:"target"`
+	// Format is json (the default) or cef, one event per line or request
+	Format string `yaml:"format"`
+	// Events are the kinds exported: commands (blocked commands) and secrets
+	// (secrets found in outgoing commits). Empty exports both.
+	Events []string `yaml:"events"`
+	// Redact are the fields replaced with "[redacted]": detail, user, host,
+	// project, or session
+	Redact []string `yaml:"redact"`
+}
+
+// Default returns the configuration used when no config file exists
+func Default() *Config {
+	return &Config{
+		Go: GoConfig{
+			Concurrency: GoConcurrencyConfig{
+				Enabled: true,
+			},
+			Dependents: DependentsConfig{
+				Enabled: false,
+				Max:     20,
+			},
+			API: APIConfig{
+				Base: "main",
+			},
+		},
+		Push: PushConfig{
+			Enabled:     true,
+			MaxFileSize: 5 * 1024 * 1024,
+			DisallowedPaths: []string{
+				".env", ".env.local", ".env.*.local", "*.pem", "*.key", "*.p12", "*.pfx",
+				"id_rsa", "id_ed25519", "*.tfstate", "credentials.json",
+			},
+		},
+		Monorepo: MonorepoConfig{
+			Targets: []string{"lint", "test"},
+		},
+		Content: ContentConfig{
+			Enabled:           true,
+			MaxDeletedLines:   300,
+			MaxWriteSize:      1024 * 1024,
+			BinaryPaths:       []string{"testdata", "fixtures", "__fixtures__", "__snapshots__"},
+			OutputPaths:       []string{"cmd", "scripts", "bin", "tools", "examples"},
+			MaxRewritePercent: 50,
+		},
+		Duplicates: DuplicatesConfig{
+			MinTokens: 75,
+		},
+		Complexity: ComplexityConfig{
+			MaxCyclomatic: 15,
+			MaxLines:      80,
+		},
+		Bundle: BundleConfig{
+			MaxIncrease: 50 * 1024,
+		},
+		EditorConfig: EditorConfigConfig{
+			Enabled: true,
+		},
+		Encoding: EncodingConfig{
+			Enabled: true,
+		},
+		MergeArtifacts: MergeArtifactsConfig{
+			Enabled: true,
+		},
+		Placeholders: PlaceholdersConfig{
+			Enabled: true,
+		},
+		Images: ImagesConfig{
+			Enabled: true,
+		},
+		Security: SecurityConfig{
+			Block: "high",
+		},
+		Semgrep: SemgrepConfig{
+			Severities: map[string]string{"ERROR": SemgrepBlock, "WARNING": SemgrepWarn, "INFO": SemgrepWarn},
+		},
+		Paths: PathsConfig{
+			Outside: OutsideWarn,
+		},
+		Guardrails: GuardrailsConfig{
+			Changelog: ChangelogConfig{
+				File:     "CHANGELOG.md",
+				Template: "## [Unreleased]\n\n### Changed\n\n- Describe the change to {files}\n",
+			},
+			Loops: LoopsConfig{
+				MaxBlocks: 5,
+			},
+		},
+		Rollback: RollbackConfig{
+			Enabled:          true,
+			Keep:             20,
+			MaxUntrackedSize: 100 * 1024 * 1024,
+		},
+		Reasons: ReasonsConfig{
+			MaxChars: 8000,
+		},
+		Fixes: FixesConfig{
+			Enabled: true,
+		},
+	}
+}
+
+// layer is a config file applied on top of the defaults
+type layer struct {
+	path string
+	data []byte
+}
+
+// Load reads the user config, then the nearest config file at or above dir
+// over it, falling back to defaults, with the scope dir is in applied. Keys
+// the project sets replace the user's; lists and other values aren't merged.
+func Load(dir string) (*Config, error) {
+	var paths []string
+	if global := GlobalPath(); global != "" {
+		if _, err := os.Stat(global); err == nil {
+			paths = append(paths, global)
+		}
+	}
+	// The project may be the home directory, or use the same file through
+	// $CLAUDE_HOOKS_GLOBAL_CONFIG
+	if path := find(dir); path != "" && !slices.Contains(paths, path) {
+		paths = append(paths, path)
+	}
+
+	cfg := Default()
+	var applied []layer
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			err = fmt.Errorf("reading %s: %w", path, err)
+		} else {
+			err = cfg.apply(path, data)
+		}
+		if good, ok := lastGood(path, data, err); ok {
+			data = good
+			cfg, err = replay(append(applied, layer{path, data}))
+		}
+		if err != nil {
+			return nil, err
+		}
+		applied = append(applied, layer{path, data})
+	}
+	if err := cfg.applyScope(dir); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// replay applies layers to the defaults again, for when one is replaced by
+// its last good version
+func replay(layers []layer) (*Config, error) {
+	cfg := Default()
+	for _, l := range layers {
+		if err := cfg.apply(l.path, l.data); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// apply parses the config file at path over cfg, as the user config when
+// it's the global one. Scopes are directories relative to the project, so
+// the user config can't have them.
+func (cfg *Config) apply(path string, data []byte) error {
+	if path != GlobalPath() {
+		return parse(cfg, path, data)
+	}
+	project := cfg.Path
+	if err := parse(cfg, path, data); err != nil {
+		return err
+	}
+	cfg.Path, cfg.Global, cfg.Scopes = project, path, nil
+	return nil
+}
+
+// GlobalPath is the user config: $CLAUDE_HOOKS_GLOBAL_CONFIG, or
+// ~/.claude-hooks.yaml. It is "" when the variable is "off" or there's no
+// home directory.
+func GlobalPath() string {
+	if path := strings.TrimSpace(os.Getenv(GlobalConfigEnv)); path != "" {
+		if path == "off" {
+			return ""
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, FileName)
+}
+
+// parse applies the config file at path, with content data, to cfg once it
+// fits the schema
+func parse(cfg *Config, path string, data []byte) error {
+	problems, err := Validate(data)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(problems) > 0 {
+		return &SchemaError{Path: path, Problems: problems}
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	cfg.Path = path
+	return cfg.applyProfile()
+}
+
+// find walks up from dir looking for the config file
+func find(dir string) string {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		candidate := filepath.Join(current, FileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return ""
+		}
+		current = parent
+	}
+}
@@ -0,0 +1,462 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Path != "" || cfg.Go.Dependents.Enabled || cfg.Go.Dependents.Max != 20 {
+		t.Errorf("Expected defaults, got %+v", cfg)
+	}
+}
+
+func TestLoadFindsParentConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "internal", "foo")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	content := "go:\n  dependents:\n    enabled: true\n"
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(nested)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Path != filepath.Join(root, FileName) {
+		t.Errorf("Expected config from %s, got %q", root, cfg.Path)
+	}
+	if !cfg.Go.Dependents.Enabled {
+		t.Error("Expected dependents to be enabled")
+	}
+	// Unset keys keep their defaults
+	if cfg.Go.Dependents.Max != 20 {
+		t.Errorf("Expected default cap to survive partial config, got %d", cfg.Go.Dependents.Max)
+	}
+}
+
+func TestLoadGlobalConfig(t *testing.T) {
+	global := filepath.Join(t.TempDir(), FileName)
+	t.Setenv(GlobalConfigEnv, global)
+	if err := os.WriteFile(global, []byte("go:\n  dependents:\n    enabled: true\n    max: 5\nscopes:\n  services:\n    enforcement: warn\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	root := t.TempDir()
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Path != "" || cfg.Global != global || !cfg.Go.Dependents.Enabled || cfg.Go.Dependents.Max != 5 {
+		t.Errorf("Expected the user config alone, got %+v", cfg)
+	}
+	if len(cfg.Scopes) != 0 {
+		t.Errorf("Expected the user config's scopes to be dropped, got %v", cfg.Scopes)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte("go:\n  dependents:\n    max: 10\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err = Load(root)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Path != filepath.Join(root, FileName) || cfg.Global != global {
+		t.Errorf("Expected both configs, got path %q and global %q", cfg.Path, cfg.Global)
+	}
+	if !cfg.Go.Dependents.Enabled || cfg.Go.Dependents.Max != 10 {
+		t.Errorf("Expected the project's max over the user's, keeping enabled, got %+v", cfg.Go.Dependents)
+	}
+
+	if err := os.WriteFile(global, []byte("go: [unclosed"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := Load(root); err == nil || !strings.Contains(err.Error(), global) {
+		t.Errorf("Expected the broken user config to fail the load, got %v", err)
+	}
+
+	t.Setenv(GlobalConfigEnv, "off")
+	if cfg, err := Load(root); err != nil {
+		t.Errorf("Load failed: %v", err)
+	} else if cfg.Global != "" {
+		t.Errorf("Expected no user config when off, got %q", cfg.Global)
+	}
+}
+
+func TestLoadRejectsInvalidYAML(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte("go: [unclosed"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Fatal("Expected error for invalid YAML")
+	}
+}
+
+func TestKeepLastGood(t *testing.T) {
+	var got []Reload
+	KeepLastGood(func(r Reload) { got = append(got, r) })
+	t.Cleanup(func() { reloads.good, reloads.rejected, reloads.onReload = nil, nil, nil })
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	load := func() *Config {
+		t.Helper()
+		cfg, err := Load(dir)
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		return cfg
+	}
+
+	write("go:\n  dependents:\n    max: 5\n")
+	if cfg := load(); cfg.Go.Dependents.Max != 5 || len(got) != 0 {
+		t.Fatalf("Expected the first version to load silently, got max %d and %+v", cfg.Go.Dependents.Max, got)
+	}
+	write("go:\n  dependents:\n    max: 7\n")
+	if cfg := load(); cfg.Go.Dependents.Max != 7 || len(got) != 1 || got[0].Err != nil {
+		t.Fatalf("Expected the change to be reloaded and reported, got max %d and %+v", cfg.Go.Dependents.Max, got)
+	}
+
+	write("go: [broken\n")
+	load()
+	if cfg := load(); cfg.Go.Dependents.Max != 7 || cfg.Path != path {
+		t.Errorf("Expected the last good version to stay active, got max %d", cfg.Go.Dependents.Max)
+	}
+	if len(got) != 2 || got[1].Err == nil || got[1].Path != path {
+		t.Errorf("Expected the broken version to be reported once, got %+v", got)
+	}
+
+	write("go:\n  dependents:\n    max: 9\n")
+	if cfg := load(); cfg.Go.Dependents.Max != 9 || len(got) != 3 || got[2].Err != nil {
+		t.Errorf("Expected the fixed version to be reloaded, got max %d and %+v", cfg.Go.Dependents.Max, got)
+	}
+}
+
+func TestLoadTimeouts(t *testing.T) {
+	dir := t.TempDir()
+	content := "timeouts:\n  languages:\n    go: 3m\n  steps:\n    go test: 90s\n"
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Timeouts.Languages["go"] != 3*time.Minute || cfg.Timeouts.Steps["go test"] != 90*time.Second {
+		t.Errorf("Expected the durations to parse, got %+v", cfg.Timeouts)
+	}
+}
+
+func TestResolveEnforcement(t *testing.T) {
+	cfg := &Config{Enforcement: "warn", Path: ".claude-hooks.yaml"}
+	t.Setenv(EnforcementEnv, "")
+	t.Setenv(DryRunEnv, "")
+	t.Setenv(ReadOnlyEnv, "")
+	if level, err := ResolveEnforcement(cfg); err != nil || level != EnforceWarn {
+		t.Errorf("Expected the config's warn, got %q (%v)", level, err)
+	}
+
+	t.Setenv(EnforcementEnv, "block")
+	if level, _ := ResolveEnforcement(cfg); level != EnforceBlock {
+		t.Errorf("Expected $%s to override the config, got %q", EnforcementEnv, level)
+	}
+
+	t.Setenv(DryRunEnv, "1")
+	if level, _ := ResolveEnforcement(cfg); level != EnforceDryRun {
+		t.Errorf("Expected $%s to win, got %q", DryRunEnv, level)
+	}
+
+	t.Setenv(DryRunEnv, "")
+	t.Setenv(ReadOnlyEnv, "true")
+	if level, _ := ResolveEnforcement(cfg); level != EnforceWarn || !ReadOnly() {
+		t.Errorf("Expected $%s to downgrade blocks to warnings, got %q", ReadOnlyEnv, level)
+	}
+
+	t.Setenv(ReadOnlyEnv, "")
+	t.Setenv(EnforcementEnv, "wran")
+	if level, err := ResolveEnforcement(cfg); err == nil || level != EnforceBlock {
+		t.Errorf("Expected an unknown level to block with an error, got %q (%v)", level, err)
+	}
+}
+
+func TestResolveOffline(t *testing.T) {
+	cfg := &Config{Offline: true}
+	t.Setenv(OfflineEnv, "")
+	if !ResolveOffline(cfg) {
+		t.Error("Expected the config's offline without the env var")
+	}
+	t.Setenv(OfflineEnv, "false")
+	if ResolveOffline(cfg) {
+		t.Errorf("Expected $%s=false to override the config", OfflineEnv)
+	}
+	t.Setenv(OfflineEnv, "true")
+	if !ResolveOffline(nil) {
+		t.Errorf("Expected $%s=true without a config to be offline", OfflineEnv)
+	}
+}
+
+func TestAgents(t *testing.T) {
+	no := false
+	agents := AgentsConfig{
+		Subagents: AgentConfig{Bash: AgentBashReadOnly, Commit: &no},
+		Types:     map[string]AgentConfig{"builder": {}},
+	}
+	if a := agents.For("", ""); a.ReadOnly() || !a.CanCommit() || !a.CanEdit() {
+		t.Errorf("The main agent was restricted: %+v", a)
+	}
+	if a := agents.For("a1", "Explore"); !a.ReadOnly() || a.CanCommit() || !a.CanEdit() {
+		t.Errorf("Expected subagents' restrictions for an unlisted type, got %+v", a)
+	}
+	if a := agents.For("a2", "builder"); a.ReadOnly() || !a.CanCommit() {
+		t.Errorf("Expected the builder type to replace subagents, got %+v", a)
+	}
+}
+
+func TestResolveCABundle(t *testing.T) {
+	t.Setenv(CABundleEnv, "")
+	cfg := &Config{Path: filepath.Join("project", FileName), CABundle: "certs/corp.pem"}
+	if got, want := ResolveCABundle(cfg), filepath.Join("project", "certs", "corp.pem"); got != want {
+		t.Errorf("Expected the bundle relative to the config, got %q, want %q", got, want)
+	}
+	if got := ResolveCABundle(&Config{}); got != "" {
+		t.Errorf("Expected no bundle by default, got %q", got)
+	}
+	t.Setenv(CABundleEnv, "/etc/ssl/corp.pem")
+	if got := ResolveCABundle(cfg); got != "/etc/ssl/corp.pem" {
+		t.Errorf("Expected $%s to override the config, got %q", CABundleEnv, got)
+	}
+}
+
+func TestSettingsProfiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(SettingsProfileEnv, "")
+	dir := t.TempDir()
+	content := `enforcement: warn
+timeouts:
+  steps:
+    go test: 1m
+profiles:
+  strict:
+    enforcement: block
+    profile: unattended
+    timeouts:
+      steps:
+        tsc: 2m
+    plan_review:
+      reviewers: [claude]
+`
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.SettingsProfile != "" || cfg.Enforcement != "warn" {
+		t.Errorf("Expected no profile until one is selected, got %q with enforcement %s", cfg.SettingsProfile, cfg.Enforcement)
+	}
+
+	if err := UseProfile("strict"); err != nil {
+		t.Fatalf("UseProfile failed: %v", err)
+	}
+	if name, source, err := SelectedProfile(); err != nil || name != "strict" || !strings.HasSuffix(source, "profile.json") {
+		t.Errorf("Expected strict from the saved selection, got %q from %q (%v)", name, source, err)
+	}
+	cfg, err = Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.SettingsProfile != "strict" || cfg.Enforcement != "block" || cfg.Profile != "unattended" || !slices.Equal(cfg.PlanReview.Reviewers, []string{"claude"}) {
+		t.Errorf("Expected the strict profile's settings, got %+v", cfg)
+	}
+	// Settings the profile doesn't mention are the project's own
+	if cfg.Timeouts.Steps["go test"] != time.Minute || cfg.Timeouts.Steps["tsc"] != 2*time.Minute {
+		t.Errorf("Expected the profile's step timeouts added to the project's, got %v", cfg.Timeouts.Steps)
+	}
+
+	// The environment wins, and a profile the project doesn't define changes nothing
+	t.Setenv(SettingsProfileEnv, "personal")
+	if cfg, err = Load(dir); err != nil || cfg.SettingsProfile != "" || cfg.Enforcement != "warn" {
+		t.Errorf("Expected the project's settings for an undefined profile, got %+v (%v)", cfg, err)
+	}
+
+	t.Setenv(SettingsProfileEnv, "")
+	if err := UseProfile(""); err != nil {
+		t.Fatalf("Clearing the profile failed: %v", err)
+	}
+	if name, _, err := SelectedProfile(); err != nil || name != "" {
+		t.Errorf("Expected no profile after clearing it, got %q (%v)", name, err)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(SettingsProfileEnv, "")
+	dir := t.TempDir()
+	content := `enforcement: warn
+go:
+  tests:
+    enabled: false
+plan_review:
+  reviewers: [claude]
+scopes:
+  services:
+    go:
+      tests:
+        enabled: true
+  ./services/payments/:
+    enforcement: block
+    plan_review:
+      reviewers: [claude, codex, gemini]
+    bash:
+      rules:
+        - name: no-prod
+          match: prod
+`
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		dir, scope, enforcement string
+		tests                   bool
+		reviewers, rules        int
+	}{
+		{".", "", "warn", false, 1, 0},
+		{"services", "services", "warn", true, 1, 0},
+		{"services/search/api", "services", "warn", true, 1, 0},
+		{"services/payments", "services/payments", "block", false, 3, 1},
+		{"services/payments/ledger", "services/payments", "block", false, 3, 1},
+		{"services/payments-v2", "services", "warn", true, 1, 0},
+	}
+	for _, tt := range tests {
+		cfg, err := Load(filepath.Join(dir, tt.dir))
+		if err != nil {
+			t.Fatalf("Load(%s) failed: %v", tt.dir, err)
+		}
+		// Only the longest scope applies, over the top level
+		if cfg.Scope != tt.scope || cfg.Enforcement != tt.enforcement || cfg.Go.Tests.Enabled != tt.tests || len(cfg.PlanReview.Reviewers) != tt.reviewers || len(cfg.Bash.Rules) != tt.rules {
+			t.Errorf("Load(%s) = scope %q, enforcement %s, tests %v, %d reviewers, %d rules", tt.dir, cfg.Scope, cfg.Enforcement, cfg.Go.Tests.Enabled, len(cfg.PlanReview.Reviewers), len(cfg.Bash.Rules))
+		}
+	}
+
+	problems, err := Validate([]byte("scopes:\n  services/payments:\n    enforcement: strict\n"))
+	if err != nil || !slices.Equal(problems, []string{`line 3: scopes.services/payments.enforcement: expected one of block, warn, dry-run, got "strict"`}) {
+		t.Errorf("Expected the scope's setting checked, got %v (%v)", problems, err)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	if got := strings.Join(Templates(), ","); got != "go-service,infra,monorepo,ts-webapp" {
+		t.Errorf("Unexpected templates %s", got)
+	}
+	for _, name := range Templates() {
+		data, err := Template(name)
+		if err != nil {
+			t.Fatalf("Template(%s) failed: %v", name, err)
+		}
+		// Every key must be a real setting: a typo would silently do nothing
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(Default()); err != nil {
+			t.Errorf("Template %s doesn't parse as a config: %v", name, err)
+		}
+		if problems, err := Validate(data); err != nil || len(problems) > 0 {
+			t.Errorf("Template %s doesn't fit the schema: %v %v", name, problems, err)
+		}
+	}
+	if _, err := Template("rails"); err == nil || !strings.Contains(err.Error(), "go-service") {
+		t.Errorf("Expected an unknown template to fail listing the known ones, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	data := []byte(`go:
+  dependants:
+    enabled: true
+  tests:
+    enabled: yes
+plan_review:
+  reviewers: [claude, fast]
+  secrets:
+    codex:
+      OPENAI_API_KEY: op://Engineering/OpenAI/credential
+    gemini:
+      GEMINI_API_KEY: sk-plaintext
+timeouts:
+  steps:
+    go test: fast
+content:
+  max_write_size: big
+  disabled: lint-suppression
+paths:
+  outside: ""
+profiles:
+  strict:
+    enforcement: strict
+`)
+	problems, err := Validate(data)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	want := []string{
+		"line 2: go.dependants: unknown setting (did you mean dependents?)",
+		`line 5: go.tests.enabled: expected true or false, got "yes"`,
+		`line 7: plan_review.reviewers[1]: expected one of claude, codex, gemini, got "fast"`,
+		`line 12: plan_review.secrets.gemini.GEMINI_API_KEY: expected a secret reference like op://vault/item/field or keyring:service/account, got "sk-plaintext"`,
+		`line 15: timeouts.steps.go test: expected a duration like 30s or 2m, got "fast"`,
+		`line 17: content.max_write_size: expected a whole number, got "big"`,
+		`line 18: content.disabled: expected a list, got "lint-suppression"`,
+		`line 23: profiles.strict.enforcement: expected one of block, warn, dry-run, got "strict"`,
+	}
+	if strings.Join(problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected problems:\n%s", strings.Join(problems, "\n"))
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, FileName), data, 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	var schemaErr *SchemaError
+	if _, err := Load(dir); !errors.As(err, &schemaErr) || len(schemaErr.Problems) != len(want) {
+		t.Errorf("Expected Load to reject the config with every problem, got %v", err)
+	}
+}
+
+func TestSchemaFile(t *testing.T) {
+	schema, err := Schema()
+	if err != nil {
+		t.Fatalf("Schema failed: %v", err)
+	}
+	shipped, err := os.ReadFile(filepath.Join("..", "..", "schema.json"))
+	if err != nil {
+		t.Fatalf("Failed to read schema.json: %v", err)
+	}
+	if !bytes.Equal(schema, shipped) {
+		t.Error("schema.json is out of date; regenerate it with `go run ./cmd/claude-hook config schema > schema.json`")
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Environment variables overriding the config for one shell or session,
+// without editing the config or re-running setup
+const (
+	EnforcementEnv = "CLAUDE_HOOKS_ENFORCEMENT"
+	// DryRunEnv set to a true value is short for $CLAUDE_HOOKS_ENFORCEMENT=dry-run
+	DryRunEnv   = "CLAUDE_HOOKS_DRY_RUN"
+	OfflineEnv  = "CLAUDE_HOOKS_OFFLINE"
+	CABundleEnv = "CLAUDE_HOOKS_CA_BUNDLE"
+	// ReadOnlyEnv set to a true value runs every check and reports it, but
+	// the hooks change nothing in the project and block nothing, for demos
+	// and pairing
+	ReadOnlyEnv = "CLAUDE_HOOKS_READ_ONLY"
+	// GlobalConfigEnv is the user config to use instead of
+	// ~/.claude-hooks.yaml, or "off" for none
+	GlobalConfigEnv = "CLAUDE_HOOKS_GLOBAL_CONFIG"
+)
+
+// Enforcement levels
+const (
+	// EnforceBlock blocks what fails a check
+	EnforceBlock = "block"
+	// EnforceWarn shows the reason it would block, to Claude after an edit and
+	// to the user otherwise, and lets the tool call or turn go ahead
+	EnforceWarn = "warn"
+	// EnforceDryRun only logs what would have been blocked, for trying out
+	// rules on a project before turning them on
+	EnforceDryRun = "dry-run"
+)
+
+// ResolveEnforcement returns the enforcement level from $CLAUDE_HOOKS_DRY_RUN,
+// then $CLAUDE_HOOKS_READ_ONLY (warn), then $CLAUDE_HOOKS_ENFORCEMENT, then
+// the config's enforcement, defaulting to block. An unknown level resolves to
+// block, since a typo must not silently stop the checks from blocking; the
+// error says so.
+func ResolveEnforcement(cfg *Config) (string, error) {
+	if dryRun, ok := envBool(DryRunEnv); ok && dryRun {
+		return EnforceDryRun, nil
+	}
+	if ReadOnly() {
+		return EnforceWarn, nil
+	}
+	level := os.Getenv(EnforcementEnv)
+	source := "$" + EnforcementEnv
+	if level == "" && cfg != nil {
+		level, source = cfg.Enforcement, "enforcement in "+cfg.Path
+	}
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "", EnforceBlock:
+		return EnforceBlock, nil
+	case EnforceWarn:
+		return EnforceWarn, nil
+	case EnforceDryRun, "dryrun":
+		return EnforceDryRun, nil
+	default:
+		return EnforceBlock, fmt.Errorf("unknown %s %q (want %s, %s, or %s), using %s", source, level, EnforceBlock, EnforceWarn, EnforceDryRun, EnforceBlock)
+	}
+}
+
+// ResolveOffline returns $CLAUDE_HOOKS_OFFLINE when it's set, and the
+// config's offline otherwise
+func ResolveOffline(cfg *Config) bool {
+	if offline, ok := envBool(OfflineEnv); ok {
+		return offline
+	}
+	return cfg != nil && cfg.Offline
+}
+
+// ResolveCABundle returns $CLAUDE_HOOKS_CA_BUNDLE when it's set, and the
+// config's ca_bundle otherwise, made absolute against the config's directory.
+// Empty means only the system's certificates are trusted.
+func ResolveCABundle(cfg *Config) string {
+	if path := os.Getenv(CABundleEnv); path != "" {
+		return path
+	}
+	if cfg == nil || cfg.CABundle == "" {
+		return ""
+	}
+	if filepath.IsAbs(cfg.CABundle) || cfg.Path == "" {
+		return cfg.CABundle
+	}
+	return filepath.Join(filepath.Dir(cfg.Path), cfg.CABundle)
+}
+
+// ResolveAuditExport returns the config's audit export target, with a file
+// made absolute against the config's directory. Empty means no export.
+func ResolveAuditExport(cfg *Config) string {
+	target := cfg.Audit.Export.Target
+	if target == "" || strings.Contains(target, "://") || filepath.IsAbs(target) || cfg.Path == "" {
+		return target
+	}
+	return filepath.Join(filepath.Dir(cfg.Path), target)
+}
+
+// ReadOnly reports whether $CLAUDE_HOOKS_READ_ONLY is set: hooks skip what
+// writes to the project, like code generation, fixers, and rollback snapshots
+func ReadOnly() bool {
+	readOnly, ok := envBool(ReadOnlyEnv)
+	return ok && readOnly
+}
+
+// envBool parses a boolean environment variable, reporting whether it's set
+// to one
+func envBool(name string) (value, ok bool) {
+	value, err := strconv.ParseBool(strings.TrimSpace(os.Getenv(name)))
+	return value, err == nil
+}
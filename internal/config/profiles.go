@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SettingsProfileEnv selects the settings profile for one shell or session,
+// overriding the one saved by `claude-hook profile use`. It is unrelated to
+// $CLAUDE_HOOKS_PROFILE, which picks interactive or unattended.
+const SettingsProfileEnv = "CLAUDE_HOOKS_SETTINGS_PROFILE"
+
+// profileSelection is the settings profile saved by `claude-hook profile use`
+type profileSelection struct {
+	Profile string `json:"profile"`
+}
+
+// selectionPath lives in the user config directory, like the telemetry
+// preferences, so it applies to every project and survives cleaning state
+func selectionPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating user config directory: %w", err)
+	}
+	return filepath.Join(dir, "claude-hooks", "profile.json"), nil
+}
+
+// SelectedProfile returns the name of the settings profile in use and where
+// it was selected: $CLAUDE_HOOKS_SETTINGS_PROFILE, then the one saved by
+// `claude-hook profile use`. The name is empty when none is.
+func SelectedProfile() (name, source string, err error) {
+	if name := strings.TrimSpace(os.Getenv(SettingsProfileEnv)); name != "" {
+		return name, "$" + SettingsProfileEnv, nil
+	}
+	path, err := selectionPath()
+	if err != nil {
+		return "", "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("reading the settings profile: %w", err)
+	}
+	var selection profileSelection
+	if err := json.Unmarshal(data, &selection); err != nil {
+		return "", "", fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return selection.Profile, path, nil
+}
+
+// UseProfile saves name as the settings profile of every project; an empty
+// name goes back to the projects' own settings
+func UseProfile(name string) error {
+	path, err := selectionPath()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("clearing the settings profile: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(profileSelection{Profile: name}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing the settings profile: %w", err)
+	}
+	return nil
+}
+
+// applyProfile overrides the config with the selected profile, when the
+// config defines it. A project without the profile keeps its own settings,
+// since one selection covers projects with different profiles. A selection
+// that can't be read is ignored here; `claude-hook profile` reports it.
+func (cfg *Config) applyProfile() error {
+	name, _, err := SelectedProfile()
+	if err != nil || name == "" {
+		return nil
+	}
+	node, ok := cfg.Profiles[name]
+	if !ok {
+		return nil
+	}
+	profiles := cfg.Profiles
+	if err := node.Decode(cfg); err != nil {
+		return fmt.Errorf("parsing profile %s in %s: %w", name, cfg.Path, err)
+	}
+	cfg.Profiles, cfg.SettingsProfile = profiles, name
+	return nil
+}
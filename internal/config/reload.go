@@ -0,0 +1,66 @@
+package config
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Reload is a change of a config file seen by a process keeping the last
+// good configs
+type Reload struct {
+	Path string
+	// Err is why the new version was rejected, keeping the last good one
+	// active; nil when the new version is active
+	Err error
+}
+
+// reloads tracks the config files of a process that called KeepLastGood
+var reloads struct {
+	sync.Mutex
+	onReload func(Reload)
+	good     map[string][]byte // Path -> content of the last version that loaded
+	rejected map[string][]byte // Path -> content of the version last reported broken
+}
+
+// KeepLastGood is for long-running processes like watch and lsp, which load
+// the config again for every run and so pick up changes without restarting.
+// From then on, a config file that no longer loads, like one saved halfway
+// through an edit, is replaced by the last version of it that did, instead of
+// turning checks off or failing them. onReload is called when a changed
+// version is made active or rejected; each rejected version is reported once.
+func KeepLastGood(onReload func(Reload)) {
+	reloads.Lock()
+	defer reloads.Unlock()
+	reloads.onReload = onReload
+	reloads.good = make(map[string][]byte)
+	reloads.rejected = make(map[string][]byte)
+}
+
+// lastGood records how the config file at path, with content data, loaded,
+// and returns the content to load instead when it failed with err and a
+// previous version loaded. It is a no-op until KeepLastGood is called.
+func lastGood(path string, data []byte, err error) ([]byte, bool) {
+	reloads.Lock()
+	defer reloads.Unlock()
+	if reloads.good == nil {
+		return nil, false
+	}
+
+	good, known := reloads.good[path]
+	if err == nil {
+		reloads.good[path] = data
+		delete(reloads.rejected, path)
+		if known && !bytes.Equal(good, data) {
+			reloads.onReload(Reload{Path: path})
+		}
+		return nil, false
+	}
+	if !known {
+		return nil, false // Nothing to fall back to
+	}
+	if rejected, ok := reloads.rejected[path]; !ok || !bytes.Equal(rejected, data) {
+		reloads.rejected[path] = data
+		reloads.onReload(Reload{Path: path, Err: err})
+	}
+	return good, true
+}
@@ -0,0 +1,333 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// enums are the values of settings that take one of a fixed set, by their
+// path in the schema: list items are "[]" and map values "*"
+var enums = map[string][]string{
+	"enforcement":               {EnforceBlock, EnforceWarn, EnforceDryRun},
+	"profile":                   {"interactive", "unattended"},
+	"paths.outside":             {OutsideBlock, OutsideWarn, OutsideValidate},
+	"plan_review.reviewers[]":   {"claude", "codex", "gemini"},
+	"bash.rules[].decision":     {"deny", "ask", "allow"},
+	"policy.engine":             {"opa", "exec"},
+	"policy.hooks[]":            {"pre-bash", "pre-edit", "stop"},
+	"go.concurrency.disabled[]": {"loop-capture", "lock-copy", "waitgroup-add", "sleep-sync"},
+	"go.errors.disabled[]":      {"unwrapped", "discarded", "no-context"},
+	"placeholders.disabled[]":   {"elided-code", "not-implemented", "empty-catch", "todo-stub", "lorem-ipsum"},
+	"security.block":            append(slices.Clone(SecuritySeverities), "none"),
+	"semgrep.severities.*":      {SemgrepBlock, SemgrepWarn, SemgrepIgnore},
+	"agents.subagents.bash":     {AgentBashFull, AgentBashReadOnly},
+	"agents.types.*.bash":       {AgentBashFull, AgentBashReadOnly},
+	"audit.export.format":       {"json", "cef"},
+	"audit.export.events[]":     {"commands", "secrets"},
+	"audit.export.redact[]":     {"detail", "user", "host", "project", "session"},
+}
+
+// patterns are what values of free-form settings must look like, by their
+// path in the schema
+var patterns = map[string]struct {
+	re   *regexp.Regexp
+	want string
+}{
+	"plan_review.secrets.*.*": {regexp.MustCompile(`^((keyring|vault|aws-sm|env):.+|op://.+)$`), "a secret reference like op://vault/item/field or keyring:service/account"},
+}
+
+// durationPattern matches what time.ParseDuration accepts
+var durationPattern = regexp.MustCompile(`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`)
+
+var (
+	durationType = reflect.TypeFor[time.Duration]()
+	nodeType     = reflect.TypeFor[yaml.Node]()
+	configType   = reflect.TypeFor[Config]()
+)
+
+// SchemaError is a config file that parses as YAML but doesn't fit the
+// schema, with every problem found
+type SchemaError struct {
+	Path     string
+	Problems []string // Each "line N: setting: problem"
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s is invalid:\n  %s", e.Path, strings.Join(e.Problems, "\n  "))
+}
+
+// Validate checks config file content against the schema and returns its
+// problems, e.g. `line 4: plan_review.reviewers[1]: expected one of claude,
+// codex, gemini, got "fast"`. Settings profiles are checked like the top
+// level, and so are scopes. Content that isn't YAML is returned as an error.
+func Validate(data []byte) ([]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil // Empty file
+	}
+	var problems []string
+	validateNode(doc.Content[0], configType, "", "", &problems)
+	return problems, nil
+}
+
+// validateNode checks node against t, appending problems. display is the
+// setting's path as shown, and path is its path in the schema.
+func validateNode(node *yaml.Node, t reflect.Type, display, path string, problems *[]string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.ShortTag() == "!!null" {
+		return // Leaves the default
+	}
+	fail := func(format string, args ...any) {
+		name := display
+		if name == "" {
+			name = "top level"
+		}
+		*problems = append(*problems, fmt.Sprintf("line %d: %s: %s", node.Line, name, fmt.Sprintf(format, args...)))
+	}
+
+	if t == nodeType {
+		// Profiles and scopes override the top level's settings
+		if strings.HasPrefix(path, "profiles.") || strings.HasPrefix(path, "scopes.") {
+			validateNode(node, configType, display, "", problems)
+		}
+		return
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		validateNode(node, t.Elem(), display, path, problems)
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			fail("expected a mapping, got %s", describe(node))
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				known := slices.Sorted(maps.Keys(fields))
+				msg := fmt.Sprintf("line %d: %s: unknown setting", key.Line, join(display, key.Value))
+				if suggestion := closest(key.Value, known); suggestion != "" {
+					msg += fmt.Sprintf(" (did you mean %s?)", suggestion)
+				}
+				*problems = append(*problems, msg)
+				continue
+			}
+			validateNode(value, field.Type, join(display, key.Value), join(path, key.Value), problems)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			fail("expected a mapping, got %s", describe(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			validateNode(value, t.Elem(), join(display, key.Value), join(path, "*"), problems)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			fail("expected a list, got %s", describe(node))
+			return
+		}
+		for i, item := range node.Content {
+			validateNode(item, t.Elem(), fmt.Sprintf("%s[%d]", display, i), path+"[]", problems)
+		}
+	default:
+		if node.Kind != yaml.ScalarNode {
+			fail("expected %s, got %s", scalarName(t), describe(node))
+			return
+		}
+		if problem := checkScalar(node, t); problem != "" {
+			fail("%s", problem)
+			return
+		}
+		// Empty means the default, as if the setting were left out
+		if values, ok := enums[path]; ok && node.Value != "" && !slices.Contains(values, node.Value) {
+			fail("expected one of %s, got %q", strings.Join(values, ", "), node.Value)
+		}
+		if p, ok := patterns[path]; ok && !p.re.MatchString(node.Value) {
+			fail("expected %s, got %q", p.want, node.Value)
+		}
+	}
+}
+
+// checkScalar returns why the scalar node can't be decoded into t, or ""
+func checkScalar(node *yaml.Node, t reflect.Type) string {
+	tag := node.ShortTag()
+	switch {
+	case t == durationType:
+		if tag == "!!int" || durationPattern.MatchString(node.Value) {
+			return ""
+		}
+		return fmt.Sprintf("expected a duration like 30s or 2m, got %q", node.Value)
+	case t.Kind() == reflect.Bool:
+		if tag != "!!bool" {
+			return fmt.Sprintf("expected true or false, got %q", node.Value)
+		}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		if tag != "!!int" {
+			return fmt.Sprintf("expected a whole number, got %q", node.Value)
+		}
+		if _, err := strconv.ParseInt(node.Value, 0, 64); err != nil && t.Kind() < reflect.Uint {
+			return fmt.Sprintf("expected a whole number, got %q", node.Value)
+		}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		if tag != "!!int" && tag != "!!float" {
+			return fmt.Sprintf("expected a number, got %q", node.Value)
+		}
+	}
+	return ""
+}
+
+// yamlFields returns the fields of struct t by their yaml names
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// join appends a setting's name to its parent's path
+func join(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// describe names the kind of value node holds for an error
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return strconv.Quote(node.Value)
+	}
+}
+
+// scalarName describes the value a scalar of type t takes
+func scalarName(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return "a duration"
+	case t.Kind() == reflect.Bool:
+		return "true or false"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Float64:
+		return "a number"
+	default:
+		return "a string"
+	}
+}
+
+// closest returns the candidate within two edits of name, or ""
+func closest(name string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, c := range candidates {
+		if d := editDistance(name, c); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// Schema returns the JSON Schema of the config file, for editors to complete
+// and check it with. schema.json at the repository root is this output.
+func Schema() ([]byte, error) {
+	schema := schemaFor(configType, "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "claude-hooks configuration (" + FileName + ")"
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, errors.New("encoding the schema: " + err.Error())
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaFor returns the JSON Schema of type t at path
+func schemaFor(t reflect.Type, path string) map[string]any {
+	if t == nodeType {
+		if strings.HasPrefix(path, "profiles.") || strings.HasPrefix(path, "scopes.") {
+			return map[string]any{"$ref": "#"}
+		}
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), path)
+	case reflect.Struct:
+		properties := make(map[string]any)
+		for name, f := range yamlFields(t) {
+			properties[name] = schemaFor(f.Type, join(path, name))
+		}
+		return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), join(path, "*"))}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), path+"[]")}
+	}
+
+	var schema map[string]any
+	switch {
+	case t == durationType:
+		schema = map[string]any{"type": []string{"string", "integer"}, "pattern": durationPattern.String()}
+	case t.Kind() == reflect.Bool:
+		schema = map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = map[string]any{"type": "number"}
+	default:
+		schema = map[string]any{"type": "string"}
+	}
+	if values, ok := enums[path]; ok {
+		schema["enum"] = values
+	}
+	if p, ok := patterns[path]; ok {
+		schema["pattern"] = p.re.String()
+	}
+	return schema
+}
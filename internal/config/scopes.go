@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// cleanScope returns a scope's directory as matched: slash-separated, without
+// a leading ./ or a trailing slash
+func cleanScope(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+}
+
+// ScopeFor returns the scope of cfg that files in dir are in: the longest one
+// that is dir or one of its parents, relative to the config file. It returns
+// "" when none is.
+func (cfg *Config) ScopeFor(dir string) string {
+	if cfg.Path == "" || len(cfg.Scopes) == 0 {
+		return ""
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(filepath.Dir(cfg.Path), abs)
+	if err != nil {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+	best := ""
+	for name := range cfg.Scopes {
+		clean := cleanScope(name)
+		if clean == "" || rel != clean && !strings.HasPrefix(rel, clean+"/") {
+			continue
+		}
+		if best == "" || len(clean) > len(cleanScope(best)) {
+			best = name
+		}
+	}
+	return best
+}
+
+// applyScope overrides the config with the scope dir is in. Settings the
+// scope doesn't mention keep the top level's values.
+func (cfg *Config) applyScope(dir string) error {
+	name := cfg.ScopeFor(dir)
+	if name == "" {
+		return nil
+	}
+	node := cfg.Scopes[name]
+	scopes, profiles := cfg.Scopes, cfg.Profiles
+	if err := node.Decode(cfg); err != nil {
+		return fmt.Errorf("parsing scope %s in %s: %w", name, cfg.Path, err)
+	}
+	cfg.Scopes, cfg.Profiles = scopes, profiles
+	cfg.Scope = cleanScope(name)
+	return nil
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// templates are starting configs for common kinds of project, written by
+// `claude-hook init`
+//
+//go:embed templates/*.yaml
+var templates embed.FS
+
+// Templates returns the names of the project templates, sorted
+func Templates() []string {
+	entries, _ := fs.ReadDir(templates, "templates")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".yaml"))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Template returns the config file of the named project template
+func Template(name string) ([]byte, error) {
+	if !slices.Contains(Templates(), name) {
+		return nil, fmt.Errorf("unknown template %q (expected one of %s)", name, strings.Join(Templates(), ", "))
+	}
+	return templates.ReadFile("templates/" + name + ".yaml")
+}
@@ -0,0 +1,256 @@
+// Package editorconfig resolves the .editorconfig properties of a file, per
+// https://spec.editorconfig.org: every .editorconfig from the file's directory
+// up to one with root = true, the closest taking precedence
+package editorconfig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileName is the name of EditorConfig files
+const FileName = ".editorconfig"
+
+// Properties are a file's resolved properties, keys and values lowercased,
+// e.g. indent_style: space. Properties set to "unset" are left out.
+type Properties map[string]string
+
+// Resolve returns the properties that apply to file
+func Resolve(file string) (Properties, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collected closest first, applied farthest first
+	var configs []*config
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		c, err := parseFile(filepath.Join(dir, FileName))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if c != nil {
+			c.dir = dir
+			configs = append(configs, c)
+			if c.root {
+				break
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	props := make(Properties)
+	for i := len(configs) - 1; i >= 0; i-- {
+		c := configs[i]
+		rel, err := filepath.Rel(c.dir, abs)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, s := range c.sections {
+			if !s.glob.MatchString(rel) {
+				continue
+			}
+			for _, kv := range s.props {
+				props[kv[0]] = kv[1]
+			}
+		}
+	}
+	for k, v := range props {
+		if v == "unset" {
+			delete(props, k)
+		}
+	}
+	return props, nil
+}
+
+type config struct {
+	dir      string
+	root     bool
+	sections []section
+}
+
+type section struct {
+	glob  *regexp.Regexp
+	props [][2]string // In file order
+}
+
+// parseFile reads an .editorconfig file. Lines it can't make sense of are
+// skipped, like editors do.
+func parseFile(path string) (*config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &config{}
+	var current *section
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			glob, err := compileGlob(line[1 : len(line)-1])
+			if err != nil {
+				current = nil
+				continue
+			}
+			c.sections = append(c.sections, section{glob: glob})
+			current = &c.sections[len(c.sections)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if lower := strings.ToLower(value); lower == "true" || lower == "false" || lower == "unset" || isKnown(key) {
+			value = lower
+		}
+		if current == nil {
+			if key == "root" {
+				c.root = value == "true"
+			}
+			continue
+		}
+		current.props = append(current.props, [2]string{key, value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// isKnown reports whether key is a property of the spec, whose values are
+// case insensitive
+func isKnown(key string) bool {
+	switch key {
+	case "indent_style", "indent_size", "tab_width", "end_of_line", "charset",
+		"trim_trailing_whitespace", "insert_final_newline", "max_line_length":
+		return true
+	}
+	return false
+}
+
+// compileGlob turns a section name into a regexp matching slash-separated
+// paths relative to the .editorconfig's directory. A glob without a slash
+// matches files in any directory.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	} else {
+		glob = strings.TrimPrefix(glob, "/")
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	braces := 0
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				re.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// **/ also matches no directory at all
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					re.WriteString("(?:.*/)?")
+				} else {
+					re.WriteString(".*")
+				}
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			re.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			i += end + 1
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+		case '{':
+			end := strings.IndexByte(glob[i+1:], '}')
+			if end < 0 {
+				re.WriteString(`\{`)
+				continue
+			}
+			inner := glob[i+1 : i+1+end]
+			if lo, hi, ok := numericRange(inner); ok {
+				i += end + 1
+				var alts []string
+				for n := lo; n <= hi; n++ {
+					alts = append(alts, strconv.Itoa(n))
+				}
+				re.WriteString("(?:" + strings.Join(alts, "|") + ")")
+				continue
+			}
+			if !strings.Contains(inner, ",") {
+				// A single choice is literal, braces included
+				i += end + 1
+				re.WriteString(regexp.QuoteMeta("{" + inner + "}"))
+				continue
+			}
+			braces++
+			re.WriteString("(?:")
+		case '}':
+			if braces > 0 {
+				braces--
+				re.WriteString(")")
+			} else {
+				re.WriteString(`\}`)
+			}
+		case ',':
+			if braces > 0 {
+				re.WriteString("|")
+			} else {
+				re.WriteString(",")
+			}
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if braces > 0 {
+		return nil, fmt.Errorf("unclosed brace in %q", glob)
+	}
+	re.WriteString("$")
+	return regexp.Compile(re.String())
+}
+
+// numericRange parses {n1..n2}, which matches the integers from n1 to n2
+func numericRange(s string) (lo, hi int, ok bool) {
+	a, b, found := strings.Cut(s, "..")
+	if !found {
+		return 0, 0, false
+	}
+	lo, err1 := strconv.Atoi(a)
+	hi, err2 := strconv.Atoi(b)
+	if err1 != nil || err2 != nil || lo > hi || hi-lo > 10000 {
+		return 0, 0, false
+	}
+	return lo, hi, true
+}
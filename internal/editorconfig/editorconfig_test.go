@@ -0,0 +1,98 @@
+package editorconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func write(t *testing.T, root, path, content string) string {
+	t.Helper()
+	full := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return full
+}
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, ".editorconfig", `root = true
+
+[*]
+indent_style = space
+indent_size = 2
+end_of_line = LF
+insert_final_newline = true
+
+# Go is always tabs
+[*.go]
+indent_style = tab
+indent_size = unset
+
+[{Makefile,*.mk}]
+indent_style = tab
+
+[docs/**.md]
+trim_trailing_whitespace = false
+`)
+	write(t, root, "web/.editorconfig", "[*.ts]\nindent_size = 4\n")
+	// Above the root, so never read
+	write(t, filepath.Dir(root), ".editorconfig", "[*]\ncharset = latin1\n")
+
+	for _, tt := range []struct {
+		file string
+		want Properties
+	}{
+		{"main.go", Properties{"indent_style": "tab", "end_of_line": "lf", "insert_final_newline": "true"}},
+		{"web/app.ts", Properties{"indent_style": "space", "indent_size": "4", "end_of_line": "lf", "insert_final_newline": "true"}},
+		{"build/rules.mk", Properties{"indent_style": "tab", "indent_size": "2", "end_of_line": "lf", "insert_final_newline": "true"}},
+		{"docs/guide/intro.md", Properties{"indent_style": "space", "indent_size": "2", "end_of_line": "lf", "insert_final_newline": "true", "trim_trailing_whitespace": "false"}},
+	} {
+		got, err := Resolve(filepath.Join(root, tt.file))
+		if err != nil {
+			t.Fatalf("Resolve(%s) failed: %v", tt.file, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Resolve(%s) = %v, want %v", tt.file, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("Resolve(%s)[%s] = %q, want %q", tt.file, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	for _, tt := range []struct {
+		glob, path string
+		want       bool
+	}{
+		{"*.go", "a/b/c.go", true},
+		{"/*.go", "a/c.go", false},
+		{"src/*.js", "src/a.js", true},
+		{"src/*.js", "src/lib/a.js", false},
+		{"src/**/*.js", "src/a.js", true},
+		{"src/**/*.js", "src/lib/a.js", true},
+		{"file?.txt", "file1.txt", true},
+		{"[!a]*.txt", "b.txt", true},
+		{"[!a]*.txt", "a.txt", false},
+		{"*.{js,ts}", "x.ts", true},
+		{"v{1..3}.txt", "v2.txt", true},
+		{"v{1..3}.txt", "v4.txt", false},
+		{"{single}.txt", "{single}.txt", true},
+	} {
+		re, err := compileGlob(tt.glob)
+		if err != nil {
+			t.Fatalf("compileGlob(%q) failed: %v", tt.glob, err)
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.glob, tt.path, got, tt.want)
+		}
+	}
+}
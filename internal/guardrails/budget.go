@@ -0,0 +1,175 @@
+package guardrails
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// budgetFile holds a session's counters in its session state directory
+const budgetFile = "budget.json"
+
+// errCorrupt is a budget file that no longer parses
+var errCorrupt = errors.New("corrupt")
+
+// lockTimeout bounds the wait for hooks of the same session running in parallel
+const lockTimeout = 5 * time.Second
+
+// Counters are what one session has done so far
+type Counters struct {
+	Files    []string  `json:"files"` // Unique files modified
+	Commands int       `json:"commands"`
+	APICalls int       `json:"api_calls"`
+	SpendUSD float64   `json:"spend_usd"`
+	Updated  time.Time `json:"updated"`
+	// Blocks counts post-edit blocks per rule and file, keyed "<rule> <file>"
+	Blocks map[string]int `json:"blocks,omitempty"`
+}
+
+// Enabled reports whether any session ceiling is configured
+func Enabled(cfg config.SessionBudgetConfig) bool {
+	return cfg.MaxFilesModified > 0 || cfg.MaxCommands > 0 || cfg.MaxAPICalls > 0 || cfg.MaxSpendUSD > 0
+}
+
+// Exceeded lists the ceilings the counters are over, empty when within budget
+func Exceeded(cfg config.SessionBudgetConfig, c Counters) []string {
+	var over []string
+	if cfg.MaxFilesModified > 0 && len(c.Files) > cfg.MaxFilesModified {
+		over = append(over, fmt.Sprintf("%d files modified (limit %d)", len(c.Files), cfg.MaxFilesModified))
+	}
+	if cfg.MaxCommands > 0 && c.Commands > cfg.MaxCommands {
+		over = append(over, fmt.Sprintf("%d commands run (limit %d)", c.Commands, cfg.MaxCommands))
+	}
+	if cfg.MaxAPICalls > 0 && c.APICalls > cfg.MaxAPICalls {
+		over = append(over, fmt.Sprintf("%d reviewer API calls (limit %d)", c.APICalls, cfg.MaxAPICalls))
+	}
+	if cfg.MaxSpendUSD > 0 && c.SpendUSD > cfg.MaxSpendUSD {
+		over = append(over, fmt.Sprintf("$%.2f estimated API spend (limit $%.2f)", c.SpendUSD, cfg.MaxSpendUSD))
+	}
+	return over
+}
+
+// Session identifies the session whose counters are read or updated
+type Session struct {
+	Dir            string // Directory inside the project
+	ID             string
+	TranscriptPath string
+}
+
+// Update applies change to the session's counters under a lock, so hooks of the
+// same session running in parallel don't lose counts, and returns the result
+func Update(s Session, change func(*Counters)) (Counters, error) {
+	path, err := state.SessionPath(s.Dir, s.ID, s.TranscriptPath, budgetFile)
+	if err != nil {
+		return Counters{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+	unlock, err := state.Lock(ctx, path+".lock")
+	if err != nil {
+		return Counters{}, err
+	}
+	defer unlock()
+
+	counters, err := read(path)
+	if errors.Is(err, errCorrupt) {
+		// Say it was cut short by a crash: counting starts over rather than
+		// every hook of the session failing on it
+		err = state.Quarantine(path)
+	}
+	if err != nil {
+		return Counters{}, err
+	}
+	change(&counters)
+	counters.Updated = time.Now().UTC()
+
+	data, err := json.MarshalIndent(counters, "", "  ")
+	if err != nil {
+		return Counters{}, fmt.Errorf("encoding session budget: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return Counters{}, fmt.Errorf("writing session budget: %w", err)
+	}
+	return counters, nil
+}
+
+// AddFiles records files as modified, counting each file once
+func (c *Counters) AddFiles(files ...string) {
+	for _, f := range files {
+		if !slices.Contains(c.Files, f) {
+			c.Files = append(c.Files, f)
+		}
+	}
+}
+
+// List returns the counters of every session of the project containing dir,
+// keyed by session id
+func List(dir string) (map[string]Counters, error) {
+	sessionsDir, err := state.ProjectPath(dir, state.Sessions, "")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(sessionsDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing sessions: %w", err)
+	}
+
+	sessions := make(map[string]Counters)
+	for _, entry := range entries {
+		path := filepath.Join(sessionsDir, entry.Name(), budgetFile)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		counters, err := read(path)
+		if errors.Is(err, errCorrupt) {
+			continue // Started over at the session's next hook
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions[entry.Name()] = counters
+	}
+	return sessions, nil
+}
+
+// Reset clears the counters of one session of the project containing dir
+func Reset(dir, sessionID string) error {
+	if sessionID == "" || sessionID != filepath.Base(sessionID) {
+		return fmt.Errorf("invalid session id %q", sessionID)
+	}
+	path, err := state.ProjectPath(dir, state.Sessions, filepath.Join(sessionID, budgetFile))
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("resetting session budget: %w", err)
+	}
+	return nil
+}
+
+func read(path string) (Counters, error) {
+	var counters Counters
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return counters, nil
+	}
+	if err != nil {
+		return counters, fmt.Errorf("reading session budget: %w", err)
+	}
+	if err := json.Unmarshal(data, &counters); err != nil {
+		return Counters{}, fmt.Errorf("%w session budget %s: %w", errCorrupt, path, err)
+	}
+	return counters, nil
+}
@@ -0,0 +1,100 @@
+package guardrails
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+func TestUpdateCountsAcrossParallelHooks(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	project := t.TempDir()
+	if err := os.Mkdir(filepath.Join(project, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	session := Session{Dir: project, ID: "session-1"}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if _, err := Update(session, func(c *Counters) { c.Commands++ }); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	counters, err := Update(session, func(c *Counters) { c.AddFiles("a.go", "b.go", "a.go") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counters.Commands != 10 || len(counters.Files) != 2 {
+		t.Errorf("Expected 10 commands and 2 unique files, got %d and %v", counters.Commands, counters.Files)
+	}
+
+	sessions, err := List(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sessions["session-1"]; !ok || len(sessions) != 1 {
+		t.Errorf("Expected session-1 to be listed, got %v", sessions)
+	}
+
+	if err := Reset(project, "session-1"); err != nil {
+		t.Fatal(err)
+	}
+	if counters, _ := Update(session, func(*Counters) {}); counters.Commands != 0 {
+		t.Errorf("Expected counters cleared by Reset, got %d commands", counters.Commands)
+	}
+	if err := Reset(project, "../escape"); err == nil {
+		t.Error("Expected an invalid session id to be rejected")
+	}
+}
+
+func TestUpdateRecoversCorruptBudget(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	project := t.TempDir()
+	session := Session{Dir: project, ID: "session-1"}
+	path, err := state.SessionPath(project, session.ID, "", budgetFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"files": ["a.go"], "comm`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if sessions, err := List(project); err != nil || len(sessions) != 0 {
+		t.Errorf("Expected a corrupt budget to be left out of the list, got %v, %v", sessions, err)
+	}
+	counters, err := Update(session, func(c *Counters) { c.Commands++ })
+	if err != nil {
+		t.Fatalf("Expected a corrupt budget to start over, got %v", err)
+	}
+	if counters.Commands != 1 || len(counters.Files) != 0 {
+		t.Errorf("Expected fresh counters, got %+v", counters)
+	}
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Errorf("Expected the corrupt budget kept aside: %v", err)
+	}
+}
+
+func TestExceeded(t *testing.T) {
+	budget := config.SessionBudgetConfig{MaxFilesModified: 2, MaxCommands: 5, MaxSpendUSD: 1}
+	if !Enabled(budget) || Enabled(config.SessionBudgetConfig{CostPerReviewUSD: 0.1}) {
+		t.Error("Expected only ceilings to enable the budget")
+	}
+
+	within := Counters{Files: []string{"a", "b"}, Commands: 5, APICalls: 100, SpendUSD: 1}
+	if over := Exceeded(budget, within); len(over) != 0 {
+		t.Errorf("Expected counters at the limits to be within budget, got %v", over)
+	}
+
+	over := Exceeded(budget, Counters{Files: []string{"a", "b", "c"}, Commands: 6, SpendUSD: 1.5})
+	if len(over) != 3 {
+		t.Errorf("Expected files, commands, and spend over budget, got %v", over)
+	}
+}
@@ -0,0 +1,93 @@
+package guardrails
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+)
+
+// ChangelogMissing returns the files of the repository at root, relative to it,
+// that need a changelog entry when neither the changelog nor a fragment was
+// updated. files are what the session modified; uncommitted changes to the
+// changelog count too, as Claude may have written it with a shell command. An
+// empty result means the rule is satisfied.
+func ChangelogMissing(cfg config.ChangelogConfig, root string, files []string) []string {
+	var relevant []string
+	for _, f := range files {
+		rel := relPath(root, f)
+		if rel == "" {
+			continue
+		}
+		if isChangelogEntry(cfg, rel) {
+			return nil
+		}
+		if len(cfg.Paths) == 0 || pathMatches(rel, cfg.Paths) {
+			relevant = append(relevant, rel)
+		}
+	}
+	if len(relevant) == 0 {
+		return nil
+	}
+
+	output, err := proc.Command("git", "-C", root, "status", "--porcelain", "-z", "--untracked-files=all").Output()
+	if err == nil {
+		for entry := range strings.SplitSeq(string(output), "\x00") {
+			if len(entry) > 3 && isChangelogEntry(cfg, entry[3:]) {
+				return nil
+			}
+		}
+	}
+	slices.Sort(relevant)
+	return slices.Compact(relevant)
+}
+
+// ChangelogEntry fills in the configured template for the files
+func ChangelogEntry(cfg config.ChangelogConfig, files []string, now time.Time) string {
+	quoted := make([]string, len(files))
+	for i, f := range files {
+		quoted[i] = "`" + f + "`"
+	}
+	return strings.NewReplacer("{files}", strings.Join(quoted, ", "), "{date}", now.Format(time.DateOnly)).Replace(cfg.Template)
+}
+
+// isChangelogEntry reports whether the slash-separated relative path is the
+// changelog or a fragment
+func isChangelogEntry(cfg config.ChangelogConfig, rel string) bool {
+	if cfg.File != "" && rel == path.Clean(filepath.ToSlash(cfg.File)) {
+		return true
+	}
+	dir := strings.Trim(path.Clean(filepath.ToSlash(cfg.FragmentsDir)), "/")
+	return cfg.FragmentsDir != "" && strings.HasPrefix(rel, dir+"/")
+}
+
+// relPath returns file relative to root with slashes, or "" when it is outside root
+func relPath(root, file string) string {
+	rel, err := filepath.Rel(root, file)
+	if err != nil || !filepath.IsLocal(rel) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// pathMatches reports whether a pattern matches the path, its base name, or
+// one of its parent directories
+func pathMatches(rel string, patterns []string) bool {
+	candidates := []string{rel, path.Base(rel)}
+	for dir := path.Dir(rel); dir != "."; dir = path.Dir(dir) {
+		candidates = append(candidates, dir)
+	}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(pattern, "/")
+		for _, c := range candidates {
+			if ok, _ := path.Match(pattern, c); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package guardrails
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func TestChangelogMissing(t *testing.T) {
+	root := t.TempDir()
+	if output, err := exec.Command("git", "init", "-q", root).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, output)
+	}
+	abs := func(names ...string) []string {
+		var files []string
+		for _, n := range names {
+			files = append(files, filepath.Join(root, n))
+		}
+		return files
+	}
+	cfg := config.ChangelogConfig{Enabled: true, Paths: []string{"src", "*.go"}, File: "CHANGELOG.md", FragmentsDir: ".changeset/"}
+
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{"covered changes", abs("src/lib/a.ts", "main.go", "docs/guide.md"), []string{"main.go", "src/lib/a.ts"}},
+		{"nothing covered", abs("docs/guide.md", "README.md"), nil},
+		{"changelog edited", abs("src/a.ts", "CHANGELOG.md"), nil},
+		{"fragment added", abs("src/a.ts", ".changeset/quick-fox.md"), nil},
+		{"outside the repository", []string{"/elsewhere/src/a.ts"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChangelogMissing(cfg, root, tt.files); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// A changelog written by a shell command only shows up in git status
+	if err := os.WriteFile(filepath.Join(root, "CHANGELOG.md"), []byte("- fixed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := ChangelogMissing(cfg, root, abs("src/a.ts")); len(got) != 0 {
+		t.Errorf("Expected the uncommitted changelog to count, got %v", got)
+	}
+}
+
+func TestChangelogEntry(t *testing.T) {
+	cfg := config.Default().Guardrails.Changelog
+	cfg.Template = "## {date}\n- Describe the change to {files}\n"
+	got := ChangelogEntry(cfg, []string{"main.go", "src/a.ts"}, time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC))
+	if want := "## 2026-03-14\n- Describe the change to `main.go`, `src/a.ts`\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got := ChangelogEntry(config.Default().Guardrails.Changelog, []string{"a.go"}, time.Now()); !strings.Contains(got, "[Unreleased]") {
+		t.Errorf("Expected the default template to add an Unreleased entry, got %q", got)
+	}
+}
@@ -0,0 +1,64 @@
+package guardrails
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+// Loop is a file the hooks keep blocking for the same rule
+type Loop struct {
+	Rule   string
+	File   string
+	Blocks int  // Blocks of the file for the rule this session, this one included
+	New    bool // This block is the first over the limit
+}
+
+// RecordBlocks counts one block of each file for each rule in the session and
+// returns the pairs over loops.max_blocks, ordered by rule and file. It is a
+// no-op when loop detection is disabled.
+func RecordBlocks(cfg config.LoopsConfig, s Session, rules, files []string) ([]Loop, error) {
+	if cfg.MaxBlocks <= 0 || s.ID == "" || len(rules) == 0 || len(files) == 0 {
+		return nil, nil
+	}
+	var loops []Loop
+	_, err := Update(s, func(c *Counters) {
+		if c.Blocks == nil {
+			c.Blocks = make(map[string]int)
+		}
+		for _, rule := range rules {
+			for _, file := range files {
+				key := rule + " " + file
+				c.Blocks[key]++
+				if n := c.Blocks[key]; n > cfg.MaxBlocks {
+					loops = append(loops, Loop{Rule: rule, File: file, Blocks: n, New: n == cfg.MaxBlocks+1})
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(loops, func(a, b Loop) int {
+		return strings.Compare(a.Rule+" "+a.File, b.Rule+" "+b.File)
+	})
+	return slices.CompactFunc(loops, func(a, b Loop) bool { return a.Rule == b.Rule && a.File == b.File }), nil
+}
+
+// LoopGuidance is the escalation added to the reason of a block that is part
+// of a loop: Claude is told to stop repeating the same kind of fix
+func LoopGuidance(cfg config.LoopsConfig, loops []Loop) string {
+	var b strings.Builder
+	b.WriteString("🔁 LOOP DETECTED: the same check keeps failing on the same file this session:\n")
+	for _, l := range loops {
+		fmt.Fprintf(&b, "- %s: blocked by %s %d times (limit %d)\n", l.File, l.Rule, l.Blocks, cfg.MaxBlocks)
+	}
+	b.WriteString("\nThe last attempts didn't work, so another small variation of them won't either. Before editing again:\n")
+	b.WriteString("1. Re-read the full failure (`claude-hook explain <rule>`) instead of the summary.\n")
+	b.WriteString("2. Question the assumption behind the last fixes; the cause may be in a different file than the one being edited.\n")
+	b.WriteString("3. Consider reverting the file to its last passing version and starting over with a different approach.\n")
+	b.WriteString("4. If it still fails, stop and ask the user instead of trying again.")
+	return b.String()
+}
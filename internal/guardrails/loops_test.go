@@ -0,0 +1,48 @@
+package guardrails
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func TestRecordBlocksDetectsLoops(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	cfg := config.LoopsConfig{MaxBlocks: 2}
+	session := Session{Dir: t.TempDir(), ID: "session-1"}
+
+	for i := range 2 {
+		loops, err := RecordBlocks(cfg, session, []string{"go-tests"}, []string{"/repo/a.go"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(loops) != 0 {
+			t.Fatalf("Expected no loop after %d blocks, got %+v", i+1, loops)
+		}
+	}
+
+	// Another rule on the same file is counted on its own
+	loops, err := RecordBlocks(cfg, session, []string{"go-tests", "complexity"}, []string{"/repo/a.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loops) != 1 || loops[0] != (Loop{Rule: "go-tests", File: "/repo/a.go", Blocks: 3, New: true}) {
+		t.Fatalf("Expected a new go-tests loop on a.go, got %+v", loops)
+	}
+
+	loops, _ = RecordBlocks(cfg, session, []string{"go-tests"}, []string{"/repo/a.go"})
+	if len(loops) != 1 || loops[0].New || loops[0].Blocks != 4 {
+		t.Fatalf("Expected the loop to continue without being new, got %+v", loops)
+	}
+
+	guidance := LoopGuidance(cfg, loops)
+	if !strings.Contains(guidance, "/repo/a.go: blocked by go-tests 4 times (limit 2)") || !strings.Contains(guidance, "ask the user") {
+		t.Errorf("Unexpected guidance:\n%s", guidance)
+	}
+
+	// Disabled detection records nothing
+	if loops, err := RecordBlocks(config.LoopsConfig{}, session, []string{"go-tests"}, []string{"/repo/a.go"}); err != nil || loops != nil {
+		t.Errorf("Expected no loops with max_blocks 0, got %+v: %v", loops, err)
+	}
+}
@@ -0,0 +1,121 @@
+package guardrails
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+// DefaultDeployCommands match commands that change running infrastructure
+var DefaultDeployCommands = []string{
+	`^(kubectl|oc)\s+(.*\s)?(apply|create|delete|replace|patch|rollout|scale|set)\b`,
+	`^helm\s+(.*\s)?(install|upgrade|uninstall|rollback)\b`,
+	`^(terraform|tofu|terragrunt)\s+(.*\s)?(apply|destroy)\b`,
+	`^pulumi\s+(up|destroy)\b`,
+	`^(fly|flyctl)\s+deploy\b`,
+	`^(vercel|netlify)\b.*--prod\b`,
+	`^(serverless|sls|cdk)\s+deploy\b`,
+	`^gcloud\s+.*\bdeploy\b`,
+	`^aws\s+(cloudformation\s+deploy|ecs\s+update-service|lambda\s+update-function-code)\b`,
+	`^git\s+push\s+heroku\b`,
+	`^(make|just|npm\s+run|yarn|pnpm)\s+(deploy|release)\b`,
+	`(^|/)deploy(\.sh)?(\s|$)`,
+}
+
+// DeployPattern returns the pattern the command matches when it looks like a
+// deploy, or "" when it doesn't
+func DeployPattern(cfg config.DeployWindowConfig, command string) (string, error) {
+	patterns := cfg.Commands
+	if len(patterns) == 0 {
+		patterns = DefaultDeployCommands
+	}
+	command = strings.TrimSpace(command)
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid deploy command pattern %q: %w", pattern, err)
+		}
+		if re.MatchString(command) {
+			return pattern, nil
+		}
+	}
+	return "", nil
+}
+
+// weekdays maps config day names to time.Weekday
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// InWindow reports whether now falls inside the deploy window. A window without
+// hours is always open.
+func InWindow(cfg config.DeployWindowConfig, now time.Time) (bool, error) {
+	if cfg.Hours == "" {
+		return true, nil
+	}
+
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return false, fmt.Errorf("invalid deploy window timezone: %w", err)
+		}
+		now = now.In(loc)
+	}
+
+	start, end, err := parseHours(cfg.Hours)
+	if err != nil {
+		return false, err
+	}
+	var allowed []time.Weekday
+	for _, name := range cfg.Days {
+		lower := strings.ToLower(name)
+		d, ok := weekdays[lower[:min(3, len(lower))]]
+		if !ok {
+			return false, fmt.Errorf("invalid deploy window day %q", name)
+		}
+		allowed = append(allowed, d)
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+
+	day := now.Weekday()
+	inHours := minute >= start && minute < end
+	if end <= start { // Wraps past midnight, e.g. 22:00-06:00
+		inHours = minute >= start || minute < end
+		if minute < end {
+			day = (day + 6) % 7 // The window opened the day before
+		}
+	}
+	if !inHours {
+		return false, nil
+	}
+
+	return len(allowed) == 0 || slices.Contains(allowed, day), nil
+}
+
+// parseHours parses "HH:MM-HH:MM" into minutes since midnight
+func parseHours(hours string) (start, end int, err error) {
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid deploy window hours %q (want HH:MM-HH:MM)", hours)
+	}
+	parse := func(s string) (int, error) {
+		t, err := time.Parse("15:04", strings.TrimSpace(s))
+		if err != nil {
+			return 0, fmt.Errorf("invalid deploy window hours %q (want HH:MM-HH:MM)", hours)
+		}
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if start, err = parse(from); err != nil {
+		return 0, 0, err
+	}
+	if end, err = parse(to); err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
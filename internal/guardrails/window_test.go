@@ -0,0 +1,85 @@
+package guardrails
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func TestInWindow(t *testing.T) {
+	workdays := config.DeployWindowConfig{Hours: "09:00-17:00", Days: []string{"mon", "tue", "wed", "thu", "friday"}, Timezone: "UTC"}
+	overnight := config.DeployWindowConfig{Hours: "22:00-06:00", Days: []string{"mon"}, Timezone: "UTC"}
+
+	tests := []struct {
+		name   string
+		window config.DeployWindowConfig
+		at     string
+		want   bool
+	}{
+		{"open", workdays, "2026-10-14T10:30:00Z", true}, // Wednesday
+		{"before hours", workdays, "2026-10-14T08:59:00Z", false},
+		{"end is exclusive", workdays, "2026-10-14T17:00:00Z", false},
+		{"friday", workdays, "2026-10-16T16:00:00Z", true},
+		{"weekend", workdays, "2026-10-17T10:30:00Z", false},
+		{"timezone", config.DeployWindowConfig{Hours: "09:00-17:00", Timezone: "America/New_York"}, "2026-10-14T14:00:00Z", true},
+		{"overnight start", overnight, "2026-10-12T23:00:00Z", true}, // Monday night
+		{"overnight after midnight", overnight, "2026-10-13T05:00:00Z", true},
+		{"overnight wrong day", overnight, "2026-10-14T05:00:00Z", false},
+		{"no hours", config.DeployWindowConfig{}, "2026-10-17T03:00:00Z", true},
+	}
+	for _, tt := range tests {
+		at, err := time.Parse(time.RFC3339, tt.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := InWindow(tt.window, at)
+		if err != nil {
+			t.Fatalf("%s: InWindow failed: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %v at %s, got %v", tt.name, tt.want, tt.at, got)
+		}
+	}
+
+	for _, bad := range []config.DeployWindowConfig{{Hours: "9-5"}, {Hours: "09:00-17:00", Days: []string{"someday"}}, {Hours: "09:00-17:00", Timezone: "Nowhere/City"}} {
+		if _, err := InWindow(bad, time.Now()); err == nil {
+			t.Errorf("Expected an error for %+v", bad)
+		}
+	}
+}
+
+func TestDeployPattern(t *testing.T) {
+	deploys := []string{
+		"kubectl apply -f k8s/",
+		"kubectl -n prod rollout restart deploy/web",
+		"helm upgrade --install web ./chart",
+		"terraform apply -auto-approve",
+		"flyctl deploy",
+		"vercel deploy --prod",
+		"gcloud run deploy web --image x",
+		"npm run deploy",
+		"./scripts/deploy.sh staging",
+		"git push heroku main",
+	}
+	for _, command := range deploys {
+		if pattern, err := DeployPattern(config.DeployWindowConfig{}, command); err != nil || pattern == "" {
+			t.Errorf("Expected %q to look like a deploy (err %v)", command, err)
+		}
+	}
+
+	safe := []string{"kubectl get pods", "terraform plan", "helm template ./chart", "vercel dev", "go test ./deploy/...", "git push origin feature"}
+	for _, command := range safe {
+		if pattern, _ := DeployPattern(config.DeployWindowConfig{}, command); pattern != "" {
+			t.Errorf("Expected %q not to look like a deploy, matched %s", command, pattern)
+		}
+	}
+
+	custom := config.DeployWindowConfig{Commands: []string{`^bin/ship\b`}}
+	if pattern, _ := DeployPattern(custom, "kubectl apply -f x"); pattern != "" {
+		t.Errorf("Expected custom commands to replace the defaults, matched %s", pattern)
+	}
+	if pattern, _ := DeployPattern(custom, "bin/ship prod"); pattern == "" {
+		t.Error("Expected the custom deploy command to match")
+	}
+}
@@ -0,0 +1,181 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// fileName is the heartbeat in the state directory, shared by all projects
+// since they run the same installation
+const fileName = "heartbeat.json"
+
+// StaleAfter is how long sessions may keep starting without a hook that fired
+// before firing again until it counts as broken
+const StaleAfter = 7 * 24 * time.Hour
+
+// Watched are the hooks nearly every session fires, on every edit or command,
+// so a long silence means they stopped working rather than weren't needed
+var Watched = []string{"post-edit", "pre-bash", "pre-edit"}
+
+// Beat is one hook's last run that got as far as reading its input
+type Beat struct {
+	Time    time.Time `json:"time"`
+	Version string    `json:"version"`
+	Binary  string    `json:"binary"` // The executable that ran it
+}
+
+// Heartbeat is the last beat of each hook type
+type Heartbeat struct {
+	Hooks map[string]Beat `json:"hooks"`
+}
+
+// Path returns the location of the heartbeat
+func Path() (string, error) {
+	return state.Path(fileName)
+}
+
+// Load returns the heartbeat; a missing file means no hook fired yet
+func Load() (Heartbeat, error) {
+	h := Heartbeat{Hooks: map[string]Beat{}}
+	path, err := Path()
+	if err != nil {
+		return h, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, fmt.Errorf("reading heartbeat: %w", err)
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return Heartbeat{Hooks: map[string]Beat{}}, fmt.Errorf("parsing heartbeat: %w", err)
+	}
+	if h.Hooks == nil {
+		h.Hooks = map[string]Beat{}
+	}
+	return h, nil
+}
+
+// Record notes that hook ran now, returning the heartbeat from before. Hooks
+// running at the same time can drop each other's beat, which only delays it
+// to the next run.
+func Record(hook, version string) (Heartbeat, error) {
+	h, err := Load()
+	if err != nil {
+		h = Heartbeat{Hooks: map[string]Beat{}} // Start over from a corrupt file
+	}
+	previous := Heartbeat{Hooks: maps.Clone(h.Hooks)}
+
+	binary, _ := os.Executable()
+	h.Hooks[hook] = Beat{Time: time.Now(), Version: version, Binary: binary}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return previous, fmt.Errorf("marshaling heartbeat: %w", err)
+	}
+	path, err := Path()
+	if err != nil {
+		return previous, err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return previous, fmt.Errorf("writing heartbeat: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return previous, fmt.Errorf("replacing heartbeat: %w", err)
+	}
+	return previous, nil
+}
+
+// Stale returns the watched hooks that fired before but not in the StaleAfter
+// before the last session started, sorted
+func (h Heartbeat) Stale() []string {
+	start, ok := h.Hooks["session-start"]
+	if !ok {
+		return nil
+	}
+	var stale []string
+	for _, hook := range Watched {
+		if beat, ok := h.Hooks[hook]; ok && start.Time.Sub(beat.Time) > StaleAfter {
+			stale = append(stale, hook)
+		}
+	}
+	slices.Sort(stale)
+	return stale
+}
+
+// NewerThan returns those of paths modified after the binary, for a directory
+// whichever of its Go files changed last, so a binary built before them can be
+// told to be rebuilt. Paths that don't exist are left out.
+func NewerThan(binary string, paths []string) ([]string, error) {
+	info, err := os.Stat(binary)
+	if err != nil {
+		return nil, fmt.Errorf("checking the hook binary: %w", err)
+	}
+	var newer []string
+	for _, path := range paths {
+		if modTime(path).After(info.ModTime()) {
+			newer = append(newer, path)
+		}
+	}
+	return newer, nil
+}
+
+// modTime returns when path last changed, or the zero time
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	if !info.IsDir() {
+		return info.ModTime()
+	}
+	var latest time.Time
+	_ = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".go") {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+		return nil
+	})
+	return latest
+}
+
+// Checkout returns the claude-hooks checkout containing dir, or ""
+func Checkout(dir string) string {
+	root := state.ProjectRoot(dir)
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for line := range strings.Lines(string(data)) {
+		if strings.TrimSpace(line) == "module github.com/brianleishman/claude-hooks" {
+			return root
+		}
+	}
+	return ""
+}
+
+// Sources are what a binary built from the checkout at root is compiled from
+func Sources(root string) []string {
+	return []string{filepath.Join(root, "cmd"), filepath.Join(root, "internal")}
+}
+
+// FromSource tells whether binary was built by go run, and so is as new as
+// the checkout it was run from
+func FromSource(binary string) bool {
+	return strings.Contains(filepath.ToSlash(binary), "/go-build")
+}
@@ -0,0 +1,84 @@
+package health
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+
+	previous, err := Record("post-edit", "v1.2.3")
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if len(previous.Hooks) != 0 {
+		t.Errorf("Expected no earlier beats, got %v", previous.Hooks)
+	}
+	if previous, err = Record("session-start", "v1.2.3"); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if _, ok := previous.Hooks["post-edit"]; !ok {
+		t.Errorf("Expected the earlier post-edit beat, got %v", previous.Hooks)
+	}
+
+	h, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if beat := h.Hooks["post-edit"]; beat.Version != "v1.2.3" || beat.Binary == "" || time.Since(beat.Time) > time.Minute {
+		t.Errorf("Expected a fresh post-edit beat, got %+v", beat)
+	}
+}
+
+func TestStale(t *testing.T) {
+	now := time.Now()
+	h := Heartbeat{Hooks: map[string]Beat{
+		"session-start": {Time: now},
+		"post-edit":     {Time: now.Add(-8 * 24 * time.Hour)},
+		"pre-bash":      {Time: now.Add(-time.Hour)},
+		"stop":          {Time: now.Add(-30 * 24 * time.Hour)}, // Opt-in, so it's not watched
+	}}
+	if stale := h.Stale(); !slices.Equal(stale, []string{"post-edit"}) {
+		t.Errorf("Expected only post-edit to be stale, got %v", stale)
+	}
+
+	delete(h.Hooks, "session-start")
+	if stale := h.Stale(); len(stale) != 0 {
+		t.Errorf("Expected nothing stale before a session started, got %v", stale)
+	}
+}
+
+func TestNewerThan(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, mod time.Time) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	built := time.Now().Add(-time.Hour)
+	binary := write("bin/claude-hook", built)
+	config := write(".claude-hooks.yaml", built.Add(time.Minute))
+	write("internal/hooks/go_hook.go", built.Add(-time.Minute))
+	write("cmd/claude-hook/main.go", built.Add(2*time.Minute))
+	write("internal/README.md", built.Add(time.Hour)) // Not a source
+
+	newer, err := NewerThan(binary, []string{config, filepath.Join(dir, "internal"), filepath.Join(dir, "cmd"), filepath.Join(dir, "missing")})
+	if err != nil {
+		t.Fatalf("NewerThan failed: %v", err)
+	}
+	if want := []string{config, filepath.Join(dir, "cmd")}; !slices.Equal(newer, want) {
+		t.Errorf("Expected %v, got %v", want, newer)
+	}
+}
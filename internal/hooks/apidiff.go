@@ -0,0 +1,270 @@
+package hooks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// APIChange is an incompatible change to the exported API of a package
+type APIChange struct {
+	Package string // Import path
+	Change  string // apidiff's description, e.g. "Load: changed from func() error to func(string) error"
+}
+
+func (c APIChange) String() string {
+	return c.Package + ": " + c.Change
+}
+
+// FindAPIBreaks compares the exported API of the edited Go packages with
+// go.api.base using apidiff and returns the incompatible changes. Commands,
+// internal packages, and packages that don't exist at the base are left out,
+// as nothing outside the module can import them. It is a no-op unless
+// go.api.enabled is set, and is skipped when apidiff isn't installed.
+func FindAPIBreaks(files []string, verbose bool) ([]APIChange, error) {
+	var sources []string
+	for _, f := range files {
+		if strings.HasSuffix(f, ".go") && !strings.HasSuffix(f, "_test.go") {
+			sources = append(sources, f)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+	cfg, err := config.Load(filepath.Dir(sources[0]))
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Go.API.Enabled {
+		return nil, nil
+	}
+	if checkoutCoverage(filepath.Dir(sources[0])).Reduced() {
+		vlog.Printf(verbose, "⏭️  Skipping API compatibility check: checking out the base would bring in the whole tree of a sparse or partial clone\n")
+		return nil, nil
+	}
+	if _, err := exec.LookPath("apidiff"); err != nil {
+		vlog.Printf(verbose, "⏭️  Skipping API compatibility check: apidiff not installed\n")
+		return nil, nil
+	}
+
+	// Group the public packages by repository so each needs one checkout of the base
+	type pkg struct {
+		dir, moduleRoot, importPath string
+	}
+	byRepo := make(map[string][]pkg)
+	var repos []string
+	for _, dir := range packageDirs(sources) {
+		if !hasGoFiles(dir) || goPackageName(dir) == "main" {
+			continue
+		}
+		moduleRoot, err := findModuleRoot(dir)
+		if err != nil {
+			return nil, err
+		}
+		importPath, err := importPathForDir(moduleRoot, dir)
+		if err != nil || slices.Contains(strings.Split(importPath, "/"), "internal") {
+			continue
+		}
+		repo := state.ProjectRoot(dir)
+		if _, ok := byRepo[repo]; !ok {
+			repos = append(repos, repo)
+		}
+		byRepo[repo] = append(byRepo[repo], pkg{dir, moduleRoot, importPath})
+	}
+
+	var changes []APIChange
+	for _, repo := range repos {
+		base, err := resolveBase(repo, cfg.Go.API.Base)
+		if err != nil {
+			vlog.Printf(verbose, "⏭️  Skipping API compatibility check in %s: %v\n", repo, err)
+			continue
+		}
+
+		var worktree string
+		defer func() {
+			if worktree != "" {
+				_ = proc.Command("git", "-C", repo, "worktree", "remove", "--force", worktree).Run()
+			}
+		}()
+		// The base is only checked out when an export file isn't cached yet
+		checkout := func() (string, error) {
+			if worktree != "" {
+				return worktree, nil
+			}
+			dir, err := os.MkdirTemp("", "claude-hooks-apidiff-")
+			if err != nil {
+				return "", fmt.Errorf("creating worktree directory: %w", err)
+			}
+			if output, err := proc.Command("git", "-C", repo, "worktree", "add", "--detach", "--quiet", dir, base).CombinedOutput(); err != nil {
+				os.RemoveAll(dir)
+				return "", fmt.Errorf("checking out %s: %w\n%s", cfg.Go.API.Base, err, strings.TrimSpace(string(output)))
+			}
+			worktree = dir
+			return worktree, nil
+		}
+
+		for _, p := range byRepo[repo] {
+			export, err := baseExport(repo, base, p.dir, p.moduleRoot, p.importPath, checkout, verbose)
+			if err != nil {
+				return nil, err
+			}
+			if export == "" {
+				continue
+			}
+
+			vlog.Printf(verbose, "🔧 apidiff -incompatible %s (in %s)\n", p.importPath, p.moduleRoot)
+			cmd := proc.Command("apidiff", "-incompatible", export, p.importPath)
+			cmd.Dir = p.moduleRoot
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				return nil, fmt.Errorf("running apidiff on %s: %w\n%s", p.importPath, err, strings.TrimSpace(string(output)))
+			}
+			for line := range strings.Lines(string(output)) {
+				if change, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok {
+					changes = append(changes, APIChange{Package: p.importPath, Change: change})
+				}
+			}
+		}
+	}
+	return changes, nil
+}
+
+// resolveBase returns the commit name refers to, trying origin/<name> when
+// there is no local branch of that name
+func resolveBase(repo, name string) (string, error) {
+	for _, ref := range []string{name, "origin/" + name} {
+		output, err := proc.Command("git", "-C", repo, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Output()
+		if err == nil {
+			return strings.TrimSpace(string(output)), nil
+		}
+	}
+	return "", fmt.Errorf("base %q not found", name)
+}
+
+// baseExport returns apidiff's export data for the package as it was at base,
+// writing it from a checkout of base the first time. It is cached per commit,
+// so only edits after the base moves pay for the checkout. An empty path means
+// the package didn't exist or didn't build at base.
+func baseExport(repo, base, dir, moduleRoot, importPath string, checkout func() (string, error), verbose bool) (string, error) {
+	sum := sha256.Sum256([]byte(base + " " + importPath))
+	export, err := state.ProjectPath(repo, state.Cache, "apidiff-"+hex.EncodeToString(sum[:8]))
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(export); err == nil {
+		recordCacheLookup(true)
+		return export, nil
+	}
+	if _, err := os.Stat(export + ".missing"); err == nil {
+		recordCacheLookup(true)
+		return "", nil
+	}
+	recordCacheLookup(false)
+
+	relModule, err := filepath.Rel(repo, moduleRoot)
+	if err != nil {
+		return "", fmt.Errorf("resolving module %s: %w", moduleRoot, err)
+	}
+	relDir, err := filepath.Rel(repo, dir)
+	if err != nil {
+		return "", fmt.Errorf("resolving package %s: %w", dir, err)
+	}
+	if relDir == "." {
+		relDir = "" // <commit>: is the root tree
+	}
+	if err := proc.Command("git", "-C", repo, "cat-file", "-e", base+":"+filepath.ToSlash(relDir)).Run(); err != nil {
+		return "", os.WriteFile(export+".missing", nil, 0o644) // New package: nothing to break
+	}
+
+	worktree, err := checkout()
+	if err != nil {
+		return "", err
+	}
+	vlog.Printf(verbose, "🔧 apidiff -w %s (at %s)\n", importPath, base[:min(len(base), 12)])
+	cmd := proc.Command("apidiff", "-w", export, importPath)
+	cmd.Dir = filepath.Join(worktree, relModule)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		vlog.Printf(verbose, "⏭️  %s doesn't load at the base, skipping: %s\n", importPath, strings.TrimSpace(string(output)))
+		os.Remove(export)
+		return "", os.WriteFile(export+".missing", nil, 0o644)
+	}
+	return export, nil
+}
+
+// goPackageName returns the package clause of the first Go file in dir
+func goPackageName(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(token.NewFileSet(), f, nil, parser.PackageClauseOnly)
+		if err == nil {
+			return parsed.Name.Name
+		}
+	}
+	return ""
+}
+
+// breakingChangePattern matches a Conventional Commits style declaration on a
+// line of its own, optionally as a list item or in bold
+var breakingChangePattern = regexp.MustCompile(`(?m)^[\s>*_#-]*BREAKING[ -]CHANGES?\b`)
+
+// PlanDeclaresBreakingChange reports whether the session's plan has a
+// "BREAKING CHANGE:" line. The plan is the last one Claude submitted with
+// ExitPlanMode, or else the most plan-like assistant message.
+func PlanDeclaresBreakingChange(transcriptPath string) bool {
+	if transcriptPath == "" {
+		return false
+	}
+	plan := lastSubmittedPlan(transcriptPath)
+	if plan == "" {
+		plan, _ = extractPlanFromTranscript(transcriptPath, false)
+	}
+	return breakingChangePattern.MatchString(plan)
+}
+
+// lastSubmittedPlan returns the plan of the last ExitPlanMode call in the transcript
+func lastSubmittedPlan(transcriptPath string) string {
+	data, err := os.ReadFile(transcriptPath)
+	if err != nil {
+		return ""
+	}
+	var plan string
+	for line := range strings.Lines(string(data)) {
+		var entry struct {
+			Message struct {
+				Content []struct {
+					Type  string `json:"type"`
+					Name  string `json:"name"`
+					Input struct {
+						Plan string `json:"plan"`
+					} `json:"input"`
+				} `json:"content"`
+			} `json:"message"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue // String content, or a malformed line
+		}
+		for _, c := range entry.Message.Content {
+			if c.Type == "tool_use" && c.Name == "ExitPlanMode" && c.Input.Plan != "" {
+				plan = c.Input.Plan
+			}
+		}
+	}
+	return plan
+}
@@ -0,0 +1,109 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFindAPIBreaks runs a stub apidiff and expects only the public package
+// that existed on main to be compared, with its base export data cached
+func TestFindAPIBreaks(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	repo := t.TempDir()
+	writeFile := func(path, content string) { writeTestFile(t, repo, path, content) }
+	run := func(args ...string) { runInDir(t, repo, args...) }
+
+	writeFile(".claude-hooks.yaml", "go:\n  api:\n    enabled: true\n")
+	writeFile("go.mod", "module example.com/lib\n\ngo 1.22\n")
+	writeFile("lib.go", "package lib\n\nfunc Load() {}\n")
+	writeFile("internal/util/util.go", "package util\n\nfunc Help() {}\n")
+	writeFile("cmd/tool/main.go", "package main\n\nfunc main() {}\n")
+	run("git", "init", "-q", "-b", "main")
+	run("git", "add", ".")
+	run("git", "commit", "-q", "-m", "init")
+	writeFile("lib.go", "package lib\n\nfunc Load(path string) {}\n")
+	writeFile("internal/util/util.go", "package util\n\nfunc Help(n int) {}\n")
+	writeFile("cmd/tool/main.go", "package main\n\nfunc main() { println() }\n")
+	writeFile("extra/extra.go", "package extra\n")
+
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	writeTestFile(t, bin, "apidiff", "#!/bin/sh\necho \"$1 $3 $(pwd)\" >> "+calls+"\n"+
+		"if [ \"$1\" = -w ]; then echo export > \"$2\"; exit 0; fi\n"+
+		"echo 'Incompatible changes:'\necho '- Load: changed from func() to func(string)'\n")
+	if err := os.Chmod(filepath.Join(bin, "apidiff"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	files := []string{
+		filepath.Join(repo, "lib.go"),
+		filepath.Join(repo, "internal/util/util.go"),
+		filepath.Join(repo, "cmd/tool/main.go"),
+		filepath.Join(repo, "extra/extra.go"),
+	}
+	for range 2 {
+		changes, err := FindAPIBreaks(files, false)
+		if err != nil {
+			t.Fatalf("FindAPIBreaks failed: %v", err)
+		}
+		want := APIChange{Package: "example.com/lib", Change: "Load: changed from func() to func(string)"}
+		if len(changes) != 1 || changes[0] != want {
+			t.Fatalf("Expected only %v, got %v", want, changes)
+		}
+	}
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "-w example.com/lib ") || strings.Contains(lines[0], repo+" ") {
+		t.Fatalf("Expected one export from a checkout of main, then two comparisons, got:\n%s", data)
+	}
+	for _, line := range lines[1:] {
+		if line != "-incompatible example.com/lib "+repo {
+			t.Errorf("Expected a comparison in the working tree, got %q", line)
+		}
+	}
+}
+
+func TestFindAPIBreaksDisabled(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "lib.go", "package lib\n")
+	t.Setenv("PATH", t.TempDir()) // Nothing to run: the check must not get that far
+
+	changes, err := FindAPIBreaks([]string{filepath.Join(repo, "lib.go")}, false)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("Expected nothing without go.api.enabled, got %v, %v", changes, err)
+	}
+}
+
+func TestPlanDeclaresBreakingChange(t *testing.T) {
+	planEntry := func(plan string) string {
+		return `{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","name":"ExitPlanMode","input":{"plan":` + strings.ReplaceAll(`"`+plan+`"`, "\n", `\n`) + `}}]}}` + "\n"
+	}
+	tests := []struct {
+		name       string
+		transcript string
+		want       bool
+	}{
+		{"declared", planEntry("## Plan\n1. Rename Load\n\nBREAKING CHANGE: Load takes a path"), true},
+		{"list item", planEntry("## Plan\n- **BREAKING CHANGE**: Load takes a path"), true},
+		{"denied", planEntry("## Plan\n1. Add LoadPath\n\nNo breaking changes."), false},
+		{"latest plan wins", planEntry("BREAKING CHANGE: drop Load") + planEntry("## Plan\n1. Keep Load"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "transcript.jsonl")
+			if err := os.WriteFile(path, []byte(tt.transcript), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := PlanDeclaresBreakingChange(path); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
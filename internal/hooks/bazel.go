@@ -0,0 +1,301 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// buildSystem describes how the build graph of one kind of workspace is queried
+type buildSystem struct {
+	name       string   // The hook's file type and the default command
+	markers    []string // Files at the workspace root
+	buildFiles []string // Names of the files declaring a package
+	keepGoing  string   // Flag building as much as possible past a failure
+	quiet      []string // Flags keeping progress out of the output
+	allTargets string   // Suffix of a package's label naming all its targets
+}
+
+var buildSystems = []buildSystem{
+	{
+		name:       "bazel",
+		markers:    []string{"MODULE.bazel", "WORKSPACE.bazel", "WORKSPACE"},
+		buildFiles: []string{"BUILD.bazel", "BUILD"},
+		keepGoing:  "--keep_going",
+		quiet:      []string{"--noshow_progress", "--color=no", "--curses=no"},
+		allTargets: ":all",
+	},
+	{
+		name:       "buck2",
+		markers:    []string{".buckconfig"},
+		buildFiles: []string{"BUCK", "BUCK.v2"},
+		keepGoing:  "--keep-going",
+		quiet:      []string{"--console=simplenotty"},
+		allTargets: ":",
+	},
+}
+
+// workspace is the Bazel or Buck2 workspace a file is in
+type workspace struct {
+	system buildSystem
+	root   string
+}
+
+// findWorkspace returns the workspace containing file, searching up to its project root
+func findWorkspace(file string) (workspace, bool) {
+	dir := filepath.Dir(file)
+	root := state.ProjectRoot(dir)
+	for _, system := range buildSystems {
+		for _, marker := range system.markers {
+			if ws := findUp(dir, root, marker); ws != "" {
+				return workspace{system: system, root: ws}, true
+			}
+		}
+	}
+	return workspace{}, false
+}
+
+// isBuildFile reports whether file declares a package or Starlark macros
+func (ws workspace) isBuildFile(file string) bool {
+	base := filepath.Base(file)
+	return slices.Contains(ws.system.buildFiles, base) || slices.Contains(ws.system.markers, base) || strings.HasSuffix(base, ".bzl")
+}
+
+// pkg returns the package directory of file, relative to the workspace root
+// with slashes, or false when no build file is above it
+func (ws workspace) pkg(file string) (string, bool) {
+	for dir := filepath.Dir(file); ; dir = filepath.Dir(dir) {
+		for _, name := range ws.system.buildFiles {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				rel, err := filepath.Rel(ws.root, dir)
+				if err != nil {
+					return "", false
+				}
+				if rel == "." {
+					rel = "" // The root package, //:name
+				}
+				return filepath.ToSlash(rel), true
+			}
+		}
+		if dir == ws.root || filepath.Dir(dir) == dir {
+			return "", false
+		}
+	}
+}
+
+// bazelRoute returns the build system's hook for file when bazel.enabled is
+// set and file belongs to a package of a workspace, or ""
+func bazelRoute(cfg config.BazelConfig, file string) string {
+	if !cfg.Enabled {
+		return ""
+	}
+	ws, ok := findWorkspace(file)
+	if !ok {
+		return ""
+	}
+	if _, inPackage := ws.pkg(file); !inPackage && !ws.isBuildFile(file) {
+		return ""
+	}
+	return ws.system.name
+}
+
+// BazelHook checks edits through the build graph of a Bazel or Buck2
+// workspace: the targets owning the edited files are built, and with
+// bazel.test their tests and their direct dependents' run, instead of go vet or
+// tsc. Edited BUILD and .bzl files are checked with buildifier when it's
+// installed, and their packages are built.
+type BazelHook struct{}
+
+func (h *BazelHook) PreEdit(files []string, verbose bool) error {
+	return nil
+}
+
+func (h *BazelHook) PostEdit(files []string, verbose bool) error {
+	return h.PostEditJSON(files, verbose)
+}
+
+func (h *BazelHook) PostEditJSON(files []string, verbose bool) error {
+	cfg, err := config.Load(filepath.Dir(files[0]))
+	if err != nil {
+		return err
+	}
+	ws, ok := findWorkspace(files[0])
+	if !ok {
+		return nil
+	}
+	command := cfg.Bazel.Command
+	if command == "" {
+		command = ws.system.name
+	}
+
+	var buildFiles, sources []string
+	for _, f := range files {
+		if ws.isBuildFile(f) {
+			buildFiles = append(buildFiles, f)
+		} else {
+			sources = append(sources, f)
+		}
+	}
+
+	var failures []string
+	if len(buildFiles) > 0 {
+		if err := runBuildifier(ws.root, buildFiles, verbose); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	targets, err := ws.owners(command, sources, verbose)
+	if err != nil {
+		return err
+	}
+	for _, f := range buildFiles {
+		if pkg, ok := ws.pkg(f); ok && slices.Contains(ws.system.buildFiles, filepath.Base(f)) {
+			targets = append(targets, "//"+pkg+ws.system.allTargets)
+		}
+	}
+	slices.Sort(targets)
+	targets = slices.Compact(targets)
+	if len(targets) == 0 {
+		vlog.Printf(verbose, "⏭️  No %s targets own the edited files\n", ws.system.name)
+		return joinFailures(failures)
+	}
+
+	if err := ws.run(command, "build", cfg.Bazel.Flags, targets, verbose); err != nil {
+		failures = append(failures, err.Error())
+		return joinFailures(failures) // Tests of targets that don't build can't pass
+	}
+
+	if cfg.Bazel.Test {
+		tests, err := ws.tests(command, targets, verbose)
+		if err != nil {
+			return err
+		}
+		if len(tests) > 0 {
+			if err := ws.run(command, "test", cfg.Bazel.Flags, tests, verbose); err != nil {
+				failures = append(failures, err.Error())
+			}
+		}
+	}
+	return joinFailures(failures)
+}
+
+func joinFailures(failures []string) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(failures, "\n\n"))
+}
+
+// owners returns the rules that have the files as sources
+func (ws workspace) owners(command string, files []string, verbose bool) ([]string, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	var labels, paths, universe []string
+	for _, f := range files {
+		pkg, ok := ws.pkg(f)
+		if !ok {
+			continue
+		}
+		rel, err := filepath.Rel(filepath.Join(ws.root, filepath.FromSlash(pkg)), f)
+		if err != nil {
+			continue
+		}
+		labels = append(labels, "//"+pkg+":"+filepath.ToSlash(rel))
+		if p, err := filepath.Rel(ws.root, f); err == nil {
+			paths = append(paths, filepath.ToSlash(p))
+		}
+		if u := "//" + pkg + ":*"; !slices.Contains(universe, u) {
+			universe = append(universe, u)
+		}
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	if ws.system.name == "buck2" {
+		return ws.query(command, fmt.Sprintf("owner(set(%s))", strings.Join(paths, " ")), verbose)
+	}
+	// Sources can only belong to rules of their own package
+	return ws.query(command, fmt.Sprintf("kind(rule, rdeps(set(%s), set(%s), 1))", strings.Join(universe, " "), strings.Join(labels, " ")), verbose)
+}
+
+// tests returns the tests among the targets and their direct dependents
+func (ws workspace) tests(command string, targets []string, verbose bool) ([]string, error) {
+	if ws.system.name == "buck2" {
+		return ws.query(command, fmt.Sprintf("testsof(set(%s))", strings.Join(targets, " ")), verbose)
+	}
+	return ws.query(command, fmt.Sprintf("tests(rdeps(//..., set(%s), 1))", strings.Join(targets, " ")), verbose)
+}
+
+// query runs a build graph query and returns the labels it prints
+func (ws workspace) query(command, expr string, verbose bool) ([]string, error) {
+	args := []string{"query", ws.system.keepGoing, "--output=label", expr}
+	if ws.system.name == "buck2" {
+		args = []string{"uquery", expr}
+	}
+	vlog.Printf(verbose, "🔧 %s %s (in %s)\n", command, strings.Join(args, " "), ws.root)
+	cmd := proc.Command(command, args...)
+	cmd.Dir = ws.root
+	output, err := cmd.Output()
+	// bazel exits 3 when --keep_going left out some of the targets, like new files
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 3) {
+		stderr := ""
+		if exitErr != nil {
+			stderr = strings.TrimSpace(string(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("%s query failed: %w\n%s", command, err, stderr)
+	}
+	var labels []string
+	for line := range strings.Lines(string(output)) {
+		if label := strings.TrimSpace(line); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
+
+// run builds or tests the targets, returning the output of a failure with
+// paths resolved against the workspace root
+func (ws workspace) run(command, verb string, flags, targets []string, verbose bool) error {
+	args := append([]string{verb}, ws.system.quiet...)
+	args = append(args, ws.system.keepGoing)
+	if verb == "test" && ws.system.name == "bazel" {
+		args = append(args, "--test_output=errors")
+	}
+	args = append(append(args, flags...), targets...)
+	vlog.Printf(verbose, "🔧 %s %s (in %s)\n", command, strings.Join(args, " "), ws.root)
+	cmd := proc.Command(command, args...)
+	cmd.Dir = ws.root
+	output, err := cmd.CombinedOutput()
+	vlog.Output(command+" "+verb, output)
+	if err != nil && !proc.Killed(cmd) {
+		return fmt.Errorf("%s %s failed: %v\n%s", command, verb, err, ResolvePaths(strings.TrimSpace(string(output)), ws.root))
+	}
+	return nil
+}
+
+// runBuildifier checks the formatting and lint of BUILD and .bzl files
+func runBuildifier(root string, files []string, verbose bool) error {
+	if _, err := exec.LookPath("buildifier"); err != nil {
+		vlog.Printf(verbose, "⏭️  Skipping buildifier: not installed\n")
+		return nil
+	}
+	vlog.Printf(verbose, "🔧 buildifier -mode=check -lint=warn %s\n", strings.Join(files, " "))
+	cmd := proc.Command("buildifier", append([]string{"-mode=check", "-lint=warn"}, files...)...)
+	cmd.Dir = root
+	if output, err := cmd.CombinedOutput(); err != nil && !proc.Killed(cmd) {
+		return fmt.Errorf("buildifier failed: %v\n%s\nRun `buildifier -lint=fix` on the files to fix formatting and the fixable warnings.", err, ResolvePaths(strings.TrimSpace(string(output)), root))
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubBazel puts a bazel on PATH that logs its arguments and answers queries
+// with the given labels, failing builds when failBuild is set
+func stubBazel(t *testing.T, labels string, failBuild bool) string {
+	t.Helper()
+	bin := t.TempDir()
+	log := filepath.Join(bin, "calls")
+	build := "exit 0"
+	if failBuild {
+		build = "echo 'pkg/a.go:3:1: undefined: x' >&2; exit 1"
+	}
+	writeTestFile(t, bin, "bazel", "#!/bin/sh\necho \"$@\" >> "+log+"\n"+
+		"case \"$1\" in\n"+
+		"query) printf '"+labels+"' ;;\n"+
+		"*) "+build+" ;;\n"+
+		"esac\n")
+	if err := os.Chmod(filepath.Join(bin, "bazel"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+func bazelWorkspace(t *testing.T, config string) string {
+	t.Helper()
+	root := t.TempDir()
+	runInDir(t, root, "git", "init", "-q")
+	writeTestFile(t, root, ".claude-hooks.yaml", config)
+	writeTestFile(t, root, "MODULE.bazel", "module(name = \"x\")\n")
+	writeTestFile(t, root, "pkg/BUILD.bazel", "go_library(name = \"pkg\", srcs = [\"a.go\"])\n")
+	writeTestFile(t, root, "pkg/a.go", "package pkg\n")
+	writeTestFile(t, root, "docs/notes.md", "not in a package\n")
+	return root
+}
+
+func TestBazelRouting(t *testing.T) {
+	root := bazelWorkspace(t, "bazel:\n  enabled: true\n")
+	groups := GroupFiles([]string{
+		filepath.Join(root, "pkg", "a.go"),
+		filepath.Join(root, "pkg", "BUILD.bazel"),
+		filepath.Join(root, "docs", "notes.md"),
+	})
+	if len(groups) != 1 || len(groups["bazel"]) != 2 {
+		t.Errorf("Expected the package's files to go to bazel, got %v", groups)
+	}
+
+	root = bazelWorkspace(t, "bazel:\n  enabled: false\n")
+	if groups := GroupFiles([]string{filepath.Join(root, "pkg", "a.go")}); len(groups["go"]) != 1 {
+		t.Errorf("Expected the go hook without bazel.enabled, got %v", groups)
+	}
+}
+
+func TestBazelHookBuildsAndTestsOwners(t *testing.T) {
+	root := bazelWorkspace(t, "bazel:\n  enabled: true\n  test: true\n  flags: [--config=ci]\n")
+	log := stubBazel(t, `//pkg:pkg\n//pkg:pkg_test\n`, false)
+
+	hook := GetHook("bazel")
+	if err := hook.PostEditJSON([]string{filepath.Join(root, "pkg", "a.go")}, false); err != nil {
+		t.Fatalf("Expected the build to pass, got %v", err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(calls) != 4 {
+		t.Fatalf("Expected query, build, query, test, got %q", calls)
+	}
+	if !strings.Contains(calls[0], "kind(rule, rdeps(set(//pkg:*), set(//pkg:a.go), 1))") {
+		t.Errorf("Expected the owners of a.go to be queried, got %q", calls[0])
+	}
+	if !strings.HasPrefix(calls[1], "build ") || !strings.Contains(calls[1], "--config=ci //pkg:pkg //pkg:pkg_test") {
+		t.Errorf("Expected the owners to be built with the flags, got %q", calls[1])
+	}
+	if !strings.Contains(calls[2], "tests(rdeps(//..., set(//pkg:pkg //pkg:pkg_test), 1))") {
+		t.Errorf("Expected the tests of the owners and dependents to be queried, got %q", calls[2])
+	}
+	if !strings.HasPrefix(calls[3], "test ") || !strings.Contains(calls[3], "--test_output=errors") {
+		t.Errorf("Expected the tests to run, got %q", calls[3])
+	}
+}
+
+func TestBazelHookBuildFailure(t *testing.T) {
+	root := bazelWorkspace(t, "bazel:\n  enabled: true\n  test: true\n")
+	log := stubBazel(t, `//pkg:pkg\n`, true)
+
+	err := GetHook("bazel").PostEditJSON([]string{filepath.Join(root, "pkg", "BUILD.bazel")}, false)
+	if err == nil {
+		t.Fatal("Expected the failing build to fail the hook")
+	}
+	if !strings.Contains(err.Error(), filepath.Join(root, "pkg", "a.go")+":3:1: undefined: x") {
+		t.Errorf("Expected the failure with resolved paths, got %v", err)
+	}
+
+	data, _ := os.ReadFile(log)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	// A BUILD file builds its whole package, and failed builds aren't tested
+	if len(calls) != 1 || !strings.HasSuffix(calls[0], " //pkg:all") {
+		t.Errorf("Expected only a build of //pkg:all, got %q", calls)
+	}
+}
@@ -0,0 +1,144 @@
+package hooks
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// BuildCheck is what CheckBuildConfigs validated
+type BuildCheck struct {
+	// Validated are the go.builds configurations the edited packages were
+	// vetted under
+	Validated []string
+	// Unchecked are edited files that neither the default build nor any
+	// configured one compiles
+	Unchecked []string
+}
+
+// CheckBuildConfigs vets the edited Go files that build constraints (//go:build
+// lines and _GOOS/_GOARCH file names) leave out of the default build, under
+// each go.builds configuration that includes them. Plain go vet never
+// compiles those files.
+func CheckBuildConfigs(files []string, verbose bool) (BuildCheck, error) {
+	var check BuildCheck
+	var goFiles []string
+	for _, f := range files {
+		if strings.HasSuffix(f, ".go") {
+			goFiles = append(goFiles, f)
+		}
+	}
+	if len(goFiles) == 0 {
+		return check, nil
+	}
+	cfg, err := config.Load(filepath.Dir(goFiles[0]))
+	if err != nil {
+		return check, err
+	}
+
+	included := make([][]string, len(cfg.Go.Builds)) // Edited files per build
+	for _, f := range goFiles {
+		if compiles(build.Default, f) {
+			continue
+		}
+		covered := false
+		for i, b := range cfg.Go.Builds {
+			if compiles(buildContext(b), f) {
+				covered = true
+				included[i] = append(included[i], f)
+			}
+		}
+		if !covered {
+			check.Unchecked = append(check.Unchecked, f)
+		}
+	}
+
+	var failures []string
+	for i, b := range cfg.Go.Builds {
+		if len(included[i]) == 0 {
+			continue
+		}
+		dirs := buildableDirs(packageDirs(included[i]), verbose)
+		name := buildName(b)
+		vlog.Printf(verbose, "🏗️  Vetting %d package(s) for %s\n", len(dirs), name)
+		args := []string{"vet"}
+		if len(b.Tags) > 0 {
+			args = append(args, "-tags="+strings.Join(b.Tags, ","))
+		}
+		if err := runGoPerModuleEnv(dirs, args, buildEnv(b), verbose); err != nil {
+			failures = append(failures, fmt.Sprintf("%s:\n%v", name, err))
+			continue
+		}
+		check.Validated = append(check.Validated, name)
+	}
+	if len(failures) > 0 {
+		return check, fmt.Errorf("%s", strings.Join(failures, "\n\n"))
+	}
+	return check, nil
+}
+
+// compiles reports whether ctx compiles file, by its name and constraints.
+// Unreadable files count as compiled: go vet reports them.
+func compiles(ctx build.Context, file string) bool {
+	ok, err := ctx.MatchFile(filepath.Dir(file), filepath.Base(file))
+	return ok || err != nil
+}
+
+// buildContext returns the go/build context of b
+func buildContext(b config.GoBuildConfig) build.Context {
+	ctx := build.Default
+	if b.GOOS != "" {
+		ctx.GOOS = b.GOOS
+	}
+	if b.GOARCH != "" {
+		ctx.GOARCH = b.GOARCH
+	}
+	if b.CGO != nil {
+		ctx.CgoEnabled = *b.CGO
+	} else if ctx.GOOS != runtime.GOOS || ctx.GOARCH != runtime.GOARCH {
+		ctx.CgoEnabled = false // go disables cgo when cross-compiling
+	}
+	ctx.BuildTags = b.Tags
+	return ctx
+}
+
+// buildEnv returns the environment go runs with under b
+func buildEnv(b config.GoBuildConfig) []string {
+	var env []string
+	if b.GOOS != "" {
+		env = append(env, "GOOS="+b.GOOS)
+	}
+	if b.GOARCH != "" {
+		env = append(env, "GOARCH="+b.GOARCH)
+	}
+	if b.CGO != nil {
+		cgo := "0"
+		if *b.CGO {
+			cgo = "1"
+		}
+		env = append(env, "CGO_ENABLED="+cgo)
+	}
+	return env
+}
+
+// buildName describes b the way it's reported, e.g. "linux/arm64 tags=integration cgo=off"
+func buildName(b config.GoBuildConfig) string {
+	ctx := buildContext(b)
+	name := ctx.GOOS + "/" + ctx.GOARCH
+	if len(b.Tags) > 0 {
+		name += " tags=" + strings.Join(b.Tags, ",")
+	}
+	switch {
+	case b.CGO == nil:
+	case *b.CGO:
+		name += " cgo=on"
+	default:
+		name += " cgo=off"
+	}
+	return name
+}
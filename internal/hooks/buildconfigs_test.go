@@ -0,0 +1,57 @@
+package hooks
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckBuildConfigs(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "go.mod", "module example.com/tags\n\ngo 1.22\n")
+	writeTestFile(t, repo, "app.go", "package app\n\nfunc Name() string { return \"app\" }\n")
+	// Only compiled for windows, and broken there
+	writeTestFile(t, repo, "app_windows.go", "package app\n\nvar _ int = Name()\n")
+	writeTestFile(t, repo, "e2e/e2e_test.go", "//go:build integration\n\npackage e2e\n\nimport \"testing\"\n\nfunc TestE2E(t *testing.T) {}\n")
+	writeTestFile(t, repo, "plan9.go", "//go:build plan9\n\npackage app\n")
+	edited := []string{
+		filepath.Join(repo, "app.go"),
+		filepath.Join(repo, "app_windows.go"),
+		filepath.Join(repo, "e2e/e2e_test.go"),
+		filepath.Join(repo, "plan9.go"),
+	}
+
+	// Without configurations, nothing is vetted and the files are reported
+	check, err := CheckBuildConfigs(edited, false)
+	if err != nil || len(check.Validated) > 0 || len(check.Unchecked) != 3 {
+		t.Fatalf("Expected the three constrained files to be unchecked, got %+v (%v)", check, err)
+	}
+
+	writeTestFile(t, repo, ".claude-hooks.yaml", `go:
+  builds:
+    - goos: windows
+      goarch: amd64
+    - tags: [integration]
+      cgo: false
+`)
+	check, err = CheckBuildConfigs(edited, false)
+	if err == nil || !strings.Contains(err.Error(), "windows/amd64:") || !strings.Contains(err.Error(), "app_windows.go:3") {
+		t.Fatalf("Expected the windows build to fail on app_windows.go, got %v", err)
+	}
+	if len(check.Validated) != 1 || !strings.HasSuffix(check.Validated[0], " tags=integration cgo=off") {
+		t.Errorf("Expected the integration build to be validated, got %v", check.Validated)
+	}
+	if len(check.Unchecked) != 1 || filepath.Base(check.Unchecked[0]) != "plan9.go" {
+		t.Errorf("Expected only plan9.go to be unchecked, got %v", check.Unchecked)
+	}
+
+	writeTestFile(t, repo, "app_windows.go", "package app\n\nvar _ string = Name()\n")
+	if check, err := CheckBuildConfigs(edited, false); err != nil || len(check.Validated) != 2 {
+		t.Errorf("Expected both builds to pass once fixed, got %+v (%v)", check, err)
+	}
+
+	// Edits the default build compiles are go vet's alone
+	if check, err := CheckBuildConfigs(edited[:1], false); err != nil || len(check.Validated)+len(check.Unchecked) > 0 {
+		t.Errorf("Expected nothing to check for an unconstrained file, got %+v (%v)", check, err)
+	}
+}
@@ -0,0 +1,365 @@
+package hooks
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vcs"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// BundleGrowth is how much an edit grew a web package's minified bundle
+type BundleGrowth struct {
+	Dir           string // Directory of the package.json, absolute
+	Before, After int64  // Bytes of output at HEAD and now
+	Limit         int64
+	Block         bool
+	Packages      []PackageSize // Dependencies that grew the most, largest first
+}
+
+// PackageSize is how many bytes one dependency adds to a bundle; Name is
+// empty for the project's own code
+type PackageSize struct {
+	Name  string
+	Bytes int64
+}
+
+func (g BundleGrowth) String() string {
+	msg := fmt.Sprintf("%s: the bundle grows by %s (%s to %s, limit +%s)",
+		filepath.Join(g.Dir, "package.json"), kib(g.After-g.Before), kib(g.Before), kib(g.After), kib(g.Limit))
+	if len(g.Packages) > 0 {
+		var parts []string
+		for _, p := range g.Packages {
+			parts = append(parts, fmt.Sprintf("%s +%s", cmp.Or(p.Name, "project code"), kib(p.Bytes)))
+		}
+		msg += "; largest increases: " + strings.Join(parts, ", ")
+	}
+	return msg + ". Import only the parts you need, or use a lighter dependency."
+}
+
+func kib(n int64) string {
+	return fmt.Sprintf("%.1f KiB", float64(n)/1024)
+}
+
+// FindBundleGrowth estimates the bundle size change of each web package whose
+// package.json dependencies the edit changed, or whose edited sources gained an
+// import of a package. The entrypoints are bundled and minified with the
+// project's esbuild in the working tree and in a checkout of HEAD (cached per
+// commit), and packages growing more than bundle.max_increase are returned. It
+// is a no-op unless bundle.enabled is set, and is skipped when esbuild isn't
+// installed.
+func FindBundleGrowth(files []string, verbose bool) ([]BundleGrowth, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	cfg, err := config.Load(filepath.Dir(files[0]))
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Bundle.Enabled {
+		return nil, nil
+	}
+	if checkoutCoverage(filepath.Dir(files[0])).Reduced() {
+		vlog.Printf(verbose, "⏭️  Skipping bundle size check: checking out HEAD would bring in the whole tree of a sparse or partial clone\n")
+		return nil, nil
+	}
+
+	var dirs []string
+	for _, f := range files {
+		root := state.ProjectRoot(filepath.Dir(f))
+		dir := findUp(filepath.Dir(f), root, "package.json")
+		if dir == "" || slices.Contains(dirs, dir) || !bundleAffected(root, f) {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+
+	var growth []BundleGrowth
+	for _, dir := range dirs {
+		root := state.ProjectRoot(dir)
+		esbuild := findUp(dir, root, filepath.Join("node_modules", ".bin", "esbuild"))
+		if esbuild == "" {
+			vlog.Printf(verbose, "⏭️  Skipping bundle size check in %s: esbuild not installed\n", dir)
+			continue
+		}
+		esbuild = filepath.Join(esbuild, "node_modules", ".bin", "esbuild")
+
+		entrypoints := cfg.Bundle.Entrypoints
+		if len(entrypoints) == 0 {
+			entrypoints = defaultEntrypoint(dir)
+		}
+		if len(entrypoints) == 0 {
+			vlog.Printf(verbose, "⏭️  Skipping bundle size check in %s: no bundle.entrypoints\n", dir)
+			continue
+		}
+
+		after, err := bundleSizeOf(esbuild, dir, entrypoints, verbose)
+		if err != nil {
+			return nil, err
+		}
+		before, ok, err := baselineBundleSize(root, dir, esbuild, entrypoints, verbose)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || after.Total-before.Total <= cfg.Bundle.MaxIncrease {
+			continue
+		}
+		growth = append(growth, BundleGrowth{
+			Dir:      dir,
+			Before:   before.Total,
+			After:    after.Total,
+			Limit:    cfg.Bundle.MaxIncrease,
+			Block:    cfg.Bundle.Block,
+			Packages: largestIncreases(before, after, 5),
+		})
+	}
+	return growth, nil
+}
+
+// bundleImportPattern matches imports and requires of bare package specifiers,
+// leaving out relative paths, node: builtins, and @/ or ~/ path aliases
+var bundleImportPattern = regexp.MustCompile(`(?:\bfrom\s*|\bimport\s*\(?\s*|\brequire\s*\(\s*)["']([^"'./~][^"']*)["']`)
+
+// bundleAffected reports whether the edit to file can change what gets bundled:
+// package.json dependencies changed, or a source file gained a package import
+func bundleAffected(root, file string) bool {
+	if filepath.Base(file) == "package.json" {
+		return dependenciesChanged(root, file)
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs":
+	default:
+		return false
+	}
+	for _, line := range addedLines(root, file) {
+		for _, m := range bundleImportPattern.FindAllStringSubmatch(line, -1) {
+			if !strings.HasPrefix(m[1], "node:") && !strings.HasPrefix(m[1], "@/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// packageManifest is the part of package.json the check compares
+type packageManifest struct {
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+// dependenciesChanged compares the dependencies of package.json with the last
+// commit's; a package.json that isn't committed counts as changed
+func dependenciesChanged(root, file string) bool {
+	repo := vcs.Detect(root)
+	if repo == nil {
+		return true
+	}
+	old, ok := repo.Base(file)
+	if !ok {
+		return true
+	}
+	current, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	var before, after packageManifest
+	if json.Unmarshal(old, &before) != nil || json.Unmarshal(current, &after) != nil {
+		return true
+	}
+	return !maps.Equal(before.Dependencies, after.Dependencies) || !maps.Equal(before.DevDependencies, after.DevDependencies)
+}
+
+// addedLines returns the lines of file added since the last commit, or all of
+// them when the file isn't tracked
+func addedLines(root, file string) []string {
+	repo := vcs.Detect(root)
+	if repo == nil || !repo.Tracked(file) {
+		data, _ := os.ReadFile(file)
+		return strings.Split(string(data), "\n")
+	}
+	output, err := repo.Diff(0, file)
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		if line, ok := strings.CutPrefix(scanner.Text(), "+"); ok && !strings.HasPrefix(line, "++") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// defaultEntrypoint returns the first conventional entrypoint in dir
+func defaultEntrypoint(dir string) []string {
+	for _, name := range []string{"index", "main"} {
+		for _, ext := range []string{".ts", ".tsx", ".js", ".jsx"} {
+			if _, err := os.Stat(filepath.Join(dir, "src", name+ext)); err == nil {
+				return []string{"src/" + name + ext}
+			}
+		}
+	}
+	return nil
+}
+
+// bundleSize is what a build of the entrypoints produced
+type bundleSize struct {
+	Total    int64            `json:"total"`
+	Packages map[string]int64 `json:"packages"` // Bytes in the output per dependency, "" for project code
+}
+
+// esbuildMetafile is the part of esbuild's --metafile output the check reads
+type esbuildMetafile struct {
+	Outputs map[string]struct {
+		Bytes  int64 `json:"bytes"`
+		Inputs map[string]struct {
+			BytesInOutput int64 `json:"bytesInOutput"`
+		} `json:"inputs"`
+	} `json:"outputs"`
+}
+
+// bundleLoaders bundle assets as files so imports of them don't fail the build
+var bundleLoaders = []string{".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".woff", ".woff2", ".ttf", ".eot"}
+
+// bundleSizeOf bundles and minifies the entrypoints in dir with esbuild
+func bundleSizeOf(esbuild, dir string, entrypoints []string, verbose bool) (bundleSize, error) {
+	out, err := os.MkdirTemp("", "claude-hooks-esbuild-")
+	if err != nil {
+		return bundleSize{}, fmt.Errorf("creating esbuild output directory: %w", err)
+	}
+	defer os.RemoveAll(out)
+	metafile := filepath.Join(out, "meta.json")
+
+	args := append(slices.Clone(entrypoints), "--bundle", "--minify", "--format=esm", "--log-level=error",
+		"--outdir="+filepath.Join(out, "dist"), "--metafile="+metafile)
+	for _, ext := range bundleLoaders {
+		args = append(args, "--loader:"+ext+"=file")
+	}
+	vlog.Printf(verbose, "🔧 esbuild --bundle --minify %s (in %s)\n", strings.Join(entrypoints, " "), dir)
+	cmd := proc.Command(esbuild, args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return bundleSize{}, fmt.Errorf("running esbuild: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+
+	data, err := os.ReadFile(metafile)
+	if err != nil {
+		return bundleSize{}, fmt.Errorf("reading esbuild metafile: %w", err)
+	}
+	var meta esbuildMetafile
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return bundleSize{}, fmt.Errorf("parsing esbuild metafile: %w", err)
+	}
+
+	size := bundleSize{Packages: make(map[string]int64)}
+	for _, output := range meta.Outputs {
+		size.Total += output.Bytes
+		for input, in := range output.Inputs {
+			size.Packages[dependencyOf(input)] += in.BytesInOutput
+		}
+	}
+	return size, nil
+}
+
+// dependencyOf returns the npm package a bundled input belongs to, "" for project code
+func dependencyOf(input string) string {
+	i := strings.LastIndex(input, "node_modules/")
+	if i < 0 {
+		return ""
+	}
+	parts := strings.SplitN(input[i+len("node_modules/"):], "/", 3)
+	if strings.HasPrefix(parts[0], "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+// baselineBundleSize bundles the entrypoints as they are at HEAD, in a worktree
+// that shares the working tree's node_modules. ok is false when there is no
+// HEAD or it doesn't build, so there is nothing to compare against.
+func baselineBundleSize(root, dir, esbuild string, entrypoints []string, verbose bool) (size bundleSize, ok bool, err error) {
+	output, err := proc.Command("git", "-C", root, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return bundleSize{}, false, nil
+	}
+	head := strings.TrimSpace(string(output))
+	relDir, err := filepath.Rel(root, dir)
+	if err != nil {
+		return bundleSize{}, false, fmt.Errorf("resolving package %s: %w", dir, err)
+	}
+
+	sum := sha256.Sum256([]byte(head + " " + relDir + " " + strings.Join(entrypoints, " ")))
+	cache, err := state.ProjectPath(root, state.Cache, "bundle-"+hex.EncodeToString(sum[:8])+".json")
+	if err != nil {
+		return bundleSize{}, false, err
+	}
+	if data, err := os.ReadFile(cache); err == nil && json.Unmarshal(data, &size) == nil {
+		recordCacheLookup(true)
+		return size, true, nil
+	}
+	recordCacheLookup(false)
+
+	worktree, err := os.MkdirTemp("", "claude-hooks-bundle-")
+	if err != nil {
+		return bundleSize{}, false, fmt.Errorf("creating worktree directory: %w", err)
+	}
+	if output, err := proc.Command("git", "-C", root, "worktree", "add", "--detach", "--quiet", worktree, head).CombinedOutput(); err != nil {
+		os.RemoveAll(worktree)
+		return bundleSize{}, false, fmt.Errorf("checking out HEAD: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	defer func() {
+		_ = proc.Command("git", "-C", root, "worktree", "remove", "--force", worktree).Run()
+	}()
+
+	// Dependencies aren't committed, so the checkout uses the installed ones
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "node_modules")); err == nil {
+			rel, _ := filepath.Rel(root, d)
+			if err := os.Symlink(filepath.Join(d, "node_modules"), filepath.Join(worktree, rel, "node_modules")); err != nil && !errors.Is(err, os.ErrExist) {
+				return bundleSize{}, false, fmt.Errorf("linking node_modules: %w", err)
+			}
+		}
+		if d == root || filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	size, err = bundleSizeOf(esbuild, filepath.Join(worktree, relDir), entrypoints, verbose)
+	if err != nil {
+		vlog.Printf(verbose, "⏭️  HEAD doesn't bundle, skipping the size comparison: %v\n", err)
+		return bundleSize{}, false, nil
+	}
+	if data, err := json.Marshal(size); err == nil {
+		_ = os.WriteFile(cache, data, 0o644)
+	}
+	return size, true, nil
+}
+
+// largestIncreases returns up to n dependencies that grew the most
+func largestIncreases(before, after bundleSize, n int) []PackageSize {
+	var grown []PackageSize
+	for name, bytes := range after.Packages {
+		if delta := bytes - before.Packages[name]; delta > 0 {
+			grown = append(grown, PackageSize{Name: name, Bytes: delta})
+		}
+	}
+	slices.SortFunc(grown, func(a, b PackageSize) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), strings.Compare(a.Name, b.Name))
+	})
+	return grown[:min(len(grown), n)]
+}
@@ -0,0 +1,78 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFindBundleGrowth runs a stub esbuild that reports a bigger bundle in the
+// working tree than in the checkout of HEAD
+func TestFindBundleGrowth(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	repo := t.TempDir()
+	writeFile := func(path, content string) { writeTestFile(t, repo, path, content) }
+	run := func(args ...string) { runInDir(t, repo, args...) }
+
+	writeFile(".claude-hooks.yaml", "bundle:\n  enabled: true\n  max_increase: 10240\n")
+	writeFile(".gitignore", "node_modules/\n")
+	writeFile("package.json", `{"dependencies": {"react": "^19.0.0"}}`)
+	writeFile("src/index.ts", "import { render } from 'react'\n")
+	writeFile("src/util.ts", "export const one = 1\n")
+	// The checkout of HEAD is a temporary directory named claude-hooks-bundle-*
+	writeFile("node_modules/.bin/esbuild", `#!/bin/sh
+for arg; do case "$arg" in --metafile=*) meta="${arg#--metafile=}";; esac; done
+case "$(pwd)" in
+*claude-hooks-bundle-*) echo '{"outputs":{"dist/index.js":{"bytes":40960,"inputs":{"node_modules/react/index.js":{"bytesInOutput":30720},"src/index.ts":{"bytesInOutput":10240}}}}}' > "$meta";;
+*) echo '{"outputs":{"dist/index.js":{"bytes":102400,"inputs":{"node_modules/react/index.js":{"bytesInOutput":30720},"node_modules/@scope/big/dist/big.js":{"bytesInOutput":51200},"node_modules/lodash/lodash.js":{"bytesInOutput":10240},"src/index.ts":{"bytesInOutput":10240}}}}}' > "$meta";;
+esac
+`)
+	if err := os.Chmod(filepath.Join(repo, "node_modules/.bin/esbuild"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	run("git", "init", "-q")
+	run("git", "add", ".")
+	run("git", "commit", "-q", "-m", "init")
+
+	// A local import can't grow the bundle by itself, so nothing is built
+	writeFile("src/util.ts", "import { one } from './one'\nexport const two = one + 1\n")
+	growth, err := FindBundleGrowth([]string{filepath.Join(repo, "src/util.ts")}, false)
+	if err != nil || len(growth) != 0 {
+		t.Fatalf("Expected no check for a relative import, got %v, %v", growth, err)
+	}
+
+	writeFile("src/util.ts", "import big from '@scope/big'\nimport { chunk } from \"lodash\"\nexport const one = 1\n")
+	growth, err = FindBundleGrowth([]string{filepath.Join(repo, "src/util.ts")}, false)
+	if err != nil {
+		t.Fatalf("FindBundleGrowth failed: %v", err)
+	}
+	if len(growth) != 1 {
+		t.Fatalf("Expected one package over the limit, got %v", growth)
+	}
+	g := growth[0]
+	if g.Before != 40960 || g.After != 102400 || len(g.Packages) != 2 || g.Packages[0].Name != "@scope/big" || g.Packages[1].Name != "lodash" {
+		t.Errorf("Expected 40 KiB to 100 KiB led by @scope/big and lodash, got %+v", g)
+	}
+	if msg := g.String(); !strings.Contains(msg, "grows by 60.0 KiB") || !strings.Contains(msg, "@scope/big +50.0 KiB") {
+		t.Errorf("Expected the growth and its largest contributor in the message, got %q", msg)
+	}
+}
+
+func TestDependenciesChanged(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "package.json", `{"name": "app", "dependencies": {"react": "^19.0.0"}}`)
+	runInDir(t, repo, "git", "init", "-q")
+	runInDir(t, repo, "git", "add", ".")
+	runInDir(t, repo, "git", "commit", "-q", "-m", "init")
+	file := filepath.Join(repo, "package.json")
+
+	writeTestFile(t, repo, "package.json", `{"name": "app", "version": "1.0.1", "dependencies": {"react": "^19.0.0"}}`)
+	if dependenciesChanged(repo, file) {
+		t.Error("Expected a version bump not to count as a dependency change")
+	}
+	writeTestFile(t, repo, "package.json", `{"name": "app", "dependencies": {"react": "^19.0.0", "moment": "^2.30.0"}}`)
+	if !dependenciesChanged(repo, file) {
+		t.Error("Expected an added dependency to count")
+	}
+}
@@ -0,0 +1,36 @@
+package hooks
+
+import "sync"
+
+// cacheLookups counts how often the checks found what they needed in their
+// caches under the project's cache directory, for the audit log
+var cacheLookups struct {
+	sync.Mutex
+	hits, misses int
+}
+
+// recordCacheLookup notes one lookup in a check's cache
+func recordCacheLookup(hit bool) {
+	cacheLookups.Lock()
+	defer cacheLookups.Unlock()
+	if hit {
+		cacheLookups.hits++
+	} else {
+		cacheLookups.misses++
+	}
+}
+
+// CacheResult is the audit log's cache outcome of this invocation: "miss"
+// when any check had to do the work its cache would have saved, "hit" when
+// every lookup was served from a cache, and "" when no cache was consulted
+func CacheResult() string {
+	cacheLookups.Lock()
+	defer cacheLookups.Unlock()
+	switch {
+	case cacheLookups.misses > 0:
+		return "miss"
+	case cacheLookups.hits > 0:
+		return "hit"
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package hooks
+
+import "testing"
+
+func TestCacheResult(t *testing.T) {
+	reset := func() {
+		cacheLookups.Lock()
+		cacheLookups.hits, cacheLookups.misses = 0, 0
+		cacheLookups.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+
+	if got := CacheResult(); got != "" {
+		t.Errorf("Expected no result without lookups, got %q", got)
+	}
+	recordCacheLookup(true)
+	recordCacheLookup(true)
+	if got := CacheResult(); got != "hit" {
+		t.Errorf("Expected a hit when every lookup hit, got %q", got)
+	}
+	recordCacheLookup(false)
+	if got := CacheResult(); got != "miss" {
+		t.Errorf("Expected a miss once any lookup missed, got %q", got)
+	}
+}
@@ -0,0 +1,181 @@
+package hooks
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/policy"
+)
+
+// language is a file type whose hook a project's edits reach when one of its
+// marker files is at the project's root
+type language struct {
+	fileType string
+	name     string
+	markers  []string
+}
+
+// languages are listed in the summary in this order
+var languages = []language{
+	{"go", "Go", []string{"go.mod", "go.work"}},
+	{"typescript", "TS", []string{"tsconfig.json"}},
+	{"javascript", "JS", []string{"package.json"}},
+	{"proto", "Proto", []string{"buf.yaml", "buf.work.yaml"}},
+	{"rust", "Rust", []string{"Cargo.toml"}},
+}
+
+// Capabilities summarizes, in one line for the start of a session, what
+// claude-hooks checks in the project at root under cfg, e.g. "claude-hooks
+// active: Go(vet,tests), edits: encoding,merge-artifacts, content rules: 5,
+// push scan, plan review: full council". Claude can conform to it up front,
+// and a user can spot a check they expected and don't see.
+func Capabilities(cfg *config.Config, root string, offline bool) string {
+	var parts []string
+	for _, l := range projectLanguages(root) {
+		parts = append(parts, fmt.Sprintf("%s(%s)", l.name, strings.Join(languageChecks(cfg, l.fileType), ",")))
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Hooks)) {
+		if !slices.ContainsFunc(languages, func(l language) bool { return l.fileType == name }) {
+			parts = append(parts, fmt.Sprintf("%s(%s)", name, commandChecks(cfg.Hooks[name])))
+		}
+	}
+	if cfg.Bazel.Enabled {
+		parts = append(parts, "Bazel(build,test)")
+	}
+	if cfg.Monorepo.Enabled && len(cfg.Monorepo.Targets) > 0 {
+		parts = append(parts, fmt.Sprintf("monorepo(%s)", strings.Join(cfg.Monorepo.Targets, ",")))
+	}
+
+	var edits []string
+	for _, check := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"encoding", cfg.Encoding.Enabled},
+		{"editorconfig", cfg.EditorConfig.Enabled},
+		{"merge-artifacts", cfg.MergeArtifacts.Enabled},
+		{"placeholders", cfg.Placeholders.Enabled},
+		{"images", cfg.Images.Enabled},
+		{"semgrep", cfg.Semgrep.Enabled && len(cfg.Semgrep.Rulesets) > 0},
+		{"gosec", cfg.Security.Gosec},
+		{"dependency-audit", cfg.Security.Audit},
+		{"duplicates", cfg.Duplicates.Enabled},
+		{"complexity", cfg.Complexity.Enabled},
+		{"bundle-size", cfg.Bundle.Enabled},
+	} {
+		if check.enabled {
+			edits = append(edits, check.name)
+		}
+	}
+	if len(edits) > 0 {
+		parts = append(parts, "edits: "+strings.Join(edits, ","))
+	}
+
+	if cfg.Content.Enabled {
+		parts = append(parts, fmt.Sprintf("content rules: %d", len(contentRuleNames(cfg.Content))))
+	} else {
+		parts = append(parts, "content rules: off")
+	}
+	if len(cfg.Bash.Rules) > 0 {
+		parts = append(parts, fmt.Sprintf("bash rules: %d", len(cfg.Bash.Rules)))
+	}
+	if cfg.Push.Enabled {
+		parts = append(parts, "push scan")
+	}
+
+	switch {
+	case offline:
+		parts = append(parts, "plan review: off (offline)")
+	case len(cfg.PlanReview.Reviewers) == 0:
+		parts = append(parts, "plan review: full council")
+	default:
+		parts = append(parts, "plan review: "+strings.Join(cfg.PlanReview.Reviewers, ","))
+	}
+	return "claude-hooks active: " + strings.Join(parts, ", ")
+}
+
+// projectLanguages returns the languages with a marker file at root. JS is
+// left out of a TS project, which has a package.json too.
+func projectLanguages(root string) []language {
+	var found []language
+	for _, l := range languages {
+		if l.fileType == "javascript" && slices.ContainsFunc(found, func(f language) bool { return f.fileType == "typescript" }) {
+			continue
+		}
+		for _, marker := range l.markers {
+			if _, err := os.Stat(filepath.Join(root, marker)); err == nil {
+				found = append(found, l)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// languageChecks names what runs after an edit of a fileType file: the
+// command of a hook configured in its place, or what the built-in hook does
+func languageChecks(cfg *config.Config, fileType string) []string {
+	if custom, ok := cfg.Hooks[fileType]; ok && (strings.TrimSpace(custom.Command) != "" || len(custom.Make) > 0) {
+		return []string{commandChecks(custom)}
+	}
+	switch fileType {
+	case "go":
+		var checks []string
+		if cfg.Go.Vet {
+			checks = append(checks, "vet")
+		}
+		if cfg.Go.Tests.Enabled {
+			checks = append(checks, "tests")
+		}
+		if cfg.Go.Dependents.Enabled {
+			checks = append(checks, "dependents")
+		}
+		if cfg.Go.API.Enabled {
+			checks = append(checks, "api")
+		}
+		if cfg.Go.Errors.Enabled {
+			checks = append(checks, "errors")
+		}
+		if len(cfg.Go.Builds) > 0 {
+			checks = append(checks, fmt.Sprintf("%d builds", len(cfg.Go.Builds)))
+		}
+		return checks
+	case "rust":
+		return []string{"fmt", "clippy", "tests"}
+	case "proto":
+		if cfg.Proto.Generate != "" {
+			return []string{"buf", "generate"}
+		}
+		return []string{"buf"}
+	}
+	// The built-in TS and JS hook leaves them to the policy and edit checks
+	return []string{"none"}
+}
+
+// commandChecks names what a configured command hook runs
+func commandChecks(h config.CommandHookConfig) string {
+	if len(h.Make) > 0 {
+		return "make " + strings.Join(h.Make, " ")
+	}
+	if fields := strings.Fields(h.Command); len(fields) > 0 {
+		return filepath.Base(fields[0])
+	}
+	return "none"
+}
+
+// contentRuleNames returns the names of the built-in content rules cfg
+// doesn't disable
+func contentRuleNames(cfg config.ContentConfig) []string {
+	var names []string
+	for _, r := range policy.ContentRules {
+		if !slices.Contains(cfg.Disabled, r.Name) && !slices.Contains(names, r.Name) {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
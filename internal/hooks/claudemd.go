@@ -0,0 +1,262 @@
+package hooks
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/policy"
+)
+
+// Markers of the section of CLAUDE.md that sync-claude-md manages; text
+// outside them is the user's and left alone
+const (
+	ClaudeMDBegin = "<!-- claude-hooks:begin (generated by `claude-hook sync-claude-md`; edits here are replaced) -->"
+	ClaudeMDEnd   = "<!-- claude-hooks:end -->"
+)
+
+// claudeMDBeginPrefix finds the begin marker whatever its explanation says
+const claudeMDBeginPrefix = "<!-- claude-hooks:begin"
+
+// ClaudeMDSection describes, as Markdown between the markers, what the hooks
+// enforce in the project at root under cfg: the commands they block, what
+// runs after each edit, the code they reject, and what's checked before a
+// turn ends. It changes only when the enforcement does.
+func ClaudeMDSection(cfg *config.Config, root string) string {
+	var b strings.Builder
+	b.WriteString(ClaudeMDBegin + "\n")
+	b.WriteString("## Enforced by claude-hooks\n\n")
+	b.WriteString("Hooks check these on every tool call; follow them up front instead of waiting for a block.\n")
+
+	commands := []string{
+		"`mysql`, `mysqldump`, and `mariadb` are blocked; use the project's database code instead.",
+		"Commits directly to `main` or `master` are blocked; commit on a feature branch.",
+	}
+	for _, r := range cfg.Bash.Rules {
+		decision := r.Decision
+		if decision == "" {
+			decision = policy.Deny
+		}
+		if decision == policy.Allow {
+			continue
+		}
+		name := r.Name
+		if name == "" {
+			name = strings.Join(r.Commands, ",")
+		}
+		line := fmt.Sprintf("`%s` (%s)", name, decision)
+		if r.Reason != "" {
+			line += ": " + r.Reason
+		}
+		commands = append(commands, line)
+	}
+	if w := cfg.Guardrails.DeployWindow; w.Hours != "" {
+		days := "every day"
+		if len(w.Days) > 0 {
+			days = strings.Join(w.Days, ", ")
+		}
+		commands = append(commands, fmt.Sprintf("Deploy-like commands only run %s, %s.", w.Hours, days))
+	}
+	if cfg.Push.Enabled {
+		commands = append(commands, "`git push` scans the outgoing commits for secrets, oversized files, and disallowed paths.")
+	}
+	if cfg.Profile == "unattended" {
+		commands = append(commands, "The unattended profile denies what can't be undone, like recursive deletes and force pushes.")
+	}
+	if policy.Applies(cfg.Policy, "pre-bash") {
+		commands = append(commands, "Project policies under `policy` decide commands too.")
+	}
+	writeList(&b, "Commands", commands)
+
+	var agents []string
+	if a := cfg.Agents.Subagents; a.ReadOnly() {
+		agents = append(agents, "Subagents may only run commands that read, without redirections to files.")
+	}
+	if !cfg.Agents.Subagents.CanCommit() {
+		agents = append(agents, "Subagents can't commit, push, merge, rebase, or tag.")
+	}
+	if !cfg.Agents.Subagents.CanEdit() {
+		agents = append(agents, "Subagents can't edit files.")
+	}
+	if len(cfg.Agents.Types) > 0 {
+		agents = append(agents, fmt.Sprintf("These subagent types have rights of their own under `agents.types`: %s.", strings.Join(slices.Sorted(maps.Keys(cfg.Agents.Types)), ", ")))
+	}
+	writeList(&b, "Subagents", agents)
+
+	var edits []string
+	for _, l := range projectLanguages(root) {
+		if checks := languageChecks(cfg, l.fileType); !slices.Equal(checks, []string{"none"}) {
+			edits = append(edits, fmt.Sprintf("%s: %s.", l.name, editChecks(l.fileType, checks)))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Hooks)) {
+		if !slices.ContainsFunc(languages, func(l language) bool { return l.fileType == name }) {
+			edits = append(edits, fmt.Sprintf("%s files: `%s` must pass.", name, commandChecks(cfg.Hooks[name])))
+		}
+	}
+	if cfg.EditorConfig.Enabled {
+		edits = append(edits, "Files follow the project's .editorconfig (indentation, final newline, trailing whitespace).")
+	}
+	if cfg.Encoding.Enabled {
+		edits = append(edits, "Files keep their encoding and line endings.")
+	}
+	if cfg.MergeArtifacts.Enabled {
+		edits = append(edits, "No merge conflict markers.")
+	}
+	if cfg.Placeholders.Enabled {
+		edits = append(edits, "No placeholders: no comments standing in for elided code, unimplemented stubs, empty catch blocks, or lorem ipsum.")
+	}
+	if cfg.Images.Enabled {
+		image := "Container images are pinned to a version tag, not latest"
+		if cfg.Images.RequireDigest {
+			image += ", and to an @sha256 digest"
+		}
+		if len(cfg.Images.Registries) > 0 {
+			image += fmt.Sprintf(", and come from %s", strings.Join(cfg.Images.Registries, ", "))
+		}
+		edits = append(edits, image+".")
+	}
+	if cfg.Semgrep.Enabled && len(cfg.Semgrep.Rulesets) > 0 {
+		edits = append(edits, fmt.Sprintf("Edited files pass Semgrep (%s).", strings.Join(cfg.Semgrep.Rulesets, ", ")))
+	}
+	if cfg.Security.Gosec {
+		edits = append(edits, "Edited Go packages pass gosec.")
+	}
+	if cfg.Security.Audit {
+		edits = append(edits, "Dependencies added to a manifest have no known vulnerabilities.")
+	}
+	if cfg.Duplicates.Enabled {
+		edits = append(edits, "No copies of code that exists elsewhere in the project.")
+	}
+	if cfg.Complexity.Enabled {
+		edits = append(edits, "Functions stay within the complexity limits.")
+	}
+	writeList(&b, "After each edit", edits)
+
+	var content []string
+	if cfg.Content.Enabled {
+		var seen []string
+		for _, r := range policy.ContentRules {
+			if !slices.Contains(cfg.Content.Disabled, r.Name) && !slices.Contains(seen, r.Name) {
+				seen = append(seen, r.Name)
+				content = append(content, fmt.Sprintf("`%s`: %s.", r.Name, r.Reason))
+			}
+		}
+		if cfg.Content.MaxDeletedLines > 0 && !slices.Contains(cfg.Content.Disabled, "large-deletion") {
+			content = append(content, fmt.Sprintf("`large-deletion`: one edit removes at most %d lines of a file.", cfg.Content.MaxDeletedLines))
+		}
+		if cfg.Content.MaxWriteSize > 0 {
+			content = append(content, fmt.Sprintf("Writes create files of at most %d bytes, and no binary or base64 content outside `content.binary_paths`.", cfg.Content.MaxWriteSize))
+		}
+	}
+	writeList(&b, "Code edits can't introduce", content)
+
+	var stop []string
+	if cfg.Go.Tests.Enabled {
+		stop = append(stop, "The Go tests of the packages edited this session pass.")
+	}
+	if cfg.Guardrails.Changelog.Enabled {
+		stop = append(stop, fmt.Sprintf("Changes come with an entry in %s.", changelogName(cfg.Guardrails.Changelog)))
+	}
+	writeList(&b, "Before the turn ends", stop)
+
+	b.WriteString("\n" + ClaudeMDEnd + "\n")
+	return b.String()
+}
+
+// editChecks describes the checks of a language's built-in hook
+func editChecks(fileType string, checks []string) string {
+	var parts []string
+	for _, c := range checks {
+		switch {
+		case fileType == "go" && c == "vet":
+			parts = append(parts, "edited packages build and pass `go vet`")
+		case fileType == "rust" && c == "fmt":
+			parts = append(parts, "`cargo fmt --check` passes")
+		case fileType == "rust" && c == "clippy":
+			parts = append(parts, "`cargo clippy` has no warnings")
+		case c == "tests":
+			parts = append(parts, "their tests pass")
+		case c == "dependents":
+			parts = append(parts, "the packages importing them still build and pass their tests")
+		case c == "api":
+			parts = append(parts, "the exported API stays compatible")
+		case c == "errors":
+			parts = append(parts, "errors from other packages are wrapped with context, not discarded into `_`, and `errors.New` messages say what failed")
+		case strings.HasSuffix(c, " builds"):
+			parts = append(parts, fmt.Sprintf("they vet under the %s configured", c))
+		case c == "buf":
+			parts = append(parts, "the code generated from the protos is up to date, compiles, and implements every RPC")
+		case c == "generate":
+			parts = append(parts, "it's regenerated first")
+		default:
+			parts = append(parts, "`"+c+"` passes")
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func changelogName(c config.ChangelogConfig) string {
+	if c.File != "" {
+		return "`" + c.File + "`"
+	}
+	return "the changelog"
+}
+
+func writeList(b *strings.Builder, heading string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n### %s\n\n", heading)
+	for _, item := range items {
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+}
+
+// SyncClaudeMD writes section into the CLAUDE.md at path, replacing the
+// managed section it has or appending one, and reports whether the file
+// changed. A missing file is created.
+func SyncClaudeMD(path, section string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	updated, err := ReplaceClaudeMDSection(string(data), section)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if updated == string(data) {
+		return false, nil
+	}
+	if err := os.WriteFile(path, []byte(updated), 0o644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReplaceClaudeMDSection returns doc with its managed section replaced by
+// section, or with section appended when it has none
+func ReplaceClaudeMDSection(doc, section string) (string, error) {
+	start := strings.Index(doc, claudeMDBeginPrefix)
+	if start < 0 {
+		switch {
+		case doc == "":
+			return section, nil
+		case !strings.HasSuffix(doc, "\n"):
+			doc += "\n"
+		}
+		return doc + "\n" + section, nil
+	}
+	end := strings.Index(doc[start:], ClaudeMDEnd)
+	if end < 0 {
+		return "", fmt.Errorf("the claude-hooks section has no end marker %s", ClaudeMDEnd)
+	}
+	end += start + len(ClaudeMDEnd)
+	if end < len(doc) && doc[end] == '\n' {
+		end++
+	}
+	return doc[:start] + section + doc[end:], nil
+}
@@ -0,0 +1,67 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func TestClaudeMDSection(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "go.mod", "module example.com/m\n")
+	cfg := config.Default()
+	cfg.Go.Vet = true
+	cfg.Go.Tests.Enabled = true
+	cfg.Content.Disabled = []string{"lint-suppression"}
+	cfg.Bash.Rules = []config.CommandRule{
+		{Name: "prod-psql", Commands: []string{"psql"}, Decision: "ask", Reason: "Production needs a human."},
+		{Name: "local", Decision: "allow"},
+	}
+
+	section := ClaudeMDSection(cfg, root)
+	for _, want := range []string{
+		"`prod-psql` (ask): Production needs a human.",
+		"Go: edited packages build and pass `go vet`; their tests pass.",
+		"`todo-panic`: placeholder panics",
+		"The Go tests of the packages edited this session pass.",
+	} {
+		if !strings.Contains(section, want) {
+			t.Errorf("Expected the section to contain %q, got:\n%s", want, section)
+		}
+	}
+	for _, unwanted := range []string{"lint-suppression", "`local`", "Subagents"} {
+		if strings.Contains(section, unwanted) {
+			t.Errorf("Expected the section not to mention %q, got:\n%s", unwanted, section)
+		}
+	}
+}
+
+func TestSyncClaudeMD(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "CLAUDE.md")
+	section := ClaudeMDBegin + "\nrules v1\n" + ClaudeMDEnd + "\n"
+
+	if changed, err := SyncClaudeMD(path, section); err != nil || !changed {
+		t.Fatalf("Expected a missing CLAUDE.md to be created, got %v, %v", changed, err)
+	}
+	writeTestFile(t, dir, "CLAUDE.md", "# Project\n\nOurs.\n\n"+section+"\n## After\n")
+	if changed, err := SyncClaudeMD(path, section); err != nil || changed {
+		t.Errorf("Expected an up-to-date section to be left alone, got %v, %v", changed, err)
+	}
+
+	if _, err := SyncClaudeMD(path, strings.Replace(section, "v1", "v2", 1)); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "# Project\n\nOurs.\n\n" + ClaudeMDBegin + "\nrules v2\n" + ClaudeMDEnd + "\n\n## After\n"; string(data) != want {
+		t.Errorf("Expected only the section to change, got:\n%s", data)
+	}
+
+	writeTestFile(t, dir, "CLAUDE.md", ClaudeMDBegin+"\nno end\n")
+	if _, err := SyncClaudeMD(path, section); err == nil {
+		t.Error("Expected a section without an end marker to fail")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
 )
 
 // isCommandAvailable checks if a command is available on the system PATH
@@ -14,28 +16,87 @@ func isCommandAvailable(name string) bool {
 }
 
 // findModuleRoot finds the Go module root directory by looking for go.mod
-// starting from the given directory and walking up the parent directories
+// starting from the given directory and walking up the parent directories.
+// A directory reached through a symlink, with no go.mod above where it's
+// linked from, is looked up from its target.
 func findModuleRoot(dir string) (string, error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return "", err
 	}
+	if root := moduleRootOf(absDir); root != "" {
+		return root, nil
+	}
+	if real := state.RealPath(absDir); real != absDir {
+		if root := moduleRootOf(real); root != "" {
+			return root, nil
+		}
+	}
 
-	current := absDir
+	// No go.mod found, return the original directory
+	return absDir, nil
+}
+
+// moduleRootOf returns the closest directory of dir or its parents with a
+// go.mod, or ""
+func moduleRootOf(dir string) string {
+	current := dir
 	for {
 		goMod := filepath.Join(current, "go.mod")
 		if _, err := os.Stat(goMod); err == nil {
-			return current, nil
+			return current
 		}
 
 		parent := filepath.Dir(current)
 		if parent == current {
 			// Reached the root directory
-			break
+			return ""
 		}
 		current = parent
 	}
+}
 
-	// No go.mod found, return the original directory
-	return absDir, nil
+// goModFlags returns the -mod flag go commands need in moduleRoot:
+// -mod=vendor when its dependencies are vendored, by the module itself or by
+// the go.work workspace it's in. go only picks vendoring on its own for
+// modules outside workspaces, and a -mod in GOFLAGS overrides even that.
+func goModFlags(moduleRoot string) []string {
+	if vendored(moduleRoot) {
+		return []string{"-mod=vendor"}
+	}
+	return nil
+}
+
+// vendored reports whether the dependencies of the module at moduleRoot come
+// from a vendor directory: the workspace's in workspace mode, or its own
+func vendored(moduleRoot string) bool {
+	dir := moduleRoot
+	if work := goWorkDir(moduleRoot); work != "" {
+		dir = work
+	}
+	_, err := os.Stat(filepath.Join(dir, "vendor", "modules.txt"))
+	return err == nil
+}
+
+// goWorkDir returns the directory of the go.work file the module at
+// moduleRoot is built in, following GOWORK like go does, or "" outside
+// workspace mode
+func goWorkDir(moduleRoot string) string {
+	switch work := os.Getenv("GOWORK"); work {
+	case "off":
+		return ""
+	case "", "auto":
+	default:
+		return filepath.Dir(work)
+	}
+	for current := moduleRoot; ; {
+		if _, err := os.Stat(filepath.Join(current, "go.work")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return ""
+		}
+		current = parent
+	}
 }
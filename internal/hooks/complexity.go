@@ -0,0 +1,351 @@
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// ComplexFunction is an edited function over the complexity or length limits
+type ComplexFunction struct {
+	File         string // Absolute
+	Line, Column int    // Start of the function, 1-based
+	End          int    // Last line of the function
+	Name         string
+	Complexity   int // Cyclomatic complexity, 0 when within the limit
+	Lines        int // Length in lines, 0 when within the limit
+	Limits       config.ComplexityConfig
+}
+
+func (f ComplexFunction) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", f.File, f.Line, f.Column, f.Diagnostic().Message)
+}
+
+// Diagnostic reports the function on its first line; severity is "error" when
+// complexity.block is set
+func (f ComplexFunction) Diagnostic() Diagnostic {
+	var over []string
+	if f.Complexity > 0 {
+		over = append(over, fmt.Sprintf("cyclomatic complexity %d (limit %d)", f.Complexity, f.Limits.MaxCyclomatic))
+	}
+	if f.Lines > 0 {
+		over = append(over, fmt.Sprintf("%d lines (limit %d)", f.Lines, f.Limits.MaxLines))
+	}
+	severity := "warning"
+	if f.Limits.Block {
+		severity = "error"
+	}
+	return Diagnostic{
+		File:     f.File,
+		Line:     f.Line,
+		Column:   f.Column,
+		Severity: severity,
+		Message:  fmt.Sprintf("%s has %s; split it into smaller functions", f.Name, strings.Join(over, " and ")),
+		Source:   "complexity",
+	}
+}
+
+// FindComplexFunctions returns the functions in the edited files that are over
+// complexity.max_cyclomatic or complexity.max_lines and overlap lines changed
+// since HEAD, so functions that were already too big aren't reported on every
+// edit. Go is measured natively; TypeScript and JavaScript use the project's
+// eslint and are skipped when it isn't installed. Test files are left out:
+// table-driven tests are long by design. It is a no-op unless
+// complexity.enabled is set.
+func FindComplexFunctions(files []string, verbose bool) ([]ComplexFunction, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	cfg, err := config.Load(filepath.Dir(files[0]))
+	if err != nil {
+		return nil, err
+	}
+	limits := cfg.Complexity
+	if !limits.Enabled || (limits.MaxCyclomatic <= 0 && limits.MaxLines <= 0) {
+		return nil, nil
+	}
+
+	var found []ComplexFunction
+	var scripts []string
+	for _, f := range files {
+		if isTestFile(f) {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(f)) {
+		case ".go":
+			funcs, err := goComplexity(f, limits)
+			if err != nil {
+				vlog.Printf(verbose, "⏭️  Skipping complexity check of %s: %v\n", f, err)
+				continue
+			}
+			found = append(found, funcs...)
+		case ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs":
+			scripts = append(scripts, f)
+		}
+	}
+
+	if len(scripts) > 0 {
+		funcs, err := eslintComplexity(scripts, limits, verbose)
+		switch {
+		case errors.Is(err, exec.ErrNotFound):
+			vlog.Printf(verbose, "⏭️  Skipping complexity check: eslint not installed\n")
+		case err != nil:
+			return nil, err
+		default:
+			found = append(found, funcs...)
+		}
+	}
+
+	ranges := make(map[string][][2]int)
+	var edited []ComplexFunction
+	for _, f := range found {
+		changed, ok := ranges[f.File]
+		if !ok {
+			changed = changedRanges(state.ProjectRoot(filepath.Dir(f.File)), f.File)
+			ranges[f.File] = changed
+		}
+		if overlaps(changed, f.Line, f.End) {
+			edited = append(edited, f)
+		}
+	}
+	return edited, nil
+}
+
+// isTestFile reports whether path follows the Go or JavaScript test naming
+func isTestFile(path string) bool {
+	base := strings.ToLower(filepath.Base(path))
+	if strings.HasSuffix(base, "_test.go") {
+		return true
+	}
+	return strings.Contains(base, ".test.") || strings.Contains(base, ".spec.")
+}
+
+// goComplexity returns the functions in a Go file over the limits
+func goComplexity(file string, limits config.ComplexityConfig) ([]ComplexFunction, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	var funcs []ComplexFunction
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		start := fset.Position(fn.Pos())
+		end := fset.Position(fn.End()).Line
+		f := ComplexFunction{
+			File:       file,
+			Line:       start.Line,
+			Column:     start.Column,
+			End:        end,
+			Name:       goFuncName(fn),
+			Complexity: cyclomatic(fn),
+			Lines:      end - start.Line + 1,
+			Limits:     limits,
+		}
+		if f, ok := overLimits(f); ok {
+			funcs = append(funcs, f)
+		}
+	}
+	return funcs, nil
+}
+
+// overLimits zeroes the measurements within the limits and reports whether
+// any are left
+func overLimits(f ComplexFunction) (ComplexFunction, bool) {
+	if f.Limits.MaxCyclomatic <= 0 || f.Complexity <= f.Limits.MaxCyclomatic {
+		f.Complexity = 0
+	}
+	if f.Limits.MaxLines <= 0 || f.Lines <= f.Limits.MaxLines {
+		f.Lines = 0
+	}
+	return f, f.Complexity > 0 || f.Lines > 0
+}
+
+// goFuncName names methods after their receiver type, as gocyclo does
+func goFuncName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return "function " + fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	switch r := recv.(type) {
+	case *ast.IndexExpr:
+		recv = r.X
+	case *ast.IndexListExpr:
+		recv = r.X
+	}
+	if ident, ok := recv.(*ast.Ident); ok {
+		return fmt.Sprintf("method (%s).%s", ident.Name, fn.Name.Name)
+	}
+	return "method " + fn.Name.Name
+}
+
+// cyclomatic counts decision points the way gocyclo does: one plus each if,
+// for, range, non-default case, and && or ||, including those in closures
+func cyclomatic(fn *ast.FuncDecl) int {
+	complexity := 1
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// eslintResult is the part of eslint's JSON formatter output the check reads
+type eslintResult struct {
+	FilePath string `json:"filePath"`
+	Messages []struct {
+		RuleID  string `json:"ruleId"`
+		Message string `json:"message"`
+		Line    int    `json:"line"`
+		Column  int    `json:"column"`
+		EndLine int    `json:"endLine"`
+	} `json:"messages"`
+}
+
+var (
+	// "Function 'load' has a complexity of 23. Maximum allowed is 15."
+	eslintComplexityPattern = regexp.MustCompile(`^(.+?) has a complexity of (\d+)`)
+	// "Arrow function has too many lines (12). Maximum allowed is 0."
+	eslintFunctionPattern = regexp.MustCompile(`^(.+?) has too many lines`)
+)
+
+// eslintComplexity runs the project's eslint over the files with its
+// complexity rule set to the limit. max-lines-per-function at 0 reports every
+// function with its full span, which gives the lengths and lets each
+// complexity finding (reported on the function's head) be matched to the
+// function around it.
+func eslintComplexity(files []string, limits config.ComplexityConfig, verbose bool) ([]ComplexFunction, error) {
+	eslintBin := filepath.Join("node_modules", ".bin", "eslint")
+	groups := make(map[string][]string)
+	var dirs []string
+	for _, f := range files {
+		dir := findUp(filepath.Dir(f), state.ProjectRoot(filepath.Dir(f)), eslintBin)
+		if dir == "" {
+			continue
+		}
+		if _, ok := groups[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		groups[dir] = append(groups[dir], f)
+	}
+	if len(dirs) == 0 {
+		return nil, exec.ErrNotFound
+	}
+
+	args := []string{"--format", "json", "--rule", `{"max-lines-per-function": ["warn", {"max": 0}]}`}
+	if limits.MaxCyclomatic > 0 {
+		args = append(args, "--rule", fmt.Sprintf(`{"complexity": ["warn", %d]}`, limits.MaxCyclomatic))
+	}
+
+	var funcs []ComplexFunction
+	for _, dir := range dirs {
+		vlog.Printf(verbose, "🔧 eslint complexity (in %s)\n", dir)
+		cmd := proc.Command(filepath.Join(dir, eslintBin), append(args, groups[dir]...)...)
+		cmd.Dir = dir
+		output, err := cmd.Output()
+		// Exit status 1 means findings, which the project's own rules can add
+		var exitErr *exec.ExitError
+		if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+			return nil, fmt.Errorf("running eslint: %w\n%s", err, strings.TrimSpace(string(output)))
+		}
+
+		var results []eslintResult
+		if err := json.Unmarshal(output, &results); err != nil {
+			return nil, fmt.Errorf("parsing eslint output: %w", err)
+		}
+		for _, r := range results {
+			funcs = append(funcs, eslintFunctions(absPath(dir, r.FilePath), r, limits)...)
+		}
+	}
+	return funcs, nil
+}
+
+// eslintFunctions combines one file's function spans and complexity findings
+// into the functions over the limits
+func eslintFunctions(file string, result eslintResult, limits config.ComplexityConfig) []ComplexFunction {
+	var all []ComplexFunction
+	for _, m := range result.Messages {
+		if m.RuleID != "max-lines-per-function" {
+			continue
+		}
+		name := "function"
+		if match := eslintFunctionPattern.FindStringSubmatch(m.Message); match != nil {
+			name = lowerFirst(match[1])
+		}
+		end := max(m.EndLine, m.Line)
+		all = append(all, ComplexFunction{
+			File: file, Line: m.Line, Column: m.Column, End: end,
+			Name: name, Lines: end - m.Line + 1, Limits: limits,
+		})
+	}
+
+	for _, m := range result.Messages {
+		match := eslintComplexityPattern.FindStringSubmatch(m.Message)
+		if m.RuleID != "complexity" || match == nil {
+			continue
+		}
+		complexity, _ := strconv.Atoi(match[2])
+		// The innermost function containing the head is the one reported
+		inner := -1
+		for i, f := range all {
+			if f.Line <= m.Line && m.Line <= f.End && (inner < 0 || f.End-f.Line < all[inner].End-all[inner].Line) {
+				inner = i
+			}
+		}
+		if inner < 0 {
+			all = append(all, ComplexFunction{File: file, Line: m.Line, Column: m.Column, End: m.Line, Name: lowerFirst(match[1]), Limits: limits})
+			inner = len(all) - 1
+		}
+		all[inner].Complexity = complexity
+	}
+
+	var funcs []ComplexFunction
+	for _, f := range all {
+		if f, ok := overLimits(f); ok {
+			funcs = append(funcs, f)
+		}
+	}
+	return funcs
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,80 @@
+package hooks
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+// TestFindComplexFunctions expects only the oversized function the edit
+// touched to be reported, not the one that was already there
+func TestFindComplexFunctions(t *testing.T) {
+	repo := t.TempDir()
+	writeFile := func(path, content string) { writeTestFile(t, repo, path, content) }
+	run := func(args ...string) { runInDir(t, repo, args...) }
+
+	branchy := func(name string) string {
+		return "func " + name + "(n int) int {\n" + strings.Repeat("\tif n > 0 && n < 10 {\n\t\tn++\n\t}\n", 3) + "\treturn n\n}\n"
+	}
+	writeFile(".claude-hooks.yaml", "complexity:\n  enabled: true\n  max_cyclomatic: 5\n  max_lines: 10\n")
+	writeFile("a.go", "package a\n\n"+branchy("Old"))
+	run("git", "init", "-q")
+	run("git", "add", ".")
+	run("git", "commit", "-q", "-m", "init")
+	writeFile("a.go", "package a\n\n"+branchy("Old")+"\n"+branchy("New")+"\nfunc Small() {}\n")
+	writeFile("a_test.go", "package a\n\n"+branchy("TestLong"))
+
+	funcs, err := FindComplexFunctions([]string{filepath.Join(repo, "a.go"), filepath.Join(repo, "a_test.go")}, false)
+	if err != nil {
+		t.Fatalf("FindComplexFunctions failed: %v", err)
+	}
+	if len(funcs) != 1 {
+		t.Fatalf("Expected only New reported, got %v", funcs)
+	}
+	f := funcs[0]
+	if f.Name != "function New" || f.Line != 16 || f.Complexity != 7 || f.Lines != 12 {
+		t.Errorf("Expected New on line 16 with complexity 7 and 12 lines, got %+v", f)
+	}
+
+	d := f.Diagnostic()
+	if d.Severity != "warning" || !strings.Contains(d.Message, "cyclomatic complexity 7 (limit 5)") || !strings.Contains(d.Message, "12 lines (limit 10)") {
+		t.Errorf("Expected a warning naming both limits, got %+v", d)
+	}
+}
+
+func TestFindComplexFunctionsDisabled(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "a.go", "package a\n\nfunc A() {\n"+strings.Repeat("\t_ = 1\n", 200)+"}\n")
+
+	funcs, err := FindComplexFunctions([]string{filepath.Join(repo, "a.go")}, false)
+	if err != nil || len(funcs) != 0 {
+		t.Errorf("Expected nothing without complexity.enabled, got %v, %v", funcs, err)
+	}
+}
+
+func TestEslintFunctions(t *testing.T) {
+	var result eslintResult
+	output := `{"filePath": "/p/a.ts", "messages": [
+		{"ruleId": "max-lines-per-function", "message": "Function 'outer' has too many lines (40). Maximum allowed is 0.", "line": 1, "column": 1, "endLine": 40},
+		{"ruleId": "max-lines-per-function", "message": "Arrow function has too many lines (5). Maximum allowed is 0.", "line": 10, "column": 9, "endLine": 14},
+		{"ruleId": "complexity", "message": "Arrow function has a complexity of 12. Maximum allowed is 10.", "line": 10, "column": 20},
+		{"ruleId": "no-unused-vars", "message": "'x' is defined but never used.", "line": 2, "column": 7}
+	]}`
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := eslintFunctions("/p/a.ts", result, config.ComplexityConfig{MaxCyclomatic: 10, MaxLines: 30})
+	if len(funcs) != 2 {
+		t.Fatalf("Expected outer (too long) and the arrow function (too complex), got %+v", funcs)
+	}
+	if funcs[0].Name != "function 'outer'" || funcs[0].Lines != 40 || funcs[0].Complexity != 0 {
+		t.Errorf("Expected outer reported for its 40 lines only, got %+v", funcs[0])
+	}
+	if funcs[1].Name != "arrow function" || funcs[1].Complexity != 12 || funcs[1].Lines != 0 || funcs[1].End != 14 {
+		t.Errorf("Expected the inner arrow function reported for complexity 12, got %+v", funcs[1])
+	}
+}
@@ -0,0 +1,344 @@
+package hooks
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// Checks of go.concurrency
+const (
+	ConcurrencyLoopCapture  = "loop-capture"  // Goroutines using loop variables before Go 1.22
+	ConcurrencyLockCopy     = "lock-copy"     // Mutexes and WaitGroups passed by value
+	ConcurrencyWaitGroupAdd = "waitgroup-add" // WaitGroup.Add inside the goroutine it counts
+	ConcurrencySleepSync    = "sleep-sync"    // time.Sleep waiting for goroutines in tests
+)
+
+// syncTypes are the types of package sync that must not be copied after use
+var syncTypes = []string{"Mutex", "RWMutex", "WaitGroup", "Once", "Cond", "Map", "Pool"}
+
+// ConcurrencyHazard is a concurrency bug pattern in an edited Go file
+type ConcurrencyHazard struct {
+	File         string // Absolute
+	Line, Column int    // 1-based
+	Check        string
+	Message      string
+}
+
+func (h ConcurrencyHazard) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", h.File, h.Line, h.Column, h.Message, h.Check)
+}
+
+// Diagnostic reports the hazard as a warning
+func (h ConcurrencyHazard) Diagnostic() Diagnostic {
+	return Diagnostic{File: h.File, Line: h.Line, Column: h.Column, Severity: "warning", Message: h.Message, Source: "go-concurrency"}
+}
+
+// FindConcurrencyHazards returns the concurrency bugs agents commonly write,
+// on lines of the edited Go files changed since HEAD: goroutines capturing
+// loop variables in modules before Go 1.22, locks copied by value,
+// WaitGroup.Add inside the goroutine, and time.Sleep in tests. It's a quick
+// syntactic pass, without types, so it only warns. It is a no-op when
+// go.concurrency.enabled is turned off.
+func FindConcurrencyHazards(files []string, verbose bool) ([]ConcurrencyHazard, error) {
+	var found []ConcurrencyHazard
+	for _, f := range files {
+		if filepath.Ext(f) != ".go" {
+			continue
+		}
+		cfg, err := config.Load(filepath.Dir(f))
+		if err != nil {
+			return found, err
+		}
+		if !cfg.Go.Concurrency.Enabled {
+			continue
+		}
+		hazards, err := concurrencyHazards(f, cfg.Go.Concurrency.Disabled)
+		if err != nil {
+			vlog.Printf(verbose, "⏭️  Skipping concurrency check of %s: %v\n", f, err)
+			continue
+		}
+		changed := changedRanges(state.ProjectRoot(filepath.Dir(f)), f)
+		for _, h := range hazards {
+			if overlaps(changed, h.Line, h.Line) {
+				found = append(found, h)
+			}
+		}
+	}
+	return found, nil
+}
+
+// concurrencyHazards returns the hazards of a Go file
+func concurrencyHazards(file string, disabled []string) ([]ConcurrencyHazard, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	imports := importNames(parsed)
+
+	var hazards []ConcurrencyHazard
+	add := func(node ast.Node, check, message string) {
+		if slices.Contains(disabled, check) {
+			return
+		}
+		pos := fset.Position(node.Pos())
+		hazards = append(hazards, ConcurrencyHazard{File: file, Line: pos.Line, Column: pos.Column, Check: check, Message: message})
+	}
+
+	perIteration := goVersionAtLeast(filepath.Dir(file), 22)
+	locks := lockTypes(parsed, imports["sync"])
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		for _, field := range fieldsOf(fn.Recv, fn.Type.Params) {
+			if name := lockName(field.Type, imports["sync"], locks); name != "" {
+				add(field.Type, ConcurrencyLockCopy, fmt.Sprintf("%s is passed by value, which copies its lock; use a pointer", name))
+			}
+		}
+		if fn.Body == nil {
+			continue
+		}
+		waited := waitedOn(fn.Body)
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.RangeStmt:
+				if !perIteration {
+					loopCaptures(n.Body, identNames(n.Key, n.Value), add)
+				}
+			case *ast.ForStmt:
+				if !perIteration {
+					if init, ok := n.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+						loopCaptures(n.Body, identNames(init.Lhs...), add)
+					}
+				}
+			case *ast.GoStmt:
+				if lit, ok := n.Call.Fun.(*ast.FuncLit); ok {
+					waitGroupAdds(lit.Body, waited, add)
+				}
+			case *ast.CallExpr:
+				if isTestFile(file) && callName(n) == imports["time"]+".Sleep" {
+					add(n, ConcurrencySleepSync, "time.Sleep waits a guessed time for goroutines, which is flaky under load; wait on a channel, a sync.WaitGroup, or poll the condition with a deadline")
+				}
+			}
+			return true
+		})
+	}
+	return hazards, nil
+}
+
+// loopCaptures reports go statements in a loop body whose function literal
+// uses the loop's variables, which the iterations share before Go 1.22,
+// unless the body copies them first ("v := v") or passes them as arguments
+func loopCaptures(body *ast.BlockStmt, vars []string, add func(ast.Node, string, string)) {
+	for _, stmt := range body.List {
+		if assign, ok := stmt.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+			for i, lhs := range assign.Lhs {
+				if i < len(assign.Rhs) && slices.Contains(vars, identName(lhs)) && identName(lhs) == identName(assign.Rhs[i]) {
+					vars = slices.DeleteFunc(slices.Clone(vars), func(v string) bool { return v == identName(lhs) })
+				}
+			}
+		}
+	}
+	if len(vars) == 0 {
+		return
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		goStmt, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		lit, ok := goStmt.Call.Fun.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+		params := identNames(paramNames(lit.Type.Params)...)
+		for _, v := range vars {
+			if !slices.Contains(params, v) && uses(lit.Body, v) {
+				add(goStmt, ConcurrencyLoopCapture, fmt.Sprintf("the goroutine uses the loop variable %s, which every iteration shares before Go 1.22; pass it as an argument, or copy it with %s := %s", v, v, v))
+			}
+		}
+		return false
+	})
+}
+
+// waitGroupAdds reports Add calls in a goroutine on what its enclosing
+// function waits on: Wait can run before the goroutine gets to Add
+func waitGroupAdds(body *ast.BlockStmt, waited []string, add func(ast.Node, string, string)) {
+	for _, stmt := range body.List {
+		expr, ok := stmt.(*ast.ExprStmt)
+		if !ok {
+			continue
+		}
+		call, ok := expr.X.(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Add" && slices.Contains(waited, exprString(sel.X)) {
+			add(call, ConcurrencyWaitGroupAdd, fmt.Sprintf("%s.Add runs inside the goroutine, so %s.Wait can return before it does; call Add before the go statement", exprString(sel.X), exprString(sel.X)))
+		}
+	}
+}
+
+// waitedOn returns what the function calls Wait on, like "wg" or "s.wg"
+func waitedOn(body *ast.BlockStmt) []string {
+	var waited []string
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Wait" && len(call.Args) == 0 {
+				waited = append(waited, exprString(sel.X))
+			}
+		}
+		return true
+	})
+	return waited
+}
+
+// lockTypes returns the struct types of the file holding a sync lock by value
+func lockTypes(file *ast.File, syncName string) []string {
+	var locks []string
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			if slices.ContainsFunc(st.Fields.List, func(f *ast.Field) bool { return isSyncType(f.Type, syncName) }) {
+				locks = append(locks, ts.Name.Name)
+			}
+		}
+	}
+	return locks
+}
+
+// lockName returns how a parameter type that copies a lock is written, or ""
+func lockName(t ast.Expr, syncName string, locks []string) string {
+	if isSyncType(t, syncName) {
+		return exprString(t)
+	}
+	if ident, ok := t.(*ast.Ident); ok && slices.Contains(locks, ident.Name) {
+		return ident.Name
+	}
+	return ""
+}
+
+func isSyncType(t ast.Expr, syncName string) bool {
+	sel, ok := t.(*ast.SelectorExpr)
+	if !ok || syncName == "" {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	return ok && x.Name == syncName && slices.Contains(syncTypes, sel.Sel.Name)
+}
+
+// goVersionAtLeast reports whether the module dir is in declares Go 1.minor
+// or later; without a go.mod it assumes so, as the toolchain would
+func goVersionAtLeast(dir string, minor int) bool {
+	root := moduleRootOf(dir)
+	if root == "" {
+		return true
+	}
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return true
+	}
+	for line := range strings.Lines(string(data)) {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "go ")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(strings.TrimSpace(rest), ".")
+		if len(parts) < 2 {
+			return true
+		}
+		n, err := strconv.Atoi(parts[1])
+		return err != nil || parts[0] != "1" || n >= minor
+	}
+	return false // go.mod without a go directive means Go 1.16
+}
+
+func fieldsOf(lists ...*ast.FieldList) []*ast.Field {
+	var fields []*ast.Field
+	for _, l := range lists {
+		if l != nil {
+			fields = append(fields, l.List...)
+		}
+	}
+	return fields
+}
+
+func paramNames(params *ast.FieldList) []ast.Expr {
+	var names []ast.Expr
+	for _, f := range fieldsOf(params) {
+		for _, n := range f.Names {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// identNames returns the names of the identifiers among exprs, but _
+func identNames(exprs ...ast.Expr) []string {
+	var names []string
+	for _, e := range exprs {
+		if name := identName(e); name != "" && name != "_" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func identName(e ast.Expr) string {
+	if ident, ok := e.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// uses reports whether node refers to name
+func uses(node ast.Node, name string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			found = found || uses(n.X, name)
+			return false // The selected field or method isn't the variable
+		case *ast.Ident:
+			found = found || n.Name == name
+		}
+		return !found
+	})
+	return found
+}
+
+// exprString renders simple expressions like wg, s.wg, and sync.Mutex
+func exprString(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	case *ast.UnaryExpr:
+		return e.Op.String() + exprString(e.X)
+	}
+	return ""
+}
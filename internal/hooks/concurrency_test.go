@@ -0,0 +1,77 @@
+package hooks
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindConcurrencyHazards(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "go.mod", "module example.com/app\n\ngo 1.21\n")
+	writeTestFile(t, root, "worker/worker.go", `package worker
+
+import "sync"
+
+type Pool struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (p Pool) Count() int { return p.count }
+
+func Run(items []string, wg sync.WaitGroup) {
+	for _, item := range items {
+		go func() {
+			wg.Add(1)
+			defer wg.Done()
+			process(item)
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		i := i
+		go func() { process(i) }()
+	}
+	for _, item := range items {
+		go func(item string) { process(item) }(item)
+	}
+	wg.Wait()
+}
+
+func process(any) {}
+`)
+	writeTestFile(t, root, "worker/worker_test.go", "package worker\n\nimport (\n\t\"testing\"\n\t\"time\"\n)\n\nfunc TestRun(t *testing.T) {\n\ttime.Sleep(10 * time.Millisecond)\n}\n")
+	files := []string{filepath.Join(root, "worker", "worker.go"), filepath.Join(root, "worker", "worker_test.go")}
+
+	hazards, err := FindConcurrencyHazards(files, false)
+	if err != nil {
+		t.Fatalf("FindConcurrencyHazards failed: %v", err)
+	}
+	var got []string
+	for _, h := range hazards {
+		got = append(got, fmt.Sprintf("%s:%d %s", filepath.Base(h.File), h.Line, h.Check))
+	}
+	want := []string{
+		"worker.go:10 lock-copy",
+		"worker.go:12 lock-copy",
+		"worker.go:14 loop-capture",
+		"worker.go:15 waitgroup-add",
+		"worker_test.go:9 sleep-sync",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Unexpected hazards:\n%s", strings.Join(got, "\n"))
+	}
+
+	// Go 1.22 gives each iteration its own variables
+	writeTestFile(t, root, "go.mod", "module example.com/app\n\ngo 1.22\n")
+	writeTestFile(t, root, ".claude-hooks.yaml", "go:\n  concurrency:\n    disabled: [sleep-sync]\n")
+	if hazards, err := FindConcurrencyHazards(files, false); err != nil || len(hazards) != 3 {
+		t.Fatalf("Expected no loop-capture or sleep-sync, got %+v (%v)", hazards, err)
+	}
+
+	writeTestFile(t, root, ".claude-hooks.yaml", "go:\n  concurrency:\n    enabled: false\n")
+	if hazards, err := FindConcurrencyHazards(files, false); err != nil || len(hazards) != 0 {
+		t.Fatalf("Expected nothing when disabled, got %+v (%v)", hazards, err)
+	}
+}
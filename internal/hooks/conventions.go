@@ -0,0 +1,199 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/brianleishman/claude-hooks/internal/editorconfig"
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// golangciConfigs are golangci-lint's config files, in its lookup order
+var golangciConfigs = []string{".golangci.yml", ".golangci.yaml", ".golangci.toml", ".golangci.json"}
+
+// prettierConfigs are prettier's config files that are data rather than code
+var prettierConfigs = []string{".prettierrc", ".prettierrc.json", ".prettierrc.yaml", ".prettierrc.yml"}
+
+// prettierCodeConfigs are prettier's config files that are code, named but not read
+var prettierCodeConfigs = []string{"prettier.config.js", "prettier.config.mjs", "prettier.config.cjs", ".prettierrc.js", ".prettierrc.mjs", ".prettierrc.cjs"}
+
+// eslintConfigs are eslint's flat and legacy config files
+var eslintConfigs = []string{"eslint.config.js", "eslint.config.mjs", "eslint.config.cjs", "eslint.config.ts",
+	".eslintrc.js", ".eslintrc.cjs", ".eslintrc.json", ".eslintrc.yml", ".eslintrc.yaml", ".eslintrc"}
+
+// conventionsFile holds the conventions a session was already given
+const conventionsFile = "conventions.json"
+
+// NewConventions returns the Conventions of files that session s wasn't given
+// yet, and remembers them as given. Without a session every one is new.
+func NewConventions(files []string, s Session) ([]string, error) {
+	lines := Conventions(files)
+	if s.ID == "" || len(lines) == 0 {
+		return lines, nil
+	}
+	path, err := state.SessionPath(s.Dir, s.ID, s.TranscriptPath, conventionsFile)
+	if err != nil {
+		return lines, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), goTestLockTimeout)
+	defer cancel()
+	unlock, err := state.Lock(ctx, path+".lock")
+	if err != nil {
+		return lines, err
+	}
+	defer unlock()
+
+	var given []string
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &given) // A corrupt file starts over
+	}
+	lines = slices.DeleteFunc(lines, func(line string) bool { return slices.Contains(given, line) })
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	data, err := json.MarshalIndent(append(given, lines...), "", "  ")
+	if err != nil {
+		return lines, fmt.Errorf("encoding conventions: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return lines, fmt.Errorf("writing conventions: %w", err)
+	}
+	return lines, nil
+}
+
+// Conventions returns the formatting and lint conventions of the project that
+// apply to files, one line each, for Claude to follow before editing them:
+// their .editorconfig properties, the Go formatter and golangci-lint linters,
+// and prettier's options and eslint's config for JavaScript and TypeScript
+func Conventions(files []string) []string {
+	var lines []string
+	add := func(line string) {
+		if line != "" && !slices.Contains(lines, line) {
+			lines = append(lines, line)
+		}
+	}
+
+	for _, f := range files {
+		root := state.ProjectRoot(filepath.Dir(f))
+		rel := relTo(root, f)
+
+		if props, err := editorconfig.Resolve(f); err == nil && len(props) > 0 {
+			var settings []string
+			for _, k := range slices.Sorted(maps.Keys(props)) {
+				settings = append(settings, k+" = "+props[k])
+			}
+			add(fmt.Sprintf("%s: %s (.editorconfig).", rel, strings.Join(settings, ", ")))
+		}
+
+		switch filepath.Ext(f) {
+		case ".go":
+			linters, path := golangciLinters(filepath.Dir(f), root)
+			if slices.Contains(linters, "gofumpt") {
+				add("Go code is formatted with gofumpt: gofmt plus no empty lines at the start or end of blocks, no empty line after a function signature, and 0o octal literals.")
+			} else {
+				add("Go code is formatted with gofmt (tabs) and goimports (standard library imports grouped first).")
+			}
+			if path != "" {
+				add(fmt.Sprintf("golangci-lint (%s) runs: %s.", relTo(root, path), strings.Join(linters, ", ")))
+			}
+		case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts":
+			add(prettierConvention(filepath.Dir(f), root))
+			for _, name := range eslintConfigs {
+				if dir := findUp(filepath.Dir(f), root, name); dir != "" {
+					add(fmt.Sprintf("eslint (%s) checks JavaScript and TypeScript after each edit; don't disable its rules inline.", relTo(root, filepath.Join(dir, name))))
+					break
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// golangciLinters returns the linters and formatters the closest golangci-lint
+// config enables, with its path, or no path without one
+func golangciLinters(dir, root string) ([]string, string) {
+	for _, name := range golangciConfigs {
+		found := findUp(dir, root, name)
+		if found == "" {
+			continue
+		}
+		path := filepath.Join(found, name)
+		var cfg struct {
+			Linters struct {
+				Enable []string `yaml:"enable" json:"enable"`
+			} `yaml:"linters" json:"linters"`
+			Formatters struct {
+				Enable []string `yaml:"enable" json:"enable"`
+			} `yaml:"formatters" json:"formatters"`
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || filepath.Ext(name) == ".toml" {
+			return nil, path // Named, not read
+		}
+		_ = yaml.Unmarshal(data, &cfg) // JSON is YAML too
+		linters := slices.Concat(cfg.Linters.Enable, cfg.Formatters.Enable)
+		slices.Sort(linters)
+		return slices.Compact(linters), path
+	}
+	return nil, ""
+}
+
+// prettierConvention describes the closest prettier config, or "" without one
+func prettierConvention(dir, root string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		for _, name := range prettierConfigs {
+			if data, err := os.ReadFile(filepath.Join(d, name)); err == nil {
+				var options map[string]any
+				_ = yaml.Unmarshal(data, &options)
+				return describePrettier(relTo(root, filepath.Join(d, name)), options)
+			}
+		}
+		for _, name := range prettierCodeConfigs {
+			if _, err := os.Stat(filepath.Join(d, name)); err == nil {
+				return fmt.Sprintf("prettier (%s) formats JavaScript and TypeScript; follow its options.", relTo(root, filepath.Join(d, name)))
+			}
+		}
+		if data, err := os.ReadFile(filepath.Join(d, "package.json")); err == nil {
+			var pkg struct {
+				Prettier map[string]any `json:"prettier"`
+			}
+			if json.Unmarshal(data, &pkg) == nil && pkg.Prettier != nil {
+				return describePrettier(relTo(root, filepath.Join(d, "package.json"))+" prettier", pkg.Prettier)
+			}
+		}
+		if d == root || filepath.Dir(d) == d {
+			return ""
+		}
+	}
+}
+
+// describePrettier lists prettier's options from source
+func describePrettier(source string, options map[string]any) string {
+	if len(options) == 0 {
+		return fmt.Sprintf("prettier (%s) formats JavaScript and TypeScript with its default options.", source)
+	}
+	var settings []string
+	for _, k := range slices.Sorted(maps.Keys(options)) {
+		if k == "overrides" || k == "plugins" || k == "$schema" {
+			continue
+		}
+		settings = append(settings, fmt.Sprintf("%s: %v", k, options[k]))
+	}
+	return fmt.Sprintf("prettier (%s) formats JavaScript and TypeScript with %s.", source, strings.Join(settings, ", "))
+}
+
+// relTo returns path relative to root, or path itself outside it
+func relTo(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
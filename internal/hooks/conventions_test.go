@@ -0,0 +1,49 @@
+package hooks
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConventions(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	repo := t.TempDir()
+	writeTestFile(t, repo, ".git/HEAD", "ref: refs/heads/main\n")
+	writeTestFile(t, repo, ".editorconfig", "root = true\n\n[*]\ninsert_final_newline = true\n\n[*.ts]\nindent_style = space\nindent_size = 2\n")
+	writeTestFile(t, repo, ".golangci.yml", "version: \"2\"\nlinters:\n  enable: [errcheck, revive]\nformatters:\n  enable: [gofumpt]\n")
+	writeTestFile(t, repo, "web/package.json", `{"name": "web", "prettier": {"semi": false, "singleQuote": true}}`)
+	writeTestFile(t, repo, "web/eslint.config.js", "export default []\n")
+	goFile := filepath.Join(repo, "cmd/main.go")
+	tsFile := filepath.Join(repo, "web/src/app.ts")
+
+	got := strings.Join(Conventions([]string{goFile, tsFile}), "\n")
+	for _, want := range []string{
+		"cmd/main.go: insert_final_newline = true (.editorconfig).",
+		"formatted with gofumpt",
+		"golangci-lint (.golangci.yml) runs: errcheck, gofumpt, revive.",
+		"web/src/app.ts: indent_size = 2, indent_style = space, insert_final_newline = true (.editorconfig).",
+		"prettier (web/package.json prettier) formats JavaScript and TypeScript with semi: false, singleQuote: true.",
+		"eslint (web/eslint.config.js)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected the conventions to contain %q, got:\n%s", want, got)
+		}
+	}
+
+	session := Session{Dir: repo, ID: "s1"}
+	first, err := NewConventions([]string{goFile}, session)
+	if err != nil || len(first) != 3 {
+		t.Fatalf("Expected the Go file's three conventions, got %v (%v)", first, err)
+	}
+	if again, err := NewConventions([]string{goFile}, session); err != nil || len(again) != 0 {
+		t.Errorf("Expected conventions to be given once per session, got %v (%v)", again, err)
+	}
+	// The TypeScript file only adds what the session hasn't seen
+	if ts, _ := NewConventions([]string{tsFile}, session); len(ts) != 3 || strings.Contains(strings.Join(ts, "\n"), "gofumpt") {
+		t.Errorf("Expected only the TypeScript conventions, got %v", ts)
+	}
+	if other, _ := NewConventions([]string{goFile}, Session{Dir: repo, ID: "s2"}); len(other) != 3 {
+		t.Errorf("Expected another session to get the conventions again, got %v", other)
+	}
+}
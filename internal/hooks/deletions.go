@@ -0,0 +1,138 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// DetectDeletions lists tracked files that are deleted in the working tree of the
+// git repository at repoRoot, as absolute paths
+func DetectDeletions(repoRoot string) ([]string, error) {
+	cmd := proc.Command("git", "-C", repoRoot, "status", "--porcelain")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running git status: %w", err)
+	}
+
+	_, deleted, _ := parseGitStatus(output)
+	for i, f := range deleted {
+		deleted[i] = filepath.Join(repoRoot, f)
+	}
+	return deleted, nil
+}
+
+// DetectChangedFiles lists files that are added, modified, renamed, or untracked
+// in the working tree of the git repository at repoRoot, as absolute paths.
+// Deleted files are left to DetectDeletions.
+func DetectChangedFiles(repoRoot string) ([]string, error) {
+	cmd := proc.Command("git", "-C", repoRoot, "status", "--porcelain", "--untracked-files=all")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running git status: %w", err)
+	}
+
+	var changed []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if len(line) < 4 {
+			continue
+		}
+		status, path := line[:2], line[3:]
+		if strings.Contains(status, "D") {
+			continue
+		}
+		if _, to, ok := strings.Cut(path, " -> "); ok {
+			path = to
+		}
+		changed = append(changed, filepath.Join(repoRoot, unquoteGitPath(path)))
+	}
+	return changed, nil
+}
+
+// CheckDeletedFiles re-verifies the packages that lost files. A package that still
+// has Go files is built and tested; a package that is now gone entirely has its
+// importers checked instead, since they're the code that can still break.
+func CheckDeletedFiles(deleted []string, verbose bool) error {
+	var pkgDirs []string
+	goneDirs := make(map[string]bool)
+
+	for _, f := range deleted {
+		if filepath.Ext(f) != ".go" {
+			continue
+		}
+		dir := filepath.Dir(f)
+		if hasGoFiles(dir) {
+			if !slices.Contains(pkgDirs, dir) {
+				pkgDirs = append(pkgDirs, dir)
+			}
+		} else {
+			goneDirs[dir] = true
+		}
+	}
+
+	for dir := range goneDirs {
+		importers, err := importersOfDir(dir, verbose)
+		if err != nil {
+			return err
+		}
+		for _, importer := range importers {
+			if !slices.Contains(pkgDirs, importer) {
+				pkgDirs = append(pkgDirs, importer)
+			}
+		}
+	}
+
+	if len(pkgDirs) == 0 {
+		return nil
+	}
+
+	vlog.Printf(verbose, "🗑️  Re-verifying %d package(s) affected by deleted files\n", len(pkgDirs))
+
+	if err := verifyGoPackages(pkgDirs, verbose); err != nil {
+		return fmt.Errorf("packages no longer build or pass tests after deleting files:\n%w", err)
+	}
+	return nil
+}
+
+// importersOfDir returns the directories of packages importing the package that lived in dir
+func importersOfDir(dir string, verbose bool) ([]string, error) {
+	// The directory may be gone, so find the module from its closest existing parent
+	existing := dir
+	for {
+		if _, err := os.Stat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+
+	moduleRoot, err := findModuleRoot(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	importPath, err := importPathForDir(moduleRoot, dir)
+	if err != nil {
+		vlog.Printf(verbose, "⚠️  Skipping importer check for %s: %v\n", dir, err)
+		return nil, nil
+	}
+
+	rels, err := findImporters(moduleRoot, importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0, len(rels))
+	for _, rel := range rels {
+		dirs = append(dirs, filepath.Join(moduleRoot, strings.TrimPrefix(rel, "./")))
+	}
+	return dirs, nil
+}
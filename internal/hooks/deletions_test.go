@@ -0,0 +1,57 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckDeletedFiles(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "go.mod", "module example.com/deleted\n\ngo 1.25\n")
+	writeTestFile(t, repo, "calc/add.go", "package calc\n\nfunc Add(a, b int) int { return a + b }\n")
+	writeTestFile(t, repo, "calc/double.go", "package calc\n\nfunc Double(a int) int { return Add(a, a) }\n")
+	writeTestFile(t, repo, "util/util.go", "package util\n\nfunc Hello() string { return \"hi\" }\n")
+	writeTestFile(t, repo, "app/app.go", "package app\n\nimport \"example.com/deleted/util\"\n\nvar Greeting = util.Hello()\n")
+	runInDir(t, repo, "git", "init", "-q")
+	runInDir(t, repo, "git", "add", ".")
+	runInDir(t, repo, "git", "commit", "-q", "-m", "init")
+
+	t.Run("package still has files", func(t *testing.T) {
+		addFile := filepath.Join(repo, "calc/add.go")
+		if err := os.Remove(addFile); err != nil {
+			t.Fatalf("Failed to delete file: %v", err)
+		}
+		t.Cleanup(func() { runInDir(t, repo, "git", "checkout", "--", ".") })
+
+		deleted, err := DetectDeletions(repo)
+		if err != nil {
+			t.Fatalf("DetectDeletions failed: %v", err)
+		}
+		if len(deleted) != 1 || deleted[0] != addFile {
+			t.Fatalf("Expected %s to be detected as deleted, got %v", addFile, deleted)
+		}
+
+		err = CheckDeletedFiles(deleted, false)
+		if err == nil || !strings.Contains(err.Error(), "undefined: Add") {
+			t.Fatalf("Expected build failure for calc, got: %v", err)
+		}
+	})
+
+	t.Run("package removed entirely", func(t *testing.T) {
+		runInDir(t, repo, "git", "rm", "-q", "-r", "util")
+		t.Cleanup(func() { runInDir(t, repo, "git", "reset", "-q", "--hard") })
+
+		err := CheckDeletedFiles([]string{filepath.Join(repo, "util/util.go")}, false)
+		if err == nil || !strings.Contains(err.Error(), "example.com/deleted/util") {
+			t.Fatalf("Expected importer of removed package to fail, got: %v", err)
+		}
+	})
+
+	t.Run("non-Go files are ignored", func(t *testing.T) {
+		if err := CheckDeletedFiles([]string{filepath.Join(repo, "README.md")}, false); err != nil {
+			t.Fatalf("Expected no error for non-Go deletion, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,310 @@
+package hooks
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is a single line-level finding reported by a hook
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`             // 1-based
+	Column   int    `json:"column,omitempty"` // 1-based, 0 when unknown
+	Severity string `json:"severity"`         // "error", "warning", or "info"
+	Message  string `json:"message"`
+	Source   string `json:"source"`        // Hook or tool that produced the finding
+	Fix      string `json:"fix,omitempty"` // Unified diff fixing it, when a tool made one
+}
+
+var (
+	// file.go:12:5: message (go, golangci-lint, eslint unix formatter)
+	colonDiagnosticPattern = regexp.MustCompile(`^\s*([^\s:][^:]*):(\d+)(?::(\d+))?:\s*(.+)$`)
+	// file.ts(12,5): error TS2322: message (tsc)
+	parenDiagnosticPattern = regexp.MustCompile(`^\s*([^\s(][^(]*)\((\d+),(\d+)\):\s*(.+)$`)
+)
+
+// ParseDiagnostics extracts file/line findings from tool output, ignoring lines
+// that don't point at a location
+func ParseDiagnostics(output, source string) []Diagnostic {
+	var diags []Diagnostic
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "vet: ")
+
+		match := colonDiagnosticPattern.FindStringSubmatch(line)
+		if match == nil {
+			match = parenDiagnosticPattern.FindStringSubmatch(line)
+		}
+		if match == nil {
+			continue
+		}
+
+		lineNum, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		message := strings.TrimSpace(match[4])
+
+		severity := "error"
+		lower := strings.ToLower(message)
+		if strings.HasPrefix(lower, "warning") {
+			severity = "warning"
+		} else if strings.HasPrefix(lower, "info") || strings.HasPrefix(lower, "note") {
+			severity = "info"
+		}
+
+		diags = append(diags, Diagnostic{
+			File:     strings.TrimSpace(match[1]),
+			Line:     lineNum,
+			Column:   column,
+			Severity: severity,
+			Message:  message,
+			Source:   source,
+		})
+	}
+
+	return diags
+}
+
+// ResolvePaths rewrites file locations in tool output that are relative to dir
+// (the directory the tool ran in) to absolute paths, so findings can be mapped
+// back to files no matter where they are read
+func ResolvePaths(output, dir string) string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimPrefix(strings.TrimSpace(line), "vet: ")
+		match := colonDiagnosticPattern.FindStringSubmatch(trimmed)
+		if match == nil {
+			match = parenDiagnosticPattern.FindStringSubmatch(trimmed)
+		}
+		if match == nil {
+			continue
+		}
+		// go test logs paths relative to the package, not dir; leave what doesn't resolve
+		file := strings.TrimSpace(match[1])
+		abs := filepath.Join(dir, file)
+		if _, err := os.Stat(abs); filepath.IsAbs(file) || err != nil {
+			continue
+		}
+		lines[i] = strings.Replace(line, file, abs, 1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Supported diagnostic output formats
+const (
+	DiagnosticsFormatReviewdog = "rdjsonl" // reviewdog Diagnostic JSON lines
+	DiagnosticsFormatLSP       = "lsp"     // array of LSP publishDiagnostics notifications
+)
+
+// DefaultDiagnosticsFile is the well-known file name editor integrations read
+func DefaultDiagnosticsFile(format string) string {
+	if format == DiagnosticsFormatLSP {
+		return "diagnostics.lsp.json"
+	}
+	return "diagnostics.rdjsonl"
+}
+
+// WriteDiagnostics writes diags to path in the given format, replacing any previous run's
+// findings. files lists every file that was checked so LSP clients can clear stale
+// diagnostics for files that are now clean.
+func WriteDiagnostics(path, format string, files []string, diags []Diagnostic) error {
+	var data []byte
+	var err error
+
+	switch format {
+	case DiagnosticsFormatReviewdog:
+		data, err = encodeReviewdog(diags)
+	case DiagnosticsFormatLSP:
+		data, err = encodeLSP(files, diags)
+	default:
+		return fmt.Errorf("unknown diagnostics format %q (expected %s or %s)", format, DiagnosticsFormatReviewdog, DiagnosticsFormatLSP)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename so editors never read a partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing diagnostics: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing diagnostics file: %w", err)
+	}
+	return nil
+}
+
+type rdPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+}
+
+type rdDiagnostic struct {
+	Message  string `json:"message"`
+	Location struct {
+		Path  string `json:"path"`
+		Range struct {
+			Start rdPosition `json:"start"`
+		} `json:"range"`
+	} `json:"location"`
+	Severity string `json:"severity"`
+	Source   struct {
+		Name string `json:"name"`
+	} `json:"source"`
+	Suggestions []rdSuggestion `json:"suggestions,omitempty"`
+}
+
+type rdSuggestion struct {
+	Range struct {
+		Start rdPosition `json:"start"`
+		End   rdPosition `json:"end"`
+	} `json:"range"`
+	Text string `json:"text"`
+}
+
+// rdSuggestions turns a diagnostic's patch into reviewdog's line replacements
+func rdSuggestions(fix string) []rdSuggestion {
+	var suggestions []rdSuggestion
+	for _, h := range splitHunks(fix) {
+		var s rdSuggestion
+		start, _ := h.span()
+		// The range ends at the start of the first line after the old lines
+		s.Range.Start = rdPosition{Line: start, Column: 1}
+		s.Range.End = rdPosition{Line: start + h.oldLines, Column: 1}
+		s.Text = h.newText
+		suggestions = append(suggestions, s)
+	}
+	return suggestions
+}
+
+func encodeReviewdog(diags []Diagnostic) ([]byte, error) {
+	var sb strings.Builder
+	for _, d := range diags {
+		var rd rdDiagnostic
+		rd.Message = d.Message
+		rd.Location.Path = d.File
+		rd.Location.Range.Start = rdPosition{Line: d.Line, Column: d.Column}
+		rd.Severity = strings.ToUpper(d.Severity)
+		rd.Source.Name = "claude-hooks/" + d.Source
+		rd.Suggestions = rdSuggestions(d.Fix)
+
+		line, err := json.Marshal(rd)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling diagnostic: %w", err)
+		}
+		sb.Write(line)
+		sb.WriteString("\n")
+	}
+	return []byte(sb.String()), nil
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspDiagnostic struct {
+	Range struct {
+		Start lspPosition `json:"start"`
+		End   lspPosition `json:"end"`
+	} `json:"range"`
+	Severity int      `json:"severity"` // 1 error, 2 warning, 3 information
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+	Data     *lspData `json:"data,omitempty"`
+}
+
+// lspData is kept by clients and sent back with code action requests
+type lspData struct {
+	Fix string `json:"fix"` // Unified diff fixing the finding
+}
+
+// LSPNotification is a textDocument/publishDiagnostics notification
+type LSPNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  struct {
+		URI         string          `json:"uri"`
+		Diagnostics []lspDiagnostic `json:"diagnostics"`
+	} `json:"params"`
+}
+
+func encodeLSP(files []string, diags []Diagnostic) ([]byte, error) {
+	data, err := json.MarshalIndent(LSPNotifications(files, diags), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling diagnostics: %w", err)
+	}
+	return data, nil
+}
+
+// LSPNotifications groups diags into one publishDiagnostics notification per
+// file. Every file in files gets one, empty if it is clean, so clients clear
+// findings that were fixed.
+func LSPNotifications(files []string, diags []Diagnostic) []LSPNotification {
+	byFile := make(map[string][]lspDiagnostic)
+	var order []string
+
+	// Seed with every checked file so clean files publish an empty list
+	for _, f := range files {
+		uri := FileURI(f)
+		if _, ok := byFile[uri]; !ok {
+			byFile[uri] = []lspDiagnostic{}
+			order = append(order, uri)
+		}
+	}
+
+	for _, d := range diags {
+		var ld lspDiagnostic
+		// LSP positions are 0-based
+		ld.Range.Start = lspPosition{Line: max(d.Line-1, 0), Character: max(d.Column-1, 0)}
+		ld.Range.End = ld.Range.Start
+		ld.Severity = lspSeverity(d.Severity)
+		ld.Source = "claude-hooks/" + d.Source
+		ld.Message = d.Message
+		if d.Fix != "" {
+			ld.Data = &lspData{Fix: d.Fix}
+		}
+
+		uri := FileURI(d.File)
+		if _, ok := byFile[uri]; !ok {
+			order = append(order, uri)
+		}
+		byFile[uri] = append(byFile[uri], ld)
+	}
+
+	notifications := make([]LSPNotification, 0, len(order))
+	for _, uri := range order {
+		var n LSPNotification
+		n.JSONRPC = "2.0"
+		n.Method = "textDocument/publishDiagnostics"
+		n.Params.URI = uri
+		n.Params.Diagnostics = byFile[uri]
+		notifications = append(notifications, n)
+	}
+	return notifications
+}
+
+func lspSeverity(severity string) int {
+	switch severity {
+	case "warning":
+		return 2
+	case "info":
+		return 3
+	default:
+		return 1
+	}
+}
+
+// FileURI returns the file:// URI LSP clients use for path
+func FileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return "file://" + filepath.ToSlash(path)
+}
@@ -0,0 +1,138 @@
+package hooks
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	output := `# example.com/pkg
+internal/foo/foo.go:12:5: undefined: bar
+internal/foo/foo.go:20: warning: unused variable
+src/app.ts(3,7): error TS2322: Type 'string' is not assignable to type 'number'.
+FAIL	example.com/pkg [build failed]`
+
+	diags := ParseDiagnostics(output, "go")
+	if len(diags) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %d: %+v", len(diags), diags)
+	}
+
+	if d := diags[0]; d.File != "internal/foo/foo.go" || d.Line != 12 || d.Column != 5 || d.Severity != "error" || d.Message != "undefined: bar" {
+		t.Errorf("Unexpected first diagnostic: %+v", d)
+	}
+	if d := diags[1]; d.Line != 20 || d.Column != 0 || d.Severity != "warning" {
+		t.Errorf("Unexpected second diagnostic: %+v", d)
+	}
+	if d := diags[2]; d.File != "src/app.ts" || d.Line != 3 || d.Column != 7 {
+		t.Errorf("Unexpected tsc diagnostic: %+v", d)
+	}
+}
+
+func TestWriteDiagnosticsReviewdog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.rdjsonl")
+	diags := []Diagnostic{{File: "a.go", Line: 2, Column: 3, Severity: "error", Message: "boom", Source: "go"}}
+
+	if err := WriteDiagnostics(path, DiagnosticsFormatReviewdog, []string{"a.go"}, diags); err != nil {
+		t.Fatalf("WriteDiagnostics failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var rd rdDiagnostic
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(data))), &rd); err != nil {
+		t.Fatalf("Output is not a reviewdog diagnostic: %v\n%s", err, data)
+	}
+	if rd.Location.Path != "a.go" || rd.Location.Range.Start.Line != 2 || rd.Severity != "ERROR" {
+		t.Errorf("Unexpected reviewdog diagnostic: %s", data)
+	}
+	if len(rd.Suggestions) != 0 {
+		t.Errorf("Expected no suggestions without a fix, got %+v", rd.Suggestions)
+	}
+}
+
+func TestWriteDiagnosticsReviewdogSuggestions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.rdjsonl")
+	fix := "--- a.go\n+++ a.go\n@@ -2,2 +2,2 @@\n-\tos.Remove(x)\n+\t_ = os.Remove(x)\n }\n"
+	diags := []Diagnostic{{File: "a.go", Line: 2, Severity: "error", Message: "unchecked", Source: "go", Fix: fix}}
+
+	if err := WriteDiagnostics(path, DiagnosticsFormatReviewdog, []string{"a.go"}, diags); err != nil {
+		t.Fatalf("WriteDiagnostics failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var rd rdDiagnostic
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(data))), &rd); err != nil {
+		t.Fatalf("Output is not a reviewdog diagnostic: %v\n%s", err, data)
+	}
+	if len(rd.Suggestions) != 1 {
+		t.Fatalf("Expected one suggestion, got %s", data)
+	}
+	s := rd.Suggestions[0]
+	if s.Range.Start.Line != 2 || s.Range.End.Line != 4 || s.Text != "\t_ = os.Remove(x)\n}\n" {
+		t.Errorf("Expected lines 2-3 replaced, got %+v", s)
+	}
+}
+
+func TestWriteDiagnosticsLSPClearsCleanFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.json")
+	dirty := filepath.Join(dir, "dirty.go")
+	clean := filepath.Join(dir, "clean.go")
+	diags := []Diagnostic{{File: dirty, Line: 1, Column: 1, Severity: "warning", Message: "meh", Source: "go"}}
+
+	if err := WriteDiagnostics(path, DiagnosticsFormatLSP, []string{dirty, clean}, diags); err != nil {
+		t.Fatalf("WriteDiagnostics failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	var notifications []LSPNotification
+	if err := json.Unmarshal(data, &notifications); err != nil {
+		t.Fatalf("Output is not LSP JSON: %v", err)
+	}
+	if len(notifications) != 2 {
+		t.Fatalf("Expected a notification per checked file, got %d", len(notifications))
+	}
+
+	first := notifications[0].Params
+	if len(first.Diagnostics) != 1 || first.Diagnostics[0].Range.Start.Line != 0 || first.Diagnostics[0].Severity != 2 {
+		t.Errorf("Expected 0-based warning diagnostic, got %+v", first.Diagnostics)
+	}
+	if first.Diagnostics[0].Data != nil {
+		t.Errorf("Expected no data without a fix, got %+v", first.Diagnostics[0].Data)
+	}
+	if len(notifications[1].Params.Diagnostics) != 0 {
+		t.Errorf("Expected clean file to publish no diagnostics, got %+v", notifications[1].Params.Diagnostics)
+	}
+}
+
+func TestResolvePaths(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "pkg/a.go", "package pkg\n")
+
+	output := "# example.com/pkg\nvet: ./pkg/a.go:2:12: declared and not used: x\n    a_test.go:9: not resolvable from dir"
+	resolved := ResolvePaths(output, dir)
+
+	diags := ParseDiagnostics(resolved, "go")
+	if len(diags) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %+v", diags)
+	}
+	if want := filepath.Join(dir, "pkg", "a.go"); diags[0].File != want || diags[0].Line != 2 || diags[0].Column != 12 {
+		t.Errorf("Expected vet finding at %s:2:12, got %+v", want, diags[0])
+	}
+	if diags[1].File != "a_test.go" {
+		t.Errorf("Expected an unresolvable path to be left alone, got %s", diags[1].File)
+	}
+}
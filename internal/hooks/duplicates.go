@@ -0,0 +1,269 @@
+package hooks
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vcs"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// Clone is a block of an edited file that duplicates code elsewhere
+type Clone struct {
+	File       string // Edited file, absolute
+	Start, End int    // Lines of the copy, 1-based and inclusive
+	Original   string // File holding the other copy, absolute
+	OrigStart  int
+	OrigEnd    int
+}
+
+func (c Clone) String() string {
+	return fmt.Sprintf("%s:%d-%d duplicates %s:%d-%d", c.File, c.Start, c.End, c.Original, c.OrigStart, c.OrigEnd)
+}
+
+// Diagnostic reports the clone as a warning on the copy
+func (c Clone) Diagnostic() Diagnostic {
+	return Diagnostic{
+		File:     c.File,
+		Line:     c.Start,
+		Severity: "warning",
+		Message:  fmt.Sprintf("lines %d-%d duplicate %s:%d-%d; reuse or extract the existing code instead of copying it", c.Start, c.End, c.Original, c.OrigStart, c.OrigEnd),
+		Source:   "duplicates",
+	}
+}
+
+// duplicateFormats maps file extensions to the clone detector that handles them
+var duplicateFormats = map[string]string{
+	".go": "dupl",
+	".ts": "jscpd", ".tsx": "jscpd", ".js": "jscpd", ".jsx": "jscpd",
+}
+
+// FindDuplicates runs the clone detectors over the repositories of the edited
+// files and returns the clones that overlap lines changed in them, so code that
+// was already duplicated isn't reported on every edit. It is a no-op unless
+// duplicates.enabled is set, and detectors that aren't installed are skipped.
+func FindDuplicates(files []string, verbose bool) ([]Clone, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	cfg, err := config.Load(filepath.Dir(files[0]))
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Duplicates.Enabled {
+		return nil, nil
+	}
+
+	type target struct {
+		root, tool string
+	}
+	edited := make(map[target][]string)
+	var targets []target
+	for _, f := range files {
+		tool := duplicateFormats[strings.ToLower(filepath.Ext(f))]
+		if tool == "" {
+			continue
+		}
+		t := target{state.ProjectRoot(filepath.Dir(f)), tool}
+		if _, ok := edited[t]; !ok {
+			targets = append(targets, t)
+		}
+		edited[t] = append(edited[t], f)
+	}
+
+	var clones []Clone
+	for _, t := range targets {
+		var found []Clone
+		var err error
+		switch t.tool {
+		case "dupl":
+			found, err = runDupl(t.root, cfg.Duplicates.MinTokens, verbose)
+		case "jscpd":
+			found, err = runJscpd(t.root, cfg.Duplicates.MinTokens, verbose)
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			vlog.Printf(verbose, "⏭️  Skipping duplicate check: %s not installed\n", t.tool)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		clones = append(clones, editedClones(t.root, found, edited[t])...)
+	}
+	return clones, nil
+}
+
+// editedClones orients each pair so the copy is in an edited file and keeps
+// the ones overlapping lines changed there
+func editedClones(root string, pairs []Clone, edited []string) []Clone {
+	ranges := make(map[string][][2]int)
+	var clones []Clone
+	for _, pair := range pairs {
+		for _, c := range []Clone{pair, {File: pair.Original, Start: pair.OrigStart, End: pair.OrigEnd, Original: pair.File, OrigStart: pair.Start, OrigEnd: pair.End}} {
+			if !slices.Contains(edited, c.File) {
+				continue
+			}
+			changed, ok := ranges[c.File]
+			if !ok {
+				changed = changedRanges(root, c.File)
+				ranges[c.File] = changed
+			}
+			if overlaps(changed, c.Start, c.End) && !slices.Contains(clones, c) {
+				clones = append(clones, c)
+				break // One report per pair
+			}
+		}
+	}
+	return clones
+}
+
+// changedRanges returns the line ranges of file that differ from the last
+// commit; nil means the whole file is new (untracked, or outside a repository)
+func changedRanges(root, file string) [][2]int {
+	repo := vcs.Detect(root)
+	if repo == nil || !repo.Tracked(file) {
+		return nil
+	}
+	output, err := repo.Diff(0, file)
+	if err != nil {
+		return nil
+	}
+	ranges := [][2]int{}
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		if !strings.HasPrefix(scanner.Text(), "@@") {
+			continue
+		}
+		start := hunkStartLine(scanner.Text())
+		count := 1
+		if _, rest, ok := strings.Cut(scanner.Text(), "+"); ok {
+			span, _, _ := strings.Cut(rest, " ")
+			if _, n, ok := strings.Cut(span, ","); ok {
+				count, _ = strconv.Atoi(n)
+			}
+		}
+		if count > 0 {
+			ranges = append(ranges, [2]int{start, start + count - 1})
+		}
+	}
+	return ranges
+}
+
+// overlaps reports whether lines start-end touch a changed range; nil ranges
+// mean everything changed
+func overlaps(ranges [][2]int, start, end int) bool {
+	if ranges == nil {
+		return true
+	}
+	for _, r := range ranges {
+		if start <= r[1] && end >= r[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// duplPattern matches dupl's -plumbing output:
+// "a.go:10-20: duplicate of b.go:30-40"
+var duplPattern = regexp.MustCompile(`^(.+):(\d+)-(\d+): duplicate of (.+):(\d+)-(\d+)$`)
+
+func runDupl(root string, minTokens int, verbose bool) ([]Clone, error) {
+	if _, err := exec.LookPath("dupl"); err != nil {
+		return nil, exec.ErrNotFound
+	}
+	vlog.Printf(verbose, "🔧 dupl -t %d (in %s)\n", minTokens, root)
+	cmd := proc.Command("dupl", "-plumbing", "-t", strconv.Itoa(minTokens), ".")
+	cmd.Dir = root
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running dupl: %w", err)
+	}
+
+	var clones []Clone
+	for _, line := range strings.Split(string(output), "\n") {
+		m := duplPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		n := func(s string) int { v, _ := strconv.Atoi(s); return v }
+		clones = append(clones, Clone{
+			File: absPath(root, m[1]), Start: n(m[2]), End: n(m[3]),
+			Original: absPath(root, m[4]), OrigStart: n(m[5]), OrigEnd: n(m[6]),
+		})
+	}
+	return clones, nil
+}
+
+// jscpdReport is the part of jscpd-report.json the check reads
+type jscpdReport struct {
+	Duplicates []struct {
+		FirstFile  jscpdLocation `json:"firstFile"`
+		SecondFile jscpdLocation `json:"secondFile"`
+	} `json:"duplicates"`
+}
+
+type jscpdLocation struct {
+	Name  string `json:"name"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+func runJscpd(root string, minTokens int, verbose bool) ([]Clone, error) {
+	jscpd := "jscpd"
+	if dir := findUp(root, root, filepath.Join("node_modules", ".bin", "jscpd")); dir != "" {
+		jscpd = filepath.Join(dir, "node_modules", ".bin", "jscpd")
+	} else if _, err := exec.LookPath(jscpd); err != nil {
+		return nil, exec.ErrNotFound
+	}
+
+	outDir, err := os.MkdirTemp("", "claude-hooks-jscpd-")
+	if err != nil {
+		return nil, fmt.Errorf("creating jscpd output directory: %w", err)
+	}
+	defer os.RemoveAll(outDir)
+
+	vlog.Printf(verbose, "🔧 jscpd --min-tokens %d (in %s)\n", minTokens, root)
+	cmd := proc.Command(jscpd, "--silent", "--gitignore", "--min-tokens", strconv.Itoa(minTokens),
+		"--format", "javascript,typescript,jsx,tsx", "--reporters", "json", "--output", outDir, ".")
+	cmd.Dir = root
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("running jscpd: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "jscpd-report.json"))
+	if err != nil {
+		return nil, fmt.Errorf("reading jscpd report: %w", err)
+	}
+	var report jscpdReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("parsing jscpd report: %w", err)
+	}
+
+	var clones []Clone
+	for _, d := range report.Duplicates {
+		clones = append(clones, Clone{
+			File: absPath(root, d.FirstFile.Name), Start: d.FirstFile.Start, End: d.FirstFile.End,
+			Original: absPath(root, d.SecondFile.Name), OrigStart: d.SecondFile.Start, OrigEnd: d.SecondFile.End,
+		})
+	}
+	return clones, nil
+}
+
+func absPath(root, name string) string {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name)
+	}
+	return filepath.Join(root, name)
+}
@@ -0,0 +1,62 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFindDuplicates runs a stub dupl and expects only the clone touching the
+// edited lines to be reported, pointing at the original
+func TestFindDuplicates(t *testing.T) {
+	repo := t.TempDir()
+	writeFile := func(path, content string) { writeTestFile(t, repo, path, content) }
+	run := func(args ...string) { runInDir(t, repo, args...) }
+
+	body := strings.Repeat("\t_ = 1\n", 10)
+	writeFile(".claude-hooks.yaml", "duplicates:\n  enabled: true\n")
+	writeFile("a.go", "package a\n\nfunc A() {\n"+body+"}\n")
+	writeFile("b.go", "package a\n\nfunc B() {\n\t_ = 2\n}\n")
+	run("git", "init", "-q")
+	run("git", "add", ".")
+	run("git", "commit", "-q", "-m", "init")
+	writeFile("b.go", "package a\n\nfunc B() {\n\t_ = 2\n}\n\nfunc C() {\n"+body+"}\n")
+
+	// dupl lists each clone from both sides; b.go:1-5 predates the edit
+	bin := t.TempDir()
+	writeTestFile(t, bin, "dupl", "#!/bin/sh\ncat <<'EOF'\n"+
+		"a.go:3-14: duplicate of b.go:7-18\n"+
+		"b.go:7-18: duplicate of a.go:3-14\n"+
+		"b.go:1-5: duplicate of a.go:1-5\n"+
+		"EOF\n")
+	if err := os.Chmod(filepath.Join(bin, "dupl"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	clones, err := FindDuplicates([]string{filepath.Join(repo, "b.go")}, false)
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	want := Clone{File: filepath.Join(repo, "b.go"), Start: 7, End: 18, Original: filepath.Join(repo, "a.go"), OrigStart: 3, OrigEnd: 14}
+	if len(clones) != 1 || clones[0] != want {
+		t.Fatalf("Expected only %v, got %v", want, clones)
+	}
+
+	d := clones[0].Diagnostic()
+	if d.Severity != "warning" || d.Line != 7 || !strings.Contains(d.Message, "a.go:3-14") {
+		t.Errorf("Expected a warning on line 7 pointing at a.go:3-14, got %+v", d)
+	}
+}
+
+func TestFindDuplicatesDisabled(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "a.go", "package a\n")
+	t.Setenv("PATH", t.TempDir()) // Nothing to run: the check must not get that far
+
+	clones, err := FindDuplicates([]string{filepath.Join(repo, "a.go")}, false)
+	if err != nil || len(clones) != 0 {
+		t.Errorf("Expected no clones without duplicates.enabled, got %v, %v", clones, err)
+	}
+}
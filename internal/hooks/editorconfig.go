@@ -0,0 +1,178 @@
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/editorconfig"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// utf8BOM starts files saved as utf-8-bom
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// EditorConfigViolation is a property of an edited file's .editorconfig that
+// its changed lines break
+type EditorConfigViolation struct {
+	File     string // Absolute
+	Line     int    // First changed line breaking it, 1-based
+	Lines    int    // How many changed lines break it
+	Property string // e.g. "indent_style"
+	Message  string // What's wrong, e.g. "indented with tabs instead of spaces"
+	Block    bool   // editorconfig.block is set
+}
+
+func (v EditorConfigViolation) String() string {
+	return fmt.Sprintf("%s:%d: %s", v.File, v.Line, v.Diagnostic().Message)
+}
+
+// Diagnostic reports the violation on its first line; severity is "error" when
+// editorconfig.block is set
+func (v EditorConfigViolation) Diagnostic() Diagnostic {
+	message := v.Message
+	if v.Lines > 1 {
+		message += fmt.Sprintf(" on %d changed lines", v.Lines)
+	}
+	severity := "warning"
+	if v.Block {
+		severity = "error"
+	}
+	return Diagnostic{
+		File:     v.File,
+		Line:     v.Line,
+		Severity: severity,
+		Message:  fmt.Sprintf("%s (.editorconfig %s)", message, v.Property),
+		Source:   "editorconfig",
+	}
+}
+
+// CheckEditorConfig returns the .editorconfig properties the edited files
+// break on lines changed since HEAD, so files that already broke them aren't
+// reported on every edit. Binary files and files without an .editorconfig
+// are skipped. It is a no-op when editorconfig.enabled is turned off.
+func CheckEditorConfig(files []string, verbose bool) ([]EditorConfigViolation, error) {
+	var violations []EditorConfigViolation
+	for _, f := range files {
+		cfg, err := config.Load(filepath.Dir(f))
+		if err != nil {
+			return violations, err
+		}
+		if !cfg.EditorConfig.Enabled {
+			continue
+		}
+		props, err := editorconfig.Resolve(f)
+		if err != nil {
+			return violations, err
+		}
+		if len(props) == 0 {
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil || bytes.IndexByte(data, 0) >= 0 {
+			continue // Deleted since, or binary
+		}
+		ranges := changedRanges(state.ProjectRoot(filepath.Dir(f)), f)
+		found := checkProperties(data, props, ranges)
+		for i := range found {
+			found[i].File = f
+			found[i].Block = cfg.EditorConfig.Block
+		}
+		vlog.Printf(verbose, "📐 %s: %d .editorconfig violation(s)\n", f, len(found))
+		violations = append(violations, found...)
+	}
+	return violations, nil
+}
+
+// checkProperties checks data against props, on the lines in ranges (nil for
+// every line)
+func checkProperties(data []byte, props editorconfig.Properties, ranges [][2]int) []EditorConfigViolation {
+	var violations []EditorConfigViolation
+	index := make(map[string]int) // Property -> its violation
+	report := func(line int, property, message string) {
+		if !overlaps(ranges, line, line) {
+			return
+		}
+		if i, ok := index[property]; ok {
+			violations[i].Lines++
+			return
+		}
+		index[property] = len(violations)
+		violations = append(violations, EditorConfigViolation{Line: line, Lines: 1, Property: property, Message: message})
+	}
+
+	switch props["charset"] {
+	case "utf-8":
+		if bytes.HasPrefix(data, utf8BOM) {
+			report(1, "charset", "starts with a byte order mark, but the charset is utf-8 without one")
+		}
+		if !utf8.Valid(data) {
+			report(1, "charset", "isn't valid UTF-8")
+		}
+	case "utf-8-bom":
+		if !bytes.HasPrefix(data, utf8BOM) {
+			report(1, "charset", "has no byte order mark, but the charset is utf-8-bom")
+		}
+	}
+
+	width := indentWidth(props)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	for i, raw := range lines {
+		n := i + 1
+		line := string(raw)
+		content := strings.TrimRight(line, "\r\n")
+		ending := line[len(content):]
+
+		switch props["end_of_line"] {
+		case "lf":
+			if ending == "\r\n" {
+				report(n, "end_of_line", "ends with CRLF instead of LF")
+			}
+		case "crlf":
+			if ending == "\n" {
+				report(n, "end_of_line", "ends with LF instead of CRLF")
+			}
+		}
+		if props["trim_trailing_whitespace"] == "true" && strings.TrimRight(content, " \t") != content {
+			report(n, "trim_trailing_whitespace", "has trailing whitespace")
+		}
+		indent := content[:len(content)-len(strings.TrimLeft(content, " \t"))]
+		if indent == content {
+			continue // Blank lines have no indentation to check
+		}
+		switch props["indent_style"] {
+		case "space":
+			if strings.Contains(indent, "\t") {
+				report(n, "indent_style", "is indented with tabs instead of spaces")
+			}
+		case "tab":
+			if strings.HasPrefix(indent, strings.Repeat(" ", width)) {
+				report(n, "indent_style", "is indented with spaces instead of tabs")
+			}
+		}
+	}
+
+	if props["insert_final_newline"] == "true" && len(data) > 0 && data[len(data)-1] != '\n' {
+		report(len(lines), "insert_final_newline", "doesn't end with a newline")
+	}
+	return violations
+}
+
+// indentWidth is how many spaces make one level of indentation under props
+func indentWidth(props editorconfig.Properties) int {
+	for _, key := range []string{"indent_size", "tab_width"} {
+		if n, err := strconv.Atoi(props[key]); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 4
+}
@@ -0,0 +1,80 @@
+package hooks
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckEditorConfig(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, ".editorconfig", `root = true
+
+[*]
+end_of_line = lf
+insert_final_newline = true
+trim_trailing_whitespace = true
+charset = utf-8
+
+[*.py]
+indent_style = space
+indent_size = 4
+
+[Makefile]
+indent_style = tab
+`)
+	py := filepath.Join(repo, "app.py")
+	writeTestFile(t, repo, "app.py", "def f():\n\treturn 1  \n\n\tpass\r\nx = 1")
+	mk := filepath.Join(repo, "Makefile")
+	writeTestFile(t, repo, "Makefile", "all:\n    echo hi\n")
+	ok := filepath.Join(repo, "ok.py")
+	writeTestFile(t, repo, "ok.py", "def f():\n    return 1\n")
+
+	violations, err := CheckEditorConfig([]string{py, mk, ok}, false)
+	if err != nil {
+		t.Fatalf("CheckEditorConfig failed: %v", err)
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	want := []string{
+		py + ":2: has trailing whitespace (.editorconfig trim_trailing_whitespace)",
+		py + ":2: is indented with tabs instead of spaces on 2 changed lines (.editorconfig indent_style)",
+		py + ":4: ends with CRLF instead of LF (.editorconfig end_of_line)",
+		py + ":5: doesn't end with a newline (.editorconfig insert_final_newline)",
+		mk + ":2: is indented with spaces instead of tabs (.editorconfig indent_style)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected violations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if violations[0].Diagnostic().Severity != "warning" {
+		t.Errorf("Expected warnings by default, got %+v", violations[0].Diagnostic())
+	}
+
+	writeTestFile(t, repo, ".claude-hooks.yaml", "editorconfig:\n  block: true\n")
+	if violations, _ := CheckEditorConfig([]string{mk}, false); len(violations) != 1 || !violations[0].Block {
+		t.Errorf("Expected editorconfig.block to block, got %+v", violations)
+	}
+	writeTestFile(t, repo, ".claude-hooks.yaml", "editorconfig:\n  enabled: false\n")
+	if violations, _ := CheckEditorConfig([]string{mk}, false); len(violations) != 0 {
+		t.Errorf("Expected no check when disabled, got %+v", violations)
+	}
+}
+
+func TestCheckEditorConfigChangedLines(t *testing.T) {
+	repo := t.TempDir()
+	runInDir(t, repo, "git", "init", "-q")
+	writeTestFile(t, repo, ".editorconfig", "root = true\n\n[*]\ntrim_trailing_whitespace = true\n")
+	file := filepath.Join(repo, "notes.txt")
+	writeTestFile(t, repo, "notes.txt", "old line \nkept\n")
+	runInDir(t, repo, "git", "add", ".")
+	runInDir(t, repo, "git", "commit", "-qm", "init")
+
+	// Whitespace that was already there isn't the edit's fault
+	writeTestFile(t, repo, "notes.txt", "old line \nkept\nnew line\t\n")
+	violations, err := CheckEditorConfig([]string{file}, false)
+	if err != nil || len(violations) != 1 || violations[0].Line != 3 {
+		t.Errorf("Expected only the added line to be reported, got %+v (%v)", violations, err)
+	}
+}
@@ -0,0 +1,83 @@
+package hooks
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// CheckEmbeds vets the Go packages whose //go:embed patterns match a changed
+// file that isn't Go source: edited, deleted, or moved from or to. A pattern
+// that no longer matches any file breaks the build of its package, which is
+// what renaming an embedded asset does, and go vet reports it at the directive.
+func CheckEmbeds(paths []string, verbose bool) error {
+	patterns := make(map[string][]string) // Package dir -> embed patterns
+	var dirs []string
+	for _, p := range paths {
+		if filepath.Ext(p) == ".go" {
+			continue
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+		moduleRoot, err := findModuleRoot(filepath.Dir(abs))
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(moduleRoot, "go.mod")); err != nil {
+			continue // Outside a module, nothing builds with it
+		}
+
+		// Patterns only reach files in their package's directory and below
+		for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+			if _, ok := patterns[dir]; !ok {
+				patterns[dir] = embedPatterns(dir)
+			}
+			rel, _ := filepath.Rel(dir, abs)
+			if !slices.Contains(dirs, dir) && slices.ContainsFunc(patterns[dir], func(pattern string) bool { return embedMatches(pattern, filepath.ToSlash(rel)) }) {
+				vlog.Printf(verbose, "📦 %s is embedded by the package in %s\n", p, dir)
+				dirs = append(dirs, dir)
+			}
+			if dir == moduleRoot {
+				break
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	if err := runGoPerModule(dirs, []string{"vet"}, verbose); err != nil {
+		return fmt.Errorf("packages embedding the changed files no longer build:\n%w", err)
+	}
+	return nil
+}
+
+// embedPatterns returns the //go:embed patterns of the package in dir,
+// including its tests', or nil when dir has no Go package
+func embedPatterns(dir string) []string {
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil && pkg == nil {
+		return nil
+	}
+	return slices.Concat(pkg.EmbedPatterns, pkg.TestEmbedPatterns, pkg.XTestEmbedPatterns)
+}
+
+// embedMatches reports whether pattern embeds the file at rel, relative to
+// the package: the pattern matches the file itself or a directory holding it
+func embedMatches(pattern, rel string) bool {
+	pattern = strings.TrimPrefix(pattern, "all:")
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		if ok, _ := path.Match(pattern, strings.Join(parts[:i+1], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
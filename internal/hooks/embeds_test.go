@@ -0,0 +1,58 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckEmbeds(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "go.mod", "module example.com/site\n\ngo 1.22\n")
+	writeTestFile(t, repo, "web/web.go", "package web\n\nimport \"embed\"\n\n//go:embed templates/*.html static\nvar FS embed.FS\n")
+	writeTestFile(t, repo, "web/templates/index.html", "<h1>hi</h1>\n")
+	writeTestFile(t, repo, "web/static/css/app.css", "body {}\n")
+	writeTestFile(t, repo, "docs/notes.md", "unrelated\n")
+
+	edited := []string{
+		filepath.Join(repo, "web/templates/index.html"),
+		filepath.Join(repo, "web/static/css/app.css"),
+		filepath.Join(repo, "docs/notes.md"),
+	}
+	if err := CheckEmbeds(edited, false); err != nil {
+		t.Fatalf("Expected embedded files that still match to pass, got: %v", err)
+	}
+
+	// Renaming the only template leaves the pattern matching nothing
+	renamed := filepath.Join(repo, "web/templates/index.tmpl")
+	if err := os.Rename(edited[0], renamed); err != nil {
+		t.Fatal(err)
+	}
+	err := CheckEmbeds([]string{edited[0], renamed}, false)
+	if err == nil || !strings.Contains(err.Error(), "pattern templates/*.html: no matching files found") {
+		t.Fatalf("Expected the broken pattern to be reported, got: %v", err)
+	}
+
+	// Files no pattern embeds don't trigger a check, even beside a broken one
+	if err := CheckEmbeds([]string{filepath.Join(repo, "docs/notes.md")}, false); err != nil {
+		t.Errorf("Expected an unembedded file to be ignored, got: %v", err)
+	}
+}
+
+func TestEmbedMatches(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"templates/*.html", "templates/index.html", true},
+		{"templates/*.html", "templates/index.tmpl", false},
+		{"static", "static/css/app.css", true},
+		{"all:static", "static/.hidden", true},
+		{"*.txt", "sub/a.txt", false},
+	} {
+		if got := embedMatches(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("embedMatches(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
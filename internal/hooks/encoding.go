@@ -0,0 +1,128 @@
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vcs"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// EncodingChange is an edit changing how a file is encoded rather than what
+// it says: its line endings, its byte order mark, or valid UTF-8
+type EncodingChange struct {
+	File    string // Absolute
+	Message string // e.g. "line endings changed from LF to CRLF on 12 lines"
+	// Fixed is set when encoding.fix put the file back the way it was
+	Fixed bool
+}
+
+func (c EncodingChange) String() string {
+	if c.Fixed {
+		return fmt.Sprintf("%s: %s; restored (encoding.fix)", c.File, c.Message)
+	}
+	return fmt.Sprintf("%s: %s", c.File, c.Message)
+}
+
+// CheckEncoding compares the encoding of each edited file with HEAD's,
+// checked out the way git would (so core.autocrlf doesn't count as a change),
+// and returns what the edit changed. With encoding.fix, line endings and the
+// byte order mark are restored in place, unless CLAUDE_HOOKS_READ_ONLY is
+// set. Files new since HEAD, binary files, and files whose line endings were
+// already mixed only get the UTF-8 check against HEAD's.
+func CheckEncoding(files []string, verbose bool) ([]EncodingChange, error) {
+	var changes []EncodingChange
+	for _, f := range files {
+		cfg, err := config.Load(filepath.Dir(f))
+		if err != nil {
+			return changes, err
+		}
+		if !cfg.Encoding.Enabled {
+			continue
+		}
+		after, err := os.ReadFile(f)
+		if err != nil {
+			continue // Deleted since
+		}
+		before, ok := checkedOutHead(state.ProjectRoot(filepath.Dir(f)), f)
+		if !ok || bytes.IndexByte(before, 0) >= 0 || bytes.IndexByte(after, 0) >= 0 {
+			continue
+		}
+
+		found, fixed := encodingChanges(before, after)
+		if len(found) == 0 {
+			continue
+		}
+		fix := cfg.Encoding.Fix && !config.ReadOnly() && fixed != nil
+		if fix {
+			if err := restoreFile(f, fixed); err != nil {
+				return changes, err
+			}
+			vlog.Printf(verbose, "🔧 Restored the encoding of %s\n", f)
+		}
+		for _, message := range found {
+			// Invalid UTF-8 has no known original to restore
+			changes = append(changes, EncodingChange{File: f, Message: message, Fixed: fix && !strings.HasPrefix(message, "invalid UTF-8")})
+		}
+	}
+	return changes, nil
+}
+
+// checkedOutHead returns file's content at the last commit as it would be
+// checked out, or false when it isn't committed
+func checkedOutHead(root, file string) ([]byte, bool) {
+	repo := vcs.Detect(root)
+	if repo == nil {
+		return nil, false
+	}
+	return repo.Base(file)
+}
+
+// encodingChanges describes how after's encoding differs from before's and
+// returns after with before's line endings and byte order mark, or nil when
+// that can't fix it (after isn't valid UTF-8)
+func encodingChanges(before, after []byte) (changes []string, fixed []byte) {
+	beforeBOM, afterBOM := bytes.HasPrefix(before, utf8BOM), bytes.HasPrefix(after, utf8BOM)
+	body := bytes.TrimPrefix(after, utf8BOM)
+	switch {
+	case afterBOM && !beforeBOM:
+		changes = append(changes, "a byte order mark was added")
+	case beforeBOM && !afterBOM:
+		changes = append(changes, "the byte order mark was removed")
+	}
+
+	crlfBefore, lfBefore := countLineEndings(before)
+	crlfAfter, lfAfter := countLineEndings(after)
+	switch {
+	case lfBefore > 0 && crlfBefore == 0 && crlfAfter > 0:
+		changes = append(changes, fmt.Sprintf("line endings changed from LF to CRLF on %d line(s)", crlfAfter))
+		body = bytes.ReplaceAll(body, []byte("\r\n"), []byte("\n"))
+	case crlfBefore > 0 && lfBefore == 0 && lfAfter > 0:
+		changes = append(changes, fmt.Sprintf("line endings changed from CRLF to LF on %d line(s)", lfAfter))
+		body = bytes.ReplaceAll(bytes.ReplaceAll(body, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+	}
+
+	valid := utf8.Valid(after)
+	if utf8.Valid(before) && !valid {
+		changes = append(changes, "invalid UTF-8 was introduced; rewrite the file as UTF-8")
+	}
+	if len(changes) == 0 || !valid {
+		return changes, nil
+	}
+	if beforeBOM {
+		return changes, append(bytes.Clone(utf8BOM), body...)
+	}
+	return changes, body
+}
+
+// countLineEndings counts the CRLF and lone LF line endings of data
+func countLineEndings(data []byte) (crlf, lf int) {
+	crlf = bytes.Count(data, []byte("\r\n"))
+	return crlf, bytes.Count(data, []byte("\n")) - crlf
+}
@@ -0,0 +1,59 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckEncoding(t *testing.T) {
+	repo := t.TempDir()
+	runInDir(t, repo, "git", "init", "-q")
+	writeTestFile(t, repo, "unix.txt", "a\nb\n")
+	writeTestFile(t, repo, "dos.txt", "a\r\nb\r\n")
+	writeTestFile(t, repo, "bom.txt", "\ufeffa\n")
+	writeTestFile(t, repo, "utf8.txt", "héllo\n")
+	runInDir(t, repo, "git", "add", ".")
+	runInDir(t, repo, "git", "commit", "-qm", "init")
+	file := func(name string) string { return filepath.Join(repo, name) }
+
+	writeTestFile(t, repo, "unix.txt", "a\r\nb\r\nc\r\n")
+	writeTestFile(t, repo, "dos.txt", "a\r\nb\r\nc\n")
+	writeTestFile(t, repo, "bom.txt", "a\nb\n")
+	writeTestFile(t, repo, "utf8.txt", "h\xe9llo\n")
+	writeTestFile(t, repo, "new.txt", "\ufeffnew\r\n")
+	files := []string{file("unix.txt"), file("dos.txt"), file("bom.txt"), file("utf8.txt"), file("new.txt")}
+
+	changes, err := CheckEncoding(files, false)
+	if err != nil {
+		t.Fatalf("CheckEncoding failed: %v", err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, strings.TrimPrefix(c.String(), repo+"/"))
+	}
+	want := []string{
+		"unix.txt: line endings changed from LF to CRLF on 3 line(s)",
+		"dos.txt: line endings changed from CRLF to LF on 1 line(s)",
+		"bom.txt: the byte order mark was removed",
+		"utf8.txt: invalid UTF-8 was introduced; rewrite the file as UTF-8",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Unexpected changes:\n%s", strings.Join(got, "\n"))
+	}
+
+	writeTestFile(t, repo, ".claude-hooks.yaml", "encoding:\n  fix: true\n")
+	changes, err = CheckEncoding(files, false)
+	if err != nil || len(changes) != 4 {
+		t.Fatalf("Expected the same changes, got %+v (%v)", changes, err)
+	}
+	for name, want := range map[string]string{"unix.txt": "a\nb\nc\n", "dos.txt": "a\r\nb\r\nc\r\n", "bom.txt": "\ufeffa\nb\n", "utf8.txt": "h\xe9llo\n"} {
+		if data, _ := os.ReadFile(file(name)); string(data) != want {
+			t.Errorf("Expected %s to be %q after the fix, got %q", name, want, data)
+		}
+	}
+	if !changes[0].Fixed || changes[3].Fixed {
+		t.Errorf("Expected everything but invalid UTF-8 to be fixed, got %+v", changes)
+	}
+}
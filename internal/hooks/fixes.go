@@ -0,0 +1,355 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// SuggestFixes adds machine-made patches to diags for the edited files:
+// gopls quick fixes for compile and vet errors, golangci-lint --fix for Go
+// lint findings, and eslint --fix-dry-run for scripts. A patch for lines no
+// diagnostic points at becomes a diagnostic of its own. Tools that aren't
+// installed are skipped. It is a no-op unless fixes.enabled is set.
+func SuggestFixes(files []string, diags []Diagnostic, verbose bool) ([]Diagnostic, error) {
+	if len(files) == 0 {
+		return diags, nil
+	}
+	cfg, err := config.Load(filepath.Dir(files[0]))
+	if err != nil {
+		return diags, err
+	}
+	if !cfg.Fixes.Enabled {
+		return diags, nil
+	}
+
+	diags = slices.Clone(diags)
+	var goFiles, scripts []string
+	for _, f := range files {
+		switch strings.ToLower(filepath.Ext(f)) {
+		case ".go":
+			goFiles = append(goFiles, f)
+		case ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs":
+			scripts = append(scripts, f)
+		}
+	}
+
+	var errs []error
+	if len(goFiles) > 0 {
+		if _, err := exec.LookPath("gopls"); err == nil {
+			goplsFixes(diags, verbose)
+		} else {
+			vlog.Printf(verbose, "⏭️  Skipping gopls fixes: gopls not installed\n")
+		}
+
+		// golangci-lint only fixes in place, even if the files are put back after
+		if _, err := exec.LookPath("golangci-lint"); err == nil && !config.ReadOnly() {
+			fixes, err := golangciFixes(goFiles, verbose)
+			errs = append(errs, err)
+			for _, f := range fixes {
+				diags = attachFix(diags, f)
+			}
+		} else if err == nil {
+			vlog.Printf(verbose, "⏭️  Skipping golangci-lint fixes: read-only\n")
+		} else {
+			vlog.Printf(verbose, "⏭️  Skipping golangci-lint fixes: golangci-lint not installed\n")
+		}
+	}
+
+	if len(scripts) > 0 {
+		fixes, err := eslintFixes(scripts, verbose)
+		switch {
+		case errors.Is(err, exec.ErrNotFound):
+			vlog.Printf(verbose, "⏭️  Skipping eslint fixes: eslint not installed\n")
+		default:
+			errs = append(errs, err)
+			for _, f := range fixes {
+				diags = attachFix(diags, f)
+			}
+		}
+	}
+	return diags, errors.Join(errs...)
+}
+
+// FormatFixes lists the patches of diags for a reason, or "" when none has one
+func FormatFixes(diags []Diagnostic) string {
+	var b strings.Builder
+	for _, d := range diags {
+		if d.Fix == "" {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("Suggested fixes, made by the tools themselves. Apply the patch instead of rewriting the code by hand:\n")
+		}
+		fmt.Fprintf(&b, "\n%s:%d: %s (%s)\n```diff\n%s\n```\n", d.File, d.Line, d.Message, d.Source, strings.TrimRight(d.Fix, "\n"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// fileFix is one hunk of a tool's patch, with the diagnostic to report when
+// no existing one points at its lines
+type fileFix struct {
+	hunk
+	diagnostic Diagnostic
+}
+
+// attachFix sets the patch on the first diagnostic of its file within its
+// lines that has none yet, or adds fix's own diagnostic
+func attachFix(diags []Diagnostic, fix fileFix) []Diagnostic {
+	start, end := fix.span()
+	for i, d := range diags {
+		if d.Fix == "" && filepath.Clean(d.File) == filepath.Clean(fix.diagnostic.File) && d.Line >= start && d.Line <= end {
+			diags[i].Fix = fix.patch
+			return diags
+		}
+	}
+	d := fix.diagnostic
+	d.Fix = fix.patch
+	return append(diags, d)
+}
+
+// goplsFixes sets, on each compile or vet error of a Go file that has no
+// patch, the diff of gopls' first quick fix at its position
+func goplsFixes(diags []Diagnostic, verbose bool) {
+	for i, d := range diags {
+		if d.Fix != "" || d.Source != "go" || d.Line <= 0 || !strings.HasSuffix(d.File, ".go") || !filepath.IsAbs(d.File) {
+			continue
+		}
+		location := fmt.Sprintf("%s:%d:%d", d.File, d.Line, max(d.Column, 1))
+		vlog.Printf(verbose, "🔧 gopls codeaction -kind=quickfix %s\n", location)
+		cmd := proc.Command("gopls", "codeaction", "-exec", "-kind=quickfix", "-diff", location)
+		cmd.Dir = filepath.Dir(d.File)
+		output, err := cmd.Output()
+		// gopls exits non-zero when no action applies; that's the common case
+		if err != nil || len(bytes.TrimSpace(output)) == 0 {
+			continue
+		}
+		diags[i].Fix = string(output)
+	}
+}
+
+// golangciFixes runs golangci-lint --fix on the edited packages and returns
+// what it changed. golangci-lint can only fix files in place, so the package
+// files are restored right after.
+func golangciFixes(files []string, verbose bool) ([]fileFix, error) {
+	byRoot := make(map[string][]string)
+	var roots []string
+	for _, dir := range buildableDirs(packageDirs(files), verbose) {
+		root, err := findModuleRoot(dir)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return nil, fmt.Errorf("resolving package %s: %w", dir, err)
+		}
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], "./"+filepath.ToSlash(rel))
+	}
+
+	var fixes []fileFix
+	for _, root := range roots {
+		// The files are changed in place for a moment, which another
+		// session's fixer mustn't take for its own edit
+		unlock, err := lockWrites(root, "golangci-lint --fix", verbose)
+		if err != nil {
+			return fixes, err
+		}
+		found, err := golangciFixRoot(root, byRoot[root], verbose)
+		unlock()
+		fixes = append(fixes, found...)
+		if err != nil {
+			return fixes, err
+		}
+	}
+	return fixes, nil
+}
+
+// golangciFixRoot runs golangci-lint --fix on pkgs of the module at root
+func golangciFixRoot(root string, pkgs []string, verbose bool) ([]fileFix, error) {
+	var fixes []fileFix
+	var sources []string
+	for _, pkg := range pkgs {
+		matches, _ := filepath.Glob(filepath.Join(root, pkg, "*.go"))
+		sources = append(sources, matches...)
+	}
+	before := make(map[string][]byte, len(sources))
+	for _, f := range sources {
+		if data, err := os.ReadFile(f); err == nil {
+			before[f] = data
+		}
+	}
+
+	args := []string{"run", "--fix", "--issues-exit-code=0"}
+	if vendored(root) {
+		args = append(args, "--modules-download-mode=vendor")
+	}
+	args = append(args, pkgs...)
+	vlog.Printf(verbose, "🔧 golangci-lint %s (in %s)\n", strings.Join(args, " "), root)
+	cmd := proc.Command("golangci-lint", args...)
+	cmd.Dir = root
+	output, runErr := cmd.CombinedOutput()
+
+	for _, f := range sources {
+		original, ok := before[f]
+		after, err := os.ReadFile(f)
+		if !ok || err != nil || bytes.Equal(original, after) {
+			continue
+		}
+		if err := restoreFile(f, original); err != nil {
+			return fixes, err
+		}
+		found, err := fileFixes(f, original, after, "golangci-lint")
+		if err != nil {
+			return fixes, err
+		}
+		fixes = append(fixes, found...)
+	}
+	if runErr != nil {
+		return fixes, fmt.Errorf("running golangci-lint --fix: %w\n%s", runErr, strings.TrimSpace(string(output)))
+	}
+	return fixes, nil
+}
+
+// restoreFile puts back the content a fixer replaced
+func restoreFile(path string, original []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("restoring %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, original, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("restoring %s: %w", path, err)
+	}
+	return nil
+}
+
+// eslintFixResult is the part of eslint's JSON formatter output with the fixes
+type eslintFixResult struct {
+	FilePath string `json:"filePath"`
+	Output   string `json:"output"` // The fixed source, only when a fix applies
+}
+
+// eslintFixes runs the project's eslint with --fix-dry-run over the scripts and
+// returns what its fixes change
+func eslintFixes(files []string, verbose bool) ([]fileFix, error) {
+	eslintBin := filepath.Join("node_modules", ".bin", "eslint")
+	groups := make(map[string][]string)
+	var dirs []string
+	for _, f := range files {
+		dir := findUp(filepath.Dir(f), state.ProjectRoot(filepath.Dir(f)), eslintBin)
+		if dir == "" {
+			continue
+		}
+		if _, ok := groups[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		groups[dir] = append(groups[dir], f)
+	}
+	if len(dirs) == 0 {
+		return nil, exec.ErrNotFound
+	}
+
+	var fixes []fileFix
+	for _, dir := range dirs {
+		vlog.Printf(verbose, "🔧 eslint --fix-dry-run (in %s)\n", dir)
+		cmd := proc.Command(filepath.Join(dir, eslintBin), append([]string{"--fix-dry-run", "--format", "json"}, groups[dir]...)...)
+		cmd.Dir = dir
+		output, err := cmd.Output()
+		// Exit status 1 means findings that remain after the fixes
+		var exitErr *exec.ExitError
+		if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+			return fixes, fmt.Errorf("running eslint: %w\n%s", err, strings.TrimSpace(string(output)))
+		}
+
+		var results []eslintFixResult
+		if err := json.Unmarshal(output, &results); err != nil {
+			return fixes, fmt.Errorf("parsing eslint output: %w", err)
+		}
+		for _, r := range results {
+			file := absPath(dir, r.FilePath)
+			original, err := os.ReadFile(file)
+			if r.Output == "" || err != nil {
+				continue
+			}
+			found, err := fileFixes(file, original, []byte(r.Output), "eslint")
+			if err != nil {
+				return fixes, err
+			}
+			fixes = append(fixes, found...)
+		}
+	}
+	return fixes, nil
+}
+
+// fileFixes splits the change a fixer made to file into hunks
+func fileFixes(file string, before, after []byte, tool string) ([]fileFix, error) {
+	patch, err := unifiedDiff(file, before, after)
+	if err != nil {
+		return nil, err
+	}
+	var fixes []fileFix
+	for _, h := range splitHunks(patch) {
+		fixes = append(fixes, fileFix{hunk: h, diagnostic: Diagnostic{
+			File: file, Line: h.line, Severity: "info",
+			Message: tool + " --fix changes these lines", Source: tool,
+		}})
+	}
+	return fixes, nil
+}
+
+// unifiedDiff is the patch turning before into after, labeled with path. It
+// uses git's diff, which works outside repositories with --no-index.
+func unifiedDiff(path string, before, after []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "claude-hooks-fix-")
+	if err != nil {
+		return "", fmt.Errorf("creating diff directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	if err := os.WriteFile(a, before, 0o644); err != nil {
+		return "", fmt.Errorf("writing diff input: %w", err)
+	}
+	if err := os.WriteFile(b, after, 0o644); err != nil {
+		return "", fmt.Errorf("writing diff input: %w", err)
+	}
+	output, err := proc.Command("git", "diff", "--no-index", "--no-color", "--no-ext-diff", "-U1", a, b).Output()
+	// Exit status 1 means the files differ
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return "", fmt.Errorf("diffing %s: %w", path, err)
+	}
+	return relabelDiff(string(output), path), nil
+}
+
+// relabelDiff drops a diff's git header lines and names path on its ---/+++ lines
+func relabelDiff(diff, path string) string {
+	var b strings.Builder
+	header := 0 // ---/+++ lines seen
+	for line := range strings.Lines(diff) {
+		switch {
+		case header == 0 && strings.HasPrefix(line, "--- "):
+			fmt.Fprintf(&b, "--- %s\n", path)
+			header++
+		case header == 1 && strings.HasPrefix(line, "+++ "):
+			fmt.Fprintf(&b, "+++ %s\n", path)
+			header++
+		case header == 2:
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSuggestFixesGolangci(t *testing.T) {
+	module := t.TempDir()
+	writeTestFile(t, module, "go.mod", "module example.com/a\n\ngo 1.22\n")
+	original := "package a\n\nimport \"os\"\n\nfunc A() {\n\tos.Remove(\"x\")\n}\n"
+	writeTestFile(t, module, "a.go", original)
+	file := filepath.Join(module, "a.go")
+
+	// golangci-lint fixes in place; the stub checks errors the way errcheck's fix would
+	bin := t.TempDir()
+	writeTestFile(t, bin, "golangci-lint", "#!/bin/sh\nsed -i 's/\\tos.Remove/\\t_ = os.Remove/' a.go\n")
+	if err := os.Chmod(filepath.Join(bin, "golangci-lint"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	diags := []Diagnostic{
+		{File: file, Line: 6, Column: 11, Severity: "error", Message: "Error return value of `os.Remove` is not checked", Source: "go"},
+		{File: file, Line: 1, Severity: "error", Message: "unrelated", Source: "go"},
+	}
+	got, err := SuggestFixes([]string{file}, diags, false)
+	if err != nil {
+		t.Fatalf("SuggestFixes failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(file); string(data) != original {
+		t.Errorf("Expected the fixed file to be restored, got %q", data)
+	}
+	if len(got) != 2 || got[1].Fix != "" {
+		t.Fatalf("Expected the fix on the diagnostic of its line, got %+v", got)
+	}
+	if fix := got[0].Fix; !strings.HasPrefix(fix, "--- "+file+"\n+++ "+file+"\n@@") || !strings.Contains(fix, "-\tos.Remove(\"x\")\n+\t_ = os.Remove(\"x\")") {
+		t.Errorf("Expected a patch of the file, got %q", fix)
+	}
+	if diags[0].Fix != "" {
+		t.Error("Expected the diagnostics passed in to be left alone")
+	}
+
+	text := FormatFixes(got)
+	if !strings.Contains(text, file+":6: Error return value") || !strings.Contains(text, "```diff\n--- ") {
+		t.Errorf("Expected the reason to carry the patch, got %q", text)
+	}
+	if FormatFixes(diags) != "" {
+		t.Error("Expected no text without fixes")
+	}
+}
+
+func TestSuggestFixesEslint(t *testing.T) {
+	project := t.TempDir()
+	runInDir(t, project, "git", "init", "-q")
+	writeTestFile(t, project, "package.json", "{}\n")
+	writeTestFile(t, project, "src/app.ts", "let a = 1;\nexport const b = a;\n")
+	file := filepath.Join(project, "src", "app.ts")
+
+	// Fixed findings don't stay in messages; the fixed source is in output
+	writeTestFile(t, project, "node_modules/.bin/eslint", "#!/bin/sh\ncat <<'EOF'\n"+
+		`[{"filePath":"`+file+`","messages":[],"output":"const a = 1;\nexport const b = a;\n"}]`+"\nEOF\n")
+	if err := os.Chmod(filepath.Join(project, "node_modules", ".bin", "eslint"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := SuggestFixes([]string{file}, nil, false)
+	if err != nil {
+		t.Fatalf("SuggestFixes failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Expected the fix as a diagnostic of its own, got %+v", got)
+	}
+	if d := got[0]; d.File != file || d.Line != 1 || d.Source != "eslint" || d.Severity != "info" || !strings.Contains(d.Fix, "-let a = 1;\n+const a = 1;") {
+		t.Errorf("Unexpected eslint fix: %+v", d)
+	}
+}
+
+func TestSuggestFixesDisabled(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".claude-hooks.yaml", "fixes:\n  enabled: false\n")
+	writeTestFile(t, dir, "a.go", "package a\n")
+	t.Setenv("PATH", t.TempDir()) // Nothing to run: the fixers must not get that far
+
+	diags := []Diagnostic{{File: filepath.Join(dir, "a.go"), Line: 1, Message: "x", Source: "go"}}
+	got, err := SuggestFixes([]string{filepath.Join(dir, "a.go")}, diags, false)
+	if err != nil || len(got) != 1 || got[0].Fix != "" {
+		t.Errorf("Expected the diagnostics unchanged, got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,400 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// fixPatchFile is the patch of every fix the hooks found, in the project's cache
+const fixPatchFile = "fixes.patch"
+
+// fixPatchLockTimeout bounds the wait for another hook or apply-fixes on the patch
+const fixPatchLockTimeout = 5 * time.Second
+
+// hunk is one @@ section of a patch
+type hunk struct {
+	oldStart, oldLines int    // The old lines it replaces, from its @@ line
+	line               int    // First changed line of the old file
+	oldText, newText   string // The old lines and what replaces them, context included
+	body               string // The @@ line and the hunk's lines
+	patch              string // The file's ---/+++ header and the hunk, a patch of its own
+}
+
+// span is the range of old lines the hunk covers; an insertion covers the
+// line after it
+func (h hunk) span() (int, int) {
+	if h.oldLines == 0 {
+		return h.oldStart + 1, h.oldStart + 1
+	}
+	return h.oldStart, h.oldStart + h.oldLines - 1
+}
+
+// describe names the hunk of path in messages, by its @@ line
+func (h hunk) describe(path string) string {
+	return path + ": " + hunkHeaderPattern.FindString(h.body)
+}
+
+// filePatch is the part of a patch that changes one file
+type filePatch struct {
+	path   string
+	header string // The ---/+++ lines
+	hunks  []hunk
+}
+
+func (p filePatch) String() string {
+	var b strings.Builder
+	b.WriteString(p.header)
+	for _, h := range p.hunks {
+		b.WriteString(h.body)
+	}
+	return b.String()
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// parsePatch reads the files and hunks of a unified diff. The line counts of
+// each @@ line decide where its hunk ends, so a removed line starting with
+// "--" isn't taken for the next file's header.
+func parsePatch(patch string) []filePatch {
+	var files []filePatch
+	var header string
+	oldLeft, newLeft := 0, 0 // Lines of the current hunk still to come
+	old := 0                 // Old line the next line of the hunk is at
+	changed := false
+	for line := range strings.Lines(patch) {
+		text := strings.TrimSuffix(line, "\n")
+		if oldLeft > 0 || newLeft > 0 {
+			p := &files[len(files)-1]
+			h := &p.hunks[len(p.hunks)-1]
+			h.body += line
+			kind, content := byte(' '), ""
+			if text != "" {
+				kind, content = text[0], text[1:]
+			}
+			switch kind {
+			case '-':
+				h.oldText += content + "\n"
+				oldLeft--
+			case '+':
+				h.newText += content + "\n"
+				newLeft--
+			default:
+				h.oldText += content + "\n"
+				h.newText += content + "\n"
+				oldLeft--
+				newLeft--
+				old++
+				continue
+			}
+			if !changed {
+				h.line, changed = max(old, 1), true
+			}
+			if kind == '-' {
+				old++
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			header = line
+		case strings.HasPrefix(line, "+++ ") && header != "":
+			name, _, _ := strings.Cut(strings.TrimPrefix(text, "+++ "), "\t")
+			files = append(files, filePatch{path: name, header: header + line})
+			header = ""
+		case strings.HasPrefix(line, `\`) && len(files) > 0 && len(files[len(files)-1].hunks) > 0:
+			// "\ No newline at end of file" belongs to the hunk before it
+			p := &files[len(files)-1]
+			p.hunks[len(p.hunks)-1].body += line
+		default:
+			m := hunkHeaderPattern.FindStringSubmatch(line)
+			if m == nil || len(files) == 0 {
+				continue
+			}
+			p := &files[len(files)-1]
+			start, _ := strconv.Atoi(m[1])
+			oldLeft, newLeft = 1, 1
+			if m[2] != "" {
+				oldLeft, _ = strconv.Atoi(m[2])
+			}
+			if m[3] != "" {
+				newLeft, _ = strconv.Atoi(m[3])
+			}
+			h := hunk{oldStart: start, oldLines: oldLeft, body: line}
+			h.line, _ = h.span()
+			p.hunks = append(p.hunks, h)
+			old, changed = h.line, false
+		}
+	}
+
+	for i := range files {
+		for j := range files[i].hunks {
+			files[i].hunks[j].patch = files[i].header + files[i].hunks[j].body
+		}
+	}
+	return files
+}
+
+// splitHunks splits a patch into its hunks
+func splitHunks(patch string) []hunk {
+	var hunks []hunk
+	for _, p := range parsePatch(patch) {
+		hunks = append(hunks, p.hunks...)
+	}
+	return hunks
+}
+
+// WriteFixPatch adds the fixes of diags, and the formatting gofmt or the
+// project's prettier gives the edited files, to the project's fix patch for
+// `claude-hook apply-fixes`. They replace what the patch had for the same
+// files. It returns the patch's path and how many hunks it has, and is a
+// no-op unless fixes.patch is set.
+func WriteFixPatch(files []string, diags []Diagnostic, verbose bool) (string, int, error) {
+	if len(files) == 0 {
+		return "", 0, nil
+	}
+	cfg, err := config.Load(filepath.Dir(files[0]))
+	if err != nil {
+		return "", 0, err
+	}
+	if !cfg.Fixes.Patch {
+		return "", 0, nil
+	}
+
+	var found []filePatch
+	for _, d := range diags {
+		if d.Fix != "" {
+			found = append(found, parsePatch(d.Fix)...)
+		}
+	}
+	found = append(found, formattingPatches(files, verbose)...)
+	replaced := slices.Clone(files)
+	for _, p := range found {
+		replaced = append(replaced, p.path)
+	}
+
+	path, err := state.ProjectPath(filepath.Dir(files[0]), state.Cache, fixPatchFile)
+	if err != nil {
+		return "", 0, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fixPatchLockTimeout)
+	defer cancel()
+	unlock, err := state.Lock(ctx, path+".lock")
+	if err != nil {
+		return "", 0, err
+	}
+	defer unlock()
+
+	var patches []filePatch
+	if data, err := os.ReadFile(path); err == nil {
+		for _, p := range parsePatch(string(data)) {
+			if !slices.Contains(replaced, p.path) {
+				patches = append(patches, p)
+			}
+		}
+	}
+	var b strings.Builder
+	hunks := 0
+	for _, p := range append(patches, found...) {
+		text := p.String()
+		if strings.Contains(b.String(), text) {
+			continue // The same fix found for two diagnostics
+		}
+		b.WriteString(text)
+		hunks += len(p.hunks)
+	}
+
+	if b.Len() == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", 0, fmt.Errorf("removing fix patch: %w", err)
+		}
+		return path, 0, nil
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return "", 0, fmt.Errorf("writing fix patch: %w", err)
+	}
+	vlog.Printf(verbose, "📝 Wrote %d fix hunks to %s\n", hunks, path)
+	return path, hunks, nil
+}
+
+// formattingPatches diffs the edited files against gofmt's formatting, or for
+// scripts the project's prettier. Files that don't parse are left out.
+func formattingPatches(files []string, verbose bool) []filePatch {
+	prettierBin := filepath.Join("node_modules", ".bin", "prettier")
+	var patches []filePatch
+	for _, f := range files {
+		var cmd *exec.Cmd
+		switch strings.ToLower(filepath.Ext(f)) {
+		case ".go":
+			cmd = proc.Command("gofmt", f)
+		case ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs":
+			if dir := findUp(filepath.Dir(f), state.ProjectRoot(filepath.Dir(f)), prettierBin); dir != "" {
+				cmd = proc.Command(filepath.Join(dir, prettierBin), f)
+				cmd.Dir = dir
+			}
+		}
+		if cmd == nil {
+			continue
+		}
+
+		original, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		formatted, err := cmd.Output()
+		if err != nil || string(formatted) == string(original) {
+			continue
+		}
+		vlog.Printf(verbose, "🔧 %s needs formatting\n", f)
+		patch, err := unifiedDiff(f, original, formatted)
+		if err != nil {
+			continue
+		}
+		patches = append(patches, parsePatch(patch)...)
+	}
+	return patches
+}
+
+// AppliedFixes is what ApplyFixPatch did
+type AppliedFixes struct {
+	Path    string   // The patch file
+	Applied int      // Hunks applied
+	Files   []string // Files changed
+	Failed  []string // Hunks that no longer apply, as "<file>: @@ ... @@"
+}
+
+// ApplyFixPatch applies the fix patch of the project containing dir and
+// removes it. Each fix applies on its own: one whose lines have changed since
+// is reported in Failed and the others still apply. With dryRun, files and
+// the patch are left as they are.
+func ApplyFixPatch(dir string, dryRun bool) (AppliedFixes, error) {
+	path, err := state.ProjectPath(dir, state.Cache, fixPatchFile)
+	if err != nil {
+		return AppliedFixes{}, err
+	}
+	result := AppliedFixes{Path: path}
+	ctx, cancel := context.WithTimeout(context.Background(), fixPatchLockTimeout)
+	defer cancel()
+	unlock, err := state.Lock(ctx, path+".lock")
+	if err != nil {
+		return result, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("reading fix patch: %w", err)
+	}
+
+	for _, p := range parsePatch(string(data)) {
+		info, err := os.Stat(p.path)
+		if err != nil {
+			for _, h := range p.hunks {
+				result.Failed = append(result.Failed, h.describe(p.path))
+			}
+			continue
+		}
+		content, err := os.ReadFile(p.path)
+		if err != nil {
+			return result, fmt.Errorf("reading %s: %w", p.path, err)
+		}
+		fixed, failed := applyHunks(string(content), p.hunks)
+		for _, h := range failed {
+			result.Failed = append(result.Failed, h.describe(p.path))
+		}
+		result.Applied += len(p.hunks) - len(failed)
+		if fixed == string(content) {
+			continue
+		}
+		if !slices.Contains(result.Files, p.path) {
+			result.Files = append(result.Files, p.path)
+		}
+		if dryRun {
+			continue
+		}
+		// Later fixes of the same file apply to what the earlier ones made
+		if err := os.WriteFile(p.path, []byte(fixed), info.Mode().Perm()); err != nil {
+			return result, fmt.Errorf("writing %s: %w", p.path, err)
+		}
+	}
+
+	if !dryRun {
+		if err := os.Remove(path); err != nil {
+			return result, fmt.Errorf("removing fix patch: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// applyHunks applies the hunks of one file's patch to content. A hunk whose
+// old lines moved is applied where they are now, the nearest match to where
+// the patch expects them; one whose old lines are gone is returned as failed.
+func applyHunks(content string, hunks []hunk) (string, []hunk) {
+	lines := splitLines(content)
+	var failed []hunk
+	shift := 0 // How far earlier hunks moved the lines after them
+	for _, h := range hunks {
+		old, replacement := splitLines(h.oldText), splitLines(h.newText)
+		want := h.oldStart - 1 + shift
+		if h.oldLines == 0 {
+			want = h.oldStart + shift
+		}
+		at := findLines(lines, old, want)
+		if at < 0 {
+			failed = append(failed, h)
+			continue
+		}
+		lines = slices.Replace(lines, at, at+len(old), replacement...)
+		shift += at - want + len(replacement) - len(old)
+	}
+	return strings.Join(lines, ""), failed
+}
+
+// findLines returns where lines has want's lines, the match nearest to near, or -1
+func findLines(lines, want []string, near int) int {
+	if len(want) == 0 {
+		return min(max(near, 0), len(lines))
+	}
+	matches := func(at int) bool {
+		if at < 0 || at+len(want) > len(lines) {
+			return false
+		}
+		for i, w := range want {
+			if strings.TrimSuffix(lines[at+i], "\n") != strings.TrimSuffix(w, "\n") {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; d <= len(lines); d++ {
+		if matches(near - d) {
+			return near - d
+		}
+		if matches(near + d) {
+			return near + d
+		}
+	}
+	return -1
+}
+
+// splitLines splits text into lines that keep their newline
+func splitLines(text string) []string {
+	return slices.Collect(strings.Lines(text))
+}
@@ -0,0 +1,123 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePatch(t *testing.T) {
+	// The removed "-- comment" line would read as a header without the counts
+	patch := "diff --git a/q.sql b/q.sql\n--- a/q.sql\n+++ b/q.sql\n" +
+		"@@ -1,2 +1,1 @@\n--- comment\n select 1;\n" +
+		"--- b.go\n+++ b.go\n@@ -3 +3 @@\n-x\n+y\n\\ No newline at end of file\n"
+	files := parsePatch(patch)
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %+v", files)
+	}
+	if p := files[0]; p.path != "b/q.sql" || len(p.hunks) != 1 || p.hunks[0].oldText != "-- comment\nselect 1;\n" || p.hunks[0].newText != "select 1;\n" {
+		t.Errorf("Unexpected first file: %+v", p)
+	}
+	if p := files[1]; p.path != "b.go" || len(p.hunks) != 1 || p.hunks[0].line != 3 || !strings.HasSuffix(p.String(), "+y\n\\ No newline at end of file\n") {
+		t.Errorf("Unexpected second file: %+v", p)
+	}
+}
+
+func TestSplitHunks(t *testing.T) {
+	patch := "--- a.go\n+++ a.go\n" +
+		"@@ -2,3 +2,2 @@\n ctx\n-\tx := 1\n ctx\n" +
+		"@@ -9,0 +9,1 @@\n+\treturn nil\n"
+	hunks := splitHunks(patch)
+	if len(hunks) != 2 {
+		t.Fatalf("Expected 2 hunks, got %+v", hunks)
+	}
+
+	if h := hunks[0]; h.line != 3 || h.oldLines != 3 || h.newText != "ctx\nctx\n" || !strings.HasPrefix(h.patch, "--- a.go\n+++ a.go\n@@ -2,3") {
+		t.Errorf("Unexpected first hunk: %+v", h)
+	}
+	if start, end := hunks[1].span(); start != 10 || end != 10 || hunks[1].line != 10 || hunks[1].newText != "\treturn nil\n" {
+		t.Errorf("Expected an insertion before line 10, got %d-%d in %+v", start, end, hunks[1])
+	}
+	if strings.Contains(hunks[1].patch, "x := 1") {
+		t.Errorf("Expected each hunk to be a patch of its own, got %q", hunks[1].patch)
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	content := "a\nb\nc\nd\ne\n"
+	hunks := splitHunks("--- f\n+++ f\n@@ -2,2 +2,2 @@\n b\n-c\n+C\n@@ -5,1 +5,2 @@\n e\n+f\n")
+
+	// Two lines added at the top move the old lines; the hunks still find them
+	got, failed := applyHunks("x\ny\n"+content, hunks)
+	if want := "x\ny\na\nb\nC\nd\ne\nf\n"; got != want || len(failed) != 0 {
+		t.Errorf("Expected %q, got %q with %d failed", want, got, len(failed))
+	}
+
+	got, failed = applyHunks("a\nb\nZ\nd\ne\n", hunks)
+	if len(failed) != 1 || failed[0].oldStart != 2 || got != "a\nb\nZ\nd\ne\nf\n" {
+		t.Errorf("Expected only the hunk whose lines changed to fail, got %q with %+v", got, failed)
+	}
+}
+
+func TestWriteAndApplyFixPatch(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".claude-hooks.yaml", "fixes:\n  patch: true\n")
+	writeTestFile(t, dir, "a.go", "package a\n\nfunc A() {\n\tx := 1\n}\n")
+	writeTestFile(t, dir, "b.go", "package a\nvar  B = 1\n")
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+
+	fix := "--- " + a + "\n+++ " + a + "\n@@ -3,3 +3,2 @@\n func A() {\n-\tx := 1\n }\n"
+	diags := []Diagnostic{{File: a, Line: 4, Message: "declared and not used: x", Source: "go", Fix: fix}}
+	path, hunks, err := WriteFixPatch([]string{a, b}, diags, false)
+	if err != nil {
+		t.Fatalf("WriteFixPatch failed: %v", err)
+	}
+	// The fix of a.go, and gofmt's spacing of b.go
+	if hunks != 2 {
+		data, _ := os.ReadFile(path)
+		t.Fatalf("Expected 2 hunks, got %d:\n%s", hunks, data)
+	}
+
+	// Another edit of b.go replaces what the patch had for it and keeps a.go's fix
+	writeTestFile(t, dir, "b.go", "package a\n\nvar B = 1\n")
+	if _, hunks, err := WriteFixPatch([]string{b}, nil, false); err != nil || hunks != 1 {
+		t.Fatalf("Expected only a.go's fix to be left, got %d: %v", hunks, err)
+	}
+
+	dry, err := ApplyFixPatch(dir, true)
+	if err != nil || dry.Applied != 1 {
+		t.Fatalf("Expected a dry run to find 1 fix, got %+v: %v", dry, err)
+	}
+	if data, _ := os.ReadFile(a); !strings.Contains(string(data), "x := 1") {
+		t.Fatal("Expected a dry run to leave the file alone")
+	}
+
+	result, err := ApplyFixPatch(dir, false)
+	if err != nil {
+		t.Fatalf("ApplyFixPatch failed: %v", err)
+	}
+	if result.Applied != 1 || len(result.Files) != 1 || result.Files[0] != a || len(result.Failed) != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "package a\n\nfunc A() {\n}\n" {
+		t.Errorf("Expected the fix applied, got %q", data)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the patch to be removed once applied")
+	}
+	if again, err := ApplyFixPatch(dir, false); err != nil || again.Applied != 0 {
+		t.Errorf("Expected nothing left to apply, got %+v: %v", again, err)
+	}
+}
+
+func TestWriteFixPatchDisabled(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+	writeTestFile(t, dir, "b.go", "package a\nvar  B = 1\n")
+
+	if path, hunks, err := WriteFixPatch([]string{filepath.Join(dir, "b.go")}, nil, false); err != nil || path != "" || hunks != 0 {
+		t.Errorf("Expected no patch without fixes.patch, got %q with %d: %v", path, hunks, err)
+	}
+}
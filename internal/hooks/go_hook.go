@@ -1,5 +1,19 @@
 package hooks
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
 type GoHook struct{}
 
 func (h *GoHook) PreEdit(files []string, verbose bool) error {
@@ -8,11 +22,205 @@ func (h *GoHook) PreEdit(files []string, verbose bool) error {
 }
 
 func (h *GoHook) PostEdit(files []string, verbose bool) error {
-	// Auto checks disabled for speed - run manually if needed
-	return nil
+	return h.PostEditJSON(files, verbose)
 }
 
 func (h *GoHook) PostEditJSON(files []string, verbose bool) error {
-	// Auto checks disabled for speed - run manually if needed
+	cfg, err := config.Load(filepath.Dir(files[0]))
+	if err != nil {
+		return err
+	}
+
+	// A sparse checkout can leave out packages the edited ones import
+	dirs := buildableDirs(packageDirs(files), verbose)
+
+	// Auto checks are disabled for speed unless go.vet opts in to the compile check
+	if cfg.Go.Vet {
+		if err := runGoPerModule(dirs, []string{"vet"}, verbose); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Go.Dependents.Enabled {
+		dependents, err := findDependents(dirs, cfg.Go.Dependents.Max, verbose)
+		if err != nil {
+			return err
+		}
+		if len(dependents) > 0 {
+			if err := verifyGoPackages(dependents, verbose); err != nil {
+				return fmt.Errorf("packages depending on the edited code no longer build or pass tests:\n%w", err)
+			}
+		}
+	}
+
 	return nil
 }
+
+// defaultGoTestTimeout bounds each test binary so a hanging test can't stall Claude
+const defaultGoTestTimeout = 30 * time.Second
+
+// goTestTimeout is go test's -timeout: the "go test" step timeout, or the default
+func goTestTimeout() string {
+	if limit := proc.StepTimeout("go test"); limit > 0 {
+		return limit.String()
+	}
+	return defaultGoTestTimeout.String()
+}
+
+// goTestTimedOut matches go test's report of a test binary it stopped at -timeout
+var goTestTimedOut = regexp.MustCompile(`(?m)^panic: test timed out after (\S+)`)
+
+// testTimeout returns the timeout go test reports in output, or nil. It is
+// recorded as one, not treated as a failure.
+func testTimeout(output []byte) *proc.Timeout {
+	match := goTestTimedOut.FindSubmatch(output)
+	if match == nil {
+		return nil
+	}
+	limit, _ := time.ParseDuration(string(match[1]))
+	t := &proc.Timeout{Step: "go test", Limit: limit}
+	proc.RecordTimeout(t)
+	return t
+}
+
+// verifyGoPackages builds and tests the packages in dirs
+func verifyGoPackages(dirs []string, verbose bool) error {
+	// go test compiles the package and its tests, so it doubles as the build check
+	return runGoPerModule(dirs, []string{"test", "-timeout=" + goTestTimeout()}, verbose)
+}
+
+// runGoPerModule runs `go <args> <packages>` once per module so packages are resolved
+// against the right go.mod. Directories outside any module are checked file by file.
+func runGoPerModule(dirs []string, args []string, verbose bool) error {
+	return runGoPerModuleEnv(dirs, args, nil, verbose)
+}
+
+// runGoPerModuleEnv is runGoPerModule with env added to go's environment
+func runGoPerModuleEnv(dirs []string, args []string, env []string, verbose bool) error {
+	type target struct {
+		dir   string
+		flags []string // -mod flags of the module
+		pkgs  []string
+	}
+	var targets []*target
+	byRoot := make(map[string]*target)
+
+	for _, dir := range dirs {
+		moduleRoot, err := findModuleRoot(dir)
+		if err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(filepath.Join(moduleRoot, "go.mod")); err != nil {
+			files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+			targets = append(targets, &target{dir: dir, pkgs: files})
+			continue
+		}
+
+		rel, err := filepath.Rel(moduleRoot, dir)
+		if err != nil {
+			return fmt.Errorf("resolving package %s: %w", dir, err)
+		}
+		t := byRoot[moduleRoot]
+		if t == nil {
+			t = &target{dir: moduleRoot, flags: goModFlags(moduleRoot)}
+			byRoot[moduleRoot] = t
+			targets = append(targets, t)
+		}
+		if pkg := "./" + filepath.ToSlash(rel); !slices.Contains(t.pkgs, pkg) {
+			t.pkgs = append(t.pkgs, pkg)
+		}
+	}
+
+	var failures []string
+	for _, t := range targets {
+		if len(t.pkgs) == 0 {
+			continue
+		}
+		cmdArgs := slices.Concat(args[:1], t.flags, args[1:], t.pkgs)
+		vlog.Printf(verbose, "🔧 go %s (in %s)\n", strings.Join(cmdArgs, " "), t.dir)
+
+		cmd := proc.Command("go", cmdArgs...)
+		cmd.Dir = t.dir
+		if len(env) > 0 {
+			if cmd.Env == nil {
+				cmd.Env = os.Environ()
+			}
+			cmd.Env = append(cmd.Env, env...)
+		}
+		output, err := cmd.CombinedOutput()
+		vlog.Output("go "+args[0], output)
+		if err != nil && !proc.Killed(cmd) && testTimeout(output) == nil {
+			failures = append(failures, ResolvePaths(strings.TrimSpace(string(output)), t.dir))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "\n\n"))
+	}
+	return nil
+}
+
+// findDependents returns the directories of packages that directly import any of the
+// edited package dirs, excluding the edited packages themselves, capped at limit
+func findDependents(dirs []string, limit int, verbose bool) ([]string, error) {
+	importPaths := make(map[string][]string) // module root -> edited import paths
+	for _, dir := range dirs {
+		moduleRoot, err := findModuleRoot(dir)
+		if err != nil {
+			return nil, err
+		}
+		importPath, err := importPathForDir(moduleRoot, dir)
+		if err != nil {
+			continue // Not part of a module, so nothing can import it
+		}
+		importPaths[moduleRoot] = append(importPaths[moduleRoot], importPath)
+	}
+
+	var dependents []string
+	for moduleRoot, paths := range importPaths {
+		rels, err := findImporters(moduleRoot, paths...)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range rels {
+			dir := filepath.Join(moduleRoot, strings.TrimPrefix(rel, "./"))
+			if !slices.Contains(dirs, dir) && !slices.Contains(dependents, dir) {
+				dependents = append(dependents, dir)
+			}
+		}
+	}
+	slices.Sort(dependents)
+	dependents = buildableDirs(dependents, verbose)
+
+	if limit > 0 && len(dependents) > limit {
+		vlog.Printf(verbose, "⚠️  %d dependent packages found, only checking the first %d\n", len(dependents), limit)
+		dependents = dependents[:limit]
+	}
+
+	if verbose && len(dependents) > 0 {
+		fmt.Fprintf(os.Stderr, "🔗 Checking %d dependent package(s)\n", len(dependents))
+	}
+	return dependents, nil
+}
+
+// packageDirs returns the unique absolute package directories of files
+func packageDirs(files []string) []string {
+	var dirs []string
+	for _, f := range files {
+		dir := filepath.Dir(f)
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// hasGoFiles reports whether dir still contains any Go source files
+func hasGoFiles(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	return len(matches) > 0
+}
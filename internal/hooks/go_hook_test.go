@@ -0,0 +1,101 @@
+package hooks
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+)
+
+func TestGoHookChecksDependents(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "go.mod", "module example.com/deps\n\ngo 1.25\n")
+	writeTestFile(t, repo, "util/util.go", "package util\n\nfunc Hello(name string) string { return \"hi \" + name }\n")
+	writeTestFile(t, repo, "app/app.go", "package app\n\nimport \"example.com/deps/util\"\n\nvar Greeting = util.Hello()\n")
+	writeTestFile(t, repo, "other/other.go", "package other\n")
+
+	dependents, err := findDependents([]string{filepath.Join(repo, "util")}, 0, false)
+	if err != nil {
+		t.Fatalf("findDependents failed: %v", err)
+	}
+	if len(dependents) != 1 || dependents[0] != filepath.Join(repo, "app") {
+		t.Fatalf("Expected only app to depend on util, got %v", dependents)
+	}
+
+	hook := &GoHook{}
+	edited := []string{filepath.Join(repo, "util/util.go")}
+
+	// Without opting in, only the edited package is checked and it compiles fine
+	if err := hook.PostEditJSON(edited, false); err != nil {
+		t.Fatalf("Expected edited package to pass on its own, got: %v", err)
+	}
+
+	writeTestFile(t, repo, ".claude-hooks.yaml", "go:\n  dependents:\n    enabled: true\n")
+	err = hook.PostEditJSON(edited, false)
+	if err == nil || !strings.Contains(err.Error(), "not enough arguments") {
+		t.Fatalf("Expected broken dependent to be reported, got: %v", err)
+	}
+}
+
+func TestGoTestTimeout(t *testing.T) {
+	if got := goTestTimeout(); got != "30s" {
+		t.Errorf("Expected the default -timeout, got %s", got)
+	}
+	proc.SetStepTimeouts(map[string]time.Duration{"go test": 2 * time.Minute})
+	defer proc.SetStepTimeouts(nil)
+	if got := goTestTimeout(); got != "2m0s" {
+		t.Errorf("Expected the go test step's limit, got %s", got)
+	}
+
+	end := proc.Bound("go", 0)
+	if testTimeout([]byte("--- FAIL: TestA (0.00s)\nFAIL\n")) != nil {
+		t.Error("Expected a failing test not to be a timeout")
+	}
+	output := "panic: test timed out after 2m0s\n\trunning tests:\n\t\tTestSlow (2m0s)\n"
+	if testTimeout([]byte(output)) == nil {
+		t.Error("Expected go test's timeout panic to be a timeout")
+	}
+	timeouts := end()
+	if len(timeouts) != 1 || timeouts[0].Killed || timeouts[0].Error() != "step go test exceeded 2m0s and was aborted" {
+		t.Errorf("Expected go test's own timeout to be recorded, got %+v", timeouts)
+	}
+}
+
+func TestGoHookVendoredModule(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "go.mod", "module example.com/app\n\ngo 1.22\n\nrequire example.com/dep v1.0.0\n")
+	writeTestFile(t, repo, "vendor/modules.txt", "# example.com/dep v1.0.0\n## explicit\nexample.com/dep\n")
+	writeTestFile(t, repo, "vendor/example.com/dep/dep.go", "package dep\n\nfunc Answer() int { return 42 }\n")
+	writeTestFile(t, repo, "app.go", "package app\n\nimport \"example.com/dep\"\n\nvar X = dep.Answer()\n")
+	// Without -mod=vendor on the command line, go would try to download dep
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOWORK", "")
+
+	if got := goModFlags(repo); !slices.Equal(got, []string{"-mod=vendor"}) {
+		t.Fatalf("Expected a vendored module to get -mod=vendor, got %v", got)
+	}
+	if err := (&GoHook{}).PostEditJSON([]string{filepath.Join(repo, "app.go")}, false); err != nil {
+		t.Fatalf("Expected the vendored module to build, got: %v", err)
+	}
+
+	// In a workspace, the workspace's vendor directory is the one go uses
+	workspace := t.TempDir()
+	writeTestFile(t, workspace, "go.work", "go 1.22\n\nuse ./svc\n")
+	writeTestFile(t, workspace, "svc/go.mod", "module example.com/svc\n\ngo 1.22\n")
+	svc := filepath.Join(workspace, "svc")
+	if got := goModFlags(svc); got != nil {
+		t.Errorf("Expected no -mod flag without a workspace vendor directory, got %v", got)
+	}
+	writeTestFile(t, workspace, "vendor/modules.txt", "")
+	if got := goModFlags(svc); !slices.Equal(got, []string{"-mod=vendor"}) {
+		t.Errorf("Expected the workspace vendor directory to count, got %v", got)
+	}
+	t.Setenv("GOWORK", "off")
+	if got := goModFlags(svc); got != nil {
+		t.Errorf("Expected GOWORK=off to ignore the workspace, got %v", got)
+	}
+}
@@ -0,0 +1,289 @@
+package hooks
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// Checks of go.errors
+const (
+	ErrorsUnwrapped = "unwrapped"  // Errors of other packages returned as is, or formatted with %v
+	ErrorsDiscarded = "discarded"  // Errors assigned to _
+	ErrorsNoContext = "no-context" // errors.New messages that don't say what failed
+)
+
+// genericErrorMessages say something failed without saying what
+var genericErrorMessages = []string{"error", "failed", "failure", "invalid", "invalid input", "invalid argument", "bad request", "unknown error", "internal error", "something went wrong", "unexpected error", "oops"}
+
+// ErrorIssue is a way an edited Go function handles an error that go.errors
+// flags
+type ErrorIssue struct {
+	File         string // Absolute
+	Line, Column int    // 1-based
+	Check        string
+	Message      string
+	Block        bool // go.errors.block is set
+}
+
+func (e ErrorIssue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", e.File, e.Line, e.Column, e.Message, e.Check)
+}
+
+// Diagnostic reports the issue; severity is "error" when go.errors.block is set
+func (e ErrorIssue) Diagnostic() Diagnostic {
+	severity := "warning"
+	if e.Block {
+		severity = "error"
+	}
+	return Diagnostic{File: e.File, Line: e.Line, Column: e.Column, Severity: severity, Message: e.Message, Source: "go-errors"}
+}
+
+// FindGoErrorIssues returns how the edited Go files mishandle errors on lines
+// changed since HEAD: errors from other packages returned without context,
+// errors discarded into _, and errors.New messages too vague to act on. The
+// remediation names the project's go.errors.wrap idiom. Test files are left
+// out. It is a no-op unless go.errors.enabled is set.
+func FindGoErrorIssues(files []string, verbose bool) ([]ErrorIssue, error) {
+	var found []ErrorIssue
+	for _, f := range files {
+		if filepath.Ext(f) != ".go" || isTestFile(f) {
+			continue
+		}
+		cfg, err := config.Load(filepath.Dir(f))
+		if err != nil {
+			return found, err
+		}
+		if !cfg.Go.Errors.Enabled {
+			continue
+		}
+		issues, err := goErrorIssues(f, cfg.Go.Errors)
+		if err != nil {
+			vlog.Printf(verbose, "⏭️  Skipping error handling check of %s: %v\n", f, err)
+			continue
+		}
+		changed := changedRanges(state.ProjectRoot(filepath.Dir(f)), f)
+		for _, issue := range issues {
+			if overlaps(changed, issue.Line, issue.Line) {
+				found = append(found, issue)
+			}
+		}
+	}
+	return found, nil
+}
+
+// goErrorIssues returns the issues of every function in a Go file
+func goErrorIssues(file string, cfg config.GoErrorsConfig) ([]ErrorIssue, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file, nil, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	imports := importNames(parsed)
+	commented := make(map[int]bool) // Lines with a comment, which may say why an error is dropped
+	for _, group := range parsed.Comments {
+		for _, c := range group.List {
+			commented[fset.Position(c.Pos()).Line] = true
+		}
+	}
+	wrap := cfg.Wrap
+	if wrap == "" {
+		wrap = config.DefaultErrorsWrap
+	}
+
+	var issues []ErrorIssue
+	add := func(node ast.Node, check, message string) {
+		if slices.Contains(cfg.Disabled, check) {
+			return
+		}
+		pos := fset.Position(node.Pos())
+		issues = append(issues, ErrorIssue{File: file, Line: pos.Line, Column: pos.Column, Check: check, Message: message, Block: cfg.Block})
+	}
+
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.BlockStmt:
+				unwrappedReturns(n.List, imports, wrap, add)
+			case *ast.CaseClause:
+				unwrappedReturns(n.Body, imports, wrap, add)
+			case *ast.CommClause:
+				unwrappedReturns(n.Body, imports, wrap, add)
+			case *ast.AssignStmt:
+				if call, ok := discardedError(n); ok && !commented[fset.Position(n.Pos()).Line] {
+					add(n, ErrorsDiscarded, fmt.Sprintf("the error of %s is discarded into _; handle it, return it wrapped like %s, or say in a comment on the line why it can't fail", callName(call), wrap))
+				}
+			case *ast.CallExpr:
+				switch callName(n) {
+				case imports["errors"] + ".New":
+					if msg, ok := stringArg(n, 0); ok && vagueMessage(msg) {
+						add(n, ErrorsNoContext, fmt.Sprintf("errors.New(%q) doesn't say what failed; say what was being done and with which input, or declare it as a package-level sentinel error", msg))
+					}
+				case imports["fmt"] + ".Errorf":
+					if format, ok := stringArg(n, 0); ok && !strings.Contains(format, "%w") && slices.ContainsFunc(n.Args[1:], isErrorName) {
+						add(n, ErrorsUnwrapped, "fmt.Errorf formats the error without %w, so errors.Is and errors.As can't see it; use %w")
+					}
+				}
+			}
+			return true
+		})
+	}
+	return issues, nil
+}
+
+// unwrappedReturns reports returns of an error, fresh from a call into
+// another package, as is: "x, err := pkg.F(); if err != nil { return err }"
+func unwrappedReturns(stmts []ast.Stmt, imports map[string]string, wrap string, add func(ast.Node, string, string)) {
+	for i, stmt := range stmts {
+		ifStmt, ok := stmt.(*ast.IfStmt)
+		if !ok {
+			continue
+		}
+		name := nilCheck(ifStmt.Cond)
+		if name == "" {
+			continue
+		}
+		var source ast.Stmt = ifStmt.Init
+		if source == nil && i > 0 {
+			source = stmts[i-1]
+		}
+		call := assignedCall(source, name)
+		if call == nil {
+			continue
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			continue
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok || !slices.Contains(slices.Collect(maps.Values(imports)), pkg.Name) {
+			continue
+		}
+		for _, s := range ifStmt.Body.List {
+			ret, ok := s.(*ast.ReturnStmt)
+			if !ok || len(ret.Results) == 0 {
+				continue
+			}
+			if last, ok := ret.Results[len(ret.Results)-1].(*ast.Ident); ok && last.Name == name {
+				add(ret, ErrorsUnwrapped, fmt.Sprintf("the error of %s.%s is returned as is, without what was being done; wrap it like %s", pkg.Name, sel.Sel.Name, wrap))
+			}
+		}
+	}
+}
+
+// nilCheck returns the name compared in "name != nil", or ""
+func nilCheck(cond ast.Expr) string {
+	bin, ok := cond.(*ast.BinaryExpr)
+	if !ok || bin.Op != token.NEQ {
+		return ""
+	}
+	x, ok := bin.X.(*ast.Ident)
+	if y, isNil := bin.Y.(*ast.Ident); !ok || !isNil || y.Name != "nil" {
+		return ""
+	}
+	return x.Name
+}
+
+// assignedCall returns the call stmt assigns name from, or nil
+func assignedCall(stmt ast.Stmt, name string) *ast.CallExpr {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || len(assign.Rhs) != 1 {
+		return nil
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return nil
+	}
+	for _, lhs := range assign.Lhs {
+		if ident, ok := lhs.(*ast.Ident); ok && ident.Name == name {
+			return call
+		}
+	}
+	return nil
+}
+
+// discardedError reports an assignment of a call's result, or its last
+// result, which is an error by convention, to _
+func discardedError(assign *ast.AssignStmt) (*ast.CallExpr, bool) {
+	if len(assign.Rhs) != 1 || len(assign.Lhs) == 0 {
+		return nil, false
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return nil, false
+	}
+	last, ok := assign.Lhs[len(assign.Lhs)-1].(*ast.Ident)
+	return call, ok && last.Name == "_"
+}
+
+// callName is how a call is written: "pkg.F", "x.Method", or "f"
+func callName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		if x, ok := fun.X.(*ast.Ident); ok {
+			return x.Name + "." + fun.Sel.Name
+		}
+		return fun.Sel.Name
+	}
+	return "the call"
+}
+
+// stringArg returns the call's i-th argument when it's a string literal
+func stringArg(call *ast.CallExpr, i int) (string, bool) {
+	if len(call.Args) <= i {
+		return "", false
+	}
+	lit, ok := call.Args[i].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// vagueMessage reports an error message too short or generic to act on
+func vagueMessage(msg string) bool {
+	msg = strings.ToLower(strings.TrimRight(strings.TrimSpace(msg), ".!"))
+	return len(strings.Fields(msg)) < 2 || slices.Contains(genericErrorMessages, msg)
+}
+
+// isErrorName reports an argument named like an error: err, or ending in Err
+func isErrorName(arg ast.Expr) bool {
+	ident, ok := arg.(*ast.Ident)
+	return ok && (ident.Name == "err" || strings.HasSuffix(ident.Name, "Err"))
+}
+
+// importNames maps the import paths of a file to the names they're used by
+func importNames(file *ast.File) map[string]string {
+	names := make(map[string]string)
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := filepath.Base(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name != "_" && name != "." {
+			names[path] = name
+		}
+	}
+	return names
+}
@@ -0,0 +1,76 @@
+package hooks
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindGoErrorIssues(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "go.mod", "module example.com/app\n\ngo 1.22\n")
+	writeTestFile(t, root, ".claude-hooks.yaml", "go:\n  errors:\n    enabled: true\n    wrap: errors.Wrap(err, \"loading config\")\n")
+	writeTestFile(t, root, "store/store.go", `package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+var ErrClosed = errors.New("closed")
+
+func Load(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := check(data); err != nil {
+		return nil, err
+	}
+	if _, err := strconv.Atoi(string(data)); err != nil {
+		return nil, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("invalid")
+	}
+	n, _ := strconv.Atoi("1")
+	_ = os.Remove(path) // Best effort: a leftover temp file is harmless
+	_ = os.Remove(path + ".bak")
+	return data[:n], nil
+}
+
+func check([]byte) error { return errors.New("the config file is empty") }
+`)
+	writeTestFile(t, root, "store/store_test.go", "package store\n\nfunc f() { _ = g() }\n")
+	files := []string{filepath.Join(root, "store", "store.go"), filepath.Join(root, "store", "store_test.go")}
+
+	issues, err := FindGoErrorIssues(files, false)
+	if err != nil {
+		t.Fatalf("FindGoErrorIssues failed: %v", err)
+	}
+	var got []string
+	for _, i := range issues {
+		got = append(got, fmt.Sprintf("%d %s", i.Line, i.Check))
+		if i.Check == ErrorsUnwrapped && strings.Contains(i.Message, "os.ReadFile") && !strings.Contains(i.Message, `errors.Wrap(err, "loading config")`) {
+			t.Errorf("Expected the project's idiom in %q", i.Message)
+		}
+	}
+	want := []string{"15 unwrapped", "21 unwrapped", "24 no-context", "26 discarded", "28 discarded"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Unexpected issues:\n%s", strings.Join(got, "\n"))
+	}
+
+	writeTestFile(t, root, ".claude-hooks.yaml", "go:\n  errors:\n    enabled: true\n    disabled: [discarded, no-context]\n    block: true\n")
+	issues, err = FindGoErrorIssues(files, false)
+	if err != nil || len(issues) != 2 || !issues[0].Block || issues[0].Diagnostic().Severity != "error" {
+		t.Fatalf("Expected two blocking unwrapped issues, got %+v (%v)", issues, err)
+	}
+
+	writeTestFile(t, root, ".claude-hooks.yaml", "")
+	if issues, err := FindGoErrorIssues(files, false); err != nil || len(issues) != 0 {
+		t.Fatalf("Expected nothing when disabled, got %+v (%v)", issues, err)
+	}
+}
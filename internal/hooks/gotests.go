@@ -0,0 +1,308 @@
+package hooks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// goTestRunsFile holds, per package directory, the content hash of the
+// session's last passing test run; "" when it hasn't passed since it changed
+const goTestRunsFile = "go-tests.json"
+
+// goTestLockTimeout bounds the wait for hooks of the same session running in parallel
+const goTestLockTimeout = 5 * time.Second
+
+// Session is the Claude session whose test runs and given conventions are
+// remembered
+type Session struct {
+	Dir            string // Any directory of the project
+	ID             string
+	TranscriptPath string
+}
+
+// TestRun is the outcome of testing Go packages
+type TestRun struct {
+	Results map[string]bool // Import path -> passed, for the packages that ran
+	Skipped []string        // Import paths that already passed at the same content
+}
+
+// TestGoPackages runs the tests of the packages of the edited Go files,
+// skipping those whose tests already passed in the session with the same
+// content. Every package is remembered, so TestSessionPackages can run them
+// all again before the turn ends. The error holds the output of the failing
+// tests. It is a no-op unless go.tests.enabled is set.
+func TestGoPackages(files []string, s Session, verbose bool) (TestRun, error) {
+	var sources []string
+	for _, f := range files {
+		if strings.HasSuffix(f, ".go") {
+			sources = append(sources, f)
+		}
+	}
+	if len(sources) == 0 {
+		return TestRun{}, nil
+	}
+	cfg, err := config.Load(filepath.Dir(sources[0]))
+	if err != nil {
+		return TestRun{}, err
+	}
+	if !cfg.Go.Tests.Enabled {
+		return TestRun{}, nil
+	}
+
+	passed := readTestRuns(s, verbose)
+	var run TestRun
+	var dirs []string
+	hashes := make(map[string]string)
+	for _, dir := range buildableDirs(packageDirs(sources), verbose) {
+		if !hasGoFiles(dir) {
+			continue
+		}
+		hash := packageHash(dir)
+		hashes[dir] = hash
+		if hash != "" && passed[dir] == hash {
+			recordCacheLookup(true)
+			run.Skipped = append(run.Skipped, dir)
+			continue
+		}
+		recordCacheLookup(false)
+		dirs = append(dirs, dir)
+	}
+	if verbose && len(run.Skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "⏭️  Skipping tests of %d package(s) that already passed this session\n", len(run.Skipped))
+	}
+	for i, dir := range run.Skipped {
+		run.Skipped[i] = packageImportPath(dir)
+	}
+
+	run.Results, err = runGoTests(dirs, hashes, s, verbose)
+	return run, err
+}
+
+// TestSessionPackages runs the tests of every package tested during the
+// session, whether or not they passed earlier: a package's tests can break
+// through the packages it imports without its own files changing
+func TestSessionPackages(s Session, verbose bool) (TestRun, error) {
+	var dirs []string
+	hashes := make(map[string]string)
+	for dir := range readTestRuns(s, verbose) {
+		if hasGoFiles(dir) {
+			dirs = append(dirs, dir)
+			hashes[dir] = packageHash(dir)
+		}
+	}
+	slices.Sort(dirs)
+	results, err := runGoTests(dirs, hashes, s, verbose)
+	return TestRun{Results: results}, err
+}
+
+// goTestLine matches go test's per-package summary: "ok", "FAIL", or "?" for
+// packages without tests, then the import path
+var goTestLine = regexp.MustCompile(`^(ok|FAIL|\?)\s+(\S+)`)
+
+// runGoTests tests the packages in dirs once per module and records which
+// passed, at the given content hashes, in the session
+func runGoTests(dirs []string, hashes map[string]string, s Session, verbose bool) (map[string]bool, error) {
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+	type module struct {
+		pkgs map[string]string // Import path -> dir
+		args []string
+	}
+	modules := make(map[string]*module)
+	var roots []string
+	for _, dir := range dirs {
+		moduleRoot, err := findModuleRoot(dir)
+		if err != nil {
+			return nil, err
+		}
+		importPath, err := importPathForDir(moduleRoot, dir)
+		if err != nil {
+			continue // Outside a module, go vet already checked it file by file
+		}
+		rel, err := filepath.Rel(moduleRoot, dir)
+		if err != nil {
+			return nil, fmt.Errorf("resolving package %s: %w", dir, err)
+		}
+		m := modules[moduleRoot]
+		if m == nil {
+			m = &module{pkgs: make(map[string]string)}
+			modules[moduleRoot] = m
+			roots = append(roots, moduleRoot)
+		}
+		m.pkgs[importPath] = dir
+		m.args = append(m.args, "./"+filepath.ToSlash(rel))
+	}
+
+	results := make(map[string]bool)
+	outcome := make(map[string]string) // Dir -> hash, "" when failed
+	var failures []string
+	for _, root := range roots {
+		m := modules[root]
+		args := slices.Concat([]string{"test"}, goModFlags(root), []string{"-timeout=" + goTestTimeout()}, m.args)
+		vlog.Printf(verbose, "🔧 go %s (in %s)\n", strings.Join(args, " "), root)
+		cmd := proc.Command("go", args...)
+		cmd.Dir = root
+		output, err := cmd.CombinedOutput()
+		vlog.Output("go test", output)
+		killed := proc.Killed(cmd)
+
+		reported := make(map[string]bool)
+		for line := range strings.Lines(string(output)) {
+			match := goTestLine.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			if _, ok := m.pkgs[match[2]]; ok {
+				reported[match[2]] = match[1] != "FAIL"
+			}
+		}
+		for importPath, dir := range m.pkgs {
+			// A package missing from the summary didn't get to run
+			ok, found := reported[importPath]
+			if !found && killed {
+				continue // Cut off before it finished, so it neither passed nor failed
+			}
+			passed := ok || (!found && err == nil)
+			results[importPath] = passed
+			if passed {
+				outcome[dir] = hashes[dir]
+			} else {
+				outcome[dir] = ""
+			}
+		}
+		if err != nil && !killed && testTimeout(output) == nil {
+			failures = append(failures, ResolvePaths(strings.TrimSpace(string(output)), root))
+		}
+	}
+
+	if err := updateTestRuns(s, func(passed map[string]string) {
+		for dir, hash := range outcome {
+			passed[dir] = hash
+		}
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not record test runs: %v\n", err)
+	}
+
+	if len(failures) > 0 {
+		return results, fmt.Errorf("%s", strings.Join(failures, "\n\n"))
+	}
+	return results, nil
+}
+
+// readTestRuns returns the session's test runs, or none when they can't be read
+func readTestRuns(s Session, verbose bool) map[string]string {
+	passed := make(map[string]string)
+	if s.ID == "" {
+		return passed
+	}
+	path, err := state.SessionPath(s.Dir, s.ID, s.TranscriptPath, goTestRunsFile)
+	if err != nil {
+		vlog.Printf(verbose, "⚠️  Could not read test runs: %v\n", err)
+		return passed
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &passed)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) && verbose {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not read test runs: %v\n", err)
+	}
+	return passed
+}
+
+// updateTestRuns applies change to the session's test runs under a lock, so
+// hooks of the same session running in parallel don't lose each other's runs
+func updateTestRuns(s Session, change func(map[string]string)) error {
+	if s.ID == "" {
+		return nil
+	}
+	path, err := state.SessionPath(s.Dir, s.ID, s.TranscriptPath, goTestRunsFile)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), goTestLockTimeout)
+	defer cancel()
+	unlock, err := state.Lock(ctx, path+".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	passed := make(map[string]string)
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &passed) // A corrupt file starts over
+	}
+	change(passed)
+	data, err := json.MarshalIndent(passed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding test runs: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing test runs: %w", err)
+	}
+	return nil
+}
+
+// packageHash hashes what the tests of the package in dir read: its Go files,
+// its testdata, and the module's go.mod and go.sum. Packages it imports aren't
+// included, which is why the Stop hook tests everything again. It is "" when a
+// file can't be read.
+func packageHash(dir string) string {
+	var files []string
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	files = append(files, matches...)
+	_ = filepath.WalkDir(filepath.Join(dir, "testdata"), func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if moduleRoot, err := findModuleRoot(dir); err == nil {
+		files = append(files, filepath.Join(moduleRoot, "go.mod"), filepath.Join(moduleRoot, "go.sum"))
+	}
+
+	h := sha256.New()
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if errors.Is(err, fs.ErrNotExist) && filepath.Base(f) == "go.sum" {
+			continue // Modules without dependencies have none
+		}
+		if err != nil {
+			return ""
+		}
+		fmt.Fprintf(h, "%s %d\n", f, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// packageImportPath returns the import path of the package in dir, or dir
+// itself outside a module
+func packageImportPath(dir string) string {
+	moduleRoot, err := findModuleRoot(dir)
+	if err != nil {
+		return dir
+	}
+	importPath, err := importPathForDir(moduleRoot, dir)
+	if err != nil {
+		return dir
+	}
+	return importPath
+}
@@ -0,0 +1,79 @@
+package hooks
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGoTestsSkipPackagesAlreadyGreen(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	repo := t.TempDir()
+	writeTestFile(t, repo, ".claude-hooks.yaml", "go:\n  tests:\n    enabled: true\n")
+	writeTestFile(t, repo, "go.mod", "module example.com/tests\n\ngo 1.25\n")
+	writeTestFile(t, repo, "util/util.go", "package util\n\nfunc Double(n int) int { return n * 2 }\n")
+	writeTestFile(t, repo, "app/app.go", "package app\n\nimport \"example.com/tests/util\"\n\nfunc Four() int { return util.Double(2) }\n")
+	writeTestFile(t, repo, "app/app_test.go", "package app\n\nimport \"testing\"\n\nfunc TestFour(t *testing.T) {\n\tif Four() != 4 {\n\t\tt.Fatal(\"Expected 4\")\n\t}\n}\n")
+
+	session := Session{Dir: repo, ID: "session-1"}
+	util := filepath.Join(repo, "util/util.go")
+	app := filepath.Join(repo, "app/app.go")
+
+	run, err := TestGoPackages([]string{util, app}, session, false)
+	if err != nil {
+		t.Fatalf("Expected tests to pass, got: %v", err)
+	}
+	if !run.Results["example.com/tests/app"] || !run.Results["example.com/tests/util"] || len(run.Skipped) != 0 {
+		t.Fatalf("Expected both packages to run and pass, got %+v", run)
+	}
+
+	// Unchanged since passing: skipped
+	run, err = TestGoPackages([]string{app}, session, false)
+	if err != nil {
+		t.Fatalf("Expected skipped tests to pass, got: %v", err)
+	}
+	if len(run.Results) != 0 || !slices.Equal(run.Skipped, []string{"example.com/tests/app"}) {
+		t.Fatalf("Expected app to be skipped, got %+v", run)
+	}
+
+	// Other sessions have their own runs
+	run, _ = TestGoPackages([]string{app}, Session{Dir: repo, ID: "session-2"}, false)
+	if len(run.Skipped) != 0 {
+		t.Fatalf("Expected a new session to run the tests, got %+v", run)
+	}
+
+	// Breaking util breaks app's tests without changing app's files, which
+	// only the full pass catches
+	writeTestFile(t, repo, "util/util.go", "package util\n\nfunc Double(n int) int { return n * 3 }\n")
+	run, err = TestGoPackages([]string{util}, session, false)
+	if err != nil || !run.Results["example.com/tests/util"] {
+		t.Fatalf("Expected util's own tests to pass, got %+v: %v", run, err)
+	}
+	run, err = TestSessionPackages(session, false)
+	if err == nil || !strings.Contains(err.Error(), "Expected 4") {
+		t.Fatalf("Expected the full pass to report app's failure, got: %v", err)
+	}
+	if run.Results["example.com/tests/app"] || !run.Results["example.com/tests/util"] {
+		t.Fatalf("Expected only app to fail, got %+v", run.Results)
+	}
+
+	// A failed package runs again even though its files didn't change
+	writeTestFile(t, repo, "util/util.go", "package util\n\nfunc Double(n int) int { return n * 2 }\n")
+	run, err = TestGoPackages([]string{app}, session, false)
+	if err != nil || !run.Results["example.com/tests/app"] {
+		t.Fatalf("Expected app to run again and pass, got %+v: %v", run, err)
+	}
+}
+
+func TestGoTestsDisabled(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	repo := t.TempDir()
+	writeTestFile(t, repo, "go.mod", "module example.com/tests\n\ngo 1.25\n")
+	writeTestFile(t, repo, "a/a_test.go", "package a\n\nimport \"testing\"\n\nfunc TestFail(t *testing.T) { t.Fatal(\"boom\") }\n")
+
+	run, err := TestGoPackages([]string{filepath.Join(repo, "a/a_test.go")}, Session{Dir: repo, ID: "s"}, false)
+	if err != nil || len(run.Results) != 0 {
+		t.Fatalf("Expected no tests without go.tests.enabled, got %+v: %v", run, err)
+	}
+}
@@ -21,6 +21,12 @@ func init() {
 	registry["go"] = &GoHook{}
 	registry["typescript"] = &TypeScriptHook{}
 	registry["javascript"] = &TypeScriptHook{} // Reuse TS hook for JS
+	registry["proto"] = &ProtoHook{}
+	registry["rust"] = &RustHook{}
+	registry["bazel"] = &BazelHook{}
+	registry["buck2"] = &BazelHook{}
+	registry["nx"] = &TaskRunnerHook{}
+	registry["turbo"] = &TaskRunnerHook{}
 }
 
 // GetHook returns the hook for the given file type
@@ -137,6 +137,10 @@ func main() {
 	if err != nil {
 		t.Fatalf("Failed to create broken Go file: %v", err)
 	}
+	// The Go hook only compile-checks when go.vet is on
+	if err := os.WriteFile(filepath.Join(tmpDir, ".claude-hooks.yaml"), []byte("go:\n  vet: true\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
 
 	// Simulate Claude hook input
 	input := struct {
@@ -0,0 +1,203 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// Checks of images
+const (
+	ImageUnpinned = "unpinned" // No tag, or latest
+	ImageDigest   = "digest"   // No digest, with images.require_digest
+	ImageRegistry = "registry" // Not from images.registries
+)
+
+// ImageIssue is a container image reference on a changed line that isn't
+// pinned, or comes from a registry the project doesn't allow
+type ImageIssue struct {
+	File    string // Absolute
+	Line    int    // 1-based
+	Image   string
+	Check   string
+	Message string
+	Block   bool // images.block is set
+}
+
+func (i ImageIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", i.File, i.Line, i.Message, i.Check)
+}
+
+// Diagnostic reports the issue; severity is "error" when images.block is set
+func (i ImageIssue) Diagnostic() Diagnostic {
+	severity := "warning"
+	if i.Block {
+		severity = "error"
+	}
+	return Diagnostic{File: i.File, Line: i.Line, Severity: severity, Message: i.Message, Source: "images"}
+}
+
+var (
+	// dockerFrom matches a FROM instruction: its image, and stage name if any
+	dockerFrom = regexp.MustCompile(`(?i)^\s*FROM\s+(?:--\S+\s+)*(\S+)(?:\s+AS\s+(\S+))?`)
+	// yamlImage matches an image key of a compose file or Kubernetes manifest
+	yamlImage = regexp.MustCompile(`^\s*(?:-\s*)?image:\s*["']?([^"'\s#]+)`)
+	// kubernetesKind tells a Kubernetes manifest from other YAML
+	kubernetesKind = regexp.MustCompile(`(?m)^kind:\s*\S`)
+)
+
+// FindImageIssues returns the image references on the changed lines of the
+// edited Dockerfiles, compose files, and Kubernetes manifests that have no
+// tag or use latest, lack a digest when images.require_digest is set, or come
+// from outside images.registries. References built from variables or
+// templates, and earlier build stages, are left alone. It is a no-op when
+// images.enabled is turned off.
+func FindImageIssues(files []string, verbose bool) ([]ImageIssue, error) {
+	var found []ImageIssue
+	for _, f := range files {
+		kind := imageFileKind(f)
+		if kind == "" {
+			continue
+		}
+		cfg, err := config.Load(filepath.Dir(f))
+		if err != nil {
+			return found, err
+		}
+		if !cfg.Images.Enabled {
+			continue
+		}
+		content, err := os.ReadFile(f)
+		if err != nil {
+			continue // Deleted since
+		}
+		if kind == "yaml" && !kubernetesKind.Match(content) && !isComposeFile(f) {
+			continue
+		}
+		changed := changedRanges(state.ProjectRoot(filepath.Dir(f)), f)
+		for _, ref := range imageReferences(kind, string(content)) {
+			if !overlaps(changed, ref.line, ref.line) {
+				continue
+			}
+			for _, issue := range imageIssues(ref.image, cfg.Images) {
+				issue.File, issue.Line, issue.Block = f, ref.line, cfg.Images.Block
+				found = append(found, issue)
+			}
+		}
+	}
+	vlog.Printf(verbose, "🐳 %d image issue(s)\n", len(found))
+	return found, nil
+}
+
+// imageFileKind is "dockerfile" or "yaml" for files that may refer to
+// images, and "" for the rest
+func imageFileKind(file string) string {
+	name := strings.ToLower(filepath.Base(file))
+	switch {
+	case name == "dockerfile" || name == "containerfile" || strings.HasPrefix(name, "dockerfile.") || strings.HasSuffix(name, ".dockerfile"):
+		return "dockerfile"
+	case strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml"):
+		return "yaml"
+	}
+	return ""
+}
+
+// isComposeFile reports a Docker Compose file by its name
+func isComposeFile(file string) bool {
+	name := strings.ToLower(filepath.Base(file))
+	return strings.HasPrefix(name, "docker-compose") || strings.HasPrefix(name, "compose.") || strings.HasPrefix(name, "compose-")
+}
+
+// imageRef is an image reference and its 1-based line
+type imageRef struct {
+	image string
+	line  int
+}
+
+// imageReferences returns the images a Dockerfile's FROM lines or a YAML
+// file's image keys refer to. Stages, scratch, and references with
+// variables or templates aren't images that can be checked.
+func imageReferences(kind, content string) []imageRef {
+	var refs []imageRef
+	var stages []string
+	for i, line := range strings.Split(content, "\n") {
+		var image string
+		if kind == "dockerfile" {
+			m := dockerFrom.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			image = m[1]
+			earlier := slices.Contains(stages, strings.ToLower(image))
+			if m[2] != "" {
+				stages = append(stages, strings.ToLower(m[2]))
+			}
+			if earlier {
+				continue
+			}
+		} else {
+			m := yamlImage.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			image = m[1]
+		}
+		if image == "scratch" || strings.ContainsAny(image, "${}") {
+			continue
+		}
+		refs = append(refs, imageRef{image: image, line: i + 1})
+	}
+	return refs
+}
+
+// imageIssues returns what's wrong with an image reference under cfg
+func imageIssues(image string, cfg config.ImagesConfig) []ImageIssue {
+	repository, tag, digest := parseImage(image)
+	var issues []ImageIssue
+	switch {
+	case digest != "":
+		// A digest pins the image whatever the tag says
+	case tag == "":
+		issues = append(issues, ImageIssue{Image: image, Check: ImageUnpinned, Message: fmt.Sprintf("%s has no tag, so it means latest and changes under you; pin a version tag", image)})
+	case tag == "latest":
+		issues = append(issues, ImageIssue{Image: image, Check: ImageUnpinned, Message: fmt.Sprintf("%s uses latest, which changes under you; pin a version tag", image)})
+	}
+	if digest == "" && cfg.RequireDigest {
+		issues = append(issues, ImageIssue{Image: image, Check: ImageDigest, Message: fmt.Sprintf("%s isn't pinned to a digest; append @sha256:... (docker buildx imagetools inspect %s shows it)", image, image)})
+	}
+	if len(cfg.Registries) > 0 && !slices.ContainsFunc(cfg.Registries, func(allowed string) bool { return imageFrom(repository, allowed) }) {
+		issues = append(issues, ImageIssue{Image: image, Check: ImageRegistry, Message: fmt.Sprintf("%s isn't from an allowed registry (%s); use a mirror of it there", image, strings.Join(cfg.Registries, ", "))})
+	}
+	return issues
+}
+
+// parseImage splits an image reference into its full repository, with the
+// docker.io registry and library/ namespace Docker implies, its tag, and its
+// digest
+func parseImage(image string) (repository, tag, digest string) {
+	repository, digest, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+	first, _, hasSlash := strings.Cut(repository, "/")
+	if !hasSlash || !(strings.ContainsAny(first, ".:") || first == "localhost") {
+		if !hasSlash {
+			repository = "library/" + repository
+		}
+		repository = "docker.io/" + repository
+	}
+	return repository, tag, digest
+}
+
+// imageFrom reports whether repository is under allowed, a registry or a
+// repository prefix
+func imageFrom(repository, allowed string) bool {
+	allowed = strings.TrimSuffix(allowed, "/")
+	return repository == allowed || strings.HasPrefix(repository, allowed+"/")
+}
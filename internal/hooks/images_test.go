@@ -0,0 +1,72 @@
+package hooks
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func TestParseImage(t *testing.T) {
+	for _, tc := range []struct{ image, repository, tag, digest string }{
+		{"nginx", "docker.io/library/nginx", "", ""},
+		{"nginx:1.27", "docker.io/library/nginx", "1.27", ""},
+		{"bitnami/redis:7.2", "docker.io/bitnami/redis", "7.2", ""},
+		{"ghcr.io/acme/api:v1@sha256:abc", "ghcr.io/acme/api", "v1", "sha256:abc"},
+		{"localhost:5000/app", "localhost:5000/app", "", ""},
+	} {
+		repository, tag, digest := parseImage(tc.image)
+		if repository != tc.repository || tag != tc.tag || digest != tc.digest {
+			t.Errorf("parseImage(%q) = %q, %q, %q; want %q, %q, %q", tc.image, repository, tag, digest, tc.repository, tc.tag, tc.digest)
+		}
+	}
+}
+
+func TestImageIssues(t *testing.T) {
+	cfg := config.ImagesConfig{Enabled: true, RequireDigest: true, Registries: []string{"ghcr.io/acme"}}
+	for image, want := range map[string][]string{
+		"ghcr.io/acme/api@sha256:abc":    nil,
+		"ghcr.io/acme/api:1.2":           {ImageDigest},
+		"ghcr.io/acme/api:latest":        {ImageUnpinned, ImageDigest},
+		"node":                           {ImageUnpinned, ImageDigest, ImageRegistry},
+		"ghcr.io/acmeco/api@sha256:abc":  {ImageRegistry},
+		"docker.io/library/node@sha256:": {ImageRegistry},
+	} {
+		var got []string
+		for _, issue := range imageIssues(image, cfg) {
+			got = append(got, issue.Check)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("imageIssues(%q) = %v, want %v", image, got, want)
+		}
+	}
+}
+
+// TestFindImageIssues expects only the image on a line the edit changed to be
+// reported, and build stages, scratch, and variables to be left alone
+func TestFindImageIssues(t *testing.T) {
+	repo := t.TempDir()
+	run := func(args ...string) { runInDir(t, repo, args...) }
+	writeTestFile(t, repo, "Dockerfile", "FROM golang:1.25 AS build\nFROM ubuntu\n")
+	run("git", "init", "-q")
+	run("git", "add", ".")
+	run("git", "commit", "-q", "-m", "init")
+	writeTestFile(t, repo, "Dockerfile", "FROM golang:1.25 AS build\nFROM ubuntu\nFROM build AS test\nFROM ${BASE}\nFROM scratch\nFROM --platform=$BUILDPLATFORM alpine:latest\n")
+	writeTestFile(t, repo, "deploy.yaml", "apiVersion: apps/v1\nkind: Deployment\nspec:\n  containers:\n    - name: api\n      image: \"ghcr.io/acme/api\"\n")
+	writeTestFile(t, repo, "values.yaml", "image: nginx\n") // Neither compose nor Kubernetes
+
+	issues, err := FindImageIssues([]string{filepath.Join(repo, "Dockerfile"), filepath.Join(repo, "deploy.yaml"), filepath.Join(repo, "values.yaml")}, false)
+	if err != nil {
+		t.Fatalf("FindImageIssues failed: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("Expected alpine:latest and ghcr.io/acme/api, got %v", issues)
+	}
+	if i := issues[0]; i.Image != "alpine:latest" || i.Line != 6 || i.Check != ImageUnpinned {
+		t.Errorf("Expected alpine:latest unpinned on line 6, got %+v", i)
+	}
+	if i := issues[1]; i.Image != "ghcr.io/acme/api" || i.Line != 6 || i.Diagnostic().Severity != "warning" {
+		t.Errorf("Expected an untagged ghcr.io/acme/api warning on line 6, got %+v", i)
+	}
+}
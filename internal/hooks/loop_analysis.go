@@ -0,0 +1,66 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/secrets"
+	"github.com/brianleishman/claude-hooks/internal/vcs"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// loopPromptLimit caps the diff and the failure output sent for analysis
+const loopPromptLimit = 20000
+
+// AnalyzeLoop asks a single model, the council's Claude reviewer, why a check
+// keeps failing on files, from their diff against the last commit and the
+// latest failure. It returns the answer and how many model calls it took, for
+// the session budget.
+func AnalyzeLoop(root string, files []string, failure string, verbose bool) (string, int, error) {
+	var diff []byte
+	if repo := vcs.Detect(root); repo != nil {
+		diff, _ = repo.Diff(3, files...) // Without it the failure has to do
+	}
+
+	r := councilReviewers[0]
+	release, err := acquireReviewCapacity(r.Key, verbose)
+	if err != nil {
+		return "", 0, fmt.Errorf("%s review capacity unavailable: %w", r.Name, err)
+	}
+	defer release()
+
+	// The same secrets as its plan reviews
+	var env []string
+	if cfg, err := config.Load(root); err == nil {
+		if env, err = secrets.ResolveEnv(cfg.PlanReview.Secrets[r.Key]); err != nil {
+			return "", 0, fmt.Errorf("%s not started: %w", r.Name, err)
+		}
+	}
+
+	vlog.Printf(verbose, "🤖 Asking %s why the check keeps failing...\n", r.Model)
+	var review AIReview
+	output, ok := r.invoke(buildLoopPrompt(string(diff), failure), env, &review, verbose)
+	if !ok {
+		return "", review.Calls, errors.New(review.Error)
+	}
+	return output, review.Calls, nil
+}
+
+// buildLoopPrompt asks for a short diagnosis of a repeated failure
+func buildLoopPrompt(diff, failure string) string {
+	if diff == "" {
+		diff = "(no diff available)"
+	}
+	return fmt.Sprintf(`An AI coding agent has edited the same file several times, and every edit is blocked by the same check. Its fixes aren't working.
+
+Explain in at most 10 lines why the check keeps failing and what the agent should do differently. Be concrete: name the file, the line, and the change. If the check itself looks wrong, say so.
+
+## Latest failure
+
+%s
+
+## Diff of the files since the last commit
+
+`+"```diff\n%s\n```", truncateForDisplay(failure, loopPromptLimit), truncateForDisplay(diff, loopPromptLimit))
+}
@@ -0,0 +1,39 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeLoop(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	bin := t.TempDir()
+	// The stub answers with the prompt it was given, which is its last argument
+	script := "#!/bin/sh\nfor last; do :; done\necho \"diagnosis: $last\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	repo := t.TempDir()
+	runInDir(t, repo, "git", "init", "-q")
+	writeTestFile(t, repo, "a.go", "package a\n")
+	runInDir(t, repo, "git", "add", ".")
+	runInDir(t, repo, "git", "-c", "user.name=t", "-c", "user.email=t@t", "commit", "-qm", "init")
+	writeTestFile(t, repo, "a.go", "package a\n\nvar X = 1\n")
+
+	analysis, calls, err := AnalyzeLoop(repo, []string{filepath.Join(repo, "a.go")}, "a.go:3:5: X redeclared", false)
+	if err != nil {
+		t.Fatalf("AnalyzeLoop failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+	for _, want := range []string{"diagnosis:", "X redeclared", "+var X = 1"} {
+		if !strings.Contains(analysis, want) {
+			t.Errorf("Expected %q in the analysis, got:\n%s", want, analysis)
+		}
+	}
+}
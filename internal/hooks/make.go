@@ -0,0 +1,93 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+var (
+	// make[1]: Entering directory '/repo/sub', around the output of recursive makes
+	makeDirPattern = regexp.MustCompile("^g?make(?:\\[\\d+\\])?: (Entering|Leaving) directory [`'\"](.*)['\"]$")
+	// make: *** [Makefile:3: lint-go] Error 1, make's summary of a failed recipe
+	makeErrorPattern = regexp.MustCompile(`^g?make(?:\[\d+\])?: \*\*\* (?:\[.*\] Error (\d+))?`)
+	// make: *** No rule to make target 'lint-go'.  Stop.
+	makeNoRulePattern = regexp.MustCompile(`No rule to make target [` + "`" + `'"]([^'"]+)['"]`)
+)
+
+// runMakeTargets runs each target in dir with FILES set to the files, and
+// fails with the output of those whose recipes fail. A target the Makefile
+// doesn't have is a configuration problem, not the edit's, so it only warns.
+func runMakeTargets(dir string, targets, files []string, verbose bool) error {
+	rel := make([]string, 0, len(files))
+	for _, f := range files {
+		if r, err := filepath.Rel(dir, f); err == nil && !strings.HasPrefix(r, "..") {
+			f = r
+		}
+		rel = append(rel, filepath.ToSlash(f))
+	}
+	filesVar := "FILES=" + strings.Join(rel, " ")
+
+	var failures []string
+	for _, target := range targets {
+		vlog.Printf(verbose, "🔧 make %s %s (in %s)\n", target, filesVar, dir)
+		cmd := proc.Command("make", target, filesVar)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		vlog.Output("make "+target, output)
+		if err == nil || proc.Killed(cmd) {
+			continue
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return fmt.Errorf("running make %s: %w", target, err)
+		}
+		if m := makeNoRulePattern.FindStringSubmatch(string(output)); m != nil && m[1] == target {
+			fmt.Fprintf(os.Stderr, "⚠️  Skipping make %s: the Makefile in %s has no such target\n", target, dir)
+			continue
+		}
+		failures = append(failures, fmt.Sprintf("make %s failed%s:\n%s", target, recipeStatus(string(output)), cleanMakeOutput(string(output), dir)))
+	}
+	return joinFailures(failures)
+}
+
+// recipeStatus describes the exit status of the failed recipe make reports,
+// which is the checking tool's rather than make's own
+func recipeStatus(output string) string {
+	for line := range strings.Lines(output) {
+		if m := makeErrorPattern.FindStringSubmatch(strings.TrimSpace(line)); m != nil && m[1] != "" {
+			return " (exit status " + m[1] + ")"
+		}
+	}
+	return ""
+}
+
+// cleanMakeOutput drops make's own bookkeeping lines and resolves the paths
+// in each recipe's output against the directory it ran in
+func cleanMakeOutput(output, dir string) string {
+	dirs := []string{dir}
+	var lines []string
+	for line := range strings.Lines(strings.TrimSpace(output)) {
+		line = strings.TrimRight(line, "\n")
+		if m := makeDirPattern.FindStringSubmatch(line); m != nil {
+			if m[1] == "Entering" {
+				dirs = append(dirs, m[2])
+			} else if len(dirs) > 1 {
+				dirs = dirs[:len(dirs)-1]
+			}
+			continue
+		}
+		if makeErrorPattern.MatchString(line) {
+			continue
+		}
+		lines = append(lines, ResolvePaths(line, dirs[len(dirs)-1]))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,77 @@
+package hooks
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+)
+
+func TestMakeHook(t *testing.T) {
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not installed")
+	}
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".claude-hooks.yaml", "hooks:\n  go:\n    make: [lint-go, test-fast, missing]\n")
+	// lint-go reports the files it was given; test-fast fails in a sub-make
+	writeTestFile(t, dir, "Makefile", ".PHONY: lint-go test-fast\n"+
+		"lint-go:\n\t@for f in $(FILES); do echo \"$$f:1:1: lint finding\"; done; exit 3\n"+
+		"test-fast:\n\t@$(MAKE) -C sub test\n")
+	writeTestFile(t, dir, "sub/Makefile", "test:\n\t@echo 'x_test.go:7: want 1, got 2'; exit 1\n")
+	writeTestFile(t, dir, "a.go", "package a\n")
+	writeTestFile(t, dir, "sub/x_test.go", "package a\n")
+
+	hook := LookupHook("go", dir)
+	if _, ok := hook.(*CommandHook); !ok {
+		t.Fatalf("Expected hooks.go to replace the built-in hook, got %#v", hook)
+	}
+	err := hook.PostEditJSON([]string{filepath.Join(dir, "a.go")}, false)
+	if err == nil {
+		t.Fatal("Expected the failing targets to fail the hook")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "make lint-go failed (exit status 3):\n"+filepath.Join(dir, "a.go")+":1:1: lint finding") {
+		t.Errorf("Expected lint-go's finding on the relative FILES entry, got %v", msg)
+	}
+	// The sub-make's output is resolved against the directory it entered
+	if !strings.Contains(msg, filepath.Join(dir, "sub", "x_test.go")+":7: want 1, got 2") {
+		t.Errorf("Expected test-fast's failure resolved into sub, got %v", msg)
+	}
+	if strings.Contains(msg, "make: ***") || strings.Contains(msg, "Entering directory") || strings.Contains(msg, "missing") {
+		t.Errorf("Expected make's own lines and the missing target left out, got %v", msg)
+	}
+
+	diags := ParseDiagnostics(msg, "go")
+	if len(diags) != 2 {
+		t.Errorf("Expected 2 diagnostics, got %+v", diags)
+	}
+}
+
+// TestMakeHookKilledTarget expects a target killed for running out of time to
+// be left out of the error, and the failure of the target that finished kept
+func TestMakeHookKilledTarget(t *testing.T) {
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make not installed")
+	}
+	dir := t.TempDir()
+	writeTestFile(t, dir, "Makefile", ".PHONY: lint slow\n"+
+		"lint:\n\t@echo 'a.go:1:1: lint finding'; exit 1\n"+
+		"slow:\n\t@echo 'a.go:2:1: half a report'; sleep 5; exit 1\n")
+	proc.SetStepTimeouts(map[string]time.Duration{"make slow": 200 * time.Millisecond})
+	defer proc.SetStepTimeouts(nil)
+
+	end := proc.Bound("go", 0)
+	err := runMakeTargets(dir, []string{"lint", "slow"}, []string{filepath.Join(dir, "a.go")}, false)
+	if timeouts := end(); len(timeouts) != 1 || timeouts[0].Step != "make slow" {
+		t.Errorf("Expected the slow target's timeout, got %+v", timeouts)
+	}
+	if err == nil || !strings.Contains(err.Error(), "lint finding") {
+		t.Fatalf("Expected the lint failure to be kept, got %v", err)
+	}
+	if strings.Contains(err.Error(), "half a report") {
+		t.Errorf("Expected the killed target's output to be left out, got %v", err)
+	}
+}
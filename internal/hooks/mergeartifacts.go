@@ -0,0 +1,155 @@
+package hooks
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// MergeArtifact is a leftover of a merge, rebase, or patch: a conflict
+// marker, a backup or reject file, or a definition repeated in one file
+type MergeArtifact struct {
+	File    string // Absolute
+	Line    int    // 1-based, 0 for a whole file
+	Message string
+}
+
+func (a MergeArtifact) String() string {
+	if a.Line == 0 {
+		return fmt.Sprintf("%s: %s", a.File, a.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", a.File, a.Line, a.Message)
+}
+
+// Diagnostic reports the artifact as an error
+func (a MergeArtifact) Diagnostic() Diagnostic {
+	return Diagnostic{File: a.File, Line: max(a.Line, 1), Severity: "error", Message: a.Message, Source: "merge-artifacts"}
+}
+
+// strayPatterns are the files merge tools and patch leave next to the files
+// they touch
+var strayPatterns = []string{"*.orig", "*.rej", "*_BACKUP_*", "*_BASE_*", "*_LOCAL_*", "*_REMOTE_*"}
+
+// definitionPatterns match top-level function definitions by file extension;
+// the first group is the name. Later definitions silently replace earlier
+// ones in these languages, so a merge keeping both sides goes unnoticed.
+var definitionPatterns = map[string]*regexp.Regexp{
+	".py":  regexp.MustCompile(`^(?:async\s+)?def\s+(\w+)\s*\(`),
+	".js":  regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)\s*\(`),
+	".mjs": regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)\s*\(`),
+	".cjs": regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)\s*\(`),
+	".jsx": regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)\s*\(`),
+	".sh":  regexp.MustCompile(`^(?:function\s+)?([\w-]+)\s*\(\)\s*\{?`),
+	".rb":  regexp.MustCompile(`^def\s+(?:self\.)?(\w+[?!]?)`),
+}
+
+// FindMergeArtifacts returns the conflict markers and repeated top-level
+// function definitions in the edited files, and the untracked .orig, .rej,
+// and merge tool backup files in their directories. TypeScript isn't checked
+// for repeated definitions because overloads repeat names, and Go because
+// the compiler already rejects them. It is a no-op when
+// merge_artifacts.enabled is turned off.
+func FindMergeArtifacts(files []string, verbose bool) ([]MergeArtifact, error) {
+	var artifacts []MergeArtifact
+	var dirs []string
+	for _, f := range files {
+		cfg, err := config.Load(filepath.Dir(f))
+		if err != nil {
+			return artifacts, err
+		}
+		if !cfg.MergeArtifacts.Enabled {
+			continue
+		}
+		if !slices.Contains(dirs, filepath.Dir(f)) {
+			dirs = append(dirs, filepath.Dir(f))
+		}
+		found, err := fileMergeArtifacts(f)
+		if err != nil {
+			continue // Deleted since, or unreadable
+		}
+		artifacts = append(artifacts, found...)
+	}
+
+	for _, dir := range dirs {
+		var stray []string
+		for _, pattern := range strayPatterns {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			stray = append(stray, matches...)
+		}
+		for _, f := range untracked(dir, stray) {
+			artifacts = append(artifacts, MergeArtifact{File: f, Message: "leftover of a merge or patch; apply what it holds to the real file, then delete it"})
+		}
+	}
+	vlog.Printf(verbose, "🔀 %d merge artifact(s)\n", len(artifacts))
+	return artifacts, nil
+}
+
+// fileMergeArtifacts returns the conflict markers and repeated definitions in file
+func fileMergeArtifacts(file string) ([]MergeArtifact, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var artifacts []MergeArtifact
+	definition := definitionPatterns[filepath.Ext(file)]
+	defined := make(map[string]int) // Name -> line
+	conflict := 0                   // Line of the open <<<<<<< marker
+	separated := false
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "<<<<<<<") && (len(line) == 7 || line[7] == ' '):
+			conflict, separated = n, false
+		case conflict > 0 && line == "=======":
+			separated = true
+		case conflict > 0 && separated && strings.HasPrefix(line, ">>>>>>>") && (len(line) == 7 || line[7] == ' '):
+			artifacts = append(artifacts, MergeArtifact{File: file, Line: conflict, Message: fmt.Sprintf("unresolved conflict (lines %d-%d); keep the right side, or combine them, and remove the markers", conflict, n)})
+			conflict = 0
+		}
+		if definition == nil {
+			continue
+		}
+		if m := definition.FindStringSubmatch(line); m != nil {
+			if first, ok := defined[m[1]]; ok {
+				artifacts = append(artifacts, MergeArtifact{File: file, Line: n, Message: fmt.Sprintf("%s is already defined on line %d, and this definition silently replaces it; keep one", m[1], first)})
+				continue
+			}
+			defined[m[1]] = n
+		}
+	}
+	return artifacts, scanner.Err()
+}
+
+// untracked returns the files git doesn't track and doesn't ignore, or all
+// of them outside a repository
+func untracked(dir string, files []string) []string {
+	if len(files) == 0 {
+		return nil
+	}
+	root := state.ProjectRoot(dir)
+	args := append([]string{"-C", root, "ls-files", "--others", "--exclude-standard", "--"}, files...)
+	output, err := proc.Command("git", args...).Output()
+	if err != nil {
+		return files
+	}
+	var result []string
+	for line := range strings.Lines(string(output)) {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, filepath.Join(root, line))
+		}
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package hooks
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindMergeArtifacts(t *testing.T) {
+	repo := t.TempDir()
+	runInDir(t, repo, "git", "init", "-q")
+	writeTestFile(t, repo, ".gitignore", "*.ignored.orig\n")
+	writeTestFile(t, repo, "tracked.orig", "kept on purpose\n")
+	runInDir(t, repo, "git", "add", ".")
+	runInDir(t, repo, "git", "commit", "-qm", "init")
+	file := func(name string) string { return filepath.Join(repo, name) }
+
+	writeTestFile(t, repo, "app.py", "def load():\n    pass\n\n<<<<<<< HEAD\ndef save():\n    pass\n=======\ndef save(path):\n    pass\n>>>>>>> feature\n\ndef load():\n    pass\n")
+	writeTestFile(t, repo, "README.md", "Title\n=======\n\nText\n")
+	writeTestFile(t, repo, "app.py.rej", "@@ -1 +1 @@\n")
+	writeTestFile(t, repo, "old.ignored.orig", "ignored\n")
+	files := []string{file("app.py"), file("README.md")}
+
+	artifacts, err := FindMergeArtifacts(files, false)
+	if err != nil {
+		t.Fatalf("FindMergeArtifacts failed: %v", err)
+	}
+	var got []string
+	for _, a := range artifacts {
+		got = append(got, strings.TrimPrefix(a.String(), repo+"/"))
+	}
+	want := []string{
+		"app.py:8: save is already defined on line 5, and this definition silently replaces it; keep one",
+		"app.py:4: unresolved conflict (lines 4-10); keep the right side, or combine them, and remove the markers",
+		"app.py:12: load is already defined on line 1, and this definition silently replaces it; keep one",
+		"app.py.rej: leftover of a merge or patch; apply what it holds to the real file, then delete it",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Unexpected artifacts:\n%s", strings.Join(got, "\n"))
+	}
+
+	writeTestFile(t, repo, ".claude-hooks.yaml", "merge_artifacts:\n  enabled: false\n")
+	if artifacts, err := FindMergeArtifacts(files, false); err != nil || len(artifacts) != 0 {
+		t.Fatalf("Expected no artifacts when disabled, got %+v (%v)", artifacts, err)
+	}
+}
@@ -0,0 +1,193 @@
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// FileMove is a file that was renamed or moved in the working tree
+type FileMove struct {
+	From string // Absolute path the file used to live at
+	To   string // Absolute path it lives at now
+}
+
+// DetectMoves finds moved files in the git repository at repoRoot. Staged renames
+// (git mv) are reported by git directly; a deleted tracked file paired with a new
+// untracked file of the same name (Write to new path + delete) is treated as a move too.
+func DetectMoves(repoRoot string, verbose bool) ([]FileMove, error) {
+	cmd := proc.Command("git", "-C", repoRoot, "status", "--porcelain", "--untracked-files=all")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running git status: %w", err)
+	}
+
+	moves, deleted, untracked := parseGitStatus(output)
+
+	// Pair deletions with untracked files when the base name is unambiguous
+	byName := make(map[string][]string)
+	for _, f := range untracked {
+		byName[filepath.Base(f)] = append(byName[filepath.Base(f)], f)
+	}
+	for _, from := range deleted {
+		candidates := byName[filepath.Base(from)]
+		if len(candidates) == 1 {
+			moves = append(moves, FileMove{From: from, To: candidates[0]})
+		}
+	}
+
+	for i := range moves {
+		moves[i].From = filepath.Join(repoRoot, moves[i].From)
+		moves[i].To = filepath.Join(repoRoot, moves[i].To)
+	}
+
+	for _, m := range moves {
+		vlog.Printf(verbose, "🔀 Detected move: %s -> %s\n", m.From, m.To)
+	}
+
+	return moves, nil
+}
+
+// parseGitStatus splits `git status --porcelain` output into renames, deleted
+// files, and untracked files (all repo-relative)
+func parseGitStatus(output []byte) (moves []FileMove, deleted, untracked []string) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) < 4 {
+			continue
+		}
+
+		status, path := line[:2], unquoteGitPath(line[3:])
+		switch {
+		case status[0] == 'R':
+			if from, to, ok := strings.Cut(path, " -> "); ok {
+				moves = append(moves, FileMove{From: unquoteGitPath(from), To: unquoteGitPath(to)})
+			}
+		case status == "??":
+			untracked = append(untracked, path)
+		case status[0] == 'D' || status[1] == 'D':
+			deleted = append(deleted, path)
+		}
+	}
+	return moves, deleted, untracked
+}
+
+func unquoteGitPath(path string) string {
+	return strings.Trim(path, `"`)
+}
+
+// CheckMovedReferences re-validates code that referenced the old location of moved
+// files. For Go, every package in the module that imported a package whose directory
+// changed is vetted, so dangling imports are reported even though only the new file
+// was edited.
+func CheckMovedReferences(moves []FileMove, verbose bool) error {
+	// Old package directory -> new package directory
+	movedPackages := make(map[string]string)
+	for _, m := range moves {
+		if filepath.Ext(m.To) != ".go" || filepath.Dir(m.From) == filepath.Dir(m.To) {
+			continue
+		}
+		movedPackages[filepath.Dir(m.From)] = filepath.Dir(m.To)
+	}
+
+	var problems []string
+	for oldDir, newDir := range movedPackages {
+		moduleRoot, err := findModuleRoot(newDir)
+		if err != nil {
+			return err
+		}
+
+		oldImport, err := importPathForDir(moduleRoot, oldDir)
+		if err != nil {
+			vlog.Printf(verbose, "⚠️  Skipping reference check for %s: %v\n", oldDir, err)
+			continue
+		}
+
+		importers, err := findImporters(moduleRoot, oldImport)
+		if err != nil {
+			return err
+		}
+		if len(importers) == 0 {
+			continue
+		}
+
+		vlog.Printf(verbose, "🔍 Re-checking %d package(s) that import moved package %s\n", len(importers), oldImport)
+
+		args := slices.Concat([]string{"vet"}, goModFlags(moduleRoot), importers)
+		cmd := proc.Command("go", args...)
+		cmd.Dir = moduleRoot
+		if output, err := cmd.CombinedOutput(); err != nil {
+			problems = append(problems, fmt.Sprintf("Package %s moved to %s, but these packages still reference the old path:\n%s",
+				oldImport, newDir, strings.TrimSpace(string(output))))
+		}
+	}
+
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return fmt.Errorf("broken references after move:\n%s", strings.Join(problems, "\n\n"))
+	}
+	return nil
+}
+
+// importPathForDir derives the import path of dir from the module's go.mod
+func importPathForDir(moduleRoot, dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(moduleRoot, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("reading go.mod: %w", err)
+	}
+
+	var modulePath string
+	for line := range strings.Lines(string(data)) {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			modulePath = strings.Trim(strings.TrimSpace(rest), `"`)
+			break
+		}
+	}
+	if modulePath == "" {
+		return "", fmt.Errorf("no module directive in go.mod")
+	}
+
+	rel, err := filepath.Rel(moduleRoot, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is outside module %s", dir, moduleRoot)
+	}
+	if rel == "." {
+		return modulePath, nil
+	}
+	return modulePath + "/" + filepath.ToSlash(rel), nil
+}
+
+// findImporters lists the relative package patterns in the module that import any of
+// importPaths, including through their tests
+func findImporters(moduleRoot string, importPaths ...string) ([]string, error) {
+	args := slices.Concat([]string{"list"}, goModFlags(moduleRoot), []string{"-e", "-f",
+		`{{.Dir}}|{{join .Imports ","}},{{join .TestImports ","}},{{join .XTestImports ","}}`, "./..."})
+	cmd := proc.Command("go", args...)
+	cmd.Dir = moduleRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing packages: %w", err)
+	}
+
+	var importers []string
+	for line := range strings.Lines(string(output)) {
+		dir, imports, ok := strings.Cut(strings.TrimSpace(line), "|")
+		if !ok || !slices.ContainsFunc(strings.Split(imports, ","), func(imp string) bool { return slices.Contains(importPaths, imp) }) {
+			continue
+		}
+		rel, err := filepath.Rel(moduleRoot, dir)
+		if err != nil {
+			continue
+		}
+		importers = append(importers, "./"+filepath.ToSlash(rel))
+	}
+	return importers, nil
+}
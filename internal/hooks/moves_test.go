@@ -0,0 +1,80 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	output := []byte("R  old/a.go -> new/a.go\n D gone.go\nD  staged_gone.go\n?? fresh.go\n M edited.go\n")
+
+	moves, deleted, untracked := parseGitStatus(output)
+
+	if len(moves) != 1 || moves[0] != (FileMove{From: "old/a.go", To: "new/a.go"}) {
+		t.Errorf("Unexpected renames: %+v", moves)
+	}
+	if len(deleted) != 2 || deleted[0] != "gone.go" || deleted[1] != "staged_gone.go" {
+		t.Errorf("Unexpected deletions: %+v", deleted)
+	}
+	if len(untracked) != 1 || untracked[0] != "fresh.go" {
+		t.Errorf("Unexpected untracked files: %+v", untracked)
+	}
+}
+
+// TestCheckMovedReferences moves a package that another package imports and
+// expects the dangling import to be reported
+func TestCheckMovedReferences(t *testing.T) {
+	repo := t.TempDir()
+	writeFile := func(path, content string) { writeTestFile(t, repo, path, content) }
+	run := func(args ...string) { runInDir(t, repo, args...) }
+
+	writeFile("go.mod", "module example.com/moved\n\ngo 1.25\n")
+	writeFile("util/util.go", "package util\n\nfunc Hello() string { return \"hi\" }\n")
+	writeFile("app/app.go", "package app\n\nimport \"example.com/moved/util\"\n\nvar Greeting = util.Hello()\n")
+	run("git", "init", "-q")
+	run("git", "add", ".")
+	run("git", "commit", "-q", "-m", "init")
+	run("git", "mv", "util", "helpers")
+
+	moves, err := DetectMoves(repo, false)
+	if err != nil {
+		t.Fatalf("DetectMoves failed: %v", err)
+	}
+	if len(moves) != 1 || moves[0].From != filepath.Join(repo, "util/util.go") || moves[0].To != filepath.Join(repo, "helpers/util.go") {
+		t.Fatalf("Unexpected moves: %+v", moves)
+	}
+
+	err = CheckMovedReferences(moves, false)
+	if err == nil {
+		t.Fatal("Expected broken reference to be reported")
+	}
+	if !strings.Contains(err.Error(), "example.com/moved/util") {
+		t.Errorf("Expected error to name the old import path, got: %v", err)
+	}
+}
+
+// writeTestFile writes content to path under root, creating parent directories
+func writeTestFile(t *testing.T, root, path, content string) {
+	t.Helper()
+	full := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// runInDir runs a command in dir with a git identity configured, failing the test on error
+func runInDir(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v failed: %v\n%s", args, err, output)
+	}
+}
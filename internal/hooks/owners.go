@@ -0,0 +1,188 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/codeowners"
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// pingsFile holds the owners a session already pinged
+const pingsFile = "owner-pings.json"
+
+// OwnedFile is an edited file owned by someone other than owners.team, or
+// restricted by owners.restricted
+type OwnedFile struct {
+	File   string // Relative to the repository root
+	Owners []string
+	Line   int // Of the CODEOWNERS rule, 0 without one
+	// Restricted is the owners.restricted entry blocking the edit, if any
+	Restricted string
+	// Webhooks are the URLs of the owners with one in owners.webhooks
+	Webhooks map[string]string
+}
+
+func (o OwnedFile) String() string {
+	owners := "nobody in CODEOWNERS"
+	if len(o.Owners) > 0 {
+		owners = strings.Join(o.Owners, ", ") + fmt.Sprintf(" (CODEOWNERS line %d)", o.Line)
+	}
+	if o.Restricted != "" {
+		return fmt.Sprintf("%s is restricted by owners.restricted (%s) and owned by %s", o.File, o.Restricted, owners)
+	}
+	return fmt.Sprintf("%s is owned by %s", o.File, owners)
+}
+
+// CheckOwners returns the files that owners.team doesn't own, when it's set,
+// and the files owners.restricted covers, read from the CODEOWNERS file of
+// each file's repository and the config of its directory
+func CheckOwners(files []string) (others, restricted []OwnedFile, err error) {
+	owners := make(map[string]*codeowners.File) // By repository root
+	for _, f := range files {
+		cfg, err := config.Load(filepath.Dir(f))
+		if err != nil {
+			return others, restricted, err
+		}
+		if len(cfg.Owners.Team) == 0 && len(cfg.Owners.Restricted) == 0 {
+			continue
+		}
+		root := state.ProjectRoot(filepath.Dir(f))
+		file, ok := owners[root]
+		if !ok {
+			if file, err = codeowners.Load(root); err != nil {
+				return others, restricted, fmt.Errorf("reading CODEOWNERS: %w", err)
+			}
+			owners[root] = file
+		}
+		rel, ok := relativeTo(root, f)
+		if !ok {
+			continue
+		}
+		o := OwnedFile{File: filepath.ToSlash(rel)}
+		if file != nil {
+			if r := file.Match(o.File); r != nil {
+				o.Owners, o.Line = r.Owners, r.Line
+			}
+		}
+
+		if o.Restricted = restrictedBy(cfg.Owners.Restricted, o); o.Restricted != "" {
+			restricted = append(restricted, o)
+			continue
+		}
+		if len(cfg.Owners.Team) == 0 || len(o.Owners) == 0 || slices.ContainsFunc(o.Owners, func(owner string) bool { return slices.Contains(cfg.Owners.Team, owner) }) {
+			continue
+		}
+		for _, owner := range o.Owners {
+			if url := cfg.Owners.Webhooks[owner]; url != "" {
+				if o.Webhooks == nil {
+					o.Webhooks = make(map[string]string)
+				}
+				o.Webhooks[owner] = url
+			}
+		}
+		others = append(others, o)
+	}
+	return others, restricted, nil
+}
+
+// relativeTo returns file relative to root, as given or with its symlinks
+// resolved, whichever is inside root
+func relativeTo(root, file string) (string, bool) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", false
+	}
+	for _, p := range []string{abs, state.RealPath(abs)} {
+		if rel, err := filepath.Rel(root, p); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return rel, true
+		}
+	}
+	return "", false
+}
+
+// restrictedBy returns the entry of restricted covering o: a directory it's
+// in, or one of its owners
+func restrictedBy(restricted []string, o OwnedFile) string {
+	for _, entry := range restricted {
+		if strings.Contains(entry, "@") {
+			if slices.Contains(o.Owners, entry) {
+				return entry
+			}
+			continue
+		}
+		dir := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(entry)), "/")
+		if dir == "" || o.File == dir || strings.HasPrefix(o.File, dir+"/") {
+			return entry
+		}
+	}
+	return ""
+}
+
+// OwnersMessage tells Claude that files it edits belong to other teams
+func OwnersMessage(others []OwnedFile) string {
+	lines := make([]string, len(others))
+	for i, o := range others {
+		lines[i] = "- " + o.String()
+	}
+	return "⚠️ Files owned by other teams:\n" + strings.Join(lines, "\n") + "\nKeep the changes to them to what the task needs, and list them in your summary so their owners can review them."
+}
+
+// RestrictedMessage tells Claude it can't edit restricted files
+func RestrictedMessage(restricted []OwnedFile) string {
+	lines := make([]string, len(restricted))
+	for i, o := range restricted {
+		lines[i] = "- " + o.String()
+	}
+	return "These files are restricted to their owners:\n" + strings.Join(lines, "\n") + "\nDon't change them. Ask the user, or the owners, to make the change."
+}
+
+// NewOwnerPings returns the webhooks of others' owners that s didn't ping
+// yet, by owner, and remembers them as pinged. Without a session every edit
+// would ping again, so there are none.
+func NewOwnerPings(s Session, others []OwnedFile) (map[string]string, error) {
+	if s.ID == "" {
+		return nil, nil
+	}
+	pings := make(map[string]string)
+	for _, o := range others {
+		for owner, url := range o.Webhooks {
+			pings[owner] = url
+		}
+	}
+	if len(pings) == 0 {
+		return nil, nil
+	}
+	file, err := state.SessionPath(s.Dir, s.ID, s.TranscriptPath, pingsFile)
+	if err != nil {
+		return nil, err
+	}
+	var pinged []string
+	if data, err := os.ReadFile(file); err == nil {
+		_ = json.Unmarshal(data, &pinged) // A corrupt file starts over
+	}
+	for _, owner := range pinged {
+		delete(pings, owner)
+	}
+	if len(pings) == 0 {
+		return nil, nil
+	}
+	for owner := range pings {
+		pinged = append(pinged, owner)
+	}
+	slices.Sort(pinged)
+	data, err := json.Marshal(pinged)
+	if err != nil {
+		return pings, fmt.Errorf("encoding owner pings: %w", err)
+	}
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		return pings, fmt.Errorf("writing owner pings: %w", err)
+	}
+	return pings, nil
+}
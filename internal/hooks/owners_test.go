@@ -0,0 +1,60 @@
+package hooks
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckOwners(t *testing.T) {
+	repo := t.TempDir()
+	runInDir(t, repo, "git", "init", "-q")
+	writeTestFile(t, repo, ".github/CODEOWNERS", "* @acme/checkout\n/payments/ @acme/payments\n/vault/ @acme/security\n")
+	writeTestFile(t, repo, ".claude-hooks.yaml", `owners:
+  team: ["@acme/checkout"]
+  webhooks:
+    "@acme/payments": https://hooks.example.com/payments
+  restricted: [infra/prod, "@acme/security"]
+`)
+	mine := filepath.Join(repo, "cart/cart.go")
+	payments := filepath.Join(repo, "payments/charge.go")
+	prod := filepath.Join(repo, "infra/prod/main.tf")
+	vault := filepath.Join(repo, "vault/keys.go")
+
+	others, restricted, err := CheckOwners([]string{mine, payments, prod, vault})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(others) != 1 || others[0].File != "payments/charge.go" || others[0].Line != 2 {
+		t.Fatalf("others = %+v, want payments/charge.go from line 2", others)
+	}
+	if url := others[0].Webhooks["@acme/payments"]; url != "https://hooks.example.com/payments" {
+		t.Errorf("Webhook = %q", url)
+	}
+	if msg := OwnersMessage(others); !strings.Contains(msg, "payments/charge.go is owned by @acme/payments (CODEOWNERS line 2)") {
+		t.Errorf("OwnersMessage = %q", msg)
+	}
+
+	if len(restricted) != 2 || restricted[0].Restricted != "infra/prod" || restricted[1].Restricted != "@acme/security" {
+		t.Fatalf("restricted = %+v, want infra/prod and vault/keys.go", restricted)
+	}
+
+	s := Session{Dir: repo, ID: "owners-test"}
+	pings, err := NewOwnerPings(s, others)
+	if err != nil || pings["@acme/payments"] == "" {
+		t.Fatalf("First NewOwnerPings = %v, %v, want @acme/payments", pings, err)
+	}
+	if pings, err := NewOwnerPings(s, others); err != nil || len(pings) != 0 {
+		t.Errorf("Second NewOwnerPings = %v, %v, want none", pings, err)
+	}
+}
+
+func TestCheckOwnersOff(t *testing.T) {
+	repo := t.TempDir()
+	runInDir(t, repo, "git", "init", "-q")
+	writeTestFile(t, repo, "CODEOWNERS", "* @acme/other\n")
+	others, restricted, err := CheckOwners([]string{filepath.Join(repo, "a.go")})
+	if err != nil || others != nil || restricted != nil {
+		t.Errorf("Without owners config = %v, %v, %v, want nothing", others, restricted, err)
+	}
+}
@@ -0,0 +1,82 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// peersFile holds the other sessions a session was told about
+const peersFile = "peers.json"
+
+// writesLockTimeout is how long a step writing to the working tree waits for
+// another session's to finish
+const writesLockTimeout = 2 * time.Minute
+
+// NewPeers returns the other sessions active in s's project that s wasn't
+// told about yet, and remembers them as told. Without a session there are no
+// peers to tell apart from it.
+func NewPeers(s Session) ([]string, error) {
+	if s.ID == "" {
+		return nil, nil
+	}
+	active, err := state.ActiveSessions(s.Dir, s.ID, state.ActiveWithin)
+	if err != nil || len(active) == 0 {
+		return nil, err
+	}
+	path, err := state.SessionPath(s.Dir, s.ID, s.TranscriptPath, peersFile)
+	if err != nil {
+		return nil, err
+	}
+	var told []string
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &told) // A corrupt file starts over
+	}
+	fresh := slices.DeleteFunc(active, func(id string) bool { return slices.Contains(told, id) })
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(append(told, fresh...))
+	if err != nil {
+		return fresh, fmt.Errorf("encoding peers: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fresh, fmt.Errorf("writing peers: %w", err)
+	}
+	return fresh, nil
+}
+
+// PeersMessage tells Claude that other sessions work in the same repository
+func PeersMessage(peers []string) string {
+	short := make([]string, len(peers))
+	for i, id := range peers {
+		short[i] = id[:min(len(id), 8)]
+	}
+	who := "Another Claude session (" + short[0] + ") is"
+	if len(peers) > 1 {
+		who = fmt.Sprintf("%d other Claude sessions (%s) are", len(peers), strings.Join(short, ", "))
+	}
+	return "⚠️ " + who + " active in this repository. Files can change between your reads and edits: re-read a file before editing it, and don't undo changes you didn't make. Fixers and code generation take turns between sessions."
+}
+
+// lockWrites takes the project's lock on writing to the working tree for
+// step, waiting for another session's to finish
+func lockWrites(dir, step string, verbose bool) (func(), error) {
+	unlock, err := state.LockWrites(context.Background(), dir, false)
+	if !errors.Is(err, state.ErrLocked) {
+		return unlock, err
+	}
+	vlog.Printf(verbose, "⏳ Another session is writing to the project; %s waits for it\n", step)
+	ctx, cancel := context.WithTimeout(proc.Context(), writesLockTimeout)
+	defer cancel()
+	return state.LockWrites(ctx, dir, true)
+}
@@ -0,0 +1,201 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// Placeholder is stub code an edit left in a file instead of the real thing
+type Placeholder struct {
+	File    string // Absolute
+	Line    int    // 1-based
+	Rule    string
+	Message string
+}
+
+func (p Placeholder) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", p.File, p.Line, p.Message, p.Rule)
+}
+
+// Diagnostic reports the placeholder as an error
+func (p Placeholder) Diagnostic() Diagnostic {
+	return Diagnostic{File: p.File, Line: p.Line, Severity: "error", Message: p.Message, Source: "placeholders"}
+}
+
+// placeholderRule is a pattern of stub code, matched against a whole file so
+// it can span lines
+type placeholderRule struct {
+	name       string
+	extensions []string // Empty means any file
+	pattern    *regexp.Regexp
+	message    string
+}
+
+var (
+	braceFiles = []string{".go", ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".java", ".kt", ".cs", ".php", ".swift", ".rs", ".c", ".cc", ".cpp", ".h", ".hpp", ".scala", ".dart"}
+	catchFiles = []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".java", ".kt", ".cs", ".php", ".swift", ".scala", ".dart"}
+)
+
+// placeholderRules are what Claude leaves behind when it stops short: code
+// elided in a comment, unimplemented bodies, swallowed errors, and filler
+var placeholderRules = []placeholderRule{
+	{
+		name:    "elided-code",
+		pattern: regexp.MustCompile(`(?im)(//|#|/\*|<!--|--)[ \t]*(\.\.\.|…)[ \t]*(the )?(rest|remaining|existing|previous|other|same|unchanged)\b|(//|#|/\*|<!--|--)[ \t]*(\.\.\.[ \t]*)?(the )?(rest of|remainder of)( the)? (implementation|code|function|method|file|logic|class)\b|(//|#|/\*)[ \t]*(\.\.\.[ \t]*)?(existing|unchanged|previous) (code|implementation|methods|logic)( (here|remains|unchanged|stays))?[ \t]*(\.\.\.)?[ \t]*(\*/|-->)?[ \t]*$`),
+		message: "a comment stands in for code that was left out; write the code out in full",
+	},
+	{
+		name:       "not-implemented",
+		extensions: []string{".py"},
+		pattern:    regexp.MustCompile(`(?m)^([ \t]*)raise NotImplementedError\b`),
+		message:    "raises NotImplementedError instead of implementing the function",
+	},
+	{
+		name:       "not-implemented",
+		extensions: []string{".java", ".kt", ".cs", ".scala"},
+		pattern:    regexp.MustCompile(`throw\s+new\s+(NotImplementedException|UnsupportedOperationException)\s*\(\s*("(?i:not (yet )?implemented|todo)[^"]*")?\s*\)`),
+		message:    "throws instead of implementing the function",
+	},
+	{
+		name:       "empty-catch",
+		extensions: catchFiles,
+		pattern:    regexp.MustCompile(`\bcatch\b\s*(\([^)]*\))?\s*\{\s*\}`),
+		message:    "an empty catch block swallows the error; handle it, or let it propagate",
+	},
+	{
+		name:       "empty-catch",
+		extensions: []string{".py"},
+		pattern:    regexp.MustCompile(`(?m)^[ \t]*except\b[^:\n]*:[ \t]*(#[^\n]*)?\n[ \t]*pass[ \t]*$`),
+		message:    "an except that only passes swallows the error; handle it, or let it propagate",
+	},
+	{
+		name:       "empty-catch",
+		extensions: []string{".go"},
+		pattern:    regexp.MustCompile(`\bif\s+err\s*!=\s*nil\s*\{\s*\}`),
+		message:    "an empty if err != nil block ignores the error; handle it, or return it",
+	},
+	{
+		name:       "todo-stub",
+		extensions: braceFiles,
+		pattern:    regexp.MustCompile(`\{[ \t]*\n[ \t]*(//|/\*)[ \t]*(?i:todo|fixme|implement)[^\n]*\n[ \t]*return( nil| null| undefined| None| 0| ""| false| Ok\(\(\)\))?[ \t]*;?[ \t]*\n[ \t]*\}`),
+		message:    "the function is only a TODO and an empty return; implement it",
+	},
+	{
+		name:       "todo-stub",
+		extensions: []string{".py"},
+		pattern:    regexp.MustCompile(`(?m):[ \t]*\n[ \t]*#[ \t]*(?i:todo|fixme|implement)[^\n]*\n[ \t]*(return( None)?|pass|\.\.\.)[ \t]*$`),
+		message:    "the function is only a TODO and an empty return; implement it",
+	},
+	{
+		name:    "lorem-ipsum",
+		pattern: regexp.MustCompile(`(?i)\blorem ipsum\b`),
+		message: "lorem ipsum filler; use the real text",
+	},
+}
+
+// abstractMethod matches the decorator of a Python method meant to raise
+// NotImplementedError
+var abstractMethod = regexp.MustCompile(`@(abc\.)?abstractmethod\b`)
+
+// FindPlaceholders returns the placeholder code the edited files have that
+// their committed versions don't, so stubs that were already there don't
+// block every edit near them. Test files and fixtures may hold lorem ipsum.
+// It is a no-op when placeholders.enabled is turned off.
+func FindPlaceholders(files []string, verbose bool) ([]Placeholder, error) {
+	var found []Placeholder
+	for _, f := range files {
+		cfg, err := config.Load(filepath.Dir(f))
+		if err != nil {
+			return found, err
+		}
+		if !cfg.Placeholders.Enabled {
+			continue
+		}
+		content, err := os.ReadFile(f)
+		if err != nil {
+			continue // Deleted since, or unreadable
+		}
+		base, _ := checkedOutHead(filepath.Dir(f), f)
+		found = append(found, filePlaceholders(f, string(base), string(content), cfg.Placeholders.Disabled)...)
+	}
+	vlog.Printf(verbose, "🚧 %d placeholder(s)\n", len(found))
+	return found, nil
+}
+
+// filePlaceholders returns the matches of the rules in content that base,
+// the file before the session's edits, doesn't have
+func filePlaceholders(file, base, content string, disabled []string) []Placeholder {
+	ext := strings.ToLower(filepath.Ext(file))
+	var found []Placeholder
+	for _, rule := range placeholderRules {
+		if slices.Contains(disabled, rule.name) || (len(rule.extensions) > 0 && !slices.Contains(rule.extensions, ext)) {
+			continue
+		}
+		if rule.name == "lorem-ipsum" && isFixture(file) {
+			continue
+		}
+		existing := matchedTexts(rule.pattern, base)
+		for _, m := range rule.pattern.FindAllStringIndex(content, -1) {
+			text := normalizeMatch(content[m[0]:m[1]])
+			if existing[text] > 0 {
+				existing[text]-- // Was there before
+				continue
+			}
+			if rule.name == "not-implemented" && ext == ".py" && abstractMethod.MatchString(precedingLines(content, m[0], 4)) {
+				continue
+			}
+			found = append(found, Placeholder{File: file, Line: strings.Count(content[:m[0]], "\n") + 1, Rule: rule.name, Message: rule.message})
+		}
+	}
+	slices.SortStableFunc(found, func(a, b Placeholder) int { return a.Line - b.Line })
+	return found
+}
+
+// matchedTexts counts pattern's matches in text by their normalized text
+func matchedTexts(pattern *regexp.Regexp, text string) map[string]int {
+	counts := make(map[string]int)
+	for _, m := range pattern.FindAllString(text, -1) {
+		counts[normalizeMatch(m)]++
+	}
+	return counts
+}
+
+// normalizeMatch ignores indentation, which moves when code around a match does
+func normalizeMatch(match string) string {
+	return strings.Join(strings.Fields(match), " ")
+}
+
+// precedingLines returns up to n lines of content before offset
+func precedingLines(content string, offset, n int) string {
+	start := offset
+	for range n + 1 {
+		i := strings.LastIndexByte(content[:start], '\n')
+		if i < 0 {
+			return content[:offset]
+		}
+		start = i
+	}
+	return content[start:offset]
+}
+
+// isFixture reports whether file is a test or test data, where filler text
+// has a place
+func isFixture(file string) bool {
+	name := strings.ToLower(filepath.Base(file))
+	if strings.Contains(name, "_test.") || strings.Contains(name, ".test.") || strings.Contains(name, ".spec.") || strings.HasPrefix(name, "test_") {
+		return true
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(file)), "/") {
+		if slices.Contains([]string{"testdata", "fixtures", "__fixtures__", "__snapshots__", "tests", "test"}, strings.ToLower(dir)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package hooks
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindPlaceholders(t *testing.T) {
+	repo := t.TempDir()
+	runInDir(t, repo, "git", "init", "-q")
+	writeTestFile(t, repo, "legacy.py", "def old():\n    raise NotImplementedError\n")
+	runInDir(t, repo, "git", "add", ".")
+	runInDir(t, repo, "git", "commit", "-qm", "init")
+	file := func(name string) string { return filepath.Join(repo, name) }
+
+	writeTestFile(t, repo, "legacy.py", "def old():\n    raise NotImplementedError\n\n\ndef new():\n    raise NotImplementedError\n")
+	writeTestFile(t, repo, "shapes.py", "import abc\n\n\nclass Shape(abc.ABC):\n    @abc.abstractmethod\n    def area(self):\n        raise NotImplementedError\n\n\ndef load(path):\n    try:\n        return open(path).read()\n    except OSError:\n        pass\n")
+	writeTestFile(t, repo, "app.ts", "export function save(x: string) {\n  // ... rest of implementation\n}\n\nexport function load() {\n  try {\n    return read();\n  } catch (e) {}\n}\n")
+	writeTestFile(t, repo, "store.go", "package store\n\nfunc Open() error {\n\t// TODO: connect\n\treturn nil\n}\n\nconst title = \"Lorem ipsum dolor sit amet\"\n")
+	writeTestFile(t, repo, "store_test.go", "package store\n\nconst body = \"Lorem ipsum\"\n")
+	files := []string{file("legacy.py"), file("shapes.py"), file("app.ts"), file("store.go"), file("store_test.go")}
+
+	placeholders, err := FindPlaceholders(files, false)
+	if err != nil {
+		t.Fatalf("FindPlaceholders failed: %v", err)
+	}
+	var got []string
+	for _, p := range placeholders {
+		got = append(got, fmt.Sprintf("%s:%d %s", strings.TrimPrefix(p.File, repo+"/"), p.Line, p.Rule))
+	}
+	want := []string{
+		"legacy.py:6 not-implemented",
+		"shapes.py:13 empty-catch",
+		"app.ts:2 elided-code",
+		"app.ts:8 empty-catch",
+		"store.go:3 todo-stub",
+		"store.go:8 lorem-ipsum",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Unexpected placeholders:\n%s", strings.Join(got, "\n"))
+	}
+
+	writeTestFile(t, repo, ".claude-hooks.yaml", "placeholders:\n  disabled: [lorem-ipsum, empty-catch]\n")
+	if placeholders, err := FindPlaceholders([]string{file("store.go"), file("shapes.py")}, false); err != nil || len(placeholders) != 1 || placeholders[0].Rule != "todo-stub" {
+		t.Fatalf("Expected only the todo-stub with patterns disabled, got %+v (%v)", placeholders, err)
+	}
+	writeTestFile(t, repo, ".claude-hooks.yaml", "placeholders:\n  enabled: false\n")
+	if placeholders, err := FindPlaceholders(files, false); err != nil || len(placeholders) != 0 {
+		t.Fatalf("Expected no placeholders when disabled, got %+v (%v)", placeholders, err)
+	}
+}
@@ -6,25 +6,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/network"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/secrets"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
 )
 
 // PlanReviewInput contains the data needed to review a plan
 type PlanReviewInput struct {
+	SessionID      string `json:"session_id"`
 	TranscriptPath string `json:"transcript_path"`
 	Cwd            string `json:"cwd"`
 	PlanContent    string `json:"plan_content"` // Extracted from transcript or provided
+	MinSeverity    string `json:"min_severity"` // Hide issues below this severity in the summary
+	// Reviewers are the council members to ask, by CLI (claude, codex,
+	// gemini); empty means all of them
+	Reviewers []string `json:"reviewers,omitempty"`
+	// Secrets maps reviewers to the environment variables their CLI gets,
+	// as secret references resolved only when that reviewer runs
+	Secrets map[string]map[string]string `json:"-"`
 }
 
 // AIReview represents feedback from one AI reviewer
 type AIReview struct {
-	Model    string `json:"model"`
-	Feedback string `json:"feedback"`
-	Error    string `json:"error,omitempty"`
-	Duration string `json:"duration"`
+	Model      string            `json:"model"`
+	Feedback   string            `json:"feedback"`
+	Structured *StructuredReview `json:"structured,omitempty"` // Parsed JSON review, nil if the reviewer never produced valid JSON
+	Error      string            `json:"error,omitempty"`
+	Duration   string            `json:"duration"`
+	Calls      int               `json:"calls,omitempty"` // Times the reviewer CLI ran, including format retries
 }
 
 // PlanReviewResult contains all AI reviews
@@ -59,41 +74,43 @@ func ReviewPlan(input PlanReviewInput, verbose bool) (*PlanReviewResult, error)
 		return nil, fmt.Errorf("no plan content found to review")
 	}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "🔍 Plan to review (%d chars):\n%s\n\n", len(plan), truncateForDisplay(plan, 500))
+	if input.MinSeverity != "" && severityRank(input.MinSeverity) == 0 {
+		return nil, fmt.Errorf("unknown severity %q (expected one of %s)", input.MinSeverity, strings.Join(reviewSeverities, ", "))
 	}
 
-	// Run all 3 AI reviews in parallel
-	var wg sync.WaitGroup
-	reviews := make([]AIReview, 3)
-
-	reviewPrompt := buildReviewPrompt(plan)
+	council, err := selectReviewers(input.Reviewers)
+	if err != nil {
+		return nil, err
+	}
+	for i := range council {
+		council[i].Secrets = input.Secrets[council[i].Key]
+	}
 
-	wg.Add(3)
+	vlog.Printf(verbose, "🔍 Plan to review (%d chars):\n%s\n\n", len(plan), truncateForDisplay(plan, 500))
 
-	// 1. Claude Opus 4.5 review
-	go func() {
-		defer wg.Done()
-		reviews[0] = runClaudeReview(reviewPrompt, verbose)
-	}()
+	// Run all AI reviews in parallel
+	var wg sync.WaitGroup
+	reviews := make([]AIReview, len(council))
 
-	// 2. OpenAI Codex/GPT-5.2 review
-	go func() {
-		defer wg.Done()
-		reviews[1] = runCodexReview(reviewPrompt, verbose)
-	}()
+	reviewPrompt := buildReviewPrompt(plan)
 
-	// 3. Gemini review
-	go func() {
-		defer wg.Done()
-		reviews[2] = runGeminiReview(reviewPrompt, verbose)
-	}()
+	for i, r := range council {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reviews[i] = runReviewer(r, reviewPrompt, verbose)
+		}()
+	}
 
 	wg.Wait()
 
 	result := &PlanReviewResult{
 		Reviews: reviews,
-		Summary: buildReviewSummary(reviews),
+		Summary: buildReviewSummary(reviews, input.MinSeverity),
+	}
+
+	if err := saveReviewResult(result, input); err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not save plan review result: %v\n", err)
 	}
 
 	return result, nil
@@ -101,9 +118,7 @@ func ReviewPlan(input PlanReviewInput, verbose bool) (*PlanReviewResult, error)
 
 // extractPlanFromTranscript reads the transcript and extracts the most recent plan
 func extractPlanFromTranscript(transcriptPath string, verbose bool) (string, error) {
-	if verbose {
-		fmt.Fprintf(os.Stderr, "📖 Reading transcript from: %s\n", transcriptPath)
-	}
+	vlog.Printf(verbose, "📖 Reading transcript from: %s\n", transcriptPath)
 
 	data, err := os.ReadFile(transcriptPath)
 	if err != nil {
@@ -150,7 +165,7 @@ func scorePlan(content string) int {
 	if content == "" {
 		return 0
 	}
-	
+
 	// Filter out very short messages (likely conversational fillers)
 	// "Now let me write up the implementation plan." is ~44 chars
 	if len(content) < 50 {
@@ -161,9 +176,9 @@ func scorePlan(content string) int {
 	lower := strings.ToLower(content)
 
 	// High value indicators
-	if strings.Contains(content, "## Plan") || 
-	   strings.Contains(content, "## Implementation") || 
-	   strings.Contains(content, "## Proposed Approach") {
+	if strings.Contains(content, "## Plan") ||
+		strings.Contains(content, "## Implementation") ||
+		strings.Contains(content, "## Proposed Approach") {
 		score += 100
 	}
 
@@ -218,171 +233,210 @@ Focus on:
 4. **Security concerns** - Any potential vulnerabilities?
 5. **Performance implications** - Will this scale well?
 
-Be concise but thorough. If the plan looks solid, say so briefly and note any minor improvements.
+Be concise but thorough. If the plan looks solid, say so with an "approve" verdict and list any minor improvements as low severity issues.
+
+%s
 
 ## Plan to Review:
 
 %s
 
-## Your Review:`, plan)
+## Your Review (JSON only):`, reviewFormatInstructions, plan)
 }
 
-// runClaudeReview runs the plan through Claude Opus 4.5
-func runClaudeReview(prompt string, verbose bool) AIReview {
-	start := time.Now()
-	review := AIReview{Model: "Claude Opus 4.5"}
-
-	if verbose {
-		fmt.Fprintf(os.Stderr, "🤖 Starting Claude Opus 4.5 review...\n")
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-
-	// claude --print "prompt" --model ... --dangerously-skip-permissions
-	cmd := exec.CommandContext(ctx, "claude",
-		"--print",
-		"--model", "claude-opus-4-5-20251101",
-		"--dangerously-skip-permissions",
-		prompt,
-	)
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// reviewer describes how to invoke one member of the AI council
+type reviewer struct {
+	Key         string // Rate limit bucket, one per CLI/API key
+	Name        string // Short name used in progress and error messages
+	Model       string // Display name in the summary
+	Command     string // CLI executable
+	Args        func(prompt string) []string
+	Timeout     time.Duration
+	InstallHint string
+	// Secrets are the environment variables its CLI gets, by name, as secret
+	// references
+	Secrets map[string]string
+}
 
-	err := cmd.Run()
-	review.Duration = time.Since(start).Round(time.Second).String()
+// councilReviewers are the AI models every plan is reviewed by
+var councilReviewers = []reviewer{
+	{
+		Key:     "claude",
+		Name:    "Claude",
+		Model:   "Claude Opus 4.5",
+		Command: "claude",
+		// claude --print "prompt" --model ... --dangerously-skip-permissions
+		Args: func(prompt string) []string {
+			return []string{"--print", "--model", "claude-opus-4-5-20251101", "--dangerously-skip-permissions", prompt}
+		},
+		Timeout:     2 * time.Minute,
+		InstallHint: "install with: npm install -g @anthropic-ai/claude-code",
+	},
+	{
+		Key:     "codex",
+		Name:    "Codex",
+		Model:   "o3 (Codex)",
+		Command: "codex",
+		// codex exec "prompt" --model o3 --dangerously-bypass-approvals-and-sandbox
+		Args: func(prompt string) []string {
+			return []string{"exec", "--model", "o3", "--dangerously-bypass-approvals-and-sandbox", prompt}
+		},
+		Timeout:     2 * time.Minute,
+		InstallHint: "install OpenAI's Codex CLI",
+	},
+	{
+		Key:     "gemini",
+		Name:    "Gemini",
+		Model:   "Gemini 3 Pro",
+		Command: "gemini",
+		// Gemini CLI expects prompt as positional arg, not -p flag (deprecated)
+		// Use --output-format text for clean output
+		Args: func(prompt string) []string {
+			return []string{"--yolo", "--model", "gemini-2.5-pro", "--output-format", "text", prompt}
+		},
+		// Shorter timeout for Gemini since it can get stuck
+		Timeout:     60 * time.Second,
+		InstallHint: "install Google's Gemini CLI",
+	},
+}
 
-	if err != nil {
-		if strings.Contains(err.Error(), "executable file not found") {
-			review.Error = "Claude CLI not installed (npm install -g @anthropic-ai/claude-code)"
-			review.Feedback = "⚠️ Claude CLI not available - install with: npm install -g @anthropic-ai/claude-code"
-		} else if ctx.Err() == context.DeadlineExceeded {
-			review.Error = "Claude review timed out (2m)"
-			review.Feedback = "⚠️ Claude review timed out"
-		} else {
-			review.Error = fmt.Sprintf("Claude review failed: %v - %s", err, stderr.String())
-			review.Feedback = "⚠️ Claude review failed - see error"
-		}
-		if verbose {
-			fmt.Fprintf(os.Stderr, "❌ Claude review error: %s\n", review.Error)
+// selectReviewers returns the council members named by keys, in the
+// council's order, or all of them when keys is empty
+func selectReviewers(keys []string) ([]reviewer, error) {
+	if len(keys) == 0 {
+		return councilReviewers, nil
+	}
+	var known []string
+	for _, r := range councilReviewers {
+		known = append(known, r.Key)
+	}
+	for _, key := range keys {
+		if !slices.Contains(known, key) {
+			return nil, fmt.Errorf("unknown reviewer %q (expected one of %s)", key, strings.Join(known, ", "))
 		}
-		return review
 	}
-
-	review.Feedback = strings.TrimSpace(stdout.String())
-	if verbose {
-		fmt.Fprintf(os.Stderr, "✅ Claude review complete (%s)\n", review.Duration)
+	var selected []reviewer
+	for _, r := range councilReviewers {
+		if slices.Contains(keys, r.Key) {
+			selected = append(selected, r)
+		}
 	}
-	return review
+	return selected, nil
 }
 
-// runCodexReview runs the plan through OpenAI Codex CLI with o3
-func runCodexReview(prompt string, verbose bool) AIReview {
+// runReviewer runs the plan through one reviewer, retrying when its answer
+// doesn't match the structured review format
+func runReviewer(r reviewer, prompt string, verbose bool) AIReview {
 	start := time.Now()
-	review := AIReview{Model: "o3 (Codex)"}
+	review := AIReview{Model: r.Model}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "🤖 Starting Codex/o3 review...\n")
-	}
+	vlog.Printf(verbose, "🤖 Starting %s review...\n", r.Model)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
+	release, err := acquireReviewCapacity(r.Key, verbose)
+	if err != nil {
+		review.Duration = time.Since(start).Round(time.Second).String()
+		review.Error = fmt.Sprintf("%s review not started: %v", r.Name, err)
+		review.Feedback = fmt.Sprintf("⚠️ %s review skipped - reviewer capacity unavailable", r.Name)
+		return review
+	}
+	defer release()
 
-	// codex exec "prompt" --model o3 --dangerously-bypass-approvals-and-sandbox
-	cmd := exec.CommandContext(ctx, "codex", "exec",
-		"--model", "o3",
-		"--dangerously-bypass-approvals-and-sandbox",
-		prompt,
-	)
+	// Resolved once the reviewer is sure to run, since stores can prompt or bill
+	env, err := secrets.ResolveEnv(r.Secrets)
+	if err != nil {
+		review.Duration = time.Since(start).Round(time.Second).String()
+		review.Error = fmt.Sprintf("%s review not started: %v", r.Name, err)
+		review.Feedback = fmt.Sprintf("⚠️ %s review skipped - its secrets could not be resolved", r.Name)
+		vlog.Printf(verbose, "❌ %s review error: %s\n", r.Name, review.Error)
+		return review
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	attemptPrompt := prompt
+	for attempt := 0; ; attempt++ {
+		output, ok := r.invoke(attemptPrompt, env, &review, verbose)
+		if !ok {
+			review.Duration = time.Since(start).Round(time.Second).String()
+			return review
+		}
 
-	err := cmd.Run()
-	review.Duration = time.Since(start).Round(time.Second).String()
+		structured, parseErr := parseStructuredReview(output)
+		if parseErr == nil {
+			review.Structured = structured
+			review.Feedback = output
+			break
+		}
 
-	if err != nil {
-		if strings.Contains(err.Error(), "executable file not found") {
-			review.Error = "Codex CLI not installed"
-			review.Feedback = "⚠️ Codex CLI not available - install OpenAI's Codex CLI"
-		} else if ctx.Err() == context.DeadlineExceeded {
-			review.Error = "Codex review timed out (2m)"
-			review.Feedback = "⚠️ Codex review timed out"
-		} else {
-			review.Error = fmt.Sprintf("Codex review failed: %v - %s", err, stderr.String())
-			review.Feedback = "⚠️ Codex review failed - see error"
+		if attempt >= maxReviewFormatRetries {
+			// Fall back to showing the free-form answer rather than losing it
+			vlog.Printf(verbose, "⚠️  %s review still malformed after %d retries: %v\n", r.Name, attempt, parseErr)
+			review.Feedback = output
+			break
 		}
-		if verbose {
-			fmt.Fprintf(os.Stderr, "❌ Codex review error: %s\n", review.Error)
+
+		vlog.Printf(verbose, "⚠️  %s review malformed (%v), retrying...\n", r.Name, parseErr)
+
+		ctx, cancel := context.WithTimeout(proc.Context(), reviewQueueTimeout)
+		err := waitForReviewToken(ctx, r.Key, verbose)
+		cancel()
+		if err != nil {
+			review.Feedback = output
+			break
 		}
-		return review
-	}
 
-	review.Feedback = strings.TrimSpace(stdout.String())
-	if verbose {
-		fmt.Fprintf(os.Stderr, "✅ Codex review complete (%s)\n", review.Duration)
+		attemptPrompt = buildFormatRetryPrompt(prompt, output, parseErr)
 	}
+
+	review.Duration = time.Since(start).Round(time.Second).String()
+	vlog.Printf(verbose, "✅ %s review complete (%s)\n", r.Name, review.Duration)
 	return review
 }
 
-// runGeminiReview runs the plan through Google Gemini
-func runGeminiReview(prompt string, verbose bool) AIReview {
-	start := time.Now()
-	review := AIReview{Model: "Gemini 3 Pro"}
-
-	if verbose {
-		fmt.Fprintf(os.Stderr, "🤖 Starting Gemini review...\n")
-	}
-
-	// Shorter timeout for Gemini since it can get stuck
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+// invoke runs the reviewer CLI once, with env added to its environment. On failure it records the error on review and returns false.
+func (r reviewer) invoke(prompt string, env []string, review *AIReview, verbose bool) (string, bool) {
+	ctx, cancel := context.WithTimeout(proc.Context(), r.Timeout)
 	defer cancel()
 
-	// Gemini CLI expects prompt as positional arg, not -p flag (deprecated)
-	// Use --output-format text for clean output
-	cmd := exec.CommandContext(ctx, "gemini",
-		"--yolo",
-		"--model", "gemini-2.5-pro",
-		"--output-format", "text",
-		prompt,
-	)
+	cmd := proc.CommandContext(ctx, r.Command, r.Args(prompt)...)
+	// The CLIs make their own connections, so they need the CA bundle too
+	if env = append(network.Env(), env...); len(env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, env...)
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
-	review.Duration = time.Since(start).Round(time.Second).String()
-
+	if err == nil || !strings.Contains(err.Error(), "executable file not found") {
+		review.Calls++
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "executable file not found") {
-			review.Error = "Gemini CLI not installed"
-			review.Feedback = "⚠️ Gemini CLI not available - install Google's Gemini CLI"
+			review.Error = fmt.Sprintf("%s CLI not installed (%s)", r.Name, r.InstallHint)
+			review.Feedback = fmt.Sprintf("⚠️ %s CLI not available - %s", r.Name, r.InstallHint)
+		} else if proc.Interrupted() {
+			review.Error = fmt.Sprintf("%s review cancelled", r.Name)
+			review.Feedback = fmt.Sprintf("⚠️ %s review cancelled", r.Name)
 		} else if ctx.Err() == context.DeadlineExceeded {
-			review.Error = "Gemini review timed out (60s)"
-			review.Feedback = "⚠️ Gemini review timed out"
+			review.Error = fmt.Sprintf("%s review timed out (%s)", r.Name, r.Timeout)
+			review.Feedback = fmt.Sprintf("⚠️ %s review timed out", r.Name)
 		} else {
-			review.Error = fmt.Sprintf("Gemini review failed: %v - %s", err, stderr.String())
-			review.Feedback = "⚠️ Gemini review failed - see error"
-		}
-		if verbose {
-			fmt.Fprintf(os.Stderr, "❌ Gemini review error: %s\n", review.Error)
+			review.Error = fmt.Sprintf("%s review failed: %v - %s", r.Name, err, stderr.String())
+			review.Feedback = fmt.Sprintf("⚠️ %s review failed - see error", r.Name)
 		}
-		return review
+		vlog.Printf(verbose, "❌ %s review error: %s\n", r.Name, review.Error)
+		return "", false
 	}
 
-	review.Feedback = strings.TrimSpace(stdout.String())
-	if verbose {
-		fmt.Fprintf(os.Stderr, "✅ Gemini review complete (%s)\n", review.Duration)
-	}
-	return review
+	return strings.TrimSpace(stdout.String()), true
 }
 
-// buildReviewSummary creates a summary of all reviews
-func buildReviewSummary(reviews []AIReview) string {
+// buildReviewSummary creates a summary of all reviews, hiding structured
+// issues below minSeverity (empty shows everything)
+func buildReviewSummary(reviews []AIReview, minSeverity string) string {
 	var sb strings.Builder
 
 	sb.WriteString("## 🧠 AI Council Plan Review\n\n")
@@ -395,7 +449,7 @@ func buildReviewSummary(reviews []AIReview) string {
 		}
 	}
 
-	sb.WriteString(fmt.Sprintf("**Reviews completed:** %d/3\n\n", successCount))
+	sb.WriteString(fmt.Sprintf("**Reviews completed:** %d/%d\n\n", successCount, len(reviews)))
 	sb.WriteString("---\n\n")
 
 	for i, r := range reviews {
@@ -410,8 +464,12 @@ func buildReviewSummary(reviews []AIReview) string {
 			sb.WriteString(fmt.Sprintf("*Error: %s*\n\n", r.Error))
 		}
 
-		sb.WriteString(r.Feedback)
-		sb.WriteString("\n\n")
+		if r.Structured != nil {
+			writeStructuredReview(&sb, r.Structured, minSeverity)
+		} else {
+			sb.WriteString(r.Feedback)
+			sb.WriteString("\n\n")
+		}
 
 		if i < len(reviews)-1 {
 			sb.WriteString("---\n\n")
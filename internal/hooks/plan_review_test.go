@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -96,3 +97,22 @@ func TestExtractPlanFromTranscript(t *testing.T) {
 		t.Errorf("Expected extracted plan to be:\n%q\nGot:\n%q", realPlan, extractedPlan)
 	}
 }
+
+func TestSelectReviewers(t *testing.T) {
+	all, err := selectReviewers(nil)
+	if err != nil || len(all) != len(councilReviewers) {
+		t.Fatalf("Expected the whole council by default, got %d (%v)", len(all), err)
+	}
+
+	selected, err := selectReviewers([]string{"gemini", "claude"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(selected) != 2 || selected[0].Key != "claude" || selected[1].Key != "gemini" {
+		t.Errorf("Expected claude then gemini in council order, got %+v", selected)
+	}
+
+	if _, err := selectReviewers([]string{"gpt"}); err == nil || !strings.Contains(err.Error(), "claude, codex, gemini") {
+		t.Errorf("Expected an unknown reviewer to fail naming the known ones, got %v", err)
+	}
+}
@@ -0,0 +1,480 @@
+package hooks
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// ProtoHook checks the code generated from edited .proto files: that it was
+// regenerated, that it and the packages using it still compile, and that
+// service implementations cover every RPC
+type ProtoHook struct{}
+
+func (h *ProtoHook) PreEdit(files []string, verbose bool) error {
+	return nil
+}
+
+func (h *ProtoHook) PostEdit(files []string, verbose bool) error {
+	return h.PostEditJSON(files, verbose)
+}
+
+func (h *ProtoHook) PostEditJSON(files []string, verbose bool) error {
+	cfg, err := config.Load(filepath.Dir(files[0]))
+	if err != nil {
+		return err
+	}
+
+	protos := make([]string, 0, len(files))
+	for _, f := range files {
+		if abs, err := filepath.Abs(f); err == nil {
+			f = abs
+		}
+		protos = append(protos, f)
+	}
+
+	// Read-only sessions check the generated code as it is, like without a command
+	if cfg.Proto.Generate != "" && config.ReadOnly() {
+		vlog.Printf(verbose, "🔒 Read-only, not running %s\n", cfg.Proto.Generate)
+		cfg.Proto.Generate = ""
+	}
+	if cfg.Proto.Generate != "" {
+		if err := runProtoGenerate(cfg.Proto.Generate, filepath.Dir(cfg.Path), verbose); err != nil {
+			return err
+		}
+	}
+
+	generated, err := findGeneratedFiles(state.ProjectRoot(filepath.Dir(protos[0])), protos)
+	if err != nil {
+		return err
+	}
+	if len(generated) == 0 {
+		vlog.Printf(verbose, "🔍 No generated code found for the edited protos\n")
+		return nil
+	}
+
+	// Without a generate command Claude regenerates by hand; checking code that
+	// predates the edit would pass against the old definitions
+	if cfg.Proto.Generate == "" {
+		if stale := staleGeneratedFiles(generated); len(stale) > 0 {
+			return fmt.Errorf("generated code is older than the .proto it comes from; regenerate it (e.g. `buf generate`) so the handlers are checked against the new definitions:\n%s", strings.Join(stale, "\n"))
+		}
+	}
+
+	var goFiles, tsFiles []string
+	for _, g := range generated {
+		if strings.HasSuffix(g.path, ".go") {
+			goFiles = append(goFiles, g.path)
+		} else {
+			tsFiles = append(tsFiles, g.path)
+		}
+	}
+
+	var failures []string
+	if len(goFiles) > 0 {
+		if err := checkGeneratedGo(goFiles, verbose); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(tsFiles) > 0 {
+		if err := checkGeneratedTS(state.ProjectRoot(filepath.Dir(protos[0])), tsFiles, verbose); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "\n\n"))
+	}
+	return nil
+}
+
+// runProtoGenerate runs the configured generate command in dir
+func runProtoGenerate(command, dir string, verbose bool) error {
+	unlock, err := lockWrites(dir, command, verbose)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	args := strings.Fields(command)
+	vlog.Printf(verbose, "🔧 %s (in %s)\n", command, dir)
+	cmd := proc.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil && !proc.Killed(cmd) {
+		return fmt.Errorf("%s failed: %v\n%s", command, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// generatedFile is a Go or TypeScript file generated from proto
+type generatedFile struct {
+	path  string
+	proto string
+}
+
+var (
+	// protoc-gen-go, protoc-gen-go-grpc, protoc-gen-connect-go, and ts-proto
+	sourceHeaderPattern = regexp.MustCompile(`(?m)^//\s*[Ss]ource:\s*(\S+\.proto)\s*$`)
+	// protoc-gen-es and protoc-gen-connect-es
+	generatedFromPattern = regexp.MustCompile(`(?m)^//\s*@generated from file (\S+\.proto)`)
+)
+
+// generatedSuffixes are the file names protobuf generators write
+var generatedSuffixes = []string{".pb.go", ".connect.go", "_pb.ts", "_pb.js", "_connect.ts", "_connect.js", ".client.ts"}
+
+// findGeneratedFiles walks root for files whose generated-code header names one
+// of protos. Headers give the proto path relative to its import root, so it is
+// matched as a suffix of the proto's absolute path.
+func findGeneratedFiles(root string, protos []string) ([]generatedFile, error) {
+	stems := make(map[string]bool)
+	for _, p := range protos {
+		stems[strings.TrimSuffix(filepath.Base(p), ".proto")] = true
+	}
+	sourceOf := func(source string) string {
+		for _, p := range protos {
+			if slashed := filepath.ToSlash(p); slashed == source || strings.HasSuffix(slashed, "/"+source) {
+				return p
+			}
+		}
+		return ""
+	}
+
+	var generated []generatedFile
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// ts-proto names its output after the proto, with no suffix
+		stem := strings.TrimSuffix(name, filepath.Ext(name))
+		if !slices.ContainsFunc(generatedSuffixes, func(s string) bool { return strings.HasSuffix(name, s) }) &&
+			!(stems[stem] && strings.HasSuffix(name, ".ts")) {
+			return nil
+		}
+
+		header, err := readHeader(path)
+		if err != nil {
+			return nil
+		}
+		for _, pattern := range []*regexp.Regexp{sourceHeaderPattern, generatedFromPattern} {
+			for _, match := range pattern.FindAllStringSubmatch(header, -1) {
+				if proto := sourceOf(match[1]); proto != "" {
+					generated = append(generated, generatedFile{path: path, proto: proto})
+					return nil
+				}
+			}
+		}
+		return nil
+	})
+	return generated, err
+}
+
+// readHeader returns the start of a file, where generators put their comments
+func readHeader(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	header, err := io.ReadAll(io.LimitReader(file, 2048))
+	return string(header), err
+}
+
+// staleGeneratedFiles lists generated files last written before their proto
+func staleGeneratedFiles(generated []generatedFile) []string {
+	var stale []string
+	for _, g := range generated {
+		genInfo, err := os.Stat(g.path)
+		if err != nil {
+			continue
+		}
+		protoInfo, err := os.Stat(g.proto)
+		if err != nil {
+			continue
+		}
+		if genInfo.ModTime().Before(protoInfo.ModTime()) {
+			stale = append(stale, fmt.Sprintf("  %s (from %s)", g.path, filepath.Base(g.proto)))
+		}
+	}
+	return stale
+}
+
+// checkGeneratedGo compiles the generated packages and the packages importing
+// them, which is where handlers that no longer satisfy a service interface
+// fail with "missing method" errors
+func checkGeneratedGo(files []string, verbose bool) error {
+	dirs := packageDirs(files)
+	users, err := findDependents(dirs, 0, verbose)
+	if err != nil {
+		return err
+	}
+
+	if err := runGoPerModule(append(slices.Clone(dirs), users...), []string{"vet"}, verbose); err != nil {
+		return fmt.Errorf("generated Go code or the packages using it no longer compile:\n%w", err)
+	}
+	if missing := unimplementedRPCs(files, users); len(missing) > 0 {
+		return fmt.Errorf("service implementations are missing RPCs:\n%s", strings.Join(missing, "\n"))
+	}
+	return nil
+}
+
+// service is a generated server interface with an Unimplemented<Interface>
+// struct that implementations embed for forward compatibility
+type service struct {
+	pkgName string
+	methods []string
+}
+
+// unimplementedRPCs finds types in userDirs that embed a generated
+// Unimplemented* struct without defining every RPC of its interface. Those
+// compile fine, so the compiler can't catch them; the missing RPCs would just
+// return Unimplemented at runtime.
+func unimplementedRPCs(generated []string, userDirs []string) []string {
+	services := make(map[string]map[string]service) // import path -> interface -> service
+	for _, dir := range packageDirs(generated) {
+		moduleRoot, err := findModuleRoot(dir)
+		if err != nil {
+			continue
+		}
+		importPath, err := importPathForDir(moduleRoot, dir)
+		if err != nil {
+			continue
+		}
+		if found := generatedServices(dir); len(found) > 0 {
+			services[importPath] = found
+		}
+	}
+	if len(services) == 0 {
+		return nil
+	}
+
+	var problems []string
+	fset := token.NewFileSet()
+	for _, dir := range userDirs {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+		var files []*ast.File
+		for _, path := range paths {
+			if f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution); err == nil {
+				files = append(files, f)
+			}
+		}
+
+		methods := make(map[string][]string) // receiver type -> methods
+		for _, f := range files {
+			for _, decl := range f.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil && len(fn.Recv.List) == 1 {
+					recv := receiverName(fn.Recv.List[0].Type)
+					methods[recv] = append(methods[recv], fn.Name.Name)
+				}
+			}
+		}
+
+		for _, f := range files {
+			imported := make(map[string]map[string]service) // local name -> services
+			for _, imp := range f.Imports {
+				importPath := strings.Trim(imp.Path.Value, `"`)
+				found, ok := services[importPath]
+				if !ok {
+					continue
+				}
+				for _, svc := range found {
+					local := svc.pkgName
+					if imp.Name != nil {
+						local = imp.Name.Name
+					}
+					imported[local] = found
+					break
+				}
+			}
+			if len(imported) == 0 {
+				continue
+			}
+
+			ast.Inspect(f, func(n ast.Node) bool {
+				spec, ok := n.(*ast.TypeSpec)
+				if !ok {
+					return true
+				}
+				st, ok := spec.Type.(*ast.StructType)
+				if !ok {
+					return false
+				}
+
+				var embedded []*ast.SelectorExpr
+				others := false
+				for _, field := range st.Fields.List {
+					if len(field.Names) > 0 {
+						continue
+					}
+					typ := field.Type
+					if star, ok := typ.(*ast.StarExpr); ok {
+						typ = star.X
+					}
+					if sel, ok := typ.(*ast.SelectorExpr); ok && strings.HasPrefix(sel.Sel.Name, "Unimplemented") {
+						embedded = append(embedded, sel)
+					} else {
+						others = true // Could promote the RPC methods; don't guess
+					}
+				}
+				if others {
+					return false
+				}
+
+				for _, sel := range embedded {
+					pkg, ok := sel.X.(*ast.Ident)
+					if !ok {
+						continue
+					}
+					iface := strings.TrimPrefix(sel.Sel.Name, "Unimplemented")
+					svc, ok := imported[pkg.Name][iface]
+					if !ok {
+						continue
+					}
+					var missing []string
+					for _, m := range svc.methods {
+						if !slices.Contains(methods[spec.Name.Name], m) {
+							missing = append(missing, m)
+						}
+					}
+					if len(missing) > 0 {
+						pos := fset.Position(spec.Pos())
+						problems = append(problems, fmt.Sprintf("%s:%d:%d: %s embeds %s.%s but doesn't implement %s, so those RPCs return Unimplemented",
+							pos.Filename, pos.Line, pos.Column, spec.Name.Name, pkg.Name, sel.Sel.Name, strings.Join(missing, ", ")))
+					}
+				}
+				return false
+			})
+		}
+	}
+	slices.Sort(problems)
+	return problems
+}
+
+// generatedServices returns the server interfaces in the generated package in
+// dir that have an Unimplemented struct, keyed by interface name
+func generatedServices(dir string) map[string]service {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	interfaces := make(map[string][]string)
+	structs := make(map[string]bool)
+	var pkgName string
+
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		pkgName = f.Name.Name
+		ast.Inspect(f, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			switch t := spec.Type.(type) {
+			case *ast.InterfaceType:
+				var methods []string
+				for _, m := range t.Methods.List {
+					for _, name := range m.Names {
+						// Skips mustEmbedUnimplemented..., which the embedded struct provides
+						if name.IsExported() {
+							methods = append(methods, name.Name)
+						}
+					}
+				}
+				interfaces[spec.Name.Name] = methods
+			case *ast.StructType:
+				structs[spec.Name.Name] = true
+			}
+			return false
+		})
+	}
+
+	services := make(map[string]service)
+	for name, methods := range interfaces {
+		if structs["Unimplemented"+name] {
+			services[name] = service{pkgName: pkgName, methods: methods}
+		}
+	}
+	return services
+}
+
+// receiverName returns the type name of a method receiver (T, *T, or T[P])
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// checkGeneratedTS type-checks each TypeScript project containing generated
+// files with the project's own tsc, so connect-es service implementations that
+// no longer match are reported. Projects without a local tsc are skipped.
+func checkGeneratedTS(root string, files []string, verbose bool) error {
+	var projects []string
+	for _, f := range files {
+		if dir := findUp(filepath.Dir(f), root, "tsconfig.json"); dir != "" && !slices.Contains(projects, dir) {
+			projects = append(projects, dir)
+		}
+	}
+
+	var failures []string
+	for _, dir := range projects {
+		binDir := findUp(dir, root, filepath.Join("node_modules", ".bin", "tsc"))
+		if binDir == "" {
+			vlog.Printf(verbose, "⚠️  No local tsc for %s, skipping the TypeScript check\n", dir)
+			continue
+		}
+		vlog.Printf(verbose, "🔧 tsc --noEmit (in %s)\n", dir)
+
+		cmd := proc.Command(filepath.Join(binDir, "node_modules", ".bin", "tsc"), "--noEmit", "--pretty", "false", "-p", ".")
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil && !proc.Killed(cmd) {
+			failures = append(failures, ResolvePaths(strings.TrimSpace(string(output)), dir))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("generated TypeScript code or the code using it no longer compiles:\n%s", strings.Join(failures, "\n\n"))
+	}
+	return nil
+}
+
+// findUp returns the nearest directory from dir up to root containing name
+func findUp(dir, root, name string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, name)); err == nil {
+			return d
+		}
+		if d == root || filepath.Dir(d) == d {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+const testGreeterGRPC = `// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// source: greeter/v1/greeter.proto
+
+package greeterv1
+
+type GreeterServer interface {
+	Hello() error
+	Bye() error
+	mustEmbedUnimplementedGreeterServer()
+}
+
+type UnimplementedGreeterServer struct{}
+
+func (UnimplementedGreeterServer) Hello() error                         { return nil }
+func (UnimplementedGreeterServer) Bye() error                           { return nil }
+func (UnimplementedGreeterServer) mustEmbedUnimplementedGreeterServer() {}
+
+type GreeterHandler interface {
+	Hello() error
+	Bye() error
+}
+`
+
+// writeProtoRepo lays out a module with a proto, code generated from it, and a
+// server package using the generated code
+func writeProtoRepo(t *testing.T, server string) (repo, proto string) {
+	t.Helper()
+	repo = t.TempDir()
+	writeTestFile(t, repo, ".git/HEAD", "ref: refs/heads/main\n")
+	writeTestFile(t, repo, "go.mod", "module example.com/greeter\n\ngo 1.25\n")
+	writeTestFile(t, repo, "proto/greeter/v1/greeter.proto", "syntax = \"proto3\";\n")
+	writeTestFile(t, repo, "gen/greeter/v1/greeter_grpc.pb.go", testGreeterGRPC)
+	writeTestFile(t, repo, "server/server.go", server)
+
+	// The generated code is newer than the proto, as after regenerating
+	proto = filepath.Join(repo, "proto/greeter/v1/greeter.proto")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(proto, old, old); err != nil {
+		t.Fatal(err)
+	}
+	return repo, proto
+}
+
+func TestProtoHookReportsUnimplementedRPCs(t *testing.T) {
+	_, proto := writeProtoRepo(t, `package server
+
+import greeterv1 "example.com/greeter/gen/greeter/v1"
+
+type greeter struct {
+	greeterv1.UnimplementedGreeterServer
+}
+
+func (g *greeter) Hello() error { return nil }
+
+var _ greeterv1.GreeterServer = (*greeter)(nil)
+`)
+
+	err := (&ProtoHook{}).PostEditJSON([]string{proto}, false)
+	if err == nil || !strings.Contains(err.Error(), "greeter embeds greeterv1.UnimplementedGreeterServer but doesn't implement Bye") {
+		t.Fatalf("Expected the missing Bye RPC to be reported, got: %v", err)
+	}
+	if diags := ParseDiagnostics(err.Error(), "proto"); len(diags) != 1 || !strings.HasSuffix(diags[0].File, "server.go") || diags[0].Line != 5 {
+		t.Errorf("Expected a diagnostic on the greeter type, got %+v", diags)
+	}
+}
+
+func TestProtoHookReportsMissingMethods(t *testing.T) {
+	_, proto := writeProtoRepo(t, `package server
+
+import greeterv1 "example.com/greeter/gen/greeter/v1"
+
+type handler struct{}
+
+func (handler) Hello() error { return nil }
+
+var _ greeterv1.GreeterHandler = handler{}
+`)
+
+	err := (&ProtoHook{}).PostEditJSON([]string{proto}, false)
+	if err == nil || !strings.Contains(err.Error(), "missing method Bye") {
+		t.Fatalf("Expected the compiler's missing method error, got: %v", err)
+	}
+}
+
+func TestProtoHookPassesCompleteImplementation(t *testing.T) {
+	_, proto := writeProtoRepo(t, `package server
+
+import greeterv1 "example.com/greeter/gen/greeter/v1"
+
+type greeter struct {
+	greeterv1.UnimplementedGreeterServer
+}
+
+func (g *greeter) Hello() error { return nil }
+func (g *greeter) Bye() error   { return nil }
+`)
+
+	if err := (&ProtoHook{}).PostEditJSON([]string{proto}, false); err != nil {
+		t.Fatalf("Expected a complete implementation to pass, got: %v", err)
+	}
+}
+
+func TestProtoHookReportsStaleGeneratedCode(t *testing.T) {
+	_, proto := writeProtoRepo(t, "package server\n")
+	now := time.Now().Add(time.Minute)
+	if err := os.Chtimes(proto, now, now); err != nil {
+		t.Fatal(err)
+	}
+
+	err := (&ProtoHook{}).PostEditJSON([]string{proto}, false)
+	if err == nil || !strings.Contains(err.Error(), "regenerate") || !strings.Contains(err.Error(), "greeter_grpc.pb.go") {
+		t.Fatalf("Expected stale generated code to be reported, got: %v", err)
+	}
+}
+
+func TestProtoHookReadOnlySkipsGenerate(t *testing.T) {
+	repo, proto := writeProtoRepo(t, "package server\n")
+	writeTestFile(t, repo, ".claude-hooks.yaml", "proto:\n  generate: touch generated-by-hook\n")
+	now := time.Now().Add(time.Minute)
+	if err := os.Chtimes(proto, now, now); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.ReadOnlyEnv, "1")
+
+	// The generated code is checked as it is, so it's reported as stale
+	err := (&ProtoHook{}).PostEditJSON([]string{proto}, false)
+	if err == nil || !strings.Contains(err.Error(), "regenerate") {
+		t.Fatalf("Expected stale generated code to be reported, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "generated-by-hook")); err == nil {
+		t.Error("Expected the generate command not to run in read-only mode")
+	}
+}
+
+func TestFindGeneratedFiles(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "proto/greeter/v1/greeter.proto", "syntax = \"proto3\";\n")
+	writeTestFile(t, repo, "gen/greeter/v1/greeter.pb.go", "// source: greeter/v1/greeter.proto\n\npackage greeterv1\n")
+	writeTestFile(t, repo, "gen/greeter/v1/greeterv1connect/greeter.connect.go", "// Source: greeter/v1/greeter.proto\n\npackage greeterv1connect\n")
+	writeTestFile(t, repo, "web/gen/greeter/v1/greeter_pb.ts", "// @generated from file greeter/v1/greeter.proto (package greeter.v1, syntax proto3)\n")
+	writeTestFile(t, repo, "web/gen/greeter/v1/greeter.ts", "// source: greeter/v1/greeter.proto\n")
+	writeTestFile(t, repo, "gen/other/v1/other.pb.go", "// source: other/v1/other.proto\n\npackage otherv1\n")
+	writeTestFile(t, repo, "web/node_modules/pkg/greeter_pb.ts", "// @generated from file greeter/v1/greeter.proto\n")
+
+	generated, err := findGeneratedFiles(repo, []string{filepath.Join(repo, "proto/greeter/v1/greeter.proto")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, g := range generated {
+		rel, _ := filepath.Rel(repo, g.path)
+		found = append(found, filepath.ToSlash(rel))
+	}
+	want := []string{
+		"gen/greeter/v1/greeter.pb.go",
+		"gen/greeter/v1/greeterv1connect/greeter.connect.go",
+		"web/gen/greeter/v1/greeter.ts",
+		"web/gen/greeter/v1/greeter_pb.ts",
+	}
+	if strings.Join(found, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %v, got %v", want, found)
+	}
+}
@@ -0,0 +1,265 @@
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/vcs"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// PushFinding is a problem in a commit that is about to be pushed
+type PushFinding struct {
+	Commit string // Short commit hash
+	File   string
+	Line   int // 0 when the finding is about the whole file
+	Kind   string
+	Detail string
+}
+
+func (f PushFinding) String() string {
+	location := f.File
+	if f.Line > 0 {
+		location = fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return fmt.Sprintf("%s %s: %s (%s)", f.Commit, location, f.Kind, f.Detail)
+}
+
+// SecretFinding is the Kind of findings of credentials
+const SecretFinding = "possible secret"
+
+// secretPatterns match credentials that should never leave the machine
+var secretPatterns = []struct {
+	Name    string
+	Pattern *regexp.Regexp
+}{
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN ([A-Z]+ )?PRIVATE KEY-----`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"Stripe live key", regexp.MustCompile(`\b[rs]k_live_[0-9a-zA-Z]{24,}`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{"hardcoded credential", regexp.MustCompile(`(?i)\b(api[_-]?key|secret|password|passwd|access[_-]?token)\b\s*[:=]\s*["'][^"'\s]{12,}["']`)},
+}
+
+// ScanOutgoingCommits inspects the commits push would send from repoDir:
+// added lines are checked for secrets, and added or modified files for size and
+// disallowed paths
+func ScanOutgoingCommits(repoDir string, push vcs.GitPush, cfg config.PushConfig, verbose bool) ([]PushFinding, error) {
+	commits, err := outgoingCommits(repoDir, push)
+	if err != nil {
+		return nil, err
+	}
+
+	vlog.Printf(verbose, "🔍 Scanning %d outgoing commit(s) before push\n", len(commits))
+	return scanCommits(repoDir, commits, cfg)
+}
+
+// ScanCommitRange applies the push checks to every commit in a rev-list range
+// such as base..HEAD, e.g. the commits of a pull request
+func ScanCommitRange(repoDir, revRange string, cfg config.PushConfig) ([]PushFinding, error) {
+	output, err := proc.Command("git", "-C", repoDir, "rev-list", revRange).Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing commits in %s: %w", revRange, err)
+	}
+	return scanCommits(repoDir, strings.Fields(string(output)), cfg)
+}
+
+func scanCommits(repoDir string, commits []string, cfg config.PushConfig) ([]PushFinding, error) {
+	var findings []PushFinding
+	for _, commit := range commits {
+		short := commit[:min(len(commit), 8)]
+
+		files, err := changedBlobs(repoDir, commit)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if pattern := matchDisallowedPath(f.path, cfg.DisallowedPaths); pattern != "" {
+				findings = append(findings, PushFinding{Commit: short, File: f.path, Kind: "disallowed path", Detail: "matches " + pattern})
+			}
+			if cfg.MaxFileSize > 0 && f.size > cfg.MaxFileSize {
+				findings = append(findings, PushFinding{Commit: short, File: f.path, Kind: "oversized file",
+					Detail: fmt.Sprintf("%d bytes, limit %d", f.size, cfg.MaxFileSize)})
+			}
+		}
+
+		secrets, err := scanCommitForSecrets(repoDir, commit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range secrets {
+			secrets[i].Commit = short
+		}
+		findings = append(findings, secrets...)
+	}
+
+	return findings, nil
+}
+
+// outgoingCommits lists the commits push would send: those reachable from the
+// refs it pushes that the remote's tracking refs don't have yet. A remote
+// without tracking refs, like a URL, is compared against every remote.
+func outgoingCommits(repoDir string, push vcs.GitPush) ([]string, error) {
+	if push.Deletes {
+		return nil, nil
+	}
+	args := []string{"-C", repoDir, "rev-list"}
+	if push.All {
+		args = append(args, "--branches")
+	}
+	if push.Tags {
+		args = append(args, "--tags")
+	}
+	for _, src := range push.Refspecs {
+		if strings.Contains(src, "*") {
+			src = "--glob=" + src
+		}
+		args = append(args, src)
+	}
+	if !push.All && !push.Tags && len(push.Refspecs) == 0 {
+		args = append(args, "HEAD") // The current branch
+	}
+	args = append(args, "--not", remoteRefs(repoDir, push.Remote))
+
+	output, err := proc.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing outgoing commits: %w", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// remoteRefs returns the rev-list option for the tracking refs of remote, or
+// of the current branch's push remote when it's empty
+func remoteRefs(repoDir, remote string) string {
+	output, err := proc.Command("git", "-C", repoDir, "remote").Output()
+	if err != nil {
+		return "--remotes"
+	}
+	remotes := strings.Fields(string(output))
+	if remote == "" {
+		// Where the branch pushes to, like origin/feature
+		if dest, err := proc.Command("git", "-C", repoDir, "rev-parse", "--abbrev-ref", "@{push}").Output(); err == nil {
+			for _, r := range remotes {
+				if strings.HasPrefix(string(dest), r+"/") {
+					remote = r
+				}
+			}
+		}
+	}
+	if !slices.Contains(remotes, remote) {
+		return "--remotes"
+	}
+	return "--remotes=" + remote
+}
+
+type changedBlob struct {
+	path string
+	size int64
+}
+
+// changedBlobs returns the files a commit added or modified along with their new sizes
+func changedBlobs(repoDir, commit string) ([]changedBlob, error) {
+	output, err := proc.Command("git", "-C", repoDir, "diff-tree", "--no-commit-id", "-r", "--root", "--no-abbrev", "--diff-filter=AMR", commit).Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing files in %s: %w", commit, err)
+	}
+
+	var blobs []changedBlob
+	var shas []string
+	for line := range strings.Lines(string(output)) {
+		// :100644 100644 <old> <new> M\tpath
+		meta, file, ok := strings.Cut(strings.TrimRight(line, "\n"), "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) < 5 {
+			continue
+		}
+		if _, renamed, ok := strings.Cut(file, "\t"); ok {
+			file = renamed
+		}
+		blobs = append(blobs, changedBlob{path: file})
+		shas = append(shas, fields[3])
+	}
+	if len(blobs) == 0 {
+		return nil, nil
+	}
+
+	cmd := proc.Command("git", "-C", repoDir, "cat-file", "--batch-check=%(objectsize)")
+	cmd.Stdin = strings.NewReader(strings.Join(shas, "\n") + "\n")
+	sizes, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("reading blob sizes: %w", err)
+	}
+	for i, field := range strings.Fields(string(sizes)) {
+		if i < len(blobs) {
+			blobs[i].size, _ = strconv.ParseInt(field, 10, 64)
+		}
+	}
+	return blobs, nil
+}
+
+// scanCommitForSecrets checks every line a commit adds against secretPatterns
+func scanCommitForSecrets(repoDir, commit string) ([]PushFinding, error) {
+	output, err := proc.Command("git", "-C", repoDir, "show", "--format=", "--no-color", "-U0", "--root", commit).Output()
+	if err != nil {
+		return nil, fmt.Errorf("reading diff of %s: %w", commit, err)
+	}
+
+	var findings []PushFinding
+	var file string
+	line := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+		case strings.HasPrefix(text, "@@"):
+			line = hunkStartLine(text)
+		case strings.HasPrefix(text, "+"):
+			for _, p := range secretPatterns {
+				if p.Pattern.MatchString(text) {
+					findings = append(findings, PushFinding{File: file, Line: line, Kind: SecretFinding, Detail: p.Name})
+					break
+				}
+			}
+			line++
+		}
+	}
+	return findings, scanner.Err()
+}
+
+// hunkStartLine parses the new-file start line from "@@ -a,b +c,d @@"
+func hunkStartLine(header string) int {
+	_, rest, ok := strings.Cut(header, "+")
+	if !ok {
+		return 0
+	}
+	start, _, _ := strings.Cut(rest, ",")
+	start, _, _ = strings.Cut(start, " ")
+	n, _ := strconv.Atoi(start)
+	return n
+}
+
+// matchDisallowedPath returns the first pattern matching the file's path or base name
+func matchDisallowedPath(file string, patterns []string) string {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, file); ok {
+			return pattern
+		}
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return pattern
+		}
+	}
+	return ""
+}
@@ -0,0 +1,179 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// Reviewer capacity is shared by every claude-hook process on the machine through
+// lock files in the state directory, so parallel sessions and subagents queue for
+// their turn instead of all hitting the same API keys at once and failing with 429s.
+
+const (
+	defaultMaxConcurrentReviews = 3
+	defaultReviewsPerMinute     = 6
+	defaultReviewBurst          = 3
+
+	reviewSlotPollInterval = 500 * time.Millisecond
+	rateLimitStateFile     = "ratelimit.json"
+	rateLimitLockFile      = "ratelimit.lock"
+)
+
+// reviewQueueTimeout bounds how long a reviewer waits for capacity before giving up
+var reviewQueueTimeout = 5 * time.Minute
+
+// tokenBucket tracks the request budget of a single reviewer CLI
+type tokenBucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// take refills the bucket for the time elapsed since its last update and tries to
+// consume one token. It returns zero on success, or how long until a token is available.
+func (b *tokenBucket) take(now time.Time, perMinute, burst float64) time.Duration {
+	if b.Updated.IsZero() {
+		b.Tokens = burst
+	} else if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed.Minutes()*perMinute)
+	}
+	b.Updated = now
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return 0
+	}
+
+	missing := 1 - b.Tokens
+	return time.Duration(missing / perMinute * float64(time.Minute))
+}
+
+// acquireReviewCapacity waits for a global concurrency slot and then a rate
+// limit token for the reviewer. The slot comes first so a call that times out
+// waiting for one hasn't spent a token. The returned function releases the slot.
+func acquireReviewCapacity(reviewer string, verbose bool) (func(), error) {
+	ctx, cancel := context.WithTimeout(proc.Context(), reviewQueueTimeout)
+	defer cancel()
+
+	release, err := acquireReviewSlot(ctx, reviewer, verbose)
+	if err != nil {
+		return nil, err
+	}
+	if err := waitForReviewToken(ctx, reviewer, verbose); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// waitForReviewToken blocks until the reviewer's token bucket allows another request
+func waitForReviewToken(ctx context.Context, reviewer string, verbose bool) error {
+	perMinute := float64(envInt("CLAUDE_HOOKS_REVIEWS_PER_MINUTE", defaultReviewsPerMinute))
+	burst := float64(envInt("CLAUDE_HOOKS_REVIEW_BURST", defaultReviewBurst))
+
+	lockPath, err := state.Path(rateLimitLockFile)
+	if err != nil {
+		return err
+	}
+	statePath, err := state.Path(rateLimitStateFile)
+	if err != nil {
+		return err
+	}
+
+	for {
+		wait, err := takeReviewToken(ctx, lockPath, statePath, reviewer, perMinute, burst)
+		if err != nil {
+			return err
+		}
+		if wait == 0 {
+			return nil
+		}
+
+		vlog.Printf(verbose, "⏳ %s rate limit reached, waiting %s...\n", reviewer, wait.Round(time.Second))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s rate limit", reviewer)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// takeReviewToken updates the persisted bucket for reviewer under the rate limit lock
+func takeReviewToken(ctx context.Context, lockPath, statePath, reviewer string, perMinute, burst float64) (time.Duration, error) {
+	unlock, err := state.Lock(ctx, lockPath)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	buckets := make(map[string]*tokenBucket)
+	if data, err := os.ReadFile(statePath); err == nil {
+		// A corrupt state file just resets the buckets
+		_ = json.Unmarshal(data, &buckets)
+	}
+
+	bucket := buckets[reviewer]
+	if bucket == nil {
+		bucket = &tokenBucket{}
+		buckets[reviewer] = bucket
+	}
+
+	wait := bucket.take(time.Now(), perMinute, burst)
+
+	data, err := json.Marshal(buckets)
+	if err != nil {
+		return 0, fmt.Errorf("marshaling rate limit state: %w", err)
+	}
+	if err := os.WriteFile(statePath, data, 0o644); err != nil {
+		return 0, fmt.Errorf("writing rate limit state: %w", err)
+	}
+
+	return wait, nil
+}
+
+// acquireReviewSlot takes one of the global reviewer slots, waiting until one frees up
+func acquireReviewSlot(ctx context.Context, reviewer string, verbose bool) (func(), error) {
+	slots := envInt("CLAUDE_HOOKS_MAX_CONCURRENT_REVIEWS", defaultMaxConcurrentReviews)
+	announced := false
+
+	for {
+		for i := range slots {
+			path, err := state.Path(fmt.Sprintf("review-slot-%d.lock", i))
+			if err != nil {
+				return nil, err
+			}
+			if release, err := state.TryLock(path); err == nil {
+				return release, nil
+			}
+		}
+
+		if verbose && !announced {
+			fmt.Fprintf(os.Stderr, "⏳ All %d reviewer slots busy, %s review queued...\n", slots, reviewer)
+			announced = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for a free reviewer slot")
+		case <-time.After(reviewSlotPollInterval):
+		}
+	}
+}
+
+// envInt reads a positive integer from the environment, falling back to def
+func envInt(name string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
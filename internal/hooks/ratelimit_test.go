@@ -0,0 +1,98 @@
+package hooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	now := time.Now()
+	bucket := &tokenBucket{}
+
+	// A fresh bucket starts full
+	for i := range 3 {
+		if wait := bucket.take(now, 6, 3); wait != 0 {
+			t.Fatalf("take %d: expected token, got wait %s", i, wait)
+		}
+	}
+
+	// Empty bucket at 6/minute needs 10s for the next token
+	if wait := bucket.take(now, 6, 3); wait != 10*time.Second {
+		t.Fatalf("Expected 10s wait on empty bucket, got %s", wait)
+	}
+
+	// After refilling, a token is available again
+	if wait := bucket.take(now.Add(10*time.Second), 6, 3); wait != 0 {
+		t.Fatalf("Expected token after refill, got wait %s", wait)
+	}
+
+	// Refill never exceeds the burst size
+	bucket.take(now.Add(time.Hour), 6, 3)
+	if bucket.Tokens != 2 {
+		t.Fatalf("Expected bucket capped at burst, got %v tokens left", bucket.Tokens)
+	}
+}
+
+func TestAcquireReviewSlotLimitsConcurrency(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	t.Setenv("CLAUDE_HOOKS_MAX_CONCURRENT_REVIEWS", "1")
+
+	release, err := acquireReviewCapacity("claude", false)
+	if err != nil {
+		t.Fatalf("Failed to acquire first slot: %v", err)
+	}
+
+	// With the only slot taken, a second reviewer must queue
+	done := make(chan struct{})
+	go func() {
+		second, err := acquireReviewCapacity("codex", false)
+		if err != nil {
+			t.Errorf("Failed to acquire slot after release: %v", err)
+			close(done)
+			return
+		}
+		second()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Second reviewer acquired a slot while the first still held it")
+	case <-time.After(2 * reviewSlotPollInterval):
+	}
+
+	release()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Second reviewer never acquired the released slot")
+	}
+}
+
+// TestAcquireReviewCapacityTimeoutKeepsToken times out waiting for the only
+// slot and expects the reviewer's single token unspent, so the next call gets
+// it as soon as the slot frees
+func TestAcquireReviewCapacityTimeoutKeepsToken(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	t.Setenv("CLAUDE_HOOKS_MAX_CONCURRENT_REVIEWS", "1")
+	t.Setenv("CLAUDE_HOOKS_REVIEWS_PER_MINUTE", "1")
+	t.Setenv("CLAUDE_HOOKS_REVIEW_BURST", "1")
+	defer func(timeout time.Duration) { reviewQueueTimeout = timeout }(reviewQueueTimeout)
+	reviewQueueTimeout = 2 * reviewSlotPollInterval
+
+	release, err := acquireReviewCapacity("claude", false)
+	if err != nil {
+		t.Fatalf("Failed to acquire the slot: %v", err)
+	}
+	if _, err := acquireReviewCapacity("codex", false); err == nil {
+		t.Fatal("Expected codex to time out waiting for the only slot")
+	}
+	release()
+
+	second, err := acquireReviewCapacity("codex", false)
+	if err != nil {
+		t.Fatalf("Expected codex's token to be left by the timed out call: %v", err)
+	}
+	second()
+}
@@ -0,0 +1,206 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// StructuredReview is the JSON document reviewers are asked to respond with
+type StructuredReview struct {
+	Verdict string        `json:"verdict"` // "approve", "revise", or "reject"
+	Summary string        `json:"summary"`
+	Issues  []ReviewIssue `json:"issues"`
+}
+
+// ReviewIssue is a single problem a reviewer found in the plan
+type ReviewIssue struct {
+	Severity    string `json:"severity"` // "critical", "high", "medium", or "low"
+	Description string `json:"description"`
+	Suggestion  string `json:"suggestion,omitempty"`
+}
+
+// maxReviewFormatRetries is how many times a reviewer is re-asked after malformed output
+const maxReviewFormatRetries = 2
+
+// reviewResultFile holds the last plan review for machine consumption
+const reviewResultFile = "last-plan-review.json"
+
+// reviewSeverities lists issue severities from most to least severe
+var reviewSeverities = []string{"critical", "high", "medium", "low"}
+
+var reviewVerdicts = []string{"approve", "revise", "reject"}
+
+const reviewFormatInstructions = `Respond with ONLY a JSON object (no prose, no markdown fences) matching this schema:
+
+{
+  "verdict": "approve" | "revise" | "reject",
+  "summary": "one or two sentence overall assessment",
+  "issues": [
+    {
+      "severity": "critical" | "high" | "medium" | "low",
+      "description": "what is wrong or missing",
+      "suggestion": "how to address it"
+    }
+  ]
+}
+
+Use an empty issues array if you found nothing worth changing.`
+
+// severityRank orders severities so higher is more severe; unknown severities rank 0
+func severityRank(severity string) int {
+	idx := slices.Index(reviewSeverities, strings.ToLower(severity))
+	if idx < 0 {
+		return 0
+	}
+	return len(reviewSeverities) - idx
+}
+
+// parseStructuredReview extracts and validates the JSON review from reviewer output,
+// tolerating markdown fences or stray text around the object
+func parseStructuredReview(output string) (*StructuredReview, error) {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in response")
+	}
+
+	var review StructuredReview
+	if err := json.Unmarshal([]byte(output[start:end+1]), &review); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	review.Verdict = strings.ToLower(strings.TrimSpace(review.Verdict))
+	if !slices.Contains(reviewVerdicts, review.Verdict) {
+		return nil, fmt.Errorf("verdict %q is not one of %s", review.Verdict, strings.Join(reviewVerdicts, ", "))
+	}
+
+	for i := range review.Issues {
+		issue := &review.Issues[i]
+		issue.Severity = strings.ToLower(strings.TrimSpace(issue.Severity))
+		if severityRank(issue.Severity) == 0 {
+			return nil, fmt.Errorf("issues[%d].severity %q is not one of %s", i, issue.Severity, strings.Join(reviewSeverities, ", "))
+		}
+		if strings.TrimSpace(issue.Description) == "" {
+			return nil, fmt.Errorf("issues[%d].description is empty", i)
+		}
+	}
+
+	// Most severe issues first
+	slices.SortStableFunc(review.Issues, func(a, b ReviewIssue) int {
+		return severityRank(b.Severity) - severityRank(a.Severity)
+	})
+
+	return &review, nil
+}
+
+// buildFormatRetryPrompt asks the reviewer to restate its previous answer in the required format
+func buildFormatRetryPrompt(originalPrompt, previousOutput string, parseErr error) string {
+	return fmt.Sprintf(`%s
+
+## Format Correction
+
+Your previous response could not be parsed (%v). Previous response:
+
+%s
+
+Restate that review as a single JSON object exactly matching the schema above. Output only the JSON.`, originalPrompt, parseErr, truncateForDisplay(previousOutput, 4000))
+}
+
+// writeStructuredReview renders a structured review as markdown, hiding issues below minSeverity
+func writeStructuredReview(sb *strings.Builder, review *StructuredReview, minSeverity string) {
+	sb.WriteString(fmt.Sprintf("**Verdict:** %s\n\n", review.Verdict))
+	if review.Summary != "" {
+		sb.WriteString(review.Summary)
+		sb.WriteString("\n\n")
+	}
+
+	minRank := severityRank(minSeverity)
+	hidden := 0
+	for _, issue := range review.Issues {
+		if severityRank(issue.Severity) < minRank {
+			hidden++
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("- **[%s]** %s\n", issue.Severity, issue.Description))
+		if issue.Suggestion != "" {
+			sb.WriteString(fmt.Sprintf("  - *Suggestion:* %s\n", issue.Suggestion))
+		}
+	}
+
+	if len(review.Issues) == 0 {
+		sb.WriteString("- No issues found\n")
+	}
+	if hidden > 0 {
+		sb.WriteString(fmt.Sprintf("- *%d issue(s) below %s severity hidden*\n", hidden, minSeverity))
+	}
+	sb.WriteString("\n")
+}
+
+// saveReviewResult writes the full review result as JSON to the session's state,
+// falling back to the project's logs or the shared state directory when the
+// session or project is unknown
+func saveReviewResult(result *PlanReviewResult, input PlanReviewInput) error {
+	path, err := reviewResultPath(input)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling review result: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing review result: %w", err)
+	}
+	return nil
+}
+
+// ArchivedReviews returns the plan reviews saved since then, in every
+// project's and session's state
+func ArchivedReviews(since time.Time) ([]PlanReviewResult, error) {
+	root, err := state.Dir()
+	if err != nil {
+		return nil, err
+	}
+	var results []PlanReviewResult
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != reviewResultFile {
+			return nil // Unreadable directories are skipped
+		}
+		if info, err := d.Info(); err != nil || info.ModTime().Before(since) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var result PlanReviewResult
+		if json.Unmarshal(data, &result) == nil {
+			results = append(results, result)
+		}
+		return nil
+	})
+	return results, err
+}
+
+// reviewResultPath is where saveReviewResult writes for input
+func reviewResultPath(input PlanReviewInput) (string, error) {
+	switch {
+	case input.Cwd == "":
+		return state.Path(reviewResultFile)
+	case input.SessionID == "":
+		return state.ProjectPath(input.Cwd, state.Logs, reviewResultFile)
+	default:
+		return state.SessionPath(input.Cwd, input.SessionID, input.TranscriptPath, reviewResultFile)
+	}
+}
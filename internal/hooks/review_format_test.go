@@ -0,0 +1,96 @@
+package hooks
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/pkg/hooktest/golden"
+)
+
+func TestParseStructuredReview(t *testing.T) {
+	output := "Here is my review:\n```json\n" + `{
+  "verdict": "Revise",
+  "summary": "Mostly fine.",
+  "issues": [
+    {"severity": "low", "description": "Naming is inconsistent"},
+    {"severity": "critical", "description": "No rollback plan", "suggestion": "Add a down migration"}
+  ]
+}` + "\n```"
+
+	review, err := parseStructuredReview(output)
+	if err != nil {
+		t.Fatalf("Failed to parse review: %v", err)
+	}
+
+	if review.Verdict != "revise" {
+		t.Errorf("Expected verdict to be normalized to %q, got %q", "revise", review.Verdict)
+	}
+	if len(review.Issues) != 2 || review.Issues[0].Severity != "critical" {
+		t.Errorf("Expected issues sorted by severity, got %+v", review.Issues)
+	}
+}
+
+func TestParseStructuredReviewRejectsMalformed(t *testing.T) {
+	tests := map[string]string{
+		"no json":          "The plan looks good to me.",
+		"bad verdict":      `{"verdict": "lgtm", "issues": []}`,
+		"bad severity":     `{"verdict": "approve", "issues": [{"severity": "urgent", "description": "x"}]}`,
+		"empty issue":      `{"verdict": "approve", "issues": [{"severity": "low", "description": ""}]}`,
+		"truncated object": `{"verdict": "approve", "issues": [}`,
+	}
+
+	for name, output := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseStructuredReview(output); err == nil {
+				t.Errorf("Expected error for %q", output)
+			}
+		})
+	}
+}
+
+func TestBuildReviewSummaryFiltersSeverity(t *testing.T) {
+	reviews := []AIReview{{
+		Model:    "Test Model",
+		Duration: "1s",
+		Structured: &StructuredReview{
+			Verdict: "revise",
+			Issues: []ReviewIssue{
+				{Severity: "high", Description: "Missing auth check"},
+				{Severity: "low", Description: "Typo in step 3"},
+			},
+		},
+	}}
+
+	summary := buildReviewSummary(reviews, "medium")
+
+	if !strings.Contains(summary, "Missing auth check") {
+		t.Errorf("Expected high severity issue in summary, got:\n%s", summary)
+	}
+	if strings.Contains(summary, "Typo in step 3") {
+		t.Errorf("Expected low severity issue to be hidden, got:\n%s", summary)
+	}
+	if !strings.Contains(summary, "1 issue(s) below medium severity hidden") {
+		t.Errorf("Expected hidden issue count in summary, got:\n%s", summary)
+	}
+}
+
+func TestGoldenReviewSummary(t *testing.T) {
+	reviews := []AIReview{
+		{
+			Model:    "Claude",
+			Duration: "41s",
+			Structured: &StructuredReview{
+				Verdict: "revise",
+				Summary: "The migration plan is sound, but it has no way back.",
+				Issues: []ReviewIssue{
+					{Severity: "critical", Description: "No rollback for the schema change", Suggestion: "Add a down migration and test it"},
+					{Severity: "medium", Description: "Step 4 reindexes the table during business hours"},
+					{Severity: "low", Description: "Typo in step 2"},
+				},
+			},
+		},
+		{Model: "Codex", Duration: "1m2s", Feedback: "Looks fine overall; consider batching the backfill."},
+		{Model: "Gemini", Duration: "0s", Error: "gemini: command not found"},
+	}
+	golden.AssertString(t, "review_summary", buildReviewSummary(reviews, "medium"))
+}
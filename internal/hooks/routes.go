@@ -0,0 +1,186 @@
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// routeHeadSize is how much of a file shebang and content routes look at
+const routeHeadSize = 4096
+
+// extensionTypes are the file types of the built-in extensions
+var extensionTypes = map[string]string{
+	".go":    "go",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".js":    "javascript",
+	".jsx":   "javascript",
+	".py":    "python",
+	".proto": "proto",
+	".rs":    "rust",
+}
+
+// GroupFiles groups files by the hook they go to, dropping files no route or
+// extension matches
+func GroupFiles(files []string) map[string][]string {
+	groups := make(map[string][]string)
+	configs := make(map[string]*config.Config) // By directory
+	for _, f := range files {
+		dir := filepath.Dir(f)
+		cfg, ok := configs[dir]
+		if !ok {
+			var err error
+			if cfg, err = config.Load(dir); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Failed to load config, using defaults: %v\n", err)
+				cfg = config.Default()
+			}
+			configs[dir] = cfg
+		}
+		if fileType := route(cfg, f); fileType != "" {
+			groups[fileType] = append(groups[fileType], f)
+		}
+	}
+	return groups
+}
+
+// route returns the hook of the first route matching file, then the build
+// system's for a Bazel or Buck2 package, then the task runner's for a JS or TS
+// file in an Nx or Turborepo workspace, then the file type of its extension
+func route(cfg *config.Config, file string) string {
+	var head []byte
+	headRead := false
+	for _, r := range cfg.Routes {
+		if r.Hook == "" || (r.Glob == "" && r.Shebang == "" && r.Content == "") {
+			continue
+		}
+		if r.Glob != "" && !matchRouteGlob(r.Glob, file) {
+			continue
+		}
+		if (r.Shebang != "" || r.Content != "") && !headRead {
+			head, headRead = readHead(file), true
+		}
+		if r.Shebang != "" && !matchShebang(r.Shebang, head) {
+			continue
+		}
+		if r.Content != "" && !matchPattern(r.Content, head) {
+			continue
+		}
+		return r.Hook
+	}
+	if fileType := bazelRoute(cfg.Bazel, file); fileType != "" {
+		return fileType
+	}
+	fileType := extensionTypes[strings.ToLower(filepath.Ext(file))]
+	if runner := taskRunnerRoute(cfg.Monorepo, file, fileType); runner != "" {
+		return runner
+	}
+	return fileType
+}
+
+// matchRouteGlob matches file's path relative to its project root, or its base name
+func matchRouteGlob(pattern, file string) bool {
+	rel := filepath.Base(file)
+	if r, err := filepath.Rel(state.ProjectRoot(filepath.Dir(file)), file); err == nil {
+		rel = filepath.ToSlash(r)
+	}
+	if ok, _ := path.Match(pattern, rel); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, filepath.Base(file))
+	return ok
+}
+
+// matchShebang matches pattern against the interpreter line of a script
+func matchShebang(pattern string, head []byte) bool {
+	if !bytes.HasPrefix(head, []byte("#!")) {
+		return false
+	}
+	line, _, _ := bytes.Cut(head[2:], []byte("\n"))
+	return matchPattern(pattern, bytes.TrimSpace(line))
+}
+
+// matchPattern reports whether the regular expression matches; an invalid one
+// matches nothing
+func matchPattern(pattern string, data []byte) bool {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Ignoring route with invalid pattern %q: %v\n", pattern, err)
+		return false
+	}
+	return re.Match(data)
+}
+
+// readHead returns the start of file, or nothing when it can't be read
+func readHead(file string) []byte {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	head, _ := io.ReadAll(io.LimitReader(f, routeHeadSize))
+	return head
+}
+
+// CommandHook runs a configured command or make targets on the files routed to it
+type CommandHook struct {
+	Name    string
+	Command string
+	Make    []string // Targets run instead of Command
+	Dir     string   // Where the command runs, the config file's directory
+}
+
+func (h *CommandHook) PreEdit(files []string, verbose bool) error {
+	return nil
+}
+
+func (h *CommandHook) PostEdit(files []string, verbose bool) error {
+	return h.PostEditJSON(files, verbose)
+}
+
+func (h *CommandHook) PostEditJSON(files []string, verbose bool) error {
+	if len(h.Make) > 0 {
+		return runMakeTargets(h.Dir, h.Make, files, verbose)
+	}
+	args := strings.Fields(h.Command)
+	if len(args) == 0 {
+		return nil
+	}
+	vlog.Printf(verbose, "🔧 %s %s (in %s)\n", h.Command, strings.Join(files, " "), h.Dir)
+	cmd := proc.Command(args[0], append(args[1:], files...)...)
+	cmd.Dir = h.Dir
+	output, err := cmd.CombinedOutput()
+	vlog.Output(h.Command, output)
+	if err != nil && !proc.Killed(cmd) {
+		return fmt.Errorf("%s failed: %v\n%s", h.Command, err, ResolvePaths(strings.TrimSpace(string(output)), h.Dir))
+	}
+	return nil
+}
+
+// LookupHook returns the hook for a file type: one under hooks in the config
+// for dir, or else a built-in one
+func LookupHook(fileType, dir string) Hook {
+	cfg, err := config.Load(dir)
+	if err != nil {
+		return GetHook(fileType)
+	}
+	custom, ok := cfg.Hooks[fileType]
+	if !ok || (strings.TrimSpace(custom.Command) == "" && len(custom.Make) == 0) {
+		return GetHook(fileType)
+	}
+	hookDir := dir
+	if cfg.Path != "" {
+		hookDir = filepath.Dir(cfg.Path)
+	}
+	return &CommandHook{Name: fileType, Command: custom.Command, Make: custom.Make, Dir: hookDir}
+}
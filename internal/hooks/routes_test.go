@@ -0,0 +1,106 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGroupFiles(t *testing.T) {
+	root := t.TempDir()
+	runInDir(t, root, "git", "init", "-q")
+	writeTestFile(t, root, ".claude-hooks.yaml", `routes:
+  - glob: "*.bazel"
+    hook: starlark
+  - glob: Tiltfile
+    hook: starlark
+  - shebang: "python3?$"
+    hook: python
+  - glob: "scripts/*"
+    content: "^#!/usr/bin/env node"
+    hook: javascript
+  - glob: "*.go"
+    content: "^// Code generated .* DO NOT EDIT"
+    hook: generated
+  - shebang: "("
+    hook: broken
+`)
+	files := map[string]string{
+		"a/BUILD.bazel":   "go_library(name = \"a\")\n",
+		"deploy/Tiltfile": "k8s_yaml('app.yaml')\n",
+		"bin/tool":        "#!/usr/bin/env python3\nprint(1)\n",
+		"scripts/build":   "#!/usr/bin/env node\nconsole.log(1)\n",
+		"scripts/run":     "#!/bin/sh\necho 1\n",
+		"gen.go":          "// Code generated by stringer; DO NOT EDIT.\npackage a\n",
+		"main.go":         "package a\n",
+		"app.tsx":         "export {}\n",
+		"README":          "nothing to check\n",
+	}
+	var paths []string
+	for name, content := range files {
+		writeTestFile(t, root, name, content)
+		paths = append(paths, filepath.Join(root, name))
+	}
+
+	groups := GroupFiles(paths)
+	want := map[string][]string{
+		"starlark":   {"a/BUILD.bazel", "deploy/Tiltfile"},
+		"python":     {"bin/tool"},
+		"javascript": {"scripts/build"},
+		"generated":  {"gen.go"},
+		"go":         {"main.go"},
+		"typescript": {"app.tsx"},
+	}
+	if len(groups) != len(want) {
+		t.Errorf("Expected %d groups, got %v", len(want), groups)
+	}
+	for fileType, names := range want {
+		if len(groups[fileType]) != len(names) {
+			t.Errorf("Expected %s to get %v, got %v", fileType, names, groups[fileType])
+			continue
+		}
+		for _, name := range names {
+			found := false
+			for _, f := range groups[fileType] {
+				found = found || f == filepath.Join(root, name)
+			}
+			if !found {
+				t.Errorf("Expected %s to go to %s, got %v", name, fileType, groups)
+			}
+		}
+	}
+}
+
+func TestLookupHookCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, ".claude-hooks.yaml", "hooks:\n  starlark:\n    command: lint-starlark --check\n")
+	writeTestFile(t, dir, "BUILD.bazel", "x\n")
+
+	bin := t.TempDir()
+	writeTestFile(t, bin, "lint-starlark", "#!/bin/sh\n[ \"$1\" = --check ] || exit 2\necho \"BUILD.bazel:1:1: bad rule\"\nexit 1\n")
+	if err := os.Chmod(filepath.Join(bin, "lint-starlark"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if hook := LookupHook("go", dir); hook != GetHook("go") {
+		t.Errorf("Expected the built-in go hook, got %#v", hook)
+	}
+	if hook := LookupHook("unknown", dir); hook != nil {
+		t.Errorf("Expected no hook for an unconfigured type, got %#v", hook)
+	}
+
+	hook := LookupHook("starlark", dir)
+	if hook == nil {
+		t.Fatal("Expected the configured starlark hook")
+	}
+	err := hook.PostEditJSON([]string{filepath.Join(dir, "BUILD.bazel")}, false)
+	if err == nil {
+		t.Fatal("Expected the failing command to fail the hook")
+	}
+	// Paths in the output are resolved so diagnostics point at the file
+	if !strings.Contains(err.Error(), filepath.Join(dir, "BUILD.bazel")+":1:1: bad rule") {
+		t.Errorf("Expected the command's output with resolved paths, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// RustHook checks edited Rust files with cargo, crate by crate: formatting
+// with cargo fmt --check, lints with cargo clippy, whose warnings block, and
+// the crate's tests with cargo test. Each runs from the Cargo workspace root,
+// so crates share its target directory.
+type RustHook struct{}
+
+func (h *RustHook) PreEdit(files []string, verbose bool) error {
+	return nil
+}
+
+func (h *RustHook) PostEdit(files []string, verbose bool) error {
+	return h.PostEditJSON(files, verbose)
+}
+
+func (h *RustHook) PostEditJSON(files []string, verbose bool) error {
+	if _, err := exec.LookPath("cargo"); err != nil {
+		vlog.Printf(verbose, "⏭️  Skipping Rust checks: cargo not installed\n")
+		return nil
+	}
+
+	var failures []string
+	for _, c := range findCrates(files) {
+		manifest := filepath.Join(c.dir, "Cargo.toml")
+		for _, args := range [][]string{
+			{"fmt", "--check", "--manifest-path", manifest},
+			{"clippy", "--quiet", "--all-targets", "--message-format=short", "--manifest-path", manifest, "--", "-D", "warnings"},
+			{"test", "--quiet", "--manifest-path", manifest},
+		} {
+			if err := runCargo(c.workspace, args, verbose); err != nil {
+				failures = append(failures, err.Error())
+				break // Later steps would fail on the same problem
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "\n\n"))
+	}
+	return nil
+}
+
+// crate is a Cargo package with edited files
+type crate struct {
+	dir       string // Holding its Cargo.toml
+	workspace string // The workspace root, dir when it's in none
+}
+
+// cargoWorkspace matches the [workspace] table of a Cargo.toml
+var cargoWorkspace = regexp.MustCompile(`(?m)^\s*\[workspace\]`)
+
+// findCrates returns the crates the files are in, in the order first seen.
+// Files outside any crate are left out.
+func findCrates(files []string) []crate {
+	var crates []crate
+	for _, f := range files {
+		if abs, err := filepath.Abs(f); err == nil {
+			f = abs
+		}
+		dir := cargoPackageDir(filepath.Dir(f))
+		if dir == "" || slices.ContainsFunc(crates, func(c crate) bool { return c.dir == dir }) {
+			continue
+		}
+		crates = append(crates, crate{dir: dir, workspace: cargoWorkspaceRoot(dir)})
+	}
+	return crates
+}
+
+// cargoPackageDir returns the closest directory of dir or its parents with a
+// Cargo.toml that has a [package], or ""
+func cargoPackageDir(dir string) string {
+	for current := dir; ; current = filepath.Dir(current) {
+		if data, err := os.ReadFile(filepath.Join(current, "Cargo.toml")); err == nil && strings.Contains(string(data), "[package]") {
+			return current
+		}
+		if filepath.Dir(current) == current {
+			return ""
+		}
+	}
+}
+
+// cargoWorkspaceRoot returns the closest directory of dir or its parents with
+// a Cargo.toml declaring a [workspace], or dir
+func cargoWorkspaceRoot(dir string) string {
+	for current := dir; ; current = filepath.Dir(current) {
+		if data, err := os.ReadFile(filepath.Join(current, "Cargo.toml")); err == nil && cargoWorkspace.Match(data) {
+			return current
+		}
+		if filepath.Dir(current) == current {
+			return dir
+		}
+	}
+}
+
+// runCargo runs cargo with args in dir, returning its output with absolute
+// paths when it fails. A missing component, like clippy, is skipped.
+func runCargo(dir string, args []string, verbose bool) error {
+	vlog.Printf(verbose, "🔧 cargo %s (in %s)\n", strings.Join(args, " "), dir)
+	cmd := proc.Command("cargo", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	vlog.Output("cargo "+args[0], output)
+	if err != nil && strings.Contains(string(output), "no such command") {
+		vlog.Printf(verbose, "⏭️  Skipping cargo %s: not installed (rustup component add)\n", args[0])
+		return nil
+	}
+	if err != nil && !proc.Killed(cmd) {
+		return fmt.Errorf("cargo %s failed:\n%s", args[0], ResolvePaths(strings.TrimSpace(string(output)), dir))
+	}
+	return nil
+}
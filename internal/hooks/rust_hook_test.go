@@ -0,0 +1,68 @@
+package hooks
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindCrates(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "Cargo.toml", "[workspace]\nmembers = [\"crates/*\"]\n")
+	writeTestFile(t, root, "crates/core/Cargo.toml", "[package]\nname = \"core\"\n")
+	writeTestFile(t, root, "crates/cli/Cargo.toml", "[package]\nname = \"cli\"\n")
+	single := t.TempDir()
+	writeTestFile(t, single, "Cargo.toml", "[package]\nname = \"single\"\n")
+
+	crates := findCrates([]string{
+		filepath.Join(root, "crates/core/src/lib.rs"),
+		filepath.Join(root, "crates/core/src/parse/mod.rs"),
+		filepath.Join(root, "crates/cli/src/main.rs"),
+		filepath.Join(single, "src/lib.rs"),
+		filepath.Join(t.TempDir(), "loose.rs"),
+	})
+	want := []crate{
+		{dir: filepath.Join(root, "crates/core"), workspace: root},
+		{dir: filepath.Join(root, "crates/cli"), workspace: root},
+		{dir: single, workspace: single},
+	}
+	if len(crates) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, crates)
+	}
+	for i := range want {
+		if crates[i] != want[i] {
+			t.Errorf("Crate %d: expected %v, got %v", i, want[i], crates[i])
+		}
+	}
+}
+
+func TestRustHook(t *testing.T) {
+	if _, err := exec.LookPath("cargo"); err != nil {
+		t.Skip("cargo not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("CARGO_TARGET_DIR", filepath.Join(t.TempDir(), "target"))
+	writeTestFile(t, dir, "Cargo.toml", "[package]\nname = \"calc\"\nversion = \"0.1.0\"\nedition = \"2021\"\n")
+	lib := filepath.Join(dir, "src", "lib.rs")
+	writeTestFile(t, dir, "src/lib.rs", "pub fn add(a: i32, b: i32) -> i32 {\n    a + b\n}\n\n#[cfg(test)]\nmod tests {\n    #[test]\n    fn adds() {\n        assert_eq!(super::add(1, 2), 3);\n    }\n}\n")
+
+	if GetHook("rust") == nil || GroupFiles([]string{lib})["rust"] == nil {
+		t.Fatal("Expected .rs files to go to the rust hook")
+	}
+	if err := (&RustHook{}).PostEditJSON([]string{lib}, false); err != nil {
+		t.Fatalf("Expected a clean crate to pass, got %v", err)
+	}
+
+	writeTestFile(t, dir, "src/lib.rs", "pub fn add(a: i32, b: i32) -> i32 {\n    a - b\n}\n\n#[cfg(test)]\nmod tests {\n    #[test]\n    fn adds() {\n        assert_eq!(super::add(1, 2), 3);\n    }\n}\n")
+	err := (&RustHook{}).PostEditJSON([]string{lib}, false)
+	if err == nil || !strings.Contains(err.Error(), "cargo test failed") {
+		t.Fatalf("Expected the failing test to fail the hook, got %v", err)
+	}
+
+	writeTestFile(t, dir, "src/lib.rs", "pub fn add(a:i32,b:i32)->i32{a+b}\n")
+	err = (&RustHook{}).PostEditJSON([]string{lib}, false)
+	if err == nil || !strings.Contains(err.Error(), "cargo fmt failed") {
+		t.Fatalf("Expected unformatted code to fail the hook, got %v", err)
+	}
+}
@@ -0,0 +1,399 @@
+package hooks
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// osvManifests are the manifests osv-scanner audits; package.json and
+// package-lock.json go to npm audit when npm is installed
+var osvManifests = []string{"go.mod", "requirements.txt", "Pipfile.lock", "poetry.lock", "uv.lock", "Cargo.lock", "Gemfile.lock", "composer.lock", "pom.xml", "yarn.lock", "pnpm-lock.yaml", "package-lock.json"}
+
+// SecurityFinding is a gosec issue in an edited Go file, or a known
+// vulnerability in a dependency of an edited manifest
+type SecurityFinding struct {
+	File         string // Absolute
+	Line, Column int    // 1-based; Column is 0 for dependencies
+	Tool         string // gosec, npm-audit, or osv-scanner
+	ID           string // The gosec rule, or the advisory
+	Package      string // The vulnerable dependency, empty for gosec
+	Severity     string // One of config.SecuritySeverities
+	Message      string
+	Block        bool // Severity is at least security.block
+}
+
+func (f SecurityFinding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s %s, %s)", f.File, f.Line, f.Column, f.Message, f.Tool, f.ID, f.Severity)
+}
+
+// Diagnostic reports the finding; severity is "error" when it blocks
+func (f SecurityFinding) Diagnostic() Diagnostic {
+	severity := "warning"
+	if f.Block {
+		severity = "error"
+	}
+	return Diagnostic{File: f.File, Line: f.Line, Column: f.Column, Severity: severity, Message: f.Message, Source: f.Tool}
+}
+
+// FindSecurityIssues runs the security scanners the edited files' configs
+// turn on: gosec on the packages of edited Go files, reporting only issues in
+// those files, and a dependency audit of each edited manifest. Findings
+// reported more than once, like an advisory reached through two paths, are
+// kept once. Each scanner is skipped when it isn't installed.
+func FindSecurityIssues(files []string, verbose bool) ([]SecurityFinding, error) {
+	blockAt := make(map[string]string) // File -> its security.block
+	gosecDirs := make(map[string][]string)
+	var modules, manifests []string
+	for _, f := range files {
+		cfg, err := config.Load(filepath.Dir(f))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(f); err != nil {
+			continue // Deleted since
+		}
+		blockAt[f] = cfg.Security.Block
+		switch {
+		case cfg.Security.Gosec && filepath.Ext(f) == ".go" && !isTestFile(f):
+			root := moduleRootOf(filepath.Dir(f))
+			if root == "" {
+				continue
+			}
+			if !slices.Contains(modules, root) {
+				modules = append(modules, root)
+			}
+			if !slices.Contains(gosecDirs[root], filepath.Dir(f)) {
+				gosecDirs[root] = append(gosecDirs[root], filepath.Dir(f))
+			}
+		case cfg.Security.Audit && (filepath.Base(f) == "package.json" || slices.Contains(osvManifests, filepath.Base(f))):
+			manifests = append(manifests, f)
+		}
+	}
+
+	var found []SecurityFinding
+	var failures []string
+	if len(modules) > 0 {
+		if _, err := exec.LookPath("gosec"); err != nil {
+			vlog.Printf(verbose, "⏭️  Skipping gosec: not installed\n")
+		} else {
+			for _, root := range modules {
+				findings, err := runGosec(root, gosecDirs[root], verbose)
+				if err != nil {
+					failures = append(failures, err.Error())
+				}
+				for _, finding := range findings {
+					if _, edited := blockAt[finding.File]; edited {
+						found = append(found, finding)
+					}
+				}
+			}
+		}
+	}
+	for _, manifest := range manifests {
+		findings, err := auditManifest(manifest, verbose)
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+		found = append(found, findings...)
+	}
+
+	seen := make(map[string]bool)
+	var unique []SecurityFinding
+	for _, f := range found {
+		key := fmt.Sprintf("%s\x00%d\x00%s\x00%s", f.File, f.Line, f.ID, f.Package)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		f.Block = severityAtLeast(f.Severity, blockAt[f.File])
+		unique = append(unique, f)
+	}
+	vlog.Printf(verbose, "🛡️  %d security finding(s)\n", len(unique))
+	if len(failures) > 0 {
+		return unique, errors.New(strings.Join(failures, "\n"))
+	}
+	return unique, nil
+}
+
+// severityAtLeast reports whether severity is block or above; "none", or an
+// empty block, never blocks
+func severityAtLeast(severity, block string) bool {
+	lowest := slices.Index(config.SecuritySeverities, block)
+	return lowest >= 0 && slices.Index(config.SecuritySeverities, severity) >= lowest
+}
+
+// normalizeSeverity maps the severities scanners report, like MODERATE or a
+// CVSS score of 7.5, to config.SecuritySeverities
+func normalizeSeverity(severity string) string {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical":
+		return "critical"
+	case "high":
+		return "high"
+	case "medium", "moderate":
+		return "medium"
+	case "low", "info":
+		return "low"
+	}
+	if score, err := strconv.ParseFloat(severity, 64); err == nil {
+		switch {
+		case score >= 9:
+			return "critical"
+		case score >= 7:
+			return "high"
+		case score >= 4:
+			return "medium"
+		}
+		return "low"
+	}
+	return "medium" // Unrated
+}
+
+// gosecOutput is the part of gosec -fmt=json output the hook reads
+type gosecOutput struct {
+	Issues []struct {
+		Severity string `json:"severity"`
+		RuleID   string `json:"rule_id"`
+		Details  string `json:"details"`
+		File     string `json:"file"`
+		Line     string `json:"line"` // "12", or "12-14" for a range
+		Column   string `json:"column"`
+	} `json:"Issues"`
+}
+
+// runGosec scans the package directories of the module at root
+func runGosec(root string, dirs []string, verbose bool) ([]SecurityFinding, error) {
+	args := []string{"-fmt=json", "-quiet", "-no-fail"}
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return nil, fmt.Errorf("resolving package %s: %w", dir, err)
+		}
+		args = append(args, "./"+filepath.ToSlash(rel))
+	}
+	vlog.Printf(verbose, "🔧 gosec %s (in %s)\n", strings.Join(args, " "), root)
+	cmd := proc.Command("gosec", args...)
+	cmd.Dir = root
+	output, err := cmd.Output()
+	vlog.Output("gosec", output)
+	if err != nil {
+		return nil, fmt.Errorf("running gosec: %w", err)
+	}
+
+	var out gosecOutput
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, fmt.Errorf("parsing gosec output: %w", err)
+	}
+	var findings []SecurityFinding
+	for _, issue := range out.Issues {
+		first, _, _ := strings.Cut(issue.Line, "-")
+		line, _ := strconv.Atoi(first)
+		column, _ := strconv.Atoi(issue.Column)
+		findings = append(findings, SecurityFinding{
+			File:     absPath(root, issue.File),
+			Line:     line,
+			Column:   column,
+			Tool:     "gosec",
+			ID:       issue.RuleID,
+			Severity: normalizeSeverity(issue.Severity),
+			Message:  issue.Details,
+		})
+	}
+	return findings, nil
+}
+
+// auditManifest audits the dependencies of an edited manifest with npm audit
+// or osv-scanner, whichever applies and is installed
+func auditManifest(manifest string, verbose bool) ([]SecurityFinding, error) {
+	name := filepath.Base(manifest)
+	if name == "package.json" || name == "package-lock.json" {
+		if _, err := exec.LookPath("npm"); err == nil {
+			if _, err := os.Stat(filepath.Join(filepath.Dir(manifest), "package-lock.json")); err != nil {
+				vlog.Printf(verbose, "⏭️  Skipping npm audit of %s: no package-lock.json\n", manifest)
+				return nil, nil
+			}
+			return runNpmAudit(manifest, verbose)
+		}
+		if name == "package.json" {
+			manifest = filepath.Join(filepath.Dir(manifest), "package-lock.json")
+			if _, err := os.Stat(manifest); err != nil {
+				return nil, nil
+			}
+		}
+	}
+	if _, err := exec.LookPath("osv-scanner"); err != nil {
+		vlog.Printf(verbose, "⏭️  Skipping dependency audit of %s: osv-scanner not installed\n", manifest)
+		return nil, nil
+	}
+	return runOSVScanner(manifest, verbose)
+}
+
+// npmAuditOutput is the part of npm audit --json output the hook reads
+type npmAuditOutput struct {
+	Vulnerabilities map[string]struct {
+		Via []json.RawMessage `json:"via"` // Advisories, or names of vulnerable dependencies
+	} `json:"vulnerabilities"`
+	Error *struct {
+		Summary string `json:"summary"`
+	} `json:"error"`
+}
+
+// npmAdvisory is an advisory in npm audit's via lists
+type npmAdvisory struct {
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	Severity string `json:"severity"`
+	Range    string `json:"range"`
+}
+
+// runNpmAudit audits the production dependencies of the package holding
+// manifest. Each advisory is reported once, on the package it's about, not
+// on every package depending on it.
+func runNpmAudit(manifest string, verbose bool) ([]SecurityFinding, error) {
+	dir := filepath.Dir(manifest)
+	vlog.Printf(verbose, "🔧 npm audit --omit=dev --json (in %s)\n", dir)
+	cmd := proc.Command("npm", "audit", "--omit=dev", "--json")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	vlog.Output("npm audit", output)
+	// npm audit exits 1 when it finds vulnerabilities, so the output decides
+	var out npmAuditOutput
+	if jsonErr := json.Unmarshal(output, &out); jsonErr != nil {
+		if err != nil {
+			return nil, fmt.Errorf("running npm audit in %s: %w", dir, err)
+		}
+		return nil, fmt.Errorf("parsing npm audit output: %w", jsonErr)
+	}
+	if out.Error != nil {
+		return nil, fmt.Errorf("npm audit in %s failed: %s", dir, out.Error.Summary)
+	}
+
+	content, _ := os.ReadFile(manifest)
+	var findings []SecurityFinding
+	for _, vuln := range out.Vulnerabilities {
+		for _, raw := range vuln.Via {
+			var advisory npmAdvisory
+			if json.Unmarshal(raw, &advisory) != nil || advisory.Name == "" {
+				continue // The name of a dependency with its own entry
+			}
+			id := advisory.URL[strings.LastIndex(advisory.URL, "/")+1:]
+			findings = append(findings, SecurityFinding{
+				File:     manifest,
+				Line:     dependencyLine(string(content), advisory.Name),
+				Tool:     "npm-audit",
+				ID:       id,
+				Package:  advisory.Name,
+				Severity: normalizeSeverity(advisory.Severity),
+				Message:  fmt.Sprintf("%s %s: %s (%s)", advisory.Name, advisory.Range, advisory.Title, advisory.URL),
+			})
+		}
+	}
+	slices.SortFunc(findings, func(a, b SecurityFinding) int {
+		return strings.Compare(a.Package+a.ID, b.Package+b.ID)
+	})
+	return findings, nil
+}
+
+// osvOutput is the part of osv-scanner --format json output the hook reads
+type osvOutput struct {
+	Results []struct {
+		Packages []struct {
+			Package struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"package"`
+			Vulnerabilities []struct {
+				ID               string `json:"id"`
+				Summary          string `json:"summary"`
+				DatabaseSpecific struct {
+					Severity string `json:"severity"`
+				} `json:"database_specific"`
+			} `json:"vulnerabilities"`
+			// Groups are the vulnerabilities that are aliases of each other
+			Groups []struct {
+				IDs         []string `json:"ids"`
+				MaxSeverity string   `json:"max_severity"` // A CVSS score
+			} `json:"groups"`
+		} `json:"packages"`
+	} `json:"results"`
+}
+
+// osvNoPackages is osv-scanner's exit status when the file has no packages
+const osvNoPackages = 128
+
+// runOSVScanner audits a lockfile or manifest with osv-scanner. Advisories
+// that are aliases of each other are reported once.
+func runOSVScanner(manifest string, verbose bool) ([]SecurityFinding, error) {
+	vlog.Printf(verbose, "🔧 osv-scanner --format json --lockfile %s\n", manifest)
+	cmd := proc.Command("osv-scanner", "--format", "json", "--lockfile", manifest)
+	cmd.Dir = filepath.Dir(manifest)
+	output, err := cmd.Output()
+	vlog.Output("osv-scanner", output)
+	// Exit status 1 means vulnerabilities were found
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == osvNoPackages:
+		return nil, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() != 1:
+		return nil, fmt.Errorf("running osv-scanner on %s: %w\n%s", manifest, err, strings.TrimSpace(string(exitErr.Stderr)))
+	case err != nil && exitErr == nil:
+		return nil, fmt.Errorf("running osv-scanner on %s: %w", manifest, err)
+	}
+
+	var out osvOutput
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, fmt.Errorf("parsing osv-scanner output: %w", err)
+	}
+	content, _ := os.ReadFile(manifest)
+	var findings []SecurityFinding
+	for _, result := range out.Results {
+		for _, pkg := range result.Packages {
+			for _, group := range pkg.Groups {
+				if len(group.IDs) == 0 {
+					continue
+				}
+				summary, severity := "", group.MaxSeverity
+				for _, v := range pkg.Vulnerabilities {
+					if slices.Contains(group.IDs, v.ID) {
+						summary = cmp.Or(summary, v.Summary)
+						severity = cmp.Or(severity, v.DatabaseSpecific.Severity)
+					}
+				}
+				findings = append(findings, SecurityFinding{
+					File:     manifest,
+					Line:     dependencyLine(string(content), pkg.Package.Name),
+					Tool:     "osv-scanner",
+					ID:       group.IDs[0],
+					Package:  pkg.Package.Name,
+					Severity: normalizeSeverity(severity),
+					Message:  fmt.Sprintf("%s %s: %s (%s)", pkg.Package.Name, pkg.Package.Version, cmp.Or(summary, "known vulnerability"), strings.Join(group.IDs, ", ")),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// dependencyLine returns the first line of a manifest naming the dependency,
+// or 1 when none does, like a transitive dependency missing from package.json
+func dependencyLine(content, name string) int {
+	for i, line := range strings.Split(content, "\n") {
+		if strings.Contains(line, `"`+name+`"`) || strings.Contains(line, name+" ") || strings.Contains(line, name+"=") {
+			return i + 1
+		}
+	}
+	return 1
+}
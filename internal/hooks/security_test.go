@@ -0,0 +1,99 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeStub writes an executable script named name to dir
+func writeStub(t *testing.T, dir, name, script string) {
+	t.Helper()
+	writeTestFile(t, dir, name, "#!/bin/sh\n"+script)
+	if err := os.Chmod(filepath.Join(dir, name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFindSecurityIssuesGosec runs a stub gosec reporting issues in an edited
+// and an unedited file, and expects only the edited file's, gated by severity
+func TestFindSecurityIssuesGosec(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, ".claude-hooks.yaml", "security:\n  gosec: true\n")
+	writeTestFile(t, repo, "go.mod", "module example.com/a\n\ngo 1.25\n")
+	writeTestFile(t, repo, "a.go", "package a\n")
+	writeTestFile(t, repo, "b.go", "package a\n")
+
+	bin := t.TempDir()
+	writeStub(t, bin, "gosec", "cat <<EOF\n"+`{"Issues":[`+
+		`{"severity":"HIGH","rule_id":"G101","details":"Potential hardcoded credentials","file":"`+filepath.Join(repo, "a.go")+`","line":"3","column":"2"},`+
+		`{"severity":"MEDIUM","rule_id":"G304","details":"File inclusion via variable","file":"`+filepath.Join(repo, "a.go")+`","line":"5-7","column":"9"},`+
+		`{"severity":"HIGH","rule_id":"G101","details":"Potential hardcoded credentials","file":"`+filepath.Join(repo, "b.go")+`","line":"3","column":"2"}]}`+"\nEOF\n")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	findings, err := FindSecurityIssues([]string{filepath.Join(repo, "a.go")}, false)
+	if err != nil {
+		t.Fatalf("FindSecurityIssues failed: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected the 2 issues of a.go, got %v", findings)
+	}
+	if f := findings[0]; !f.Block || f.ID != "G101" || f.Line != 3 || f.Severity != "high" {
+		t.Errorf("Expected the high G101 to block at line 3, got %+v", f)
+	}
+	if f := findings[1]; f.Block || f.Line != 5 || f.Severity != "medium" {
+		t.Errorf("Expected the medium G304 on line 5 to warn, got %+v", f)
+	}
+}
+
+// TestFindSecurityIssuesAudit runs a stub npm audit listing one advisory
+// through two packages and expects it reported once, on the package.json line
+// of the dependency
+func TestFindSecurityIssuesAudit(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, ".claude-hooks.yaml", "security:\n  audit: true\n  block: critical\n")
+	writeTestFile(t, repo, "package.json", "{\n  \"dependencies\": {\n    \"lodash\": \"4.17.20\",\n    \"app-utils\": \"1.0.0\"\n  }\n}\n")
+	writeTestFile(t, repo, "package-lock.json", "{}\n")
+
+	advisory := `{"source":1,"name":"lodash","title":"Prototype Pollution","url":"https://github.com/advisories/GHSA-p6mc","severity":"high","range":"<4.17.21"}`
+	bin := t.TempDir()
+	writeStub(t, bin, "npm", "cat <<'EOF'\n"+`{"vulnerabilities":{`+
+		`"lodash":{"via":[`+advisory+`]},`+
+		`"app-utils":{"via":["lodash",`+advisory+`]}}}`+"\nEOF\nexit 1\n")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	findings, err := FindSecurityIssues([]string{filepath.Join(repo, "package.json")}, false)
+	if err != nil {
+		t.Fatalf("FindSecurityIssues failed: %v", err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("Expected the advisory once, got %v", findings)
+	}
+	f := findings[0]
+	if f.ID != "GHSA-p6mc" || f.Package != "lodash" || f.Line != 3 || f.Severity != "high" {
+		t.Errorf("Expected GHSA-p6mc for lodash on line 3, got %+v", f)
+	}
+	if f.Block {
+		t.Error("Expected a high finding to only warn under security.block: critical")
+	}
+}
+
+func TestFindSecurityIssuesDisabled(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "a.go", "package a\n")
+	writeTestFile(t, repo, "package.json", "{}\n")
+	t.Setenv("PATH", t.TempDir()) // Nothing to run: the scanners must not get that far
+
+	findings, err := FindSecurityIssues([]string{filepath.Join(repo, "a.go"), filepath.Join(repo, "package.json")}, false)
+	if err != nil || len(findings) != 0 {
+		t.Errorf("Expected no findings with the scanners off, got %v, %v", findings, err)
+	}
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	for in, want := range map[string]string{"MODERATE": "medium", "HIGH": "high", "info": "low", "9.8": "critical", "7.5": "high", "5.3": "medium", "": "medium"} {
+		if got := normalizeSeverity(in); got != want {
+			t.Errorf("normalizeSeverity(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package hooks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// SemgrepFinding is a match of a Semgrep rule in an edited file
+type SemgrepFinding struct {
+	File         string // Absolute
+	Line, Column int    // 1-based
+	Rule         string // The check id, e.g. go.lang.security.audit.sqli.string-formatted-query
+	Severity     string // As Semgrep reports it: ERROR, WARNING, or INFO
+	Message      string
+	Block        bool // semgrep.severities maps Severity to block
+}
+
+func (f SemgrepFinding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (semgrep %s)", f.File, f.Line, f.Column, f.Message, f.Rule)
+}
+
+// Diagnostic reports the rule match where Semgrep found it; severity is "error"
+// when semgrep.severities maps the rule's severity to block
+func (f SemgrepFinding) Diagnostic() Diagnostic {
+	severity := "warning"
+	if f.Block {
+		severity = "error"
+	}
+	return Diagnostic{File: f.File, Line: f.Line, Column: f.Column, Severity: severity, Message: f.Message, Source: "semgrep"}
+}
+
+// semgrepScan is the files scanned together: those with the same rulesets
+type semgrepScan struct {
+	root     string // Project root, where semgrep runs
+	rulesets []string
+	files    []string
+}
+
+// FindSemgrepFindings scans the edited files with the semgrep.rulesets of
+// their config and returns the findings that semgrep.severities doesn't
+// ignore. Findings are cached by file content and rulesets, so only files
+// that changed since their last scan are scanned. It is a no-op unless
+// semgrep.enabled is set, and skipped without semgrep.
+func FindSemgrepFindings(files []string, verbose bool) ([]SemgrepFinding, error) {
+	var scans []*semgrepScan
+	severities := make(map[string]map[string]string) // File -> its config's severities
+	for _, f := range files {
+		cfg, err := config.Load(filepath.Dir(f))
+		if err != nil {
+			return nil, err
+		}
+		if !cfg.Semgrep.Enabled || len(cfg.Semgrep.Rulesets) == 0 {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			continue // Deleted since
+		}
+		rulesets := resolveRulesets(cfg)
+		root := state.ProjectRoot(filepath.Dir(f))
+		i := slices.IndexFunc(scans, func(s *semgrepScan) bool { return s.root == root && slices.Equal(s.rulesets, rulesets) })
+		if i < 0 {
+			scans = append(scans, &semgrepScan{root: root, rulesets: rulesets})
+			i = len(scans) - 1
+		}
+		scans[i].files = append(scans[i].files, f)
+		severities[f] = cfg.Semgrep.Severities
+	}
+	if len(scans) == 0 {
+		return nil, nil
+	}
+	if _, err := exec.LookPath("semgrep"); err != nil {
+		vlog.Printf(verbose, "⏭️  Skipping Semgrep: semgrep not installed\n")
+		return nil, nil
+	}
+
+	var found []SemgrepFinding
+	for _, scan := range scans {
+		results, err := scanCached(scan, verbose)
+		if err != nil {
+			return found, err
+		}
+		for _, f := range scan.files {
+			for _, r := range results[f] {
+				r.File = f
+				switch action := severities[f][strings.ToUpper(r.Severity)]; action {
+				case config.SemgrepIgnore:
+					continue
+				case config.SemgrepBlock:
+					r.Block = true
+				}
+				found = append(found, r)
+			}
+		}
+	}
+	vlog.Printf(verbose, "🔎 %d Semgrep finding(s)\n", len(found))
+	return found, nil
+}
+
+// resolveRulesets makes the rule files and directories among the config's
+// rulesets absolute against its directory; registry rulesets and URLs are
+// passed as they are
+func resolveRulesets(cfg *config.Config) []string {
+	var rulesets []string
+	for _, r := range cfg.Semgrep.Rulesets {
+		if !filepath.IsAbs(r) && cfg.Path != "" && !strings.Contains(r, "://") && !strings.HasPrefix(r, "p/") && !strings.HasPrefix(r, "r/") && r != "auto" {
+			r = filepath.Join(filepath.Dir(cfg.Path), r)
+		}
+		rulesets = append(rulesets, r)
+	}
+	return rulesets
+}
+
+// scanCached returns the findings of each file of the scan, running semgrep
+// only on the files whose content and rulesets have no cached findings
+func scanCached(scan *semgrepScan, verbose bool) (map[string][]SemgrepFinding, error) {
+	results := make(map[string][]SemgrepFinding)
+	caches := make(map[string]string) // File -> cache path
+	rules := rulesetsHash(scan.rulesets)
+	var uncached []string
+	for _, f := range scan.files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f, err)
+		}
+		sum := sha256.Sum256([]byte(rules + " " + filepath.Ext(f) + "\x00" + string(content)))
+		cache, err := state.ProjectPath(scan.root, state.Cache, "semgrep-"+hex.EncodeToString(sum[:8])+".json")
+		if err != nil {
+			return nil, err
+		}
+		var cached []SemgrepFinding
+		if data, err := os.ReadFile(cache); err == nil && json.Unmarshal(data, &cached) == nil {
+			vlog.Printf(verbose, "♻️  Semgrep results of %s are cached\n", f)
+			recordCacheLookup(true)
+			results[f] = cached
+			continue
+		}
+		recordCacheLookup(false)
+		caches[f] = cache
+		uncached = append(uncached, f)
+	}
+	if len(uncached) == 0 {
+		return results, nil
+	}
+
+	scanned, err := runSemgrep(scan.root, scan.rulesets, uncached, verbose)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range uncached {
+		results[f] = scanned[f]
+		data, err := json.Marshal(scanned[f])
+		if err == nil {
+			err = os.WriteFile(caches[f], data, 0o644)
+		}
+		if err != nil {
+			vlog.Printf(verbose, "⚠️  Could not cache Semgrep results of %s: %v\n", f, err)
+		}
+	}
+	return results, nil
+}
+
+// rulesetsHash hashes the rulesets, with the content of the local rule files,
+// so editing a rule scans again
+func rulesetsHash(rulesets []string) string {
+	h := sha256.New()
+	for _, r := range rulesets {
+		fmt.Fprintf(h, "%s\n", r)
+		_ = filepath.WalkDir(r, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return nil
+			}
+			if data, err := os.ReadFile(path); err == nil {
+				fmt.Fprintf(h, "%s %d\n", path, len(data))
+				h.Write(data)
+			}
+			return nil
+		})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// semgrepOutput is the part of semgrep --json output the hook reads
+type semgrepOutput struct {
+	Results []struct {
+		CheckID string `json:"check_id"`
+		Path    string `json:"path"`
+		Start   struct {
+			Line int `json:"line"`
+			Col  int `json:"col"`
+		} `json:"start"`
+		Extra struct {
+			Message  string `json:"message"`
+			Severity string `json:"severity"`
+		} `json:"extra"`
+	} `json:"results"`
+}
+
+// runSemgrep scans files with the rulesets from dir and returns the findings
+// by file
+func runSemgrep(dir string, rulesets, files []string, verbose bool) (map[string][]SemgrepFinding, error) {
+	args := []string{"scan", "--json", "--quiet", "--metrics=off"}
+	for _, r := range rulesets {
+		args = append(args, "--config", r)
+	}
+	args = append(args, files...)
+	vlog.Printf(verbose, "🔧 semgrep %s\n", strings.Join(args, " "))
+	cmd := proc.Command("semgrep", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	vlog.Output("semgrep", output)
+	// Findings exit 0 too; anything else means the scan didn't run, like a
+	// ruleset that can't be fetched
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("running semgrep: %w\n%s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("running semgrep: %w", err)
+	}
+
+	var out semgrepOutput
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, fmt.Errorf("parsing semgrep output: %w", err)
+	}
+	findings := make(map[string][]SemgrepFinding)
+	for _, r := range out.Results {
+		path := r.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		findings[path] = append(findings[path], SemgrepFinding{
+			Line:     r.Start.Line,
+			Column:   r.Start.Col,
+			Rule:     r.CheckID,
+			Severity: r.Extra.Severity,
+			Message:  strings.TrimSpace(r.Extra.Message),
+		})
+	}
+	return findings, nil
+}
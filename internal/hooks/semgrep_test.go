@@ -0,0 +1,81 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFindSemgrepFindings runs a stub semgrep reporting one finding of each
+// severity and expects them mapped by semgrep.severities, and a second scan of
+// the same content to come from the cache
+func TestFindSemgrepFindings(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	repo := t.TempDir()
+	writeTestFile(t, repo, ".claude-hooks.yaml", "semgrep:\n  enabled: true\n  rulesets: [p/golang, rules]\n  severities:\n    INFO: ignore\n")
+	writeTestFile(t, repo, "rules/org.yaml", "rules: []\n")
+	writeTestFile(t, repo, "a.go", "package a\n")
+	runInDir(t, repo, "git", "init", "-q")
+
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	writeTestFile(t, bin, "semgrep", "#!/bin/sh\necho \"$@\" >> "+calls+"\ncat <<'EOF'\n"+
+		`{"results":[`+
+		`{"check_id":"sqli","path":"a.go","start":{"line":3,"col":2},"extra":{"message":"SQL built from a string","severity":"ERROR"}},`+
+		`{"check_id":"weak-hash","path":"a.go","start":{"line":5,"col":9},"extra":{"message":"md5 is weak","severity":"WARNING"}},`+
+		`{"check_id":"style","path":"a.go","start":{"line":7,"col":1},"extra":{"message":"nit","severity":"INFO"}}]}`+
+		"\nEOF\n")
+	if err := os.Chmod(filepath.Join(bin, "semgrep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	file := filepath.Join(repo, "a.go")
+	for range 2 {
+		findings, err := FindSemgrepFindings([]string{file}, false)
+		if err != nil {
+			t.Fatalf("FindSemgrepFindings failed: %v", err)
+		}
+		if len(findings) != 2 {
+			t.Fatalf("Expected the ERROR and WARNING findings, got %v", findings)
+		}
+		if f := findings[0]; !f.Block || f.File != file || f.Line != 3 || f.Rule != "sqli" {
+			t.Errorf("Expected the ERROR finding to block at a.go:3, got %+v", f)
+		}
+		if findings[1].Block {
+			t.Errorf("Expected the WARNING finding to warn, got %+v", findings[1])
+		}
+	}
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 1 {
+		t.Errorf("Expected semgrep to run once, the second scan cached, ran %d times", n)
+	}
+	if want := "--config p/golang --config " + filepath.Join(repo, "rules"); !strings.Contains(string(data), want) {
+		t.Errorf("Expected %q in the arguments, got %s", want, data)
+	}
+
+	// Changing the file scans it again
+	writeTestFile(t, repo, "a.go", "package a\n\nvar x = 1\n")
+	if _, err := FindSemgrepFindings([]string{file}, false); err != nil {
+		t.Fatalf("FindSemgrepFindings failed: %v", err)
+	}
+	if data, _ := os.ReadFile(calls); strings.Count(string(data), "\n") != 2 {
+		t.Errorf("Expected an edited file to be scanned again, got calls:\n%s", data)
+	}
+}
+
+func TestFindSemgrepFindingsDisabled(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, repo, "a.go", "package a\n")
+	t.Setenv("PATH", t.TempDir()) // Nothing to run: the scan must not get that far
+
+	findings, err := FindSemgrepFindings([]string{filepath.Join(repo, "a.go")}, false)
+	if err != nil || len(findings) != 0 {
+		t.Errorf("Expected no findings without semgrep.enabled, got %v, %v", findings, err)
+	}
+}
@@ -0,0 +1,139 @@
+package hooks
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vcs"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// coverages caches each repository root's vcs.Coverage for the process
+var coverages sync.Map
+
+// checkoutCoverage returns how much of the repository containing dir is on
+// disk. Outside a repository everything is.
+func checkoutCoverage(dir string) vcs.Coverage {
+	root := state.ProjectRoot(dir)
+	if c, ok := coverages.Load(root); ok {
+		return c.(vcs.Coverage)
+	}
+	var c vcs.Coverage
+	if repo := vcs.Detect(root); repo != nil {
+		c = repo.Coverage()
+	}
+	coverages.Store(root, c)
+	return c
+}
+
+// missingImports returns the imports of the package in dir that belong to
+// its own module but aren't on disk, left out by a sparse checkout. go can't
+// build such a package: it looks the imports up as modules instead.
+func missingImports(dir string) []string {
+	if !checkoutCoverage(dir).Sparse {
+		return nil
+	}
+	moduleRoot, err := findModuleRoot(dir)
+	if err != nil {
+		return nil
+	}
+	modulePath, err := importPathForDir(moduleRoot, moduleRoot)
+	if err != nil {
+		return nil // Not in a module
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	var missing []string
+	fset := token.NewFileSet()
+	for _, f := range files {
+		parsed, err := parser.ParseFile(fset, f, nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, spec := range parsed.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			rel, ok := strings.CutPrefix(path, modulePath+"/")
+			if !ok || slices.Contains(missing, path) {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(moduleRoot, filepath.FromSlash(rel))); err != nil {
+				missing = append(missing, path)
+			}
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// buildableDirs drops the package dirs that import packages a sparse checkout
+// left out, which go would fail to build for reasons that aren't the edit's
+func buildableDirs(dirs []string, verbose bool) []string {
+	return slices.DeleteFunc(slices.Clone(dirs), func(dir string) bool {
+		missing := missingImports(dir)
+		if len(missing) > 0 {
+			vlog.Printf(verbose, "⏭️  Skipping %s: imports %s, outside the sparse checkout\n", dir, strings.Join(missing, ", "))
+		}
+		return len(missing) > 0
+	})
+}
+
+// CoverageNotes tells Claude what the checks of files couldn't cover because
+// the checkout is sparse or the clone partial: Go packages importing code the
+// checkout leaves out aren't built or tested, only materialized packages are
+// found as dependents, and baselines that need another revision checked out
+// aren't computed.
+func CoverageNotes(files []string) []string {
+	var notes []string
+	var roots []string
+	for _, f := range files {
+		root := state.ProjectRoot(filepath.Dir(f))
+		if slices.Contains(roots, root) {
+			continue
+		}
+		roots = append(roots, root)
+		c := checkoutCoverage(root)
+		if !c.Reduced() {
+			continue
+		}
+		cfg, err := config.Load(root)
+		if err != nil {
+			cfg = config.Default()
+		}
+
+		kind := "a partial clone"
+		if c.Sparse {
+			kind = "a sparse checkout"
+		}
+		var gaps []string
+		goFiles := slices.DeleteFunc(slices.Clone(files), func(f string) bool { return filepath.Ext(f) != ".go" })
+		if c.Sparse && len(goFiles) > 0 {
+			for _, dir := range packageDirs(goFiles) {
+				if state.ProjectRoot(dir) != root {
+					continue
+				}
+				if missing := missingImports(dir); len(missing) > 0 {
+					rel, _ := filepath.Rel(root, dir)
+					gaps = append(gaps, fmt.Sprintf("%s wasn't built or tested (it imports %s, which the checkout leaves out)", filepath.ToSlash(rel), strings.Join(missing, ", ")))
+				}
+			}
+			if cfg.Go.Dependents.Enabled {
+				gaps = append(gaps, "only packages in the checkout were checked as dependents")
+			}
+		}
+		if cfg.Go.API.Enabled || cfg.Bundle.Enabled {
+			gaps = append(gaps, "API compatibility and bundle size weren't compared with the last commit, since checking it out would bring in the whole tree")
+		}
+		if len(gaps) > 0 {
+			notes = append(notes, fmt.Sprintf("%s is %s, so checks covered less: %s.", root, kind, strings.Join(gaps, "; ")))
+		}
+	}
+	return notes
+}
@@ -0,0 +1,52 @@
+package hooks
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSparseCheckout(t *testing.T) {
+	repo := t.TempDir()
+	runInDir(t, repo, "git", "init", "-q")
+	writeTestFile(t, repo, "go.mod", "module example.com/m\n\ngo 1.22\n")
+	writeTestFile(t, repo, "a/a.go", "package a\n\nimport _ \"example.com/m/b\"\n")
+	writeTestFile(t, repo, "b/b.go", "package b\n")
+	writeTestFile(t, repo, "c/c.go", "package c\n\nimport _ \"fmt\"\n")
+	writeTestFile(t, repo, ".claude-hooks.yaml", "go:\n  api:\n    enabled: true\n")
+	runInDir(t, repo, "git", "add", ".")
+	runInDir(t, repo, "git", "commit", "-qm", "init")
+
+	a, c := filepath.Join(repo, "a"), filepath.Join(repo, "c")
+	if missing := missingImports(a); missing != nil {
+		t.Fatalf("A full checkout has nothing missing, got %v", missing)
+	}
+	if notes := CoverageNotes([]string{filepath.Join(a, "a.go")}); notes != nil {
+		t.Fatalf("A full checkout needs no notes, got %v", notes)
+	}
+
+	runInDir(t, repo, "git", "sparse-checkout", "set", "a", "c")
+	coverages.Clear()
+	t.Cleanup(coverages.Clear)
+
+	if missing := missingImports(a); !slices.Equal(missing, []string{"example.com/m/b"}) {
+		t.Errorf("missingImports(a) = %v, want example.com/m/b", missing)
+	}
+	if dirs := buildableDirs([]string{a, c}, false); !slices.Equal(dirs, []string{c}) {
+		t.Errorf("buildableDirs = %v, want only c", dirs)
+	}
+
+	notes := CoverageNotes([]string{filepath.Join(a, "a.go"), filepath.Join(c, "c.go")})
+	if len(notes) != 1 {
+		t.Fatalf("Expected one note, got %v", notes)
+	}
+	for _, want := range []string{"a sparse checkout", "a wasn't built or tested (it imports example.com/m/b", "API compatibility"} {
+		if !strings.Contains(notes[0], want) {
+			t.Errorf("Note %q doesn't mention %q", notes[0], want)
+		}
+	}
+	if strings.Contains(notes[0], "c wasn't") {
+		t.Errorf("Note %q mentions c, which builds", notes[0])
+	}
+}
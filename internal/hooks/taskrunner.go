@@ -0,0 +1,161 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// taskRunner is a JS monorepo tool that knows which projects an edit affects
+type taskRunner struct {
+	name   string // The hook's file type and the command
+	marker string // File at the workspace root
+}
+
+var taskRunners = []taskRunner{
+	{name: "nx", marker: "nx.json"},
+	{name: "turbo", marker: "turbo.json"},
+}
+
+// findTaskRunner returns the task runner of the workspace containing file and
+// the workspace's root, searching up to its project root
+func findTaskRunner(file string) (taskRunner, string, bool) {
+	dir := filepath.Dir(file)
+	root := state.ProjectRoot(dir)
+	for _, runner := range taskRunners {
+		if ws := findUp(dir, root, runner.marker); ws != "" {
+			return runner, ws, true
+		}
+	}
+	return taskRunner{}, "", false
+}
+
+// taskRunnerRoute returns the task runner's hook for a JS or TS file when
+// monorepo.enabled is set and file is in an Nx or Turborepo workspace, or ""
+func taskRunnerRoute(cfg config.MonorepoConfig, file, fileType string) string {
+	if !cfg.Enabled || (fileType != "typescript" && fileType != "javascript") {
+		return ""
+	}
+	runner, _, ok := findTaskRunner(file)
+	if !ok {
+		return ""
+	}
+	return runner.name
+}
+
+// TaskRunnerHook checks JS and TS edits in an Nx or Turborepo workspace by
+// running monorepo.targets for the projects the edited files affect, so the
+// workspace's own configuration and cache are used instead of eslint and tsc
+type TaskRunnerHook struct{}
+
+func (h *TaskRunnerHook) PreEdit(files []string, verbose bool) error {
+	return nil
+}
+
+func (h *TaskRunnerHook) PostEdit(files []string, verbose bool) error {
+	return h.PostEditJSON(files, verbose)
+}
+
+func (h *TaskRunnerHook) PostEditJSON(files []string, verbose bool) error {
+	cfg, err := config.Load(filepath.Dir(files[0]))
+	if err != nil {
+		return err
+	}
+	runner, root, ok := findTaskRunner(files[0])
+	if !ok || len(cfg.Monorepo.Targets) == 0 {
+		return nil
+	}
+
+	command := runner.name
+	if dir := findUp(root, root, filepath.Join("node_modules", ".bin", runner.name)); dir != "" {
+		command = filepath.Join(dir, "node_modules", ".bin", runner.name)
+	} else if _, err := exec.LookPath(command); err != nil {
+		vlog.Printf(verbose, "⏭️  Skipping %s: not installed in %s\n", runner.name, root)
+		return nil
+	}
+
+	var args []string
+	if runner.name == "nx" {
+		args = nxAffectedArgs(root, files, cfg.Monorepo.Targets)
+	} else {
+		args = turboRunArgs(root, files, cfg.Monorepo.Targets)
+	}
+	if len(args) == 0 {
+		vlog.Printf(verbose, "⏭️  No %s projects own the edited files\n", runner.name)
+		return nil
+	}
+
+	vlog.Printf(verbose, "🔧 %s %s (in %s)\n", runner.name, strings.Join(args, " "), root)
+	cmd := proc.Command(command, args...)
+	cmd.Dir = root
+	output, err := cmd.CombinedOutput()
+	vlog.Output(runner.name+" "+args[0], output)
+	if err != nil && !proc.Killed(cmd) {
+		return fmt.Errorf("%s %s failed: %v\n%s", runner.name, args[0], err, ResolvePaths(strings.TrimSpace(string(output)), root))
+	}
+	return nil
+}
+
+// nxAffectedArgs runs the targets for the projects affected by the files,
+// which nx works out from its project graph
+func nxAffectedArgs(root string, files, targets []string) []string {
+	var rel []string
+	for _, f := range files {
+		if r, err := filepath.Rel(root, f); err == nil {
+			rel = append(rel, filepath.ToSlash(r))
+		}
+	}
+	if len(rel) == 0 {
+		return nil
+	}
+	return []string{"affected", "--targets=" + strings.Join(targets, ","), "--files=" + strings.Join(rel, ","), "--outputStyle=static"}
+}
+
+// turboRunArgs runs the targets for the packages owning the files and the
+// packages depending on them
+func turboRunArgs(root string, files, targets []string) []string {
+	var filters []string
+	for _, f := range files {
+		dir := findUp(filepath.Dir(f), root, "package.json")
+		if dir == "" || dir == root {
+			continue // The root isn't a workspace package
+		}
+		name := packageName(dir)
+		if name == "" {
+			continue
+		}
+		if filter := "--filter=..." + name; !slices.Contains(filters, filter) {
+			filters = append(filters, filter)
+		}
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	args := append([]string{"run"}, targets...)
+	args = append(args, filters...)
+	return append(args, "--continue", "--output-logs=errors-only")
+}
+
+// packageName returns the name in dir's package.json, or ""
+func packageName(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	return pkg.Name
+}
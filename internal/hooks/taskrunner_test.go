@@ -0,0 +1,79 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// taskRunnerWorkspace is a monorepo with a stub runner in node_modules/.bin
+// that logs its arguments and fails when they mention failOn
+func taskRunnerWorkspace(t *testing.T, runner, failOn string) (root, log string) {
+	t.Helper()
+	root = t.TempDir()
+	runInDir(t, root, "git", "init", "-q")
+	log = filepath.Join(root, "calls")
+	writeTestFile(t, root, ".claude-hooks.yaml", "monorepo:\n  enabled: true\n")
+	writeTestFile(t, root, runner+".json", "{}\n")
+	writeTestFile(t, root, "package.json", `{"name": "root"}`+"\n")
+	writeTestFile(t, root, "packages/ui/package.json", `{"name": "@acme/ui"}`+"\n")
+	writeTestFile(t, root, "packages/ui/src/button.tsx", "export {}\n")
+	writeTestFile(t, root, "apps/web/package.json", `{"name": "web"}`+"\n")
+	writeTestFile(t, root, "apps/web/index.js", "export {}\n")
+	writeTestFile(t, root, "tools/build.go", "package tools\n")
+	writeTestFile(t, root, "node_modules/.bin/"+runner, "#!/bin/sh\necho \"$@\" >> "+log+"\n"+
+		"case \"$*\" in *"+failOn+"*) echo 'packages/ui/src/button.tsx(1,1): error TS2304: nope'; exit 1 ;; esac\n")
+	if err := os.Chmod(filepath.Join(root, "node_modules", ".bin", runner), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return root, log
+}
+
+func TestTaskRunnerRouting(t *testing.T) {
+	root, _ := taskRunnerWorkspace(t, "nx", "never")
+	groups := GroupFiles([]string{
+		filepath.Join(root, "packages", "ui", "src", "button.tsx"),
+		filepath.Join(root, "apps", "web", "index.js"),
+		filepath.Join(root, "tools", "build.go"),
+	})
+	if len(groups["nx"]) != 2 || len(groups["go"]) != 1 {
+		t.Errorf("Expected JS and TS files to go to nx and Go to keep its hook, got %v", groups)
+	}
+}
+
+func TestNxAffected(t *testing.T) {
+	root, log := taskRunnerWorkspace(t, "nx", "never")
+	files := []string{filepath.Join(root, "packages", "ui", "src", "button.tsx"), filepath.Join(root, "apps", "web", "index.js")}
+	if err := GetHook("nx").PostEditJSON(files, false); err != nil {
+		t.Fatalf("Expected nx to pass, got %v", err)
+	}
+	data, _ := os.ReadFile(log)
+	want := "affected --targets=lint,test --files=packages/ui/src/button.tsx,apps/web/index.js --outputStyle=static"
+	if got := strings.TrimSpace(string(data)); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestTurboRun(t *testing.T) {
+	root, log := taskRunnerWorkspace(t, "turbo", "@acme/ui")
+	writeTestFile(t, root, ".claude-hooks.yaml", "monorepo:\n  enabled: true\n  targets: [typecheck]\n")
+	files := []string{
+		filepath.Join(root, "packages", "ui", "src", "button.tsx"),
+		filepath.Join(root, "apps", "web", "index.js"),
+		filepath.Join(root, "index.js"), // In the root package, which turbo doesn't filter on
+	}
+
+	err := GetHook("turbo").PostEditJSON(files, false)
+	if err == nil {
+		t.Fatal("Expected the failing task to fail the hook")
+	}
+	if !strings.Contains(err.Error(), filepath.Join(root, "packages", "ui", "src", "button.tsx")+"(1,1): error TS2304") {
+		t.Errorf("Expected the task output with resolved paths, got %v", err)
+	}
+	data, _ := os.ReadFile(log)
+	want := "run typecheck --filter=...@acme/ui --filter=...web --continue --output-logs=errors-only"
+	if got := strings.TrimSpace(string(data)); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
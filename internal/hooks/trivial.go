@@ -0,0 +1,165 @@
+package hooks
+
+import (
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// TrivialEdit reports whether changing path from before to after only touched
+// comments or whitespace, so none of the checks can change their verdict. Go
+// is compared token by token, ignoring comments and layout. JavaScript,
+// TypeScript, and proto files are compared line by line: only comment lines,
+// blank lines, and trailing whitespace may differ, since indentation can be
+// part of a template literal. Comments carrying directives for the compiler or
+// other tools never count as trivial, and other file types never do.
+func TrivialEdit(path, before, after string) bool {
+	if before == after {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return trivialGoEdit(before, after)
+	case ".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", ".proto":
+		return trivialLineEdit(before, after)
+	}
+	return false
+}
+
+// goDirective matches comments the toolchain reads: //go: directives, build
+// constraints, cgo exports, and line directives
+var goDirective = regexp.MustCompile(`^//(go:|\s*\+build|export |line |extern |nolint)`)
+
+type goToken struct {
+	tok token.Token
+	lit string
+}
+
+func trivialGoEdit(before, after string) bool {
+	// A broken file gets its errors reported again, whatever the edit was
+	if _, err := parser.ParseFile(token.NewFileSet(), "", after, parser.SkipObjectResolution); err != nil {
+		return false
+	}
+	beforeTokens, beforeDirectives, ok := goTokens(before)
+	if !ok {
+		return false
+	}
+	afterTokens, afterDirectives, ok := goTokens(after)
+	if !ok {
+		return false
+	}
+	return slices.Equal(beforeTokens, afterTokens) && slices.Equal(beforeDirectives, afterDirectives)
+}
+
+// goTokens returns the file's tokens without comments, and its directive
+// comments. ok is false when the file doesn't scan or uses cgo, whose preamble
+// is a comment.
+func goTokens(src string) (tokens []goToken, directives []string, ok bool) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	failed := false
+	s.Init(file, []byte(src), func(token.Position, string) { failed = true }, scanner.ScanComments)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		switch tok {
+		case token.COMMENT:
+			if goDirective.MatchString(lit) {
+				directives = append(directives, lit)
+			}
+			continue
+		case token.SEMICOLON:
+			lit = ";" // Automatic semicolons are "\n"
+		case token.STRING:
+			if lit == `"C"` {
+				return nil, nil, false
+			}
+		}
+		// gofmt drops the semicolon before a closing brace when joining lines
+		if n := len(tokens); n > 0 && (tok == token.RBRACE || tok == token.RPAREN) && tokens[n-1].tok == token.SEMICOLON {
+			tokens = tokens[:n-1]
+		}
+		tokens = append(tokens, goToken{tok, lit})
+	}
+	return tokens, directives, !failed
+}
+
+// toolComment matches comments other tools act on: TypeScript and lint
+// suppressions, triple-slash references, JSX pragmas, and bundler hints
+var toolComment = regexp.MustCompile(`@ts-|eslint|tslint|prettier-ignore|^///\s*<|@jsx|webpack|@vite-ignore|istanbul|c8 ignore|@license|@preserve`)
+
+func trivialLineEdit(before, after string) bool {
+	oldLines, newLines := strings.Split(before, "\n"), strings.Split(after, "\n")
+	oldComments, newComments := blockCommentLines(oldLines), blockCommentLines(newLines)
+
+	// Only the lines between the common prefix and suffix changed
+	start := 0
+	for start < len(oldLines) && start < len(newLines) && oldLines[start] == newLines[start] {
+		start++
+	}
+	oldEnd, newEnd := len(oldLines), len(newLines)
+	for oldEnd > start && newEnd > start && oldLines[oldEnd-1] == newLines[newEnd-1] {
+		oldEnd--
+		newEnd--
+	}
+
+	oldCode, ok := codeLines(oldLines[start:oldEnd], oldComments[start:oldEnd])
+	if !ok {
+		return false
+	}
+	newCode, ok := codeLines(newLines[start:newEnd], newComments[start:newEnd])
+	if !ok {
+		return false
+	}
+	return slices.Equal(oldCode, newCode)
+}
+
+// codeLines drops comment and blank lines and trailing whitespace. ok is false
+// when a dropped comment is one tools read.
+func codeLines(lines []string, inComment []bool) (code []string, ok bool) {
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case inComment[i] || strings.HasPrefix(trimmed, "//"):
+			if toolComment.MatchString(trimmed) {
+				return nil, false
+			}
+		default:
+			code = append(code, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	return code, true
+}
+
+// blockCommentLines marks the lines that are entirely inside a /* */ comment
+// opened at the start of a line. Comments opened after code leave their lines
+// marked as code, so changing them is never treated as trivial.
+func blockCommentLines(lines []string) []bool {
+	marks := make([]bool, len(lines))
+	open := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !open && strings.HasPrefix(trimmed, "/*") {
+			open = true
+		}
+		if open {
+			marks[i] = true
+			if end := strings.Index(trimmed, "*/"); end >= 0 {
+				open = false
+				// Code after the comment's end makes the line code
+				if strings.TrimSpace(trimmed[end+2:]) != "" {
+					marks[i] = false
+				}
+			}
+		}
+	}
+	return marks
+}
@@ -0,0 +1,42 @@
+package hooks
+
+import "testing"
+
+func TestTrivialEdit(t *testing.T) {
+	goFile := "package a\n\n// Load reads the config\nfunc Load() error {\n\treturn nil\n}\n"
+	tsFile := "import { a } from './a'\n\n/**\n * Sum adds\n */\nexport function sum(x: number) {\n  return x + a // add\n}\n"
+
+	tests := []struct {
+		name          string
+		path          string
+		before, after string
+		want          bool
+	}{
+		{"go comment", "a.go", goFile, "package a\n\n// Load reads the config file from disk\nfunc Load() error {\n\treturn nil\n}\n", true},
+		{"go layout", "a.go", goFile, "package a\n// Load reads the config\nfunc Load() error { return nil }\n", true},
+		{"go code", "a.go", goFile, "package a\n\n// Load reads the config\nfunc Load() error {\n\treturn errNope\n}\n", false},
+		{"go string", "a.go", `package a` + "\n\nvar s = \"a  b\"\n", `package a` + "\n\nvar s = \"a b\"\n", false},
+		{"go build constraint", "a.go", goFile, "//go:build linux\n\n" + goFile, false},
+		{"go embed", "a.go", goFile, "package a\n\n//go:embed config.yaml\nvar config string\n" + goFile[len("package a\n"):], false},
+		{"go cgo preamble", "a.go", "package a\n\n// #include <stdio.h>\nimport \"C\"\n", "package a\n\n// #include <stdlib.h>\nimport \"C\"\n", false},
+		{"go unparseable", "a.go", "package a\n\nfunc (", "package a\n\n// x\nfunc (", false},
+
+		{"ts doc comment", "a.ts", tsFile, "import { a } from './a'\n\n/**\n * Sum adds a to x\n * and returns it\n */\nexport function sum(x: number) {\n  return x + a // add\n}\n", true},
+		{"ts line comment and blank lines", "a.ts", tsFile, "import { a } from './a'\n// helpers\n\n\n" + tsFile[len("import { a } from './a'\n"):], true},
+		{"ts trailing comment", "a.ts", tsFile, "import { a } from './a'\n\n/**\n * Sum adds\n */\nexport function sum(x: number) {\n  return x + a // add a\n}\n", false},
+		{"ts indentation", "a.ts", tsFile, "import { a } from './a'\n\n/**\n * Sum adds\n */\nexport function sum(x: number) {\n    return x + a // add\n}\n", false},
+		{"ts trailing whitespace", "a.ts", tsFile, "import { a } from './a'  \n\n/**\n * Sum adds\n */\nexport function sum(x: number) {\n  return x + a // add\n}\n", true},
+		{"ts suppression", "a.ts", tsFile, "import { a } from './a'\n// @ts-ignore\n" + tsFile[len("import { a } from './a'\n"):], false},
+		{"ts code", "a.ts", tsFile, "import { b } from './a'\n" + tsFile[len("import { a } from './a'\n"):], false},
+
+		{"markdown", "README.md", "# A\n", "# B\n", false},
+		{"unchanged", "README.md", "# A\n", "# A\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TrivialEdit(tt.path, tt.before, tt.after); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
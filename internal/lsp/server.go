@@ -0,0 +1,248 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/brianleishman/claude-hooks/internal/hooks"
+)
+
+// Checker runs the hook pipeline over files and returns its findings
+type Checker func(files []string) []hooks.Diagnostic
+
+// Server is a minimal language server that publishes hook diagnostics for files
+// when they are opened or saved. Unsaved buffers aren't checked: the hooks see
+// what is on disk, exactly as they do when Claude edits a file.
+type Server struct {
+	check Checker
+	in    *bufio.Reader
+	out   io.Writer
+
+	writeMu sync.Mutex
+	queue   chan string
+	// reported remembers which files each checked file produced findings for, so
+	// findings in other files are cleared once fixed
+	reported map[string][]string
+}
+
+// NewServer returns a server speaking JSON-RPC over in and out (usually stdio)
+func NewServer(in io.Reader, out io.Writer, check Checker) *Server {
+	return &Server{
+		check:    check,
+		in:       bufio.NewReader(in),
+		out:      out,
+		queue:    make(chan string, 64),
+		reported: make(map[string][]string),
+	}
+}
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"` // Present even when null, as JSON-RPC requires
+}
+
+type errorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// codeMethodNotFound is the JSON-RPC error for requests the server doesn't handle
+const codeMethodNotFound = -32601
+
+// Serve handles messages until the client sends exit or closes the stream
+func (s *Server) Serve() error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.work()
+	}()
+	defer func() {
+		close(s.queue)
+		<-done
+	}()
+
+	shutdown := false
+	for {
+		msg, err := s.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch msg.Method {
+		case "initialize":
+			s.reply(msg.ID, map[string]any{
+				"capabilities": map[string]any{
+					"textDocumentSync": map[string]any{"openClose": true, "change": 0, "save": true},
+				},
+				"serverInfo": map[string]string{"name": "claude-hooks"},
+			})
+		case "shutdown":
+			shutdown = true
+			s.reply(msg.ID, nil)
+		case "exit":
+			if !shutdown {
+				return fmt.Errorf("client exited without shutdown")
+			}
+			return nil
+		case "textDocument/didOpen", "textDocument/didSave":
+			if path, ok := documentPath(msg.Params); ok {
+				s.queue <- path
+			}
+		case "textDocument/didClose":
+			if path, ok := documentPath(msg.Params); ok {
+				s.publish(path, nil)
+			}
+		default:
+			// Unhandled notifications are ignored, but requests must get an answer
+			if msg.ID != nil {
+				resp := errorResponse{JSONRPC: "2.0", ID: msg.ID}
+				resp.Error.Code, resp.Error.Message = codeMethodNotFound, "method not supported: "+msg.Method
+				s.write(resp)
+			}
+		}
+	}
+}
+
+// work runs checks one at a time, coalescing queued duplicates so a burst of
+// saves checks each file once
+func (s *Server) work() {
+	for path := range s.queue {
+		pending := []string{path}
+	drain:
+		for {
+			select {
+			case next, ok := <-s.queue:
+				if !ok {
+					break drain
+				}
+				if !slices.Contains(pending, next) {
+					pending = append(pending, next)
+				}
+			default:
+				break drain
+			}
+		}
+		for _, p := range pending {
+			s.publish(p, s.check([]string{p}))
+		}
+	}
+}
+
+// publish sends the findings from checking path: one notification for path and
+// each file the findings point at, plus empty ones for files that had findings
+// last time but don't now
+func (s *Server) publish(path string, diags []hooks.Diagnostic) {
+	files := []string{path}
+	for _, d := range diags {
+		if !slices.Contains(files, d.File) {
+			files = append(files, d.File)
+		}
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	var stale []string
+	for _, f := range s.reported[path] {
+		if !slices.Contains(files, f) {
+			stale = append(stale, f)
+		}
+	}
+	for _, n := range hooks.LSPNotifications(append(files, stale...), diags) {
+		s.writeLocked(n)
+	}
+	s.reported[path] = files
+}
+
+// documentPath extracts the local path of a textDocument notification
+func documentPath(params json.RawMessage) (string, bool) {
+	var p struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return "", false
+	}
+	u, err := url.Parse(p.TextDocument.URI)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	return u.Path, true
+}
+
+func (s *Server) reply(id json.RawMessage, result any) {
+	s.write(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) write(v any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.writeLocked(v)
+}
+
+// writeLocked frames v with a Content-Length header; writeMu must be held
+func (s *Server) writeLocked(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// read parses one Content-Length framed message, skipping bodies that aren't JSON
+func (s *Server) read() (message, error) {
+	for {
+		length := -1
+		for {
+			line, err := s.in.ReadString('\n')
+			if err != nil {
+				return message{}, err
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if line == "" {
+				break
+			}
+			if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+				if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+					return message{}, fmt.Errorf("invalid Content-Length %q", value)
+				}
+			}
+		}
+		if length < 0 {
+			return message{}, fmt.Errorf("message without Content-Length")
+		}
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(s.in, body); err != nil {
+			return message{}, err
+		}
+		var msg message
+		if err := json.Unmarshal(body, &msg); err == nil {
+			return msg, nil
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/hooks"
+)
+
+func frame(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// readAll splits server output back into messages
+func readAll(t *testing.T, out []byte) []map[string]any {
+	t.Helper()
+	var msgs []map[string]any
+	r := bufio.NewReader(bytes.NewReader(out))
+	for {
+		header, err := r.ReadString('\n')
+		if err == io.EOF {
+			return msgs
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+		if err != nil {
+			t.Fatalf("Bad header %q", header)
+		}
+		_, _ = r.ReadString('\n')
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			t.Fatal(err)
+		}
+		var msg map[string]any
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestServerPublishesDiagnostics(t *testing.T) {
+	open := func(uri string) map[string]any {
+		return map[string]any{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": map[string]any{"textDocument": map[string]any{"uri": uri}}}
+	}
+	input := frame(t, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{}}) +
+		frame(t, map[string]any{"jsonrpc": "2.0", "method": "initialized"}) +
+		frame(t, open("file:///repo/bad.go")) +
+		frame(t, map[string]any{"jsonrpc": "2.0", "id": 2, "method": "textDocument/hover"}) +
+		frame(t, map[string]any{"jsonrpc": "2.0", "id": 3, "method": "shutdown"}) +
+		frame(t, map[string]any{"jsonrpc": "2.0", "method": "exit"})
+
+	var checked []string
+	var out bytes.Buffer
+	server := NewServer(strings.NewReader(input), &out, func(files []string) []hooks.Diagnostic {
+		checked = append(checked, files...)
+		return []hooks.Diagnostic{{File: "/repo/bad.go", Line: 3, Column: 2, Severity: "error", Message: "undefined: x", Source: "go"}}
+	})
+	if err := server.Serve(); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	if len(checked) != 1 || checked[0] != "/repo/bad.go" {
+		t.Errorf("Expected /repo/bad.go to be checked once, got %v", checked)
+	}
+
+	var sawInit, sawHoverError, sawShutdown, sawDiagnostics bool
+	for _, msg := range readAll(t, out.Bytes()) {
+		switch {
+		case msg["id"] == float64(1):
+			_, sawInit = msg["result"].(map[string]any)["capabilities"]
+		case msg["id"] == float64(2):
+			sawHoverError = msg["error"] != nil
+		case msg["id"] == float64(3):
+			_, sawShutdown = msg["result"]
+		case msg["method"] == "textDocument/publishDiagnostics":
+			params := msg["params"].(map[string]any)
+			diags := params["diagnostics"].([]any)
+			if params["uri"] == "file:///repo/bad.go" && len(diags) == 1 {
+				sawDiagnostics = true
+			}
+		}
+	}
+	if !sawInit || !sawHoverError || !sawShutdown || !sawDiagnostics {
+		t.Errorf("Expected initialize result, hover error, shutdown result, and diagnostics; got init=%v hover=%v shutdown=%v diagnostics=%v\n%s",
+			sawInit, sawHoverError, sawShutdown, sawDiagnostics, out.String())
+	}
+}
@@ -0,0 +1,124 @@
+// Package network builds the HTTP clients for what claude-hook sends over the
+// network: webhooks, telemetry, CI reports, and self-update. They go through
+// the proxy in $HTTPS_PROXY or $HTTP_PROXY (minus $NO_PROXY), and trust a
+// configured CA bundle besides the system's certificates, for corporate
+// networks whose proxy intercepts TLS.
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	mu       sync.Mutex
+	caBundle string
+)
+
+// UseCABundle makes clients built after it trust the certificates in the PEM
+// file at path too; "" trusts only the system's
+func UseCABundle(path string) {
+	mu.Lock()
+	defer mu.Unlock()
+	caBundle = path
+}
+
+// CABundle returns the bundle set with UseCABundle
+func CABundle() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return caBundle
+}
+
+// Transport returns a transport through the environment's proxy that trusts
+// the CA bundle. A bundle that can't be read or holds no certificates is an
+// error, since connections would fail later with a less helpful one.
+func Transport() (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	path := CABundle()
+	if path == "" {
+		return t, nil
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool() // No system store to add to
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("CA bundle %s has no PEM certificates", path)
+	}
+	t.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return t, nil
+}
+
+// Client returns a client using Transport whose requests time out after
+// timeout, or never when it's 0
+func Client(timeout time.Duration) (*http.Client, error) {
+	t, err := Transport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: t, Timeout: timeout}, nil
+}
+
+// Env returns the variables pointing child processes that make their own
+// connections, like the reviewer CLIs, at the CA bundle: NODE_EXTRA_CA_CERTS
+// for Node and SSL_CERT_FILE for OpenSSL and most others. Ones already set
+// are left alone. They inherit the proxy variables as they are.
+func Env() []string {
+	path := CABundle()
+	if path == "" {
+		return nil
+	}
+	var env []string
+	for _, name := range []string{"NODE_EXTRA_CA_CERTS", "SSL_CERT_FILE"} {
+		if os.Getenv(name) == "" {
+			env = append(env, name+"="+path)
+		}
+	}
+	return env
+}
+
+// Reach connects to target the way clients do and returns the proxy it went
+// through, redacted, or "" for a direct connection. Any HTTP response counts
+// as reaching it. A certificate the client doesn't trust is explained, since
+// behind an intercepting proxy it means the CA bundle is missing.
+func Reach(ctx context.Context, target string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	var proxy string
+	if u, err := http.ProxyFromEnvironment(req); err != nil {
+		return "", fmt.Errorf("invalid proxy setting: %w", err)
+	} else if u != nil {
+		proxy = u.Redacted()
+	}
+
+	client, err := Client(0)
+	if err != nil {
+		return proxy, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var unknown x509.UnknownAuthorityError
+		var verify *tls.CertificateVerificationError
+		if errors.As(err, &unknown) || errors.As(err, &verify) {
+			return proxy, fmt.Errorf("%w (a proxy intercepting TLS needs its CA in ca_bundle or $CLAUDE_HOOKS_CA_BUNDLE)", err)
+		}
+		return proxy, err
+	}
+	resp.Body.Close()
+	return proxy, nil
+}
@@ -0,0 +1,76 @@
+package network
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeBundle saves the certificate of server as a PEM CA bundle
+func writeBundle(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	t.Cleanup(func() { UseCABundle("") })
+
+	UseCABundle("")
+	_, err := Reach(context.Background(), server.URL)
+	if err == nil || !strings.Contains(err.Error(), "ca_bundle") {
+		t.Errorf("Expected an untrusted certificate to point at ca_bundle, got %v", err)
+	}
+
+	UseCABundle(writeBundle(t, server))
+	proxy, err := Reach(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected the bundle's certificate to be trusted, got %v", err)
+	}
+	if proxy != "" {
+		t.Errorf("Expected a direct connection to localhost, got proxy %q", proxy)
+	}
+}
+
+func TestInvalidCABundle(t *testing.T) {
+	t.Cleanup(func() { UseCABundle("") })
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not a certificate\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	UseCABundle(path)
+	if _, err := Client(0); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Errorf("Expected a bundle without certificates to fail, got %v", err)
+	}
+	UseCABundle(filepath.Join(t.TempDir(), "missing.pem"))
+	if _, err := Client(0); err == nil {
+		t.Error("Expected a missing bundle to fail")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	t.Cleanup(func() { UseCABundle("") })
+	t.Setenv("NODE_EXTRA_CA_CERTS", "")
+	t.Setenv("SSL_CERT_FILE", "/etc/ssl/mine.pem")
+
+	UseCABundle("")
+	if env := Env(); len(env) != 0 {
+		t.Errorf("Expected nothing without a bundle, got %v", env)
+	}
+	UseCABundle("/etc/ssl/corp.pem")
+	env := Env()
+	if len(env) != 1 || env[0] != "NODE_EXTRA_CA_CERTS=/etc/ssl/corp.pem" {
+		t.Errorf("Expected only the unset variable to point at the bundle, got %v", env)
+	}
+}
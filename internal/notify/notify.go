@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/network"
+)
+
+// WebhookEnv overrides the webhook URL from the project config
+const WebhookEnv = "CLAUDE_HOOKS_WEBHOOK_URL"
+
+// sendTimeout keeps a slow webhook from delaying the hook's decision
+const sendTimeout = 3 * time.Second
+
+// Event is the JSON body posted to the webhook
+type Event struct {
+	Time     time.Time `json:"time"`
+	Hook     string    `json:"hook"`
+	Decision string    `json:"decision"` // block, deny, ask, or warn; report for a digest
+	Rule     string    `json:"rule,omitempty"`
+	Session  string    `json:"session,omitempty"`
+	Project  string    `json:"project,omitempty"`
+	Profile  string    `json:"profile"`
+	Message  string    `json:"message,omitempty"` // Details for a human, like the files of a loop
+}
+
+// WebhookURL returns where notifications go, empty when none is configured
+func WebhookURL(cfg config.UnattendedConfig) string {
+	if url := os.Getenv(WebhookEnv); url != "" {
+		return url
+	}
+	return cfg.Webhook
+}
+
+// Send posts ev to the webhook at url
+func Send(url string, ev Event) error {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshaling notification: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client, err := network.Client(0)
+	if err != nil {
+		return fmt.Errorf("sending notification: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("sending notification: %s", resp.Status)
+	}
+	return nil
+}
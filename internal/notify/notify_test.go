@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func TestSend(t *testing.T) {
+	var got Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected JSON POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("Failed to decode body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	ev := Event{Hook: "pre-bash", Decision: "deny", Rule: "unattended:force-push", Session: "abc", Profile: "unattended"}
+	if err := Send(server.URL, ev); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if got.Rule != ev.Rule || got.Session != ev.Session || got.Time.IsZero() {
+		t.Errorf("Expected %+v with a timestamp, got %+v", ev, got)
+	}
+}
+
+func TestSendReportsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	if err := Send(server.URL, Event{Hook: "stop", Decision: "block"}); err == nil {
+		t.Error("Expected an error for a 403 response")
+	}
+}
+
+func TestWebhookURL(t *testing.T) {
+	cfg := config.UnattendedConfig{Webhook: "https://example.com/config"}
+
+	t.Setenv(WebhookEnv, "")
+	if got := WebhookURL(cfg); got != cfg.Webhook {
+		t.Errorf("Expected %q, got %q", cfg.Webhook, got)
+	}
+
+	t.Setenv(WebhookEnv, "https://example.com/env")
+	if got := WebhookURL(cfg); got != "https://example.com/env" {
+		t.Errorf("Expected the environment to override the config, got %q", got)
+	}
+}
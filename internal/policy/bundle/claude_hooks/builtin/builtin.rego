@@ -0,0 +1,39 @@
+# The rules claude-hook checks in Go, as Rego to start policies from: add
+# data.claude_hooks.builtin.decisions to yours and change what you need.
+# content_rules is written next to this file from the built-in content rules,
+# without those disabled under content.disabled, and without debug-output,
+# whose exemptions for tests and entry points only the Go check applies.
+package claude_hooks.builtin
+
+import rego.v1
+
+mysql_clients := {"mysql", "mysqldump", "mariadb"}
+
+decisions contains {
+	"decision": "deny",
+	"rule": "mysql-cli",
+	"reason": sprintf("MySQL commands are not allowed (%s). Use the Go database code instead.", [cmd.text]),
+} if {
+	input.hook == "pre-bash"
+	some cmd in input.command.commands
+	cmd.executable in mysql_clients
+}
+
+decisions contains {
+	"decision": "deny",
+	"rule": sprintf("content:%s", [rule.name]),
+	"reason": sprintf("%s: %s", [edit.path, rule.reason]),
+} if {
+	input.hook == "pre-edit"
+	some edit in input.edits
+	some rule in data.claude_hooks.builtin.content_rules
+	applies(rule, edit.path)
+	count(regex.find_n(rule.pattern, edit.new, -1)) > count(regex.find_n(rule.pattern, edit.old, -1))
+}
+
+applies(rule, _) if count(rule.extensions) == 0
+
+applies(rule, path) if {
+	some ext in rule.extensions
+	endswith(lower(path), ext)
+}
@@ -0,0 +1,207 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+// ContentRule flags code an edit introduces. Several entries can share a name
+// to cover the same problem in different languages.
+type ContentRule struct {
+	Name       string
+	Extensions []string // File extensions the rule applies to; empty means any file
+	Pattern    *regexp.Regexp
+	Reason     string
+	// Output marks debug output, which is allowed under content.output_paths,
+	// in Go main packages and Python scripts, and in Go and JS/TS tests, where
+	// printing is the point
+	Output bool
+}
+
+var (
+	goFiles     = []string{".go"}
+	jsFiles     = []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx"}
+	pythonFiles = []string{".py"}
+	shellFiles  = []string{".sh", ".bash", ".zsh"}
+)
+
+// goSQLString matches a Go string literal, interpreted or raw, that starts a
+// SQL statement. Requiring the statement keeps calls like http.Get or a
+// Redis client's Get, which share method names with sqlx, out of the rule.
+const goSQLString = "[\"`]\\s*(?i:select|insert|update|delete|with)\\s[^\"`]*[\"`]"
+
+// ContentRules are the built-in rules, disabled by name under content.disabled
+var ContentRules = []ContentRule{
+	{Name: "todo-panic", Extensions: goFiles, Pattern: regexp.MustCompile(`\bpanic\(\s*"(?i:todo|fixme|not implemented|unimplemented)`), Reason: "placeholder panics crash at runtime instead of failing the build"},
+	{Name: "todo-panic", Extensions: jsFiles, Pattern: regexp.MustCompile("throw\\s+new\\s+Error\\(\\s*[\"'`](?i:todo|fixme|not implemented)"), Reason: "placeholder throws crash at runtime instead of failing the build"},
+	{Name: "todo-panic", Extensions: []string{".rs"}, Pattern: regexp.MustCompile(`\b(todo|unimplemented)!\(`), Reason: "placeholder macros crash at runtime instead of failing the build"},
+
+	{Name: "eval-input", Extensions: jsFiles, Pattern: regexp.MustCompile("\\beval\\s*\\(\\s*[^\\s'\"`)]|\\bnew\\s+Function\\s*\\("), Reason: "evaluating non-literal strings runs whatever the input contains"},
+	{Name: "eval-input", Extensions: pythonFiles, Pattern: regexp.MustCompile(`\b(eval|exec)\s*\(\s*[^\s'")]`), Reason: "evaluating non-literal strings runs whatever the input contains"},
+	{Name: "eval-input", Extensions: shellFiles, Pattern: regexp.MustCompile(`\beval\s+["']?\$`), Reason: "eval of variables runs whatever they contain"},
+
+	{Name: "tls-verification-disabled", Extensions: goFiles, Pattern: regexp.MustCompile(`InsecureSkipVerify:\s*true`), Reason: "skipping certificate verification allows man-in-the-middle attacks"},
+	{Name: "tls-verification-disabled", Extensions: jsFiles, Pattern: regexp.MustCompile(`rejectUnauthorized:\s*false|NODE_TLS_REJECT_UNAUTHORIZED`), Reason: "skipping certificate verification allows man-in-the-middle attacks"},
+	{Name: "tls-verification-disabled", Extensions: pythonFiles, Pattern: regexp.MustCompile(`\bverify\s*=\s*False\b|_create_unverified_context|\bCERT_NONE\b`), Reason: "skipping certificate verification allows man-in-the-middle attacks"},
+	{Name: "tls-verification-disabled", Extensions: shellFiles, Pattern: regexp.MustCompile(`\bcurl\s.*(\s-k\b|--insecure\b)`), Reason: "skipping certificate verification allows man-in-the-middle attacks"},
+
+	{Name: "sql-injection", Extensions: goFiles, Pattern: regexp.MustCompile(`\.(Query|QueryRow|QueryContext|QueryRowContext|Queryx|QueryRowx|QueryxContext|QueryRowxContext|Exec|ExecContext|MustExec|MustExecContext|Prepare|PrepareContext|Get|GetContext|Select|SelectContext|Raw)\(\s*(ctx,\s*)?(&?\w+,\s*)?(fmt\.Sprintf\(\s*` + goSQLString + `|` + goSQLString + `\s*\+)|fmt\.Sprintf\(\s*["` + "`" + `]\s*(?i:select\s[^"` + "`" + `]*\sfrom|insert\s+into|update\s+\w+\s+set|delete\s+from)\s[^"` + "`" + `]*%[sv]`), Reason: "building SQL from strings allows SQL injection; pass the values as arguments with placeholders, e.g. db.QueryContext(ctx, \"SELECT name FROM users WHERE id = $1\", id) (? for MySQL and SQLite)"},
+	{Name: "sql-injection", Extensions: jsFiles, Pattern: regexp.MustCompile("\\.(query|execute|raw|whereRaw|\\$queryRawUnsafe|\\$executeRawUnsafe|unsafe)\\(\\s*(`[^`]*\\$\\{|[\"'][^\"']*[\"']\\s*\\+)"), Reason: "interpolating values into SQL allows SQL injection; pass them as parameters, e.g. db.query('SELECT name FROM users WHERE id = $1', [id]), or use a tagged template that parameterizes, like sql`...` or prisma.$queryRaw`...`"},
+	{Name: "sql-injection", Extensions: pythonFiles, Pattern: regexp.MustCompile(`\.(execute|executemany|raw)\(\s*(f["']|["'][^"']*["']\s*(%\s|\+|\.format\())`), Reason: "formatting values into SQL allows SQL injection; pass them as parameters, e.g. cursor.execute(\"SELECT name FROM users WHERE id = %s\", (user_id,))"},
+
+	{Name: "debug-output", Extensions: jsFiles, Pattern: regexp.MustCompile(`(?m)\bconsole\.(log|debug|trace|dir|table)\s*\(|^\s*debugger\s*;?\s*$`), Reason: "console output and debugger statements are debugging leftovers; remove them, or use the project's logger", Output: true},
+	{Name: "debug-output", Extensions: goFiles, Pattern: regexp.MustCompile(`(?m)\bfmt\.Print(ln|f)?\s*\(|\blog\.Print(ln|f)?\s*\(|^\s*println\s*\(|\bspew\.Dump\s*\(`), Reason: "printing from a library package is a debugging leftover; return the value or error, or use the project's logger", Output: true},
+	{Name: "debug-output", Extensions: pythonFiles, Pattern: regexp.MustCompile(`(?m)^\s*print\s*\(|\bbreakpoint\s*\(\s*\)|\bpdb\.set_trace\s*\(`), Reason: "print and breakpoint calls are debugging leftovers; remove them, or use logging", Output: true},
+
+	{Name: "lint-suppression", Pattern: regexp.MustCompile(`#\s*nosec\b|//\s*nolint\b|//\s*lint:ignore\b|eslint-disable|@ts-(ignore|nocheck|expect-error)\b|#\s*noqa\b|#\s*type:\s*ignore\b|pylint:\s*disable|#!?\[allow\(`), Reason: "suppressing a finding hides it instead of fixing it"},
+}
+
+// Edit is a proposed change to one file: the text it replaces and the
+// replacement. A Write replaces the whole file.
+type Edit struct {
+	Path string `json:"path"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// ContentViolation is one rule an edit breaks
+type ContentViolation struct {
+	Rule   string
+	Path   string
+	Reason string
+	Line   string // First offending line the edit introduces
+}
+
+func (v ContentViolation) String() string {
+	if v.Line == "" {
+		return fmt.Sprintf("%s: %s (%s)", v.Path, v.Reason, v.Rule)
+	}
+	return fmt.Sprintf("%s: %s (%s): %s", v.Path, v.Reason, v.Rule, v.Line)
+}
+
+// CheckEdits returns the violations the edits, of files in the repository at
+// root, would introduce. A pattern only counts when the new text has more
+// matches than the text it replaces, so editing around existing code isn't
+// blocked for what was already there.
+func CheckEdits(cfg config.ContentConfig, root string, edits []Edit) []ContentViolation {
+	var violations []ContentViolation
+	deleted := make(map[string]int)
+	var paths []string
+
+	for _, edit := range edits {
+		ext := strings.ToLower(filepath.Ext(edit.Path))
+		for _, rule := range ContentRules {
+			if slices.Contains(cfg.Disabled, rule.Name) || (len(rule.Extensions) > 0 && !slices.Contains(rule.Extensions, ext)) {
+				continue
+			}
+			if len(rule.Pattern.FindAllStringIndex(edit.New, -1)) <= len(rule.Pattern.FindAllStringIndex(edit.Old, -1)) {
+				continue
+			}
+			if rule.Output && outputAllowed(cfg, root, edit) {
+				continue
+			}
+			violations = append(violations, ContentViolation{
+				Rule:   rule.Name,
+				Path:   edit.Path,
+				Reason: rule.Reason,
+				Line:   introducedLine(rule.Pattern, edit.Old, edit.New),
+			})
+		}
+
+		if removed := lineCount(edit.Old) - lineCount(edit.New); removed > 0 {
+			if _, seen := deleted[edit.Path]; !seen {
+				paths = append(paths, edit.Path)
+			}
+			deleted[edit.Path] += removed
+		}
+	}
+
+	if cfg.MaxDeletedLines > 0 && !slices.Contains(cfg.Disabled, "large-deletion") {
+		for _, path := range paths {
+			if deleted[path] > cfg.MaxDeletedLines {
+				violations = append(violations, ContentViolation{
+					Rule:   "large-deletion",
+					Path:   path,
+					Reason: fmt.Sprintf("removes %d lines (limit %d); make smaller edits so each can be reviewed", deleted[path], cfg.MaxDeletedLines),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+var (
+	// goMainPackage matches the package clause of a command
+	goMainPackage = regexp.MustCompile(`(?m)^package main\b`)
+	// pythonMain matches the entry point guard of a Python script
+	pythonMain = regexp.MustCompile(`(?m)^if\s+__name__\s*==\s*["']__main__["']`)
+	// jsTestFile matches JS/TS tests by the Jest, Vitest, and Mocha conventions
+	jsTestFile = regexp.MustCompile(`(^|/)__tests__/|\.(test|spec)\.[cm]?[jt]sx?$`)
+)
+
+// outputAllowed reports whether the edited file may print: it's under
+// content.output_paths, like a CLI or scripts directory, it's a Go or JS/TS
+// test, or it's a Go main package or a Python script run as __main__, judged
+// by the new text when it has the package clause or guard and by the file
+// otherwise
+func outputAllowed(cfg config.ContentConfig, root string, edit Edit) bool {
+	rel := edit.Path
+	if root != "" {
+		if r, err := filepath.Rel(root, edit.Path); err == nil && !strings.HasPrefix(r, "..") {
+			rel = r
+		}
+	}
+	if binaryPathAllowed(filepath.ToSlash(rel), cfg.OutputPaths) {
+		return true
+	}
+	var entry *regexp.Regexp
+	switch ext := filepath.Ext(edit.Path); {
+	case ext == ".go":
+		if strings.HasSuffix(edit.Path, "_test.go") {
+			return true
+		}
+		entry = goMainPackage
+	case ext == ".py":
+		entry = pythonMain
+	case slices.Contains(jsFiles, ext):
+		return jsTestFile.MatchString(filepath.ToSlash(edit.Path))
+	default:
+		return false
+	}
+	if entry.MatchString(edit.New) {
+		return true
+	}
+	data, err := os.ReadFile(edit.Path)
+	return err == nil && entry.Match(data)
+}
+
+// introducedLine returns the first line of newText matching pattern that isn't
+// among oldText's matching lines
+func introducedLine(pattern *regexp.Regexp, oldText, newText string) string {
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(oldText, "\n") {
+		if pattern.MatchString(line) {
+			existing[strings.TrimSpace(line)] = true
+		}
+	}
+	for _, line := range strings.Split(newText, "\n") {
+		line = strings.TrimSpace(line)
+		if pattern.MatchString(line) && !existing[line] {
+			return line
+		}
+	}
+	return ""
+}
+
+func lineCount(s string) int {
+	if s == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(s, "\n"), "\n") + 1
+}
@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func TestCheckEdits(t *testing.T) {
+	cfg := config.ContentConfig{Enabled: true, MaxDeletedLines: 300, OutputPaths: []string{"scripts"}}
+
+	tests := []struct {
+		name string
+		edit Edit
+		want []string // Rule names
+	}{
+		{"todo panic", Edit{Path: "a.go", New: `func f() { panic("TODO: implement") }`}, []string{"todo-panic"}},
+		{"real panic", Edit{Path: "a.go", New: `panic("unreachable: bad state")`}, nil},
+		{"todo throw", Edit{Path: "a.ts", New: `throw new Error("not implemented")`}, []string{"todo-panic"}},
+		{"js eval of input", Edit{Path: "a.js", New: `eval(req.query.expr)`}, []string{"eval-input"}},
+		{"js eval of literal", Edit{Path: "a.js", New: `eval("1 + 1")`}, nil},
+		{"python exec", Edit{Path: "a.py", New: `exec(payload)`}, []string{"eval-input"}},
+		{"go insecure tls", Edit{Path: "a.go", New: `tls.Config{InsecureSkipVerify: true}`}, []string{"tls-verification-disabled"}},
+		{"python verify false", Edit{Path: "a.py", New: `requests.get(url, verify=False)`}, []string{"tls-verification-disabled"}},
+		{"nosec", Edit{Path: "a.go", New: `x := exec.Command(name) // #nosec G204`}, []string{"lint-suppression"}},
+		{"nolint", Edit{Path: "a.go", New: `_ = f() //nolint:errcheck`}, []string{"lint-suppression"}},
+		{"ts-ignore", Edit{Path: "a.ts", New: "// @ts-ignore\nconst x: number = y"}, []string{"lint-suppression"}},
+		{"rules are per language", Edit{Path: "a.md", New: `InsecureSkipVerify: true and eval(x)`}, nil},
+		{"existing code kept", Edit{Path: "a.go", Old: "_ = f() //nolint:errcheck\n", New: "_ = f() //nolint:errcheck\n_ = g()\n"}, nil},
+		{"existing code duplicated", Edit{Path: "a.go", Old: "_ = f() //nolint:errcheck\n", New: "_ = f() //nolint:errcheck\n_ = g() //nolint:errcheck\n"}, []string{"lint-suppression"}},
+		{"large deletion", Edit{Path: "a.go", Old: strings.Repeat("x\n", 302), New: "x\n"}, []string{"large-deletion"}},
+		{"console.log", Edit{Path: "src/a.ts", New: "console.log(user)\n"}, []string{"debug-output"}},
+		{"debugger", Edit{Path: "src/a.js", New: "  debugger;\n"}, []string{"debug-output"}},
+		{"debugger in a function", Edit{Path: "src/a.js", New: "function f() {\n  debugger;\n}\n"}, []string{"debug-output"}},
+		{"console.log in a test", Edit{Path: "src/a.test.ts", New: "console.log(user)\n"}, nil},
+		{"debugger in __tests__", Edit{Path: "src/__tests__/a.js", New: "  debugger;\n"}, nil},
+		{"console.error", Edit{Path: "src/a.ts", New: "console.error(err)\n"}, nil},
+		{"console.log in scripts", Edit{Path: "scripts/seed.ts", New: "console.log(count)\n"}, nil},
+		{"go library print", Edit{Path: "store/a.go", New: "fmt.Println(rows)\nlog.Printf(\"%v\", x)\n"}, []string{"debug-output"}},
+		{"go main print", Edit{Path: "a.go", New: "package main\n\nfunc main() { fmt.Println(\"hi\") }\n"}, nil},
+		{"go test print", Edit{Path: "a_test.go", New: "fmt.Println(x)\n"}, nil},
+		{"go fprintf", Edit{Path: "a.go", New: "fmt.Fprintf(w, \"%d\", n)\n"}, nil},
+		{"python print", Edit{Path: "a.py", New: "    print(result)\n"}, []string{"debug-output"}},
+		{"python print in a function", Edit{Path: "a.py", New: "def f(x):\n    print(x)\n"}, []string{"debug-output"}},
+		{"python script print", Edit{Path: "a.py", New: "def main():\n    print(1)\n\nif __name__ == \"__main__\":\n    main()\n"}, nil},
+		{"go println in a function", Edit{Path: "store/a.go", New: "func f() {\n\tprintln(x)\n}\n"}, []string{"debug-output"}},
+		{"python breakpoint", Edit{Path: "a.py", New: "breakpoint()\n"}, []string{"debug-output"}},
+		{"go sprintf query", Edit{Path: "a.go", New: `rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM users WHERE name = '%s'", name))`}, []string{"sql-injection"}},
+		{"go sprintf sql", Edit{Path: "a.go", New: `q := fmt.Sprintf("DELETE FROM users WHERE id = %v", id)`}, []string{"sql-injection"}},
+		{"go concatenated query", Edit{Path: "a.go", New: `db.Exec("UPDATE users SET name = '" + name + "'")`}, []string{"sql-injection"}},
+		{"go raw string query", Edit{Path: "a.go", New: "rows, err := db.Query(`SELECT * FROM t WHERE id = ` + id)"}, []string{"sql-injection"}},
+		{"go sqlx get", Edit{Path: "a.go", New: `err := db.Get(&user, "SELECT * FROM users WHERE id = " + id)`}, []string{"sql-injection"}},
+		{"go http get", Edit{Path: "a.go", New: `resp, err := http.Get(fmt.Sprintf("https://api.example.com/users/%s", id))`}, nil},
+		{"go redis get", Edit{Path: "a.go", New: `v, err := rdb.Get(ctx, "user:" + id).Result()`}, nil},
+		{"go router get", Edit{Path: "a.go", New: `r.Get("/users/" + id, handler)`}, nil},
+		{"go parameterized query", Edit{Path: "a.go", New: `db.QueryContext(ctx, "SELECT * FROM users WHERE id = $1", id)`}, nil},
+		{"go sprintf table name", Edit{Path: "a.go", New: `msg := fmt.Sprintf("select a file: %s", name)`}, nil},
+		{"ts template query", Edit{Path: "a.ts", New: "await pool.query(`SELECT * FROM users WHERE id = ${id}`)"}, []string{"sql-injection"}},
+		{"ts tagged template", Edit{Path: "a.ts", New: "await prisma.$queryRaw`SELECT * FROM users WHERE id = ${id}`"}, nil},
+		{"ts parameterized query", Edit{Path: "a.ts", New: "await pool.query('SELECT * FROM users WHERE id = $1', [id])"}, nil},
+		{"python f-string query", Edit{Path: "a.py", New: `cursor.execute(f"SELECT * FROM users WHERE id = {user_id}")`}, []string{"sql-injection"}},
+		{"python percent query", Edit{Path: "a.py", New: `cursor.execute("SELECT * FROM users WHERE id = %s" % user_id)`}, []string{"sql-injection"}},
+		{"python parameterized query", Edit{Path: "a.py", New: `cursor.execute("SELECT * FROM users WHERE id = %s", (user_id,))`}, nil},
+		{"deletion under limit", Edit{Path: "a.go", Old: strings.Repeat("x\n", 301), New: "x\n"}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, v := range CheckEdits(cfg, "", []Edit{tt.edit}) {
+			got = append(got, v.Rule)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCheckEditsSumsDeletionsPerFile(t *testing.T) {
+	cfg := config.ContentConfig{Enabled: true, MaxDeletedLines: 10}
+	edits := []Edit{
+		{Path: "a.go", Old: strings.Repeat("x\n", 6)},
+		{Path: "a.go", Old: strings.Repeat("y\n", 6)},
+		{Path: "b.go", Old: strings.Repeat("z\n", 6)},
+	}
+	violations := CheckEdits(cfg, "", edits)
+	if len(violations) != 1 || violations[0].Path != "a.go" {
+		t.Errorf("Expected only a.go over the limit, got %v", violations)
+	}
+}
+
+func TestCheckEditsDisabledRules(t *testing.T) {
+	cfg := config.ContentConfig{Enabled: true, Disabled: []string{"lint-suppression", "large-deletion"}, MaxDeletedLines: 1}
+	edits := []Edit{{Path: "a.go", Old: "a\nb\nc\n", New: "_ = f() //nolint:errcheck"}}
+	if violations := CheckEdits(cfg, "", edits); len(violations) != 0 {
+		t.Errorf("Expected disabled rules to be skipped, got %v", violations)
+	}
+}
+
+func TestContentViolationLine(t *testing.T) {
+	edits := []Edit{{Path: "a.go", Old: "package a\n", New: "package a\n\nfunc f() {\n\tpanic(\"TODO\")\n}\n"}}
+	violations := CheckEdits(config.ContentConfig{}, "", edits)
+	if len(violations) != 1 || violations[0].Line != `panic("TODO")` {
+		t.Fatalf("Expected the offending line, got %v", violations)
+	}
+	if got := violations[0].String(); !strings.Contains(got, "a.go") || !strings.Contains(got, "todo-panic") {
+		t.Errorf("Expected path and rule in %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package policy
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// Context is the environment a command would run in
+type Context struct {
+	KubeContext string            // Current kube context, empty when there's no kubeconfig
+	AWSProfile  string            // Active AWS profile, "default" when none is selected
+	DotEnv      map[string]string // Values from the project's .env files
+	lookupEnv   func(string) (string, bool)
+}
+
+// dotEnvFiles are read in order, later files overriding earlier ones
+var dotEnvFiles = []string{".env", ".env.local"}
+
+// LoadContext reads the context for commands run from dir: the kube context
+// from $KUBECONFIG or ~/.kube/config, the AWS profile from the environment, and
+// the .env files at the project root and in dir
+func LoadContext(dir string) *Context {
+	ctx := &Context{
+		KubeContext: currentKubeContext(),
+		AWSProfile:  "default",
+		DotEnv:      make(map[string]string),
+		lookupEnv:   os.LookupEnv,
+	}
+	for _, name := range []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE"} {
+		if profile := os.Getenv(name); profile != "" {
+			ctx.AWSProfile = profile
+			break
+		}
+	}
+
+	dirs := []string{state.ProjectRoot(dir)}
+	if abs, err := filepath.Abs(dir); err == nil && abs != dirs[0] {
+		dirs = append(dirs, abs)
+	}
+	for _, d := range dirs {
+		for _, name := range dotEnvFiles {
+			for k, v := range parseDotEnv(filepath.Join(d, name)) {
+				ctx.DotEnv[k] = v
+			}
+		}
+	}
+	return ctx
+}
+
+// lookup resolves a variable the way the command would see it: the process
+// environment first, then .env files, since tools commonly load those themselves
+func (c *Context) lookup(name string) (string, bool) {
+	if c.lookupEnv != nil {
+		if value, ok := c.lookupEnv(name); ok {
+			return value, true
+		}
+	}
+	value, ok := c.DotEnv[name]
+	return value, ok
+}
+
+// currentKubeContext returns current-context from the first kubeconfig that sets
+// it, following kubectl's $KUBECONFIG merge order
+func currentKubeContext() string {
+	var paths []string
+	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+		paths = filepath.SplitList(kubeconfig)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = []string{filepath.Join(home, ".kube", "config")}
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var config struct {
+			CurrentContext string `yaml:"current-context"`
+		}
+		if yaml.Unmarshal(data, &config) == nil && config.CurrentContext != "" {
+			return config.CurrentContext
+		}
+	}
+	return ""
+}
+
+// parseDotEnv reads KEY=value lines, ignoring comments, `export` prefixes, and
+// surrounding quotes. Missing files yield nothing.
+func parseDotEnv(path string) map[string]string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values
+}
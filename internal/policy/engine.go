@@ -0,0 +1,282 @@
+package policy
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/guardrails"
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/shell"
+)
+
+// Policy engines
+const (
+	EngineOPA  = "opa"
+	EngineExec = "exec"
+)
+
+// DefaultQuery is the Rego query of user policies' decisions
+const DefaultQuery = "data.claude_hooks.decisions"
+
+// builtinQuery is evaluated when no policy paths are configured
+const builtinQuery = "data.claude_hooks.builtin.decisions"
+
+//go:embed bundle
+var bundle embed.FS
+
+// Input is what a policy decides on. Only the fields of its hook are set.
+type Input struct {
+	Hook    string `json:"hook"` // pre-bash, pre-edit, or stop
+	Tool    string `json:"tool,omitempty"`
+	Cwd     string `json:"cwd,omitempty"`
+	Project string `json:"project,omitempty"`
+	Session string `json:"session,omitempty"`
+	Profile string `json:"profile,omitempty"`
+	// Agent is the subagent calling the tool; nil for the main agent
+	Agent *AgentInput `json:"agent,omitempty"`
+	// Command is the command line of pre-bash
+	Command *CommandInput `json:"command,omitempty"`
+	// Edits are the changes pre-edit is asked about
+	Edits []Edit `json:"edits,omitempty"`
+	// Files are the files changed in the working tree, at stop
+	Files []string `json:"files,omitempty"`
+	// State is what the session did so far, when its budget is tracked
+	State *guardrails.Counters `json:"session_state,omitempty"`
+}
+
+// AgentInput identifies a subagent
+type AgentInput struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// CommandInput is a command line and the commands it runs
+type CommandInput struct {
+	Text     string          `json:"text"`
+	Commands []CommandRunner `json:"commands"`
+}
+
+// CommandRunner is one command a command line runs, as shell.Split finds it
+type CommandRunner struct {
+	Text        string   `json:"text"`
+	Executable  string   `json:"executable"` // Base name, lower-case, without wrappers like sudo
+	Args        []string `json:"args"`
+	Assignments []string `json:"assignments"` // NAME=value prefixes
+}
+
+// NewCommandInput parses command into the commands it runs, including those
+// in substitutions, subshells, and sh -c scripts. A command behind a wrapper
+// like sudo is listed once, as the wrapper's text.
+func NewCommandInput(command string) *CommandInput {
+	in := &CommandInput{Text: command, Commands: []CommandRunner{}}
+	for _, sub := range shell.Split(command) {
+		assignments, words := shell.Unwrap(shell.Words(sub))
+		if len(words) == 0 {
+			continue
+		}
+		c := CommandRunner{
+			Text:        sub,
+			Executable:  strings.ToLower(filepath.Base(words[0])),
+			Args:        append([]string{}, words[1:]...),
+			Assignments: append([]string{}, assignments...),
+		}
+		// shell.Split follows a wrapped command with the command alone
+		if n := len(in.Commands); n > 0 && in.Commands[n-1].Executable == c.Executable && slices.Equal(in.Commands[n-1].Args, c.Args) {
+			continue
+		}
+		in.Commands = append(in.Commands, c)
+	}
+	return in
+}
+
+// Decision is what a policy decided
+type Decision struct {
+	Decision string `json:"decision"` // deny, ask, block, or allow
+	Rule     string `json:"rule"`
+	Reason   string `json:"reason"`
+}
+
+// Applies reports whether cfg has policies decide in hook
+func Applies(cfg config.PolicyConfig, hook string) bool {
+	return cfg.Engine != "" && (len(cfg.Hooks) == 0 || slices.Contains(cfg.Hooks, hook))
+}
+
+// Decide evaluates the policies of cfg, found relative to dir, on in and
+// returns the decision that stops the most: a deny or block, then an ask.
+// Allows and no decisions return nil; policies add to the built-in checks
+// and can't waive them.
+func Decide(cfg config.PolicyConfig, content config.ContentConfig, dir string, in Input) (*Decision, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("encoding policy input: %w", err)
+	}
+
+	var out []byte
+	switch cfg.Engine {
+	case EngineOPA:
+		out, err = evalOPA(cfg, content, dir, data)
+	case EngineExec:
+		out, err = evalExec(cfg, dir, data)
+	default:
+		return nil, fmt.Errorf("unknown policy engine %q (want %s or %s)", cfg.Engine, EngineOPA, EngineExec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decisions, err := parseDecisions(out)
+	if err != nil {
+		return nil, err
+	}
+	var decided *Decision
+	for i, d := range decisions {
+		d.Decision = strings.ToLower(d.Decision)
+		switch d.Decision {
+		case Deny, "block":
+			return &decisions[i], nil
+		case Ask:
+			if decided == nil {
+				decided = &decisions[i]
+			}
+		case Allow, "":
+		default:
+			return nil, fmt.Errorf("policy rule %s decided %q (want deny, ask, block, or allow)", d.Rule, d.Decision)
+		}
+	}
+	return decided, nil
+}
+
+// evalOPA runs opa eval with the built-in bundle and cfg's paths
+func evalOPA(cfg config.PolicyConfig, content config.ContentConfig, dir string, input []byte) ([]byte, error) {
+	builtin, err := os.MkdirTemp("", "claude-hooks-policy-")
+	if err != nil {
+		return nil, fmt.Errorf("writing the built-in policy bundle: %w", err)
+	}
+	defer os.RemoveAll(builtin)
+	if err := WriteBundle(builtin, content); err != nil {
+		return nil, err
+	}
+
+	query := cfg.Query
+	switch {
+	case len(cfg.Paths) == 0:
+		query = builtinQuery
+	case query == "":
+		query = DefaultQuery
+	}
+	args := []string{"eval", "--format", "json", "--stdin-input", "--data", builtin}
+	for _, p := range cfg.Paths {
+		args = append(args, "--data", p)
+	}
+	args = append(args, query)
+
+	out, err := run(proc.Command("opa", args...), dir, input)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Result []struct {
+			Expressions []struct {
+				Value json.RawMessage `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("reading opa output: %w", err)
+	}
+	if len(result.Result) == 0 || len(result.Result[0].Expressions) == 0 {
+		return nil, nil // Undefined: nothing was decided
+	}
+	return result.Result[0].Expressions[0].Value, nil
+}
+
+// evalExec runs cfg's command with the input on stdin
+func evalExec(cfg config.PolicyConfig, dir string, input []byte) ([]byte, error) {
+	if len(cfg.Command) == 0 {
+		return nil, errors.New("policy.command is empty")
+	}
+	return run(proc.Command(cfg.Command[0], cfg.Command[1:]...), dir, input)
+}
+
+func run(cmd *exec.Cmd, dir string, input []byte) ([]byte, error) {
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("evaluating policies with %s: %w: %s", filepath.Base(cmd.Args[0]), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// parseDecisions reads one decision, a list of them, or nothing
+func parseDecisions(out []byte) ([]Decision, error) {
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 || string(out) == "null" {
+		return nil, nil
+	}
+	var decisions []Decision
+	if out[0] != '[' {
+		out = append(append([]byte{'['}, out...), ']')
+	}
+	if err := json.Unmarshal(out, &decisions); err != nil {
+		return nil, fmt.Errorf("reading policy decisions: %w", err)
+	}
+	return decisions, nil
+}
+
+// WriteBundle writes the built-in rules as a Rego bundle to dir: the rules,
+// and the content rules content doesn't disable as their data. Debug output
+// rules are left out: their exemptions for tests, entry points, and
+// content.output_paths are only checked in Go, by CheckEdits.
+func WriteBundle(dir string, content config.ContentConfig) error {
+	err := fs.WalkDir(bundle, "bundle", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := bundle.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(path, "bundle/")))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0o644)
+	})
+	if err != nil {
+		return fmt.Errorf("writing the built-in policy bundle: %w", err)
+	}
+
+	type rule struct {
+		Name       string   `json:"name"`
+		Extensions []string `json:"extensions"`
+		Pattern    string   `json:"pattern"`
+		Reason     string   `json:"reason"`
+	}
+	rules := []rule{}
+	for _, r := range ContentRules {
+		if !r.Output && !slices.Contains(content.Disabled, r.Name) {
+			rules = append(rules, rule{Name: r.Name, Extensions: append([]string{}, r.Extensions...), Pattern: r.Pattern.String(), Reason: r.Reason})
+		}
+	}
+	data, err := json.MarshalIndent(map[string]any{"content_rules": rules}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding the built-in content rules: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "claude_hooks", "builtin", "data.json"), data, 0o644); err != nil {
+		return fmt.Errorf("writing the built-in policy bundle: %w", err)
+	}
+	return nil
+}
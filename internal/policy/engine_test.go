@@ -0,0 +1,167 @@
+package policy_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/policy"
+	"github.com/brianleishman/claude-hooks/pkg/hooktest"
+)
+
+func TestNewCommandInput(t *testing.T) {
+	in := policy.NewCommandInput(`cd api && sudo PGHOST=prod /usr/bin/psql -c 'select 1' | tee out`)
+	var executables []string
+	for _, c := range in.Commands {
+		executables = append(executables, c.Executable)
+	}
+	if !slices.Equal(executables, []string{"cd", "psql", "tee"}) {
+		t.Fatalf("Executables = %q", executables)
+	}
+	psql := in.Commands[1]
+	if !slices.Equal(psql.Args, []string{"-c", "select 1"}) || !slices.Equal(psql.Assignments, []string{"PGHOST=prod"}) {
+		t.Errorf("psql = %+v", psql)
+	}
+}
+
+func TestDecideExec(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.json")
+	cfg := config.PolicyConfig{Engine: policy.EngineExec, Command: []string{"sh", "-c", `cat > input.json; echo '[{"decision":"ask","rule":"a"},{"decision":"deny","rule":"d","reason":"no"}]'`}}
+
+	d, err := policy.Decide(cfg, config.ContentConfig{}, dir, policy.Input{Hook: "pre-bash", Command: policy.NewCommandInput("rm -rf /")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d == nil || d.Decision != policy.Deny || d.Rule != "d" || d.Reason != "no" {
+		t.Errorf("Decide = %+v, want the deny over the ask", d)
+	}
+	data, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got policy.Input
+	if err := json.Unmarshal(data, &got); err != nil || got.Hook != "pre-bash" || got.Command.Commands[0].Executable != "rm" {
+		t.Errorf("The command read %s, %v", data, err)
+	}
+
+	cfg.Command = []string{"sh", "-c", `echo '{"decision":"allow","rule":"ok"}'`}
+	if d, err := policy.Decide(cfg, config.ContentConfig{}, dir, policy.Input{Hook: "stop"}); d != nil || err != nil {
+		t.Errorf("An allow = %+v, %v, want no decision", d, err)
+	}
+	cfg.Command = []string{"sh", "-c", `echo '{"decision":"maybe"}'`}
+	if _, err := policy.Decide(cfg, config.ContentConfig{}, dir, policy.Input{Hook: "stop"}); err == nil {
+		t.Error("An unknown decision should fail")
+	}
+}
+
+func TestDecideOPA(t *testing.T) {
+	opa := hooktest.FakeTool(t, "opa", `echo '{"result":[{"expressions":[{"value":[{"decision":"deny","rule":"mysql-cli","reason":"MySQL"}]}]}]}'`)
+	dir := t.TempDir()
+
+	cfg := config.PolicyConfig{Engine: policy.EngineOPA}
+	d, err := policy.Decide(cfg, config.ContentConfig{}, dir, policy.Input{Hook: "pre-bash", Command: policy.NewCommandInput("mysql")})
+	if err != nil || d == nil || d.Rule != "mysql-cli" {
+		t.Fatalf("Decide = %+v, %v", d, err)
+	}
+
+	cfg.Paths = []string{"policies"}
+	if _, err := policy.Decide(cfg, config.ContentConfig{}, dir, policy.Input{Hook: "pre-bash"}); err != nil {
+		t.Fatal(err)
+	}
+	calls := hooktest.Calls(t, opa)
+	if len(calls) != 2 || !strings.HasSuffix(calls[0], "claude_hooks.builtin.decisions") || !strings.HasSuffix(calls[1], "--data policies "+policy.DefaultQuery) {
+		t.Errorf("Expected the built-in query alone, then the policies', got %q", calls)
+	}
+
+	hooktest.FakeTool(t, "opa", `echo '{}'`)
+	if d, err := policy.Decide(cfg, config.ContentConfig{}, dir, policy.Input{Hook: "pre-bash"}); d != nil || err != nil {
+		t.Errorf("An undefined result = %+v, %v, want no decision", d, err)
+	}
+}
+
+// TestDecideOPADebugOutput runs a test file's fmt.Println through the
+// built-in bundle, with a stub opa denying debug-output when the bundle has
+// it, and expects it allowed as CheckEdits allows it
+func TestDecideOPADebugOutput(t *testing.T) {
+	hooktest.FakeTool(t, "opa", `cat > /dev/null
+if grep -q '"debug-output"' "$5/claude_hooks/builtin/data.json"; then
+  echo '{"result":[{"expressions":[{"value":[{"decision":"deny","rule":"content:debug-output","reason":"debug output"}]}]}]}'
+else
+  echo '{}'
+fi`)
+	dir := t.TempDir()
+
+	edit := policy.Edit{Path: filepath.Join(dir, "a_test.go"), New: "fmt.Println(got)\n"}
+	if v := policy.CheckEdits(config.ContentConfig{Enabled: true}, dir, []policy.Edit{edit}); len(v) != 0 {
+		t.Fatalf("CheckEdits = %v, want a test's output allowed", v)
+	}
+	d, err := policy.Decide(config.PolicyConfig{Engine: policy.EngineOPA}, config.ContentConfig{Enabled: true}, dir, policy.Input{Hook: "pre-edit", Edits: []policy.Edit{edit}})
+	if err != nil || d != nil {
+		t.Errorf("Decide = %+v, %v, want a test's output allowed", d, err)
+	}
+}
+
+func TestWriteBundle(t *testing.T) {
+	dir := t.TempDir()
+	if err := policy.WriteBundle(dir, config.ContentConfig{Disabled: []string{"lint-suppression"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "claude_hooks", "builtin", "builtin.rego")); err != nil {
+		t.Error(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "claude_hooks", "builtin", "data.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"todo-panic"`) || strings.Contains(string(data), "lint-suppression") || strings.Contains(string(data), "debug-output") {
+		t.Errorf("data.json should have the enabled content rules, without debug-output:\n%s", data)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	dir := t.TempDir()
+	script := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Current: deny mysql. Candidate: deny mysql and ask about terraform, allow the rest.
+	script("current.sh", `grep -q '"executable":"mysql"' && echo '{"decision":"deny","rule":"mysql"}'; true`)
+	script("candidate.sh", `in=$(cat); case "$in" in *'"executable":"terraform"'*) echo '{"decision":"ask","rule":"tf"}';; esac`)
+	if err := os.WriteFile(filepath.Join(dir, "candidate.yaml"), []byte("policy:\n  engine: exec\n  command: [./candidate.sh]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	current := policy.Policies{Config: config.PolicyConfig{Engine: policy.EngineExec, Command: []string{"./current.sh"}}, Dir: dir}
+	candidate, err := policy.Candidate(current, filepath.Join(dir, "candidate.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var corpus strings.Builder
+	if err := policy.WriteCorpus(&corpus, []string{"ls", "mysql -e 'show tables'", "terraform apply", "cat <<EOF\nx\nEOF"}); err != nil {
+		t.Fatal(err)
+	}
+	commands, err := policy.ReadCorpus(strings.NewReader("# from the audit log\n\n" + corpus.String()))
+	if err != nil || len(commands) != 4 || commands[3] != "cat <<EOF\nx\nEOF" {
+		t.Fatalf("ReadCorpus = %q, %v", commands, err)
+	}
+
+	changes, err := policy.Simulate(current, candidate, commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, c := range changes {
+		kinds = append(kinds, c.Kind()+" "+c.Command)
+	}
+	if !slices.Equal(kinds, []string{"allowed mysql -e 'show tables'", "blocked terraform apply"}) {
+		t.Errorf("Changes = %q", kinds)
+	}
+}
@@ -0,0 +1,304 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/shell"
+)
+
+// Decisions a rule can make
+const (
+	Deny  = "deny"
+	Ask   = "ask"
+	Allow = "allow"
+)
+
+// Verdict is the decision of the rule that matched a command
+type Verdict struct {
+	Rule     config.CommandRule
+	Decision string
+	// Matched describes the conditions that matched, without the values, which
+	// may be credentials
+	Matched []string
+}
+
+// Name identifies the rule in messages and the audit log
+func (v *Verdict) Name() string {
+	if v.Rule.Name != "" {
+		return v.Rule.Name
+	}
+	return strings.Join(v.Rule.Commands, ",")
+}
+
+// Evaluate returns the verdict of the first rule matching command (a single
+// command, not a pipeline or list), or nil when none does. Invalid rules are
+// skipped and reported in the error, which can accompany a verdict.
+func Evaluate(rules []config.CommandRule, command string, ctx *Context) (*Verdict, error) {
+	inv := parseInvocation(command, ctx.lookup)
+	if inv.executable == "" {
+		return nil, nil
+	}
+
+	var errs []error
+	for i, rule := range rules {
+		matched, err := inv.match(rule, ctx)
+		if err != nil {
+			name := rule.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			errs = append(errs, fmt.Errorf("bash rule %s: %w", name, err))
+			continue
+		}
+		if matched == nil {
+			continue
+		}
+
+		decision := strings.ToLower(rule.Decision)
+		if decision == "" {
+			decision = Deny
+		}
+		return &Verdict{Rule: rule, Decision: decision, Matched: matched}, errors.Join(errs...)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// invocation is a command split into what it runs and the environment it runs in
+type invocation struct {
+	executable  string
+	text        string            // The executable and its arguments after $VAR expansion
+	inline      map[string]string // NAME=value prefixes
+	kubeContext string            // From --context or --kube-context
+	awsProfile  string            // From --profile
+}
+
+func parseInvocation(command string, lookup func(string) (string, bool)) invocation {
+	inv := invocation{inline: make(map[string]string)}
+	assignments, ws := shell.Unwrap(splitWords(command, lookup))
+	for _, a := range assignments {
+		name, value, _ := strings.Cut(a, "=")
+		inv.inline[strings.TrimSuffix(name, "+")] = value
+	}
+	if len(ws) == 0 {
+		return inv
+	}
+	inv.executable = strings.ToLower(filepath.Base(ws[0]))
+	inv.text = strings.Join(ws, " ")
+
+	args := ws[1:]
+	for i, arg := range args {
+		for flag, dst := range map[string]*string{"--context": &inv.kubeContext, "--kube-context": &inv.kubeContext, "--profile": &inv.awsProfile} {
+			if value, ok := strings.CutPrefix(arg, flag+"="); ok {
+				*dst = value
+			} else if arg == flag && i+1 < len(args) {
+				*dst = args[i+1]
+			}
+		}
+	}
+	return inv
+}
+
+// match returns the conditions of rule that the invocation matches, or nil if
+// any condition doesn't
+func (inv invocation) match(rule config.CommandRule, ctx *Context) ([]string, error) {
+	switch strings.ToLower(rule.Decision) {
+	case "", Deny, Ask, Allow:
+	default:
+		return nil, fmt.Errorf("invalid decision %q (want deny, ask, or allow)", rule.Decision)
+	}
+	// A rule without conditions would match every command, which is almost
+	// certainly a mistake in the config rather than intent
+	if len(rule.Commands) == 0 && rule.Match == "" && rule.KubeContext == "" && rule.AWSProfile == "" && len(rule.Env) == 0 {
+		return nil, nil
+	}
+
+	var matched []string
+
+	if len(rule.Commands) > 0 {
+		if !slices.ContainsFunc(rule.Commands, func(c string) bool { return strings.EqualFold(c, inv.executable) }) {
+			return nil, nil
+		}
+		matched = append(matched, "runs "+inv.executable)
+	}
+
+	if rule.Match != "" {
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match: %w", err)
+		}
+		if !re.MatchString(inv.text) {
+			return nil, nil
+		}
+		matched = append(matched, fmt.Sprintf("command matches %q", rule.Match))
+	}
+
+	if rule.KubeContext != "" {
+		kube := firstNonEmpty(inv.kubeContext, ctx.KubeContext)
+		if kube == "" || !globMatch(rule.KubeContext, kube) {
+			return nil, nil
+		}
+		matched = append(matched, fmt.Sprintf("kube context %q", kube))
+	}
+
+	if rule.AWSProfile != "" {
+		profile := firstNonEmpty(inv.awsProfile, inv.inline["AWS_PROFILE"], inv.inline["AWS_DEFAULT_PROFILE"], ctx.AWSProfile)
+		if !globMatch(rule.AWSProfile, profile) {
+			return nil, nil
+		}
+		matched = append(matched, fmt.Sprintf("AWS profile %q", profile))
+	}
+
+	names := make([]string, 0, len(rule.Env))
+	for name := range rule.Env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		value, ok := inv.inline[name]
+		if !ok {
+			value, ok = ctx.lookup(name)
+		}
+		if !ok || !globMatch(rule.Env[name], value) {
+			return nil, nil
+		}
+		matched = append(matched, fmt.Sprintf("%s matches %q", name, rule.Env[name]))
+	}
+
+	return matched, nil
+}
+
+// splitWords splits a command into shell words, honoring quotes and expanding
+// $NAME and ${NAME} outside single quotes. Substitutions and globs aren't run;
+// the policy only needs the text the command would see. An unset $IFS splits
+// words like the whitespace it defaults to.
+func splitWords(command string, lookup func(string) (string, bool)) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote byte
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '\\' && i+1 < len(command) && (quote == 0 || strings.IndexByte("$`\"\\", command[i+1]) >= 0):
+			i++
+			word.WriteByte(command[i])
+			inWord = true
+		case c == '\'' || c == '"':
+			if quote == c {
+				quote = 0
+			} else if quote == 0 {
+				quote = c
+			} else {
+				word.WriteByte(c)
+			}
+			inWord = true
+		case c == '$' && i+1 < len(command):
+			name, n := variableAt(command[i+1:])
+			if name == "" {
+				word.WriteByte(c)
+				inWord = true
+			} else if value, ok := lookup(name); ok || name != "IFS" || quote != 0 {
+				word.WriteString(value)
+				i += n
+				inWord = true
+			} else {
+				i += n
+				if inWord {
+					words = append(words, word.String())
+					word.Reset()
+					inWord = false
+				}
+			}
+		case (c == ' ' || c == '\t' || c == '\n') && quote == 0:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// variableAt parses the variable name at the start of s (after a $), returning
+// it and how many bytes it spans
+func variableAt(s string) (string, int) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 || !isName(s[1:end]) {
+			return "", 0
+		}
+		return s[1:end], end + 1
+	}
+	n := 0
+	for n < len(s) && (s[n] == '_' || isAlnum(s[n])) {
+		n++
+	}
+	if n == 0 || (s[0] >= '0' && s[0] <= '9') {
+		return "", 0
+	}
+	return s[:n], n
+}
+
+func isName(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' && !isAlnum(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// globMatch matches s against a pattern where * matches any run of characters
+// (including /, which kube contexts and ARNs contain) and ? matches one
+func globMatch(pattern, s string) bool {
+	var re strings.Builder
+	re.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+	return regexp.MustCompile(re.String()).MatchString(s)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/shell"
+)
+
+// readOnlyCommands change nothing whatever their arguments, short of a
+// redirection
+var readOnlyCommands = []string{
+	"ls", "cat", "head", "tail", "less", "more", "wc", "grep", "egrep", "fgrep", "rg", "ag", "ack",
+	"file", "stat", "du", "df", "pwd", "cd", "echo", "printf", "which", "whereis", "type", "tree",
+	"diff", "cmp", "comm", "cut", "tr", "uniq", "nl", "od", "hexdump", "xxd", "basename", "dirname",
+	"realpath", "readlink", "date", "true", "false", "test", "[", "jq", "column", "fold", "seq",
+	"sha1sum", "sha256sum", "md5sum", "shasum", "id", "whoami", "uname", "hostname", "ps", "printenv",
+	"sleep", "gofmt", "ldd", "nm", "objdump", "strings", "tac", "rev", "paste", "join", "expand",
+}
+
+// readOnlySubcommands are the subcommands of tools that only read, by tool
+var readOnlySubcommands = map[string][]string{
+	"git":     {"status", "log", "diff", "show", "blame", "grep", "ls-files", "ls-tree", "ls-remote", "rev-parse", "rev-list", "describe", "shortlog", "cat-file", "merge-base", "name-rev", "whatchanged", "check-ignore", "version", "help"},
+	"go":      {"list", "vet", "version", "doc", "env", "test", "help"},
+	"jj":      {"log", "diff", "show", "status", "st", "file", "op", "evolog", "obslog", "help"},
+	"hg":      {"log", "diff", "status", "st", "show", "annotate", "blame", "files", "cat", "summary", "sum", "id", "identify", "branches", "bookmarks", "heads", "help"},
+	"npm":     {"ls", "list", "view", "info", "outdated", "why", "explain", "help"},
+	"kubectl": {"get", "describe", "logs", "explain", "version", "top", "api-resources", "api-versions", "cluster-info"},
+	"docker":  {"ps", "images", "logs", "inspect", "version", "info", "history"},
+	"cargo":   {"check", "tree", "metadata", "search", "version", "help"},
+}
+
+// listingSubcommands only read when given no other words than listFlags and
+// the words listed: git branch lists branches, git branch topic creates one
+var listingSubcommands = map[string]map[string][]string{
+	"git": {"branch": nil, "tag": nil, "remote": {"show", "get-url"}, "stash": {"list", "show"}},
+}
+
+// listFlags are the flags of listingSubcommands that don't change anything
+var listFlags = []string{"-a", "--all", "-r", "--remotes", "-v", "-vv", "--verbose", "-l", "--list", "--show-current", "--merged", "--no-merged", "--no-color", "-n"}
+
+// writingFlags make otherwise read-only commands write, by command
+var writingFlags = map[string][]string{
+	"find": {"-delete", "-exec", "-execdir", "-ok", "-okdir", "-fprint", "-fprint0", "-fprintf", "-fls"},
+	"sed":  {"-i", "--in-place"},
+	"sort": {"-o", "--output"},
+}
+
+// toolFlags are the flags before a tool's subcommand that take a value
+var toolFlags = []string{"-C", "-R", "--repository", "--git-dir", "--work-tree", "--context", "--namespace"}
+
+// ReadOnly returns the first command the command line runs that could change
+// something, or "" when they all only read: read-only tools like ls and
+// grep, and read-only subcommands like git log or go vet, without redirections
+// to files. Unknown commands count as writing.
+func ReadOnly(command string) string {
+	for _, sub := range shell.Split(command) {
+		if shell.WritesFile(sub) {
+			return sub
+		}
+		_, words := shell.Unwrap(shell.Words(sub))
+		if len(words) > 0 && !readsOnly(strings.ToLower(filepath.Base(words[0])), words[1:]) {
+			return sub
+		}
+	}
+	return ""
+}
+
+func readsOnly(executable string, args []string) bool {
+	if flags, ok := writingFlags[executable]; ok {
+		return !slices.ContainsFunc(args, func(a string) bool {
+			return slices.ContainsFunc(flags, func(flag string) bool {
+				return a == flag || strings.HasPrefix(a, flag+"=") || (flag == "-i" && strings.HasPrefix(a, "-i"))
+			})
+		})
+	}
+	if slices.Contains(readOnlyCommands, executable) {
+		return true
+	}
+
+	subcommands, ok := readOnlySubcommands[executable]
+	if !ok {
+		return false
+	}
+	// The first word that isn't a flag, skipping git -C dir and the like
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "-") {
+		if args[i] == "-c" || args[i] == "--config" {
+			return false // Configuration like core.pager can run anything
+		}
+		if slices.Contains(toolFlags, args[i]) {
+			i++
+		}
+		i++
+	}
+	if i >= len(args) {
+		return true // The tool's version or help
+	}
+	sub, rest := args[i], args[i+1:]
+	if slices.Contains(subcommands, sub) {
+		return !(executable == "go" && sub == "env" && (slices.Contains(rest, "-w") || slices.Contains(rest, "-u"))) &&
+			!slices.ContainsFunc(rest, func(a string) bool { return strings.HasPrefix(a, "--output") })
+	}
+	words, ok := listingSubcommands[executable][sub]
+	if !ok {
+		return false
+	}
+	for _, a := range rest {
+		if !slices.Contains(listFlags, a) && !slices.Contains(words, a) {
+			return false
+		}
+	}
+	return true
+}
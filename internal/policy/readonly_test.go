@@ -0,0 +1,32 @@
+package policy
+
+import "testing"
+
+func TestReadOnly(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string // The sub-command that could write
+	}{
+		{"ls -la && grep -rn TODO . | head", ""},
+		{"git -C repo log --oneline -5", ""},
+		{"git branch -a", ""},
+		{"git stash list", ""},
+		{"go vet ./... && go env GOPATH", ""},
+		{"sudo cat /etc/hosts 2>/dev/null", ""},
+		{"cat a > b", "cat a > b"},
+		{"ls && rm -rf build", "rm -rf build"},
+		{"git branch topic", "git branch topic"},
+		{"git -c core.pager=sh log", "git -c core.pager=sh log"},
+		{"git checkout main", "git checkout main"},
+		{"go env -w GOFLAGS=-mod=mod", "go env -w GOFLAGS=-mod=mod"},
+		{"sed -i s/a/b/ file", "sed -i s/a/b/ file"},
+		{"find . -name '*.tmp' -delete", "find . -name '*.tmp' -delete"},
+		{"echo $(touch x)", "touch x"},
+		{"make", "make"},
+	}
+	for _, tt := range tests {
+		if got := ReadOnly(tt.command); got != tt.want {
+			t.Errorf("ReadOnly(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package policy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+// Policies are a policy configuration with what it's evaluated against: the
+// content rules of the built-in bundle, and the directory its paths and
+// command are relative to
+type Policies struct {
+	Config  config.PolicyConfig
+	Content config.ContentConfig
+	Dir     string
+}
+
+// Decide evaluates the policies on in, deciding nothing without an engine
+func (p Policies) Decide(in Input) (*Decision, error) {
+	if p.Config.Engine == "" {
+		return nil, nil
+	}
+	return Decide(p.Config, p.Content, p.Dir, in)
+}
+
+// Candidate reads the policies to try from path: the policy section of a
+// YAML config file, relative to it, or a Rego file or directory replacing the
+// paths of current, evaluated with opa
+func Candidate(current Policies, path string) (Policies, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return current, err
+	}
+	candidate := current
+	if ext := filepath.Ext(abs); ext == ".yaml" || ext == ".yml" {
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return current, fmt.Errorf("reading candidate policies: %w", err)
+		}
+		var file struct {
+			Policy config.PolicyConfig `yaml:"policy"`
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return current, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if file.Policy.Engine == "" {
+			return current, fmt.Errorf("%s has no policy.engine", path)
+		}
+		candidate.Config, candidate.Dir = file.Policy, filepath.Dir(abs)
+		return candidate, nil
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return current, fmt.Errorf("reading candidate policies: %w", err)
+	}
+	candidate.Config.Engine, candidate.Config.Paths = EngineOPA, []string{abs}
+	return candidate, nil
+}
+
+// Change is a command the candidate policies decide differently
+type Change struct {
+	Command string
+	Before  *Decision // nil when it was allowed
+	After   *Decision // nil when it's allowed
+}
+
+// Kind is "blocked" for a command now denied or asked about, "allowed" for
+// one no longer stopped, and "changed" for one stopped differently
+func (c Change) Kind() string {
+	switch {
+	case c.Before == nil:
+		return "blocked"
+	case c.After == nil:
+		return "allowed"
+	}
+	return "changed"
+}
+
+// Simulate runs the pre-bash commands of a corpus through the current and
+// the candidate policies and returns those they decide differently: another
+// decision, or another rule making it
+func Simulate(current, candidate Policies, commands []string) ([]Change, error) {
+	var changes []Change
+	for _, command := range commands {
+		in := Input{Hook: "pre-bash", Tool: "Bash", Command: NewCommandInput(command)}
+		before, err := current.Decide(in)
+		if err != nil {
+			return changes, fmt.Errorf("current policies on %q: %w", command, err)
+		}
+		after, err := candidate.Decide(in)
+		if err != nil {
+			return changes, fmt.Errorf("candidate policies on %q: %w", command, err)
+		}
+		if !sameDecision(before, after) {
+			changes = append(changes, Change{Command: command, Before: before, After: after})
+		}
+	}
+	return changes, nil
+}
+
+func sameDecision(a, b *Decision) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return normalize(a.Decision) == normalize(b.Decision) && a.Rule == b.Rule
+}
+
+// normalize makes block and deny, which stop the same way, compare equal
+func normalize(decision string) string {
+	if decision = strings.ToLower(decision); decision == "block" {
+		return Deny
+	}
+	return decision
+}
+
+// ReadCorpus reads a corpus of commands: one per line, or a JSON string for
+// a command spanning lines. Blank lines and lines starting with # are skipped.
+func ReadCorpus(r io.Reader) ([]string, error) {
+	var commands []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, `"`):
+			var command string
+			if err := json.Unmarshal([]byte(line), &command); err != nil {
+				return nil, fmt.Errorf("corpus line %d: %w", n, err)
+			}
+			line = command
+		}
+		commands = append(commands, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading corpus: %w", err)
+	}
+	return commands, nil
+}
+
+// WriteCorpus writes commands the way ReadCorpus reads them back
+func WriteCorpus(w io.Writer, commands []string) error {
+	for _, command := range commands {
+		line := command
+		if strings.ContainsAny(command, "\r\n") || strings.HasPrefix(command, `"`) || strings.HasPrefix(command, "#") || strings.TrimSpace(command) != command {
+			data, err := json.Marshal(command)
+			if err != nil {
+				return err
+			}
+			line = string(data)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+// base64Blob matches embedded base64 payloads: data URIs and long unbroken runs
+// of base64 characters, which source code, hashes, and lockfiles don't have
+var base64Blob = regexp.MustCompile(`data:[\w/+.-]+;base64,[A-Za-z0-9+/=]{128,}|[A-Za-z0-9+/]{512,}={0,2}`)
+
+// CheckWrite returns the violations of a Write creating path (inside the
+// repository at root) with content: files over the size limit, binary content,
+// and base64 blobs, unless the path is under content.binary_paths
+func CheckWrite(cfg config.ContentConfig, root, file, content string) []ContentViolation {
+	rel, err := filepath.Rel(root, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = file
+	}
+	rel = filepath.ToSlash(rel)
+	if binaryPathAllowed(rel, cfg.BinaryPaths) {
+		return nil
+	}
+
+	var violations []ContentViolation
+	add := func(rule, reason string) {
+		if !slices.Contains(cfg.Disabled, rule) {
+			violations = append(violations, ContentViolation{Rule: rule, Path: file, Reason: reason})
+		}
+	}
+
+	if cfg.MaxWriteSize > 0 && int64(len(content)) > cfg.MaxWriteSize {
+		add("large-write", fmt.Sprintf("writes %d bytes (limit %d); generate large files with a command and keep artifacts out of the repository", len(content), cfg.MaxWriteSize))
+	}
+	if strings.ContainsRune(content, 0) || !utf8.ValidString(content) {
+		add("binary-write", "writes binary content; binary files belong in content.binary_paths or outside the repository")
+	} else if blob := findBase64Blob(content); blob != "" {
+		add("base64-blob", fmt.Sprintf("embeds a %d character base64 payload; reference the file instead of inlining it", len(blob)))
+	}
+	return violations
+}
+
+// findBase64Blob returns the first base64Blob match that mixes upper and lower
+// case letters and digits, as encoded data does and long repeated runs don't
+func findBase64Blob(content string) string {
+	for _, blob := range base64Blob.FindAllString(content, -1) {
+		if strings.ContainsAny(blob, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") &&
+			strings.ContainsAny(blob, "abcdefghijklmnopqrstuvwxyz") &&
+			strings.ContainsAny(blob, "0123456789") {
+			return blob
+		}
+	}
+	return ""
+}
+
+// binaryPathAllowed reports whether a pattern matches the slash-separated
+// relative path, its base name, or one of its parent directories
+func binaryPathAllowed(rel string, patterns []string) bool {
+	candidates := []string{rel, path.Base(rel)}
+	for dir := path.Dir(rel); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		candidates = append(candidates, dir, path.Base(dir))
+	}
+	for _, pattern := range patterns {
+		for _, c := range candidates {
+			if ok, _ := path.Match(pattern, c); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// minRewriteLines is the smallest file a rewrite is reported for: short files
+// are as easy to review rewritten as edited
+const minRewriteLines = 10
+
+// Rewrite is a Write replacing an existing file with content that changes
+// more of its lines than content.max_rewrite_percent
+type Rewrite struct {
+	Path    string
+	Changed int  // Lines of the old file missing from the new one
+	Lines   int  // Non-blank lines of the old file
+	Block   bool // The old file is at least content.block_rewrite_size bytes
+}
+
+func (r Rewrite) String() string {
+	return fmt.Sprintf("%s: rewrites %d of its %d lines (%d%%); a Write replaces the whole file, so unrelated code can be lost without notice. Use Edit to change only what the task needs (whole-file-rewrite)",
+		r.Path, r.Changed, r.Lines, 100*r.Changed/r.Lines)
+}
+
+// CheckRewrite reports a Write of content over the existing file old that
+// changes more than content.max_rewrite_percent of its non-blank lines.
+// Lines are compared as a multiset, so moved lines don't count as changed.
+func CheckRewrite(cfg config.ContentConfig, file, old, content string) (Rewrite, bool) {
+	if cfg.MaxRewritePercent <= 0 || slices.Contains(cfg.Disabled, "whole-file-rewrite") {
+		return Rewrite{}, false
+	}
+	kept := make(map[string]int)
+	for line := range strings.Lines(content) {
+		if line := strings.TrimSpace(line); line != "" {
+			kept[line]++
+		}
+	}
+	r := Rewrite{Path: file}
+	for line := range strings.Lines(old) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		r.Lines++
+		if kept[line] > 0 {
+			kept[line]--
+		} else {
+			r.Changed++
+		}
+	}
+	if r.Lines < minRewriteLines || 100*r.Changed <= cfg.MaxRewritePercent*r.Lines {
+		return Rewrite{}, false
+	}
+	r.Block = cfg.BlockRewriteSize > 0 && int64(len(old)) >= cfg.BlockRewriteSize
+	return r, true
+}
@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func TestCheckWrite(t *testing.T) {
+	cfg := config.ContentConfig{
+		MaxWriteSize: 1024,
+		BinaryPaths:  []string{"testdata", "assets/*.png"},
+	}
+	blob := strings.Repeat("QUJDZGVmZ2hpams0NTY3", 40)
+
+	tests := []struct {
+		name, file, content string
+		want                []string
+	}{
+		{"small source", "/repo/main.go", "package main\n", nil},
+		{"too large", "/repo/dump.json", strings.Repeat("x", 2048), []string{"large-write"}},
+		{"binary", "/repo/image.png", "\x89PNG\r\n\x1a\n\x00\x00", []string{"binary-write"}},
+		{"invalid utf-8", "/repo/data.bin", "\xff\xfe\xfd", []string{"binary-write"}},
+		{"base64 blob", "/repo/logo.ts", "export const logo = \"" + blob + "\"\n", []string{"base64-blob"}},
+		{"data uri", "/repo/style.css", "a { background: url(data:image/png;base64," + strings.Repeat("iVBORw0KGgo", 15) + ") }", []string{"base64-blob"}},
+		{"repeated characters", "/repo/a.txt", strings.Repeat("a", 600), nil},
+		{"short base64", "/repo/a.go", `const token = "c29tZSBzaG9ydCB2YWx1ZQ=="`, nil},
+		{"allowed directory", "/repo/pkg/testdata/golden.bin", "\x00\x01" + strings.Repeat("x", 2048), nil},
+		{"allowed glob", "/repo/assets/logo.png", "\x89PNG\x00", nil},
+		{"glob doesn't cover other files", "/repo/assets/logo.bin", "\x00", []string{"binary-write"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, v := range CheckWrite(cfg, "/repo", tt.file, tt.content) {
+			got = append(got, v.Rule)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCheckWriteDisabledRules(t *testing.T) {
+	cfg := config.ContentConfig{MaxWriteSize: 10, Disabled: []string{"large-write", "binary-write"}}
+	if violations := CheckWrite(cfg, "/repo", "/repo/a.bin", "\x00"+strings.Repeat("x", 100)); len(violations) != 0 {
+		t.Errorf("Expected disabled rules to be skipped, got %v", violations)
+	}
+}
+
+func TestCheckRewrite(t *testing.T) {
+	cfg := config.Default().Content
+	var old strings.Builder
+	for i := range 20 {
+		fmt.Fprintf(&old, "line %d\n", i)
+	}
+
+	// Reordering and a few changes are fine
+	moved := strings.Replace(old.String(), "line 0\n", "", 1) + "line 0\nline 20\n"
+	if r, ok := CheckRewrite(cfg, "a.txt", old.String(), moved); ok {
+		t.Errorf("Expected moved lines not to count as a rewrite, got %s", r)
+	}
+
+	rewritten := strings.ReplaceAll(old.String(), "line 1", "row 1") // lines 1 and 10-19
+	r, ok := CheckRewrite(cfg, "a.txt", old.String(), rewritten)
+	if !ok || r.Changed != 11 || r.Lines != 20 || r.Block {
+		t.Fatalf("Expected a warned rewrite of 11 of 20 lines, got %+v (%v)", r, ok)
+	}
+	if !strings.Contains(r.String(), "rewrites 11 of its 20 lines (55%)") {
+		t.Errorf("Unexpected message %q", r.String())
+	}
+
+	cfg.BlockRewriteSize = int64(old.Len())
+	if r, _ := CheckRewrite(cfg, "a.txt", old.String(), rewritten); !r.Block {
+		t.Errorf("Expected a file at block_rewrite_size to be blocked, got %+v", r)
+	}
+	if _, ok := CheckRewrite(cfg, "a.txt", "a\nb\n", "c\nd\n"); ok {
+		t.Error("Expected short files to be left alone")
+	}
+	cfg.Disabled = []string{"whole-file-rewrite"}
+	if _, ok := CheckRewrite(cfg, "a.txt", old.String(), rewritten); ok {
+		t.Error("Expected the disabled rule not to report")
+	}
+}
@@ -0,0 +1,127 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// Environment variables limiting the tools hooks spawn, so a runaway go test or
+// tsc can't freeze the machine during a session
+const (
+	NiceEnv        = "CLAUDE_HOOKS_NICE"         // Scheduling niceness, default 10; 0 disables
+	IONiceEnv      = "CLAUDE_HOOKS_IONICE"       // I/O class on Linux: best-effort (default), idle, or off
+	MaxProcsEnv    = "CLAUDE_HOOKS_MAX_PROCS"    // CPUs Go tools may use (GOMAXPROCS and go -p); unset means all
+	MemoryLimitEnv = "CLAUDE_HOOKS_MEMORY_LIMIT" // Soft heap limit for Go and Node tools, e.g. 2GB; unset means none
+)
+
+const defaultNice = 10
+
+// Limits are the resource limits applied to spawned tools
+type Limits struct {
+	Nice        int    // Added niceness; 0 leaves priority alone
+	IOClass     string // "best-effort", "idle", or "" for no change
+	MaxProcs    int    // 0 means unlimited
+	MemoryBytes int64  // 0 means unlimited
+}
+
+// LimitsFromEnv reads Limits from the environment, ignoring invalid values
+func LimitsFromEnv() Limits {
+	limits := Limits{Nice: defaultNice, IOClass: "best-effort"}
+
+	if value := os.Getenv(NiceEnv); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 19 {
+			limits.Nice = n
+		}
+	}
+	switch value := strings.ToLower(os.Getenv(IONiceEnv)); value {
+	case "best-effort", "idle":
+		limits.IOClass = value
+	case "off", "none", "0":
+		limits.IOClass = ""
+	}
+	if n, err := strconv.Atoi(os.Getenv(MaxProcsEnv)); err == nil && n > 0 {
+		limits.MaxProcs = n
+	}
+	if value := os.Getenv(MemoryLimitEnv); value != "" {
+		if n, err := state.ParseSize(value); err == nil {
+			limits.MemoryBytes = n
+		}
+	}
+	return limits
+}
+
+var (
+	nicePath   = sync.OnceValue(func() string { path, _ := exec.LookPath("nice"); return path })
+	ionicePath = sync.OnceValue(func() string { path, _ := exec.LookPath("ionice"); return path })
+)
+
+// wrap prefixes name and args with nice and ionice where those limits are set and
+// available. Commands that can't be found are left alone, so callers still see
+// exec's "executable file not found" error.
+func (l Limits) wrap(name string, args []string) (string, []string) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return name, args
+	}
+
+	argv := append([]string{path}, args...)
+	if l.IOClass != "" && ionicePath() != "" {
+		// -t runs the command anyway where the priority can't be set (e.g. containers)
+		class := []string{"-t", "-c", "2", "-n", "7"}
+		if l.IOClass == "idle" {
+			class = []string{"-t", "-c", "3"}
+		}
+		argv = append(append([]string{ionicePath()}, class...), argv...)
+	}
+	if l.Nice > 0 && nicePath() != "" {
+		argv = append([]string{nicePath(), "-n", strconv.Itoa(l.Nice)}, argv...)
+	}
+	if argv[0] == path {
+		return name, args
+	}
+	return argv[0], argv[1:]
+}
+
+// env returns environ plus the variables Go and Node tools read their limits
+// from. Values the user already set win, and nil is returned when nothing needs
+// adding so the child simply inherits the environment.
+func (l Limits) env(environ []string) []string {
+	lookup := func(key string) (string, bool) {
+		for _, kv := range slices.Backward(environ) {
+			if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+				return v, true
+			}
+		}
+		return "", false
+	}
+
+	var extra []string
+	if l.MaxProcs > 0 {
+		if _, ok := lookup("GOMAXPROCS"); !ok {
+			extra = append(extra, fmt.Sprintf("GOMAXPROCS=%d", l.MaxProcs))
+		}
+		if goflags, _ := lookup("GOFLAGS"); !strings.Contains(goflags, "-p=") {
+			extra = append(extra, "GOFLAGS="+strings.TrimSpace(fmt.Sprintf("%s -p=%d", goflags, l.MaxProcs)))
+		}
+	}
+	if l.MemoryBytes > 0 {
+		if _, ok := lookup("GOMEMLIMIT"); !ok {
+			extra = append(extra, fmt.Sprintf("GOMEMLIMIT=%d", l.MemoryBytes))
+		}
+		if nodeOptions, _ := lookup("NODE_OPTIONS"); !strings.Contains(nodeOptions, "--max-old-space-size") {
+			extra = append(extra, "NODE_OPTIONS="+strings.TrimSpace(fmt.Sprintf("%s --max-old-space-size=%d", nodeOptions, l.MemoryBytes>>20)))
+		}
+	}
+
+	if len(extra) == 0 {
+		return nil
+	}
+	return append(slices.Clone(environ), extra...)
+}
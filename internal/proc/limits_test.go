@@ -0,0 +1,49 @@
+package proc
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLimitsFromEnv(t *testing.T) {
+	t.Setenv(NiceEnv, "")
+	t.Setenv(IONiceEnv, "")
+	t.Setenv(MaxProcsEnv, "")
+	t.Setenv(MemoryLimitEnv, "")
+	if got := LimitsFromEnv(); got != (Limits{Nice: defaultNice, IOClass: "best-effort"}) {
+		t.Errorf("Expected default limits, got %+v", got)
+	}
+
+	t.Setenv(NiceEnv, "0")
+	t.Setenv(IONiceEnv, "off")
+	t.Setenv(MaxProcsEnv, "2")
+	t.Setenv(MemoryLimitEnv, "1GB")
+	want := Limits{MaxProcs: 2, MemoryBytes: 1 << 30}
+	if got := LimitsFromEnv(); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestLimitsEnv(t *testing.T) {
+	if env := (Limits{Nice: 10}).env([]string{"PATH=/bin"}); env != nil {
+		t.Errorf("Expected no environment override without CPU or memory limits, got %v", env)
+	}
+
+	limits := Limits{MaxProcs: 2, MemoryBytes: 512 << 20}
+	env := limits.env([]string{"PATH=/bin", "GOFLAGS=-mod=mod", "GOMEMLIMIT=1GiB"})
+	for _, want := range []string{"GOMAXPROCS=2", "GOFLAGS=-mod=mod -p=2", "NODE_OPTIONS=--max-old-space-size=512"} {
+		if !slices.Contains(env, want) {
+			t.Errorf("Expected %s in %v", want, env)
+		}
+	}
+	if slices.Contains(env, "GOMEMLIMIT=536870912") {
+		t.Errorf("Expected the user's GOMEMLIMIT to win, got %v", env)
+	}
+}
+
+func TestLimitsWrapMissingCommand(t *testing.T) {
+	name, args := Limits{Nice: 10, IOClass: "idle"}.wrap("definitely-not-a-real-command", []string{"-x"})
+	if name != "definitely-not-a-real-command" || !slices.Equal(args, []string{"-x"}) {
+		t.Errorf("Expected a missing command to be left unwrapped, got %s %v", name, args)
+	}
+}
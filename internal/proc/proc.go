@@ -0,0 +1,95 @@
+package proc
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// killGrace is how long a cancelled child's process group gets between SIGTERM
+// and SIGKILL
+const killGrace = 2 * time.Second
+
+var (
+	ctx, cancel = context.WithCancel(context.Background())
+	interrupted atomic.Bool
+)
+
+// Context is cancelled when the hook is interrupted. Work that should stop when
+// Claude aborts the tool call derives its context from this one.
+func Context() context.Context {
+	return ctx
+}
+
+// Interrupted reports whether a SIGINT or SIGTERM has been received
+func Interrupted() bool {
+	return interrupted.Load()
+}
+
+// HandleInterrupts cancels Context on SIGINT or SIGTERM, which kills every child
+// started with Command. The caller is expected to notice Interrupted and exit on
+// its own; if it hasn't within timeout (e.g. it is blocked on something that
+// ignores cancellation), onTimeout is called instead.
+func HandleInterrupts(timeout time.Duration, onTimeout func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		interrupted.Store(true)
+		cancel()
+		time.Sleep(timeout)
+		onTimeout()
+	}()
+}
+
+// Command is exec.Command bound to Context, and to the current Bound and the
+// command's step timeout. A command they abort records its Timeout.
+func Command(name string, args ...string) *exec.Cmd {
+	stepCtx, cancel := stepContext(StepName(name, args))
+	cmd := CommandContext(stepCtx, name, args...)
+	terminate := cmd.Cancel
+	cmd.Cancel = func() error {
+		defer cancel()
+		if recordTimeout(stepCtx) {
+			killed.Store(cmd, true)
+		}
+		return terminate()
+	}
+	if stepCtx.Err() != nil && recordTimeout(stepCtx) {
+		killed.Store(cmd, true) // Out of time already, so it won't start
+	}
+	return cmd
+}
+
+// killed holds the commands Command killed for running out of time
+var killed sync.Map
+
+// Killed reports whether Command killed cmd for exceeding its step's or the
+// current Bound's limit, or didn't start it for having none left. Its output is cut off, so it says nothing about the
+// edit and shouldn't be reported as a failure.
+func Killed(cmd *exec.Cmd) bool {
+	_, ok := killed.Load(cmd)
+	return ok
+}
+
+// CommandContext is exec.CommandContext, except that the child runs in its own
+// process group and cancellation terminates the whole group, so grandchildren
+// (test binaries under go test, node under npx) don't outlive the hook. The child
+// also runs under LimitsFromEnv.
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	limits := LimitsFromEnv()
+	name, args = limits.wrap(name, args)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = limits.env(os.Environ())
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return terminateGroup(cmd, killGrace)
+	}
+	cmd.WaitDelay = 2 * killGrace
+	return cmd
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package proc
+
+import (
+	"os/exec"
+	"time"
+)
+
+// Process groups are only used on unix; elsewhere only the direct child is killed
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func terminateGroup(cmd *exec.Cmd, grace time.Duration) error {
+	return cmd.Process.Kill()
+}
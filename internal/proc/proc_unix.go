@@ -0,0 +1,29 @@
+//go:build unix
+
+package proc
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// terminateGroup sends SIGTERM to the child's process group, then SIGKILL to
+// whatever is left after grace
+func terminateGroup(cmd *exec.Cmd, grace time.Duration) error {
+	pgid := cmd.Process.Pid
+	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+		return cmd.Process.Kill()
+	}
+	time.AfterFunc(grace, func() {
+		_ = syscall.Kill(-pgid, syscall.SIGKILL)
+	})
+	return nil
+}
@@ -0,0 +1,51 @@
+//go:build unix
+
+package proc
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCommandContextKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The shell prints the pid of a grandchild that would outlive a plain kill
+	cmd := CommandContext(ctx, "sh", "-c", "sleep 30 & echo $!; wait")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read grandchild pid: %v", err)
+	}
+	grandchild, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatalf("Unexpected output %q", line)
+	}
+
+	cancel()
+	if err := cmd.Wait(); err == nil {
+		t.Error("Expected a cancelled command to fail")
+	}
+
+	deadline := time.Now().Add(killGrace * 2)
+	for syscall.Kill(grandchild, 0) == nil {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(grandchild, syscall.SIGKILL)
+			t.Fatal("Expected the grandchild to be killed with its process group")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
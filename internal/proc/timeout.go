@@ -0,0 +1,134 @@
+package proc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timeout is the error of a step aborted for running longer than its limit.
+// Hooks report it apart from failures: a slow suite says nothing about the edit.
+type Timeout struct {
+	Step  string
+	Limit time.Duration
+	// Killed is set when the hook killed the command, cutting its output off,
+	// rather than the tool enforcing its own limit, like go test -timeout
+	Killed bool
+}
+
+func (t *Timeout) Error() string {
+	return fmt.Sprintf("step %s exceeded %s and was aborted", t.Step, t.Limit)
+}
+
+var limits struct {
+	sync.Mutex
+	ctx   context.Context // Bound's, nil outside of one
+	steps map[string]time.Duration
+	hit   []*Timeout
+}
+
+// SetStepTimeouts bounds every command Command starts by the limit of its step,
+// as named by StepName. Steps without a limit run until they finish.
+func SetStepTimeouts(steps map[string]time.Duration) {
+	limits.Lock()
+	defer limits.Unlock()
+	limits.steps = steps
+}
+
+// StepTimeout returns the limit of step, or 0 when it has none
+func StepTimeout(step string) time.Duration {
+	limits.Lock()
+	defer limits.Unlock()
+	return limits.steps[step]
+}
+
+// Bound limits the commands Command starts, together, to limit (0 for no
+// limit) until the returned end is called. end returns the timeouts hit in
+// between, by the bound itself or by the steps.
+func Bound(step string, limit time.Duration) (end func() []*Timeout) {
+	parent := ctx
+	boundCtx, cancel := context.WithCancel(parent)
+	if limit > 0 {
+		boundCtx, cancel = context.WithTimeoutCause(parent, limit, &Timeout{Step: step, Limit: limit})
+	}
+	limits.Lock()
+	limits.ctx, limits.hit = boundCtx, nil
+	limits.Unlock()
+	return func() []*Timeout {
+		recordTimeout(boundCtx) // Also when it ran out between commands
+		cancel()
+		limits.Lock()
+		defer limits.Unlock()
+		hit := limits.hit
+		limits.ctx, limits.hit = nil, nil
+		return hit
+	}
+}
+
+// StepName names the step a command is: its program, plus its subcommand for
+// tools like go, make, or bazel, e.g. "go test", "tsc", or "make lint-go"
+func StepName(name string, args []string) string {
+	step := filepath.Base(name)
+	if len(args) > 0 && isSubcommand(args[0]) {
+		step += " " + args[0]
+	}
+	return step
+}
+
+// isSubcommand tells a subcommand from a flag or a file argument
+func isSubcommand(arg string) bool {
+	if arg == "" || !(arg[0] >= 'a' && arg[0] <= 'z') {
+		return false
+	}
+	return !strings.ContainsFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	})
+}
+
+// stepContext returns the context a command of step runs under: Bound's or
+// the process's, limited to the step's timeout. A command that finishes in
+// time leaves the step's timer to release the context, so cancel only needs
+// calling once it's done.
+func stepContext(step string) (context.Context, context.CancelFunc) {
+	limits.Lock()
+	parent, limit := limits.ctx, limits.steps[step]
+	limits.Unlock()
+	if parent == nil {
+		parent = ctx
+	}
+	if limit <= 0 {
+		return parent, func() {}
+	}
+	return context.WithTimeoutCause(parent, limit, &Timeout{Step: step, Limit: limit})
+}
+
+// RecordTimeout notes a timeout a tool reported itself, so the current Bound
+// returns it
+func RecordTimeout(t *Timeout) {
+	record(t, false)
+}
+
+// recordTimeout notes the timeout that cancelled ctx, if that's why it was,
+// and reports whether it was
+func recordTimeout(ctx context.Context) bool {
+	var t *Timeout
+	if !errors.As(context.Cause(ctx), &t) {
+		return false
+	}
+	record(t, true)
+	return true
+}
+
+func record(t *Timeout, killed bool) {
+	limits.Lock()
+	defer limits.Unlock()
+	t.Killed = t.Killed || killed
+	if !slices.Contains(limits.hit, t) {
+		limits.hit = append(limits.hit, t)
+	}
+}
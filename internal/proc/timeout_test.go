@@ -0,0 +1,70 @@
+//go:build unix
+
+package proc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStepName(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"go", []string{"test", "-timeout=30s", "./..."}, "go test"},
+		{"/repo/node_modules/.bin/tsc", []string{"--noEmit"}, "tsc"},
+		{"make", []string{"lint-go", "FILES=a.go"}, "make lint-go"},
+		{"eslint", []string{"src/app.ts"}, "eslint"},
+		{"gofmt", nil, "gofmt"},
+		{"sleep", []string{"5"}, "sleep"},
+	}
+	for _, tt := range tests {
+		if got := StepName(tt.name, tt.args); got != tt.want {
+			t.Errorf("StepName(%q, %q): expected %q, got %q", tt.name, tt.args, tt.want, got)
+		}
+	}
+}
+
+func TestStepTimeout(t *testing.T) {
+	SetStepTimeouts(map[string]time.Duration{"sleep": 100 * time.Millisecond})
+	defer SetStepTimeouts(nil)
+
+	end := Bound("go", 0)
+	if err := Command("sleep", "5").Run(); err == nil {
+		t.Error("Expected the step to be killed")
+	}
+	if err := Command("true").Run(); err != nil {
+		t.Errorf("Expected steps without a limit to run, got %v", err)
+	}
+	timeouts := end()
+	if len(timeouts) != 1 || !timeouts[0].Killed || timeouts[0].Error() != "step sleep exceeded 100ms and was aborted" {
+		t.Errorf("Expected the sleep step's timeout, got %+v", timeouts)
+	}
+}
+
+func TestBoundTimeout(t *testing.T) {
+	end := Bound("typescript", 100*time.Millisecond)
+	start := time.Now()
+	first := Command("sleep", "5")
+	_ = first.Run()
+	second := Command("sleep", "5") // Started after the bound ran out
+	_ = second.Run()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the bound to stop both commands, took %s", elapsed)
+	}
+	timeouts := end()
+	if len(timeouts) != 1 || timeouts[0].Step != "typescript" || !timeouts[0].Killed {
+		t.Errorf("Expected one timeout of the whole hook, got %+v", timeouts)
+	}
+	if !Killed(first) || !Killed(second) {
+		t.Errorf("Expected both commands to count as killed, got %v and %v", Killed(first), Killed(second))
+	}
+
+	// Outside a bound nothing is recorded or limited
+	cmd := Command("true")
+	if err := cmd.Run(); err != nil || Killed(cmd) {
+		t.Errorf("Expected the command to run, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package profile
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/shell"
+)
+
+// DenyRule is a command the unattended profile never runs without a human
+type DenyRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Reason  string
+	// Pipeline matches against the whole command line instead of each sub-command,
+	// for patterns like curl | sh that span a pipe
+	Pipeline bool
+}
+
+// Denylist holds commands that are easy to regret and hard to undo. Interactive
+// sessions leave them to Claude Code's permission prompts.
+var Denylist = []DenyRule{
+	{Name: "recursive-delete", Pattern: regexp.MustCompile(`^rm\s(.*\s)?(-[a-zA-Z]*[rR][a-zA-Z]*|--recursive)(\s|$)`), Reason: "Recursive deletes can remove far more than intended"},
+	{Name: "pipe-to-shell", Pattern: regexp.MustCompile(`\b(curl|wget)\b[^|]*\|\s*(sudo\s+)?(ba|z|k|da)?sh\b`), Reason: "Piping downloaded scripts into a shell runs unreviewed code", Pipeline: true},
+	{Name: "privilege-escalation", Pattern: regexp.MustCompile(`^(sudo|doas|su)(\s|$)`), Reason: "Commands must not run with elevated privileges"},
+	{Name: "force-push", Pattern: regexp.MustCompile(`^git\s+push\s(.*\s)?(--force(-with-lease)?(=\S*)?|-[a-zA-Z]*f[a-zA-Z]*|\+\S+)(\s|$)`), Reason: "Force pushes rewrite shared history"},
+	{Name: "discard-changes", Pattern: regexp.MustCompile(`^git\s+(reset\s(.*\s)?--hard|clean\s(.*\s)?-[a-zA-Z]*f[a-zA-Z]*|checkout\s(.*\s)?--\s+\.)(\s|$)`), Reason: "Discarding the working tree loses uncommitted work"},
+	{Name: "world-writable", Pattern: regexp.MustCompile(`^chmod\s(.*\s)?0?777(\s|$)`), Reason: "World-writable permissions are a security hole"},
+	{Name: "disk-write", Pattern: regexp.MustCompile(`^(dd|mkfs(\.\w+)?|fdisk|parted|shred|wipefs)(\s|$)`), Reason: "Raw disk tools can destroy filesystems"},
+	{Name: "infra-destroy", Pattern: regexp.MustCompile(`^(terraform|tofu|terragrunt|pulumi)\s(.*\s)?destroy(\s|$)`), Reason: "Destroying infrastructure needs a human"},
+	{Name: "package-publish", Pattern: regexp.MustCompile(`^((npm|pnpm|yarn|cargo)\s+publish|twine\s+upload|gem\s+push)(\s|$)`), Reason: "Publishing packages can't be taken back"},
+}
+
+// Denied returns the denylist rule matching command, given as the full command
+// line and its sub-commands from shell.Split, or nil when none does. Rules see
+// each sub-command's words after quote removal and without NAME=value
+// prefixes, which don't change what runs.
+func Denied(command string, subCommands []string) *DenyRule {
+	for i, rule := range Denylist {
+		if rule.Pipeline {
+			if rule.Pattern.MatchString(command) {
+				return &Denylist[i]
+			}
+			continue
+		}
+		for _, sub := range subCommands {
+			words := shell.Words(sub)
+			for len(words) > 0 && shell.IsAssignment(words[0]) {
+				words = words[1:]
+			}
+			if rule.Pattern.MatchString(strings.Join(words, " ")) {
+				return &Denylist[i]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+// Env selects the profile for one session, overriding the project config, so
+// the same checkout can run interactively and headless
+const Env = "CLAUDE_HOOKS_PROFILE"
+
+// Profiles
+const (
+	// Interactive trusts the user watching the session: hooks fail open and
+	// advise rather than gate
+	Interactive = "interactive"
+	// Unattended is for headless and auto-accept sessions where nobody reviews
+	// each step: hooks fail closed, a stricter denylist applies, plan review
+	// gates, the turn is verified at Stop, and blocks are sent to a webhook
+	Unattended = "unattended"
+)
+
+// Resolve returns the active profile from $CLAUDE_HOOKS_PROFILE, then the
+// config's profile, defaulting to interactive. An unknown name resolves to
+// unattended, since a typo must not silently disable the stricter checks; the
+// error says so.
+func Resolve(cfg *config.Config) (string, error) {
+	name := os.Getenv(Env)
+	source := "$" + Env
+	if name == "" && cfg != nil {
+		name, source = cfg.Profile, "profile in "+cfg.Path
+	}
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", Interactive:
+		return Interactive, nil
+	case Unattended:
+		return Unattended, nil
+	default:
+		return Unattended, fmt.Errorf("unknown %s %q (want %s or %s), using %s", source, name, Interactive, Unattended, Unattended)
+	}
+}
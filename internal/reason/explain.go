@@ -0,0 +1,320 @@
+package reason
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Rule documents a rule the hooks block, deny, or warn under
+type Rule struct {
+	ID          string
+	Summary     string
+	Remediation []string
+}
+
+// rules are the rule ids that show up in reasons and the audit log. Ids ending
+// in ":" are prefixes of a family, like "content:eval-input".
+var rules = []Rule{
+	{"go-post-edit", "The edited Go packages don't pass gofmt, goimports, or go vet: a compile error, a vet finding, or code that can't be formatted.", []string{
+		"Fix each reported file:line; the output is the compiler's or vet's, with paths made absolute.",
+		"Run `go vet ./...` in the module to check the fix before editing again.",
+		"When go.dependents is enabled, packages that import the edited ones are built and tested too; a failure there means the edit broke a caller.",
+	}},
+	{"go-build-configs", "An edited Go file is only compiled under a go.builds configuration (GOOS/GOARCH, build tags, cgo), and go vet fails there.", []string{
+		"Fix each reported file:line; the output names the configuration it failed under.",
+		"Reproduce with the configuration's settings, e.g. `GOOS=windows go vet -tags=integration ./<package>`.",
+	}},
+	{"go-embed", "A changed, renamed, or deleted file is embedded by a Go package with //go:embed, and that package no longer builds, usually because a pattern matches no file anymore.", []string{
+		"Update the //go:embed pattern at the reported file:line to the file's new name, or restore the file.",
+		"Run `go vet ./<package>` to check the fix.",
+	}},
+	{"typescript-post-edit", "The edited TypeScript files fail eslint, prettier, or tsc.", []string{
+		"Fix type errors first: they usually cause the lint findings around them.",
+		"Run `npx tsc --noEmit` and `npx eslint <file>` in the project to check the fix.",
+		"Don't silence findings with eslint-disable or @ts-ignore; the content policy blocks suppressions.",
+	}},
+	{"javascript-post-edit", "The edited JavaScript files fail eslint or prettier.", []string{
+		"Fix each reported file:line, then run `npx eslint <file>` to check.",
+	}},
+	{"proto-post-edit", "The edited proto files fail buf format or buf lint, or the generated code is stale.", []string{
+		"Run `buf lint` and `buf format -w` in the module.",
+		"Regenerate the code (`buf generate`) after changing messages or services.",
+	}},
+	{"rust-post-edit", "The crate of the edited Rust files isn't formatted, has clippy warnings, or fails its tests.", []string{
+		"Run `cargo fmt` in the crate for formatting; fix each clippy warning at its file:line rather than allowing it.",
+		"Run `cargo test` in the crate to check a fix.",
+	}},
+	{"bazel-post-edit", "The targets owning the edited files don't build with bazel, their tests fail, or buildifier flags an edited BUILD or .bzl file.", []string{
+		"Fix each reported file:line; a missing dependency means the target's deps in its BUILD file need the new import.",
+		"Run `bazel build` on the reported targets to check the fix, or `buildifier -lint=fix` on BUILD files.",
+	}},
+	{"buck2-post-edit", "The targets owning the edited files don't build with buck2, their tests fail, or buildifier flags an edited BUCK or .bzl file.", []string{
+		"Fix each reported file:line, then run `buck2 build` on the reported targets to check.",
+	}},
+	{"nx-post-edit", "A monorepo.targets task of an Nx project affected by the edited files fails.", []string{
+		"Fix each reported file:line; the output is the task's, e.g. eslint's or tsc's.",
+		"Run `npx nx affected --targets=lint,test` to check the fix; nx replays the tasks it has cached.",
+	}},
+	{"turbo-post-edit", "A monorepo.targets task of a Turborepo package owning the edited files, or of one depending on it, fails.", []string{
+		"Fix each reported file:line, then run `npx turbo run lint test --filter=...<package>` to check.",
+	}},
+	{"merge-artifacts", "An edited file holds unresolved conflict markers or a top-level function defined twice, or a merge or patch left .orig, .rej, or backup files next to it.", []string{
+		"Resolve each conflict: keep the right side, or combine both, and delete the <<<<<<<, =======, and >>>>>>> lines.",
+		"Keep one of two definitions with the same name; the later one silently replaces the first.",
+		"Apply what a .rej or .orig file holds to the real file, then delete it.",
+	}},
+	{"placeholders", "An edit left placeholder code: a comment like `// ... rest of implementation` standing in for code, a `raise NotImplementedError`, an empty catch block, a function that is only a TODO and an empty return, or lorem ipsum text.", []string{
+		"Write out the code the comment stands for, in full.",
+		"Implement the stub; if it can't be done yet, say so to the user instead of leaving it.",
+		"Handle the error the catch swallows, or let it propagate.",
+		"Only what the session's edits added blocks; disable a pattern under `placeholders.disabled` if the project wants it.",
+	}},
+	{"semgrep", "A rule of the semgrep.rulesets matched an edited file at a severity semgrep.severities maps to block.", []string{
+		"Fix each reported file:line as the rule's message says; `semgrep scan --config <ruleset> <file>` shows the match.",
+		"If the rule is wrong for this code, add a `// nosemgrep: <rule-id>` comment on the line and say why.",
+		"Map the severity to warn under semgrep.severities to only be told about such findings.",
+	}},
+	{"images", "A changed line of a Dockerfile, compose file, or Kubernetes manifest refers to a container image without a tag or tagged latest, without a digest under images.require_digest, or from a registry images.registries doesn't list.", []string{
+		"Pin a version tag, like node:22.11-alpine, and with images.require_digest its digest: node:22.11-alpine@sha256:...",
+		"`docker buildx imagetools inspect <image>` prints the digest of a tag.",
+		"Pull from an allowed registry, e.g. the organization's mirror of the image.",
+	}},
+	{"security", "gosec found an issue in an edited Go file, or an edited manifest has a dependency with a known vulnerability, at or above the security.block severity.", []string{
+		"Fix the gosec issue at the reported file:line; if it's a false positive, add `// #nosec <rule> -- <why>` on the line.",
+		"Upgrade the vulnerable dependency to a fixed version (`npm audit fix --omit=dev`, or bump it in the manifest), or choose another package.",
+		"Raise security.block, or set it to none, to only be told about such findings.",
+	}},
+	{"go-errors", "Edited Go code handles errors in a way go.errors flags: an error from another package returned as is, fmt.Errorf without %w, an error discarded into _, or an errors.New message that doesn't say what failed.", []string{
+		"Wrap the error with what was being done, in the project's idiom from go.errors.wrap.",
+		"Handle or return a discarded error; if it really can't fail, say why in a comment on the same line.",
+		"Give errors.New a message naming the operation and input, or make it a package-level sentinel.",
+	}},
+	{"moved-references", "Files were moved, and code still refers to their old location.", []string{
+		"Update the imports of the moved packages in every importer the output lists.",
+	}},
+	{"deleted-files", "Files were deleted that other code still uses, or their packages no longer build.", []string{
+		"Restore the file, or remove or replace its remaining uses.",
+	}},
+	{"go-tests", "Tests of the edited Go packages fail. At Stop, every package the session touched is tested again.", []string{
+		"Read the failing test's output and fix the code, not the test, unless the test's expectation is what changed.",
+		"Run `go test ./<package>` to reproduce; a package the session didn't edit can fail through the packages it imports.",
+	}},
+	{"outside-repo", "An edited file is outside the session's git repository, by its path or through a symlink, and paths.outside is block.", []string{
+		"Make the change in the repository instead; a file reached through a symlink is edited where the symlink points.",
+		"If the file outside really has to change, ask the user, or set paths.outside to warn or validate in .claude-hooks.yaml.",
+	}},
+	{"codeowners-restricted", "An edited file is in a directory owners.restricted lists, or CODEOWNERS gives it to an owner owners.restricted lists.", []string{
+		"Leave the file alone and tell the user what it would need; its owners make the change.",
+		"If the restriction is wrong, ask the user to change owners.restricted in .claude-hooks.yaml.",
+	}},
+	{"editorconfig", "Changed lines of an edited file break its .editorconfig: indentation style, line endings, final newline, trailing whitespace, or charset. Blocks only with editorconfig.block.", []string{
+		"Rewrite the reported lines the way the property says, e.g. tabs for indent_style = tab.",
+		"The .editorconfig files from the file's directory up to the one with root = true apply; the closest wins.",
+	}},
+	{"encoding", "The edit changed an edited file's line endings (LF and CRLF), added or removed its byte order mark, or made it invalid UTF-8, compared with HEAD.", []string{
+		"Write the file back with the line endings and byte order mark it has at HEAD; `git diff --stat` shows every line changed when they differ.",
+		"Set encoding.fix to have line endings and byte order marks restored automatically instead.",
+	}},
+	{"complexity", "An edited function is over complexity.max_cyclomatic or complexity.max_lines.", []string{
+		"Split the function: extract loops, branches, and setup into helpers with names.",
+		"Only functions overlapping the edited lines are reported, so untouched code doesn't need to change.",
+	}},
+	{"bundle-size", "The edit grows the minified bundle by more than bundle.max_increase.", []string{
+		"Import only what's used (named imports of a tree-shakeable build) or pick a smaller dependency.",
+		"Load large dependencies lazily with a dynamic import().",
+	}},
+	{"api-compatibility", "The edit changes the exported API of a Go package incompatibly, compared with go.api.base.", []string{
+		"Restore the removed or changed identifiers; add new ones next to them instead.",
+		"If the break is intended, add a \"BREAKING CHANGE:\" line to the plan explaining it.",
+	}},
+	{"session-budget", "The session went over one of the guardrails.session ceilings.", []string{
+		"Stop and ask the user before continuing.",
+		"They can raise the limits in .claude-hooks.yaml or reset the session with `claude-hook budget -reset <session>`.",
+	}},
+	{"changelog", "The session changed files under guardrails.changelog.paths without a changelog entry.", []string{
+		"Add an entry to the changelog file, or a fragment under the fragments directory, describing the change.",
+	}},
+	{"warnings", "Findings that don't block: duplicate code, and complexity or bundle growth under their warn settings.", []string{
+		"Address them when they point at code you just wrote; they are shown so copies and growth don't pile up.",
+	}},
+	{"timeouts", "Not a failure: a check ran longer than its limit under timeouts in .claude-hooks.yaml and was aborted, so it neither passed nor failed.", []string{
+		"If the edit could make the check hang, like a loop or a blocking call in a test, run the check yourself to find out.",
+		"Otherwise the suite is just slow: raise the step's or the language's limit.",
+	}},
+	{"suggested-fixes", "Not a rule: patches from gopls quick fixes, golangci-lint --fix, and eslint --fix-dry-run for the findings of the blocked edit.", []string{
+		"Apply a patch as it is when it fixes the finding; it is what the tool itself would change.",
+		"They are suggestions: check that the patch keeps the code's meaning, e.g. that a removed variable wasn't meant to be used.",
+	}},
+	{"mysql-cli", "MySQL commands are blocked: they can read or change live data.", []string{
+		"Ask the user to run the query, or use the project's migrations and test database.",
+	}},
+	{"protected-branch", "Commits on the main branches are blocked: git and hg commits on main or master, and jj bookmark set, create, or move of them.", []string{
+		"Create a feature branch (`git checkout -b <name>`, `hg bookmark <name>`, or `jj bookmark create <name>`) and commit there.",
+	}},
+	{"pre-push", "The commits being pushed add secrets, disallowed paths, or oversized files.", []string{
+		"Remove the file from the commits (amend or rebase), rotate any secret that was committed, and push again.",
+	}},
+	{"deploy-window", "Deploy-like commands are blocked outside guardrails.deploy_window.", []string{
+		"Wait for the window, or ask the user to deploy.",
+	}},
+	{"trivial-edit", "Not a block: the edit only changed comments or whitespace, so the checks were skipped.", nil},
+	{"content:whole-file-rewrite", "A Write replaces an existing file and changes more than content.max_rewrite_percent of its lines. It warns, and blocks for files of content.block_rewrite_size bytes or more.", []string{
+		"Use Edit or MultiEdit to change only the lines the task needs; a rewrite can silently drop unrelated code.",
+		"If the whole file really changes, say so, and the user can raise or disable the limits under content.",
+	}},
+	{"content:sql-injection", "The edit builds SQL from strings, with fmt.Sprintf, +, a template literal, an f-string, or % formatting, and passes it to a query API. Any value in it can change the query.", []string{
+		"Use placeholders and pass the values separately: db.QueryContext(ctx, \"... WHERE id = $1\", id) in Go, db.query('... WHERE id = $1', [id]) in JS/TS, cursor.execute(\"... WHERE id = %s\", (id,)) in Python.",
+		"Identifiers like table or column names can't be parameters; pick them from a fixed list in code instead of interpolating input.",
+	}},
+	{"content:debug-output", "The edit adds debug output to library code: console.log or debugger in JavaScript and TypeScript outside tests, fmt.Print or log.Print in a Go package other than main, or print or breakpoint in a Python module that isn't a script.", []string{
+		"Remove the statement, or report through the project's logger or the function's return value.",
+		"CLIs and scripts may print: the user lists their directories under content.output_paths.",
+	}},
+	{"content:", "The edit adds code the content policy blocks, like placeholder panics, eval of input, disabled TLS verification, or lint suppressions.", []string{
+		"Write the real implementation, or fix the finding instead of suppressing it.",
+		"Exceptions are configured under content in .claude-hooks.yaml by the user, not by the session.",
+	}},
+	{"bash-rule:", "The command matches a rule under bash.rules in .claude-hooks.yaml.", []string{
+		"Use another way to do it, or ask the user to run the command.",
+	}},
+	{"agent:", "The agents section of .claude-hooks.yaml limits the subagent that made the call: agent:read-only denies Bash commands that could change anything, agent:commit commits, pushes, merges, rebases, and tags, and agent:edit file edits. The main agent isn't limited.", []string{
+		"Report what should change and leave it to the main agent.",
+		"For a read-only subagent, use commands that only read, like ls, grep, git log, or git diff, without redirections to files.",
+	}},
+	{"policy:", "A policy under policy in .claude-hooks.yaml (Rego with opa, or the exec command) denied the command or edit, asked about it, or blocked the stop. policy:unavailable means the policies couldn't be evaluated and policy.fail_closed is set.", []string{
+		"Follow the reason the policy gave; it's the policy's author's, not the hook's.",
+		"`claude-hook policy eval` evaluates the policies on an input, to see why they decide what they do.",
+	}},
+	{"unattended:", "The unattended profile blocks commands and plans it can't verify, like recursive deletes, force pushes, or a failed plan review.", []string{
+		"Find a narrower command, or stop and leave the step for a human.",
+	}},
+}
+
+// LookupRule returns the documentation of rule id, matching families by prefix
+func LookupRule(id string) (Rule, bool) {
+	for _, r := range rules {
+		if r.ID == id || (strings.HasSuffix(r.ID, ":") && strings.HasPrefix(id, r.ID)) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Explanation is what `claude-hook explain` prints about a rule or a tool's
+// diagnostic id
+type Explanation struct {
+	ID     string
+	Rule   Rule      // Zero for a tool's diagnostic id
+	Docs   string    // Documentation of a tool's diagnostic id, when known
+	Reason string    // Id of the saved reason the output comes from, "" when none has it
+	Time   time.Time // When the reason was given
+	Output string    // The check's exact output, or a diagnostic's lines
+}
+
+// Explain describes id, a rule such as go-tests or a tool's diagnostic id such
+// as SA1019 or no-unused-vars, with its output from the latest saved reason of
+// the project containing dir that has it
+func Explain(dir, id string) (Explanation, error) {
+	e := Explanation{ID: id}
+	rule, isRule := LookupRule(id)
+	if isRule {
+		e.Rule = rule
+	} else {
+		e.Docs = diagnosticDocs(id)
+	}
+
+	ids, err := savedIDs(dir)
+	if err != nil {
+		return e, err
+	}
+	mention := regexp.MustCompile(`(^|[^\w/@-])` + regexp.QuoteMeta(id) + `($|[^\w/-])`)
+	for _, reasonID := range ids {
+		saved, err := load(dir, reasonID)
+		if err != nil {
+			continue
+		}
+		var outputs []string
+		for _, s := range saved.Sections {
+			switch {
+			case s.Rule == id:
+				outputs = append(outputs, s.Text)
+			case !isRule:
+				outputs = append(outputs, linesMentioning(s.Text, mention)...)
+			}
+		}
+		if len(outputs) > 0 {
+			e.Reason, e.Time, e.Output = reasonID, saved.Time, strings.Join(outputs, "\n\n")
+			return e, nil
+		}
+	}
+	if !isRule && e.Docs == "" {
+		return e, fmt.Errorf("%s is not a rule, and no saved reason mentions it", id)
+	}
+	return e, nil
+}
+
+func (e Explanation) String() string {
+	var b strings.Builder
+	if e.Rule.ID != "" {
+		fmt.Fprintf(&b, "Rule %s: %s\n", e.ID, e.Rule.Summary)
+	} else {
+		fmt.Fprintf(&b, "Diagnostic %s\n", e.ID)
+	}
+	if len(e.Rule.Remediation) > 0 {
+		b.WriteString("\nHow to fix it:\n")
+		for _, step := range e.Rule.Remediation {
+			fmt.Fprintf(&b, "- %s\n", step)
+		}
+	}
+	if e.Docs != "" {
+		fmt.Fprintf(&b, "\nDocumentation: %s\n", e.Docs)
+	}
+	if e.Reason != "" {
+		fmt.Fprintf(&b, "\nOutput (reason %s, %s):\n%s\n", e.Reason, e.Time.Local().Format(time.DateTime), e.Output)
+	} else {
+		b.WriteString("\nNo saved reason of this project has output for it.\n")
+	}
+	return b.String()
+}
+
+// linesMentioning returns the lines of text mentioning a diagnostic id, each
+// with the indented lines that follow it, which is how tools continue a finding
+func linesMentioning(text string, mention *regexp.Regexp) []string {
+	lines := strings.Split(text, "\n")
+	var found []string
+	for i := 0; i < len(lines); i++ {
+		if !mention.MatchString(lines[i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "" && strings.TrimLeft(lines[end], " \t") != lines[end] && !mention.MatchString(lines[end]) {
+			end++
+		}
+		found = append(found, strings.Join(lines[i:end], "\n"))
+		i = end - 1
+	}
+	return found
+}
+
+// Diagnostic id formats whose documentation has a known address
+var (
+	staticcheckID = regexp.MustCompile(`^(SA|S|ST|QF)\d{4}$|^U1000$`)
+	eslintCoreID  = regexp.MustCompile(`^[a-z]+(-[a-z]+)*$`)
+)
+
+// diagnosticDocs returns where a tool's diagnostic id is documented, or ""
+func diagnosticDocs(id string) string {
+	switch {
+	case staticcheckID.MatchString(id):
+		return "https://staticcheck.dev/docs/checks/#" + id
+	case strings.HasPrefix(id, "@typescript-eslint/"):
+		return "https://typescript-eslint.io/rules/" + strings.TrimPrefix(id, "@typescript-eslint/")
+	case strings.HasPrefix(id, "react-hooks/"):
+		return "https://react.dev/reference/rules/rules-of-hooks"
+	case eslintCoreID.MatchString(id) && strings.Contains(id, "-"):
+		return "https://eslint.org/docs/latest/rules/" + id
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package reason
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+
+	Build(dir, "", []Section{
+		{Rule: "go-tests", Priority: Test, Text: "--- FAIL: TestParse (0.00s)\n    parse_test.go:12: Expected 1, got 2"},
+		{Rule: "typescript-post-edit", Priority: Compile, Text: "src/a.ts\n  3:7  error  'x' is assigned a value but never used  no-unused-vars\n\nsrc/b.ts\n  1:1  error  Unexpected var  no-var"},
+	}, 0)
+
+	e, err := Explain(dir, "go-tests")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	out := e.String()
+	for _, want := range []string{"Rule go-tests: Tests of the edited Go packages fail", "How to fix it:", "parse_test.go:12: Expected 1, got 2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+
+	// A tool's diagnostic id shows the lines that mention it and its docs
+	e, err = Explain(dir, "no-unused-vars")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if !strings.Contains(e.Output, "'x' is assigned a value") || strings.Contains(e.Output, "no-var") {
+		t.Errorf("Expected only the no-unused-vars finding, got %q", e.Output)
+	}
+	if e.Docs != "https://eslint.org/docs/latest/rules/no-unused-vars" {
+		t.Errorf("Expected the eslint docs, got %q", e.Docs)
+	}
+
+	// Rules without saved output still explain themselves
+	e, err = Explain(dir, "content:eval-input")
+	if err != nil || e.Rule.ID != "content:" || e.Reason != "" {
+		t.Fatalf("Expected the content policy's documentation alone, got %+v: %v", e, err)
+	}
+
+	if _, err := Explain(dir, "NothingLikeThis"); err == nil {
+		t.Error("Expected an unknown id without output to fail")
+	}
+}
+
+func TestDiagnosticDocs(t *testing.T) {
+	tests := map[string]string{
+		"SA1019":                             "https://staticcheck.dev/docs/checks/#SA1019",
+		"@typescript-eslint/no-explicit-any": "https://typescript-eslint.io/rules/no-explicit-any",
+		"prefer-const":                       "https://eslint.org/docs/latest/rules/prefer-const",
+		"TS2322":                             "",
+	}
+	for id, want := range tests {
+		if got := diagnosticDocs(id); got != want {
+			t.Errorf("diagnosticDocs(%q): expected %q, got %q", id, want, got)
+		}
+	}
+}
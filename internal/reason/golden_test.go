@@ -0,0 +1,38 @@
+package reason
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/pkg/hooktest/golden"
+)
+
+var goldenSections = []Section{
+	{Rule: "warnings", Priority: Warning, Text: "⚠️  Possible duplicate of internal/a.go:10-24 in internal/b.go:3-17"},
+	{Rule: "go-tests", Priority: Test, Text: "--- FAIL: TestParse (0.00s)\n    parse_test.go:14: Expected 3, got 2\nFAIL\tgithub.com/acme/app/parse\t0.012s"},
+	{Rule: "go-post-edit", Priority: Compile, Text: "❌ go vet failed:\n/repo/parse/parse.go:8:2: undefined: lexer"},
+}
+
+func TestGoldenBlockReason(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	golden.AssertString(t, "block_reason", Build(t.TempDir(), "Fix these before continuing:", goldenSections, 0))
+}
+
+func TestGoldenTruncatedBlockReason(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+	got := Build(dir, "Fix these before continuing:", goldenSections, 600)
+	// The saved reason's id and log path differ on every run
+	got = savedID.ReplaceAllString(got, "reason <id>`")
+	got = savedLog.ReplaceAllString(got, "is in <log>.")
+	golden.AssertString(t, "block_reason_truncated", got)
+}
+
+var (
+	savedID  = regexp.MustCompile("reason [^ `]+`")
+	savedLog = regexp.MustCompile(`is in \S+\.log\.`)
+)
+
+func TestGoldenRepeatedReason(t *testing.T) {
+	golden.AssertString(t, "repeated_reason", Repeated("Fix these before continuing:", goldenSections, 3))
+}
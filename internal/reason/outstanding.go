@@ -0,0 +1,103 @@
+package reason
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// outstandingFile holds the checks still failing in a session, by directory
+const outstandingFile = "outstanding.json"
+
+// maxReminded bounds how many failures a reminder names
+const maxReminded = 5
+
+// Failure is a check that failed on the files of Dir the last time the
+// session edited them
+type Failure struct {
+	Rule string `json:"rule"`
+	Dir  string `json:"dir"` // Relative to the project root, "." for it
+}
+
+// RecordFailures replaces the failures of dirs, whose files were just
+// checked, with rules: a passing check clears what failed there before
+func RecordFailures(s Session, dirs, rules []string) error {
+	path, err := state.SessionPath(s.Dir, s.ID, s.TranscriptPath, outstandingFile)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), repeatLockTimeout)
+	defer cancel()
+	unlock, err := state.Lock(ctx, path+".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	failures := readFailures(path)
+	failures = slices.DeleteFunc(failures, func(f Failure) bool { return slices.Contains(dirs, f.Dir) })
+	for _, dir := range dirs {
+		for _, rule := range rules {
+			if f := (Failure{Rule: rule, Dir: dir}); !slices.Contains(failures, f) {
+				failures = append(failures, f)
+			}
+		}
+	}
+
+	data, err := json.Marshal(failures)
+	if err != nil {
+		return fmt.Errorf("encoding outstanding failures: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing outstanding failures: %w", err)
+	}
+	return nil
+}
+
+// Outstanding returns the failures the session hasn't fixed yet
+func Outstanding(s Session) ([]Failure, error) {
+	path, err := state.SessionPath(s.Dir, s.ID, s.TranscriptPath, outstandingFile)
+	if err != nil {
+		return nil, err
+	}
+	return readFailures(path), nil
+}
+
+func readFailures(path string) []Failure {
+	var failures []Failure
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &failures) // A corrupt file starts over
+	}
+	return failures
+}
+
+// Reminder is the one line reminding Claude of failures, grouped by rule,
+// e.g. "Note: still failing from earlier edits: go-tests in internal/api;
+// go-vet in cmd/tool. ...". It's "" without failures.
+func Reminder(failures []Failure) string {
+	if len(failures) == 0 {
+		return ""
+	}
+	var rules []string
+	dirs := map[string][]string{}
+	for _, f := range failures[:min(len(failures), maxReminded)] {
+		if _, ok := dirs[f.Rule]; !ok {
+			rules = append(rules, f.Rule)
+		}
+		dirs[f.Rule] = append(dirs[f.Rule], f.Dir)
+	}
+	parts := make([]string, len(rules))
+	for i, rule := range rules {
+		parts[i] = rule + " in " + strings.Join(dirs[rule], ", ")
+	}
+	list := strings.Join(parts, "; ")
+	if n := len(failures) - maxReminded; n > 0 {
+		list += fmt.Sprintf("; and %d more", n)
+	}
+	return fmt.Sprintf("Note: still failing from earlier edits: %s. Fix them before finishing, or tell the user they're left (`%s explain <rule>` shows what each check wants).", list, Command)
+}
@@ -0,0 +1,46 @@
+package reason
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRecordFailures(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	s := Session{Dir: t.TempDir(), ID: "session-1"}
+
+	steps := []struct {
+		dirs, rules []string
+		want        []Failure
+	}{
+		{[]string{"internal/api"}, []string{"go-tests"}, []Failure{{"go-tests", "internal/api"}}},
+		{[]string{"cmd/tool"}, []string{"go-vet"}, []Failure{{"go-tests", "internal/api"}, {"go-vet", "cmd/tool"}}},
+		{[]string{"internal/api"}, nil, []Failure{{"go-vet", "cmd/tool"}}},
+	}
+	for i, step := range steps {
+		if err := RecordFailures(s, step.dirs, step.rules); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Outstanding(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, step.want) {
+			t.Errorf("Step %d: outstanding %v, want %v", i, got, step.want)
+		}
+	}
+}
+
+func TestReminder(t *testing.T) {
+	if got := Reminder(nil); got != "" {
+		t.Errorf("Expected no reminder without failures, got %q", got)
+	}
+	got := Reminder([]Failure{{"go-tests", "internal/api"}, {"go-vet", "cmd/tool"}, {"go-tests", "internal/db"}})
+	if !strings.HasPrefix(got, "Note: still failing from earlier edits: go-tests in internal/api, internal/db; go-vet in cmd/tool.") {
+		t.Errorf("Unexpected reminder %q", got)
+	}
+	if strings.Contains(got, "\n") {
+		t.Errorf("Expected one line, got %q", got)
+	}
+}
@@ -0,0 +1,278 @@
+// Package reason keeps the block reasons shown to Claude within a budget. Tool
+// output is cut by priority, and the full reason is saved to the project's logs
+// for `claude-hook reason` to print on request.
+package reason
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+	"github.com/brianleishman/claude-hooks/internal/settings"
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// Priorities of sections, most important first: lower priorities are cut first
+const (
+	Compile = iota // Code that doesn't build or type-check, and instructions to stop
+	Test           // Failing tests
+	Lint           // Linters and other findings
+	Warning        // Findings that don't block
+)
+
+// Section is the output of one check in a reason
+type Section struct {
+	Rule     string `json:"rule"`
+	Priority int    `json:"priority"`
+	Text     string `json:"text"`
+}
+
+// charsPerToken estimates token counts from lengths
+const charsPerToken = 4
+
+// Command runs claude-hook the way the generated hook commands do, so Claude
+// can run it with Bash
+var Command = fmt.Sprintf(`"${%s:-$HOME/.claude/bin/claude-hook}"`, settings.BinEnv)
+
+// footerReserve keeps room for the note on what was cut and where the rest is
+const footerReserve = 400
+
+// Limit returns the reason budget in characters, 0 for none
+func Limit(cfg config.ReasonsConfig) int {
+	limit := max(cfg.MaxChars, 0)
+	if cfg.MaxTokens > 0 && (limit == 0 || cfg.MaxTokens*charsPerToken < limit) {
+		limit = cfg.MaxTokens * charsPerToken
+	}
+	return limit
+}
+
+// Build joins header and the sections, in priority order, into a reason of at
+// most limit characters. Reasons with blocking sections, and reasons that
+// don't fit, are saved to the logs of the project containing dir for
+// `claude-hook reason` and `claude-hook explain`, and end with how to run them.
+func Build(dir, header string, sections []Section, limit int) string {
+	sorted := slices.Clone(sections)
+	slices.SortStableFunc(sorted, func(a, b Section) int { return a.Priority - b.Priority })
+
+	var blocking []string
+	for _, s := range sorted {
+		if s.Priority < Warning && !slices.Contains(blocking, s.Rule) {
+			blocking = append(blocking, s.Rule)
+		}
+	}
+	full := join(header, sorted)
+	footer := 0 // Room for the pointer to explain
+	if len(blocking) > 0 {
+		footer = footerReserve
+	}
+	fits := limit <= 0 || utf8.RuneCountInString(full)+footer <= limit
+	if fits && len(blocking) == 0 {
+		return full
+	}
+
+	reason, note := full, ""
+	if !fits {
+		reason, note = fit(header, sorted, max(limit-footerReserve, 0))
+	}
+	id, err := save(dir, full, sorted)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not save the reason: %v\n", err)
+		if note == "" {
+			return reason
+		}
+		return reason + "\n\n[" + note + ".]"
+	}
+
+	if fits {
+		return fmt.Sprintf("%s\n\n[Rules: %s. Run `%s explain <rule>` for the full output and how to fix it.]", reason, strings.Join(blocking, ", "), Command)
+	}
+	logPath, _ := path(dir, id, ".log")
+	return fmt.Sprintf("%s\n\n[%s. The full output (%d characters) is in %s. Run `%s reason %s` to print it, or `%s explain <rule>` for one check's output and how to fix it.]",
+		reason, note, utf8.RuneCountInString(full), logPath, Command, id, Command)
+}
+
+// join is the reason without a budget
+func join(header string, sections []Section) string {
+	var parts []string
+	if header != "" {
+		parts = append(parts, header)
+	}
+	for _, s := range sections {
+		parts = append(parts, s.Text)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// fit keeps whole sections while they fit in limit characters, cuts the first
+// one that doesn't at a line boundary, and drops the rest. note says what was
+// left out.
+func fit(header string, sections []Section, limit int) (reason, note string) {
+	var b strings.Builder
+	used := 0
+	add := func(s string) {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+			used += 2
+		}
+		b.WriteString(s)
+		used += utf8.RuneCountInString(s)
+	}
+	add(header)
+
+	var omitted []string
+	for i, s := range sections {
+		if used+2+utf8.RuneCountInString(s.Text) <= limit {
+			add(s.Text)
+			continue
+		}
+		kept, dropped := cutLines(s.Text, limit-used-2)
+		switch {
+		case kept != "" && dropped > 0:
+			add(kept)
+			omitted = append(omitted, fmt.Sprintf("%d more lines of %s", dropped, s.Rule))
+		case kept != "":
+			add(kept) // Its only line, cut short
+			omitted = append(omitted, "the rest of "+s.Rule)
+		default:
+			omitted = append(omitted, s.Rule)
+		}
+		for _, rest := range sections[i+1:] {
+			omitted = append(omitted, rest.Rule)
+		}
+		break
+	}
+	return b.String(), "Truncated: left out " + strings.Join(omitted, ", ")
+}
+
+// cutLines returns the leading lines of text within limit characters and how
+// many lines were dropped. A first line longer than the limit is cut short.
+func cutLines(text string, limit int) (kept string, dropped int) {
+	if limit <= 0 {
+		return "", 0
+	}
+	lines := strings.Split(text, "\n")
+	used := 0
+	for i, line := range lines {
+		n := utf8.RuneCountInString(line)
+		if i > 0 {
+			n++ // The newline
+		}
+		if used+n > limit {
+			if i == 0 {
+				runes := []rune(line)
+				return string(runes[:limit]), len(lines) - 1
+			}
+			return strings.Join(lines[:i], "\n"), len(lines) - i
+		}
+		used += n
+	}
+	return text, 0
+}
+
+// savedReason is what Load reads back: the sections of one reason
+type savedReason struct {
+	Time     time.Time `json:"time"`
+	Sections []Section `json:"sections"`
+}
+
+// save writes the full reason as text, along with its sections, under a new id
+func save(dir, full string, sections []Section) (string, error) {
+	suffix := make([]byte, 2)
+	_, _ = rand.Read(suffix)
+	id := time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+
+	data, err := json.MarshalIndent(savedReason{Time: time.Now().UTC(), Sections: sections}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding reason: %w", err)
+	}
+	jsonPath, err := path(dir, id, ".json")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(jsonPath, data, 0o644); err != nil {
+		return "", fmt.Errorf("writing reason: %w", err)
+	}
+	logPath, err := path(dir, id, ".log")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(logPath, []byte(full+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("writing reason: %w", err)
+	}
+	return id, nil
+}
+
+// Load returns the sections of reason id saved for the project containing dir,
+// or of the latest one when id is empty
+func Load(dir, id string) ([]Section, error) {
+	if id == "" {
+		ids, err := savedIDs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, errors.New("no reasons saved for this project")
+		}
+		id = ids[0]
+	}
+	saved, err := load(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	return saved.Sections, nil
+}
+
+// savedIDs returns the ids of the saved reasons of the project containing dir,
+// newest first
+func savedIDs(dir string) ([]string, error) {
+	logsDir, err := state.ProjectPath(dir, state.Logs, "")
+	if err != nil {
+		return nil, err
+	}
+	matches, _ := filepath.Glob(filepath.Join(logsDir, "reason-*.json"))
+	ids := make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), "reason-"), ".json")
+	}
+	// Ids start with the time
+	slices.Sort(ids)
+	slices.Reverse(ids)
+	return ids, nil
+}
+
+// load reads saved reason id of the project containing dir
+func load(dir, id string) (savedReason, error) {
+	if id != filepath.Base(id) {
+		return savedReason{}, fmt.Errorf("invalid reason id %q", id)
+	}
+	jsonPath, err := path(dir, id, ".json")
+	if err != nil {
+		return savedReason{}, err
+	}
+	data, err := os.ReadFile(jsonPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return savedReason{}, fmt.Errorf("reason %s not found", id)
+	}
+	if err != nil {
+		return savedReason{}, fmt.Errorf("reading reason: %w", err)
+	}
+	var saved savedReason
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return savedReason{}, fmt.Errorf("parsing reason %s: %w", id, err)
+	}
+	return saved, nil
+}
+
+// path returns where reason id's file with the extension is kept
+func path(dir, id, ext string) (string, error) {
+	return state.ProjectPath(dir, state.Logs, "reason-"+id+ext)
+}
@@ -0,0 +1,117 @@
+package reason
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func TestLimit(t *testing.T) {
+	tests := []struct {
+		cfg  config.ReasonsConfig
+		want int
+	}{
+		{config.ReasonsConfig{MaxChars: 8000}, 8000},
+		{config.ReasonsConfig{MaxChars: 8000, MaxTokens: 1000}, 4000},
+		{config.ReasonsConfig{MaxChars: 2000, MaxTokens: 1000}, 2000},
+		{config.ReasonsConfig{MaxTokens: 1000}, 4000},
+		{config.ReasonsConfig{}, 0},
+	}
+	for _, tt := range tests {
+		if got := Limit(tt.cfg); got != tt.want {
+			t.Errorf("Limit(%+v): expected %d, got %d", tt.cfg, tt.want, got)
+		}
+	}
+}
+
+func TestBuildWithinBudget(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+
+	sections := []Section{
+		{Rule: "complexity", Priority: Lint, Text: "too complex"},
+		{Rule: "go-post-edit", Priority: Compile, Text: "undefined: x"},
+	}
+	got := Build(dir, "Fix these:", sections, 1000)
+	if want := "Fix these:\n\nundefined: x\n\ntoo complex\n\n[Rules: go-post-edit, complexity. Run `" + Command + " explain <rule>` for the full output and how to fix it.]"; got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+	if saved, err := Load(dir, ""); err != nil || len(saved) != 2 {
+		t.Fatalf("Expected blocking reasons to be saved, got %+v: %v", saved, err)
+	}
+
+	// Warnings alone are neither saved nor annotated
+	warnings := []Section{{Rule: "warnings", Priority: Warning, Text: "a copy"}}
+	if got := Build(t.TempDir(), "", warnings, 1000); got != "a copy" {
+		t.Fatalf("Expected warnings as they are, got %q", got)
+	}
+}
+
+func TestBuildTruncatesByPriority(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+
+	var testOutput []string
+	for range 200 {
+		testOutput = append(testOutput, "--- FAIL: TestSomething (0.00s)")
+	}
+	sections := []Section{
+		{Rule: "warnings", Priority: Warning, Text: "a warning"},
+		{Rule: "go-tests", Priority: Test, Text: strings.Join(testOutput, "\n")},
+		{Rule: "complexity", Priority: Lint, Text: "too complex"},
+		{Rule: "go-post-edit", Priority: Compile, Text: "main.go:3:1: undefined: x"},
+	}
+	got := Build(dir, "", sections, 2000)
+
+	if n := utf8.RuneCountInString(got); n > 2000+len(dir)+200 {
+		t.Errorf("Expected the reason to stay near the budget, got %d characters", n)
+	}
+	if !strings.HasPrefix(got, "main.go:3:1: undefined: x\n\n--- FAIL") {
+		t.Errorf("Expected compiler errors first, then tests, got %q", got[:min(len(got), 80)])
+	}
+	if strings.Contains(got, "too complex") || strings.Contains(got, "a warning") {
+		t.Errorf("Expected lower priorities to be left out, got %q", got)
+	}
+	if !strings.Contains(got, "more lines of go-tests, complexity, warnings") {
+		t.Errorf("Expected a note on what was cut, got %q", got)
+	}
+	if !strings.Contains(got, " explain <rule>`") {
+		t.Errorf("Expected the follow-up command, got %q", got)
+	}
+
+	// The full reason is saved for the follow-up command
+	saved, err := Load(dir, "")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(saved) != 4 || saved[0].Rule != "go-post-edit" || saved[1].Text != sections[1].Text {
+		t.Fatalf("Expected the full sections in priority order, got %+v", saved)
+	}
+	start := strings.Index(got, " is in ") + len(" is in ")
+	logPath := got[start : start+strings.Index(got[start:], ". Run")]
+	full, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Expected the full log at %s: %v", logPath, err)
+	}
+	if !strings.Contains(string(full), "too complex") {
+		t.Errorf("Expected the log to have everything, got %q", full)
+	}
+
+	if _, err := Load(dir, "../x"); err == nil {
+		t.Error("Expected an invalid id to be rejected")
+	}
+}
+
+func TestCutLines(t *testing.T) {
+	kept, dropped := cutLines("one\ntwo\nthree", 8)
+	if kept != "one\ntwo" || dropped != 1 {
+		t.Errorf("Expected two whole lines, got %q and %d dropped", kept, dropped)
+	}
+	kept, dropped = cutLines("abcdefgh\nij", 4)
+	if kept != "abcd" || dropped != 1 {
+		t.Errorf("Expected a long first line to be cut, got %q and %d dropped", kept, dropped)
+	}
+}
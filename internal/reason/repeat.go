@@ -0,0 +1,124 @@
+package reason
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// repeatsFile holds the fingerprint of the last blocking reason sent in a
+// session and how many times in a row it was sent
+const repeatsFile = "reasons.json"
+
+// repeatLockTimeout bounds the wait for hooks of the same session running in parallel
+const repeatLockTimeout = 5 * time.Second
+
+// Session is the Claude session reasons are sent to
+type Session struct {
+	Dir            string // Any directory of the project
+	ID             string
+	TranscriptPath string
+}
+
+var (
+	// Timings change between runs of the same failing test: "(0.00s)", "0.123s"
+	timingPattern = regexp.MustCompile(`\b\d+(\.\d+)?(ns|µs|ms|s|m)\b`)
+	// Failures are failing tests or, without any, file:line findings
+	failedTestPattern = regexp.MustCompile(`(?m)^\s*--- FAIL: `)
+	findingPattern    = regexp.MustCompile(`(?m)(^|\s)[^\s:]+\.\w+:\d+(:\d+)?:`)
+)
+
+// Fingerprint identifies the failures of the blocking sections, ignoring
+// timings. It is "" when no section blocks.
+func Fingerprint(sections []Section) string {
+	h := sha256.New()
+	blocking := false
+	for _, s := range sections {
+		if s.Priority >= Warning {
+			continue
+		}
+		blocking = true
+		fmt.Fprintf(h, "%s\n%s\n", s.Rule, timingPattern.ReplaceAllString(s.Text, "T"))
+	}
+	if !blocking {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil)[:12])
+}
+
+// Attempt records that a reason with the fingerprint is being sent in the
+// session and returns how many times in a row it has been, 1 when it differs
+// from the last one
+func Attempt(s Session, fingerprint string) (int, error) {
+	path, err := state.SessionPath(s.Dir, s.ID, s.TranscriptPath, repeatsFile)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), repeatLockTimeout)
+	defer cancel()
+	unlock, err := state.Lock(ctx, path+".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var last struct {
+		Fingerprint string `json:"fingerprint"`
+		Attempts    int    `json:"attempts"`
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &last) // A corrupt file starts over
+	}
+	if last.Fingerprint != fingerprint {
+		last.Fingerprint, last.Attempts = fingerprint, 0
+	}
+	last.Attempts++
+
+	data, err := json.Marshal(last)
+	if err != nil {
+		return 0, fmt.Errorf("encoding reason fingerprint: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return 0, fmt.Errorf("writing reason fingerprint: %w", err)
+	}
+	return last.Attempts, nil
+}
+
+// Repeated is the compact reason sent instead of one identical to the last:
+// it says how many failures are still there and how often they were sent
+func Repeated(header string, sections []Section, attempt int) string {
+	failures := 0
+	var rules []string
+	for _, s := range sections {
+		if s.Priority >= Warning {
+			continue
+		}
+		if !slices.Contains(rules, s.Rule) {
+			rules = append(rules, s.Rule)
+		}
+		n := len(failedTestPattern.FindAllStringIndex(s.Text, -1))
+		if n == 0 {
+			n = len(findingPattern.FindAllStringIndex(s.Text, -1))
+		}
+		failures += max(n, 1)
+	}
+	noun := "failures"
+	if failures == 1 {
+		noun = "failure"
+	}
+	msg := fmt.Sprintf("Same %d %s as before (%s), attempt #%d; full details unchanged. If the fix isn't working, try a different approach or ask the user. Run `%s explain <rule>` to see the output again.",
+		failures, noun, strings.Join(rules, ", "), attempt, Command)
+	if header == "" {
+		return msg
+	}
+	return header + "\n\n" + msg
+}
@@ -0,0 +1,59 @@
+package reason
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFingerprintIgnoresTimingsAndWarnings(t *testing.T) {
+	first := []Section{
+		{Rule: "go-tests", Priority: Test, Text: "--- FAIL: TestParse (0.01s)\nFAIL\texample.com/p\t0.123s"},
+		{Rule: "warnings", Priority: Warning, Text: "a copy"},
+	}
+	again := []Section{
+		{Rule: "go-tests", Priority: Test, Text: "--- FAIL: TestParse (0.02s)\nFAIL\texample.com/p\t0.456s"},
+	}
+	other := []Section{
+		{Rule: "go-tests", Priority: Test, Text: "--- FAIL: TestFormat (0.02s)\nFAIL\texample.com/p\t0.456s"},
+	}
+	if Fingerprint(first) != Fingerprint(again) {
+		t.Error("Expected timings and warnings not to change the fingerprint")
+	}
+	if Fingerprint(first) == Fingerprint(other) {
+		t.Error("Expected a different failing test to change the fingerprint")
+	}
+	if got := Fingerprint([]Section{{Rule: "warnings", Priority: Warning, Text: "a copy"}}); got != "" {
+		t.Errorf("Expected no fingerprint without blocking sections, got %q", got)
+	}
+}
+
+func TestAttemptCountsRepeatsInARow(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	s := Session{Dir: t.TempDir(), ID: "session-1"}
+
+	for i, tt := range []struct {
+		fingerprint string
+		want        int
+	}{
+		{"a", 1}, {"a", 2}, {"a", 3}, {"b", 1}, {"a", 1},
+	} {
+		got, err := Attempt(s, tt.fingerprint)
+		if err != nil {
+			t.Fatalf("Attempt failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Call %d with %q: expected attempt %d, got %d", i, tt.fingerprint, tt.want, got)
+		}
+	}
+}
+
+func TestRepeated(t *testing.T) {
+	sections := []Section{
+		{Rule: "go-post-edit", Priority: Compile, Text: "go hook failed: a.go:3:1: undefined: x\n/abs/b.go:4:2: undefined: y"},
+		{Rule: "go-tests", Priority: Test, Text: "--- FAIL: TestParse (0.01s)\n    parse_test.go:9: Expected 1"},
+	}
+	got := Repeated("Fix these:", sections, 4)
+	if !strings.HasPrefix(got, "Fix these:\n\nSame 3 failures as before (go-post-edit, go-tests), attempt #4; full details unchanged.") {
+		t.Errorf("Unexpected compact reason: %q", got)
+	}
+}
@@ -0,0 +1,131 @@
+package rollback
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeArchive stores the files (relative to root) in a gzipped tarball at path
+func writeArchive(path, root string, files []string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating snapshot archive: %w", err)
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for _, name := range files {
+		if err := addFile(tw, root, name); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("writing snapshot archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("writing snapshot archive: %w", err)
+	}
+	return out.Close()
+}
+
+func addFile(tw *tar.Writer, root, name string) error {
+	path := filepath.Join(root, name)
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // Removed since git listed it
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	var link string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(path); err != nil {
+			return fmt.Errorf("reading %s: %w", name, err)
+		}
+	} else if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("archiving %s: %w", name, err)
+	}
+	header.Name = filepath.ToSlash(name)
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("archiving %s: %w", name, err)
+	}
+	if link != "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("archiving %s: %w", name, err)
+	}
+	return nil
+}
+
+// extractArchive restores the tarball at path into root, refusing entries that
+// would land outside it
+func extractArchive(path, root string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("opening snapshot archive: %w", err)
+	}
+	defer in.Close()
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("reading snapshot archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading snapshot archive: %w", err)
+		}
+
+		name := filepath.FromSlash(header.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("snapshot archive entry %q escapes the repository", header.Name)
+		}
+		target := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return fmt.Errorf("restoring %s: %w", header.Name, err)
+		}
+		_ = os.Remove(target)
+
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return fmt.Errorf("restoring %s: %w", header.Name, err)
+			}
+		case tar.TypeReg:
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode().Perm())
+			if err != nil {
+				return fmt.Errorf("restoring %s: %w", header.Name, err)
+			}
+			_, err = io.Copy(file, tr)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("restoring %s: %w", header.Name, err)
+			}
+		}
+	}
+}
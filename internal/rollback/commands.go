@@ -0,0 +1,47 @@
+package rollback
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+// DefaultCommands match commands that are hard to undo by hand: migrations
+// (which usually rewrite schema dumps and generated code), bulk deletes, and git
+// operations that rewrite the working tree or history
+var DefaultCommands = []string{
+	`^(\S*/)?(migrate|dbmate|goose|flyway|liquibase)(\s|$)`,
+	`^(npx\s+)?(prisma\s+(migrate|db\s+push)|knex\s+migrate|sequelize\s+db:migrate|typeorm\s+migration:run)\b`,
+	`^(bundle\s+exec\s+)?(rails|rake)\s+db:(migrate|rollback|reset|drop|schema:load)\b`,
+	`^(python3?\s+)?(\S*/)?manage\.py\s+migrate\b`,
+	`^alembic\s+(upgrade|downgrade)\b`,
+	`^(sqlx\s+migrate|diesel\s+migration)\s+(run|revert)\b`,
+	`^rm\s(.*\s)?(-[a-zA-Z]*[rR][a-zA-Z]*|--recursive)(\s|$)`,
+	`^find\s.*\s-delete\b`,
+	`^git\s+(reset\s(.*\s)?--hard|clean\s|checkout\s(.*\s)?(--\s+)?\.(\s|$)|restore\s|rebase\b|merge\b|pull\b|stash\s+(drop|clear)\b|filter-branch\b|filter-repo\b|rm\s(.*\s)?-r)`,
+}
+
+// inlineAssignments matches NAME=value prefixes, which don't change what runs
+var inlineAssignments = regexp.MustCompile(`^(\w+=\S*\s+)*`)
+
+// RiskyPattern returns the pattern the command matches when it should be
+// snapshotted first, or "" when it shouldn't
+func RiskyPattern(cfg config.RollbackConfig, command string) (string, error) {
+	patterns := cfg.Commands
+	if len(patterns) == 0 {
+		patterns = DefaultCommands
+	}
+	command = inlineAssignments.ReplaceAllString(strings.TrimSpace(command), "")
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid rollback command pattern %q: %w", pattern, err)
+		}
+		if re.MatchString(command) {
+			return pattern, nil
+		}
+	}
+	return "", nil
+}
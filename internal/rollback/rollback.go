@@ -0,0 +1,279 @@
+package rollback
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// manifestFile and archiveFile make up a bundle's directory in the project's
+// rollbacks state
+const (
+	manifestFile = "bundle.json"
+	archiveFile  = "untracked.tar.gz"
+)
+
+// refPrefix holds a ref per bundle, so the stash commit and HEAD it points at
+// survive git gc even after a rebase or reset drops them from every branch
+const refPrefix = "refs/claude-hooks/rollback/"
+
+// Bundle is a snapshot of a repository's working tree
+type Bundle struct {
+	ID        string    `json:"id"`
+	Created   time.Time `json:"created"`
+	Command   string    `json:"command"` // What the snapshot was taken before
+	Root      string    `json:"root"`
+	Head      string    `json:"head"`
+	Branch    string    `json:"branch,omitempty"`    // Empty when HEAD was detached
+	Stash     string    `json:"stash,omitempty"`     // `git stash create` commit with tracked changes, empty when clean
+	Untracked []string  `json:"untracked,omitempty"` // Non-ignored untracked files, stored in the archive
+}
+
+// Create snapshots the working tree of the repository containing dir: HEAD, the
+// branch, staged and unstaged changes to tracked files, and untracked files that
+// aren't ignored. maxUntracked caps the bytes of untracked files copied (0 for no
+// limit).
+func Create(dir, command string, maxUntracked int64) (*Bundle, error) {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	head, err := git(root, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("snapshots need a commit to start from: %w", err)
+	}
+	branch, _ := git(root, "symbolic-ref", "--quiet", "--short", "HEAD")
+
+	untracked, err := untrackedFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	if maxUntracked > 0 {
+		var total int64
+		for _, name := range untracked {
+			if info, err := os.Lstat(filepath.Join(root, name)); err == nil {
+				total += info.Size()
+			}
+		}
+		if total > maxUntracked {
+			return nil, fmt.Errorf("untracked files total %d bytes, over the %d byte snapshot limit (rollback.max_untracked_size)", total, maxUntracked)
+		}
+	}
+
+	// The stash commit is ours, so it doesn't need the user's identity (which
+	// may not be configured)
+	stash, err := git(root, "-c", "user.name=claude-hook", "-c", "user.email=claude-hook@localhost", "stash", "create")
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{
+		ID:        newID(),
+		Created:   time.Now().UTC(),
+		Command:   command,
+		Root:      root,
+		Head:      head,
+		Branch:    branch,
+		Stash:     stash,
+		Untracked: untracked,
+	}
+
+	bundleDir, err := state.ProjectPath(root, state.Rollbacks, bundle.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(bundleDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating snapshot directory: %w", err)
+	}
+	if len(untracked) > 0 {
+		if err := writeArchive(filepath.Join(bundleDir, archiveFile), root, untracked); err != nil {
+			_ = os.RemoveAll(bundleDir)
+			return nil, err
+		}
+	}
+
+	if _, err := git(root, "update-ref", refPrefix+bundle.ID, cmp.Or(stash, head)); err != nil {
+		_ = os.RemoveAll(bundleDir)
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding snapshot: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(bundleDir, manifestFile), data, 0o644); err != nil {
+		return nil, fmt.Errorf("writing snapshot: %w", err)
+	}
+	return bundle, nil
+}
+
+// Restore puts the repository containing dir back to bundle id: HEAD and the
+// branch, tracked changes, and untracked files, removing untracked files created
+// since. The current state is snapshotted first and returned, so the restore
+// can itself be undone.
+func Restore(dir, id string) (restored, current *Bundle, err error) {
+	bundle, err := Load(dir, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current, err = Create(bundle.Root, "claude-hook rollback "+id, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("snapshotting the current state first: %w", err)
+	}
+
+	// A half-done rebase would survive the reset (a half-done merge doesn't)
+	for _, op := range []string{"rebase-merge", "rebase-apply"} {
+		if path, err := git(bundle.Root, "rev-parse", "--git-path", op); err == nil {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(bundle.Root, path)
+			}
+			if _, err := os.Stat(path); err == nil {
+				if _, err := git(bundle.Root, "rebase", "--quit"); err != nil {
+					return nil, current, err
+				}
+				break
+			}
+		}
+	}
+
+	steps := [][]string{{"checkout", "--force", "--detach", bundle.Head}}
+	if bundle.Branch != "" {
+		steps = append(steps, []string{"checkout", "--force", "-B", bundle.Branch, bundle.Head})
+	}
+	steps = append(steps, []string{"reset", "--hard", bundle.Head}, []string{"clean", "--force", "-d"})
+	if bundle.Stash != "" {
+		steps = append(steps, []string{"stash", "apply", "--index", bundle.Stash})
+	}
+	for _, args := range steps {
+		if _, err := git(bundle.Root, args...); err != nil {
+			return nil, current, err
+		}
+	}
+
+	if len(bundle.Untracked) > 0 {
+		bundleDir, err := bundleDir(bundle.Root, id)
+		if err != nil {
+			return nil, current, err
+		}
+		if err := extractArchive(filepath.Join(bundleDir, archiveFile), bundle.Root); err != nil {
+			return nil, current, err
+		}
+	}
+	return bundle, current, nil
+}
+
+// Load reads bundle id of the project containing dir
+func Load(dir, id string) (*Bundle, error) {
+	bundleDir, err := bundleDir(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(bundleDir, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no snapshot %q (see claude-hook rollback -list)", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", id, err)
+	}
+	return &bundle, nil
+}
+
+// List returns the bundles of the project containing dir, newest first
+func List(dir string) ([]Bundle, error) {
+	rollbacksDir, err := state.ProjectPath(dir, state.Rollbacks, "")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(rollbacksDir)
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %w", err)
+	}
+
+	var bundles []Bundle
+	for _, entry := range entries {
+		bundle, err := Load(dir, entry.Name())
+		if err != nil {
+			continue // Incomplete or from an older version
+		}
+		bundles = append(bundles, *bundle)
+	}
+	slices.SortFunc(bundles, func(a, b Bundle) int { return b.Created.Compare(a.Created) })
+	return bundles, nil
+}
+
+// Prune removes all but the newest keep bundles of the project containing dir
+func Prune(dir string, keep int) error {
+	bundles, err := List(dir)
+	if err != nil || keep <= 0 || len(bundles) <= keep {
+		return err
+	}
+	for _, bundle := range bundles[keep:] {
+		bundleDir, err := bundleDir(dir, bundle.ID)
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(bundleDir); err != nil {
+			return fmt.Errorf("removing snapshot %s: %w", bundle.ID, err)
+		}
+		_, _ = git(bundle.Root, "update-ref", "-d", refPrefix+bundle.ID)
+	}
+	return nil
+}
+
+func bundleDir(dir, id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || id == "." || id == ".." {
+		return "", fmt.Errorf("invalid snapshot id %q", id)
+	}
+	return state.ProjectPath(dir, state.Rollbacks, id)
+}
+
+// untrackedFiles lists untracked files git doesn't ignore, relative to root
+func untrackedFiles(root string) ([]string, error) {
+	output, err := proc.Command("git", "-C", root, "ls-files", "--others", "--exclude-standard", "-z").Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing untracked files: %w", err)
+	}
+	var files []string
+	for _, name := range bytes.Split(output, []byte{0}) {
+		if len(name) > 0 {
+			files = append(files, string(name))
+		}
+	}
+	return files, nil
+}
+
+// newID is sortable by creation time and unique across parallel hooks
+func newID() string {
+	suffix := make([]byte, 2)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(dir string, args ...string) (string, error) {
+	cmd := proc.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,176 @@
+package rollback
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/config"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+
+	runGit(t, root, "init", "-q", "-b", "main")
+	write(".gitignore", "ignored.log\n")
+	write("tracked.go", "package a\n")
+	write("staged.go", "package a\n")
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "base")
+	head := runGit(t, root, "rev-parse", "HEAD")
+
+	write("tracked.go", "package a\n\nvar unstaged = 1\n")
+	write("staged.go", "package a\n\nvar staged = 1\n")
+	runGit(t, root, "add", "staged.go")
+	write("notes/todo.md", "untracked work\n")
+	write("ignored.log", "noise\n")
+
+	bundle, err := Create(root, "git reset --hard", 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if bundle.Head != head || bundle.Branch != "main" || bundle.Stash == "" {
+		t.Errorf("Expected HEAD %s on main with a stash, got %+v", head, bundle)
+	}
+	if len(bundle.Untracked) != 1 || bundle.Untracked[0] != "notes/todo.md" {
+		t.Errorf("Expected only the non-ignored untracked file, got %v", bundle.Untracked)
+	}
+
+	// The agent's mistake: throw everything away and commit something else
+	runGit(t, root, "reset", "-q", "--hard")
+	runGit(t, root, "clean", "-q", "-fd")
+	write("oops.go", "package a\n")
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "oops")
+
+	restored, current, err := Restore(root, bundle.ID)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if restored.ID != bundle.ID || current == nil {
+		t.Fatalf("Expected %s restored with a snapshot of the current state, got %+v, %+v", bundle.ID, restored, current)
+	}
+
+	if got := runGit(t, root, "rev-parse", "HEAD"); got != head {
+		t.Errorf("Expected HEAD back at %s, got %s", head, got)
+	}
+	if got := read("tracked.go"); !strings.Contains(got, "unstaged") {
+		t.Errorf("Expected the unstaged change back, got %q", got)
+	}
+	if got := runGit(t, root, "diff", "--cached", "--name-only"); got != "staged.go" {
+		t.Errorf("Expected staged.go staged again, got %q", got)
+	}
+	if got := read("notes/todo.md"); got != "untracked work\n" {
+		t.Errorf("Expected the untracked file back, got %q", got)
+	}
+	if got := read("oops.go"); got != "<missing>" {
+		t.Errorf("Expected oops.go gone, got %q", got)
+	}
+	if got := read("ignored.log"); got != "noise\n" {
+		t.Errorf("Expected ignored files left alone, got %q", got)
+	}
+
+	// The restore can itself be undone
+	if _, _, err := Restore(root, current.ID); err != nil {
+		t.Fatalf("Restoring the pre-rollback snapshot failed: %v", err)
+	}
+	if got := read("oops.go"); got != "package a\n" {
+		t.Errorf("Expected oops.go back after undoing the rollback, got %q", got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "commit", "-q", "--allow-empty", "-m", "base")
+
+	var ids []string
+	for range 3 {
+		bundle, err := Create(root, "git rebase main", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, bundle.ID)
+	}
+	if err := Prune(root, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	bundles, err := List(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundles) != 2 {
+		t.Fatalf("Expected 2 snapshots kept, got %d", len(bundles))
+	}
+	refs := runGit(t, root, "for-each-ref", "--format=%(refname)", refPrefix)
+	if strings.Count(refs, refPrefix) != 2 {
+		t.Errorf("Expected the pruned snapshot's ref removed, got:\n%s", refs)
+	}
+}
+
+func TestRiskyPattern(t *testing.T) {
+	tests := []struct {
+		command string
+		risky   bool
+	}{
+		{"npx prisma migrate dev", true},
+		{"bundle exec rails db:migrate", true},
+		{"python manage.py migrate", true},
+		{"goose up", true},
+		{"rm -rf src/generated", true},
+		{"find . -name '*.orig' -delete", true},
+		{"git reset --hard origin/main", true},
+		{"git checkout -- .", true},
+		{"git rebase -i HEAD~3", true},
+		{"git pull --rebase", true},
+		{"git stash drop", true},
+		{"RAILS_ENV=test rails db:reset", true},
+		{"git status", false},
+		{"git checkout -b feature", false},
+		{"rm build.log", false},
+		{"go test ./...", false},
+	}
+	for _, tt := range tests {
+		pattern, err := RiskyPattern(config.RollbackConfig{}, tt.command)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (pattern != "") != tt.risky {
+			t.Errorf("RiskyPattern(%q) = %q, expected risky: %v", tt.command, pattern, tt.risky)
+		}
+	}
+}
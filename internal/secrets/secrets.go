@@ -0,0 +1,164 @@
+// Package secrets resolves references to secrets kept outside the config, like
+// reviewer API keys, through the CLI of the store that holds them. A
+// reference is "<source>:<location>":
+//
+//	keyring:<service>/<account>      OS keychain (macOS security, Linux secret-tool)
+//	op://<vault>/<item>/<field>      1Password CLI
+//	vault:<path>#<field>             HashiCorp Vault KV
+//	aws-sm:<secret id>[#<json key>]  AWS Secrets Manager
+//	env:<VAR>                        another environment variable
+//
+// Secrets are only resolved when they're needed and never logged.
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+)
+
+// source resolves the location part of a reference
+type source func(location string) (string, error)
+
+// sources are the stores by reference prefix
+var sources = map[string]source{
+	"keyring": keyring,
+	"op":      onePassword,
+	"vault":   vault,
+	"aws-sm":  awsSecretsManager,
+	"env":     env,
+}
+
+// Sources are the reference prefixes Resolve understands
+func Sources() []string {
+	var names []string
+	for name := range sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Resolve returns the secret ref points at. Errors name the reference and
+// the store, never the secret.
+func Resolve(ref string) (string, error) {
+	prefix, location, ok := strings.Cut(ref, ":")
+	resolve, known := sources[prefix]
+	if !ok || !known || location == "" {
+		return "", fmt.Errorf("invalid secret reference %q (expected <source>:<location> with a source of %s)", ref, strings.Join(Sources(), ", "))
+	}
+	if prefix == "op" {
+		location = ref // op read takes the whole op:// URL
+	}
+	secret, err := resolve(location)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", ref, err)
+	}
+	secret = strings.TrimRight(secret, "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("resolving %s: the secret is empty", ref)
+	}
+	return secret, nil
+}
+
+// ResolveEnv resolves refs, a map of environment variable names to
+// references, into VAR=secret entries for a command's environment
+func ResolveEnv(refs map[string]string) ([]string, error) {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var entries []string
+	for _, name := range names {
+		secret, err := Resolve(refs[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		entries = append(entries, name+"="+secret)
+	}
+	return entries, nil
+}
+
+// keyring reads a generic password from the OS keychain
+func keyring(location string) (string, error) {
+	service, account, ok := strings.Cut(location, "/")
+	if !ok || service == "" || account == "" {
+		return "", errors.New("expected keyring:<service>/<account>")
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return run("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "linux":
+		return run("secret-tool", "lookup", "service", service, "account", account)
+	default:
+		return "", fmt.Errorf("the OS keychain isn't supported on %s", runtime.GOOS)
+	}
+}
+
+// onePassword reads a field with the 1Password CLI
+func onePassword(ref string) (string, error) {
+	return run("op", "read", "--no-newline", ref)
+}
+
+// vault reads a field of a KV secret with the Vault CLI, which takes the
+// address and token from its usual environment and token helper
+func vault(location string) (string, error) {
+	path, field, ok := strings.Cut(location, "#")
+	if !ok || path == "" || field == "" {
+		return "", errors.New("expected vault:<path>#<field>")
+	}
+	return run("vault", "kv", "get", "-field="+field, path)
+}
+
+// awsSecretsManager reads a secret with the AWS CLI, and a key of it when
+// the secret is a JSON object
+func awsSecretsManager(location string) (string, error) {
+	id, key, _ := strings.Cut(location, "#")
+	value, err := run("aws", "secretsmanager", "get-secret-value", "--secret-id", id, "--query", "SecretString", "--output", "text")
+	if err != nil || key == "" {
+		return value, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("the secret isn't a JSON object, so it has no key %s", key)
+	}
+	field, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("the secret has no key %s", key)
+	}
+	return fmt.Sprint(field), nil
+}
+
+// env reads another environment variable
+func env(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("$%s is not set", name)
+	}
+	return value, nil
+}
+
+// run returns the output of a store's CLI. Its stderr is kept for the error,
+// since stores explain there why a lookup failed.
+func run(name string, args ...string) (string, error) {
+	cmd := proc.Command(name, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", fmt.Errorf("%s is not installed", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
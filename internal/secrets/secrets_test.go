@@ -0,0 +1,51 @@
+package secrets_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/secrets"
+	"github.com/brianleishman/claude-hooks/pkg/hooktest"
+)
+
+func TestResolve(t *testing.T) {
+	t.Setenv("TEAM_OPENAI_KEY", "sk-env")
+	op := hooktest.FakeTool(t, "op", `printf sk-op`)
+	vault := hooktest.FakeTool(t, "vault", `echo sk-vault`)
+	hooktest.FakeTool(t, "aws", `echo '{"api_key":"sk-aws","other":"x"}'`)
+
+	tests := []struct{ ref, want string }{
+		{"env:TEAM_OPENAI_KEY", "sk-env"},
+		{"op://Engineering/OpenAI/credential", "sk-op"},
+		{"vault:secret/reviewers#openai", "sk-vault"},
+		{"aws-sm:prod/reviewers#api_key", "sk-aws"},
+	}
+	for _, tt := range tests {
+		got, err := secrets.Resolve(tt.ref)
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%s) = %q, %v; want %q", tt.ref, got, err, tt.want)
+		}
+	}
+	if calls := hooktest.Calls(t, op); len(calls) != 1 || calls[0] != "read --no-newline op://Engineering/OpenAI/credential" {
+		t.Errorf("Expected op read of the whole reference, got %q", calls)
+	}
+	if calls := hooktest.Calls(t, vault); len(calls) != 1 || calls[0] != "kv get -field=openai secret/reviewers" {
+		t.Errorf("Expected vault kv get of the field, got %q", calls)
+	}
+
+	for ref, want := range map[string]string{
+		"sk-plaintext":             "invalid secret reference",
+		"env:MISSING_REVIEWER_KEY": "$MISSING_REVIEWER_KEY is not set",
+		"vault:secret/reviewers":   "expected vault:<path>#<field>",
+		"aws-sm:prod/reviewers#x":  "has no key x",
+	} {
+		if _, err := secrets.Resolve(ref); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Resolve(%s): expected an error containing %q, got %v", ref, want, err)
+		}
+	}
+
+	env, err := secrets.ResolveEnv(map[string]string{"OPENAI_API_KEY": "env:TEAM_OPENAI_KEY", "GEMINI_API_KEY": "op://Engineering/Gemini/credential"})
+	if err != nil || strings.Join(env, " ") != "GEMINI_API_KEY=sk-op OPENAI_API_KEY=sk-env" {
+		t.Errorf("Unexpected environment %q (%v)", env, err)
+	}
+}
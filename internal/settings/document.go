@@ -0,0 +1,219 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Object is a JSON object that keeps its keys in document order, so settings can
+// be rewritten without reordering or dropping anything setup doesn't understand.
+// Values are nil, bool, json.Number, string, []any, or *Object.
+type Object struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewObject returns an empty object
+func NewObject() *Object {
+	return &Object{values: make(map[string]any)}
+}
+
+// Get returns the value stored under key
+func (o *Object) Get(key string) (any, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// Set stores value under key, appending the key if it is new
+func (o *Object) Set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Delete removes key
+func (o *Object) Delete(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	o.keys = slices.DeleteFunc(o.keys, func(k string) bool { return k == key })
+}
+
+// Keys returns the keys in document order
+func (o *Object) Keys() []string {
+	return slices.Clone(o.keys)
+}
+
+// decodeDocument parses a JSON object, preserving key order at every level
+func decodeDocument(data []byte) (*Object, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	value, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after settings object")
+	}
+
+	obj, ok := value.(*Object)
+	if !ok {
+		return nil, fmt.Errorf("settings must be a JSON object")
+	}
+	return obj, nil
+}
+
+func decodeValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := NewObject()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(keyTok.(string), value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case json.Delim('['):
+		arr := []any{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	default:
+		return tok, nil
+	}
+}
+
+// encodeDocument writes obj as two-space indented JSON without HTML escaping,
+// since hook commands routinely contain && and >
+func encodeDocument(obj *Object) ([]byte, error) {
+	var compact bytes.Buffer
+	if err := encodeValue(&compact, obj); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+func encodeValue(buf *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case *Object:
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeValue(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encodeValue(buf, v.values[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("encoding %v: %w", v, err)
+		}
+		// Encode appends a newline
+		buf.Truncate(buf.Len() - 1)
+	}
+	return nil
+}
+
+// cloneValue deep-copies a decoded JSON value
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case *Object:
+		c := NewObject()
+		for _, key := range v.keys {
+			c.Set(key, cloneValue(v.values[key]))
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, item := range v {
+			c[i] = cloneValue(item)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// valuesEqual compares decoded JSON values; key order within objects is ignored
+func valuesEqual(a, b any) bool {
+	switch av := a.(type) {
+	case *Object:
+		bv, ok := b.(*Object)
+		if !ok || len(av.keys) != len(bv.keys) {
+			return false
+		}
+		for _, key := range av.keys {
+			other, ok := bv.values[key]
+			if !ok || !valuesEqual(av.values[key], other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !valuesEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,372 @@
+package settings
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// lockTimeout bounds how long Update waits for another setup to finish
+const lockTimeout = 30 * time.Second
+
+// Settings is Claude's settings.json as a generic document. Setup only adds and
+// removes its own hook entries; every other field, at any depth, is written back
+// exactly as it was read, in its original order.
+type Settings struct {
+	doc *Object
+}
+
+// Hook is a single command hook setup installs
+type Hook struct {
+	Type    string
+	Command string
+}
+
+// Parse decodes settings.json content; empty data yields empty settings
+func Parse(data []byte) (*Settings, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &Settings{doc: NewObject()}, nil
+	}
+	doc, err := decodeDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing settings JSON: %w", err)
+	}
+	return &Settings{doc: doc}, nil
+}
+
+// Marshal encodes settings as indented JSON
+func (s *Settings) Marshal() ([]byte, error) {
+	data, err := encodeDocument(s.doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling settings to JSON: %w", err)
+	}
+	return data, nil
+}
+
+// Get returns a top-level field
+func (s *Settings) Get(key string) (any, bool) {
+	return s.doc.Get(key)
+}
+
+// SetEnv sets a variable in the top-level "env" object, which Claude exports to
+// every session and hook
+func (s *Settings) SetEnv(key, value string) {
+	env, ok := s.doc.values["env"].(*Object)
+	if !ok {
+		env = NewObject()
+		s.doc.Set("env", env)
+	}
+	env.Set(key, value)
+}
+
+// HasHook reports whether command is configured under the event's matcher
+func (s *Settings) HasHook(event, matcher, command string) bool {
+	entry := s.matcherEntry(event, matcher, false)
+	return entry != nil && slices.ContainsFunc(entryHooks(entry), func(h *Object) bool { return hookCommand(h) == command })
+}
+
+// MatchersWithHook lists the event's matchers that run command
+func (s *Settings) MatchersWithHook(event, command string) []string {
+	var matchers []string
+	for _, ref := range s.allHooks() {
+		if ref.event == event && hookCommand(ref.hook) == command && !slices.Contains(matchers, ref.matcher) {
+			matchers = append(matchers, ref.matcher)
+		}
+	}
+	return matchers
+}
+
+// AddHook appends hook to the event's matcher entry, creating the entry (and the
+// hooks/event containers) if needed. It returns false if the command is already there.
+func (s *Settings) AddHook(event, matcher string, hook Hook) bool {
+	obj := NewObject()
+	obj.Set("type", hook.Type)
+	obj.Set("command", hook.Command)
+	return s.addHookObject(event, matcher, obj)
+}
+
+func (s *Settings) addHookObject(event, matcher string, hook *Object) bool {
+	if s.HasHook(event, matcher, hookCommand(hook)) {
+		return false
+	}
+	entry := s.matcherEntry(event, matcher, true)
+	hooks, _ := entry.values["hooks"].([]any)
+	entry.Set("hooks", append(hooks, hook))
+	return true
+}
+
+// RemoveHook deletes command from the event's matcher entry, dropping the entry
+// and event when they become empty. It returns false if the command wasn't there.
+func (s *Settings) RemoveHook(event, matcher, command string) bool {
+	entry := s.matcherEntry(event, matcher, false)
+	if entry == nil {
+		return false
+	}
+
+	hooks, _ := entry.values["hooks"].([]any)
+	kept := slices.DeleteFunc(slices.Clone(hooks), func(v any) bool {
+		h, ok := v.(*Object)
+		return ok && hookCommand(h) == command
+	})
+	if len(kept) == len(hooks) {
+		return false
+	}
+	entry.Set("hooks", kept)
+
+	if len(kept) == 0 {
+		events := s.events(false)
+		matchers, _ := events.values[event].([]any)
+		matchers = slices.DeleteFunc(matchers, func(v any) bool { return v == any(entry) })
+		if len(matchers) == 0 {
+			events.Delete(event)
+		} else {
+			events.Set(event, matchers)
+		}
+		if len(events.keys) == 0 {
+			s.doc.Delete("hooks")
+		}
+	}
+	return true
+}
+
+// events returns the top-level hooks object, optionally creating it
+func (s *Settings) events(create bool) *Object {
+	events, ok := s.doc.values["hooks"].(*Object)
+	if !ok && create {
+		events = NewObject()
+		s.doc.Set("hooks", events)
+	}
+	return events
+}
+
+// matcherEntry finds the {"matcher": ..., "hooks": [...]} entry for an event
+func (s *Settings) matcherEntry(event, matcher string, create bool) *Object {
+	events := s.events(create)
+	if events == nil {
+		return nil
+	}
+
+	matchers, _ := events.values[event].([]any)
+	for _, v := range matchers {
+		if entry, ok := v.(*Object); ok && entry.values["matcher"] == matcher {
+			return entry
+		}
+	}
+	if !create {
+		return nil
+	}
+
+	entry := NewObject()
+	entry.Set("matcher", matcher)
+	entry.Set("hooks", []any{})
+	events.Set(event, append(matchers, entry))
+	return entry
+}
+
+func entryHooks(entry *Object) []*Object {
+	var hooks []*Object
+	values, _ := entry.values["hooks"].([]any)
+	for _, v := range values {
+		if h, ok := v.(*Object); ok {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+func hookCommand(hook *Object) string {
+	command, _ := hook.values["command"].(string)
+	return command
+}
+
+// hookRef identifies one installed hook: its event, matcher, and full hook object
+type hookRef struct {
+	event, matcher string
+	hook           *Object
+}
+
+// allHooks lists every hook in the document in order
+func (s *Settings) allHooks() []hookRef {
+	events := s.events(false)
+	if events == nil {
+		return nil
+	}
+
+	var refs []hookRef
+	for _, event := range events.keys {
+		matchers, _ := events.values[event].([]any)
+		for _, v := range matchers {
+			entry, ok := v.(*Object)
+			if !ok {
+				continue
+			}
+			matcher, _ := entry.values["matcher"].(string)
+			for _, h := range entryHooks(entry) {
+				refs = append(refs, hookRef{event: event, matcher: matcher, hook: h})
+			}
+		}
+	}
+	return refs
+}
+
+// Update applies modify to the settings file at path under an exclusive lock. If
+// the file changed on disk while modify ran (Claude itself rewrites settings.json
+// without taking our lock), the change is three-way merged onto the new content
+// rather than overwriting it. It reports whether the file was newly created.
+func Update(path string, modify func(*Settings) error) (created bool, err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+	unlock, err := state.Lock(ctx, path+".lock")
+	if err != nil {
+		return false, fmt.Errorf("locking settings file: %w", err)
+	}
+	defer unlock()
+
+	baseData, err := readIfExists(path)
+	if err != nil {
+		return false, err
+	}
+	created = baseData == nil
+
+	base, err := Parse(baseData)
+	if err != nil {
+		return false, err
+	}
+	ours, err := Parse(baseData)
+	if err != nil {
+		return false, err
+	}
+	if err := modify(ours); err != nil {
+		return false, err
+	}
+
+	result := ours
+	currentData, err := readIfExists(path)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(currentData, baseData) {
+		theirs, err := Parse(currentData)
+		if err != nil {
+			return false, err
+		}
+		var conflicts []string
+		result, conflicts = Merge(base, ours, theirs)
+		for _, key := range conflicts {
+			fmt.Fprintf(os.Stderr, "⚠️  %s was changed concurrently; keeping the on-disk value of %q\n", path, key)
+		}
+	}
+
+	data, err := result.Marshal()
+	if err != nil {
+		return false, err
+	}
+	return created, writeAtomic(path, data)
+}
+
+// Merge three-way merges settings onto theirs: top-level fields changed only by
+// ours take our value, hooks ours added to or removed from base are replayed, and
+// any other field changed on both sides keeps theirs and is reported as a conflict
+func Merge(base, ours, theirs *Settings) (*Settings, []string) {
+	result := &Settings{doc: cloneValue(theirs.doc).(*Object)}
+
+	var conflicts []string
+	for _, key := range unionKeys(base.doc, ours.doc) {
+		if key == "hooks" {
+			continue
+		}
+		b, inBase := base.doc.Get(key)
+		o, inOurs := ours.doc.Get(key)
+		t, inTheirs := theirs.doc.Get(key)
+
+		switch {
+		case inOurs == inBase && (!inOurs || valuesEqual(o, b)):
+			// Unchanged by us
+		case inOurs == inTheirs && (!inOurs || valuesEqual(o, t)):
+			// Same change on both sides
+		case inTheirs == inBase && (!inTheirs || valuesEqual(t, b)):
+			if inOurs {
+				result.doc.Set(key, cloneValue(o))
+			} else {
+				result.doc.Delete(key)
+			}
+		default:
+			conflicts = append(conflicts, key)
+		}
+	}
+
+	for _, ref := range base.allHooks() {
+		if !ours.HasHook(ref.event, ref.matcher, hookCommand(ref.hook)) {
+			result.RemoveHook(ref.event, ref.matcher, hookCommand(ref.hook))
+		}
+	}
+	for _, ref := range ours.allHooks() {
+		if !base.HasHook(ref.event, ref.matcher, hookCommand(ref.hook)) {
+			result.addHookObject(ref.event, ref.matcher, cloneValue(ref.hook).(*Object))
+		}
+	}
+
+	slices.Sort(conflicts)
+	return result, conflicts
+}
+
+func unionKeys(a, b *Object) []string {
+	keys := a.Keys()
+	for _, key := range b.keys {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func readIfExists(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading settings file: %w", err)
+	}
+	return data, nil
+}
+
+// writeAtomic replaces path via a temp file so readers never see a partial write
+func writeAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".settings-*.json")
+	if err != nil {
+		return fmt.Errorf("creating temp settings file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing settings file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing settings file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("setting settings file mode: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing settings file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func readSettings(t *testing.T, path string) *Settings {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	s, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v\n%s", err, data)
+	}
+	return s
+}
+
+// TestRoundTripPreservesDocument expects an untouched document to be written back
+// byte for byte, including key order, nested unknown fields, and unescaped &&
+func TestRoundTripPreservesDocument(t *testing.T) {
+	original := `{
+  "permissions": {
+    "deny": [],
+    "allow": [
+      "Bash(ls:*)"
+    ]
+  },
+  "model": "opus",
+  "hooks": {
+    "PreToolUse": [
+      {
+        "matcher": "Bash",
+        "hooks": [
+          {
+            "type": "command",
+            "command": "cd /x && run",
+            "timeout": 30
+          }
+        ],
+        "note": "kept"
+      }
+    ]
+  },
+  "enableAllProjectMcpServers": true,
+  "cleanupPeriodDays": 1.5
+}
+`
+	s, err := Parse([]byte(original))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	data, err := s.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != original {
+		t.Errorf("Expected document to round-trip unchanged, got:\n%s", data)
+	}
+}
+
+func TestUpdatePreservesUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	original := `{"statusLine":{"type":"command","command":"x"},"hooks":{"PreToolUse":[{"matcher":"Bash","hooks":[{"type":"command","command":"user","timeout":5}]}]},"env":{"A":"1"}}`
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+
+	created, err := Update(path, func(s *Settings) error {
+		s.AddHook("PreToolUse", "Bash", Hook{Type: "command", Command: "guard"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if created {
+		t.Error("Expected existing file not to be reported as created")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	got := string(data)
+	for _, want := range []string{`"timeout": 5`, `"command": "guard"`, `"A": "1"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected settings to contain %s, got:\n%s", want, got)
+		}
+	}
+	if statusLine, env := strings.Index(got, `"statusLine"`), strings.Index(got, `"env"`); statusLine < 0 || env < statusLine {
+		t.Errorf("Expected top-level key order to be kept, got:\n%s", got)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat settings: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected file mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+// TestUpdateMergesConcurrentWrite simulates Claude rewriting settings.json while
+// setup is modifying it and expects both sets of changes to survive
+func TestUpdateMergesConcurrentWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	base := `{"model":"opus","hooks":{"PreToolUse":[{"matcher":"Bash","hooks":[{"type":"command","command":"old"}]}]}}`
+	if err := os.WriteFile(path, []byte(base), 0o644); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+
+	_, err := Update(path, func(s *Settings) error {
+		s.AddHook("PreToolUse", "Bash", Hook{Type: "command", Command: "ours"})
+
+		theirs := `{"model":"sonnet","permissions":{"allow":[]},"hooks":{"PreToolUse":[{"matcher":"Bash","hooks":[{"type":"command","command":"old"},{"type":"command","command":"theirs"}]}]}}`
+		return os.WriteFile(path, []byte(theirs), 0o644)
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	s := readSettings(t, path)
+	if model, _ := s.Get("model"); model != "sonnet" {
+		t.Errorf("Expected concurrent model change to be kept, got %v", model)
+	}
+	if _, ok := s.Get("permissions"); !ok {
+		t.Error("Expected concurrently added permissions to be kept")
+	}
+	for _, command := range []string{"old", "theirs", "ours"} {
+		if !s.HasHook("PreToolUse", "Bash", command) {
+			t.Errorf("Expected hook %q after merge", command)
+		}
+	}
+}
+
+func TestMergeRemovesHooksOursRemoved(t *testing.T) {
+	parse := func(doc string) *Settings {
+		s, err := Parse([]byte(doc))
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		return s
+	}
+	base := parse(`{"hooks":{"SessionStart":[{"matcher":"startup","hooks":[{"type":"command","command":"stale"}]}]}}`)
+	ours := parse(`{"hooks":{"SessionStart":[{"matcher":"startup","hooks":[{"type":"command","command":"fresh"}]}]}}`)
+	theirs := parse(`{"theme":"dark","hooks":{"SessionStart":[{"matcher":"startup","hooks":[{"type":"command","command":"stale"},{"type":"command","command":"user"}]}]}}`)
+
+	merged, conflicts := Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %v", conflicts)
+	}
+
+	data, err := merged.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	got := strings.Join(strings.Fields(string(data)), "")
+	want := `{"theme":"dark","hooks":{"SessionStart":[{"matcher":"startup","hooks":[{"type":"command","command":"user"},{"type":"command","command":"fresh"}]}]}}`
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestRemoveHookDropsEmptyContainers(t *testing.T) {
+	s, err := Parse([]byte(`{"model":"opus"}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if !s.AddHook("Stop", "", Hook{Type: "command", Command: "done"}) {
+		t.Fatal("Expected hook to be added")
+	}
+	if s.AddHook("Stop", "", Hook{Type: "command", Command: "done"}) {
+		t.Error("Expected duplicate hook not to be added")
+	}
+	if !s.RemoveHook("Stop", "", "done") {
+		t.Fatal("Expected hook to be removed")
+	}
+	if _, ok := s.Get("hooks"); ok {
+		t.Error("Expected empty hooks object to be removed")
+	}
+}
+
+// TestUpdateSerializesWriters runs several updates at once and expects none of
+// their hooks to be lost
+func TestUpdateSerializesWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Go(func() {
+			_, err := Update(path, func(s *Settings) error {
+				s.AddHook("PostToolUse", "Edit", Hook{Type: "command", Command: fmt.Sprintf("hook-%d", i)})
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Update %d failed: %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	s := readSettings(t, path)
+	if hooks := s.allHooks(); len(hooks) != 5 {
+		t.Errorf("Expected 5 hooks, got %d", len(hooks))
+	}
+}
@@ -0,0 +1,200 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// BinEnv overrides the hook executable used by generated commands
+const BinEnv = "CLAUDE_HOOKS_BIN"
+
+// shimTemplate runs the hooks from a checkout; the checkout path is the only
+// machine-specific value and lives here rather than in settings.json
+const shimTemplate = `#!/bin/sh
+# Generated by claude-hooks setup. Re-run setup after moving the checkout,
+# or point CLAUDE_HOOKS_DIR at it.
+checkout=%s
+CLAUDE_HOOKS_DIR="${CLAUDE_HOOKS_DIR:-$checkout}"
+hook_type="$1"
+shift
+cd "$CLAUDE_HOOKS_DIR" && exec go run cmd/claude-hook/main.go -type "$hook_type" "$@"
+`
+
+// ShimPath is where setup installs the hook shim, relative to the home directory
+// so the generated commands are the same on every machine
+func ShimPath(homeDir string) string {
+	return filepath.Join(homeDir, ".claude", "bin", "claude-hook")
+}
+
+// InstallShim writes the shim at path pointing at the checkout in repoDir
+func InstallShim(path, repoDir string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	content := fmt.Sprintf(shimTemplate, shellQuote(repoDir))
+	if err := writeAtomic(path, []byte(content)); err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0o755); err != nil {
+		return fmt.Errorf("making shim executable: %w", err)
+	}
+	return nil
+}
+
+// BuildBinary compiles claude-hook from the checkout in repoDir into path as a
+// static binary stamped with the checkout's version, so hooks start without
+// compiling. It replaces path atomically, since a hook may be running it.
+func BuildBinary(path, repoDir string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	version := "dev"
+	if out, err := exec.Command("git", "-C", repoDir, "describe", "--tags", "--always", "--dirty").Output(); err == nil {
+		version = strings.TrimSpace(string(out))
+	}
+
+	tmp := path + ".tmp"
+	cmd := exec.Command("go", "build", "-trimpath",
+		"-ldflags", "-s -w -X github.com/brianleishman/claude-hooks/internal/version.Version="+version,
+		"-o", tmp, "./cmd/claude-hook")
+	cmd.Dir = repoDir
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("building claude-hook: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("installing claude-hook: %w", err)
+	}
+	return nil
+}
+
+// shellQuote single-quotes s for sh, so nothing in it is expanded
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var (
+	shimCheckout = regexp.MustCompile(`(?m)^checkout='(.*)'$`)
+	// legacyShimCheckout is the double-quoted default of shims from before
+	legacyShimCheckout = regexp.MustCompile(`(?m)^CLAUDE_HOOKS_DIR="\$\{CLAUDE_HOOKS_DIR:-(.*)\}"$`)
+)
+
+// ShimCheckout returns the checkout the shim at path runs, or "" when path
+// isn't a shim setup installed, e.g. a release binary
+func ShimCheckout(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "#!") {
+		return ""
+	}
+	if dir := os.Getenv("CLAUDE_HOOKS_DIR"); dir != "" {
+		return dir
+	}
+	if m := shimCheckout.FindStringSubmatch(string(data)); m != nil {
+		return strings.ReplaceAll(m[1], `'\''`, "'")
+	}
+	if m := legacyShimCheckout.FindStringSubmatch(string(data)); m != nil {
+		return strings.ReplaceAll(m[1], `\"`, `"`)
+	}
+	return ""
+}
+
+// HookCommand is the portable settings.json command for a hook type: it runs
+// $CLAUDE_HOOKS_BIN when set and the shim in ~/.claude/bin otherwise
+func HookCommand(hookType string) string {
+	return fmt.Sprintf(`"${%s:-$HOME/.claude/bin/claude-hook}" %s`, BinEnv, hookType)
+}
+
+// IsHookCommand reports whether command runs claude-hooks: a command setup
+// generates with HookCommand, or one from an older setup (IsLegacyHookCommand)
+func IsHookCommand(command string) bool {
+	return strings.HasPrefix(command, fmt.Sprintf(`"${%s:-$HOME/.claude/bin/claude-hook}" `, BinEnv)) || IsLegacyHookCommand(command)
+}
+
+// HookSpec is one hook setup installs; Flag selects which tools (or, for
+// SessionStart, which sources) trigger it
+type HookSpec struct {
+	Name        string
+	Event       string
+	Type        string // claude-hook -type value
+	Flag        string
+	Matcher     string // Default matcher
+	Description string
+}
+
+// DefaultHooks are the hooks setup installs and the matchers they use unless overridden
+var DefaultHooks = []HookSpec{
+	{Name: "PostToolUse", Event: "PostToolUse", Type: "post-edit", Flag: "edit-tools", Matcher: "Write|Edit|MultiEdit", Description: "format, lint, and check edited files"},
+	// Bash commands can move or delete files (git mv, rm), so post-edit also runs after them
+	{Name: "PostToolUse Bash", Event: "PostToolUse", Type: "post-edit", Flag: "bash-tools", Matcher: "Bash", Description: "re-check packages after mv/rm"},
+	{Name: "PreToolUse", Event: "PreToolUse", Type: "pre-bash", Flag: "guard-tools", Matcher: "Bash", Description: "MySQL blocking + git commit protection + pre-push scan"},
+	{Name: "PreToolUse Edit", Event: "PreToolUse", Type: "pre-edit", Flag: "content-tools", Matcher: "Write|Edit|MultiEdit", Description: "block dangerous code before it's written"},
+	{Name: "PlanReview", Event: "PreToolUse", Type: "plan-review", Flag: "plan-tools", Matcher: "ExitPlanMode", Description: "AI Council plan review"},
+	{Name: "SessionStart", Event: "SessionStart", Type: "session-start", Flag: "session-sources", Matcher: "startup|compact", Description: "inject agents.md"},
+	// UserPromptSubmit has no tool to match either
+	{Name: "UserPromptSubmit", Event: "UserPromptSubmit", Type: "user-prompt", Flag: "prompt-hook", Matcher: "*", Description: "remind Claude of failures left from earlier edits"},
+	// Stop has no tool to match; the hook exits immediately unless the unattended
+	// profile or the changelog rule is active
+	{Name: "Stop", Event: "Stop", Type: "stop", Flag: "stop-hook", Matcher: "*", Description: "verify changed files before an unattended turn ends"},
+}
+
+// InstallMissingHooks adds every default hook under its default matcher when
+// none of our commands are configured yet (a binary-only install that never ran
+// setup); existing configurations, including customized matchers, are left alone.
+// It returns how many hooks were added.
+func (s *Settings) InstallMissingHooks() int {
+	for _, spec := range DefaultHooks {
+		if len(s.MatchersWithHook(spec.Event, HookCommand(spec.Type))) > 0 {
+			return 0
+		}
+	}
+
+	added := 0
+	for _, spec := range DefaultHooks {
+		if s.AddHook(spec.Event, spec.Matcher, Hook{Type: "command", Command: HookCommand(spec.Type)}) {
+			added++
+		}
+	}
+	return added
+}
+
+// IsLegacyHookCommand reports whether command is an older setup-generated command
+// that hard-codes the checkout path, e.g. bash -c "cd /path && go run cmd/claude-hook/main.go -type post-edit"
+func IsLegacyHookCommand(command string) bool {
+	return strings.Contains(command, "cd ") && strings.Contains(command, "go run cmd/claude-hook/main.go -type ")
+}
+
+var legacyHookType = regexp.MustCompile(`go run cmd/claude-hook/main\.go -type ([a-z-]+)`)
+
+// RepointLegacyHooks rewrites legacy commands to HookCommand under the same
+// matcher and returns how many were replaced
+func (s *Settings) RepointLegacyHooks() int {
+	replaced := 0
+	for _, ref := range s.allHooks() {
+		command := hookCommand(ref.hook)
+		match := legacyHookType.FindStringSubmatch(command)
+		if !IsLegacyHookCommand(command) || match == nil {
+			continue
+		}
+		s.RemoveHook(ref.event, ref.matcher, command)
+		s.AddHook(ref.event, ref.matcher, Hook{Type: "command", Command: HookCommand(match[1])})
+		replaced++
+	}
+	return replaced
+}
+
+// RemoveHooksFunc deletes every hook whose command matches and returns how many were removed
+func (s *Settings) RemoveHooksFunc(match func(command string) bool) int {
+	removed := 0
+	for _, ref := range s.allHooks() {
+		if command := hookCommand(ref.hook); match(command) && s.RemoveHook(ref.event, ref.matcher, command) {
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,162 @@
+package settings
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoveLegacyHookCommands(t *testing.T) {
+	s, err := Parse([]byte(`{"hooks":{"PreToolUse":[{"matcher":"Bash","hooks":[
+		{"type":"command","command":"bash -c \"cd /old/checkout && go run cmd/claude-hook/main.go -type pre-bash\""},
+		{"type":"command","command":"my-own-guard"}]}]}}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if removed := s.RemoveHooksFunc(IsLegacyHookCommand); removed != 1 {
+		t.Errorf("Expected 1 legacy command removed, got %d", removed)
+	}
+	if !s.HasHook("PreToolUse", "Bash", "my-own-guard") {
+		t.Error("Expected unrelated hook to be kept")
+	}
+	if IsLegacyHookCommand(HookCommand("pre-bash")) {
+		t.Error("Expected generated command not to be treated as legacy")
+	}
+}
+
+func TestRemoveAllHookCommands(t *testing.T) {
+	s, err := Parse([]byte(`{"model":"opus","hooks":{
+		"PostToolUse":[{"matcher":"Write|Edit","hooks":[{"type":"command","command":"\"${CLAUDE_HOOKS_BIN:-$HOME/.claude/bin/claude-hook}\" post-edit"}]}],
+		"PreToolUse":[
+			{"matcher":"Bash","hooks":[
+				{"type":"command","command":"bash -c \"cd /old/checkout && go run cmd/claude-hook/main.go -type pre-bash\""},
+				{"type":"command","command":"my-own-guard"}]},
+			{"matcher":"ExitPlanMode","hooks":[{"type":"command","command":"\"${CLAUDE_HOOKS_BIN:-$HOME/.claude/bin/claude-hook}\" plan-review"}]}]}}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if removed := s.RemoveHooksFunc(IsHookCommand); removed != 3 {
+		t.Errorf("Expected 3 commands removed, got %d", removed)
+	}
+	data, err := s.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, gone := range []string{"PostToolUse", "ExitPlanMode", "claude-hook"} {
+		if strings.Contains(string(data), gone) {
+			t.Errorf("Expected %s to be removed, got:\n%s", gone, data)
+		}
+	}
+	if !s.HasHook("PreToolUse", "Bash", "my-own-guard") {
+		t.Error("Expected unrelated hook to be kept")
+	}
+	if _, ok := s.Get("model"); !ok {
+		t.Error("Expected other settings to be kept")
+	}
+}
+
+func TestRepointLegacyHooks(t *testing.T) {
+	s, err := Parse([]byte(`{"hooks":{"SessionStart":[{"matcher":"startup|compact","hooks":[
+		{"type":"command","command":"bash -c \"cd /old/checkout && go run cmd/claude-hook/main.go -type session-start\""}]}]}}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if n := s.RepointLegacyHooks(); n != 1 {
+		t.Errorf("Expected 1 command re-pointed, got %d", n)
+	}
+	if !s.HasHook("SessionStart", "startup|compact", HookCommand("session-start")) {
+		t.Error("Expected legacy command to be replaced by the portable command under the same matcher")
+	}
+}
+
+func TestInstallMissingHooks(t *testing.T) {
+	s, err := Parse(nil)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if n := s.InstallMissingHooks(); n != len(DefaultHooks) {
+		t.Errorf("Expected %d hooks installed, got %d", len(DefaultHooks), n)
+	}
+	if !s.HasHook("PostToolUse", "Bash", HookCommand("post-edit")) {
+		t.Error("Expected post-edit to also run after Bash")
+	}
+	if n := s.InstallMissingHooks(); n != 0 {
+		t.Errorf("Expected existing configuration to be left alone, got %d added", n)
+	}
+}
+
+// TestShimRunsHookFromCheckout installs the shim for a fake checkout and runs the
+// generated command through sh, as Claude would
+func TestShimRunsHookFromCheckout(t *testing.T) {
+	for _, name := range []string{"claude-hooks", "my $HOME `id` \\ 'checkout'"} {
+		t.Run(name, func(t *testing.T) { testShimRunsHook(t, name) })
+	}
+}
+
+// testShimRunsHook runs the hook from a checkout named name, whose quotes,
+// $, and backticks the shim must not expand
+func testShimRunsHook(t *testing.T, name string) {
+	home := t.TempDir()
+	checkout := filepath.Join(t.TempDir(), name)
+	main := filepath.Join(checkout, "cmd", "claude-hook", "main.go")
+	if err := os.MkdirAll(filepath.Dir(main), 0o755); err != nil {
+		t.Fatalf("Failed to create checkout: %v", err)
+	}
+	program := "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() { fmt.Print(os.Args[1:]) }\n"
+	if err := os.WriteFile(main, []byte(program), 0o644); err != nil {
+		t.Fatalf("Failed to write main.go: %v", err)
+	}
+
+	if err := InstallShim(ShimPath(home), checkout); err != nil {
+		t.Fatalf("InstallShim failed: %v", err)
+	}
+
+	cmd := exec.Command("sh", "-c", HookCommand("post-edit")+" -v")
+	cmd.Env = append(os.Environ(), "HOME="+home, BinEnv+"=")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Running hook command failed: %v\n%s", err, output)
+	}
+	if got := strings.TrimSpace(string(output)); got != "[-type post-edit -v]" {
+		t.Errorf("Expected shim to pass the hook type through, got %q", got)
+	}
+	t.Setenv("CLAUDE_HOOKS_DIR", "")
+	if got := ShimCheckout(ShimPath(home)); got != checkout {
+		t.Errorf("Expected the shim's checkout %q, got %q", checkout, got)
+	}
+}
+
+func TestShimCheckout(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_DIR", "")
+	dir := t.TempDir()
+	shim := filepath.Join(dir, "claude-hook")
+	if err := InstallShim(shim, "/home/me/claude-hooks"); err != nil {
+		t.Fatalf("InstallShim failed: %v", err)
+	}
+	if got := ShimCheckout(shim); got != "/home/me/claude-hooks" {
+		t.Errorf("Expected the shim's checkout, got %q", got)
+	}
+
+	// Shims from before the path was single-quoted
+	legacy := filepath.Join(dir, "legacy")
+	if err := os.WriteFile(legacy, []byte("#!/bin/sh\nCLAUDE_HOOKS_DIR=\"${CLAUDE_HOOKS_DIR:-/home/me/old}\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := ShimCheckout(legacy); got != "/home/me/old" {
+		t.Errorf("Expected an older shim's checkout, got %q", got)
+	}
+
+	binary := filepath.Join(dir, "release")
+	if err := os.WriteFile(binary, []byte("\x7fELF"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := ShimCheckout(binary); got != "" {
+		t.Errorf("Expected no checkout for a binary, got %q", got)
+	}
+}
@@ -0,0 +1,870 @@
+// Package shell finds the commands a Bash command line runs, the way a POSIX
+// shell would parse it, so policy checks can't be walked around with quoting,
+// substitutions, or wrappers. Nothing is executed or expanded: variables other
+// than $IFS and the positional parameters are left as written.
+package shell
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxDepth bounds how deeply substitutions and sh -c scripts are followed.
+// Nothing written by hand comes close, and it keeps hostile input from
+// exhausting the stack.
+const maxDepth = 64
+
+// reserved are the words that start compound commands instead of running one
+var reserved = []string{"!", "{", "}", "if", "then", "else", "elif", "fi", "do", "done", "while", "until", "coproc"}
+
+// wrappers run the command after their options, mapped to the options that
+// take a value
+var wrappers = map[string][]string{
+	"sudo":    {"-u", "-g", "-h", "-p", "-C", "-D", "-r", "-t", "-U"},
+	"doas":    {"-u", "-C"},
+	"env":     {"-u", "-C", "-S", "--unset", "--chdir"},
+	"command": nil,
+	"builtin": nil,
+	"exec":    {"-a"},
+	"nohup":   nil,
+	"time":    {"-f", "-o"},
+	"nice":    {"-n"},
+	"ionice":  {"-c", "-n", "-p"},
+	"stdbuf":  {"-i", "-o", "-e"},
+	"setsid":  nil,
+	"timeout": {"-s", "-k", "--signal", "--kill-after"},
+	"xargs":   {"-I", "-n", "-P", "-L", "-d", "-E", "-s", "-a"},
+	"watch":   {"-n", "--interval", "-q", "--equexit"},
+}
+
+// positional are how many arguments wrappers take before the command
+var positional = map[string]int{"timeout": 1}
+
+// shells run the script given to -c, or read from a here-string
+var shells = []string{"sh", "bash", "zsh", "dash", "ksh", "ash", "mksh"}
+
+// word is one shell word as written and after quote removal
+type word struct {
+	raw      string
+	value    string
+	redirect bool // A redirection such as >out or 2>&1 rather than an argument
+}
+
+type heredoc struct {
+	delim  string
+	strip  bool // <<- strips leading tabs
+	expand bool // An unquoted delimiter, so substitutions in the body run
+}
+
+type splitter struct {
+	s     string
+	i     int
+	depth int
+
+	out        []string   // Every simple command, as text
+	args       [][]string // The argument words of each top-level simple command
+	redirects  [][]string // The redirections of each, as written
+	incomplete bool       // A quote, substitution, or here-document isn't closed
+
+	words    []word
+	raw      strings.Builder
+	value    strings.Builder
+	inWord   bool
+	redirect bool
+	heredoc  int  // On the target of <<, the length of the operator
+	strip    bool // <<- strips leading tabs
+	quoted   bool
+	gap      int      // The length of a redirection's operator and the space after it
+	pending  []string // Commands run by the substitutions of the current command
+	heredocs []heredoc
+}
+
+// Split returns the simple commands a command line runs: those joined by ;,
+// &, &&, ||, |, and newlines, and those inside subshells, { } groups, $( ),
+// backtick and process substitutions, here-documents, sh -c and eval scripts,
+// here-strings fed to a shell, find -exec, and wrappers such as env, sudo,
+// xargs, and watch, which are also given without the wrapper. Each is its text as written, with redirections moved
+// to the end and the executable unquoted, so 'my'sql and \mysql read mysql.
+func Split(command string) []string {
+	sp := &splitter{s: command}
+	sp.run()
+	return sp.out
+}
+
+// Words returns the arguments of the first simple command in command after
+// quote removal, without its redirections or substitutions' commands
+func Words(command string) []string {
+	sp := &splitter{s: command, depth: maxDepth}
+	sp.run()
+	if len(sp.args) == 0 {
+		return nil
+	}
+	return sp.args[0]
+}
+
+// WritesFile reports whether the first simple command in command redirects
+// output to a file, other than /dev/null or another file descriptor
+func WritesFile(command string) bool {
+	sp := &splitter{s: command, depth: maxDepth}
+	sp.run()
+	if len(sp.redirects) == 0 {
+		return false
+	}
+	for _, r := range sp.redirects[0] {
+		op := strings.TrimLeft(r, "0123456789&")
+		if !strings.HasPrefix(op, ">") {
+			continue // Input, here-documents, and here-strings
+		}
+		target := strings.TrimSpace(strings.TrimLeft(op, ">|&"))
+		if target != "/dev/null" && target != "-" && strings.Trim(target, "0123456789") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap splits a command's words into its leading NAME=value assignments and
+// the command that runs, without the wrappers before it such as sudo or env
+func Unwrap(words []string) (assignments, command []string) {
+	for len(words) > 0 {
+		if IsAssignment(words[0]) {
+			assignments = append(assignments, words[0])
+			words = words[1:]
+			continue
+		}
+		name := filepath.Base(words[0])
+		valued, ok := wrappers[name]
+		// command -v only looks the command up
+		if !ok || (name == "command" && len(words) > 1 && (words[1] == "-v" || words[1] == "-V")) {
+			break
+		}
+		words = words[1:]
+		for len(words) > 0 && strings.HasPrefix(words[0], "-") && words[0] != "-" {
+			if words[0] == "--" {
+				words = words[1:]
+				break
+			}
+			if slices.Contains(valued, words[0]) && len(words) > 1 {
+				words = words[1:]
+			}
+			words = words[1:]
+		}
+		words = words[min(positional[name], len(words)):]
+	}
+	return assignments, words
+}
+
+// IsAssignment tells whether word sets a variable (NAME=value, NAME+=value,
+// or NAME[i]=value) rather than naming a command
+func IsAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok {
+		return false
+	}
+	name = strings.TrimSuffix(name, "+")
+	if open := strings.IndexByte(name, '['); open > 0 && strings.HasSuffix(name, "]") {
+		name = name[:open]
+	}
+	return isName(name)
+}
+
+func (sp *splitter) run() {
+	for sp.i < len(sp.s) {
+		c := sp.s[sp.i]
+		switch {
+		case c == ' ' || c == '\t':
+			sp.endWord()
+			sp.i++
+		case c == '\n':
+			sp.flush()
+			sp.i++
+			sp.readHeredocs()
+		case c == ';' || c == '|' || c == ')':
+			sp.flush()
+			sp.i++
+		case c == '&' && sp.peek(1) != '>':
+			sp.flush()
+			sp.i++
+		case (c == '<' || c == '>') && sp.peek(1) == '(':
+			sp.substitute(sp.i+2, false)
+		case c == '<' || c == '>' || c == '&':
+			sp.readRedirect()
+		case c == '(':
+			end := sp.matchParen(sp.i + 1)
+			if sp.inWord && strings.HasSuffix(sp.raw.String(), "=") {
+				// An array assignment, a=(x y)
+				sp.write(sp.s[sp.i:min(end+1, len(sp.s))])
+			} else {
+				// A subshell, or the () of a function definition
+				sp.flush()
+				sp.nest(sp.s[sp.i+1 : end])
+			}
+			sp.i = end + 1
+		case c == '#' && !sp.inWord:
+			for sp.i < len(sp.s) && sp.s[sp.i] != '\n' {
+				sp.i++
+			}
+		case c == '\'':
+			end := sp.closing(sp.i+1, '\'')
+			sp.raw.WriteString(sp.s[sp.i:end])
+			sp.raw.WriteByte('\'')
+			sp.value.WriteString(sp.s[sp.i+1 : end])
+			sp.inWord, sp.quoted = true, true
+			sp.i = end + 1
+		case c == '"':
+			sp.readDouble()
+		case c == '\\':
+			sp.readEscape()
+		case c == '$':
+			sp.readDollar()
+		case c == '`':
+			sp.substitute(sp.i+1, true)
+		default:
+			sp.write(sp.s[sp.i : sp.i+1])
+			sp.i++
+		}
+	}
+	sp.flush()
+	if len(sp.heredocs) > 0 {
+		sp.incomplete = true
+	}
+}
+
+func (sp *splitter) peek(n int) byte {
+	if sp.i+n < len(sp.s) {
+		return sp.s[sp.i+n]
+	}
+	return 0
+}
+
+// write adds text that is the same as written and after quote removal
+func (sp *splitter) write(text string) {
+	sp.raw.WriteString(text)
+	sp.value.WriteString(text)
+	sp.inWord = true
+}
+
+func (sp *splitter) endWord() {
+	if !sp.inWord {
+		return
+	}
+	w := word{raw: sp.raw.String(), value: sp.value.String(), redirect: sp.redirect}
+	if sp.incomplete {
+		// An unclosed substitution runs to the end, whitespace and all
+		w.raw = strings.TrimRight(w.raw, " \t\n")
+	} else if sp.gap == len(w.raw) {
+		w.raw = w.raw[:len(w.raw)-1] // A redirection without a target
+	}
+	if sp.heredoc > 0 {
+		if delim := w.value[sp.heredoc:]; delim == "" && !sp.quoted {
+			sp.incomplete = true // << without a delimiter is a syntax error
+		} else {
+			sp.heredocs = append(sp.heredocs, heredoc{delim: delim, strip: sp.strip, expand: !sp.quoted})
+		}
+	}
+	sp.words = append(sp.words, w)
+	sp.raw.Reset()
+	sp.value.Reset()
+	sp.inWord, sp.redirect, sp.quoted, sp.heredoc, sp.strip, sp.gap = false, false, false, 0, false, 0
+}
+
+// flush ends the current simple command
+func (sp *splitter) flush() {
+	sp.endWord()
+	words := sp.words
+	sp.words = nil
+	for len(words) > 0 && !words[0].redirect {
+		if slices.Contains(reserved, words[0].raw) {
+			words = words[1:]
+		} else if words[0].raw == "function" {
+			words = words[min(2, len(words)):] // function name { ... }
+		} else {
+			break
+		}
+	}
+	if args := arguments(words); len(args) > 0 {
+		sp.args = append(sp.args, values(args))
+		var redirects []string
+		for _, w := range words {
+			if w.redirect {
+				redirects = append(redirects, w.raw)
+			}
+		}
+		sp.redirects = append(sp.redirects, redirects)
+		sp.command(words)
+	}
+	sp.out = append(sp.out, sp.pending...)
+	sp.pending = nil
+}
+
+// command records a simple command and the commands it runs in turn
+func (sp *splitter) command(words []word) {
+	sp.out = append(sp.out, text(words))
+
+	args := arguments(words)
+	vals := values(args)
+	_, cmd := Unwrap(vals)
+	if len(cmd) == 0 {
+		return
+	}
+	if len(cmd) < len(vals) {
+		if watches(vals[:len(vals)-len(cmd)]) {
+			// Without -x, watch runs its arguments joined as an sh -c script
+			sp.nest(strings.Join(cmd, " "))
+			return
+		}
+		// Redirections stay with the command, for the here-string of sudo sh <<<
+		var redirects []word
+		for _, w := range words {
+			if w.redirect {
+				redirects = append(redirects, w)
+			}
+		}
+		sp.command(slices.Concat(args[len(vals)-len(cmd):], redirects))
+		return
+	}
+
+	switch name := strings.ToLower(filepath.Base(cmd[0])); {
+	case slices.Contains(shells, name):
+		for k := 1; k < len(cmd); k++ {
+			opt := cmd[k]
+			if opt == "--" || (!strings.HasPrefix(opt, "-") && !strings.HasPrefix(opt, "+")) {
+				break
+			}
+			if opt == "-o" || opt == "+o" || opt == "-O" || opt == "+O" {
+				k++ // -o pipefail
+				continue
+			}
+			if !strings.HasPrefix(opt, "--") && strings.ContainsRune(opt[1:], 'c') && k+1 < len(cmd) {
+				sp.nest(cmd[k+1])
+				break
+			}
+		}
+		for _, w := range words {
+			if _, script, ok := strings.Cut(w.value, "<<<"); ok && w.redirect {
+				sp.nest(script)
+			}
+		}
+	case name == "eval":
+		sp.nest(strings.Join(cmd[1:], " "))
+	case name == "find":
+		for k := 1; k < len(cmd); k++ {
+			switch cmd[k] {
+			case "-exec", "-execdir", "-ok", "-okdir":
+				end := k + 1
+				for end < len(cmd) && cmd[end] != ";" && cmd[end] != "+" {
+					end++
+				}
+				if end > k+1 {
+					sp.command(args[k+1 : end])
+				}
+				k = end
+			}
+		}
+	}
+}
+
+// watches reports whether the wrappers unwrapped from a command include a
+// watch that runs it through sh -c, which is any watch without -x
+func watches(wrapped []string) bool {
+	found := false
+	for _, w := range wrapped {
+		switch {
+		case filepath.Base(w) == "watch":
+			found = true
+		case found && (w == "-x" || w == "--exec"):
+			return false
+		}
+	}
+	return found
+}
+
+// nest splits a script run by the current command, such as a substitution's
+func (sp *splitter) nest(script string) {
+	if sp.depth >= maxDepth {
+		return
+	}
+	child := &splitter{s: script, depth: sp.depth + 1}
+	child.run()
+	sp.pending = append(sp.pending, child.out...)
+	sp.incomplete = sp.incomplete || child.incomplete
+}
+
+// substitute adds the $( ), <( ), >( ), or backtick substitution whose body
+// starts at start to the current word and splits its body
+func (sp *splitter) substitute(start int, backtick bool) {
+	var end int
+	if backtick {
+		end = sp.closingBacktick(start)
+	} else {
+		end = sp.matchParen(start)
+	}
+	sp.nest(sp.s[start:end])
+	sp.write(sp.s[sp.i:end])
+	if end < len(sp.s) {
+		sp.write(sp.s[end : end+1])
+	}
+	sp.i = end + 1
+}
+
+func (sp *splitter) readEscape() {
+	switch next := sp.peek(1); {
+	case next == '\n':
+		// A line continuation joins the lines
+	case sp.i+1 >= len(sp.s):
+		sp.incomplete = true // The shell would wait for the next line
+	default:
+		_, size := utf8.DecodeRuneInString(sp.s[sp.i+1:])
+		sp.raw.WriteString(sp.s[sp.i : sp.i+1+size])
+		sp.value.WriteString(sp.s[sp.i+1 : sp.i+1+size])
+		sp.inWord, sp.quoted = true, true
+		sp.i += size - 1
+	}
+	sp.i += 2
+}
+
+// readDouble reads a double-quoted string, where only $, `, ", and \ are special
+func (sp *splitter) readDouble() {
+	sp.raw.WriteByte('"')
+	sp.inWord, sp.quoted = true, true
+	for sp.i++; sp.i < len(sp.s); {
+		c := sp.s[sp.i]
+		switch {
+		case c == '"':
+			sp.raw.WriteByte('"')
+			sp.i++
+			return
+		case c == '\\' && strings.IndexByte("$`\"\\\n", sp.peek(1)) >= 0:
+			if sp.peek(1) != '\n' {
+				sp.raw.WriteString(sp.s[sp.i : sp.i+2])
+				sp.value.WriteByte(sp.s[sp.i+1])
+			}
+			sp.i += 2
+		case c == '$' && sp.peek(1) == '(':
+			sp.substitute(sp.i+2, false)
+		case c == '$':
+			sp.readVariable(true)
+		case c == '`':
+			sp.substitute(sp.i+1, true)
+		default:
+			sp.write(sp.s[sp.i : sp.i+1])
+			sp.i++
+		}
+	}
+	sp.incomplete = true
+	sp.raw.WriteByte('"')
+}
+
+func (sp *splitter) readDollar() {
+	switch sp.peek(1) {
+	case '(':
+		sp.substitute(sp.i+2, false)
+	case '\'':
+		// ANSI-C quoting, $'\x6dysql'
+		end := sp.i + 2
+		for end < len(sp.s) && sp.s[end] != '\'' {
+			if sp.s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(sp.s) {
+			sp.incomplete = true
+			end = len(sp.s)
+		}
+		sp.raw.WriteString(sp.s[sp.i:end])
+		sp.raw.WriteByte('\'')
+		sp.value.WriteString(decodeANSI(sp.s[sp.i+2 : end]))
+		sp.inWord, sp.quoted = true, true
+		sp.i = end + 1
+	case '"':
+		// Locale translation, $"...", is a double-quoted string otherwise
+		sp.i++
+		sp.readDouble()
+	default:
+		sp.readVariable(false)
+	}
+}
+
+// readVariable reads the $NAME, ${...}, or $ at sp.i. Outside quotes $IFS
+// splits the word, as its default value is whitespace; the positional
+// parameters are empty and $0 is the shell.
+func (sp *splitter) readVariable(quoted bool) {
+	rest := sp.s[sp.i+1:]
+	var name string
+	n := 0
+	switch {
+	case strings.HasPrefix(rest, "{"):
+		end := sp.matchBrace(sp.i + 2)
+		body := sp.s[sp.i+2 : end]
+		// ${x:-$(cmd)} runs cmd
+		inner := &splitter{s: body, depth: sp.depth}
+		inner.substitutions()
+		sp.pending = append(sp.pending, inner.pending...)
+		sp.incomplete = sp.incomplete || inner.incomplete
+		n = min(end+1, len(sp.s)) - sp.i - 1
+		name = body
+		for k := 0; k < len(body); k++ {
+			if !isNameByte(body[k]) {
+				name = body[:max(k, 1)]
+				break
+			}
+		}
+	case rest != "" && strings.IndexByte("@*#?$!-0123456789", rest[0]) >= 0:
+		name, n = rest[:1], 1
+	default:
+		for n < len(rest) && isNameByte(rest[n]) {
+			n++
+		}
+		name = rest[:n]
+	}
+
+	switch {
+	case n == 0:
+		sp.write("$")
+	case name == "IFS" && !quoted:
+		sp.endWord()
+	case name == "@" || name == "*" || (len(name) == 1 && name[0] >= '1' && name[0] <= '9'):
+		sp.raw.WriteString(sp.s[sp.i : sp.i+1+n])
+		sp.inWord = true
+	case name == "0":
+		sp.raw.WriteString(sp.s[sp.i : sp.i+1+n])
+		sp.value.WriteString("bash")
+		sp.inWord = true
+	case name == "#":
+		sp.raw.WriteString(sp.s[sp.i : sp.i+1+n])
+		sp.value.WriteString("0")
+		sp.inWord = true
+	default:
+		sp.write(sp.s[sp.i : sp.i+1+n])
+	}
+	sp.i += 1 + n
+}
+
+// readRedirect reads a redirection operator and its target as one word
+func (sp *splitter) readRedirect() {
+	// A file descriptor number is part of the operator, 2>&1
+	if sp.inWord && strings.Trim(sp.raw.String(), "0123456789") != "" {
+		sp.endWord()
+	}
+	start := sp.i
+	if sp.s[sp.i] == '&' {
+		sp.i++
+	}
+	for sp.i < len(sp.s) && (sp.s[sp.i] == '<' || sp.s[sp.i] == '>') {
+		sp.i++
+	}
+	op := sp.s[start:sp.i]
+	heredoc := strings.HasSuffix(op, "<<") && !strings.HasSuffix(op, "<<<")
+	switch {
+	case heredoc && sp.peek(0) == '-':
+		sp.strip = true
+		sp.i++
+	case sp.peek(0) == '&' || (op == ">" && sp.peek(0) == '|'):
+		sp.i++
+	}
+	if heredoc {
+		sp.heredoc = sp.value.Len() + sp.i - start
+	}
+	sp.write(sp.s[start:sp.i])
+	sp.redirect = true
+	if sp.peek(0) == ' ' || sp.peek(0) == '\t' {
+		sp.raw.WriteByte(' ') // Keeps < <(cmd) from reading <<(cmd)
+		sp.gap = sp.raw.Len()
+	}
+	for sp.i < len(sp.s) && (sp.s[sp.i] == ' ' || sp.s[sp.i] == '\t') {
+		sp.i++
+	}
+}
+
+// readHeredocs skips the bodies of the here-documents started on the line
+// just ended, splitting the substitutions in those the shell expands
+func (sp *splitter) readHeredocs() {
+	docs := sp.heredocs
+	sp.heredocs = nil
+	for _, doc := range docs {
+		start := sp.i
+		closed := false
+		for sp.i < len(sp.s) {
+			end := strings.IndexByte(sp.s[sp.i:], '\n')
+			if end < 0 {
+				end = len(sp.s)
+			} else {
+				end += sp.i
+			}
+			line := sp.s[sp.i:end]
+			if doc.strip {
+				line = strings.TrimLeft(line, "\t")
+			}
+			bodyEnd := sp.i
+			sp.i = min(end+1, len(sp.s))
+			if line == doc.delim {
+				closed = true
+				if doc.expand {
+					body := &splitter{s: sp.s[start:bodyEnd], depth: sp.depth}
+					body.substitutions()
+					sp.out = append(sp.out, body.pending...)
+					sp.incomplete = sp.incomplete || body.incomplete
+				}
+				break
+			}
+		}
+		if !closed {
+			sp.incomplete = true
+		}
+	}
+}
+
+// substitutions splits the $( ) and backtick substitutions in text where
+// quotes have no meaning, such as a here-document's body
+func (sp *splitter) substitutions() {
+	for sp.i < len(sp.s) {
+		switch c := sp.s[sp.i]; {
+		case c == '\\':
+			sp.i += 2
+		case c == '$' && sp.peek(1) == '(':
+			end := sp.matchParen(sp.i + 2)
+			sp.nest(sp.s[sp.i+2 : end])
+			sp.i = end + 1
+		case c == '`':
+			end := sp.closingBacktick(sp.i + 1)
+			sp.nest(sp.s[sp.i+1 : end])
+			sp.i = end + 1
+		default:
+			sp.i++
+		}
+	}
+}
+
+// closing returns the index of the next c at or after start, or the end of
+// the command when there is none
+func (sp *splitter) closing(start int, c byte) int {
+	if end := strings.IndexByte(sp.s[min(start, len(sp.s)):], c); end >= 0 {
+		return start + end
+	}
+	sp.incomplete = true
+	return len(sp.s)
+}
+
+func (sp *splitter) closingBacktick(start int) int {
+	for i := start; i < len(sp.s); i++ {
+		switch sp.s[i] {
+		case '\\':
+			i++
+		case '`':
+			return i
+		}
+	}
+	sp.incomplete = true
+	return len(sp.s)
+}
+
+// matchParen returns the index of the ) closing the ( before start
+func (sp *splitter) matchParen(start int) int {
+	depth := 1
+	for i := start; i < len(sp.s); i++ {
+		switch c := sp.s[i]; c {
+		case '\\':
+			i++
+		case '\'':
+			i = sp.closing(i+1, '\'')
+		case '"':
+			i = sp.closingDouble(i + 1)
+		case '`':
+			i = sp.closingBacktick(i + 1)
+		case '#':
+			if i == start || strings.IndexByte(" \t\n;&|(", sp.s[i-1]) >= 0 {
+				for i < len(sp.s) && sp.s[i] != '\n' {
+					i++
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	sp.incomplete = true
+	return len(sp.s)
+}
+
+// matchBrace returns the index of the } closing the ${ before start
+func (sp *splitter) matchBrace(start int) int {
+	depth := 1
+	for i := start; i < len(sp.s); i++ {
+		switch sp.s[i] {
+		case '\\':
+			i++
+		case '\'':
+			i = sp.closing(i+1, '\'')
+		case '"':
+			i = sp.closingDouble(i + 1)
+		case '`':
+			i = sp.closingBacktick(i + 1)
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	sp.incomplete = true
+	return len(sp.s)
+}
+
+func (sp *splitter) closingDouble(start int) int {
+	for i := start; i < len(sp.s); i++ {
+		switch sp.s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		case '`':
+			i = sp.closingBacktick(i + 1)
+		case '$':
+			if i+1 < len(sp.s) && sp.s[i+1] == '(' {
+				i = sp.matchParen(i + 2)
+			}
+		}
+	}
+	sp.incomplete = true
+	return len(sp.s)
+}
+
+// arguments are the words of a command that aren't redirections
+func arguments(words []word) []word {
+	var args []word
+	for _, w := range words {
+		if !w.redirect {
+			args = append(args, w)
+		}
+	}
+	return args
+}
+
+func values(words []word) []string {
+	vals := make([]string, len(words))
+	for i, w := range words {
+		vals[i] = w.value
+	}
+	return vals
+}
+
+// text writes a simple command back out with its redirections last and its
+// executable unquoted, so the command's own words come first
+func text(words []word) string {
+	var parts, redirects []string
+	executable := true
+	for _, w := range words {
+		switch {
+		case w.redirect:
+			redirects = append(redirects, w.raw)
+		case executable && !IsAssignment(w.raw):
+			parts = append(parts, quoteCommand(w.value))
+			executable = false
+		default:
+			parts = append(parts, w.raw)
+		}
+	}
+	return strings.Join(append(parts, redirects...), " ")
+}
+
+// quoteCommand writes s as a word that runs s in a command's first position
+func quoteCommand(s string) string {
+	safe := s != "" && !slices.Contains(reserved, s) && s != "function" && !IsAssignment(s)
+	for i := 0; safe && i < len(s); i++ {
+		safe = isNameByte(s[i]) || strings.IndexByte("-+/.,:@%^~", s[i]) >= 0
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// decodeANSI returns the text of a $'...' string
+func decodeANSI(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'e', 'E':
+			b.WriteByte(0x1b)
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case 'c':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i] & 0x1f)
+			}
+		case 'x', 'u', 'U', '0', '1', '2', '3', '4', '5', '6', '7':
+			base, digits, start := 16, map[byte]int{'x': 2, 'u': 4, 'U': 8}[c], i+1
+			if digits == 0 {
+				base, digits, start = 8, 3, i
+			}
+			end := start
+			for end < len(s) && end-start < digits && isDigit(s[end], base) {
+				end++
+			}
+			n, err := strconv.ParseUint(s[start:end], base, 32)
+			if err != nil {
+				b.WriteByte('\\')
+				b.WriteByte(c)
+				continue
+			}
+			if c == 'u' || c == 'U' {
+				b.WriteRune(rune(n))
+			} else {
+				b.WriteByte(byte(n))
+			}
+			i = end - 1
+		default:
+			b.WriteByte(c) // \\, \', \", \?
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte, base int) bool {
+	if base == 8 {
+		return c >= '0' && c <= '7'
+	}
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isName(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,229 @@
+package shell
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// bypasses are ways of running mysql that a check splitting on operators, or
+// looking only at the first word, would miss
+var bypasses = []string{
+	`ls && mysql -u root`,
+	`ls || mysql`,
+	`ls; mysql`,
+	`ls & mysql`,
+	"ls\nmysql",
+	`ls | mysql`,
+	`ls |& mysql`,
+	`'my'sql -e 'select 1'`,
+	`"my"sql`,
+	`m\ysql`,
+	`\mysql`,
+	`$'\x6dysql'`,
+	`$'\155ysql'`,
+	`$"mysql"`,
+	`my$@sql`,
+	`mysql${IFS}-e${IFS}x`,
+	`mysql$IFS-e`,
+	"mysql\\\n -e x",
+	`echo $(mysql -e 'select 1')`,
+	"echo `mysql`",
+	`echo "$(mysql)"`,
+	`echo "${x:-$(mysql)}"`,
+	`echo $(echo $(mysql))`,
+	`diff <(mysql) x`,
+	`tee >(mysql)`,
+	`(mysql)`,
+	`( cd db && mysql )`,
+	`{ mysql; }`,
+	`if true; then mysql; fi`,
+	`while true; do mysql; done`,
+	`! mysql`,
+	`f() { mysql; }; f`,
+	`function f { mysql; }`,
+	`case x in x) mysql;; esac`,
+	`bash -c "mysql -e 'select 1'"`,
+	`sh -ec 'cd /; mysql'`,
+	`bash -o pipefail -c mysql`,
+	`$0 -c mysql`,
+	`/bin/sh -c "sh -c 'mysql'"`,
+	`eval "mysql -e x"`,
+	`eval 'my''sql'`,
+	`MYSQL_PWD=x mysql`,
+	`env MYSQL_PWD=x mysql`,
+	`env -i PATH=/usr/bin mysql`,
+	`sudo -u root mysql`,
+	`command mysql`,
+	`exec mysql`,
+	`nohup mysql &`,
+	`time mysql`,
+	`nice -n 10 mysql`,
+	`timeout 5 mysql`,
+	`timeout -s KILL 5s mysql`,
+	`echo x | xargs -I{} mysql {}`,
+	`watch mysql -e 1`,
+	`watch -n 5 'mysql -e 1'`,
+	`sh <<< "mysql -e 1"`,
+	`sudo bash -s <<<'mysql'`,
+	`find . -name '*.sql' -exec mysql -e {} \;`,
+	`find . -execdir sh -c 'mysql < "$1"' _ {} +`,
+	`>out mysql`,
+	`2>/dev/null mysql`,
+	"cat <<EOF | sh\nx\nEOF\nmysql",
+	"cat <<EOF\n$(mysql)\nEOF",
+	"cat <<-EOF\n\t`mysql`\n\tEOF",
+	`/usr/bin/mysql`,
+	`MySQL`,
+}
+
+// runsMySQL tells whether a sub-command runs mysql
+func runsMySQL(sub string) bool {
+	words := Words(sub)
+	return len(words) > 0 && strings.ToLower(filepath.Base(words[0])) == "mysql"
+}
+
+func TestSplitBypasses(t *testing.T) {
+	for _, command := range bypasses {
+		subs := Split(command)
+		if !slices.ContainsFunc(subs, runsMySQL) {
+			t.Errorf("Expected mysql among the commands of %q, got %q", command, subs)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{`go test ./... && git push`, []string{"go test ./...", "git push"}},
+		{`git commit -m "fix: a && b; c | d"`, []string{`git commit -m "fix: a && b; c | d"`}},
+		{`echo 'it''s (fine)'`, []string{`echo 'it''s (fine)'`}},
+		{`ls 2>&1 >out | grep -v x`, []string{"ls 2>&1 >out", "grep -v x"}},
+		{`>out 'my'sql -e x`, []string{"mysql -e x >out"}},
+		{`echo hi # ; mysql`, []string{"echo hi"}},
+		{"cat <<'EOF'\n$(mysql)\nrm -rf /\nEOF\nls", []string{"cat <<'EOF'", "ls"}},
+		{`sudo rm -rf /`, []string{"sudo rm -rf /", "rm -rf /"}},
+		{`command -v mysql`, []string{"command -v mysql"}},
+		{`a=(x y) echo`, []string{"a=(x y) echo", "echo"}},
+		{`'if' x`, []string{"'if' x"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := Split(tt.command); !slices.Equal(got, tt.want) {
+			t.Errorf("Split(%q) = %q, expected %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{`git commit -m "a b"`, []string{"git", "commit", "-m", "a b"}},
+		{`rm '-rf' x`, []string{"rm", "-rf", "x"}},
+		{`echo "$HOME" '$HOME' \$HOME`, []string{"echo", "$HOME", "$HOME", "$HOME"}},
+		{`ls >out 2>&1 -la`, []string{"ls", "-la"}},
+		{`echo "a\"b" 'c\d'`, []string{"echo", `a"b`, `c\d`}},
+		{`printf $'a\tbé'`, []string{"printf", "a\tbé"}},
+		{`ls; rm x`, []string{"ls"}},
+	}
+	for _, tt := range tests {
+		if got := Words(tt.command); !slices.Equal(got, tt.want) {
+			t.Errorf("Words(%q) = %q, expected %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestWritesFile(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{`ls > out`, true},
+		{`echo x >>log`, true},
+		{`go test ./... 2>err.txt`, true},
+		{`make &> build.log`, true},
+		{`ls 2>&1`, false},
+		{`ls >/dev/null 2>&1`, false},
+		{`echo x >&2`, false},
+		{`make >& build.log`, true},
+		{`wc -l < in`, false},
+		{`cat <<EOF
+x
+EOF`, false},
+		{`grep ">" file`, false},
+	}
+	for _, tt := range tests {
+		if got := WritesFile(tt.command); got != tt.want {
+			t.Errorf("WritesFile(%q) = %v, expected %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	tests := []struct {
+		words       string
+		assignments []string
+		command     []string
+	}{
+		{"psql -h db", nil, []string{"psql", "-h", "db"}},
+		{"A=1 B=2 psql", []string{"A=1", "B=2"}, []string{"psql"}},
+		{"sudo -u postgres env PGHOST=db psql", []string{"PGHOST=db"}, []string{"psql"}},
+		{"timeout --signal KILL 30 /usr/bin/psql", nil, []string{"/usr/bin/psql"}},
+		{"nice -- psql", nil, []string{"psql"}},
+		{"command -v psql", nil, []string{"command", "-v", "psql"}},
+		{"env", nil, nil},
+	}
+	for _, tt := range tests {
+		assignments, command := Unwrap(strings.Fields(tt.words))
+		if !slices.Equal(assignments, tt.assignments) || !slices.Equal(command, tt.command) {
+			t.Errorf("Unwrap(%q) = %q, %q; expected %q, %q", tt.words, assignments, command, tt.assignments, tt.command)
+		}
+	}
+}
+
+func TestIsAssignment(t *testing.T) {
+	for word, want := range map[string]bool{
+		"A=1": true, "_a=": true, "PATH+=:/x": true, "arr[2]=x": true,
+		"=x": false, "1A=x": false, "a-b=x": false, "--flag=x": false, "ls": false,
+	} {
+		if got := IsAssignment(word); got != want {
+			t.Errorf("IsAssignment(%q) = %v, expected %v", word, got, want)
+		}
+	}
+}
+
+func FuzzSplit(f *testing.F) {
+	for _, command := range bypasses {
+		f.Add(command)
+	}
+	for _, command := range []string{`git commit -m "a && b"`, "cat <<EOF\nx\nEOF", `echo "${a[@]}" ${#a}`, `a=$((1 + (2 * 3)))`} {
+		f.Add(command)
+	}
+	f.Fuzz(func(t *testing.T, command string) {
+		sp := &splitter{s: command}
+		sp.run()
+		for _, sub := range sp.out {
+			if sub == "" || strings.TrimLeft(sub, " \t\n") != sub {
+				t.Fatalf("Split(%q) has an empty command or one starting with whitespace, %q", command, sub)
+			}
+		}
+		if sp.incomplete {
+			return // The shell would refuse to run it
+		}
+		// Each command stands on its own: splitting it finds it again
+		for _, sub := range sp.out {
+			if again := Split(sub); !slices.Contains(again, sub) {
+				t.Fatalf("Split(%q) has %q, which splits into %q", command, sub, again)
+			}
+		}
+		// Nothing before a newline hides a command after it
+		if subs := Split(command + "\nmysql"); !slices.ContainsFunc(subs, runsMySQL) {
+			t.Fatalf("Split(%q + newline + mysql) = %q, missing mysql", command, subs)
+		}
+	})
+}
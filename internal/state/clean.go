@@ -0,0 +1,263 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CleanOptions controls which runtime state Clean removes
+type CleanOptions struct {
+	MaxAge        time.Duration // Cache and log files not modified for this long are removed
+	MaxSize       int64         // Oldest cache and log files are removed until the total is under this; 0 means no limit
+	SessionMaxAge time.Duration // Sessions untouched for this long are removed even if their transcript still exists
+	DryRun        bool          // Report what would be removed without removing it
+}
+
+// DefaultCleanOptions is what automatic cleanup and `claude-hook clean` use
+var DefaultCleanOptions = CleanOptions{
+	MaxAge:        30 * 24 * time.Hour,
+	MaxSize:       512 << 20,
+	SessionMaxAge: 7 * 24 * time.Hour,
+}
+
+// CleanResult describes what Clean removed (or would remove, for a dry run)
+type CleanResult struct {
+	Removed []string
+	Freed   int64
+}
+
+// autoCleanInterval is how often AutoClean does any work
+const autoCleanInterval = 24 * time.Hour
+
+// autoCleanMarker's modification time records the last automatic cleanup
+const autoCleanMarker = "last-clean"
+
+// staleFile is a removable cache or log file considered for size-based GC
+type staleFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// tempMaxAge is how old a temporary file must be to count as left behind by a
+// write that crashed, rather than one in progress
+const tempMaxAge = time.Hour
+
+// Clean garbage-collects runtime state: directories of projects that no
+// longer exist, orphaned sessions, cache and log files that are too old or
+// push the total over MaxSize, and what crashes leave behind. Lock files are
+// only removed along with their whole project, since deleting a lock another
+// process holds would let a third one take it concurrently.
+func Clean(opts CleanOptions) (CleanResult, error) {
+	var result CleanResult
+	root, err := Dir()
+	if err != nil {
+		return result, err
+	}
+	cleanCrashLeftovers(root, time.Now(), opts, &result)
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return result, fmt.Errorf("reading state directory: %w", err)
+	}
+
+	now := time.Now()
+	var files []staleFile
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		projectDir := filepath.Join(root, entry.Name())
+		marker, err := os.ReadFile(filepath.Join(projectDir, projectMarker))
+		if err != nil {
+			continue // Not a project directory
+		}
+
+		if _, err := os.Stat(strings.TrimSpace(string(marker))); errors.Is(err, os.ErrNotExist) {
+			result.remove(projectDir, opts.DryRun)
+			continue
+		}
+
+		cleanSessions(filepath.Join(projectDir, Sessions), now, opts, &result)
+
+		for _, kind := range []string{Cache, Logs} {
+			_ = filepath.WalkDir(filepath.Join(projectDir, kind), func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return nil
+				}
+				info, err := d.Info()
+				if err != nil {
+					return nil
+				}
+				if opts.MaxAge > 0 && now.Sub(info.ModTime()) > opts.MaxAge {
+					result.remove(path, opts.DryRun)
+					return nil
+				}
+				files = append(files, staleFile{path: path, size: info.Size(), modTime: info.ModTime()})
+				return nil
+			})
+		}
+	}
+
+	if opts.MaxSize > 0 {
+		var total int64
+		for _, f := range files {
+			total += f.size
+		}
+		slices.SortFunc(files, func(a, b staleFile) int { return a.modTime.Compare(b.modTime) })
+		for _, f := range files {
+			if total <= opts.MaxSize {
+				break
+			}
+			result.remove(f.path, opts.DryRun)
+			total -= f.size
+		}
+	}
+
+	return result, nil
+}
+
+// cleanCrashLeftovers removes the temporary files of atomic writes that
+// never finished, and state files Quarantine moved aside once they're older
+// than MaxAge
+func cleanCrashLeftovers(root string, now time.Time, opts CleanOptions, result *CleanResult) {
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		maxAge := opts.MaxAge
+		switch {
+		case strings.HasSuffix(path, ".tmp"):
+			maxAge = tempMaxAge
+		case strings.HasSuffix(path, corruptSuffix) && opts.MaxAge > 0:
+		default:
+			return nil
+		}
+		if info, err := d.Info(); err == nil && now.Sub(info.ModTime()) > maxAge {
+			result.remove(path, opts.DryRun)
+		}
+		return nil
+	})
+}
+
+// cleanSessions removes sessions whose transcript is gone or that haven't been
+// used within SessionMaxAge
+func cleanSessions(sessionsDir string, now time.Time, opts CleanOptions, result *CleanResult) {
+	sessions, err := os.ReadDir(sessionsDir)
+	if err != nil {
+		return
+	}
+	for _, session := range sessions {
+		path := filepath.Join(sessionsDir, session.Name())
+		info, err := session.Info()
+		if err != nil || !session.IsDir() {
+			continue
+		}
+
+		orphaned := opts.SessionMaxAge > 0 && now.Sub(info.ModTime()) > opts.SessionMaxAge
+		if transcript, err := os.ReadFile(filepath.Join(path, sessionMarker)); err == nil {
+			if _, err := os.Stat(strings.TrimSpace(string(transcript))); errors.Is(err, os.ErrNotExist) {
+				orphaned = true
+			}
+		}
+		if orphaned {
+			result.remove(path, opts.DryRun)
+		}
+	}
+}
+
+// remove deletes path (recursively) and records what was freed
+func (r *CleanResult) remove(path string, dryRun bool) {
+	size := diskUsage(path)
+	if !dryRun {
+		if err := os.RemoveAll(path); err != nil {
+			return
+		}
+	}
+	r.Removed = append(r.Removed, path)
+	r.Freed += size
+}
+
+func diskUsage(path string) int64 {
+	var total int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+// AutoClean runs Clean with the default options at most once a day. Concurrent
+// callers skip rather than wait, so it is cheap to call from every session start.
+func AutoClean() (CleanResult, error) {
+	markerPath, err := Path(autoCleanMarker)
+	if err != nil {
+		return CleanResult{}, err
+	}
+	if info, err := os.Stat(markerPath); err == nil && time.Since(info.ModTime()) < autoCleanInterval {
+		return CleanResult{}, nil
+	}
+
+	lockPath, err := Path("clean.lock")
+	if err != nil {
+		return CleanResult{}, err
+	}
+	unlock, err := TryLock(lockPath)
+	if errors.Is(err, ErrLocked) {
+		return CleanResult{}, nil
+	}
+	if err != nil {
+		return CleanResult{}, err
+	}
+	defer unlock()
+
+	result, err := Clean(DefaultCleanOptions)
+	if err != nil {
+		return result, err
+	}
+	if err := os.WriteFile(markerPath, nil, 0o644); err != nil {
+		return result, fmt.Errorf("writing clean marker: %w", err)
+	}
+	now := time.Now()
+	_ = os.Chtimes(markerPath, now, now)
+	return result, nil
+}
+
+// ParseSize parses sizes like "512MB", "2G", or "1048576" (bytes)
+func ParseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		scale  int64
+	}{
+		{"GB", 1 << 30}, {"G", 1 << 30},
+		{"MB", 1 << 20}, {"M", 1 << 20},
+		{"KB", 1 << 10}, {"K", 1 << 10},
+		{"B", 1},
+	}
+
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	scale := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper, scale = strings.TrimSuffix(upper, u.suffix), u.scale
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(upper), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 512MB or 2G)", s)
+	}
+	return int64(n * float64(scale)), nil
+}
@@ -0,0 +1,144 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	modTime := time.Now().Add(-age)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set mtime of %s: %v", path, err)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestProjectPathSharesRepoRoot(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(repo, "pkg", "sub")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := ProjectPath(repo, Cache, "x")
+	if err != nil {
+		t.Fatalf("ProjectPath failed: %v", err)
+	}
+	b, err := ProjectPath(sub, Cache, "x")
+	if err != nil {
+		t.Fatalf("ProjectPath failed: %v", err)
+	}
+	if a != b {
+		t.Errorf("Expected subdirectories to share the repo's state, got %s and %s", a, b)
+	}
+
+	if _, err := SessionPath(repo, "../escape", "", "x"); err == nil {
+		t.Error("Expected a session id with a path separator to be rejected")
+	}
+}
+
+func TestClean(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	project := t.TempDir()
+
+	oldCache, _ := ProjectPath(project, Cache, "old")
+	writeFile(t, oldCache, 10, 40*24*time.Hour)
+	bigCache, _ := ProjectPath(project, Cache, "big")
+	writeFile(t, bigCache, 2000, 2*time.Hour)
+	newLog, _ := ProjectPath(project, Logs, "new")
+	writeFile(t, newLog, 100, time.Hour)
+	lock, _ := ProjectPath(project, Locks, "held.lock")
+	writeFile(t, lock, 0, 40*24*time.Hour)
+
+	transcript := filepath.Join(t.TempDir(), "session.jsonl")
+	writeFile(t, transcript, 1, 0)
+	live, _ := SessionPath(project, "live", transcript, "result.json")
+	writeFile(t, live, 1, 0)
+	gone, _ := SessionPath(project, "gone", filepath.Join(t.TempDir(), "deleted.jsonl"), "result.json")
+	writeFile(t, gone, 1, 0)
+
+	crashedWrite, _ := Path("heartbeat.json.123.tmp")
+	writeFile(t, crashedWrite, 10, 2*time.Hour)
+	pendingWrite, _ := Path("heartbeat.json.456.tmp")
+	writeFile(t, pendingWrite, 10, time.Minute)
+	oldCorrupt := filepath.Join(filepath.Dir(live), "budget.json.corrupt")
+	writeFile(t, oldCorrupt, 10, 40*24*time.Hour)
+	newCorrupt := filepath.Join(filepath.Dir(live), "repeats.json.corrupt")
+	writeFile(t, newCorrupt, 10, time.Hour)
+
+	deletedProject := filepath.Join(t.TempDir(), "deleted")
+	if err := os.MkdirAll(deletedProject, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	deletedDir, _ := ProjectDir(deletedProject)
+	if err := os.RemoveAll(deletedProject); err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := Clean(CleanOptions{MaxAge: 30 * 24 * time.Hour, MaxSize: 1000, SessionMaxAge: 7 * 24 * time.Hour, DryRun: true})
+	if err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if len(dry.Removed) == 0 || !exists(oldCache) {
+		t.Errorf("Expected a dry run to report without removing, got %v", dry.Removed)
+	}
+
+	if _, err := Clean(CleanOptions{MaxAge: 30 * 24 * time.Hour, MaxSize: 1000, SessionMaxAge: 7 * 24 * time.Hour}); err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	for path, want := range map[string]bool{
+		oldCache:           false, // Too old
+		bigCache:           false, // Oldest file over the size limit
+		newLog:             true,
+		lock:               true, // Locks are never collected on their own
+		live:               true,
+		filepath.Dir(gone): false, // Transcript deleted
+		deletedDir:         false, // Project deleted
+		crashedWrite:       false, // Left by a crash
+		pendingWrite:       true,  // Possibly still being written
+		oldCorrupt:         false,
+		newCorrupt:         true, // Kept for inspection
+	} {
+		if exists(path) != want {
+			t.Errorf("Expected %s to exist=%v after clean", path, want)
+		}
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget.json")
+	writeFile(t, path, 3, 0)
+	if err := Quarantine(path); err != nil {
+		t.Fatal(err)
+	}
+	if exists(path) || !exists(path+".corrupt") {
+		t.Error("Expected the file moved aside")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{"512MB": 512 << 20, "2g": 2 << 30, "1.5K": 1536, "100": 100, "0": 0}
+	for input, want := range tests {
+		got, err := ParseSize(input)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; expected %d", input, got, err, want)
+		}
+	}
+	if _, err := ParseSize("lots"); err == nil {
+		t.Error("Expected an error for an invalid size")
+	}
+}
@@ -0,0 +1,77 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLocked is returned by TryLock when another process holds the lock
+var ErrLocked = errors.New("lock is held by another process")
+
+// lockPollInterval is how often Lock retries a contended lock
+const lockPollInterval = 100 * time.Millisecond
+
+// Lock blocks until it holds an exclusive lock on the file at path or ctx is done.
+// The returned function releases the lock.
+func Lock(ctx context.Context, path string) (func(), error) {
+	for {
+		unlock, err := TryLock(path)
+		if err == nil {
+			return unlock, nil
+		}
+		if !errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			if pid := LockHolder(path); pid != 0 {
+				return nil, fmt.Errorf("waiting for lock %s (held by pid %d): %w", path, pid, ctx.Err())
+			}
+			return nil, fmt.Errorf("waiting for lock %s: %w", path, ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// TryLock takes an exclusive lock on the file at path without blocking, and
+// records this process in it as the holder. It returns ErrLocked if the lock
+// is held elsewhere.
+func TryLock(path string) (func(), error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	// Only for telling who holds it. A crashed holder leaves no stale lock:
+	// the system releases it, or lockFile takes it over where there are no
+	// advisory locks.
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return func() {
+		_ = unlockFile(file)
+		_ = file.Close()
+	}, nil
+}
+
+// LockHolder returns the process that last took the lock at path, or 0 when
+// unknown. It's only meaningful while the lock is held.
+func LockHolder(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
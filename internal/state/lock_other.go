@@ -0,0 +1,53 @@
+//go:build !unix && !windows
+
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Without advisory locks, a lock is a "<lock file>.pid" file created
+// exclusively. One left by a holder that died is stale and taken over, where
+// processAlive can tell; elsewhere it stays until removed by hand.
+func lockFile(file *os.File) error {
+	pidPath := file.Name() + ".pid"
+	for attempt := 0; attempt < 2; attempt++ {
+		pidFile, err := os.OpenFile(pidPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, _ = pidFile.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			return pidFile.Close()
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("locking %s: %w", file.Name(), err)
+		}
+		data, err := os.ReadFile(pidPath)
+		if err != nil {
+			continue // Released meanwhile
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err == nil && processAlive(pid) {
+			return ErrLocked
+		}
+		_ = os.Remove(pidPath) // Stale: its holder is gone
+	}
+	return ErrLocked
+}
+
+func unlockFile(file *os.File) error {
+	return os.Remove(file.Name() + ".pid")
+}
+
+// processAlive reports whether a process with the pid exists. Where
+// os.FindProcess doesn't look the process up, every pid is alive.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func lockFile(file *os.File) error {
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, lockRegion())
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return ErrLocked
+	}
+	if err != nil {
+		return fmt.Errorf("locking %s: %w", file.Name(), err)
+	}
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, lockRegion())
+}
+
+// lockRegion addresses the locked byte, at 1<<62. Windows locks are
+// mandatory, so it sits far past the holder's pid, which LockHolder must
+// still be able to read.
+func lockRegion() *windows.Overlapped {
+	return &windows.Overlapped{OffsetHigh: 1 << 30}
+}
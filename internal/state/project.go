@@ -0,0 +1,160 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Kinds of per-project state, each kept in its own subdirectory of the
+// project's runtime directory
+const (
+	Cache     = "cache"     // Reusable results; safe to delete at any time
+	Locks     = "locks"     // Lock files for work that must not overlap within a project
+	Sessions  = "sessions"  // One directory per Claude session
+	Logs      = "logs"      // Output kept for inspection
+	Rollbacks = "rollbacks" // Working tree snapshots for claude-hook rollback
+)
+
+// projectMarker records which project a runtime directory belongs to, so clean
+// can tell when the project itself is gone
+const projectMarker = "project"
+
+// sessionMarker records a session's transcript path, so clean can tell when the
+// session no longer exists
+const sessionMarker = "transcript"
+
+// ProjectRoot returns the root of the repository (git, jj, or hg) containing
+// dir, or dir itself outside a repo. A dir reached through a symlink that isn't in a repo where it's
+// linked from belongs to the repo of its target.
+func ProjectRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	if root := repoRoot(abs); root != "" {
+		return root
+	}
+	if real := RealPath(abs); real != abs {
+		if root := repoRoot(real); root != "" {
+			return root
+		}
+	}
+	return abs
+}
+
+// repoMarkers are the directories that make a directory a repository's root,
+// the ones vcs.FindRoot tells apart (vcs runs commands through proc, which
+// imports state)
+var repoMarkers = []string{".jj", ".git", ".hg"}
+
+// repoRoot returns the closest directory of dir or its parents with one of
+// repoMarkers, or ""
+func repoRoot(dir string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		for _, marker := range repoMarkers {
+			if _, err := os.Stat(filepath.Join(d, marker)); err == nil {
+				return d
+			}
+		}
+		if filepath.Dir(d) == d {
+			return ""
+		}
+	}
+}
+
+// RealPath returns the absolute path with every symlink resolved. A path
+// that doesn't exist yet, like a file about to be written, resolves through
+// its closest existing parent.
+func RealPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	var missing []string
+	for d := abs; ; d = filepath.Dir(d) {
+		if real, err := filepath.EvalSymlinks(d); err == nil {
+			return filepath.Join(append([]string{real}, missing...)...)
+		}
+		if filepath.Dir(d) == d {
+			return abs
+		}
+		missing = append([]string{filepath.Base(d)}, missing...)
+	}
+}
+
+// Within reports whether path is root or inside it once both are resolved,
+// so a symlink in root pointing elsewhere doesn't count
+func Within(root, path string) bool {
+	rel, err := filepath.Rel(RealPath(root), RealPath(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ProjectDir returns the runtime directory for the project containing dir,
+// <state dir>/<project-hash>, creating it if necessary
+func ProjectDir(dir string) (string, error) {
+	root, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	project := ProjectRoot(dir)
+	sum := sha256.Sum256([]byte(project))
+	projectDir := filepath.Join(root, hex.EncodeToString(sum[:8]))
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		return "", fmt.Errorf("creating project state directory: %w", err)
+	}
+
+	marker := filepath.Join(projectDir, projectMarker)
+	if _, err := os.Stat(marker); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(marker, []byte(project+"\n"), 0o644); err != nil {
+			return "", fmt.Errorf("writing project marker: %w", err)
+		}
+	}
+	return projectDir, nil
+}
+
+// ProjectPath returns the path of name in the given kind of state for the
+// project containing dir, creating its parent directory
+func ProjectPath(dir, kind, name string) (string, error) {
+	projectDir, err := ProjectDir(dir)
+	if err != nil {
+		return "", err
+	}
+	kindDir := filepath.Join(projectDir, kind)
+	if err := os.MkdirAll(kindDir, 0o755); err != nil {
+		return "", fmt.Errorf("creating %s directory: %w", kind, err)
+	}
+	return filepath.Join(kindDir, name), nil
+}
+
+// SessionPath returns the path of name in the state of one Claude session. Each
+// call marks the session as in use; transcriptPath, when known, lets clean drop
+// the session's state once Claude deletes the transcript.
+func SessionPath(dir, sessionID, transcriptPath, name string) (string, error) {
+	if sessionID == "" || sessionID != filepath.Base(sessionID) || sessionID == "." || sessionID == ".." {
+		return "", fmt.Errorf("invalid session id %q", sessionID)
+	}
+	sessionDir, err := ProjectPath(dir, Sessions, sessionID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(sessionDir, 0o755); err != nil {
+		return "", fmt.Errorf("creating session directory: %w", err)
+	}
+
+	if transcriptPath != "" {
+		if err := os.WriteFile(filepath.Join(sessionDir, sessionMarker), []byte(transcriptPath+"\n"), 0o644); err != nil {
+			return "", fmt.Errorf("writing session marker: %w", err)
+		}
+	}
+	now := time.Now()
+	_ = os.Chtimes(sessionDir, now, now)
+
+	return filepath.Join(sessionDir, name), nil
+}
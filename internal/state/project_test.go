@@ -0,0 +1,48 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRealPathAndWithin(t *testing.T) {
+	repo, elsewhere := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(elsewhere, filepath.Join(repo, "linked")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := RealPath(filepath.Join(repo, "linked", "new", "file.go")), filepath.Join(elsewhere, "new", "file.go"); got != want {
+		t.Errorf("Expected a missing file to resolve through its parent to %s, got %s", want, got)
+	}
+	if !Within(repo, filepath.Join(repo, "src", "main.go")) {
+		t.Error("Expected a file in the repository to be within it")
+	}
+	if Within(repo, filepath.Join(repo, "linked", "main.go")) {
+		t.Error("Expected a file behind a symlink out of the repository not to be within it")
+	}
+	if Within(repo, repo+"-sibling") {
+		t.Error("Expected a directory sharing the repository's prefix not to be within it")
+	}
+
+	// A symlink into the middle of another repository
+	if err := os.MkdirAll(filepath.Join(elsewhere, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(elsewhere, "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := ProjectRoot(filepath.Join(repo, "src")); got != repo {
+		t.Errorf("Expected the repository of a directory in it, got %s", got)
+	}
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(filepath.Join(elsewhere, "pkg"), link); err != nil {
+		t.Fatal(err)
+	}
+	if got := ProjectRoot(link); got != elsewhere {
+		t.Errorf("Expected a symlink outside any repository to belong to its target's, got %s", got)
+	}
+}
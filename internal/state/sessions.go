@@ -0,0 +1,67 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ActiveWithin is how recently a session must have run a hook to count as
+// still working in its project
+const ActiveWithin = 15 * time.Minute
+
+// writesLock serializes the steps that write to a project's working tree
+const writesLock = "writes.lock"
+
+// ActiveSessions returns the IDs of the sessions of the project containing
+// dir that ran a hook in the last within, other than except, sorted. A
+// session whose transcript is gone has ended.
+func ActiveSessions(dir, except string, within time.Duration) ([]string, error) {
+	sessionsDir, err := ProjectPath(dir, Sessions, "")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(sessionsDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading sessions: %w", err)
+	}
+	now := time.Now()
+	var active []string
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == except {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) > within {
+			continue
+		}
+		if transcript, err := os.ReadFile(filepath.Join(sessionsDir, entry.Name(), sessionMarker)); err == nil {
+			if _, err := os.Stat(strings.TrimSpace(string(transcript))); errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+		}
+		active = append(active, entry.Name())
+	}
+	slices.Sort(active)
+	return active, nil
+}
+
+// LockWrites blocks until it holds the project's lock on steps that write to
+// its working tree, like fixers and code generation, so concurrent sessions
+// take turns instead of clobbering each other's output. It returns ErrLocked
+// without waiting when wait is false.
+func LockWrites(ctx context.Context, dir string, wait bool) (func(), error) {
+	path, err := ProjectPath(dir, Locks, writesLock)
+	if err != nil {
+		return nil, err
+	}
+	if !wait {
+		return TryLock(path)
+	}
+	return Lock(ctx, path)
+}
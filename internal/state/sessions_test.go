@@ -0,0 +1,69 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestActiveSessions(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	project := t.TempDir()
+	transcript := filepath.Join(t.TempDir(), "b.jsonl")
+	writeFile(t, transcript, 1, 0)
+
+	for _, id := range []string{"self", "a", "b", "idle", "ended"} {
+		if _, err := SessionPath(project, id, "", "x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := SessionPath(project, "b", transcript, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SessionPath(project, "ended", filepath.Join(t.TempDir(), "gone.jsonl"), "x"); err != nil {
+		t.Fatal(err)
+	}
+	idle, _ := ProjectPath(project, Sessions, "idle")
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(idle, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := ActiveSessions(project, "self", ActiveWithin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(active, []string{"a", "b"}) {
+		t.Errorf("Expected the recent sessions but this one, idle, and ended ones, got %v", active)
+	}
+}
+
+func TestLockWrites(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	project := t.TempDir()
+
+	unlock, err := LockWrites(context.Background(), project, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockWrites(context.Background(), project, false); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected a second writer to find the lock held, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := LockWrites(ctx, project, true); err == nil || !strings.Contains(err.Error(), fmt.Sprintf("held by pid %d", os.Getpid())) {
+		t.Errorf("Expected waiting for a held lock to time out naming its holder, got %v", err)
+	}
+	unlock()
+	again, err := LockWrites(context.Background(), project, true)
+	if err != nil {
+		t.Fatalf("Expected the lock once released, got %v", err)
+	}
+	again()
+}
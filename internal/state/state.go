@@ -0,0 +1,49 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the directory where claude-hooks keeps state shared between
+// hook invocations, creating it if necessary.
+// CLAUDE_HOOKS_STATE_DIR overrides the default of <user cache dir>/claude-hooks.
+func Dir() (string, error) {
+	dir := os.Getenv("CLAUDE_HOOKS_STATE_DIR")
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("locating user cache directory: %w", err)
+		}
+		dir = filepath.Join(cacheDir, "claude-hooks")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating state directory: %w", err)
+	}
+
+	return dir, nil
+}
+
+// Path returns the path of name inside the state directory
+func Path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// corruptSuffix marks a state file moved aside by Quarantine
+const corruptSuffix = ".corrupt"
+
+// Quarantine moves a state file that no longer parses, say one cut short by a
+// crash, to <path>.corrupt, so the next run starts over instead of failing on
+// it too. The copy is kept for inspection until clean removes it.
+func Quarantine(path string) error {
+	if err := os.Rename(path, path+corruptSuffix); err != nil {
+		return fmt.Errorf("moving aside corrupt %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
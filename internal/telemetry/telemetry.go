@@ -0,0 +1,274 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/audit"
+	"github.com/brianleishman/claude-hooks/internal/network"
+	"github.com/brianleishman/claude-hooks/internal/reason"
+	"github.com/brianleishman/claude-hooks/internal/state"
+	"github.com/brianleishman/claude-hooks/internal/version"
+	"github.com/brianleishman/claude-hooks/internal/vlog"
+)
+
+// DisableEnv turns telemetry off regardless of the saved preference when set to 0, false, or off
+const DisableEnv = "CLAUDE_HOOKS_TELEMETRY"
+
+// EndpointEnv overrides the endpoint reports are posted to
+const EndpointEnv = "CLAUDE_HOOKS_TELEMETRY_ENDPOINT"
+
+// reportInterval is how often an aggregate report is sent at most
+const reportInterval = 24 * time.Hour
+
+// retryInterval spaces out attempts after a failed send
+const retryInterval = time.Hour
+
+// sendTimeout keeps a slow endpoint from delaying the hook that triggers a report
+const sendTimeout = 3 * time.Second
+
+// Preferences is the saved opt-in state. Telemetry is off until the user runs
+// `claude-hook telemetry enable`.
+type Preferences struct {
+	Enabled     bool      `json:"enabled"`
+	Endpoint    string    `json:"endpoint,omitempty"`
+	EnabledAt   time.Time `json:"enabled_at,omitzero"` // Events before opting in are never reported
+	LastSent    time.Time `json:"last_sent,omitzero"`
+	LastAttempt time.Time `json:"last_attempt,omitzero"`
+}
+
+// Report is the payload posted to the endpoint. It is built only from the audit
+// log's fixed identifiers (hook names, file types, built-in rule ids, durations) and
+// never includes paths, file contents, or commands.
+type Report struct {
+	Version     string           `json:"version"`
+	OS          string           `json:"os"`
+	Arch        string           `json:"arch"`
+	PeriodStart time.Time        `json:"period_start"`
+	PeriodEnd   time.Time        `json:"period_end"`
+	Hooks       map[string]int   `json:"hooks"`          // Invocations per hook type
+	Languages   map[string]int   `json:"languages"`      // Post-edit runs per validated file type
+	LatencyMS   map[string]int64 `json:"avg_latency_ms"` // Average wall time per hook type
+	Errors      map[string]int   `json:"errors"`         // Blocks and denials per built-in rule or family
+}
+
+// preferencesPath lives in the user config directory rather than the state
+// directory, so clearing caches never changes whether telemetry is on
+func preferencesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating user config directory: %w", err)
+	}
+	return filepath.Join(dir, "claude-hooks", "telemetry.json"), nil
+}
+
+// Load returns the saved preferences; a missing file means disabled
+func Load() (Preferences, error) {
+	var prefs Preferences
+	path, err := preferencesPath()
+	if err != nil {
+		return prefs, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return prefs, nil
+	}
+	if err != nil {
+		return prefs, fmt.Errorf("reading telemetry preferences: %w", err)
+	}
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return prefs, fmt.Errorf("parsing telemetry preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// Save writes the preferences
+func Save(prefs Preferences) error {
+	path, err := preferencesPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(prefs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling telemetry preferences: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing telemetry preferences: %w", err)
+	}
+	return nil
+}
+
+// Active reports whether reports should be sent, and where
+func (p Preferences) Active() (string, bool) {
+	switch strings.ToLower(os.Getenv(DisableEnv)) {
+	case "0", "false", "off":
+		return "", false
+	}
+	endpoint := p.Endpoint
+	if override := os.Getenv(EndpointEnv); override != "" {
+		endpoint = override
+	}
+	return endpoint, p.Enabled && endpoint != ""
+}
+
+// periodStart is the earliest event the next report may include
+func (p Preferences) periodStart() time.Time {
+	if p.LastSent.After(p.EnabledAt) {
+		return p.LastSent
+	}
+	return p.EnabledAt
+}
+
+// Build aggregates audit events into a report
+func Build(events []audit.Event, start, end time.Time) Report {
+	report := Report{
+		Version:     version.Current(),
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		PeriodStart: start.UTC().Truncate(time.Hour),
+		PeriodEnd:   end.UTC().Truncate(time.Hour),
+		Hooks:       make(map[string]int),
+		Languages:   make(map[string]int),
+		LatencyMS:   make(map[string]int64),
+		Errors:      make(map[string]int),
+	}
+
+	totalMS := make(map[string]int64)
+	for _, ev := range events {
+		report.Hooks[ev.Hook]++
+		totalMS[ev.Hook] += ev.DurationMS
+		for _, lang := range ev.Languages {
+			report.Languages[lang]++
+		}
+		if ev.Rule != "" {
+			family := ""
+			for rule := range strings.SplitSeq(ev.Rule, ",") {
+				// content:a,b lists b in a's family
+				if f, _, ok := strings.Cut(rule, ":"); ok {
+					family = f + ":"
+				} else {
+					rule = family + rule
+				}
+				report.Errors[category(rule)]++
+			}
+		}
+	}
+	for hook, total := range totalMS {
+		report.LatencyMS[hook] = total / int64(report.Hooks[hook])
+	}
+	return report
+}
+
+// category is what a rule is counted under: built-in rule ids as they are,
+// bash.rules and policies by family alone since their names are the
+// project's, and anything else, like a custom hook's name, as "other"
+func category(rule string) string {
+	for _, family := range []string{"bash-rule", "policy"} {
+		if strings.HasPrefix(rule, family+":") {
+			return family
+		}
+	}
+	if _, ok := reason.LookupRule(rule); ok {
+		return rule
+	}
+	return "other"
+}
+
+// Pending builds the report that would be sent next, covering events since the
+// last report (or since opting in)
+func Pending(prefs Preferences, now time.Time) (Report, error) {
+	start := prefs.periodStart()
+	events, err := audit.Read(start)
+	if err != nil {
+		return Report{}, err
+	}
+	return Build(events, start, now), nil
+}
+
+// MaybeSend posts a report when telemetry is enabled and the last one is more
+// than a day old. Only one concurrent hook sends; the rest return immediately.
+func MaybeSend(verbose bool) error {
+	prefs, err := Load()
+	if err != nil {
+		return err
+	}
+	endpoint, ok := prefs.Active()
+	if !ok || time.Since(prefs.LastSent) < reportInterval || time.Since(prefs.LastAttempt) < retryInterval {
+		return nil
+	}
+
+	lockPath, err := state.Path("telemetry.lock")
+	if err != nil {
+		return err
+	}
+	unlock, err := state.TryLock(lockPath)
+	if errors.Is(err, state.ErrLocked) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	now := time.Now()
+	report, err := Pending(prefs, now)
+	if err != nil {
+		return err
+	}
+	if len(report.Hooks) > 0 {
+		if err := send(endpoint, report); err != nil {
+			prefs.LastAttempt = now
+			_ = Save(prefs)
+			return err
+		}
+		vlog.Printf(verbose, "📊 Sent anonymous usage report to %s\n", endpoint)
+	}
+
+	prefs.LastSent, prefs.LastAttempt = now, now
+	return Save(prefs)
+}
+
+func send(endpoint string, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("marshaling telemetry report: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating telemetry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client, err := network.Client(0)
+	if err != nil {
+		return fmt.Errorf("sending telemetry report: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending telemetry report: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("sending telemetry report: %s", resp.Status)
+	}
+	return nil
+}
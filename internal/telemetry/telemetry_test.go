@@ -0,0 +1,133 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/audit"
+)
+
+func TestBuild(t *testing.T) {
+	now := time.Now()
+	events := []audit.Event{
+		{Hook: "post-edit", Decision: "allow", DurationMS: 100, Languages: []string{"go", "ts"}},
+		{Hook: "post-edit", Decision: "block", DurationMS: 300, Languages: []string{"go"}, Rule: "go-post-edit,typescript-post-edit"},
+		{Hook: "pre-bash", Decision: "deny", DurationMS: 5, Rule: "mysql-cli"},
+	}
+
+	report := Build(events, now.Add(-time.Hour), now)
+	if report.Hooks["post-edit"] != 2 || report.Hooks["pre-bash"] != 1 {
+		t.Errorf("Expected hook counts 2/1, got %v", report.Hooks)
+	}
+	if report.Languages["go"] != 2 || report.Languages["ts"] != 1 {
+		t.Errorf("Expected language counts go=2 ts=1, got %v", report.Languages)
+	}
+	if report.LatencyMS["post-edit"] != 200 {
+		t.Errorf("Expected average post-edit latency 200, got %d", report.LatencyMS["post-edit"])
+	}
+	if report.Errors["go-post-edit"] != 1 || report.Errors["typescript-post-edit"] != 1 || report.Errors["mysql-cli"] != 1 {
+		t.Errorf("Expected each rule counted once, got %v", report.Errors)
+	}
+}
+
+// TestBuildAnonymizesRules expects the names a project gives its bash.rules,
+// policies, and hooks never to reach the payload, only their families
+func TestBuildAnonymizesRules(t *testing.T) {
+	now := time.Now()
+	events := []audit.Event{
+		{Hook: "pre-bash", Decision: "deny", Rule: "bash-rule:acme-prod-db"},
+		{Hook: "pre-edit", Decision: "deny", Rule: "policy:acme-payments-freeze"},
+		{Hook: "post-edit", Decision: "block", Rule: "acme-lint"},
+		{Hook: "pre-edit", Decision: "deny", Rule: "content:eval-input,sql-injection"},
+	}
+
+	report := Build(events, now.Add(-time.Hour), now)
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "acme") {
+		t.Errorf("Expected no project's rule names in the payload, got %s", data)
+	}
+	if report.Errors["bash-rule"] != 1 || report.Errors["policy"] != 1 || report.Errors["other"] != 1 {
+		t.Errorf("Expected custom rules counted by family, got %v", report.Errors)
+	}
+	if report.Errors["content:eval-input"] != 1 || report.Errors["content:sql-injection"] != 1 {
+		t.Errorf("Expected each content rule counted in its family, got %v", report.Errors)
+	}
+}
+
+func TestMaybeSend(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	t.Setenv(EndpointEnv, "")
+	t.Setenv(DisableEnv, "")
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+	}))
+	defer server.Close()
+
+	if err := audit.Record(audit.Event{Time: time.Now().Add(-2 * time.Hour), Hook: "pre-bash", Decision: "allow"}); err != nil {
+		t.Fatalf("Failed to record event: %v", err)
+	}
+	if err := audit.Record(audit.Event{Time: time.Now(), Hook: "pre-bash", Decision: "deny", Rule: "mysql-cli"}); err != nil {
+		t.Fatalf("Failed to record event: %v", err)
+	}
+
+	// Nothing is sent before opting in
+	if err := MaybeSend(false); err != nil {
+		t.Fatalf("MaybeSend failed: %v", err)
+	}
+	if len(bodies) != 0 {
+		t.Fatalf("Expected no reports before enabling, got %d", len(bodies))
+	}
+
+	prefs := Preferences{Enabled: true, Endpoint: server.URL, EnabledAt: time.Now().Add(-time.Hour)}
+	if err := Save(prefs); err != nil {
+		t.Fatalf("Failed to save preferences: %v", err)
+	}
+
+	t.Setenv(DisableEnv, "0")
+	if err := MaybeSend(false); err != nil {
+		t.Fatalf("MaybeSend failed: %v", err)
+	}
+	if len(bodies) != 0 {
+		t.Fatalf("Expected %s=0 to suppress reports, got %d", DisableEnv, len(bodies))
+	}
+
+	t.Setenv(DisableEnv, "")
+	if err := MaybeSend(false); err != nil {
+		t.Fatalf("MaybeSend failed: %v", err)
+	}
+	if len(bodies) != 1 {
+		t.Fatalf("Expected one report, got %d", len(bodies))
+	}
+
+	var report Report
+	if err := json.Unmarshal([]byte(bodies[0]), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	// The event from before opting in is excluded
+	if report.Hooks["pre-bash"] != 1 || report.Errors["mysql-cli"] != 1 {
+		t.Errorf("Expected only the post-opt-in event, got %+v", report)
+	}
+	if strings.Contains(bodies[0], "allow") {
+		t.Errorf("Expected no per-event details in the report, got %s", bodies[0])
+	}
+
+	// A second call within the interval sends nothing
+	if err := MaybeSend(false); err != nil {
+		t.Fatalf("MaybeSend failed: %v", err)
+	}
+	if len(bodies) != 1 {
+		t.Errorf("Expected reports at most daily, got %d", len(bodies))
+	}
+}
@@ -0,0 +1,373 @@
+// Package tools carries the external binaries the hooks run, like gopls and
+// golangci-lint, to machines without internet access. A bundle is a tar of
+// the binaries found on a connected machine's PATH, with a manifest pinning
+// each one's version and sha256. Installing it puts them in the state
+// directory, which the hooks search before PATH.
+package tools
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// Known are the tools the hooks run when they're installed, with the
+// arguments that print their version, if they have one
+var Known = []Tool{
+	{Name: "gopls", VersionArgs: []string{"version"}},
+	{Name: "golangci-lint", VersionArgs: []string{"--version"}},
+	{Name: "dupl"},
+	{Name: "apidiff"},
+	{Name: "buildifier", VersionArgs: []string{"--version"}},
+	{Name: "buf", VersionArgs: []string{"--version"}},
+	{Name: "semgrep", VersionArgs: []string{"--version"}},
+	{Name: "gosec", VersionArgs: []string{"-version"}},
+	{Name: "osv-scanner", VersionArgs: []string{"--version"}},
+}
+
+// Tool is a binary the hooks can run
+type Tool struct {
+	Name        string
+	VersionArgs []string
+}
+
+// Entry is one binary in a bundle or an installation
+type Entry struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+}
+
+// Manifest lists a bundle's or an installation's binaries
+type Manifest struct {
+	Created  time.Time `json:"created"`
+	Platform string    `json:"platform"` // GOOS/GOARCH the binaries run on
+	Tools    []Entry   `json:"tools"`
+}
+
+// manifestName is the manifest's name in a bundle and in the install directory
+const manifestName = "manifest.json"
+
+// maxToolSize keeps a corrupt bundle from filling the disk
+const maxToolSize = 1 << 30
+
+// Platform is the GOOS/GOARCH of this machine
+func Platform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// Dir returns where bundles are installed, <state dir>/tools
+func Dir() (string, error) {
+	return state.Path("tools")
+}
+
+// BinDir returns the directory of the installed binaries
+func BinDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bin"), nil
+}
+
+// AddToPath puts the installed binaries before the rest of $PATH, for the
+// hooks and the tools they run. It does nothing when none are installed.
+func AddToPath() {
+	bin, err := BinDir()
+	if err != nil {
+		return
+	}
+	if info, err := os.Stat(bin); err != nil || !info.IsDir() {
+		return
+	}
+	path := os.Getenv("PATH")
+	if slices.Contains(filepath.SplitList(path), bin) {
+		return
+	}
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+path)
+}
+
+// Bundle writes the named tools found on PATH to w as a bundle and returns its
+// manifest. Known tools that aren't installed are returned as missing; any
+// other name that isn't is an error, since it was asked for explicitly.
+func Bundle(w io.Writer, names []string) (Manifest, []string, error) {
+	manifest := Manifest{Created: time.Now().UTC(), Platform: Platform()}
+	explicit := len(names) > 0
+	if !explicit {
+		for _, t := range Known {
+			names = append(names, t.Name)
+		}
+	}
+
+	type found struct {
+		entry Entry
+		data  []byte
+	}
+	var bins []found
+	var missing []string
+	for _, name := range names {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			if explicit {
+				return manifest, nil, fmt.Errorf("%s is not on PATH", name)
+			}
+			missing = append(missing, name)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return manifest, nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+		entry := Entry{Name: name, Version: version(name, path), SHA256: digest(data), Size: int64(len(data))}
+		bins = append(bins, found{entry, data})
+		manifest.Tools = append(manifest.Tools, entry)
+	}
+	if len(bins) == 0 {
+		return manifest, missing, errors.New("none of the tools are on PATH")
+	}
+
+	tw := tar.NewWriter(w)
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, nil, fmt.Errorf("marshaling manifest: %w", err)
+	}
+	if err := writeFile(tw, manifestName, append(manifestData, '\n'), 0o644); err != nil {
+		return manifest, nil, err
+	}
+	for _, b := range bins {
+		if err := writeFile(tw, "bin/"+b.entry.Name, b.data, 0o755); err != nil {
+			return manifest, nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return manifest, nil, fmt.Errorf("writing bundle: %w", err)
+	}
+	return manifest, missing, nil
+}
+
+// writeFile adds a regular file to the bundle
+func writeFile(tw *tar.Writer, name string, data []byte, mode int64) error {
+	header := &tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("writing bundle: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("writing bundle: %w", err)
+	}
+	return nil
+}
+
+// version returns the first line the tool at path prints for its version, or
+// "" when it has no version flag or it fails
+func version(name, path string) string {
+	i := slices.IndexFunc(Known, func(t Tool) bool { return t.Name == name })
+	if i < 0 || len(Known[i].VersionArgs) == 0 {
+		return ""
+	}
+	out, err := proc.Command(path, Known[i].VersionArgs...).Output()
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(line)
+}
+
+// Install extracts the bundle read from r into the install directory and
+// returns its manifest. Every binary must match the manifest's sha256 and the
+// bundle must be for this platform; nothing is installed otherwise. Tools
+// installed before and not in the bundle are kept.
+func Install(r io.Reader) (Manifest, error) {
+	var manifest Manifest
+	var haveManifest bool
+	bins := make(map[string][]byte)
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("reading bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Size > maxToolSize {
+			return manifest, fmt.Errorf("bundle entry %s is too large", header.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return manifest, fmt.Errorf("reading bundle: %w", err)
+		}
+		switch name, isBin := strings.CutPrefix(header.Name, "bin/"); {
+		case header.Name == manifestName:
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return manifest, fmt.Errorf("parsing bundle manifest: %w", err)
+			}
+			haveManifest = true
+		case isBin && name != "" && !strings.ContainsAny(name, `/\`) && name != "." && name != "..":
+			bins[name] = data
+		}
+	}
+	if !haveManifest {
+		return manifest, errors.New("not a tools bundle: it has no manifest")
+	}
+	if manifest.Platform != Platform() {
+		return manifest, fmt.Errorf("the bundle is for %s, not %s", manifest.Platform, Platform())
+	}
+	for _, entry := range manifest.Tools {
+		data, ok := bins[entry.Name]
+		if !ok {
+			return manifest, fmt.Errorf("the bundle has no binary for %s", entry.Name)
+		}
+		if digest(data) != entry.SHA256 {
+			return manifest, fmt.Errorf("%s doesn't match its sha256 in the manifest", entry.Name)
+		}
+	}
+
+	bin, err := BinDir()
+	if err != nil {
+		return manifest, err
+	}
+	if err := os.MkdirAll(bin, 0o755); err != nil {
+		return manifest, fmt.Errorf("creating %s: %w", bin, err)
+	}
+	for _, entry := range manifest.Tools {
+		if err := writeBinary(filepath.Join(bin, fileName(entry.Name)), bins[entry.Name]); err != nil {
+			return manifest, err
+		}
+	}
+
+	installed, err := Installed()
+	if err != nil {
+		installed = Manifest{} // Rebuilt from the bundle
+	}
+	for _, entry := range manifest.Tools {
+		installed.Tools = slices.DeleteFunc(installed.Tools, func(e Entry) bool { return e.Name == entry.Name })
+		installed.Tools = append(installed.Tools, entry)
+	}
+	slices.SortFunc(installed.Tools, func(a, b Entry) int { return strings.Compare(a.Name, b.Name) })
+	installed.Created, installed.Platform = manifest.Created, manifest.Platform
+	data, err := json.MarshalIndent(installed, "", "  ")
+	if err != nil {
+		return manifest, fmt.Errorf("marshaling manifest: %w", err)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return manifest, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestName), append(data, '\n'), 0o644); err != nil {
+		return manifest, fmt.Errorf("writing manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// writeBinary replaces the binary at path, so a hook running the old one
+// isn't affected
+func writeBinary(path string, data []byte) error {
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0o755); err != nil {
+		return fmt.Errorf("installing %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("installing %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// Installed returns the manifest of the installed binaries; none installed
+// is an empty manifest
+func Installed() (Manifest, error) {
+	dir, err := Dir()
+	if err != nil {
+		return Manifest{}, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Manifest{}, nil
+	}
+	if err != nil {
+		return Manifest{}, fmt.Errorf("reading tools manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("parsing tools manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Status is where a known tool is found
+type Status struct {
+	Name    string
+	Path    string // "" when it's missing
+	Version string // The pinned version, for an installed bundle's
+	Bundled bool   // Found in the install directory rather than elsewhere on PATH
+}
+
+// Statuses returns where each known tool is found, searching the install
+// directory first as the hooks do, followed by any others installed from a
+// bundle
+func Statuses() []Status {
+	installed, _ := Installed()
+	bin, _ := BinDir()
+	statuses := make([]Status, 0, len(Known))
+	for _, t := range Known {
+		s := Status{Name: t.Name}
+		if i := slices.IndexFunc(installed.Tools, func(e Entry) bool { return e.Name == t.Name }); i >= 0 && bin != "" {
+			if path := filepath.Join(bin, fileName(t.Name)); fileExists(path) {
+				s.Path, s.Version, s.Bundled = path, installed.Tools[i].Version, true
+			}
+		}
+		if s.Path == "" {
+			s.Path, _ = exec.LookPath(t.Name)
+		}
+		statuses = append(statuses, s)
+	}
+	// Others bundled by name
+	for _, entry := range installed.Tools {
+		if !slices.ContainsFunc(Known, func(t Tool) bool { return t.Name == entry.Name }) && bin != "" {
+			if path := filepath.Join(bin, fileName(entry.Name)); fileExists(path) {
+				statuses = append(statuses, Status{Name: entry.Name, Path: path, Version: entry.Version, Bundled: true})
+			}
+		}
+	}
+	return statuses
+}
+
+// digest returns the hex sha256 of data
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileName is what the named tool's binary is called on this platform
+func fileName(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,108 @@
+package tools_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/tools"
+	"github.com/brianleishman/claude-hooks/pkg/hooktest"
+)
+
+func TestBundleInstall(t *testing.T) {
+	hooktest.Isolate(t)
+	hooktest.FakeTool(t, "gopls", `echo "golang.org/x/tools/gopls v0.16.2"`)
+
+	var bundle bytes.Buffer
+	manifest, missing, err := tools.Bundle(&bundle, nil)
+	if err != nil {
+		t.Fatalf("Bundle: %v", err)
+	}
+	if len(manifest.Tools) != 1 || manifest.Tools[0].Name != "gopls" || manifest.Tools[0].Version != "golang.org/x/tools/gopls v0.16.2" {
+		t.Errorf("Expected only gopls with its version, got %+v", manifest.Tools)
+	}
+	if !strings.Contains(strings.Join(missing, ","), "golangci-lint") {
+		t.Errorf("Expected the known tools not on PATH to be missing, got %v", missing)
+	}
+	if _, _, err := tools.Bundle(io.Discard, []string{"no-such-tool"}); err == nil {
+		t.Error("Expected a named tool that isn't on PATH to fail")
+	}
+
+	installed, err := tools.Install(bytes.NewReader(bundle.Bytes()))
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	bin, _ := tools.BinDir()
+	if _, err := os.Stat(filepath.Join(bin, "gopls")); err != nil || len(installed.Tools) != 1 {
+		t.Errorf("Expected gopls installed in %s: %v", bin, err)
+	}
+	statuses := tools.Statuses()
+	if statuses[0].Name != "gopls" || !statuses[0].Bundled || statuses[0].Version == "" {
+		t.Errorf("Expected the installed gopls to be found first, got %+v", statuses[0])
+	}
+
+	tools.AddToPath()
+	if first := filepath.SplitList(os.Getenv("PATH"))[0]; first != bin {
+		t.Errorf("Expected %s first on PATH, got %s", bin, first)
+	}
+}
+
+func TestInstallRejects(t *testing.T) {
+	hooktest.Isolate(t)
+	hooktest.FakeTool(t, "buf", `echo 1.47.2`)
+	var bundle bytes.Buffer
+	if _, _, err := tools.Bundle(&bundle, []string{"buf"}); err != nil {
+		t.Fatalf("Bundle: %v", err)
+	}
+
+	// rewrite returns the bundle with the manifest and binary changed
+	rewrite := func(edit func(m map[string]any), binary []byte) []byte {
+		var out bytes.Buffer
+		tr, tw := tar.NewReader(bytes.NewReader(bundle.Bytes())), tar.NewWriter(&out)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			data, _ := io.ReadAll(tr)
+			if header.Name == "manifest.json" {
+				var m map[string]any
+				_ = json.Unmarshal(data, &m)
+				edit(m)
+				data, _ = json.Marshal(m)
+			} else if binary != nil {
+				data = binary
+			}
+			header.Size = int64(len(data))
+			_ = tw.WriteHeader(header)
+			_, _ = tw.Write(data)
+		}
+		_ = tw.Close()
+		return out.Bytes()
+	}
+
+	for name, tt := range map[string]struct {
+		bundle []byte
+		want   string
+	}{
+		"tampered": {rewrite(func(map[string]any) {}, []byte("#!/bin/sh\necho pwned\n")), "doesn't match its sha256"},
+		"platform": {rewrite(func(m map[string]any) { m["platform"] = "plan9/mips" }, nil), "the bundle is for plan9/mips"},
+		"not a bundle": {func() []byte {
+			var out bytes.Buffer
+			_ = tar.NewWriter(&out).Close()
+			return out.Bytes()
+		}(), "no manifest"},
+	} {
+		if _, err := tools.Install(bytes.NewReader(tt.bundle)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q, got %v", name, tt.want, err)
+		}
+	}
+	if installed, _ := tools.Installed(); len(installed.Tools) != 0 {
+		t.Errorf("Expected nothing installed from rejected bundles, got %+v", installed.Tools)
+	}
+}
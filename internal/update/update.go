@@ -0,0 +1,192 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/network"
+)
+
+// DefaultAPIURL is GitHub's latest-release endpoint for this repository
+const DefaultAPIURL = "https://api.github.com/repos/BrianLeishman/claude-hooks/releases/latest"
+
+// APIURLEnv overrides the release endpoint, e.g. for a mirror
+const APIURLEnv = "CLAUDE_HOOKS_RELEASES_URL"
+
+// requestTimeout bounds one request, e.g. downloading a binary
+const requestTimeout = 2 * time.Minute
+
+// Release is the subset of a GitHub release the updater needs
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a downloadable file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Asset finds the release asset with the given name
+func (r *Release) Asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// AssetName is the raw binary name the release workflow publishes for a platform,
+// e.g. claude-hook_linux_amd64 or claude-hook_windows_arm64.exe
+func AssetName(goos, goarch string) string {
+	name := fmt.Sprintf("claude-hook_%s_%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// APIURL returns the release endpoint in use
+func APIURL() string {
+	if override := os.Getenv(APIURLEnv); override != "" {
+		return override
+	}
+	return DefaultAPIURL
+}
+
+// Latest fetches the newest published release
+func Latest(ctx context.Context) (*Release, error) {
+	url := APIURL()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating release request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	client, err := network.Client(requestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("fetching latest release: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching latest release: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching latest release: %s", resp.Status)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("parsing release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release has no tag")
+	}
+	return &release, nil
+}
+
+// Download fetches url into memory
+func Download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating download request: %w", err)
+	}
+
+	client, err := network.Client(requestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", url, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", url, err)
+	}
+	return data, nil
+}
+
+// Install writes an executable to target, replacing whatever is there via a
+// rename so a running hook never sees a half-written binary
+func Install(data []byte, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(target), err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".claude-hook-*")
+	if err != nil {
+		return fmt.Errorf("creating temp binary: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return fmt.Errorf("making binary executable: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("replacing %s: %w", target, err)
+	}
+	return nil
+}
+
+// IsNewer reports whether latest is a newer vMAJOR.MINOR.PATCH than current.
+// Development builds are always considered older than a release.
+func IsNewer(current, latest string) bool {
+	c, okCurrent := parseVersion(current)
+	l, okLatest := parseVersion(latest)
+	if !okLatest {
+		return false
+	}
+	if !okCurrent {
+		return true
+	}
+	for i := range c {
+		if c[i] != l[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "-") // Ignore pre-release suffixes
+	fields := strings.Split(v, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
@@ -0,0 +1,98 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"v1.2.3", "v1.2.4", true},
+		{"v1.2.3", "v1.10.0", true},
+		{"v1.2.3", "v1.2.3", false},
+		{"v2.0.0", "v1.9.9", false},
+		{"dev", "v0.1.0", true},
+		{"v1.0.0", "nightly", false},
+		{"1.0.0-rc1", "v1.0.1", true},
+	}
+
+	for _, tt := range tests {
+		if got := IsNewer(tt.current, tt.latest); got != tt.want {
+			t.Errorf("IsNewer(%q, %q) = %v, expected %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestLatestAndInstall(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho new\n")
+	var serverURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			_ = json.NewEncoder(w).Encode(Release{
+				TagName: "v9.9.9",
+				Assets:  []Asset{{Name: AssetName("linux", "amd64"), URL: serverURL + "/bin"}},
+			})
+		case "/bin":
+			_, _ = w.Write(binary)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	serverURL = server.URL
+	t.Setenv(APIURLEnv, server.URL+"/latest")
+
+	release, err := Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if release.TagName != "v9.9.9" {
+		t.Errorf("Expected tag v9.9.9, got %q", release.TagName)
+	}
+
+	asset, ok := release.Asset("claude-hook_linux_amd64")
+	if !ok {
+		t.Fatalf("Expected linux/amd64 asset in %+v", release.Assets)
+	}
+	data, err := Download(context.Background(), asset.URL)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	target := filepath.Join(t.TempDir(), "bin", "claude-hook")
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatalf("Failed to create bin dir: %v", err)
+	}
+	if err := os.WriteFile(target, []byte("old"), 0o644); err != nil {
+		t.Fatalf("Failed to write old binary: %v", err)
+	}
+	if err := Install(data, target); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read installed binary: %v", err)
+	}
+	if string(got) != string(binary) {
+		t.Errorf("Expected installed binary to be replaced, got %q", got)
+	}
+	if info, _ := os.Stat(target); info.Mode().Perm()&0o111 == 0 {
+		t.Errorf("Expected installed binary to be executable, got %v", info.Mode())
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	if got := AssetName("windows", "arm64"); got != "claude-hook_windows_arm64.exe" {
+		t.Errorf("Expected windows asset to have .exe suffix, got %q", got)
+	}
+}
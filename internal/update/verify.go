@@ -0,0 +1,165 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// ChecksumsAsset is the GoReleaser checksum file published with every release
+const ChecksumsAsset = "checksums.txt"
+
+// SignatureSuffix names the minisign signature of an asset (checksums.txt.minisig)
+const SignatureSuffix = ".minisig"
+
+// PublicKey is the minisign public key release checksums are signed with. It is
+// set at build time with -ldflags "-X .../internal/update.PublicKey=RWQ...".
+var PublicKey = ""
+
+// PublicKeyEnv overrides PublicKey, e.g. for a mirror signing its own builds
+const PublicKeyEnv = "CLAUDE_HOOKS_MINISIGN_PUBKEY"
+
+// ErrUnverified means an artifact couldn't be proven authentic; callers may only
+// proceed past it when the user explicitly asked for an insecure install
+var ErrUnverified = errors.New("artifact is not verifiable")
+
+// Verify checks that data is the release's assetName: its sha256 must match the
+// release's checksums.txt, and checksums.txt must carry a valid minisign signature
+// from the release key. With insecure, a missing signature, key, or checksum
+// file is tolerated (with a warning), but a checksum or signature that is present
+// and wrong is always an error.
+func Verify(ctx context.Context, release *Release, assetName string, data []byte, insecure bool) error {
+	checksumsAsset, ok := release.Asset(ChecksumsAsset)
+	if !ok {
+		return allowIfInsecure(insecure, fmt.Errorf("%w: release %s publishes no %s", ErrUnverified, release.TagName, ChecksumsAsset))
+	}
+	checksums, err := Download(ctx, checksumsAsset.URL)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyChecksumsSignature(ctx, release, checksums, insecure); err != nil {
+		return err
+	}
+
+	want, ok := parseChecksums(checksums)[assetName]
+	if !ok {
+		return allowIfInsecure(insecure, fmt.Errorf("%w: %s lists no checksum for %s", ErrUnverified, ChecksumsAsset, assetName))
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", assetName, want, got)
+	}
+	return nil
+}
+
+func verifyChecksumsSignature(ctx context.Context, release *Release, checksums []byte, insecure bool) error {
+	key := PublicKey
+	if override := os.Getenv(PublicKeyEnv); override != "" {
+		key = override
+	}
+	if key == "" {
+		return allowIfInsecure(insecure, fmt.Errorf("%w: this build has no release signing key", ErrUnverified))
+	}
+
+	sigAsset, ok := release.Asset(ChecksumsAsset + SignatureSuffix)
+	if !ok {
+		return allowIfInsecure(insecure, fmt.Errorf("%w: release %s is unsigned", ErrUnverified, release.TagName))
+	}
+	sig, err := Download(ctx, sigAsset.URL)
+	if err != nil {
+		return err
+	}
+
+	if err := VerifyMinisign(key, checksums, sig); err != nil {
+		return fmt.Errorf("verifying %s signature: %w", ChecksumsAsset, err)
+	}
+	return nil
+}
+
+func allowIfInsecure(insecure bool, err error) error {
+	if !insecure {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "⚠️  %v (continuing because of -insecure)\n", err)
+	return nil
+}
+
+// parseChecksums reads "<sha256>  <file>" lines as written by sha256sum and GoReleaser
+func parseChecksums(data []byte) map[string]string {
+	sums := make(map[string]string)
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+		}
+	}
+	return sums
+}
+
+// VerifyMinisign checks a minisign signature file against message using a
+// base64 public key (the second line of a minisign .pub file). Both legacy
+// ("Ed") and prehashed ("ED") signatures are accepted, and the trusted comment
+// is authenticated by the global signature.
+func VerifyMinisign(publicKey string, message, signature []byte) error {
+	keyBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(keyBytes) != 42 || string(keyBytes[:2]) != "Ed" {
+		return fmt.Errorf("invalid minisign public key")
+	}
+	keyID, pub := keyBytes[2:10], ed25519.PublicKey(keyBytes[10:])
+
+	lines := strings.Split(strings.ReplaceAll(string(signature), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return fmt.Errorf("malformed signature file")
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sigBytes) != 74 {
+		return fmt.Errorf("malformed signature")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed global signature")
+	}
+
+	algorithm, sigKeyID, sig := string(sigBytes[:2]), sigBytes[2:10], sigBytes[10:]
+	if !bytes.Equal(sigKeyID, keyID) {
+		return fmt.Errorf("signed with a different key (id %X, expected %X)", reverse(sigKeyID), reverse(keyID))
+	}
+
+	signed := message
+	switch algorithm {
+	case "Ed":
+	case "ED":
+		digest := blake2b.Sum512(message)
+		signed = digest[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", algorithm)
+	}
+	if !ed25519.Verify(pub, signed, sig) {
+		return fmt.Errorf("signature does not match")
+	}
+
+	trusted := strings.TrimPrefix(lines[2], "trusted comment: ")
+	if !ed25519.Verify(pub, append(append([]byte{}, sig...), trusted...), globalSig) {
+		return fmt.Errorf("trusted comment signature does not match")
+	}
+	return nil
+}
+
+// reverse returns key IDs in the byte order minisign prints them
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
@@ -0,0 +1,126 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisignKey generates a key pair and returns the base64 public key the way
+// minisign writes it
+func minisignKey(t *testing.T) (string, ed25519.PrivateKey, []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	encoded := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...))
+	return encoded, priv, keyID
+}
+
+// minisignSign produces a minisign signature file for message
+func minisignSign(priv ed25519.PrivateKey, keyID, message []byte, prehash bool) []byte {
+	algorithm, signed := "Ed", message
+	if prehash {
+		digest := blake2b.Sum512(message)
+		algorithm, signed = "ED", digest[:]
+	}
+	sig := ed25519.Sign(priv, signed)
+	trusted := "timestamp:1700000000\tfile:checksums.txt"
+	global := ed25519.Sign(priv, append(append([]byte{}, sig...), trusted...))
+
+	return fmt.Appendf(nil, "untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), keyID...), sig...)),
+		trusted,
+		base64.StdEncoding.EncodeToString(global))
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	pub, priv, keyID := minisignKey(t)
+	message := []byte("abc123  claude-hook_linux_amd64\n")
+
+	for _, prehash := range []bool{false, true} {
+		sig := minisignSign(priv, keyID, message, prehash)
+		if err := VerifyMinisign(pub, message, sig); err != nil {
+			t.Errorf("Expected valid signature (prehash=%v), got: %v", prehash, err)
+		}
+		if err := VerifyMinisign(pub, []byte("tampered"), sig); err == nil {
+			t.Errorf("Expected tampered message to fail (prehash=%v)", prehash)
+		}
+	}
+
+	otherPub, _, _ := minisignKey(t)
+	if err := VerifyMinisign(otherPub, message, minisignSign(priv, keyID, message, true)); err == nil {
+		t.Error("Expected signature from another key to fail")
+	}
+}
+
+// releaseServer serves a release whose checksums are optionally signed
+func releaseServer(t *testing.T, binary []byte, checksums, signature []byte) *Release {
+	t.Helper()
+	files := map[string][]byte{
+		"claude-hook_linux_amd64": binary,
+		ChecksumsAsset:            checksums,
+	}
+	if signature != nil {
+		files[ChecksumsAsset+SignatureSuffix] = signature
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	release := &Release{TagName: "v1.0.0"}
+	for name := range files {
+		release.Assets = append(release.Assets, Asset{Name: name, URL: server.URL + "/" + name})
+	}
+	return release
+}
+
+func TestVerify(t *testing.T) {
+	pub, priv, keyID := minisignKey(t)
+	t.Setenv(PublicKeyEnv, pub)
+
+	binary := []byte("release binary")
+	sum := sha256.Sum256(binary)
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  claude-hook_linux_amd64\n")
+	ctx := context.Background()
+
+	signed := releaseServer(t, binary, checksums, minisignSign(priv, keyID, checksums, true))
+	if err := Verify(ctx, signed, "claude-hook_linux_amd64", binary, false); err != nil {
+		t.Errorf("Expected signed release to verify, got: %v", err)
+	}
+	if err := Verify(ctx, signed, "claude-hook_linux_amd64", []byte("tampered"), true); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch even with insecure, got: %v", err)
+	}
+
+	unsigned := releaseServer(t, binary, checksums, nil)
+	if err := Verify(ctx, unsigned, "claude-hook_linux_amd64", binary, false); !errors.Is(err, ErrUnverified) {
+		t.Errorf("Expected unsigned release to be refused, got: %v", err)
+	}
+	if err := Verify(ctx, unsigned, "claude-hook_linux_amd64", binary, true); err != nil {
+		t.Errorf("Expected unsigned release to be allowed with insecure, got: %v", err)
+	}
+
+	forged := releaseServer(t, binary, checksums, minisignSign(priv, keyID, []byte("other checksums"), false))
+	if err := Verify(ctx, forged, "claude-hook_linux_amd64", binary, true); err == nil {
+		t.Error("Expected a bad signature to fail even with insecure")
+	}
+}
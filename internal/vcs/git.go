@@ -0,0 +1,153 @@
+package vcs
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+)
+
+// git is a git working tree
+type git struct{ root string }
+
+func (g git) Kind() string { return Git }
+func (g git) Root() string { return g.root }
+
+func (g git) Branch() string {
+	output, err := proc.Command("git", "-C", g.root, "branch", "--show-current").Output()
+	if err != nil {
+		// Older git has no --show-current
+		if output, err = proc.Command("git", "-C", g.root, "rev-parse", "--abbrev-ref", "HEAD").Output(); err != nil {
+			return ""
+		}
+	}
+	branch := strings.TrimSpace(string(output))
+	if branch == "HEAD" {
+		return "" // Detached
+	}
+	return branch
+}
+
+func (g git) Base(file string) ([]byte, bool) {
+	r, ok := rel(g.root, file)
+	if !ok {
+		return nil, false
+	}
+	output, err := proc.Command("git", "-C", g.root, "cat-file", "--filters", "HEAD:"+r).Output()
+	if err != nil {
+		return nil, false
+	}
+	return output, true
+}
+
+func (g git) Tracked(file string) bool {
+	return proc.Command("git", "-C", g.root, "ls-files", "--error-unmatch", "--", file).Run() == nil
+}
+
+func (g git) Diff(context int, files ...string) ([]byte, error) {
+	args := append([]string{"-C", g.root, "diff", "-U" + strconv.Itoa(context), "--no-color", "HEAD", "--"}, files...)
+	return proc.Command("git", args...).Output()
+}
+
+// Coverage reads the settings sparse checkouts and partial clones turn on
+func (g git) Coverage() Coverage {
+	var c Coverage
+	output, _ := proc.Command("git", "-C", g.root, "config", "--get-regexp", `^(core\.sparsecheckout|extensions\.partialclone|remote\..*\.promisor)$`).Output()
+	for line := range strings.Lines(string(output)) {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch {
+		case key == "core.sparsecheckout":
+			c.Sparse = value == "true"
+		case key == "extensions.partialclone", value == "true":
+			c.Partial = true
+		}
+	}
+	return c
+}
+
+func (g git) CommitTargets(args []string) []string {
+	if len(args) < 2 || args[1] != "commit" {
+		return nil
+	}
+	if branch := g.Branch(); branch != "" {
+		return []string{branch}
+	}
+	return nil
+}
+
+// GitPush is what a git push command sends: from which repository, to which
+// remote, and which refs
+type GitPush struct {
+	Dir    string // Of -C, joined when given several times; empty for the current directory
+	Remote string // Empty for the branch's push remote
+	// Refspecs are the sources of the refspecs pushed, like HEAD, feature, or
+	// refs/heads/*; none for the current branch. Deletions (:dst) send nothing
+	// and are left out.
+	Refspecs []string
+	All      bool // --all, --branches, or --mirror
+	Tags     bool // --tags
+	Deletes  bool // Only deletes remote refs, so sends no commits
+}
+
+// gitPushFlags are git push's options that take a value as a separate word
+var gitPushFlags = []string{"--repo", "-o", "--push-option", "--receive-pack", "--exec"}
+
+// ParseGitPush parses a git command, split into words with the executable
+// first, into what it pushes, reporting false when it isn't a push
+func ParseGitPush(args []string) (GitPush, bool) {
+	if len(args) < 2 || strings.ToLower(filepath.Base(args[0])) != Git {
+		return GitPush{}, false
+	}
+	var push GitPush
+	globals := args[1:]
+	words := positional(globals, gitGlobalFlags...)
+	if len(words) == 0 || globals[words[0]] != "push" {
+		return GitPush{}, false
+	}
+	for i := 0; i < words[0]; i++ {
+		if globals[i] == "-C" && i+1 < words[0] {
+			i++
+			if filepath.IsAbs(globals[i]) || push.Dir == "" {
+				push.Dir = globals[i]
+			} else {
+				push.Dir = filepath.Join(push.Dir, globals[i])
+			}
+		}
+	}
+
+	rest := globals[words[0]+1:]
+	deleting := false
+flags:
+	for i, flag := range rest {
+		switch {
+		case flag == "--":
+			break flags
+		case flag == "--all" || flag == "--branches" || flag == "--mirror":
+			push.All = true
+		case flag == "--tags":
+			push.Tags = true
+		case flag == "-d" || flag == "--delete":
+			deleting = true
+		case flag == "--repo" && i+1 < len(rest):
+			push.Remote = rest[i+1]
+		case strings.HasPrefix(flag, "--repo="):
+			push.Remote = strings.TrimPrefix(flag, "--repo=")
+		}
+	}
+	// The repository argument takes precedence over --repo
+	refspecs := 0
+	for n, i := range positional(rest, gitPushFlags...) {
+		if n == 0 {
+			push.Remote = rest[i]
+			continue
+		}
+		refspecs++
+		src, _, _ := strings.Cut(strings.TrimPrefix(rest[i], "+"), ":")
+		if src != "" && !deleting {
+			push.Refspecs = append(push.Refspecs, src)
+		}
+	}
+	push.Deletes = refspecs > 0 && len(push.Refspecs) == 0 && !push.All && !push.Tags
+	return push, true
+}
@@ -0,0 +1,102 @@
+package vcs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+)
+
+// hg is a Mercurial working directory
+type hg struct{ root string }
+
+// hgGlobalFlags are hg's global flags that take a value
+var hgGlobalFlags = []string{"-R", "--repository", "--cwd", "--config", "--color", "--pager"}
+
+func (h hg) Kind() string { return HG }
+func (h hg) Root() string { return h.root }
+
+// command runs hg in the root, where the paths it's given are relative to,
+// without the user's output settings
+func (h hg) command(args ...string) *exec.Cmd {
+	cmd := proc.Command("hg", args...)
+	cmd.Dir = h.root
+	cmd.Env = append(cmd.Env, "HGPLAIN=1")
+	return cmd
+}
+
+// Branch returns the active bookmark, or the named branch when there's none
+func (h hg) Branch() string {
+	if output, err := h.command("log", "-r", ".", "-T", "{activebookmark}").Output(); err == nil {
+		if bookmark := strings.TrimSpace(string(output)); bookmark != "" {
+			return bookmark
+		}
+	}
+	output, err := h.command("branch").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func (h hg) Base(file string) ([]byte, bool) {
+	r, ok := rel(h.root, file)
+	if !ok {
+		return nil, false
+	}
+	output, err := h.command("cat", "-r", ".", "--", "path:"+r).Output()
+	if err != nil {
+		return nil, false
+	}
+	return output, true
+}
+
+func (h hg) Tracked(file string) bool {
+	r, ok := rel(h.root, file)
+	return ok && h.command("files", "--", "path:"+r).Run() == nil
+}
+
+func (h hg) Diff(context int, files ...string) ([]byte, error) {
+	args := []string{"diff", "--git", "-U", strconv.Itoa(context), "--"}
+	for _, r := range rels(h.root, files) {
+		args = append(args, "path:"+r)
+	}
+	if len(files) > 0 && len(args) == 5 {
+		return nil, errOutside
+	}
+	return h.command(args...).Output()
+}
+
+// Coverage reads the sparse extension's patterns and the repository's
+// requirements
+func (h hg) Coverage() Coverage {
+	var c Coverage
+	if info, err := os.Stat(filepath.Join(h.root, ".hg", "sparse")); err == nil && info.Size() > 0 {
+		c.Sparse = true
+	}
+	requires, _ := os.ReadFile(filepath.Join(h.root, ".hg", "requires"))
+	for line := range strings.Lines(string(requires)) {
+		switch line = strings.TrimSpace(line); {
+		case strings.HasPrefix(line, "narrowhg"):
+			c.Sparse = true
+		case strings.Contains(line, "remotefilelog"):
+			c.Partial = true
+		}
+	}
+	return c
+}
+
+func (h hg) CommitTargets(args []string) []string {
+	words := positional(args, hgGlobalFlags...)
+	if len(words) < 2 || !slices.Contains([]string{"commit", "ci"}, args[words[1]]) {
+		return nil
+	}
+	if branch := h.Branch(); branch != "" {
+		return []string{branch}
+	}
+	return nil
+}
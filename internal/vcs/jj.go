@@ -0,0 +1,116 @@
+package vcs
+
+import (
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/brianleishman/claude-hooks/internal/proc"
+)
+
+// jj is a Jujutsu workspace. The working copy is a commit of its own, @, so
+// what changed is @ against its parent, @-.
+type jj struct{ root string }
+
+// jjGlobalFlags are jj's global flags that take a value
+var jjGlobalFlags = []string{"-R", "--repository", "--at-op", "--at-operation", "--config", "--config-toml", "--color"}
+
+// jjBookmarkFlags are the flags of jj bookmark set|create|move that take a value
+var jjBookmarkFlags = []string{"-r", "--revision", "--to", "--from"}
+
+func (j jj) Kind() string { return JJ }
+func (j jj) Root() string { return j.root }
+
+// command runs jj on the workspace without colors or a pager
+func (j jj) command(args ...string) *exec.Cmd {
+	return proc.Command("jj", append([]string{"-R", j.root, "--color=never", "--no-pager"}, args...)...)
+}
+
+// fileset names exactly the file at r, relative to the workspace root
+func fileset(r string) string {
+	return "root-file:" + strconv.Quote(r)
+}
+
+// Branch returns the first bookmark on @ or @-: jj has no current branch,
+// bookmarks are moved to the commits they should point at
+func (j jj) Branch() string {
+	output, err := j.command("log", "--ignore-working-copy", "--no-graph", "-r", "@ | @-", "-T", `local_bookmarks.map(|b| b.name()).join("\n") ++ "\n"`).Output()
+	if err != nil {
+		return ""
+	}
+	for line := range strings.Lines(string(output)) {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+func (j jj) Base(file string) ([]byte, bool) {
+	r, ok := rel(j.root, file)
+	if !ok {
+		return nil, false
+	}
+	output, err := j.command("file", "show", "-r", "@-", fileset(r)).Output()
+	if err != nil {
+		return nil, false
+	}
+	// Showing a file @- doesn't have succeeds with nothing, like an empty file
+	if len(output) == 0 && !j.has("@-", r) {
+		return nil, false
+	}
+	return output, true
+}
+
+// Tracked reports whether jj tracks file; it tracks every file it doesn't
+// ignore as soon as it's written
+func (j jj) Tracked(file string) bool {
+	r, ok := rel(j.root, file)
+	return ok && j.has("@", r)
+}
+
+// has reports whether revision has the file at r
+func (j jj) has(revision, r string) bool {
+	output, err := j.command("file", "list", "-r", revision, fileset(r)).Output()
+	return err == nil && len(strings.TrimSpace(string(output))) > 0
+}
+
+func (j jj) Diff(context int, files ...string) ([]byte, error) {
+	args := []string{"diff", "--git", "--context", strconv.Itoa(context)}
+	for _, r := range rels(j.root, files) {
+		args = append(args, fileset(r))
+	}
+	if len(files) > 0 && len(args) == 4 {
+		return nil, errOutside
+	}
+	return j.command(args...).Output()
+}
+
+// Coverage reports a sparse working copy, one whose patterns aren't just the
+// root. jj has no partial clones.
+func (j jj) Coverage() Coverage {
+	output, err := j.command("sparse", "list", "--ignore-working-copy").Output()
+	if err != nil {
+		return Coverage{}
+	}
+	return Coverage{Sparse: strings.TrimSpace(string(output)) != "."}
+}
+
+// CommitTargets returns the bookmarks a jj bookmark set, create, or move
+// names: pointing a bookmark at a commit is how jj commits to a branch
+func (j jj) CommitTargets(args []string) []string {
+	words := positional(args, jjGlobalFlags...)
+	if len(words) < 3 || !slices.Contains([]string{"bookmark", "b", "branch"}, args[words[1]]) {
+		return nil
+	}
+	switch sub := words[2]; args[sub] {
+	case "set", "s", "create", "c", "move", "m":
+		var names []string
+		for _, i := range positional(args[sub+1:], jjBookmarkFlags...) {
+			names = append(names, args[sub+1+i])
+		}
+		return names
+	}
+	return nil
+}
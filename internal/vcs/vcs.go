@@ -0,0 +1,230 @@
+// Package vcs puts the version control systems claude-hooks understands behind
+// one interface: git, Jujutsu (jj), and Mercurial (hg). Branch protection,
+// root discovery, and the checks that only look at what changed use it, so a
+// repository gets the same protections whichever it uses.
+package vcs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// errOutside is Diff's error when none of its files are in the repository;
+// without any it would diff them all
+var errOutside = errors.New("files are outside the repository")
+
+// Kinds of repository
+const (
+	Git = "git"
+	JJ  = "jj"
+	HG  = "hg"
+)
+
+// markers are the directories that make a directory a repository's root, in
+// order of preference: a jj repository colocated with git has both .jj and
+// .git, and jj manages its working copy.
+var markers = []struct{ dir, kind string }{
+	{".jj", JJ},
+	{".git", Git},
+	{".hg", HG},
+}
+
+// Repo is a repository's working copy
+type Repo interface {
+	// Kind is Git, JJ, or HG
+	Kind() string
+	// Root is the working copy's top directory
+	Root() string
+	// Branch returns the branch new commits go on (a bookmark in jj, or in hg
+	// when one is active), or "" when there's none, like a detached HEAD
+	Branch() string
+	// Base returns file's content at the revision the working copy is based
+	// on (git's HEAD, jj's @-, hg's .) as it would be checked out, or false
+	// when it isn't there
+	Base(file string) ([]byte, bool)
+	// Tracked reports whether the repository tracks file
+	Tracked(file string) bool
+	// Diff returns the unified diff of files against Base, with context
+	// lines of context
+	Diff(context int, files ...string) ([]byte, error)
+	// Coverage reports how much of the repository is on disk
+	Coverage() Coverage
+	// CommitTargets returns the branches a command, split into words with
+	// the executable first, would record commits on: git commit and hg
+	// commit commit on the current branch, jj bookmark set|create|move
+	// points the bookmarks it names at a commit
+	CommitTargets(args []string) []string
+}
+
+// Coverage is how much of a repository a working copy has. Checks that look
+// past the edited files can only see what's materialized, and checking out
+// another revision would materialize, or in a partial clone fetch, the rest.
+type Coverage struct {
+	// Sparse is set when the working copy leaves out parts of the tree: a
+	// sparse checkout, or a narrow hg clone
+	Sparse bool
+	// Partial is set when objects are fetched on demand: a git partial clone
+	// or an hg remotefilelog clone
+	Partial bool
+}
+
+// Reduced reports whether anything is left out
+func (c Coverage) Reduced() bool {
+	return c.Sparse || c.Partial
+}
+
+// FindRoot returns the closest directory of dir or its parents that is a
+// repository's root, and the repository's kind, or "" when there's none. It
+// only looks at the file system.
+func FindRoot(dir string) (root, kind string) {
+	for d := dir; ; d = filepath.Dir(d) {
+		for _, m := range markers {
+			if _, err := os.Stat(filepath.Join(d, m.dir)); err == nil {
+				return d, m.kind
+			}
+		}
+		if filepath.Dir(d) == d {
+			return "", ""
+		}
+	}
+}
+
+// Detect returns the repository containing dir, or nil when it isn't in one
+func Detect(dir string) Repo {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	root, kind := FindRoot(abs)
+	return open(root, kind)
+}
+
+// ForCommand returns the repository containing dir that executable (git, jj,
+// or hg) works on, or nil when there's none. A jj repository colocated with
+// git is one for git commands too.
+func ForCommand(executable, dir string) Repo {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	root, kind := FindRoot(abs)
+	if kind == JJ && executable == Git {
+		if _, err := os.Stat(filepath.Join(root, ".git")); err == nil {
+			kind = Git
+		}
+	}
+	if kind != executable {
+		return nil
+	}
+	return open(root, kind)
+}
+
+// open returns the Repo of kind at root
+func open(root, kind string) Repo {
+	switch kind {
+	case Git:
+		return git{root}
+	case JJ:
+		return jj{root}
+	case HG:
+		return hg{root}
+	}
+	return nil
+}
+
+// rel returns file relative to root with forward slashes, the way the
+// repository names it
+func rel(root, file string) (string, bool) {
+	if !filepath.IsAbs(file) {
+		return filepath.ToSlash(file), true
+	}
+	r, err := filepath.Rel(root, file)
+	if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(r), true
+}
+
+// rels is rel for each of files, dropping those outside root
+func rels(root string, files []string) []string {
+	var out []string
+	for _, f := range files {
+		if r, ok := rel(root, f); ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// gitGlobalFlags are git's options before the subcommand that take a value
+var gitGlobalFlags = []string{"-C", "-c", "--git-dir", "--work-tree", "--namespace", "--exec-path", "--config-env"}
+
+// historyCommands are the subcommands that record, rewrite, or publish
+// commits, by executable
+var historyCommands = map[string][]string{
+	Git: {"commit", "push", "merge", "rebase", "cherry-pick", "revert", "am", "pull", "tag"},
+	HG:  {"commit", "ci", "push", "amend", "graft", "rebase", "histedit", "import", "backout", "merge", "tag", "uncommit"},
+	JJ:  {"commit", "ci", "describe", "desc", "squash", "split", "abandon", "rebase"},
+}
+
+// WritesHistory reports whether a git, jj, or hg command, split into words
+// with the executable first, records, rewrites, or publishes commits, or
+// moves a branch: what a session without commit rights can't do. Listing
+// tags (git tag, git tag -l) doesn't count.
+func WritesHistory(args []string) bool {
+	if len(args) < 2 {
+		return false
+	}
+	kind := strings.ToLower(filepath.Base(args[0]))
+	var words []int
+	switch kind {
+	case Git:
+		words = positional(args[1:], gitGlobalFlags...)
+	case HG:
+		words = positional(args[1:], hgGlobalFlags...)
+	case JJ:
+		words = positional(args[1:], jjGlobalFlags...)
+	default:
+		return false
+	}
+	if len(words) == 0 {
+		return false
+	}
+	sub := words[0] + 1
+	switch {
+	case kind == JJ && (jj{}).CommitTargets(args) != nil:
+		return true
+	case kind == JJ && args[sub] == "git":
+		return len(words) > 1 && args[words[1]+1] == "push"
+	case kind == Git && args[sub] == "tag":
+		rest := args[sub+1:]
+		return len(positional(rest)) > 0 && !slices.Contains(rest, "-l") && !slices.Contains(rest, "--list")
+	}
+	return slices.Contains(historyCommands[kind], args[sub])
+}
+
+// positional returns the indexes of args' words that aren't flags, skipping
+// the values of the flags in valued, which take one as a separate word
+func positional(args []string, valued ...string) []int {
+	var out []int
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			for j := i + 1; j < len(args); j++ {
+				out = append(out, j)
+			}
+			return out
+		case strings.HasPrefix(a, "-"):
+			if slices.Contains(valued, a) {
+				i++ // Its value
+			}
+		default:
+			out = append(out, i)
+		}
+	}
+	return out
+}
@@ -0,0 +1,187 @@
+package vcs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"git/.git", "git/sub/deep", "colocated/.git", "colocated/.jj", "hg/.hg", "hg/nested/.git", "none"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		dir, root, kind string
+	}{
+		{"git/sub/deep", "git", Git},
+		{"colocated", "colocated", JJ},
+		{"hg", "hg", HG},
+		{"hg/nested", "hg/nested", Git}, // The closest wins
+	}
+	for _, tt := range tests {
+		root, kind := FindRoot(filepath.Join(dir, tt.dir))
+		if root != filepath.Join(dir, tt.root) || kind != tt.kind {
+			t.Errorf("FindRoot(%s) = %s, %s, want %s, %s", tt.dir, root, kind, tt.root, tt.kind)
+		}
+	}
+
+	if repo := ForCommand(Git, filepath.Join(dir, "colocated")); repo == nil || repo.Kind() != Git {
+		t.Errorf("ForCommand(git) in a colocated jj repo = %v, want git", repo)
+	}
+	if repo := ForCommand(HG, filepath.Join(dir, "git")); repo != nil {
+		t.Errorf("ForCommand(hg) in a git repo = %v, want nil", repo)
+	}
+}
+
+func TestGit(t *testing.T) {
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	file := filepath.Join(root, "a.txt")
+	if err := os.WriteFile(file, []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "init")
+	if err := os.WriteFile(file, []byte("one\n2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	untracked := filepath.Join(root, "new.txt")
+	if err := os.WriteFile(untracked, []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := Detect(root)
+	if repo == nil || repo.Kind() != Git {
+		t.Fatalf("Detect = %v, want git", repo)
+	}
+	if branch := repo.Branch(); branch != "main" {
+		t.Errorf("Branch = %q, want main", branch)
+	}
+	if base, ok := repo.Base(file); !ok || string(base) != "one\ntwo\n" {
+		t.Errorf("Base = %q, %v, want the committed content", base, ok)
+	}
+	if _, ok := repo.Base(untracked); ok {
+		t.Error("Base of an untracked file should be missing")
+	}
+	if !repo.Tracked(file) || repo.Tracked(untracked) {
+		t.Error("Tracked should be true for a.txt only")
+	}
+	diff, err := repo.Diff(0, file)
+	if err != nil || !strings.Contains(string(diff), "@@ -2 +2 @@") || !strings.Contains(string(diff), "+2") {
+		t.Errorf("Diff = %q, %v", diff, err)
+	}
+
+	if targets := repo.CommitTargets([]string{"git", "commit", "-m", "x"}); !slices.Equal(targets, []string{"main"}) {
+		t.Errorf("CommitTargets(git commit) = %q, want [main]", targets)
+	}
+	if targets := repo.CommitTargets([]string{"git", "status"}); targets != nil {
+		t.Errorf("CommitTargets(git status) = %q, want none", targets)
+	}
+
+	if c := repo.Coverage(); c.Reduced() {
+		t.Errorf("Coverage of a full clone = %+v", c)
+	}
+	runGit(t, root, "config", "remote.origin.promisor", "true")
+	runGit(t, root, "config", "core.sparseCheckout", "true")
+	if c := repo.Coverage(); !c.Sparse || !c.Partial {
+		t.Errorf("Coverage of a sparse partial clone = %+v", c)
+	}
+}
+
+func TestJJCommitTargets(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"jj bookmark set main -r @-", []string{"main"}},
+		{"jj -R . b s main feature", []string{"main", "feature"}},
+		{"jj bookmark move --to @- main", []string{"main"}},
+		{"jj bookmark create -r @ topic", []string{"topic"}},
+		{"jj branch set master", []string{"master"}},
+		{"jj bookmark list", nil},
+		{"jj commit -m msg", nil},
+		{"jj describe -m main", nil},
+	}
+	for _, tt := range tests {
+		if got := (jj{}).CommitTargets(strings.Fields(tt.command)); !slices.Equal(got, tt.want) {
+			t.Errorf("CommitTargets(%s) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestHGCommitTargetsIgnoresOtherCommands(t *testing.T) {
+	for _, command := range []string{"hg status", "hg -R commit log", "hg push -B main"} {
+		if got := (hg{}).CommitTargets(strings.Fields(command)); got != nil {
+			t.Errorf("CommitTargets(%s) = %q, want none", command, got)
+		}
+	}
+}
+
+func TestWritesHistory(t *testing.T) {
+	tests := map[string]bool{
+		"git commit -m msg":            true,
+		"git -C repo push origin main": true,
+		"git tag v1.0":                 true,
+		"git tag":                      false,
+		"git tag -l v1.*":              false,
+		"git log --oneline":            false,
+		"git status":                   false,
+		"hg commit -m msg":             true,
+		"hg status":                    false,
+		"jj describe -m msg":           true,
+		"jj git push":                  true,
+		"jj git fetch":                 false,
+		"jj bookmark set main":         true,
+		"jj log":                       false,
+		"ls -la":                       false,
+	}
+	for command, want := range tests {
+		if got := WritesHistory(strings.Fields(command)); got != want {
+			t.Errorf("WritesHistory(%s) = %v, want %v", command, got, want)
+		}
+	}
+}
+
+func TestParseGitPush(t *testing.T) {
+	tests := []struct {
+		command string
+		want    GitPush
+	}{
+		{"git push", GitPush{}},
+		{"git push -u origin HEAD", GitPush{Remote: "origin", Refspecs: []string{"HEAD"}}},
+		{"git push origin feature:main +topic:refs/heads/topic", GitPush{Remote: "origin", Refspecs: []string{"feature", "topic"}}},
+		{"git -C a -C b push --all upstream", GitPush{Dir: "a/b", Remote: "upstream", All: true}},
+		{"git push -o ci.skip --tags origin", GitPush{Remote: "origin", Tags: true}},
+		{"git push origin :old", GitPush{Remote: "origin", Deletes: true}},
+		{"git push --delete origin old", GitPush{Remote: "origin", Deletes: true}},
+	}
+	for _, tt := range tests {
+		got, ok := ParseGitPush(strings.Fields(tt.command))
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseGitPush(%s) = %+v, %v; want %+v", tt.command, got, ok, tt.want)
+		}
+	}
+	for _, command := range []string{"git pull", "git log push", "jj git push"} {
+		if _, ok := ParseGitPush(strings.Fields(command)); ok {
+			t.Errorf("Expected %q not to be a git push", command)
+		}
+	}
+}
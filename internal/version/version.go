@@ -0,0 +1,51 @@
+package version
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Set at release time with
+// -ldflags "-X github.com/brianleishman/claude-hooks/internal/version.Version=v1.2.3 ..."
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Current returns the running version, falling back to module build info for
+// `go install ...@version` builds that didn't set ldflags
+func Current() string {
+	if Version != "dev" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return Version
+}
+
+// String describes the build for `claude-hook version`
+func String() string {
+	commit, date := Commit, Date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && commit == "":
+				commit = setting.Value
+			case setting.Key == "vcs.time" && date == "":
+				date = setting.Value
+			}
+		}
+	}
+
+	s := "claude-hook " + Current()
+	if commit != "" {
+		s += fmt.Sprintf(" (commit %s", commit[:min(len(commit), 12)])
+		if date != "" {
+			s += ", built " + date
+		}
+		s += ")"
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+// Package vlog is the hooks' detail: the tools they run, what they skip, and
+// the tools' full output. It always goes to the session's log file, so it can
+// be read after the fact without -v (which lives in settings.json), and also
+// to stderr with -v. The stderr Claude sees stays at the level of a summary.
+package vlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+// FileName is the log's name in the session's state directory
+const FileName = "hook.log"
+
+var (
+	mu   sync.Mutex
+	file *os.File
+)
+
+// Open appends the rest of the process's detail to the log of the session,
+// after a header naming the hook. Without a session there is no log, and
+// only -v shows the detail.
+func Open(dir, sessionID, transcriptPath, hook string) error {
+	if sessionID == "" {
+		return nil
+	}
+	path, err := state.SessionPath(dir, sessionID, transcriptPath, FileName)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening session log: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+	}
+	file = f
+	fmt.Fprintf(file, "\n== %s %s (pid %d)\n", time.Now().Format(time.RFC3339), hook, os.Getpid())
+	return nil
+}
+
+// Close stops logging; detail written after it only goes to stderr with -v
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+}
+
+// Printf logs detail, and writes it to stderr when verbose
+func Printf(verbose bool, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if verbose {
+		fmt.Fprint(os.Stderr, msg)
+	}
+	write(msg)
+}
+
+// Output logs the full output of a tool run, which block reasons may cut
+// short and passing runs don't show at all. It stays out of stderr even with
+// -v: what the hook makes of the output goes there.
+func Output(step string, output []byte) {
+	text := strings.TrimRight(string(output), "\n")
+	if text == "" {
+		return
+	}
+	write(fmt.Sprintf("--- %s output:\n%s\n--- end of %s output\n", step, text, step))
+}
+
+// Logf logs a summary line that was already written to stderr, so the log
+// tells the whole story of the run
+func Logf(format string, args ...any) {
+	write(fmt.Sprintf(format, args...))
+}
+
+func write(msg string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return
+	}
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	fmt.Fprint(file, msg)
+}
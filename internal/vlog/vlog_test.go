@@ -0,0 +1,58 @@
+package vlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/state"
+)
+
+func TestSessionLog(t *testing.T) {
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", t.TempDir())
+	project := t.TempDir()
+	defer Close()
+
+	// Without a session nothing is logged, nor does it fail
+	if err := Open(project, "", "", "post-edit"); err != nil {
+		t.Fatalf("Expected no log without a session, got %v", err)
+	}
+	Printf(false, "🔧 dropped\n")
+
+	if err := Open(project, "abc", "", "post-edit"); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	Printf(false, "🔧 go vet ./a (in %s)\n", project)
+	Output("go vet", []byte("# a\na.go:3:1: x declared and not used\n"))
+	Output("go test", nil) // Nothing to log
+	Logf("❌ go hook failed")
+
+	path, err := state.SessionPath(project, "abc", "", FileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the session log at %s: %v", path, err)
+	}
+	log := string(data)
+	for _, want := range []string{"post-edit (pid", "🔧 go vet ./a (in " + project + ")\n", "--- go vet output:\n# a\na.go:3:1: x declared and not used\n--- end of go vet output\n", "❌ go hook failed\n"} {
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected the log to contain %q, got:\n%s", want, log)
+		}
+	}
+	if strings.Contains(log, "dropped") || strings.Contains(log, "go test") {
+		t.Errorf("Unexpected lines in the log:\n%s", log)
+	}
+
+	// A later run of the session appends to the same log
+	if err := Open(project, "abc", "", "stop"); err != nil {
+		t.Fatal(err)
+	}
+	Close()
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "❌ go hook failed\n\n== ") || filepath.Base(path) != FileName {
+		t.Errorf("Expected a second run appended, got:\n%s", data)
+	}
+}
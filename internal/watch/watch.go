@@ -0,0 +1,129 @@
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Change is a file that was created, modified, or deleted
+type Change struct {
+	Path    string
+	Deleted bool
+}
+
+// Watcher reports changed files under Root. It polls rather than using inotify
+// or FSEvents, which keeps it dependency free and behaves the same on every OS
+// and on network or container mounts where change notifications are unreliable.
+type Watcher struct {
+	Root     string
+	Interval time.Duration // How often the tree is scanned
+	Debounce time.Duration // Changes are batched until the tree is quiet for this long
+
+	files map[string]fileState
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// skippedDirs are never descended into: VCS metadata, dependencies, and build output
+var skippedDirs = []string{"node_modules", "vendor", "dist", "build"}
+
+// New returns a watcher for root with default timings
+func New(root string) *Watcher {
+	return &Watcher{Root: root, Interval: 500 * time.Millisecond, Debounce: 300 * time.Millisecond}
+}
+
+// Run scans until ctx is done, calling onChange with each quiet batch of changes.
+// Files that exist when Run starts are the baseline and aren't reported.
+func (w *Watcher) Run(ctx context.Context, onChange func([]Change)) error {
+	files, err := w.scan()
+	if err != nil {
+		return err
+	}
+	w.files = files
+
+	pending := make(map[string]Change)
+	var lastChange time.Time
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		files, err := w.scan()
+		if err != nil {
+			return err
+		}
+		changes := diff(w.files, files)
+		w.files = files
+
+		for _, c := range changes {
+			pending[c.Path] = c
+			lastChange = time.Now()
+		}
+		if len(pending) > 0 && time.Since(lastChange) >= w.Debounce {
+			batch := make([]Change, 0, len(pending))
+			for _, c := range pending {
+				batch = append(batch, c)
+			}
+			slices.SortFunc(batch, func(a, b Change) int { return strings.Compare(a.Path, b.Path) })
+			clear(pending)
+			onChange(batch)
+		}
+	}
+}
+
+// scan records the size and modification time of every file under Root
+func (w *Watcher) scan() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(w.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == w.Root {
+				return err
+			}
+			return nil // Vanished mid-walk; the next scan catches up
+		}
+		if d.IsDir() {
+			if path != w.Root && (strings.HasPrefix(d.Name(), ".") || slices.Contains(skippedDirs, d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+// diff lists files that differ between two scans
+func diff(before, after map[string]fileState) []Change {
+	var changes []Change
+	for path, state := range after {
+		if prev, ok := before[path]; !ok || prev != state {
+			changes = append(changes, Change{Path: path})
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, Change{Path: path, Deleted: true})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,69 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherReportsBatchedChanges(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "existing.go")
+	if err := os.WriteFile(existing, []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New(root)
+	w.Interval = 10 * time.Millisecond
+	w.Debounce = 50 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	batches := make(chan []Change, 10)
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx, func(changes []Change) { batches <- changes })
+	}()
+
+	time.Sleep(50 * time.Millisecond) // Let the baseline scan finish
+	created := filepath.Join(root, "pkg", "new.go")
+	if err := os.MkdirAll(filepath.Dir(created), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(created, []byte("package pkg\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(existing); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".git", "index"), []byte("ignored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var batch []Change
+	select {
+	case batch = <-batches:
+	case <-ctx.Done():
+		t.Fatal("Expected a batch of changes")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := []Change{{Path: existing, Deleted: true}, {Path: created}}
+	if len(batch) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, batch)
+	}
+	for i := range want {
+		if batch[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want[i], batch[i])
+		}
+	}
+}
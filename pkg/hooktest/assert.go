@@ -0,0 +1,127 @@
+package hooktest
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/hooks"
+)
+
+// Decision is what a hook's JSON output tells Claude Code, whichever event's
+// shape it has
+type Decision struct {
+	// Kind is "block" or "deny" when it stops Claude, "ask" or "allow" for a
+	// PreToolUse permission decision, and "" when it decides nothing
+	Kind          string
+	Reason        string
+	Context       string // additionalContext, before or after a tool call
+	SystemMessage string // Shown to the user
+	// Halt is set when the output stops Claude altogether ("continue": false),
+	// with StopReason shown to the user
+	Halt       bool
+	StopReason string
+}
+
+// ParseDecision reads a hook's stdout; output that isn't JSON, like the
+// context session start injects, decides nothing
+func ParseDecision(t testing.TB, stdout string) Decision {
+	t.Helper()
+	var out struct {
+		Continue           *bool  `json:"continue"`
+		StopReason         string `json:"stopReason"`
+		Decision           string `json:"decision"`
+		Reason             string `json:"reason"`
+		SystemMessage      string `json:"systemMessage"`
+		HookSpecificOutput struct {
+			PermissionDecision       string `json:"permissionDecision"`
+			PermissionDecisionReason string `json:"permissionDecisionReason"`
+			AdditionalContext        string `json:"additionalContext"`
+		} `json:"hookSpecificOutput"`
+	}
+	for line := range strings.Lines(stdout) {
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		if err := json.Unmarshal([]byte(line), &out); err != nil {
+			t.Fatalf("Failed to parse hook output %q: %v", line, err)
+		}
+		d := Decision{
+			Kind:          out.Decision,
+			Reason:        out.Reason,
+			Context:       out.HookSpecificOutput.AdditionalContext,
+			SystemMessage: out.SystemMessage,
+			Halt:          out.Continue != nil && !*out.Continue,
+			StopReason:    out.StopReason,
+		}
+		if out.HookSpecificOutput.PermissionDecision != "" {
+			d.Kind, d.Reason = out.HookSpecificOutput.PermissionDecision, out.HookSpecificOutput.PermissionDecisionReason
+		}
+		return d
+	}
+	return Decision{}
+}
+
+// Stops tells whether the decision stops Claude: a block, a deny, an ask, or
+// a halt
+func (d Decision) Stops() bool {
+	return d.Kind == "block" || d.Kind == "deny" || d.Kind == "ask" || d.Halt
+}
+
+// AssertStopped fails the test unless the hook's stdout stops Claude with a
+// reason containing each of want
+func AssertStopped(t testing.TB, stdout string, want ...string) Decision {
+	t.Helper()
+	d := ParseDecision(t, stdout)
+	if !d.Stops() {
+		t.Fatalf("Expected the hook to stop Claude, got: %s", stdout)
+	}
+	for _, w := range want {
+		if !strings.Contains(d.Reason, w) {
+			t.Errorf("Expected the reason to contain %q, got: %s", w, d.Reason)
+		}
+	}
+	return d
+}
+
+// AssertNotStopped fails the test when the hook's stdout stops Claude
+func AssertNotStopped(t testing.TB, stdout string) Decision {
+	t.Helper()
+	d := ParseDecision(t, stdout)
+	if d.Stops() {
+		t.Fatalf("Expected the hook not to stop Claude, got %s: %s", d.Kind, d.Reason)
+	}
+	return d
+}
+
+// PostEdit runs hook's post-edit check of files and returns the diagnostics
+// its failure parses into, attributed to source, along with the failure
+func PostEdit(t testing.TB, hook hooks.Hook, source string, files ...string) ([]hooks.Diagnostic, error) {
+	t.Helper()
+	err := hook.PostEditJSON(files, false)
+	if err == nil {
+		return nil, nil
+	}
+	return hooks.ParseDiagnostics(err.Error(), source), err
+}
+
+// AssertDiagnostic fails the test unless one of diags is on file at line (any
+// line for 0) with a message containing message
+func AssertDiagnostic(t testing.TB, diags []hooks.Diagnostic, file string, line int, message string) hooks.Diagnostic {
+	t.Helper()
+	for _, d := range diags {
+		if d.File == file && (line == 0 || d.Line == line) && strings.Contains(d.Message, message) {
+			return d
+		}
+	}
+	t.Errorf("Expected a diagnostic on %s:%d containing %q, got %+v", file, line, message, diags)
+	return hooks.Diagnostic{}
+}
+
+// AssertNoDiagnostics fails the test when the check failed or found anything
+func AssertNoDiagnostics(t testing.TB, diags []hooks.Diagnostic, err error) {
+	t.Helper()
+	if err != nil || len(diags) > 0 {
+		t.Errorf("Expected the check to pass, got %v: %+v", err, diags)
+	}
+}
@@ -0,0 +1,90 @@
+// Package golden compares user-facing output with files checked in under the
+// test's testdata/ directory, so a change to how a block reason, review, or
+// report reads shows up as a diff in review rather than slipping through
+// tests that only look for substrings.
+//
+// After an intended change, rewrite the files and review their diff:
+//
+//	go test ./internal/ci -update
+//
+// It has no dependencies in this module, so tests inside any package can use it.
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite golden files under testdata/ with the current output")
+
+// Path is where the golden file called name is kept
+func Path(name string) string {
+	return filepath.Join("testdata", name+".golden")
+}
+
+// Assert fails the test unless got is the content of the golden file called
+// name, or writes got there with -update
+func Assert(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := Path(name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("Failed to update %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s (run with -update to create it): %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output differs from %s (run with -update if the change is intended):\n%s", path, diff(string(want), string(got)))
+	}
+}
+
+// AssertString is Assert for text output
+func AssertString(t testing.TB, name, got string) {
+	t.Helper()
+	Assert(t, name, []byte(got))
+}
+
+// AssertJSON is Assert for JSON output, re-indented so the golden file diffs
+// line by line however compactly it was written
+func AssertJSON(t testing.TB, name string, got []byte) {
+	t.Helper()
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, got, "", "  "); err != nil {
+		t.Fatalf("Output isn't JSON: %v\n%s", err, got)
+	}
+	indented.WriteByte('\n')
+	Assert(t, name, indented.Bytes())
+}
+
+// diff describes how got differs from want, from the first line they differ
+// on, which is enough to spot a formatting change without a diff algorithm
+func diff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	i := 0
+	for i < len(wantLines) && i < len(gotLines) && wantLines[i] == gotLines[i] {
+		i++
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "first difference at line %d\n", i+1)
+	for _, line := range wantLines[i:min(i+5, len(wantLines))] {
+		fmt.Fprintf(&sb, "- %s\n", line)
+	}
+	for _, line := range gotLines[i:min(i+5, len(gotLines))] {
+		fmt.Fprintf(&sb, "+ %s\n", line)
+	}
+	return sb.String()
+}
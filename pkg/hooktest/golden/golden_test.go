@@ -0,0 +1,17 @@
+package golden
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssertJSON(t *testing.T) {
+	AssertJSON(t, "indented", []byte(`{"a":[1,2],"b":{"c":"d"}}`))
+}
+
+func TestDiff(t *testing.T) {
+	got := diff("a\nb\nc\n", "a\nB\nc\n")
+	if !strings.HasPrefix(got, "first difference at line 2\n- b\n- c\n") || !strings.Contains(got, "+ B\n+ c\n") {
+		t.Errorf("Expected the lines from the first difference, got:\n%s", got)
+	}
+}
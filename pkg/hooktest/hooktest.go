@@ -0,0 +1,200 @@
+// Package hooktest helps test language hooks and policy rules: fixture
+// repositories, fake tools on PATH, the payloads Claude Code sends, and
+// assertions on the diagnostics and decisions hooks produce.
+//
+// Hooks and rules are called directly where they can be; RunHook runs the
+// claude-hook binary, built once per test process, for what only main does.
+package hooktest
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// overrides are the environment variables that change what hooks decide, so a
+// test starts from the defaults whatever the developer's shell sets
+var overrides = []string{
+	"CLAUDE_HOOKS_PROFILE",
+	"CLAUDE_HOOKS_SETTINGS_PROFILE",
+	"CLAUDE_HOOKS_ENFORCEMENT",
+	"CLAUDE_HOOKS_DRY_RUN",
+	"CLAUDE_HOOKS_READ_ONLY",
+	"CLAUDE_HOOKS_OFFLINE",
+	"CLAUDE_HOOKS_CA_BUNDLE",
+	"CLAUDE_HOOKS_VERBOSE",
+	"CLAUDE_HOOKS_WEBHOOK_URL",
+	"CLAUDE_CODE_CWD",
+}
+
+// Isolate gives the test its own state directory and user config directory,
+// turns telemetry off, and clears the variables overriding the config,
+// returning the state directory
+func Isolate(t testing.TB) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("CLAUDE_HOOKS_STATE_DIR", dir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir()) // Where a settings profile is saved
+	t.Setenv("CLAUDE_HOOKS_TELEMETRY", "0")
+	t.Setenv("CLAUDE_HOOKS_GLOBAL_CONFIG", "off") // The developer's ~/.claude-hooks.yaml
+	for _, name := range overrides {
+		t.Setenv(name, "")
+	}
+	return dir
+}
+
+// Repo creates a git repository with files, mapping slash-separated paths to
+// their content, committed as its first commit when there are any
+func Repo(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	Git(t, root, "init", "-q")
+	Git(t, root, "config", "user.email", "hooktest@example.com")
+	Git(t, root, "config", "user.name", "hooktest")
+	Git(t, root, "config", "commit.gpgsign", "false")
+	if len(files) == 0 {
+		return root
+	}
+	for path, content := range files {
+		WriteFile(t, root, path, content)
+	}
+	Git(t, root, "add", "-A")
+	Git(t, root, "commit", "-q", "-m", "initial")
+	return root
+}
+
+// WriteFile writes content to the slash-separated path under root, creating
+// its directories, and returns the file's absolute path
+func WriteFile(t testing.TB, root, path, content string) string {
+	t.Helper()
+	full := filepath.Join(root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatalf("Failed to create %s: %v", filepath.Dir(full), err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", full, err)
+	}
+	return full
+}
+
+// Git runs git in dir and returns its trimmed output, failing the test when
+// it fails
+func Git(t testing.TB, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// FakeTool puts an executable called name first on PATH for the rest of the
+// test. It appends its arguments to the returned log, one call per line, then
+// runs script with sh, e.g. `echo "a.go:1:1: bad"; exit 1`.
+func FakeTool(t testing.TB, name, script string) (log string) {
+	t.Helper()
+	bin := filepath.Join(t.TempDir(), "bin")
+	if err := os.MkdirAll(bin, 0o755); err != nil {
+		t.Fatalf("Failed to create %s: %v", bin, err)
+	}
+	log = filepath.Join(bin, name+".log")
+	content := "#!/bin/sh\necho \"$@\" >> '" + log + "'\n" + script + "\n"
+	if err := os.WriteFile(filepath.Join(bin, name), []byte(content), 0o755); err != nil {
+		t.Fatalf("Failed to write fake %s: %v", name, err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+// Calls returns the arguments of each call a FakeTool logged, or nil when it
+// wasn't called
+func Calls(t testing.TB, log string) []string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", log, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+var build struct {
+	once sync.Once
+	path string
+	err  error
+}
+
+// Binary returns the claude-hook binary built from this checkout. It's built
+// on first use and shared by every test of the process.
+func Binary(t testing.TB) string {
+	t.Helper()
+	build.once.Do(func() {
+		dir, err := os.MkdirTemp("", "hooktest")
+		if err != nil {
+			build.err = err
+			return
+		}
+		build.path = filepath.Join(dir, "claude-hook")
+		cmd := exec.Command("go", "build", "-o", build.path, "./cmd/claude-hook")
+		cmd.Dir = moduleRoot()
+		if output, err := cmd.CombinedOutput(); err != nil {
+			build.err = &buildError{err: err, output: string(output)}
+		}
+	})
+	if build.err != nil {
+		t.Fatalf("Failed to build claude-hook: %v", build.err)
+	}
+	return build.path
+}
+
+type buildError struct {
+	err    error
+	output string
+}
+
+func (e *buildError) Error() string {
+	return e.err.Error() + "\n" + e.output
+}
+
+// moduleRoot is the checkout this package is in
+func moduleRoot() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(filepath.Dir(filepath.Dir(file)))
+}
+
+// Result is what a hook run printed and how it exited
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// RunHook runs `claude-hook <hookType>` with the payload on stdin and env
+// ("NAME=value") added to the test's environment. It only fails the test when
+// the hook can't be started: a non-zero exit is a result like any other.
+func RunHook(t testing.TB, hookType string, payload Payload, env ...string) Result {
+	t.Helper()
+	cmd := exec.Command(Binary(t), hookType)
+	cmd.Stdin = strings.NewReader(payload.JSON(t))
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	result := Result{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("Failed to run the %s hook: %v", hookType, err)
+	}
+	return result
+}
@@ -0,0 +1,67 @@
+package hooktest_test
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brianleishman/claude-hooks/internal/hooks"
+	"github.com/brianleishman/claude-hooks/pkg/hooktest"
+)
+
+func TestPostEditWithFakeTool(t *testing.T) {
+	hooktest.Isolate(t)
+	root := hooktest.Repo(t, map[string]string{
+		".claude-hooks.yaml": "bazel:\n  enabled: true\n",
+		"MODULE.bazel":       "module(name = \"x\")\n",
+		"pkg/BUILD.bazel":    "go_library(name = \"pkg\", srcs = [\"a.go\"])\n",
+		"pkg/a.go":           "package pkg\n",
+	})
+	log := hooktest.FakeTool(t, "bazel", `case "$1" in
+query) echo //pkg:pkg ;;
+*) echo 'pkg/a.go:3:1: undefined: x' >&2; exit 1 ;;
+esac`)
+
+	file := filepath.Join(root, "pkg", "a.go")
+	diags, err := hooktest.PostEdit(t, hooks.GetHook("bazel"), "bazel", file)
+	if err == nil {
+		t.Fatal("Expected the failing build to fail the hook")
+	}
+	hooktest.AssertDiagnostic(t, diags, file, 3, "undefined: x")
+	if calls := hooktest.Calls(t, log); len(calls) < 2 || !slices.ContainsFunc(calls, func(c string) bool { return strings.HasPrefix(c, "build") }) {
+		t.Errorf("Expected a query and a build, got %q", calls)
+	}
+}
+
+func TestParseDecision(t *testing.T) {
+	tests := []struct {
+		stdout string
+		want   hooktest.Decision
+	}{
+		{`{"decision":"block","reason":"fix it"}`, hooktest.Decision{Kind: "block", Reason: "fix it"}},
+		{`{"hookSpecificOutput":{"hookEventName":"PreToolUse","permissionDecision":"deny","permissionDecisionReason":"no"}}`, hooktest.Decision{Kind: "deny", Reason: "no"}},
+		{`{"hookSpecificOutput":{"hookEventName":"PostToolUse","additionalContext":"heads up"}}`, hooktest.Decision{Context: "heads up"}},
+		{`{"continue":false,"stopReason":"out of budget","decision":"block","reason":"stop"}`, hooktest.Decision{Kind: "block", Reason: "stop", Halt: true, StopReason: "out of budget"}},
+		{"✅ All checks passed!\n", hooktest.Decision{}},
+	}
+	for _, tt := range tests {
+		if got := hooktest.ParseDecision(t, tt.stdout); got != tt.want {
+			t.Errorf("Expected %+v for %q, got %+v", tt.want, tt.stdout, got)
+		}
+	}
+}
+
+func TestRunHook(t *testing.T) {
+	hooktest.Isolate(t)
+	dir := t.TempDir()
+
+	result := hooktest.RunHook(t, "pre-bash", hooktest.Bash("mysql -u root").In(dir))
+	hooktest.AssertStopped(t, result.Stdout, "MySQL commands are not allowed")
+
+	result = hooktest.RunHook(t, "pre-bash", hooktest.Bash("ls").In(dir))
+	hooktest.AssertNotStopped(t, result.Stdout)
+	if result.ExitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d: %s", result.ExitCode, result.Stderr)
+	}
+}
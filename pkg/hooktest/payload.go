@@ -0,0 +1,96 @@
+package hooktest
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Payload is the JSON Claude Code sends a hook on stdin
+type Payload struct {
+	SessionID      string         `json:"session_id,omitempty"`
+	TranscriptPath string         `json:"transcript_path,omitempty"`
+	Cwd            string         `json:"cwd,omitempty"`
+	HookEventName  string         `json:"hook_event_name,omitempty"`
+	ToolName       string         `json:"tool_name,omitempty"`
+	ToolInput      map[string]any `json:"tool_input,omitempty"`
+	Source         string         `json:"source,omitempty"` // SessionStart: startup, resume, clear, or compact
+	StopHookActive bool           `json:"stop_hook_active,omitempty"`
+	Prompt         string         `json:"prompt,omitempty"` // UserPromptSubmit
+
+	raw *string
+}
+
+// Edit is an Edit tool call replacing old with new in file
+func Edit(file, old, new string) Payload {
+	return Payload{HookEventName: "PostToolUse", ToolName: "Edit", ToolInput: map[string]any{
+		"file_path": file, "old_string": old, "new_string": new,
+	}}
+}
+
+// Write is a Write tool call creating or overwriting file with content
+func Write(file, content string) Payload {
+	return Payload{HookEventName: "PostToolUse", ToolName: "Write", ToolInput: map[string]any{
+		"file_path": file, "content": content,
+	}}
+}
+
+// Bash is a Bash tool call running command
+func Bash(command string) Payload {
+	return Payload{HookEventName: "PreToolUse", ToolName: "Bash", ToolInput: map[string]any{"command": command}}
+}
+
+// ExitPlanMode is the tool call presenting a plan, which plan review checks
+func ExitPlanMode(plan string) Payload {
+	return Payload{HookEventName: "PreToolUse", ToolName: "ExitPlanMode", ToolInput: map[string]any{"plan": plan}}
+}
+
+// SessionStart is the payload of a session starting from source
+func SessionStart(source string) Payload {
+	return Payload{HookEventName: "SessionStart", Source: source}
+}
+
+// Stop is the payload of the turn ending
+func Stop() Payload {
+	return Payload{HookEventName: "Stop"}
+}
+
+// UserPrompt is the payload of the user sending prompt
+func UserPrompt(prompt string) Payload {
+	return Payload{HookEventName: "UserPromptSubmit", Prompt: prompt}
+}
+
+// Raw is stdin as given, e.g. malformed JSON
+func Raw(stdin string) Payload {
+	return Payload{raw: &stdin}
+}
+
+// In sets the working directory of the call
+func (p Payload) In(cwd string) Payload {
+	p.Cwd = cwd
+	return p
+}
+
+// Session sets the session the call belongs to
+func (p Payload) Session(id, transcriptPath string) Payload {
+	p.SessionID, p.TranscriptPath = id, transcriptPath
+	return p
+}
+
+// Pre makes a tool call payload the PreToolUse one, sent before it runs
+func (p Payload) Pre() Payload {
+	p.HookEventName = "PreToolUse"
+	return p
+}
+
+// JSON encodes the payload as the hook reads it
+func (p Payload) JSON(t testing.TB) string {
+	t.Helper()
+	if p.raw != nil {
+		return *p.raw
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	return string(data)
+}